@@ -0,0 +1,123 @@
+package analytics
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/event"
+	"github.com/yaoapp/yao/timeseries"
+)
+
+// Load create the rollup tables and collect the events of the bus, the chats, the messages and the tokens.
+// The rollups start from the first load, the history saved before is not counted.
+func Load(cfg config.Config) error {
+	if capsule.Global == nil {
+		return nil
+	}
+
+	if err := migrate(); err != nil {
+		return err
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if subscriber == 0 {
+		subscriber = event.Subscribe(collect, event.ChatCreated, event.MessageSaved, event.UsageRecorded)
+	}
+	return nil
+}
+
+// collect the event into the rollups of the day of it
+func collect(ev *event.Event) {
+	data, ok := ev.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	day := ev.Time.UTC().Format(layout)
+	team := toString(data["team"])
+
+	var err error
+	switch ev.Name {
+	case event.ChatCreated:
+		err = increment(day, team, "", MetricChats, 1)
+		if assistant := toString(data["assistant_id"]); err == nil && assistant != "" {
+			err = increment(day, team, assistant, MetricChats, 1)
+		}
+
+	case event.MessageSaved:
+		err = collectMessages(day, team, data)
+
+	case event.UsageRecorded:
+		metric := toString(data["metric"])
+		timeseries.Add(timeseries.Point{Metric: "usage." + metric, Tags: map[string]string{"team": team}, Value: float64(toInt64(data["quantity"])), Time: ev.Time})
+		if metric == MetricTokens {
+			err = increment(day, team, "", MetricTokens, toInt64(data["quantity"]))
+		}
+	}
+
+	if err != nil {
+		log.Error("[Analytics] %s %s: %s", ev.Name, ev.ID, err.Error())
+	}
+}
+
+// collectMessages the messages of the team and the assistants, the user sent the messages is active
+func collectMessages(day string, team string, data map[string]interface{}) error {
+	err := increment(day, team, "", MetricMessages, toInt64(data["messages"]))
+	if err != nil {
+		return err
+	}
+
+	if assistants, ok := data["assistants"].(map[string]int); ok {
+		for assistant, n := range assistants {
+			if assistant == "" {
+				continue
+			}
+			if err := increment(day, team, assistant, MetricMessages, int64(n)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if toInt64(data["prompts"]) > 0 {
+		return active(day, team, toString(data["sid"]))
+	}
+	return nil
+}
+
+func toInt64(v interface{}) int64 {
+	switch value := v.(type) {
+	case int:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	case uint64:
+		return int64(value)
+	case float64:
+		return int64(value)
+	case []byte:
+		n, _ := strconv.ParseInt(string(value), 10, 64)
+		return n
+	case string:
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return n
+	}
+	return 0
+}
+
+func toString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,59 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/event"
+)
+
+func TestPeriod(t *testing.T) {
+	now := time.Date(2024, 3, 15, 23, 30, 0, 0, time.UTC)
+
+	start, end, err := Filter{}.period(now)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-02-15", start.Format(layout))
+	assert.Equal(t, "2024-03-15", end.Format(layout))
+
+	start, end, err = Filter{Start: "2024-01-01", End: "2024-01-31"}.period(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*24*time.Hour, end.Sub(start))
+
+	_, _, err = Filter{Start: "2024-02-01", End: "2024-01-31"}.period(now)
+	assert.Error(t, err)
+	_, _, err = Filter{Start: "2022-01-01", End: "2024-01-31"}.period(now)
+	assert.Error(t, err)
+	_, _, err = Filter{End: "01/31/2024"}.period(now)
+	assert.Error(t, err)
+}
+
+func TestReport(t *testing.T) {
+	// The days without the rollups are zero
+	days, err := Daily(Filter{Start: "2024-01-30", End: "2024-02-02"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2024-01-30", "2024-01-31", "2024-02-01", "2024-02-02"}, []string{days[0].Date, days[1].Date, days[2].Date, days[3].Date})
+	assert.Equal(t, Day{Date: "2024-02-01"}, days[2])
+
+	summary, err := Total(Filter{Start: "2024-01-01", End: "2024-01-31", Team: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, &Summary{Start: "2024-01-01", End: "2024-01-31"}, summary)
+
+	assistants, err := Assistants(Filter{})
+	assert.NoError(t, err)
+	assert.Empty(t, assistants)
+
+	// The events are ignored before the tables are created
+	collect(&event.Event{ID: "e1", Name: event.MessageSaved, Time: time.Now(), Data: map[string]interface{}{"team": "1", "sid": "u1", "messages": 2, "prompts": 1}})
+}
+
+func TestTop(t *testing.T) {
+	assistants := top([]Assistant{
+		{ID: "writer", Chats: 3, Messages: 40},
+		{ID: "coder", Chats: 5, Messages: 90},
+		{ID: "reviewer", Chats: 9, Messages: 40},
+		{ID: "translator", Chats: 1, Messages: 2},
+	}, 3)
+	assert.Equal(t, []string{"coder", "reviewer", "writer"}, []string{assistants[0].ID, assistants[1].ID, assistants[2].ID})
+	assert.Len(t, top(assistants, 0), 3)
+}
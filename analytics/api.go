@@ -0,0 +1,54 @@
+package analytics
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetRoutes the reports of the dashboards, for the admins
+//
+//	GET <path>/summary?start=2024-01-01&end=2024-01-31&team=1    The totals of the period
+//	GET <path>/daily?start=2024-01-01&end=2024-01-31&team=1      The daily active users, the chats, the messages and the tokens of the days
+//	GET <path>/assistants?start=2024-01-01&team=1&limit=10       The top assistants of the period
+func SetRoutes(router *gin.Engine, path string, guards ...gin.HandlerFunc) {
+	handlers := func(handler gin.HandlerFunc) []gin.HandlerFunc {
+		return append(append([]gin.HandlerFunc{}, guards...), handler)
+	}
+	router.GET(path+"/summary", handlers(handleSummary)...)
+	router.GET(path+"/daily", handlers(handleDaily)...)
+	router.GET(path+"/assistants", handlers(handleAssistants)...)
+}
+
+// filter the filter of the query string
+func filter(c *gin.Context) Filter {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	return Filter{Start: c.Query("start"), End: c.Query("end"), Team: c.Query("team"), Limit: limit}
+}
+
+func handleSummary(c *gin.Context) {
+	summary, err := Total(filter(c))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(200, summary)
+}
+
+func handleDaily(c *gin.Context) {
+	days, err := Daily(filter(c))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(200, days)
+}
+
+func handleAssistants(c *gin.Context) {
+	assistants, err := Assistants(filter(c))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(200, assistants)
+}
@@ -0,0 +1,56 @@
+package analytics
+
+import (
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("analytics", map[string]process.Handler{
+		"summary":    processSummary,
+		"daily":      processDaily,
+		"assistants": processAssistants,
+	})
+}
+
+// processSummary analytics.Summary({"start": "2024-01-01", "end": "2024-01-31", "team": "1"}?)
+func processSummary(process *process.Process) interface{} {
+	summary, err := Total(filterOf(process))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return summary
+}
+
+// processDaily analytics.Daily({"start": "2024-01-01", "end": "2024-01-31", "team": "1"}?)
+func processDaily(process *process.Process) interface{} {
+	days, err := Daily(filterOf(process))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return days
+}
+
+// processAssistants analytics.Assistants({"start": "2024-01-01", "team": "1", "limit": 10}?)
+func processAssistants(process *process.Process) interface{} {
+	assistants, err := Assistants(filterOf(process))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return assistants
+}
+
+// filterOf the filter of the first argument
+func filterOf(process *process.Process) Filter {
+	if process.NumOfArgs() == 0 {
+		return Filter{}
+	}
+
+	option := process.ArgsMap(0)
+	return Filter{
+		Start: toString(option["start"]),
+		End:   toString(option["end"]),
+		Team:  toString(option["team"]),
+		Limit: int(toInt64(option["limit"])),
+	}
+}
@@ -0,0 +1,216 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yaoapp/xun/dbal"
+	"github.com/yaoapp/xun/dbal/query"
+)
+
+// layout the layout of the dates of the rollups
+const layout = "2006-01-02"
+
+// Daily the metrics of the days of the period, the days without the events are zero
+func Daily(filter Filter) ([]Day, error) {
+	start, end, err := filter.period(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	days := []Day{}
+	index := map[string]int{}
+	for at := start; !at.After(end); at = at.AddDate(0, 0, 1) {
+		index[at.Format(layout)] = len(days)
+		days = append(days, Day{Date: at.Format(layout)})
+	}
+
+	if !ready {
+		return days, nil
+	}
+
+	rows, err := filter.query(start, end).
+		Select("date", "metric", dbal.Raw("SUM(value) AS value")).
+		Where("assistant", "").
+		GroupBy("date", "metric").
+		Get()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		i, has := index[toString(row["date"])]
+		if !has {
+			continue
+		}
+
+		day := &days[i]
+		value := toInt64(row["value"])
+		switch toString(row["metric"]) {
+		case MetricUsers:
+			day.Users = value
+		case MetricChats:
+			day.Chats = value
+		case MetricMessages:
+			day.Messages = value
+		case MetricTokens:
+			day.Tokens = value
+		}
+	}
+	return days, nil
+}
+
+// Total the totals of the period, the users are the distinct users of the period
+func Total(filter Filter) (*Summary, error) {
+	start, end, err := filter.period(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{Start: start.Format(layout), End: end.Format(layout)}
+	if !ready {
+		return summary, nil
+	}
+
+	rows, err := filter.query(start, end).
+		Select("metric", dbal.Raw("SUM(value) AS value")).
+		Where("assistant", "").
+		GroupBy("metric").
+		Get()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		value := toInt64(row["value"])
+		switch toString(row["metric"]) {
+		case MetricChats:
+			summary.Chats = value
+		case MetricMessages:
+			summary.Messages = value
+		case MetricTokens:
+			summary.Tokens = value
+		}
+	}
+
+	qb := newQuery(activeTable).
+		Select(dbal.Raw("COUNT(DISTINCT uid) AS users")).
+		Where("date", ">=", summary.Start).
+		Where("date", "<=", summary.End)
+	if filter.Team != "" {
+		qb.Where("team", filter.Team)
+	}
+
+	row, err := qb.First()
+	if err != nil {
+		return nil, err
+	}
+	summary.Users = toInt64(row.Get("users"))
+	return summary, nil
+}
+
+// Assistants the top assistants of the period, by the messages
+func Assistants(filter Filter) ([]Assistant, error) {
+	start, end, err := filter.period(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	assistants := []Assistant{}
+	if !ready {
+		return assistants, nil
+	}
+
+	rows, err := filter.query(start, end).
+		Select("assistant", "metric", dbal.Raw("SUM(value) AS value")).
+		Where("assistant", "<>", "").
+		GroupBy("assistant", "metric").
+		Get()
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string]int{}
+	for _, row := range rows {
+		id := toString(row["assistant"])
+		i, has := index[id]
+		if !has {
+			i = len(assistants)
+			index[id] = i
+			assistants = append(assistants, Assistant{ID: id})
+		}
+
+		switch toString(row["metric"]) {
+		case MetricChats:
+			assistants[i].Chats = toInt64(row["value"])
+		case MetricMessages:
+			assistants[i].Messages = toInt64(row["value"])
+		}
+	}
+	return top(assistants, filter.Limit), nil
+}
+
+// top the assistants sorted by the messages, then the chats
+func top(assistants []Assistant, limit int) []Assistant {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	sort.Slice(assistants, func(i, j int) bool {
+		if assistants[i].Messages != assistants[j].Messages {
+			return assistants[i].Messages > assistants[j].Messages
+		}
+		if assistants[i].Chats != assistants[j].Chats {
+			return assistants[i].Chats > assistants[j].Chats
+		}
+		return assistants[i].ID < assistants[j].ID
+	})
+
+	if len(assistants) > limit {
+		assistants = assistants[:limit]
+	}
+	return assistants
+}
+
+// query the rollups of the period and the team
+func (filter Filter) query(start time.Time, end time.Time) query.Query {
+	qb := newQuery(dailyTable).
+		Where("date", ">=", start.Format(layout)).
+		Where("date", "<=", end.Format(layout))
+	if filter.Team != "" {
+		qb.Where("team", filter.Team)
+	}
+	return qb
+}
+
+// period the first and the last day of the filter, the last 30 days by default, a year at most
+func (filter Filter) period(now time.Time) (time.Time, time.Time, error) {
+	now = now.UTC()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if filter.End != "" {
+		at, err := time.Parse(layout, filter.End)
+		if err != nil {
+			return end, end, fmt.Errorf("the end %s is invalid, YYYY-MM-DD", filter.End)
+		}
+		end = at
+	}
+
+	start := end.AddDate(0, 0, -29)
+	if filter.Start != "" {
+		at, err := time.Parse(layout, filter.Start)
+		if err != nil {
+			return start, end, fmt.Errorf("the start %s is invalid, YYYY-MM-DD", filter.Start)
+		}
+		start = at
+	}
+
+	if start.After(end) {
+		return start, end, fmt.Errorf("the start %s is after the end %s", start.Format(layout), end.Format(layout))
+	}
+
+	if end.Sub(start) > 366*24*time.Hour {
+		return start, end, fmt.Errorf("the period is longer than a year")
+	}
+	return start, end, nil
+}
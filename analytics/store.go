@@ -0,0 +1,138 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+const (
+	dailyTable  = "yao_analytics_daily"
+	activeTable = "yao_analytics_active"
+)
+
+// ready the tables are created
+var ready = false
+
+// migrate create the tables of the rollups.
+// The daily rollup of the team is the row without the assistant, the rows of the assistants are the top assistants.
+func migrate() error {
+	sch := capsule.Schema()
+	has, err := sch.HasTable(dailyTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		err = sch.CreateTable(dailyTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("slot", 600).Unique() // <date>|<team>|<assistant>|<metric>
+			table.String("date", 10).Index()
+			table.String("team", 200).SetDefault("").Index()
+			table.String("assistant", 200).SetDefault("").Index()
+			table.String("metric", 50).Index()
+			table.BigInteger("value").SetDefault(0)
+			table.TimestampTz("updated_at").Null()
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the analytics table: %s", dailyTable)
+	}
+
+	has, err = sch.HasTable(activeTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		err = sch.CreateTable(activeTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("slot", 600).Unique() // <date>|<team>|<uid>
+			table.String("date", 10).Index()
+			table.String("team", 200).SetDefault("").Index()
+			table.String("uid", 255).Index()
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the analytics table: %s", activeTable)
+	}
+
+	ready = true
+	return nil
+}
+
+func newQuery(table string) query.Query {
+	qb := capsule.Query()
+	qb.Table(table)
+	return qb
+}
+
+// increment the metric of the day, the row is created by the first increment.
+// The increment is atomic, the events are collected in the background concurrently.
+func increment(day string, team string, assistant string, metric string, n int64) error {
+	if !ready || n == 0 {
+		return nil
+	}
+
+	key := fmt.Sprintf("%s|%s|%s|%s", day, team, assistant, metric)
+	update := func() (int64, error) {
+		return newQuery(dailyTable).Where("slot", key).Update(map[string]interface{}{
+			"value":      dbal.Raw(fmt.Sprintf("value + %d", n)),
+			"updated_at": time.Now(),
+		})
+	}
+
+	affected, err := update()
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	err = newQuery(dailyTable).Insert(map[string]interface{}{
+		"slot":       key,
+		"date":       day,
+		"team":       team,
+		"assistant":  assistant,
+		"metric":     metric,
+		"value":      n,
+		"updated_at": time.Now(),
+	})
+
+	// The row is created by another increment at the same time
+	if err != nil {
+		affected, e := update()
+		if e == nil && affected > 0 {
+			return nil
+		}
+	}
+	return err
+}
+
+// active the user is active in the day, the daily active users of the team are counted once a user
+func active(day string, team string, uid string) error {
+	if !ready || uid == "" {
+		return nil
+	}
+
+	key := fmt.Sprintf("%s|%s|%s", day, team, uid)
+	has, err := newQuery(activeTable).Where("slot", key).Exists()
+	if err != nil || has {
+		return err
+	}
+
+	err = newQuery(activeTable).Insert(map[string]interface{}{"slot": key, "date": day, "team": team, "uid": uid})
+	if err != nil {
+		// The user is counted by another event at the same time
+		if has, e := newQuery(activeTable).Where("slot", key).Exists(); e == nil && has {
+			return nil
+		}
+		return err
+	}
+	return increment(day, team, "", MetricUsers, 1)
+}
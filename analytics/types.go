@@ -0,0 +1,50 @@
+package analytics
+
+import "sync"
+
+// The metrics of the daily rollups
+const (
+	MetricUsers    = "users"    // The users sent the messages, the daily active users
+	MetricChats    = "chats"    // The chats created
+	MetricMessages = "messages" // The messages saved, of the users and the assistants
+	MetricTokens   = "tokens"   // The tokens recorded by billing.Record
+)
+
+// Filter the filter of the reports, the dates are the days in UTC, e.g. 2024-01-31
+type Filter struct {
+	Start string `json:"start,omitempty"` // The first day, 29 days before the end by default
+	End   string `json:"end,omitempty"`   // The last day, included, today by default
+	Team  string `json:"team,omitempty"`  // All of the teams if empty
+	Limit int    `json:"limit,omitempty"` // The number of the top assistants, 10 by default
+}
+
+// Summary the totals of the period, the users are the distinct users of the period
+type Summary struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Users    int64  `json:"users"`
+	Chats    int64  `json:"chats"`
+	Messages int64  `json:"messages"`
+	Tokens   int64  `json:"tokens"`
+}
+
+// Day the metrics of a day, the users are the daily active users
+type Day struct {
+	Date     string `json:"date"`
+	Users    int64  `json:"users"`
+	Chats    int64  `json:"chats"`
+	Messages int64  `json:"messages"`
+	Tokens   int64  `json:"tokens"`
+}
+
+// Assistant the metrics of an assistant in the period
+type Assistant struct {
+	ID       string `json:"assistant_id"`
+	Chats    int64  `json:"chats"`
+	Messages int64  `json:"messages"`
+}
+
+// subscriber the subscriber id of the event bus, the analytics subscribe the events once
+var subscriber int64 = 0
+
+var lock sync.Mutex
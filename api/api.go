@@ -13,19 +13,96 @@ import (
 // Load apis
 func Load(cfg config.Config) error {
 	messages := []string{}
+	res := map[string]*Validation{}
+	cors := map[string]*CORS{}
+	sizes := map[string]int64{}
+	deps := map[string]Version{}
+	ids := []string{}
+
+	// Remove the versioned APIs of the last load
+	versionLock.Lock()
+	for _, id := range versioned {
+		delete(api.APIs, id)
+	}
+	versionLock.Unlock()
 
 	exts := []string{"*.http.yao", "*.http.json", "*.http.jsonc"}
 	err := application.App.Walk("apis", func(root, file string, isdir bool) error {
 		if isdir {
 			return nil
 		}
-		_, err := api.Load(file, share.ID(root, file))
+		id := share.ID(root, file)
+		_, err := api.Load(file, id)
 		if err != nil {
 			messages = append(messages, err.Error())
+			return err
+		}
+
+		err = loadValidations(file, res)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+		}
+
+		err = loadLimits(file, sizes)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+		}
+
+		err = loadCORS(file, cors)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+		}
+
+		vids, err := loadVersions(file, id, res, deps)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
 		}
-		return err
+		ids = append(ids, vids...)
+		return nil
 	}, exts...)
 
+	// Proxies
+	proxies := map[string]*Proxy{}
+	application.App.Walk("apis", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+		id := share.ID(root, file)
+		p, err := loadProxy(file, id)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+		proxies[id] = p
+		if p.CORS != nil {
+			cors[groupPrefix(p.Group)] = p.CORS
+		}
+		return nil
+	}, "*.proxy.yao", "*.proxy.json", "*.proxy.jsonc")
+
+	messages = append(messages, checkProxies(proxies)...)
+
+	proxyLock.Lock()
+	Proxies = proxies
+	proxyLock.Unlock()
+
+	limitLock.Lock()
+	limits = sizes
+	limitLock.Unlock()
+
+	corsLock.Lock()
+	policies = cors
+	corsLock.Unlock()
+
+	validationLock.Lock()
+	validations = res
+	validationLock.Unlock()
+
+	versionLock.Lock()
+	deprecations = deps
+	versioned = ids
+	versionLock.Unlock()
+
 	if len(messages) > 0 {
 		return fmt.Errorf(strings.Join(messages, ";\n"))
 	}
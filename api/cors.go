@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/application"
+)
+
+// DefaultCORSHeaders the request headers allowed by default
+var DefaultCORSHeaders = []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "Accept", "Origin", "Cache-Control", "X-Requested-With"}
+
+// DefaultCORSMethods the methods allowed by default
+var DefaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// CORS the cross-origin policy of the API group, the "cors" section of the API DSL
+type CORS struct {
+	Origins     []string `json:"origins,omitempty"`     // The origins allowed, e.g. https://app.example.com, *.example.com, * allows all of the origins
+	Methods     []string `json:"methods,omitempty"`     // The methods allowed, DefaultCORSMethods by default
+	Headers     []string `json:"headers,omitempty"`     // The request headers allowed, DefaultCORSHeaders by default
+	Expose      []string `json:"expose,omitempty"`      // The response headers exposed to the browser
+	Credentials bool     `json:"credentials,omitempty"` // Allow the cookies and the authorization headers
+	MaxAge      int      `json:"maxAge,omitempty"`      // The seconds the preflight response is cached
+}
+
+// corsDSL the cors section of the API DSL, the other fields are parsed by gou
+type corsDSL struct {
+	Group    string             `json:"group,omitempty"`
+	CORS     *CORS              `json:"cors,omitempty"`
+	Versions map[string]Version `json:"versions,omitempty"`
+}
+
+// policies /api/<group> => the cross-origin policy
+var policies = map[string]*CORS{}
+var corsLock sync.RWMutex
+
+// loadCORS read the cross-origin policy of the API file, the versions of the API share the policy
+func loadCORS(file string, res map[string]*CORS) error {
+	data, err := application.App.Read(file)
+	if err != nil {
+		return err
+	}
+
+	dsl := corsDSL{}
+	err = application.Parse(file, data, &dsl)
+	if err != nil {
+		return err
+	}
+
+	if dsl.CORS == nil {
+		return nil
+	}
+
+	if len(dsl.CORS.Origins) == 0 {
+		return fmt.Errorf("cors: the origins are required")
+	}
+
+	group := strings.Trim(dsl.Group, "/")
+	res[groupPrefix(group)] = dsl.CORS
+	for version := range dsl.Versions {
+		res[groupPrefix(version+"/"+group)] = dsl.CORS
+	}
+	return nil
+}
+
+func groupPrefix(group string) string {
+	group = strings.Trim(group, "/")
+	if group == "" {
+		return "/api"
+	}
+	return "/api/" + group
+}
+
+// CORSOf the cross-origin policy of the request path, the policy of the longest matched group
+func CORSOf(route string) *CORS {
+	corsLock.RLock()
+	defer corsLock.RUnlock()
+
+	var policy *CORS
+	matched := -1
+	for prefix, p := range policies {
+		if len(prefix) > matched && (route == prefix || strings.HasPrefix(route, prefix+"/")) {
+			policy, matched = p, len(prefix)
+		}
+	}
+	return policy
+}
+
+// CrossOrigin the middleware applies the cross-origin policy of the API group, the disallowed origins are responded with 403
+func CrossOrigin(c *gin.Context) {
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		return
+	}
+
+	policy := CORSOf(c.Request.URL.Path)
+	if policy == nil {
+		return
+	}
+
+	if !policy.Allowed(origin) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"code": http.StatusForbidden, "message": origin + " not allowed"})
+		return
+	}
+
+	policy.SetHeaders(c.Writer.Header(), origin, c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "")
+	if c.Request.Method == http.MethodOptions {
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// Allowed the origin is allowed or not, the patterns without the scheme match any scheme
+func (policy *CORS) Allowed(origin string) bool {
+	origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	scheme, host, has := strings.Cut(origin, "://")
+	if !has {
+		scheme, host = "", origin
+	}
+
+	for _, pattern := range policy.Origins {
+		pattern = strings.ToLower(strings.TrimSuffix(pattern, "/"))
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+
+		pscheme, phost, has := strings.Cut(pattern, "://")
+		if !has {
+			pscheme, phost = "", pattern
+		}
+		if pscheme != "" && pscheme != scheme {
+			continue
+		}
+
+		if phost == host || (strings.HasPrefix(phost, "*.") && strings.HasSuffix(host, phost[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetHeaders set the cross-origin headers of the allowed origin, the preflight headers are set if preflight is true
+func (policy *CORS) SetHeaders(header http.Header, origin string, preflight bool) {
+	allowOrigin := origin
+	if !policy.Credentials && len(policy.Origins) == 1 && policy.Origins[0] == "*" {
+		allowOrigin = "*"
+	}
+
+	header.Set("Access-Control-Allow-Origin", allowOrigin)
+	if allowOrigin != "*" {
+		header.Add("Vary", "Origin")
+	}
+	if policy.Credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(policy.Expose) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(policy.Expose, ", "))
+	}
+
+	if !preflight {
+		return
+	}
+
+	methods := policy.Methods
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+	headers := policy.Headers
+	if len(headers) == 0 {
+		headers = DefaultCORSHeaders
+	}
+
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	header.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	if policy.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", fmt.Sprintf("%d", policy.MaxAge))
+	}
+}
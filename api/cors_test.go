@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSAllowed(t *testing.T) {
+	policy := &CORS{Origins: []string{"https://app.example.com", "*.yaoapps.com", "http://localhost:8000"}}
+	assert.True(t, policy.Allowed("https://app.example.com"))
+	assert.True(t, policy.Allowed("https://app.example.com/"))
+	assert.True(t, policy.Allowed("https://docs.yaoapps.com"))
+	assert.True(t, policy.Allowed("http://a.b.yaoapps.com"))
+	assert.True(t, policy.Allowed("http://localhost:8000"))
+	assert.False(t, policy.Allowed("http://app.example.com"))
+	assert.False(t, policy.Allowed("https://yaoapps.com.evil.com"))
+	assert.False(t, policy.Allowed("https://evilyaoapps.com"))
+	assert.False(t, policy.Allowed("http://localhost:9000"))
+	assert.True(t, (&CORS{Origins: []string{"*"}}).Allowed("https://any.com"))
+}
+
+func TestCORSOf(t *testing.T) {
+	pet := &CORS{Origins: []string{"*"}}
+	user := &CORS{Origins: []string{"https://app.example.com"}}
+	prev := policies
+	policies = map[string]*CORS{"/api/pet": pet, "/api/pet/user": user, "/api/v1/pet": pet}
+	defer func() { policies = prev }()
+
+	assert.Equal(t, pet, CORSOf("/api/pet"))
+	assert.Equal(t, pet, CORSOf("/api/pet/1"))
+	assert.Equal(t, user, CORSOf("/api/pet/user/1"))
+	assert.Equal(t, pet, CORSOf("/api/v1/pet/1"))
+	assert.Nil(t, CORSOf("/api/pets"))
+}
+
+func TestCrossOrigin(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	prev := policies
+	policies = map[string]*CORS{
+		"/api/pet":  {Origins: []string{"https://app.example.com"}, Credentials: true, MaxAge: 600, Expose: []string{"ETag"}},
+		"/api/open": {Origins: []string{"*"}},
+	}
+	defer func() { policies = prev }()
+
+	router := gin.New()
+	router.Use(CrossOrigin)
+	router.Any("/api/pet/:id", func(c *gin.Context) { c.String(200, "ok") })
+	router.Any("/api/open/:id", func(c *gin.Context) { c.String(200, "ok") })
+
+	send := func(method string, path string, origin string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if method == "OPTIONS" {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	res := send("GET", "/api/pet/1", "https://app.example.com")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "https://app.example.com", res.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", res.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "ETag", res.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", res.Header().Get("Vary"))
+	assert.Empty(t, res.Header().Get("Access-Control-Max-Age"))
+
+	res = send("OPTIONS", "/api/pet/1", "https://app.example.com")
+	assert.Equal(t, 204, res.Code)
+	assert.Equal(t, "600", res.Header().Get("Access-Control-Max-Age"))
+	assert.Contains(t, res.Header().Get("Access-Control-Allow-Methods"), "DELETE")
+	assert.Contains(t, res.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+
+	res = send("GET", "/api/pet/1", "https://evil.com")
+	assert.Equal(t, 403, res.Code)
+
+	res = send("GET", "/api/pet/1", "")
+	assert.Equal(t, 200, res.Code)
+	assert.Empty(t, res.Header().Get("Access-Control-Allow-Origin"))
+
+	res = send("GET", "/api/open/1", "https://any.com")
+	assert.Equal(t, "*", res.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, res.Header().Get("Access-Control-Allow-Credentials"))
+}
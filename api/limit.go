@@ -0,0 +1,141 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/yao/config"
+)
+
+// limitDSL the body limits of the API paths, the other fields are parsed by gou
+type limitDSL struct {
+	Group    string             `json:"group,omitempty"`
+	Versions map[string]Version `json:"versions,omitempty"`
+	Paths    []struct {
+		Path      string      `json:"path"`
+		Method    string      `json:"method"`
+		BodyLimit interface{} `json:"bodyLimit,omitempty"` // The max body size, the bytes or the size with unit, e.g. 10MB, 512KB
+	} `json:"paths,omitempty"`
+}
+
+// limits METHOD /api/<group>/<path> => the max body size in bytes
+var limits = map[string]int64{}
+var limitLock sync.RWMutex
+
+// loadLimits read the body limits of the API file, the versions of the API share the limits
+func loadLimits(file string, res map[string]int64) error {
+	data, err := application.App.Read(file)
+	if err != nil {
+		return err
+	}
+
+	dsl := limitDSL{}
+	err = application.Parse(file, data, &dsl)
+	if err != nil {
+		return err
+	}
+
+	groups := []string{dsl.Group}
+	for version := range dsl.Versions {
+		groups = append(groups, version+"/"+strings.Trim(dsl.Group, "/"))
+	}
+
+	for _, path := range dsl.Paths {
+		if path.BodyLimit == nil {
+			continue
+		}
+
+		size, err := ParseSize(path.BodyLimit)
+		if err != nil {
+			return fmt.Errorf("%s %s: %s", path.Method, path.Path, err.Error())
+		}
+
+		for _, group := range groups {
+			route := filepath.ToSlash(filepath.Join("/api", group, path.Path))
+			res[strings.ToUpper(path.Method)+" "+route] = size
+		}
+	}
+	return nil
+}
+
+// ParseSize the size in bytes, e.g. 1024, "512KB", "10M", "1GB"
+func ParseSize(v interface{}) (int64, error) {
+	switch value := v.(type) {
+	case int:
+		return int64(value), nil
+	case int64:
+		return value, nil
+	case float64:
+		return int64(value), nil
+	case string:
+		value = strings.ToUpper(strings.TrimSpace(value))
+		value = strings.TrimSuffix(value, "B")
+		unit := int64(1)
+		switch {
+		case strings.HasSuffix(value, "K"):
+			unit = 1 << 10
+		case strings.HasSuffix(value, "M"):
+			unit = 1 << 20
+		case strings.HasSuffix(value, "G"):
+			unit = 1 << 30
+		}
+		value = strings.TrimRight(value, "KMG")
+		size, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || size < 0 {
+			return 0, fmt.Errorf("the size %v is invalid", v)
+		}
+		return int64(size * float64(unit)), nil
+	}
+	return 0, fmt.Errorf("the size %v is invalid", v)
+}
+
+// LimitOf the max body size of the request in bytes, 0 is unlimited. The multipart requests are limited by the upload limit
+func LimitOf(method string, route string, contentType string) int64 {
+	limitLock.RLock()
+	size, has := limits[strings.ToUpper(method)+" "+route]
+	limitLock.RUnlock()
+	if has {
+		return size
+	}
+
+	if strings.HasPrefix(contentType, "multipart/") {
+		return int64(config.Conf.UploadLimit) << 20
+	}
+	return int64(config.Conf.BodyLimit) << 20
+}
+
+// BodyLimit the middleware limits the request body size of the API paths, responds 413 if the body is too large
+func BodyLimit(c *gin.Context) {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody || !strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		return
+	}
+
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+
+	size := LimitOf(c.Request.Method, route, c.ContentType())
+	if size <= 0 {
+		return
+	}
+
+	if c.Request.ContentLength > size {
+		abortTooLarge(c, size)
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, size)
+}
+
+func abortTooLarge(c *gin.Context, size int64) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+		"code":    http.StatusRequestEntityTooLarge,
+		"message": fmt.Sprintf("the request body is larger than %d bytes", size),
+	})
+}
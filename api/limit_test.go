@@ -0,0 +1,84 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/config"
+)
+
+func TestParseSize(t *testing.T) {
+	for value, size := range map[interface{}]int64{
+		1024: 1024, 2048.0: 2048, "512": 512, "512KB": 512 << 10, "10M": 10 << 20, "10mb": 10 << 20, "1.5G": 3 << 29,
+	} {
+		res, err := ParseSize(value)
+		assert.Nil(t, err, value)
+		assert.Equal(t, size, res, value)
+	}
+
+	for _, value := range []interface{}{"ten", "MB", "-1KB", true} {
+		_, err := ParseSize(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	bodyLimit, uploadLimit := config.Conf.BodyLimit, config.Conf.UploadLimit
+	config.Conf.BodyLimit, config.Conf.UploadLimit = 1, 2
+	prev := limits
+	limits = map[string]int64{"POST /api/pet/small": 16}
+	defer func() {
+		config.Conf.BodyLimit, config.Conf.UploadLimit = bodyLimit, uploadLimit
+		limits = prev
+	}()
+
+	router := gin.New()
+	router.Use(BodyLimit)
+	handler := func(c *gin.Context) {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(400, err.Error())
+			return
+		}
+		c.String(200, "%d", len(data))
+	}
+	router.POST("/api/pet/small", handler)
+	router.POST("/api/pet/upload", handler)
+
+	send := func(path string, contentType string, body string, chunked bool) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if chunked {
+			req.ContentLength = -1
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	res := send("/api/pet/small", "application/json", `{"name":"cat"}`, false)
+	assert.Equal(t, 200, res.Code)
+
+	res = send("/api/pet/small", "application/json", `{"name":"a cat named tom"}`, false)
+	assert.Equal(t, 413, res.Code)
+
+	res = send("/api/pet/small", "application/json", `{"name":"a cat named tom"}`, true)
+	assert.Equal(t, 400, res.Code)
+
+	res = send("/api/pet/upload", "application/json", strings.Repeat("a", 1<<20+1), false)
+	assert.Equal(t, 413, res.Code)
+
+	res = send("/api/pet/upload", "multipart/form-data; boundary=x", strings.Repeat("a", 1<<20+1), false)
+	assert.Equal(t, 200, res.Code)
+
+	// Unlimited unless configured
+	config.Conf.BodyLimit, config.Conf.UploadLimit = 0, 0
+	res = send("/api/pet/upload", "application/json", strings.Repeat("a", 1<<20+1), false)
+	assert.Equal(t, 200, res.Code)
+}
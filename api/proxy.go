@@ -0,0 +1,279 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/api"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/kun/log"
+)
+
+// Proxy the proxy API, forwards the requests of /api/<group>/... to the upstream service, the *.proxy.yao files of the apis directory
+type Proxy struct {
+	ID              string              `json:"-"`
+	Name            string              `json:"name,omitempty"`
+	Group           string              `json:"group"`
+	Upstream        string              `json:"upstream"`                  // The upstream URL, e.g. http://127.0.0.1:8080/v1
+	Timeout         int                 `json:"timeout,omitempty"`         // The seconds waiting for the response headers of the upstream, 30 by default
+	Guard           string              `json:"guard,omitempty"`           // The guards, e.g. bearer-jwt
+	Methods         []string            `json:"methods,omitempty"`         // The methods forwarded, all of the methods by default
+	Paths           []string            `json:"paths,omitempty"`           // The paths forwarded, e.g. /charges/*, all of the paths by default
+	Rewrite         []map[string]string `json:"rewrite,omitempty"`         // The path rewrite rules, e.g. {"^/charges/(.*)$": "/payments/$1"}
+	Headers         map[string]string   `json:"headers,omitempty"`         // The headers of the upstream request, the empty value removes the header, $ENV.NAME is replaced by the environment variable
+	ResponseHeaders map[string]string   `json:"responseHeaders,omitempty"` // The headers of the response, the empty value removes the header
+	CORS            *CORS               `json:"cors,omitempty"`            // The cross-origin policy of the group
+	target          *url.URL
+	rules           []proxyRule
+	proxy           *httputil.ReverseProxy
+}
+
+type proxyRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// Proxies the loaded proxy APIs
+var Proxies = map[string]*Proxy{}
+var proxyLock sync.RWMutex
+
+// loadProxy read the proxy API
+func loadProxy(file string, id string) (*Proxy, error) {
+	data, err := application.App.Read(file)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Proxy{ID: id}
+	err = application.Parse(file, data, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, p.compile()
+}
+
+// compile the upstream, the rewrite rules and the reverse proxy
+func (p *Proxy) compile() error {
+	var err error
+	p.Group = strings.Trim(p.Group, "/")
+	if p.Group == "" {
+		return fmt.Errorf("the group is required")
+	}
+
+	p.target, err = url.Parse(p.Upstream)
+	if err != nil || p.target.Scheme == "" || p.target.Host == "" {
+		return fmt.Errorf("the upstream %s is invalid", p.Upstream)
+	}
+
+	for _, rule := range p.Rewrite {
+		for pattern, replacement := range rule {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("the rewrite rule %s is invalid: %s", pattern, err.Error())
+			}
+			p.rules = append(p.rules, proxyRule{pattern: re, replacement: replacement})
+		}
+	}
+
+	timeout := 30 * time.Second
+	if p.Timeout > 0 {
+		timeout = time.Duration(p.Timeout) * time.Second
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+
+	p.proxy = &httputil.ReverseProxy{
+		Rewrite:        p.rewrite,
+		Transport:      transport,
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler:   p.errorHandler,
+		FlushInterval:  -1,
+	}
+	return nil
+}
+
+// checkProxies the groups of the proxies should not be used by the other APIs, the conflicted proxies are removed
+func checkProxies(proxies map[string]*Proxy) []string {
+	groups := map[string]string{}
+	for _, inst := range api.APIs {
+		groups[strings.Trim(inst.HTTP.Group, "/")] = inst.ID
+	}
+
+	ids := make([]string, 0, len(proxies))
+	for id := range proxies {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	messages := []string{}
+	for _, id := range ids {
+		p := proxies[id]
+		if other := conflicted(groups, p.Group); other != "" {
+			messages = append(messages, fmt.Sprintf("proxy %s: the group %s is used by %s", id, p.Group, other))
+			delete(proxies, id)
+			continue
+		}
+		groups[p.Group] = id
+	}
+	return messages
+}
+
+// conflicted the id of the API whose group is the same as the group, or nested in it, e.g. pay and pay/v2
+func conflicted(groups map[string]string, group string) string {
+	for name, id := range groups {
+		if name == group || strings.HasPrefix(name, group+"/") || strings.HasPrefix(group, name+"/") {
+			return id
+		}
+	}
+	return ""
+}
+
+// SetProxyRoutes register the routes of the proxies, /api/<group>/*path
+func SetProxyRoutes(router *gin.Engine, guards map[string]gin.HandlerFunc) {
+	proxyLock.RLock()
+	defer proxyLock.RUnlock()
+
+	for _, p := range Proxies {
+		handlers := []gin.HandlerFunc{}
+		for _, name := range strings.Split(p.Guard, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" || name == "-" {
+				continue
+			}
+			guard, has := guards[name]
+			if !has {
+				log.Error("[Proxy] %s the guard %s does not exist", p.ID, name)
+				continue
+			}
+			handlers = append(handlers, guard)
+		}
+		handlers = append(handlers, p.handle)
+		router.Any(fmt.Sprintf("/api/%s/*path", p.Group), handlers...)
+	}
+}
+
+// handle forward the request to the upstream
+func (p *Proxy) handle(c *gin.Context) {
+	if !p.Match(c.Request.Method, c.Param("path")) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"code": http.StatusNotFound, "message": "Not Found"})
+		return
+	}
+	p.proxy.ServeHTTP(c.Writer, c.Request)
+	c.Abort()
+}
+
+// Match the request is forwarded or not, the path is relative to the group
+func (p *Proxy) Match(method string, name string) bool {
+	// The dot segments are resolved by the upstreams, the paths of them could escape the allowed paths
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+
+	if len(p.Methods) > 0 {
+		matched := false
+		for _, m := range p.Methods {
+			matched = matched || strings.EqualFold(m, method)
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(p.Paths) == 0 {
+		return true
+	}
+
+	for _, pattern := range p.Paths {
+		if pattern == name {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(name, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Path the upstream path of the path relative to the group, rewritten by the first matched rule
+func (p *Proxy) Path(name string) string {
+	for _, rule := range p.rules {
+		if rule.pattern.MatchString(name) {
+			name = rule.pattern.ReplaceAllString(name, rule.replacement)
+			break
+		}
+	}
+	return singleJoin(p.target.Path, name)
+}
+
+func (p *Proxy) rewrite(r *httputil.ProxyRequest) {
+	name := strings.TrimPrefix(r.In.URL.Path, "/api/"+p.Group)
+	r.Out.URL.Scheme = p.target.Scheme
+	r.Out.URL.Host = p.target.Host
+	r.Out.URL.Path = p.Path(name)
+	r.Out.URL.RawPath = ""
+	r.Out.Host = p.target.Host
+
+	query := p.target.RawQuery
+	if query != "" && r.In.URL.RawQuery != "" {
+		query += "&"
+	}
+	r.Out.URL.RawQuery = query + r.In.URL.RawQuery
+
+	r.SetXForwarded()
+	setHeaders(r.Out.Header, p.Headers)
+}
+
+func (p *Proxy) modifyResponse(res *http.Response) error {
+	setHeaders(res.Header, p.ResponseHeaders)
+	return nil
+}
+
+func (p *Proxy) errorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	log.Error("[Proxy] %s %s %s: %s", p.ID, r.Method, r.URL.Path, err.Error())
+	code := http.StatusBadGateway
+	if strings.Contains(err.Error(), "timeout") {
+		code = http.StatusGatewayTimeout
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `{"code":%d,"message":%q}`, code, http.StatusText(code))
+}
+
+// setHeaders set the headers, the empty value removes the header, $ENV.NAME is replaced by the environment variable
+func setHeaders(header http.Header, values map[string]string) {
+	for name, value := range values {
+		if strings.HasPrefix(value, "$ENV.") {
+			value = os.Getenv(strings.TrimPrefix(value, "$ENV."))
+		}
+		if value == "" {
+			header.Del(name)
+			continue
+		}
+		header.Set(name, value)
+	}
+}
+
+func singleJoin(base string, name string) string {
+	if name == "" {
+		if base == "" {
+			return "/"
+		}
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(name, "/")
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyMatch(t *testing.T) {
+	p := &Proxy{Group: "pay", Upstream: "http://127.0.0.1:8080/v1", Methods: []string{"GET", "post"}, Paths: []string{"/charges/*", "/refunds", "/users/*/cards"}}
+	if err := p.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, p.Match("GET", "/charges/ch_1"))
+	assert.True(t, p.Match("POST", "/charges/ch_1/capture"))
+	assert.True(t, p.Match("GET", "/refunds"))
+	assert.True(t, p.Match("GET", "/users/1/cards"))
+	assert.False(t, p.Match("DELETE", "/charges/ch_1"))
+	assert.False(t, p.Match("GET", "/refunds/re_1"))
+	assert.False(t, p.Match("GET", "/users"))
+	assert.False(t, p.Match("GET", "/charges/../admin/x"))
+	assert.False(t, p.Match("GET", "/charges/./ch_1"))
+}
+
+func TestProxyPath(t *testing.T) {
+	p := &Proxy{Group: "/pay/", Upstream: "http://127.0.0.1:8080/v1", Rewrite: []map[string]string{{"^/charges/(.*)$": "/payments/$1"}}}
+	if err := p.compile(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "pay", p.Group)
+	assert.Equal(t, "/v1/payments/ch_1", p.Path("/charges/ch_1"))
+	assert.Equal(t, "/v1/refunds", p.Path("/refunds"))
+	assert.Equal(t, "/v1", p.Path(""))
+
+	assert.Error(t, (&Proxy{Group: "pay", Upstream: "127.0.0.1"}).compile())
+	assert.Error(t, (&Proxy{Upstream: "http://127.0.0.1"}).compile())
+}
+
+func TestProxyForward(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	t.Setenv("YAO_TEST_PROXY_KEY", "secret")
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/slow" {
+			time.Sleep(2 * time.Second)
+		}
+		w.Header().Set("Server", "upstream")
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("X-Query", r.URL.RawQuery)
+		w.Header().Set("X-Key", r.Header.Get("X-Api-Key"))
+		w.Header().Set("X-Cookie", r.Header.Get("Cookie"))
+		io.WriteString(w, "ok")
+	}))
+	defer upstream.Close()
+
+	p := &Proxy{
+		ID:              "pay",
+		Group:           "pay",
+		Upstream:        upstream.URL + "/v1",
+		Timeout:         1,
+		Paths:           []string{"/charges/*", "/slow"},
+		Rewrite:         []map[string]string{{"^/charges/(.*)$": "/payments/$1"}},
+		Headers:         map[string]string{"X-Api-Key": "$ENV.YAO_TEST_PROXY_KEY", "Cookie": ""},
+		ResponseHeaders: map[string]string{"Server": ""},
+	}
+	if err := p.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	proxies := Proxies
+	Proxies = map[string]*Proxy{"pay": p}
+	defer func() { Proxies = proxies }()
+
+	router := gin.New()
+	SetProxyRoutes(router, map[string]gin.HandlerFunc{})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL+"/api/pay/charges/ch_1?expand=customer", nil)
+	req.Header.Set("Cookie", "sid=1")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, "/v1/payments/ch_1", res.Header.Get("X-Path"))
+	assert.Equal(t, "expand=customer", res.Header.Get("X-Query"))
+	assert.Equal(t, "secret", res.Header.Get("X-Key"))
+	assert.Empty(t, res.Header.Get("X-Cookie"))
+	assert.Empty(t, res.Header.Get("Server"))
+
+	// The dot segments could not escape the allowed paths
+	for _, name := range []string{"/charges/../admin/x", "/charges/%2e%2e/admin/x"} {
+		res, err = http.Get(srv.URL + "/api/pay" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		assert.Equal(t, 404, res.StatusCode)
+	}
+
+	res, err = http.Get(srv.URL + "/api/pay/refunds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	assert.Equal(t, 404, res.StatusCode)
+
+	res, err = http.Get(srv.URL + "/api/pay/slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	assert.Equal(t, 504, res.StatusCode)
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema the subset of the JSON schema to validate the request
+type Schema struct {
+	Type                 string             `json:"type,omitempty"` // string, number, integer, boolean, object, array, null
+	Required             []string           `json:"required,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Message              string             `json:"message,omitempty"` // The custom message of the errors
+	pattern              *regexp.Regexp
+}
+
+// FieldError the validation error of the field
+type FieldError struct {
+	In      string `json:"in"`              // query, body
+	Field   string `json:"field,omitempty"` // The path of the field, e.g. pets[0].name
+	Message string `json:"message"`
+}
+
+// compile the patterns of the schema
+func (schema *Schema) compile() error {
+	if schema.Pattern != "" {
+		re, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return fmt.Errorf("the pattern %s is invalid: %s", schema.Pattern, err.Error())
+		}
+		schema.pattern = re
+	}
+
+	for _, prop := range schema.Properties {
+		if err := prop.compile(); err != nil {
+			return err
+		}
+	}
+
+	if schema.Items != nil {
+		return schema.Items.compile()
+	}
+	return nil
+}
+
+// Validate the JSON value, the errors are sorted by the fields
+func (schema *Schema) Validate(in string, field string, value interface{}) []FieldError {
+	errs := schema.validate(in, field, value)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+func (schema *Schema) validate(in string, field string, value interface{}) []FieldError {
+	fail := func(format string, args ...interface{}) []FieldError {
+		message := fmt.Sprintf(format, args...)
+		if schema.Message != "" {
+			message = schema.Message
+		}
+		return []FieldError{{In: in, Field: field, Message: message}}
+	}
+
+	if schema.Type != "" && !isType(schema.Type, value) {
+		return fail("should be %s", schema.Type)
+	}
+
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, option := range schema.Enum {
+			if fmt.Sprintf("%v", option) == fmt.Sprintf("%v", value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fail("should be one of %v", schema.Enum)
+		}
+	}
+
+	errs := []FieldError{}
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			return fail("should be at least %d characters", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return fail("should be at most %d characters", *schema.MaxLength)
+		}
+		if schema.pattern != nil && !schema.pattern.MatchString(v) {
+			return fail("should match %s", schema.Pattern)
+		}
+
+	case float64:
+		if schema.Minimum != nil && v < *schema.Minimum {
+			return fail("should be >= %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && v > *schema.Maximum {
+			return fail("should be <= %v", *schema.Maximum)
+		}
+
+	case []interface{}:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			return fail("should have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			return fail("should have at most %d items", *schema.MaxItems)
+		}
+		if schema.Items != nil {
+			for i, item := range v {
+				errs = append(errs, schema.Items.validate(in, fmt.Sprintf("%s[%d]", field, i), item)...)
+			}
+		}
+
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, has := v[name]; !has {
+				errs = append(errs, FieldError{In: in, Field: join(field, name), Message: "is required"})
+			}
+		}
+
+		for name, item := range v {
+			prop, has := schema.Properties[name]
+			if !has {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					errs = append(errs, FieldError{In: in, Field: join(field, name), Message: "is not allowed"})
+				}
+				continue
+			}
+			errs = append(errs, prop.validate(in, join(field, name), item)...)
+		}
+	}
+
+	return errs
+}
+
+func isType(typ string, value interface{}) bool {
+	switch typ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+
+	case "number":
+		_, ok := value.(float64)
+		return ok
+
+	case "integer":
+		v, ok := value.(float64)
+		return ok && v == math.Trunc(v)
+
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func join(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+	return strings.Join([]string{parent, name}, ".")
+}
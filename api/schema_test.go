@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schema := &Schema{}
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["name", "pets"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "minLength": 2, "maxLength": 8},
+			"email": {"type": "string", "pattern": "^[^@]+@[^@]+$", "message": "invalid email"},
+			"age": {"type": "integer", "minimum": 0},
+			"status": {"enum": ["enabled", "disabled"]},
+			"pets": {"type": "array", "minItems": 1, "items": {"type": "object", "required": ["kind"]}}
+		}
+	}`), schema)
+	assert.NoError(t, err)
+	assert.NoError(t, schema.compile())
+
+	var body interface{}
+	json.Unmarshal([]byte(`{"name": "cat", "email": "cat@yao.run", "age": 3, "status": "enabled", "pets": [{"kind": "cat"}]}`), &body)
+	assert.Empty(t, schema.Validate("body", "", body))
+
+	json.Unmarshal([]byte(`{"name": "c", "email": "cat", "age": 1.5, "status": "unknown", "pets": [{}], "extra": 1}`), &body)
+	errs := schema.Validate("body", "", body)
+	assert.Equal(t, []FieldError{
+		{In: "body", Field: "age", Message: "should be integer"},
+		{In: "body", Field: "email", Message: "invalid email"},
+		{In: "body", Field: "extra", Message: "is not allowed"},
+		{In: "body", Field: "name", Message: "should be at least 2 characters"},
+		{In: "body", Field: "pets[0].kind", Message: "is required"},
+		{In: "body", Field: "status", Message: "should be one of [enabled disabled]"},
+	}, errs)
+
+	errs = schema.Validate("body", "", []interface{}{})
+	assert.Equal(t, []FieldError{{In: "body", Message: "should be object"}}, errs)
+}
+
+func TestConvert(t *testing.T) {
+	assert.Equal(t, float64(10), convert("integer", "10"))
+	assert.Equal(t, true, convert("boolean", "true"))
+	assert.Equal(t, "abc", convert("number", "abc"))
+	assert.Equal(t, "10", convert("string", "10"))
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/kun/maps"
+)
+
+// Validation the validation of the request, the "validate" section of the API path
+type Validation struct {
+	Query *Schema `json:"query,omitempty"` // The object schema of the query parameters, the values are converted by the property types
+	Body  *Schema `json:"body,omitempty"`  // The schema of the JSON body
+	Model string  `json:"model,omitempty"` // The JSON body is validated by the column validations of the model
+}
+
+// validationDSL the paths of the API DSL with the validations, the other fields are parsed by gou
+type validationDSL struct {
+	Group string `json:"group,omitempty"`
+	Paths []struct {
+		Path     string      `json:"path"`
+		Method   string      `json:"method"`
+		Validate *Validation `json:"validate,omitempty"`
+	} `json:"paths,omitempty"`
+}
+
+// validations METHOD /api/<group>/<path> => validation
+var validations = map[string]*Validation{}
+var validationLock sync.RWMutex
+
+// loadValidations read the validations of the API file
+func loadValidations(file string, res map[string]*Validation) error {
+	data, err := application.App.Read(file)
+	if err != nil {
+		return err
+	}
+
+	dsl := validationDSL{}
+	err = application.Parse(file, data, &dsl)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range dsl.Paths {
+		if path.Validate == nil {
+			continue
+		}
+
+		for _, schema := range []*Schema{path.Validate.Query, path.Validate.Body} {
+			if schema == nil {
+				continue
+			}
+			if err := schema.compile(); err != nil {
+				return fmt.Errorf("%s %s: %s", path.Method, path.Path, err.Error())
+			}
+		}
+
+		if path.Validate.Model != "" {
+			if _, has := model.Models[path.Validate.Model]; !has {
+				return fmt.Errorf("%s %s: the model %s is not loaded", path.Method, path.Path, path.Validate.Model)
+			}
+		}
+
+		route := filepath.ToSlash(filepath.Join("/api", dsl.Group, path.Path))
+		res[strings.ToUpper(path.Method)+" "+route] = path.Validate
+	}
+	return nil
+}
+
+// Validate the middleware validates the requests of the API paths, responds 422 with the errors
+func Validate(c *gin.Context) {
+	validationLock.RLock()
+	validation, has := validations[c.Request.Method+" "+c.FullPath()]
+	validationLock.RUnlock()
+	if !has {
+		return
+	}
+
+	errs := []FieldError{}
+	if validation.Query != nil {
+		errs = append(errs, validation.Query.Validate("query", "", queryValues(c, validation.Query))...)
+	}
+
+	if validation.Body != nil || validation.Model != "" {
+		body, err := readBody(c)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			abortTooLarge(c, tooLarge.Limit)
+			return
+		}
+		if err != nil {
+			errs = append(errs, FieldError{In: "body", Message: err.Error()})
+		} else {
+			if validation.Body != nil {
+				errs = append(errs, validation.Body.Validate("body", "", body)...)
+			}
+			if validation.Model != "" {
+				errs = append(errs, modelErrors(validation.Model, body)...)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		c.AbortWithStatusJSON(422, gin.H{"code": 422, "message": "Validation failed", "errors": errs})
+	}
+}
+
+// queryValues the query parameters converted by the types of the schema properties
+func queryValues(c *gin.Context, schema *Schema) map[string]interface{} {
+	values := map[string]interface{}{}
+	for name, items := range c.Request.URL.Query() {
+		typ := ""
+		prop, has := schema.Properties[name]
+		if has {
+			typ = prop.Type
+		}
+
+		if typ == "array" {
+			itemType := ""
+			if prop.Items != nil {
+				itemType = prop.Items.Type
+			}
+
+			arr := []interface{}{}
+			for _, item := range items {
+				arr = append(arr, convert(itemType, item))
+			}
+			values[name] = arr
+			continue
+		}
+		values[name] = convert(typ, items[0])
+	}
+	return values
+}
+
+// convert the query value by the type, the value is kept as the string if it can not be converted
+func convert(typ string, value string) interface{} {
+	switch typ {
+	case "number", "integer":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
+
+// readBody read the JSON body, the body is restored for the process
+func readBody(c *gin.Context) (interface{}, error) {
+	if c.Request.Body == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	raw, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	var body interface{}
+	err = json.Unmarshal(raw, &body)
+	if err != nil {
+		return nil, fmt.Errorf("the body is not JSON: %s", err.Error())
+	}
+	return body, nil
+}
+
+// modelErrors the errors of the column validations of the model
+func modelErrors(id string, body interface{}) []FieldError {
+	row, ok := body.(map[string]interface{})
+	if !ok {
+		return []FieldError{{In: "body", Message: "should be object"}}
+	}
+
+	mod, has := model.Models[id]
+	if !has {
+		return []FieldError{{In: "body", Message: fmt.Sprintf("the model %s is not loaded", id)}}
+	}
+
+	errs := []FieldError{}
+	for _, res := range mod.Validate(maps.MapStr(row)) {
+		for _, message := range res.Messages {
+			errs = append(errs, FieldError{In: "body", Field: res.Column, Message: message})
+		}
+	}
+	return errs
+}
+
+// ValidationOf the validation of the API path, e.g. GET /api/pet/:id, nil if not declared
+func ValidationOf(method string, route string) *Validation {
+	validationLock.RLock()
+	defer validationLock.RUnlock()
+	return validations[strings.ToUpper(method)+" "+route]
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/api"
+	"github.com/yaoapp/gou/application"
+)
+
+var reVersion = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Version the version of the API, the "versions" section of the API DSL
+type Version struct {
+	Deprecated bool   `json:"deprecated,omitempty"`
+	Sunset     string `json:"sunset,omitempty"` // The date the version is removed, e.g. 2025-12-31
+	Link       string `json:"link,omitempty"`   // The successor version, e.g. /api/v2/pet
+}
+
+// PathVersion the process binding of the path in the version, the "versions" section of the API path
+type PathVersion struct {
+	Version
+	Disable  bool          `json:"disable,omitempty"` // The path is not served in the version
+	Process  string        `json:"process,omitempty"`
+	In       []interface{} `json:"in,omitempty"`
+	Out      interface{}   `json:"out,omitempty"`
+	Validate *Validation   `json:"validate,omitempty"`
+}
+
+// versionDSL the versions of the API DSL, the other fields are parsed by gou
+type versionDSL struct {
+	Group    string             `json:"group,omitempty"`
+	Versions map[string]Version `json:"versions,omitempty"`
+	Paths    []struct {
+		Path     string                 `json:"path"`
+		Method   string                 `json:"method"`
+		Validate *Validation            `json:"validate,omitempty"`
+		Versions map[string]PathVersion `json:"versions,omitempty"`
+	} `json:"paths,omitempty"`
+}
+
+// deprecations METHOD /api/<version>/<group>/<path> => the deprecated version
+var deprecations = map[string]Version{}
+
+// versioned the ids of the versioned APIs registered by the last load
+var versioned = []string{}
+var versionLock sync.RWMutex
+
+// loadVersions register the API of each version under /api/<version>/<group>, the paths are bound to the processes of the version
+func loadVersions(file string, id string, validations map[string]*Validation, deps map[string]Version) ([]string, error) {
+	data, err := application.App.Read(file)
+	if err != nil {
+		return nil, err
+	}
+
+	dsl := versionDSL{}
+	err = application.Parse(file, data, &dsl)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dsl.Versions) == 0 {
+		return nil, nil
+	}
+
+	// The raw DSL is cloned for the versions, the fields are kept as they are
+	raw := map[string]interface{}{}
+	err = application.Parse(file, data, &raw)
+	if err != nil {
+		return nil, err
+	}
+	paths, _ := raw["paths"].([]interface{})
+
+	versions := make([]string, 0, len(dsl.Versions))
+	for version := range dsl.Versions {
+		if !reVersion.MatchString(version) {
+			return nil, fmt.Errorf("the version %s is invalid", version)
+		}
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	ids := []string{}
+	for _, version := range versions {
+		group := "/" + version + "/" + strings.Trim(dsl.Group, "/")
+		source := map[string]interface{}{}
+		for key, value := range raw {
+			source[key] = value
+		}
+		source["group"] = group
+		delete(source, "versions")
+
+		vpaths := []interface{}{}
+		for i, path := range dsl.Paths {
+			if i >= len(paths) {
+				break
+			}
+
+			rawPath, ok := paths[i].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			vpath := map[string]interface{}{}
+			for key, value := range rawPath {
+				vpath[key] = value
+			}
+			delete(vpath, "versions")
+			delete(vpath, "validate")
+
+			deprecation := dsl.Versions[version]
+			validation := path.Validate
+			if override, has := path.Versions[version]; has {
+				if override.Disable {
+					continue
+				}
+				if override.Process != "" {
+					vpath["process"] = override.Process
+				}
+				if override.In != nil {
+					vpath["in"] = override.In
+				}
+				if override.Out != nil {
+					vpath["out"] = override.Out
+				}
+				if override.Validate != nil {
+					validation = override.Validate
+				}
+				if override.Deprecated || override.Sunset != "" || override.Link != "" {
+					deprecation = override.Version
+				}
+			}
+			vpaths = append(vpaths, vpath)
+
+			key := strings.ToUpper(path.Method) + " " + filepath.ToSlash(filepath.Join("/api", group, path.Path))
+			if deprecation.Deprecated {
+				deps[key] = deprecation
+			}
+
+			if validation != nil {
+				for _, schema := range []*Schema{validation.Query, validation.Body} {
+					if schema == nil {
+						continue
+					}
+					if err := schema.compile(); err != nil {
+						return nil, fmt.Errorf("%s %s: %s", path.Method, path.Path, err.Error())
+					}
+				}
+				validations[key] = validation
+			}
+		}
+		source["paths"] = vpaths
+
+		bytes, err := jsoniter.Marshal(source)
+		if err != nil {
+			return nil, err
+		}
+
+		httpDSL := api.HTTP{}
+		err = jsoniter.Unmarshal(bytes, &httpDSL)
+		if err != nil {
+			return nil, fmt.Errorf("version %s: %s", version, err.Error())
+		}
+
+		vid := fmt.Sprintf("%s@%s", id, version)
+		api.APIs[vid] = &api.API{ID: vid, File: file, HTTP: httpDSL, Type: "http"}
+		ids = append(ids, vid)
+	}
+
+	return ids, nil
+}
+
+// Deprecation the middleware sets the deprecation headers of the deprecated versions
+func Deprecation(c *gin.Context) {
+	versionLock.RLock()
+	version, has := deprecations[c.Request.Method+" "+c.FullPath()]
+	versionLock.RUnlock()
+	if !has {
+		return
+	}
+
+	c.Header("Deprecation", "true")
+	if version.Sunset != "" {
+		c.Header("Sunset", sunset(version.Sunset))
+	}
+	if version.Link != "" {
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, version.Link))
+	}
+}
+
+// sunset the HTTP date of the sunset, the value is kept if it is not a date
+func sunset(value string) string {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return value
+	}
+	return date.UTC().Format(http.TimeFormat)
+}
+
+// DeprecationOf the deprecated version of the API path, e.g. GET /api/v1/pet/:id
+func DeprecationOf(method string, route string) (Version, bool) {
+	versionLock.RLock()
+	defer versionLock.RUnlock()
+	version, has := deprecations[strings.ToUpper(method)+" "+route]
+	return version, has
+}
@@ -0,0 +1,12 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSunset(t *testing.T) {
+	assert.Equal(t, "Wed, 31 Dec 2025 00:00:00 GMT", sunset("2025-12-31"))
+	assert.Equal(t, "Wed, 31 Dec 2025 00:00:00 GMT", sunset("Wed, 31 Dec 2025 00:00:00 GMT"))
+}
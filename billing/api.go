@@ -0,0 +1,186 @@
+package billing
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/webhook"
+)
+
+// SetRoutes the stripe webhook and the billing of the team of the signed-in user
+//
+//	POST <path>/stripe                                                          The stripe webhook, verified by YAO_STRIPE_WEBHOOK_SECRET
+//	GET  <path>/plans                                                           The plans
+//	GET  <path>/subscription                                                    The plan and the subscription of the team
+//	POST <path>/checkout {"plan": "pro", "price": "monthly", "success_url": "", "cancel_url": ""}  The checkout session
+//	POST <path>/portal {"return_url": ""}                                        The billing portal session
+//	GET  <path>/usage?start=2024-01-01&end=2024-02-01                           The metered usage of the team, the current period by default
+func SetRoutes(router *gin.Engine, path string, guards ...gin.HandlerFunc) {
+	handlers := func(handler gin.HandlerFunc) []gin.HandlerFunc {
+		return append(append([]gin.HandlerFunc{}, guards...), handler)
+	}
+	router.POST(path+"/stripe", handleStripe)
+	router.GET(path+"/plans", handlers(handlePlans)...)
+	router.GET(path+"/subscription", handlers(handleSubscription)...)
+	router.POST(path+"/checkout", handlers(handleCheckout)...)
+	router.POST(path+"/portal", handlers(handlePortal)...)
+	router.GET(path+"/usage", handlers(handleUsage)...)
+}
+
+// Guard the billing-plan guard, the paths of the features are allowed for the plans of them only.
+// The team of the session is set by the guards before it, e.g. bearer-jwt,billing-plan
+func Guard(c *gin.Context) {
+	if !Guarded(c.Request.URL.Path) {
+		return
+	}
+
+	id, ok := team(c)
+	if !ok {
+		return
+	}
+
+	plan, _, err := PlanOf(id)
+	if err != nil {
+		c.AbortWithStatusJSON(402, gin.H{"code": 402, "message": err.Error()})
+		return
+	}
+
+	if !plan.Has(c.Request.URL.Path) {
+		c.AbortWithStatusJSON(402, gin.H{"code": 402, "message": fmt.Sprintf("the plan %s does not include %s, please upgrade the plan", plan.ID, c.Request.URL.Path)})
+	}
+}
+
+// team the team id of the session
+func team(c *gin.Context) (string, bool) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.AbortWithStatusJSON(403, gin.H{"code": 403, "message": "Not Authorized"})
+		return "", false
+	}
+
+	id, err := session.Global().ID(sid).Get(TeamField)
+	if err != nil || id == nil || id == "" {
+		c.AbortWithStatusJSON(403, gin.H{"code": 403, "message": "the session has no team"})
+		return "", false
+	}
+	return fmt.Sprintf("%v", id), true
+}
+
+func handleStripe(c *gin.Context) {
+	raw, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	verify := &webhook.Verify{Type: webhook.VerifyStripe, Secret: config.Conf.Stripe.WebhookSecret}
+	if verify.Secret == "" {
+		c.JSON(403, gin.H{"code": 403, "message": "the stripe webhook secret is not configured, set YAO_STRIPE_WEBHOOK_SECRET"})
+		return
+	}
+
+	if err := verify.Check(c.Request.Header, raw, time.Now()); err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	// Stripe retries the events not responded with 2xx
+	if err := Handle(raw); err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"received": true})
+}
+
+func handlePlans(c *gin.Context) {
+	c.JSON(200, List())
+}
+
+func handleSubscription(c *gin.Context) {
+	id, ok := team(c)
+	if !ok {
+		return
+	}
+
+	plan, sub, err := PlanOf(id)
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"plan": plan, "subscription": sub})
+}
+
+func handleCheckout(c *gin.Context) {
+	id, ok := team(c)
+	if !ok {
+		return
+	}
+
+	payload := struct {
+		Plan       string `json:"plan" binding:"required"`
+		Price      string `json:"price"`
+		SuccessURL string `json:"success_url" binding:"required"`
+		CancelURL  string `json:"cancel_url" binding:"required"`
+		Email      string `json:"email"`
+	}{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	session, err := Checkout(id, payload.Plan, payload.Price, payload.SuccessURL, payload.CancelURL, payload.Email)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(200, session)
+}
+
+func handlePortal(c *gin.Context) {
+	id, ok := team(c)
+	if !ok {
+		return
+	}
+
+	payload := struct {
+		ReturnURL string `json:"return_url"`
+	}{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+			return
+		}
+	}
+
+	session, err := Portal(id, payload.ReturnURL)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(200, session)
+}
+
+func handleUsage(c *gin.Context) {
+	id, ok := team(c)
+	if !ok {
+		return
+	}
+
+	start, end, err := period(id, c.Query("start"), c.Query("end"))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	statement, err := StatementOf(id, start, end)
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(200, statement)
+}
@@ -0,0 +1,243 @@
+package billing
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+// activeStatuses the statuses of the subscriptions entitled to the plan, the past due ones are in the grace period
+var activeStatuses = map[string]bool{"trialing": true, "active": true, "past_due": true}
+
+// Load the plans/*.yao plans, the subscription table is created if there are plans
+func Load(cfg config.Config) error {
+	plans := map[string]*Plan{}
+	messages := []string{}
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("plans", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		plan := Plan{}
+		err = application.Parse(file, bytes, &plan)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		plan.ID = share.ID(root, file)
+		if err := plan.validate(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		plans[plan.ID] = &plan
+		return nil
+	}, exts...)
+	if err != nil {
+		messages = append(messages, err.Error())
+	}
+
+	lock.Lock()
+	Plans = plans
+	lock.Unlock()
+
+	if len(plans) > 0 && capsule.Global != nil {
+		if err := migrate(); err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+	return nil
+}
+
+// List the plans, sorted by the id
+func List() []*Plan {
+	lock.RLock()
+	defer lock.RUnlock()
+	plans := []*Plan{}
+	for _, plan := range Plans {
+		plans = append(plans, plan)
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].ID < plans[j].ID })
+	return plans
+}
+
+// Select the plan
+func Select(id string) (*Plan, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	plan, has := Plans[id]
+	if !has {
+		return nil, fmt.Errorf("the plan %s does not exist", id)
+	}
+	return plan, nil
+}
+
+// Default the plan of the teams without the subscription, the plan marked as default or the plan "free"
+func Default() *Plan {
+	lock.RLock()
+	defer lock.RUnlock()
+	for _, plan := range Plans {
+		if plan.Default {
+			return plan
+		}
+	}
+	return Plans["free"]
+}
+
+// PlanOf the plan of the team, the default plan if the team has no active subscription
+func PlanOf(team string) (*Plan, *Subscription, error) {
+	sub, err := Get(team)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if sub != nil && activeStatuses[sub.Status] {
+		if plan, err := Select(sub.Plan); err == nil {
+			return plan, sub, nil
+		}
+	}
+
+	plan := Default()
+	if plan == nil {
+		return nil, sub, fmt.Errorf("the team %s has no plan, add plans/free.yao or mark a plan as default", team)
+	}
+	return plan, sub, nil
+}
+
+// Entitled the plan of the team has the feature
+func Entitled(team string, feature string) (bool, error) {
+	plan, _, err := PlanOf(team)
+	if err != nil {
+		return false, err
+	}
+	return plan.Has(feature), nil
+}
+
+// Has the plan has the feature, the paths of the APIs match the patterns of the features, e.g. /api/report/*
+func (plan *Plan) Has(feature string) bool {
+	for _, pattern := range plan.Features {
+		if match(pattern, feature) {
+			return true
+		}
+	}
+	return false
+}
+
+// Limit the limit of the plan, false if the plan has no limit of the name
+func (plan *Plan) Limit(name string) (int, bool) {
+	limit, has := plan.Limits[name]
+	return limit, has
+}
+
+// Guarded the path is a feature of any plan, the path not guarded is allowed for all of the plans
+func Guarded(p string) bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	for _, plan := range Plans {
+		for _, pattern := range plan.Features {
+			if strings.HasPrefix(pattern, "/") && match(pattern, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// price the price of the plan
+func (plan *Plan) price(id string) (*Price, error) {
+	if id == "" && len(plan.Prices) > 0 {
+		return &plan.Prices[0], nil
+	}
+
+	for i := range plan.Prices {
+		if plan.Prices[i].ID == id {
+			return &plan.Prices[i], nil
+		}
+	}
+	return nil, fmt.Errorf("the plan %s has no price %s", plan.ID, id)
+}
+
+func (plan *Plan) validate() error {
+	if plan.Name == "" {
+		plan.Name = plan.ID
+	}
+
+	ids := map[string]bool{}
+	for i := range plan.Prices {
+		price := &plan.Prices[i]
+		if price.ID == "" {
+			return fmt.Errorf("the id of the price is required")
+		}
+		if ids[price.ID] {
+			return fmt.Errorf("the price %s is duplicated", price.ID)
+		}
+		ids[price.ID] = true
+
+		if price.Amount < 0 {
+			return fmt.Errorf("the amount of the price %s is invalid", price.ID)
+		}
+
+		price.Currency = strings.ToLower(price.Currency)
+		if price.Currency == "" {
+			price.Currency = "usd"
+		}
+
+		switch price.Interval {
+		case "":
+			price.Interval = "month"
+		case "day", "week", "month", "year":
+		default:
+			return fmt.Errorf("the interval %s of the price %s is invalid, day, week, month or year", price.Interval, price.ID)
+		}
+	}
+
+	for metric, meter := range plan.Meters {
+		if meter.Unit == 0 {
+			meter.Unit = 1
+		}
+		if meter.Unit < 0 || meter.Included < 0 || meter.Price < 0 {
+			return fmt.Errorf("the meter %s is invalid", metric)
+		}
+		plan.Meters[metric] = meter
+	}
+
+	for _, pattern := range plan.Features {
+		if _, err := path.Match(strings.TrimSuffix(pattern, "*"), ""); err != nil {
+			return fmt.Errorf("the feature %s is invalid: %s", pattern, err.Error())
+		}
+	}
+	return nil
+}
+
+// match the pattern of the feature, the trailing * matches the rest of the path
+func match(pattern string, name string) bool {
+	if pattern == name || pattern == "*" {
+		return true
+	}
+
+	if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(name, strings.TrimSuffix(pattern, "*")) {
+		return true
+	}
+
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
@@ -0,0 +1,237 @@
+package billing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/config"
+)
+
+func prepare(t *testing.T) func() {
+	plans, key, api := Plans, config.Conf.Stripe.Key, API
+	free := &Plan{ID: "free", Features: []string{"chat"}}
+	pro := &Plan{ID: "pro", Name: "Pro", Trial: 14, Features: []string{"chat", "export", "/api/report/*"},
+		Prices: []Price{{ID: "monthly", Amount: 2000}, {ID: "yearly", Amount: 20000, Interval: "year"}},
+		Meters: map[string]Meter{"tokens": {Included: 1000000, Unit: 1000, Price: 2, Event: "yao_tokens"}, "calls": {Price: 1}}}
+	for _, plan := range []*Plan{free, pro} {
+		if err := plan.validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	Plans = map[string]*Plan{"free": free, "pro": pro}
+	config.Conf.Stripe.Key = "sk_test"
+	return func() { Plans, config.Conf.Stripe.Key, API = plans, key, api }
+}
+
+func TestPlan(t *testing.T) {
+	defer prepare(t)()
+
+	assert.Equal(t, "free", Default().ID)
+	assert.Equal(t, []string{"free", "pro"}, []string{List()[0].ID, List()[1].ID})
+
+	pro, err := Select("pro")
+	assert.NoError(t, err)
+	assert.Equal(t, "usd", pro.Prices[0].Currency)
+	assert.Equal(t, "month", pro.Prices[0].Interval)
+	assert.True(t, pro.Has("export"))
+	assert.True(t, pro.Has("/api/report/sales"))
+	assert.False(t, Plans["free"].Has("/api/report/sales"))
+
+	assert.True(t, Guarded("/api/report/sales/2024"))
+	assert.False(t, Guarded("/api/chat"))
+
+	price, err := pro.price("")
+	assert.NoError(t, err)
+	assert.Equal(t, "monthly", price.ID)
+	_, err = pro.price("weekly")
+	assert.Error(t, err)
+
+	_, err = Select("enterprise")
+	assert.Error(t, err)
+
+	// The plan of the team without the subscription is the default plan
+	plan, sub, err := PlanOf("team-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "free", plan.ID)
+	assert.Nil(t, sub)
+
+	assert.Error(t, (&Plan{ID: "bad", Prices: []Price{{ID: "daily", Interval: "hour"}}}).validate())
+	assert.Error(t, (&Plan{ID: "bad", Prices: []Price{{ID: "monthly"}, {ID: "monthly"}}}).validate())
+}
+
+func TestSubscription(t *testing.T) {
+	object := stripeSubscription{}
+	err := jsoniter.Unmarshal([]byte(`{
+		"id": "sub_1", "customer": "cus_1", "status": "active", "cancel_at_period_end": true,
+		"metadata": {"yao_team": "team-1", "yao_plan": "pro"},
+		"items": {"data": [{"current_period_end": 1735689600, "price": {"id": "price_2", "lookup_key": "yao:business:yearly"}}]}
+	}`), &object)
+	assert.NoError(t, err)
+
+	sub, err := object.subscription()
+	assert.NoError(t, err)
+	assert.Equal(t, "team-1", sub.Team)
+	assert.Equal(t, "business", sub.Plan)
+	assert.Equal(t, "yearly", sub.Price)
+	assert.Equal(t, "sub_1", sub.Subscription)
+	assert.True(t, sub.CancelAtPeriodEnd)
+	assert.Equal(t, int64(1735689600), sub.PeriodEnd.Unix())
+
+	// The subscription without the team is ignored if the customer is unknown
+	object.Metadata = map[string]string{}
+	sub, err = object.subscription()
+	assert.NoError(t, err)
+	assert.Nil(t, sub)
+
+	assert.NoError(t, Handle([]byte(`{"id": "evt_1", "type": "invoice.paid", "data": {"object": {}}}`)))
+	assert.Error(t, Handle([]byte(`{`)))
+}
+
+func TestSync(t *testing.T) {
+	defer prepare(t)()
+
+	requests := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sk_test", r.Header.Get("Authorization"))
+		r.ParseForm()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/products/yao_pro":
+			w.WriteHeader(404)
+			w.Write([]byte(`{"error": {"code": "resource_missing", "message": "No such product: 'yao_pro'"}}`))
+
+		case "POST /v1/products":
+			assert.Equal(t, "yao_pro", r.PostForm.Get("id"))
+			w.Write([]byte(`{"id": "yao_pro"}`))
+
+		case "GET /v1/prices":
+			switch r.Form.Get("lookup_keys[]") {
+			case "yao:pro:monthly":
+				w.Write([]byte(`{"data": [{"id": "price_m", "unit_amount": 2000, "currency": "usd", "recurring": {"interval": "month"}}]}`))
+			default:
+				w.Write([]byte(`{"data": [{"id": "price_y", "unit_amount": 19000, "currency": "usd", "recurring": {"interval": "year"}}]}`))
+			}
+
+		case "POST /v1/prices":
+			assert.Equal(t, "yao:pro:yearly", r.PostForm.Get("lookup_key"))
+			assert.Equal(t, "20000", r.PostForm.Get("unit_amount"))
+			assert.Equal(t, "true", r.PostForm.Get("transfer_lookup_key"))
+			w.Write([]byte(`{"id": "price_y2"}`))
+
+		case "POST /v1/prices/price_y":
+			assert.Equal(t, "false", r.PostForm.Get("active"))
+			w.Write([]byte(`{"id": "price_y"}`))
+
+		case "POST /v1/checkout/sessions":
+			assert.Equal(t, "price_m", r.PostForm.Get("line_items[0][price]"))
+			assert.Equal(t, "team-1", r.PostForm.Get("client_reference_id"))
+			assert.Equal(t, "team-1", r.PostForm.Get("subscription_data[metadata][yao_team]"))
+			assert.Equal(t, "14", r.PostForm.Get("subscription_data[trial_period_days]"))
+			assert.Equal(t, "max@example.com", r.PostForm.Get("customer_email"))
+			w.Write([]byte(`{"id": "cs_1", "url": "https://checkout.stripe.com/c/pay/cs_1"}`))
+
+		default:
+			w.WriteHeader(400)
+			w.Write([]byte(`{"error": {"message": "unexpected"}}`))
+		}
+	}))
+	defer srv.Close()
+	API = srv.URL
+
+	created, err := Sync()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"price_y2"}, created)
+	assert.Equal(t, []string{
+		"POST /v1/products/yao_pro", "POST /v1/products",
+		"GET /v1/prices",
+		"GET /v1/prices", "POST /v1/prices", "POST /v1/prices/price_y",
+	}, requests)
+
+	session, err := Checkout("team-1", "pro", "monthly", "https://example.com/ok", "https://example.com/cancel", "max@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://checkout.stripe.com/c/pay/cs_1", session.URL)
+
+	_, err = Checkout("team-1", "pro", "monthly", "", "", "")
+	assert.Error(t, err)
+
+	_, err = Portal("team-1", "")
+	assert.Error(t, err)
+
+	config.Conf.Stripe.Key = ""
+	_, err = Sync()
+	assert.Error(t, err)
+
+	assert.Equal(t, "yao:pro:monthly", lookupKey("pro", "monthly"))
+	assert.True(t, isMissing(&stripeError{Status: 404}))
+	assert.False(t, isMissing(nil))
+}
+
+func TestStatement(t *testing.T) {
+	defer prepare(t)()
+
+	pro := Plans["pro"]
+	assert.Equal(t, int64(1), pro.Meters["calls"].Unit)
+
+	statement := pro.statement(map[string]int64{"tokens": 1002001, "images": 3})
+	assert.Equal(t, "usd", statement.Currency)
+	assert.Equal(t, []string{"calls", "images", "tokens"}, []string{statement.Lines[0].Metric, statement.Lines[1].Metric, statement.Lines[2].Metric})
+	assert.Equal(t, Line{Metric: "calls", UnitAmount: 1}, statement.Lines[0])
+	assert.Equal(t, Line{Metric: "images", Quantity: 3}, statement.Lines[1])
+	assert.Equal(t, Line{Metric: "tokens", Quantity: 1002001, Included: 1000000, Overage: 2001, Units: 3, UnitAmount: 2, Amount: 6}, statement.Lines[2])
+	assert.Equal(t, int64(6), statement.Total)
+
+	// The usage within the included quantity is not billed
+	statement = pro.statement(map[string]int64{"tokens": 1000})
+	assert.Equal(t, int64(0), statement.Total)
+
+	// The period of the team without the subscription is the calendar month
+	start, end, err := Period("team-1", time.Date(2024, 2, 15, 8, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), end)
+
+	start, end, err = period("", "2024-01-01", "2024-02-01T00:00:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, 31*24*time.Hour, end.Sub(start))
+	_, _, err = period("", "2024-02-01", "2024-01-01")
+	assert.Error(t, err)
+	_, _, err = period("", "", "")
+	assert.Error(t, err)
+
+	assert.Equal(t, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), periodStart(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), "year"))
+	assert.Error(t, (&Plan{ID: "bad", Meters: map[string]Meter{"tokens": {Unit: -1}}}).validate())
+	assert.Error(t, Record("team-1", "tokens", 0))
+}
+
+func TestExport(t *testing.T) {
+	defer prepare(t)()
+
+	statement := Plans["pro"].statement(map[string]int64{"tokens": 1002001})
+	statement.Team = "team-1"
+	statement.Start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	statement.End = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	data, err := Export([]*Statement{statement}, "csv")
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, "team,plan,metric,period_start,period_end,quantity,included,overage,units,unit_amount,amount,currency", lines[0])
+	assert.Equal(t, "team-1,pro,tokens,2024-01-01T00:00:00Z,2024-02-01T00:00:00Z,1002001,1000000,2001,3,2,6,usd", lines[2])
+
+	data, err = Export([]*Statement{statement}, "json")
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"amount":6`)
+
+	_, err = Export([]*Statement{statement}, "xlsx")
+	assert.Error(t, err)
+
+	// The team without the subscription has no stripe customer to report
+	_, err = Report("team-1", statement.Start, statement.End)
+	assert.Error(t, err)
+}
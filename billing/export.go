@@ -0,0 +1,110 @@
+package billing
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Statements the statements of the teams with the usage in the period [start, end), sorted by the team
+func Statements(start time.Time, end time.Time) ([]*Statement, error) {
+	usage, err := usages("", start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := []string{}
+	for team := range usage {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+
+	statements := []*Statement{}
+	for _, team := range teams {
+		plan, _, err := PlanOf(team)
+		if err != nil {
+			return nil, err
+		}
+
+		statement := plan.statement(usage[team])
+		statement.Team, statement.Start, statement.End = team, start, end
+		statements = append(statements, statement)
+	}
+	return statements, nil
+}
+
+// Export the statements for the invoicing, csv or json, a row of the csv is a line of the statement
+func Export(statements []*Statement, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return jsoniter.Marshal(statements)
+
+	case "", "csv":
+		buf := &bytes.Buffer{}
+		w := csv.NewWriter(buf)
+		w.Write([]string{"team", "plan", "metric", "period_start", "period_end", "quantity", "included", "overage", "units", "unit_amount", "amount", "currency"})
+		for _, statement := range statements {
+			for _, line := range statement.Lines {
+				w.Write([]string{
+					statement.Team, statement.Plan, line.Metric,
+					statement.Start.UTC().Format(time.RFC3339), statement.End.UTC().Format(time.RFC3339),
+					strconv.FormatInt(line.Quantity, 10), strconv.FormatInt(line.Included, 10),
+					strconv.FormatInt(line.Overage, 10), strconv.FormatInt(line.Units, 10),
+					strconv.FormatInt(line.UnitAmount, 10), strconv.FormatInt(line.Amount, 10),
+					statement.Currency,
+				})
+			}
+		}
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	}
+	return nil, fmt.Errorf("the format %s is not supported, csv or json", format)
+}
+
+// Report the billed units of the team in the period to the stripe billing meters, the metrics with the event only.
+// The identifier of the meter event is yao:<team>:<metric>:<start>, reporting the period again is ignored by stripe.
+func Report(team string, start time.Time, end time.Time) (*Statement, error) {
+	sub, err := Get(team)
+	if err != nil {
+		return nil, err
+	}
+
+	if sub == nil || sub.Customer == "" {
+		return nil, fmt.Errorf("the team %s has no stripe customer", team)
+	}
+
+	statement, err := StatementOf(team, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := Select(statement.Plan)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range statement.Lines {
+		meter, has := plan.Meters[line.Metric]
+		if !has || meter.Event == "" || line.Units == 0 {
+			continue
+		}
+
+		err := call("POST", "/v1/billing/meter_events", url.Values{
+			"event_name":                  {meter.Event},
+			"identifier":                  {fmt.Sprintf("yao:%s:%s:%d", team, line.Metric, start.Unix())},
+			"timestamp":                   {strconv.FormatInt(end.Add(-time.Second).Unix(), 10)},
+			"payload[stripe_customer_id]": {sub.Customer},
+			"payload[value]":              {strconv.FormatInt(line.Units, 10)},
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("report the metric %s: %s", line.Metric, err.Error())
+		}
+	}
+	return statement, nil
+}
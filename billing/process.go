@@ -0,0 +1,162 @@
+package billing
+
+import (
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("billing", map[string]process.Handler{
+		"plans":        processPlans,
+		"plan":         processPlan,
+		"subscription": processSubscription,
+		"entitled":     processEntitled,
+		"checkout":     processCheckout,
+		"portal":       processPortal,
+		"sync":         processSync,
+		"record":       processRecord,
+		"meter":        processMeter,
+		"export":       processExport,
+		"report":       processReport,
+	})
+}
+
+// processPlans billing.Plans()
+func processPlans(process *process.Process) interface{} {
+	return List()
+}
+
+// processPlan billing.Plan(team), the plan of the team
+func processPlan(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	plan, _, err := PlanOf(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return plan
+}
+
+// processSubscription billing.Subscription(team), nil if the team has never subscribed
+func processSubscription(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	sub, err := Get(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return sub
+}
+
+// processEntitled billing.Entitled(team, feature), the plan of the team has the feature
+func processEntitled(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	entitled, err := Entitled(process.ArgsString(0), process.ArgsString(1))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return entitled
+}
+
+// processCheckout billing.Checkout(team, plan, {"price": "monthly", "success_url": "", "cancel_url": "", "email": ""})
+func processCheckout(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	option := process.ArgsMap(2)
+	session, err := Checkout(process.ArgsString(0), process.ArgsString(1), toString(option["price"]),
+		toString(option["success_url"]), toString(option["cancel_url"]), toString(option["email"]))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return session
+}
+
+// processPortal billing.Portal(team, return_url?)
+func processPortal(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	returnURL := ""
+	if process.NumOfArgs() > 1 {
+		returnURL = process.ArgsString(1)
+	}
+
+	session, err := Portal(process.ArgsString(0), returnURL)
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return session
+}
+
+// processSync billing.Sync(), sync the products and the prices of the plans to stripe
+func processSync(process *process.Process) interface{} {
+	created, err := Sync()
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return map[string]interface{}{"created": created}
+}
+
+// processRecord billing.Record(team, metric, quantity), record the usage of the metric, e.g. billing.Record("team-1", "tokens", 1200)
+func processRecord(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	err := Record(process.ArgsString(0), process.ArgsString(1), toInt64(process.Args[2]))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return nil
+}
+
+// processMeter billing.Meter(team, start?, end?), the statement of the metered usage, the current period by default
+func processMeter(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	team := process.ArgsString(0)
+	start, end, err := period(team, argString(process, 1), argString(process, 2))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+
+	statement, err := StatementOf(team, start, end)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return statement
+}
+
+// processExport billing.Export(start, end, format?), the statements of the teams for the invoicing, csv by default
+func processExport(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	start, end, err := period("", process.ArgsString(0), process.ArgsString(1))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+
+	statements, err := Statements(start, end)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	data, err := Export(statements, argString(process, 2))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return string(data)
+}
+
+// processReport billing.Report(team, start?, end?), report the billed units to the stripe billing meters
+func processReport(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	team := process.ArgsString(0)
+	start, end, err := period(team, argString(process, 1), argString(process, 2))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+
+	statement, err := Report(team, start, end)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return statement
+}
+
+func argString(process *process.Process, i int) string {
+	if process.NumOfArgs() > i {
+		return process.ArgsString(i)
+	}
+	return ""
+}
@@ -0,0 +1,229 @@
+package billing
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+const (
+	subscriptionTable = "yao_billing_subscription"
+	usageTable        = "yao_billing_usage"
+)
+
+// ready the table is created
+var ready = false
+
+// migrate create the table of the subscriptions
+func migrate() error {
+	if ready {
+		return nil
+	}
+
+	sch := capsule.Schema()
+	has, err := sch.HasTable(subscriptionTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		err = sch.CreateTable(subscriptionTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("team", 200).Unique().Index()
+			table.String("plan", 200).Index()
+			table.String("price", 200).Null()
+			table.String("status", 50).Index()
+			table.String("customer", 200).Null().Index()
+			table.String("subscription", 200).Null().Index()
+			table.TimestampTz("period_end").Null()
+			table.Boolean("cancel_at_period_end").SetDefault(false)
+			table.Integer("event_at").SetDefault(0) // The time of the last stripe event, the older events are ignored
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()")
+			table.TimestampTz("updated_at").Null()
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the billing table: %s", subscriptionTable)
+	}
+
+	has, err = sch.HasTable(usageTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		err = sch.CreateTable(usageTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("team", 200).Index()
+			table.String("metric", 100).Index()
+			table.Integer("quantity")
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()").Index()
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the billing table: %s", usageTable)
+	}
+
+	ready = true
+	return nil
+}
+
+func newQuery(table string) query.Query {
+	qb := capsule.Query()
+	qb.Table(table)
+	return qb
+}
+
+// Get the subscription of the team, nil if the team has never subscribed
+func Get(team string) (*Subscription, error) {
+	if !ready {
+		return nil, nil
+	}
+
+	row, err := newQuery(subscriptionTable).Where("team", team).First()
+	if err != nil {
+		return nil, err
+	}
+
+	if row == nil || len(row.ToMap()) == 0 {
+		return nil, nil
+	}
+
+	sub := Subscription{
+		Team:              toString(row.Get("team")),
+		Plan:              toString(row.Get("plan")),
+		Price:             toString(row.Get("price")),
+		Status:            toString(row.Get("status")),
+		Customer:          toString(row.Get("customer")),
+		Subscription:      toString(row.Get("subscription")),
+		CancelAtPeriodEnd: toBool(row.Get("cancel_at_period_end")),
+	}
+	if at, ok := row.Get("period_end").(time.Time); ok {
+		sub.PeriodEnd = &at
+	}
+	if at, ok := row.Get("updated_at").(time.Time); ok {
+		sub.UpdatedAt = &at
+	}
+	return &sub, nil
+}
+
+// save the subscription of the team, the events older than the saved one are ignored.
+// Returns false if the event is ignored.
+func save(sub Subscription, eventAt int64) (bool, error) {
+	if !ready {
+		return false, fmt.Errorf("the billing table is not created")
+	}
+
+	now := time.Now()
+	data := map[string]interface{}{
+		"plan":                 sub.Plan,
+		"price":                sub.Price,
+		"status":               sub.Status,
+		"subscription":         sub.Subscription,
+		"cancel_at_period_end": sub.CancelAtPeriodEnd,
+		"period_end":           nil,
+		"event_at":             eventAt,
+		"updated_at":           now,
+	}
+	if sub.PeriodEnd != nil {
+		data["period_end"] = *sub.PeriodEnd
+	}
+	if sub.Customer != "" {
+		data["customer"] = sub.Customer
+	}
+
+	row, err := newQuery(subscriptionTable).Where("team", sub.Team).First()
+	if err != nil {
+		return false, err
+	}
+
+	if row != nil && len(row.ToMap()) > 0 {
+		if toInt64(row.Get("event_at")) > eventAt {
+			return false, nil
+		}
+		_, err = newQuery(subscriptionTable).Where("team", sub.Team).Update(data)
+		return err == nil, err
+	}
+
+	data["team"] = sub.Team
+	data["created_at"] = now
+	return true, newQuery(subscriptionTable).Insert(data)
+}
+
+// link the stripe customer to the team, the customer of the checkout is reused by the next checkouts
+func link(team string, customer string) error {
+	if !ready {
+		return fmt.Errorf("the billing table is not created")
+	}
+
+	has, err := newQuery(subscriptionTable).Where("team", team).Exists()
+	if err != nil {
+		return err
+	}
+
+	if has {
+		_, err = newQuery(subscriptionTable).Where("team", team).Update(map[string]interface{}{"customer": customer, "updated_at": time.Now()})
+		return err
+	}
+
+	return newQuery(subscriptionTable).Insert(map[string]interface{}{
+		"team":       team,
+		"plan":       "",
+		"status":     "incomplete",
+		"customer":   customer,
+		"event_at":   0,
+		"created_at": time.Now(),
+	})
+}
+
+func toInt64(v interface{}) int64 {
+	switch value := v.(type) {
+	case int:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	case uint64:
+		return int64(value)
+	case float64:
+		return int64(value)
+	case []byte:
+		n, _ := strconv.ParseInt(string(value), 10, 64)
+		return n
+	case string:
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return n
+	}
+	return 0
+}
+
+func toString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func toBool(v interface{}) bool {
+	switch value := v.(type) {
+	case bool:
+		return value
+	case string:
+		enabled, _ := strconv.ParseBool(value)
+		return enabled
+	}
+	return toInt64(v) != 0
+}
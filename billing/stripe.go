@@ -0,0 +1,271 @@
+package billing
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/yao/config"
+)
+
+// API the base url of the stripe API
+var API = "https://api.stripe.com"
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Session the checkout or the billing portal session of stripe
+type Session struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// stripePrice the price of stripe
+type stripePrice struct {
+	ID         string `json:"id"`
+	LookupKey  string `json:"lookup_key"`
+	UnitAmount int64  `json:"unit_amount"`
+	Currency   string `json:"currency"`
+	Recurring  struct {
+		Interval string `json:"interval"`
+	} `json:"recurring"`
+}
+
+// Sync the products and the prices of the plans to stripe, returns the ids of the prices created.
+// The product of the plan is yao_<plan>, the price is looked up by yao:<plan>:<price>.
+// The price changed is created again and the previous one is archived, the prices of stripe are immutable.
+func Sync() ([]string, error) {
+	created := []string{}
+	for _, plan := range List() {
+		if len(plan.Prices) == 0 {
+			continue
+		}
+
+		product := "yao_" + plan.ID
+		form := url.Values{"name": {plan.Name}, "metadata[yao_plan]": {plan.ID}}
+		if plan.Description != "" {
+			form.Set("description", plan.Description)
+		}
+
+		err := call("POST", "/v1/products/"+url.PathEscape(product), form, nil)
+		if isMissing(err) {
+			form.Set("id", product)
+			err = call("POST", "/v1/products", form, nil)
+		}
+		if err != nil {
+			return created, fmt.Errorf("sync the plan %s: %s", plan.ID, err.Error())
+		}
+
+		for _, price := range plan.Prices {
+			key := lookupKey(plan.ID, price.ID)
+			existing, err := findPrice(key)
+			if err != nil {
+				return created, fmt.Errorf("sync the price %s: %s", key, err.Error())
+			}
+
+			if existing != nil && existing.UnitAmount == price.Amount && existing.Currency == price.Currency && existing.Recurring.Interval == price.Interval {
+				continue
+			}
+
+			res := stripePrice{}
+			err = call("POST", "/v1/prices", url.Values{
+				"product":             {product},
+				"unit_amount":         {strconv.FormatInt(price.Amount, 10)},
+				"currency":            {price.Currency},
+				"recurring[interval]": {price.Interval},
+				"lookup_key":          {key},
+				"transfer_lookup_key": {"true"},
+				"metadata[yao_plan]":  {plan.ID},
+				"metadata[yao_price]": {price.ID},
+				"nickname":            {plan.Name + " " + price.ID},
+			}, &res)
+			if err != nil {
+				return created, fmt.Errorf("sync the price %s: %s", key, err.Error())
+			}
+			created = append(created, res.ID)
+
+			if existing != nil {
+				err = call("POST", "/v1/prices/"+url.PathEscape(existing.ID), url.Values{"active": {"false"}}, nil)
+				if err != nil {
+					return created, fmt.Errorf("archive the price %s: %s", existing.ID, err.Error())
+				}
+			}
+		}
+	}
+	return created, nil
+}
+
+// Checkout create the checkout session of the subscription of the team.
+// The customer of the team is reused, the email is the email of the new customer.
+func Checkout(team string, planID string, priceID string, successURL string, cancelURL string, email string) (*Session, error) {
+	plan, err := Select(planID)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := plan.price(priceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if successURL == "" || cancelURL == "" {
+		return nil, fmt.Errorf("the success_url and the cancel_url are required")
+	}
+
+	existing, err := findPrice(lookupKey(plan.ID, price.ID))
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, fmt.Errorf("the price %s is not synced to stripe, run billing.sync first", lookupKey(plan.ID, price.ID))
+	}
+
+	form := url.Values{
+		"mode":                                  {"subscription"},
+		"line_items[0][price]":                  {existing.ID},
+		"line_items[0][quantity]":               {"1"},
+		"client_reference_id":                   {team},
+		"success_url":                           {successURL},
+		"cancel_url":                            {cancelURL},
+		"metadata[yao_team]":                    {team},
+		"subscription_data[metadata][yao_team]": {team},
+		"subscription_data[metadata][yao_plan]": {plan.ID},
+	}
+	if plan.Trial > 0 {
+		form.Set("subscription_data[trial_period_days]", strconv.Itoa(plan.Trial))
+	}
+
+	sub, err := Get(team)
+	if err != nil {
+		return nil, err
+	}
+	if sub != nil && sub.Customer != "" {
+		form.Set("customer", sub.Customer)
+	} else if email != "" {
+		form.Set("customer_email", email)
+	}
+
+	session := Session{}
+	err = call("POST", "/v1/checkout/sessions", form, &session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Portal create the billing portal session of the team, the customer manages the subscription by it
+func Portal(team string, returnURL string) (*Session, error) {
+	sub, err := Get(team)
+	if err != nil {
+		return nil, err
+	}
+
+	if sub == nil || sub.Customer == "" {
+		return nil, fmt.Errorf("the team %s has no stripe customer", team)
+	}
+
+	form := url.Values{"customer": {sub.Customer}}
+	if returnURL != "" {
+		form.Set("return_url", returnURL)
+	}
+
+	session := Session{}
+	err = call("POST", "/v1/billing_portal/sessions", form, &session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// findPrice the active price of the lookup key, nil if not found
+func findPrice(key string) (*stripePrice, error) {
+	res := struct {
+		Data []stripePrice `json:"data"`
+	}{}
+	err := call("GET", "/v1/prices", url.Values{"lookup_keys[]": {key}, "active": {"true"}}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Data) == 0 {
+		return nil, nil
+	}
+	return &res.Data[0], nil
+}
+
+func lookupKey(plan string, price string) string {
+	return "yao:" + plan + ":" + price
+}
+
+// stripeError the error of the stripe API
+type stripeError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *stripeError) Error() string {
+	return fmt.Sprintf("stripe responds %d: %s", err.Status, err.Message)
+}
+
+func isMissing(err error) bool {
+	e, ok := err.(*stripeError)
+	return ok && e.Status == 404
+}
+
+// call the stripe API, the form is the query of the GET requests
+func call(method string, path string, form url.Values, res interface{}) error {
+	key := config.Conf.Stripe.Key
+	if key == "" {
+		return fmt.Errorf("the stripe key is not configured, set YAO_STRIPE_KEY")
+	}
+
+	var body io.Reader
+	endpoint := API + path
+	if method == "GET" {
+		endpoint += "?" + form.Encode()
+	} else {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		e := struct {
+			Error stripeError `json:"error"`
+		}{}
+		jsoniter.Unmarshal(data, &e)
+		e.Error.Status = resp.StatusCode
+		if e.Error.Message == "" {
+			e.Error.Message = strings.TrimSpace(string(data))
+		}
+		return &e.Error
+	}
+
+	if res == nil {
+		return nil
+	}
+	return jsoniter.Unmarshal(data, res)
+}
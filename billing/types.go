@@ -0,0 +1,88 @@
+package billing
+
+import (
+	"sync"
+	"time"
+)
+
+// Plan the plan of the teams, the plans/*.yao files, the id is the name of the file.
+// The roles of the team members reference the plans, e.g. owner:free
+//
+//	{
+//	  "name": "Pro",
+//	  "prices": [{ "id": "monthly", "amount": 2000, "currency": "usd", "interval": "month" }],
+//	  "features": ["export", "/api/report/*"],
+//	  "limits": { "assistants": 20, "members": 10 },
+//	  "meters": { "tokens": { "included": 1000000, "unit": 1000, "price": 2, "event": "yao_tokens" } },
+//	  "trial": 14
+//	}
+type Plan struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Default     bool             `json:"default,omitempty"`  // The plan of the teams without the subscription, the plan "free" by default
+	Prices      []Price          `json:"prices,omitempty"`   // The plans without the prices are not synced to stripe
+	Features    []string         `json:"features,omitempty"` // The features of the plan, the ones starting with / are the paths of the APIs guarded by billing-plan
+	Limits      map[string]int   `json:"limits,omitempty"`
+	Meters      map[string]Meter `json:"meters,omitempty"` // The metered usage of the plan, e.g. tokens
+	Trial       int              `json:"trial,omitempty"`  // The days of the trial of the new subscriptions
+}
+
+// Meter the metered usage of the plan, the usage beyond the included quantity of the period is billed by the units
+type Meter struct {
+	Included int64  `json:"included,omitempty"` // The quantity included in the plan of a period
+	Unit     int64  `json:"unit,omitempty"`     // The quantity of a billed unit, e.g. 1000 tokens, 1 by default
+	Price    int64  `json:"price,omitempty"`    // The amount of a billed unit, the smallest unit of the currency of the plan
+	Event    string `json:"event,omitempty"`    // The event name of the stripe billing meter, the billed units are reported if it is set
+}
+
+// Statement the metered usage of the team in the period
+type Statement struct {
+	Team     string    `json:"team"`
+	Plan     string    `json:"plan"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Currency string    `json:"currency"`
+	Lines    []Line    `json:"lines"`
+	Total    int64     `json:"total"` // The amount of the overage of the lines
+}
+
+// Line the line item of the invoice of a metric
+type Line struct {
+	Metric     string `json:"metric"`
+	Quantity   int64  `json:"quantity"`
+	Included   int64  `json:"included"`
+	Overage    int64  `json:"overage"`
+	Units      int64  `json:"units"` // The billed units of the overage, rounded up
+	UnitAmount int64  `json:"unit_amount"`
+	Amount     int64  `json:"amount"`
+}
+
+// Price the recurring price of the plan, the lookup key of the stripe price is yao:<plan>:<price>
+type Price struct {
+	ID       string `json:"id"`
+	Amount   int64  `json:"amount"`             // The smallest unit of the currency, e.g. 2000 is $20.00
+	Currency string `json:"currency,omitempty"` // usd by default
+	Interval string `json:"interval,omitempty"` // day, week, month or year, month by default
+}
+
+// Subscription the subscription state of the team, updated by the stripe webhook
+type Subscription struct {
+	Team              string     `json:"team"`
+	Plan              string     `json:"plan"`
+	Price             string     `json:"price,omitempty"`
+	Status            string     `json:"status"` // The status of stripe, e.g. trialing, active, past_due, canceled
+	Customer          string     `json:"customer,omitempty"`
+	Subscription      string     `json:"subscription,omitempty"`
+	PeriodEnd         *time.Time `json:"period_end,omitempty"`
+	CancelAtPeriodEnd bool       `json:"cancel_at_period_end"`
+	UpdatedAt         *time.Time `json:"updated_at,omitempty"`
+}
+
+// Plans the loaded plans
+var Plans = map[string]*Plan{}
+
+// TeamField the session field of the team id
+var TeamField = "team_id"
+
+var lock sync.RWMutex
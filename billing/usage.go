@@ -0,0 +1,216 @@
+package billing
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yaoapp/xun/dbal"
+	"github.com/yaoapp/yao/event"
+)
+
+// Record the usage of the metric of the team, e.g. Record("team-1", "tokens", 1200)
+func Record(team string, metric string, quantity int64) error {
+	if team == "" || metric == "" {
+		return fmt.Errorf("the team and the metric are required")
+	}
+
+	if quantity <= 0 {
+		return fmt.Errorf("the quantity of the metric %s is invalid", metric)
+	}
+
+	if !ready {
+		return fmt.Errorf("the billing is not ready, add the plans")
+	}
+
+	err := newQuery(usageTable).Insert(map[string]interface{}{
+		"team":       team,
+		"metric":     metric,
+		"quantity":   quantity,
+		"created_at": time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	event.Publish(event.UsageRecorded, map[string]interface{}{"team": team, "metric": metric, "quantity": quantity})
+	return nil
+}
+
+// Usage the quantities of the metrics of the team in the period [start, end)
+func Usage(team string, start time.Time, end time.Time) (map[string]int64, error) {
+	usage, err := usages(team, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return usage[team], nil
+}
+
+// usages the quantities of the metrics of the teams in the period, all of the teams if the team is empty
+func usages(team string, start time.Time, end time.Time) (map[string]map[string]int64, error) {
+	res := map[string]map[string]int64{}
+	if !ready {
+		if team != "" {
+			res[team] = map[string]int64{}
+		}
+		return res, nil
+	}
+
+	qb := newQuery(usageTable).
+		Select("team", "metric", dbal.Raw("SUM(quantity) AS quantity")).
+		Where("created_at", ">=", start).
+		Where("created_at", "<", end)
+	if team != "" {
+		qb.Where("team", team)
+	}
+
+	rows, err := qb.GroupBy("team", "metric").Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if team != "" {
+		res[team] = map[string]int64{}
+	}
+	for _, row := range rows {
+		id := toString(row["team"])
+		if res[id] == nil {
+			res[id] = map[string]int64{}
+		}
+		res[id][toString(row["metric"])] = toInt64(row["quantity"])
+	}
+	return res, nil
+}
+
+// Period the current billing period of the team, the period of the subscription or the calendar month in UTC
+func Period(team string, now time.Time) (time.Time, time.Time, error) {
+	plan, sub, err := PlanOf(team)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if sub != nil && activeStatuses[sub.Status] && sub.PeriodEnd != nil && sub.PeriodEnd.After(now) {
+		interval := "month"
+		if price, err := plan.price(sub.Price); err == nil {
+			interval = price.Interval
+		}
+
+		end := *sub.PeriodEnd
+		start := periodStart(end, interval)
+		for start.After(now) {
+			end = start
+			start = periodStart(end, interval)
+		}
+		return start, end, nil
+	}
+
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// StatementOf the statement of the metered usage of the team in the period [start, end)
+func StatementOf(team string, start time.Time, end time.Time) (*Statement, error) {
+	plan, _, err := PlanOf(team)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := Usage(team, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := plan.statement(usage)
+	statement.Team, statement.Start, statement.End = team, start, end
+	return statement, nil
+}
+
+// statement the lines of the usage, the overage is billed by the units rounded up.
+// The metrics not metered by the plan are listed without the amount.
+func (plan *Plan) statement(usage map[string]int64) *Statement {
+	statement := &Statement{Plan: plan.ID, Currency: "usd", Lines: []Line{}}
+	if len(plan.Prices) > 0 {
+		statement.Currency = plan.Prices[0].Currency
+	}
+
+	metrics := []string{}
+	for metric := range usage {
+		metrics = append(metrics, metric)
+	}
+	for metric := range plan.Meters {
+		if _, has := usage[metric]; !has {
+			metrics = append(metrics, metric)
+		}
+	}
+	sort.Strings(metrics)
+
+	for _, metric := range metrics {
+		line := Line{Metric: metric, Quantity: usage[metric]}
+		if meter, has := plan.Meters[metric]; has {
+			unit := meter.Unit
+			if unit <= 0 {
+				unit = 1
+			}
+
+			line.Included = meter.Included
+			if line.Quantity > meter.Included {
+				line.Overage = line.Quantity - meter.Included
+			}
+			line.Units = (line.Overage + unit - 1) / unit
+			line.UnitAmount = meter.Price
+			line.Amount = line.Units * meter.Price
+		}
+		statement.Lines = append(statement.Lines, line)
+		statement.Total += line.Amount
+	}
+	return statement
+}
+
+// periodStart the start of the period ending at the time
+func periodStart(end time.Time, interval string) time.Time {
+	switch interval {
+	case "day":
+		return end.AddDate(0, 0, -1)
+	case "week":
+		return end.AddDate(0, 0, -7)
+	case "year":
+		return end.AddDate(-1, 0, 0)
+	}
+	return end.AddDate(0, -1, 0)
+}
+
+// period the period of the arguments, the current period of the team if both are empty
+func period(team string, start string, end string) (time.Time, time.Time, error) {
+	if start == "" && end == "" && team != "" {
+		return Period(team, time.Now())
+	}
+
+	from, err := parseTime(start)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	to, err := parseTime(end)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the start %s is not before the end %s", start, end)
+	}
+	return from, to, nil
+}
+
+// parseTime the time of RFC3339 or the date in UTC, e.g. 2024-01-01
+func parseTime(value string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+
+	at, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("the time %s is invalid, RFC3339 or YYYY-MM-DD", value)
+	}
+	return at, nil
+}
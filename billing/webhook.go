@@ -0,0 +1,161 @@
+package billing
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/event"
+)
+
+// stripeEvent the event of the stripe webhook
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object jsoniter.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// stripeSubscription the subscription object of the events
+type stripeSubscription struct {
+	ID                string            `json:"id"`
+	Customer          string            `json:"customer"`
+	Status            string            `json:"status"`
+	CancelAtPeriodEnd bool              `json:"cancel_at_period_end"`
+	CurrentPeriodEnd  int64             `json:"current_period_end"`
+	Metadata          map[string]string `json:"metadata"`
+	Items             struct {
+		Data []struct {
+			CurrentPeriodEnd int64       `json:"current_period_end"`
+			Price            stripePrice `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// stripeCheckout the checkout session object of the events
+type stripeCheckout struct {
+	ClientReferenceID string            `json:"client_reference_id"`
+	Customer          string            `json:"customer"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+// Handle the event of the stripe webhook, the signature is verified by the caller.
+// The subscription state of the team is saved and published as billing.subscription.updated or billing.subscription.deleted.
+func Handle(raw []byte) error {
+	evt := stripeEvent{}
+	if err := jsoniter.Unmarshal(raw, &evt); err != nil {
+		return fmt.Errorf("the event is invalid: %s", err.Error())
+	}
+
+	switch evt.Type {
+	case "checkout.session.completed":
+		checkout := stripeCheckout{}
+		if err := jsoniter.Unmarshal(evt.Data.Object, &checkout); err != nil {
+			return fmt.Errorf("the checkout session is invalid: %s", err.Error())
+		}
+
+		team := checkout.ClientReferenceID
+		if team == "" {
+			team = checkout.Metadata["yao_team"]
+		}
+		if team == "" || checkout.Customer == "" {
+			log.Warn("[Billing] the checkout of the event %s has no team or customer", evt.ID)
+			return nil
+		}
+		return link(team, checkout.Customer)
+
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		object := stripeSubscription{}
+		if err := jsoniter.Unmarshal(evt.Data.Object, &object); err != nil {
+			return fmt.Errorf("the subscription is invalid: %s", err.Error())
+		}
+
+		sub, err := object.subscription()
+		if err != nil {
+			return err
+		}
+		if sub == nil {
+			log.Warn("[Billing] the subscription %s of the event %s has no team", object.ID, evt.ID)
+			return nil
+		}
+
+		if evt.Type == "customer.subscription.deleted" {
+			sub.Status = "canceled"
+		}
+
+		saved, err := save(*sub, evt.Created)
+		if err != nil || !saved {
+			return err
+		}
+
+		name := "billing.subscription.updated"
+		if evt.Type == "customer.subscription.deleted" {
+			name = "billing.subscription.deleted"
+		}
+		event.Publish(name, sub)
+	}
+	return nil
+}
+
+// subscription the state of the team, the team is the metadata of the subscription or the team of the customer
+func (object *stripeSubscription) subscription() (*Subscription, error) {
+	team := object.Metadata["yao_team"]
+	if team == "" {
+		var err error
+		team, err = teamOf(object.Customer)
+		if err != nil || team == "" {
+			return nil, err
+		}
+	}
+
+	sub := &Subscription{
+		Team:              team,
+		Plan:              object.Metadata["yao_plan"],
+		Status:            object.Status,
+		Customer:          object.Customer,
+		Subscription:      object.ID,
+		CancelAtPeriodEnd: object.CancelAtPeriodEnd,
+	}
+
+	periodEnd := object.CurrentPeriodEnd
+	if len(object.Items.Data) > 0 {
+		item := object.Items.Data[0]
+
+		// The plan is changed by the price, e.g. the upgrade in the billing portal
+		if parts := strings.SplitN(item.Price.LookupKey, ":", 3); len(parts) == 3 && parts[0] == "yao" {
+			sub.Plan, sub.Price = parts[1], parts[2]
+		}
+
+		// The period of the items of the API versions since 2025-03-31
+		if periodEnd == 0 {
+			periodEnd = item.CurrentPeriodEnd
+		}
+	}
+
+	if periodEnd > 0 {
+		at := time.Unix(periodEnd, 0)
+		sub.PeriodEnd = &at
+	}
+	return sub, nil
+}
+
+// teamOf the team of the stripe customer
+func teamOf(customer string) (string, error) {
+	if !ready || customer == "" {
+		return "", nil
+	}
+
+	row, err := newQuery(subscriptionTable).Where("customer", customer).First()
+	if err != nil {
+		return "", err
+	}
+
+	if row == nil || len(row.ToMap()) == 0 {
+		return "", nil
+	}
+	return toString(row.Get("team")), nil
+}
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+)
+
+// timeout the timeout of the operations of the backend
+const timeout = 3 * time.Second
+
+// Backend the storage of the cache, the values are the JSON. The value never expires if the ttl is 0
+type Backend interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+}
+
+// Cache the cache of the backend, the keys are prefixed
+type Cache struct {
+	backend Backend
+	prefix  string
+}
+
+// entry the cached value and the generations of the tags when the value is cached
+type entry struct {
+	Value jsoniter.RawMessage `json:"v"`
+	Tags  map[string]int64    `json:"t,omitempty"`
+}
+
+var (
+	lock    sync.RWMutex
+	current = New(newMemory(), "yao:cache:")
+)
+
+// New create the cache of the backend
+func New(backend Backend, prefix string) *Cache {
+	return &Cache{backend: backend, prefix: prefix}
+}
+
+// Load the cache of the configuration, the memory cache of the process by default
+func Load(cfg config.Config) error {
+	var backend Backend
+	switch cfg.Cache.Driver {
+	case "", "memory":
+		backend = newMemory()
+	case "redis":
+		b, err := newRedis(cfg.Cache.Redis)
+		if err != nil {
+			return err
+		}
+		backend = b
+	default:
+		return fmt.Errorf("the cache driver %s is not supported, memory or redis", cfg.Cache.Driver)
+	}
+
+	prefix := cfg.Cache.Prefix
+	if prefix == "" {
+		prefix = "yao:cache:"
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	current = New(backend, prefix)
+	return nil
+}
+
+// Default the cache of the application
+func Default() *Cache {
+	lock.RLock()
+	defer lock.RUnlock()
+	return current
+}
+
+// Get the value of the default cache, see Cache.Get
+func Get(key string, v interface{}) (bool, error) {
+	return Default().Get(key, v)
+}
+
+// Set the value of the default cache, see Cache.Set
+func Set(key string, value interface{}, ttl time.Duration, tags ...string) error {
+	return Default().Set(key, value, ttl, tags...)
+}
+
+// Del the keys of the default cache
+func Del(keys ...string) error {
+	return Default().Del(keys...)
+}
+
+// Remember the value of the default cache, see Cache.Remember
+func Remember(key string, v interface{}, ttl time.Duration, fn func() (interface{}, error), tags ...string) error {
+	return Default().Remember(key, v, ttl, fn, tags...)
+}
+
+// Flush the values of the tags of the default cache, see Cache.Flush
+func Flush(tags ...string) error {
+	return Default().Flush(tags...)
+}
+
+// Get decode the value of the key to v, returns false if the key is not found, expired or the tags are flushed
+func (c *Cache) Get(key string, v interface{}) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	raw, has, err := c.backend.Get(ctx, c.prefix+key)
+	if err != nil || !has {
+		return false, err
+	}
+
+	e := entry{}
+	if err := jsoniter.UnmarshalFromString(raw, &e); err != nil {
+		return false, fmt.Errorf("the value of %s is invalid: %s", key, err.Error())
+	}
+
+	for tag, gen := range e.Tags {
+		current, err := c.generation(ctx, tag)
+		if err != nil {
+			return false, err
+		}
+		if current != gen {
+			return false, nil
+		}
+	}
+
+	if err := jsoniter.Unmarshal(e.Value, v); err != nil {
+		return false, fmt.Errorf("the value of %s is invalid: %s", key, err.Error())
+	}
+	return true, nil
+}
+
+// Set the value of the key, the value never expires if the ttl is 0. The value is invalidated when one of the tags is flushed
+func (c *Cache) Set(key string, value interface{}, ttl time.Duration, tags ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	raw, err := jsoniter.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("the value of %s is not cacheable: %s", key, err.Error())
+	}
+
+	e := entry{Value: raw}
+	if len(tags) > 0 {
+		e.Tags = map[string]int64{}
+		for _, tag := range tags {
+			gen, err := c.generation(ctx, tag)
+			if err != nil {
+				return err
+			}
+			e.Tags[tag] = gen
+		}
+	}
+
+	data, err := jsoniter.MarshalToString(e)
+	if err != nil {
+		return err
+	}
+	return c.backend.Set(ctx, c.prefix+key, data, ttl)
+}
+
+// Del remove the keys
+func (c *Cache) Del(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, c.prefix+key)
+	}
+	return c.backend.Del(ctx, names...)
+}
+
+// Remember decode the cached value to v, or cache the value returned by fn if it is not cached.
+// The value of fn is returned even if it is not cached, e.g. the backend is unreachable
+func (c *Cache) Remember(key string, v interface{}, ttl time.Duration, fn func() (interface{}, error), tags ...string) error {
+	has, err := c.Get(key, v)
+	if err != nil {
+		log.Error("[Cache] get %s: %s", key, err.Error())
+	}
+	if has {
+		return nil
+	}
+
+	value, err := fn()
+	if err != nil {
+		return err
+	}
+
+	raw, err := jsoniter.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("the value of %s is not cacheable: %s", key, err.Error())
+	}
+
+	if err := c.Set(key, value, ttl, tags...); err != nil {
+		log.Error("[Cache] set %s: %s", key, err.Error())
+	}
+	return jsoniter.Unmarshal(raw, v)
+}
+
+// Flush invalidate the values of the tags, the values are not removed but never hit again
+func (c *Cache) Flush(tags ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	gen := strconv.FormatInt(time.Now().UnixNano(), 10)
+	for _, tag := range tags {
+		if err := c.backend.Set(ctx, c.tagKey(tag), gen, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generation the time the tag is flushed, 0 if the tag is never flushed
+func (c *Cache) generation(ctx context.Context, tag string) (int64, error) {
+	raw, has, err := c.backend.Get(ctx, c.tagKey(tag))
+	if err != nil || !has {
+		return 0, err
+	}
+	gen, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, nil
+	}
+	return gen, nil
+}
+
+func (c *Cache) tagKey(tag string) string {
+	return c.prefix + "tag:" + tag
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	c := New(newMemory(), "test:")
+
+	var value map[string]interface{}
+	has, err := c.Get("user:1", &value)
+	assert.NoError(t, err)
+	assert.False(t, has)
+
+	err = c.Set("user:1", map[string]interface{}{"name": "Max"}, time.Minute, "users")
+	assert.NoError(t, err)
+	has, err = c.Get("user:1", &value)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.Equal(t, "Max", value["name"])
+
+	// The values of the tags flushed are never hit again
+	assert.NoError(t, c.Set("team:1", "Sales", 0, "teams"))
+	assert.NoError(t, c.Flush("users"))
+	has, _ = c.Get("user:1", &value)
+	assert.False(t, has)
+
+	var team string
+	has, _ = c.Get("team:1", &team)
+	assert.True(t, has)
+	assert.Equal(t, "Sales", team)
+
+	assert.NoError(t, c.Del("team:1"))
+	has, _ = c.Get("team:1", &team)
+	assert.False(t, has)
+
+	// The value is not cacheable
+	assert.Error(t, c.Set("func", func() {}, time.Minute))
+}
+
+func TestRemember(t *testing.T) {
+	c := New(newMemory(), "test:")
+
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return map[string]interface{}{"total": calls}, nil
+	}
+
+	var value map[string]interface{}
+	assert.NoError(t, c.Remember("orders", &value, time.Minute, fn, "orders"))
+	assert.Equal(t, float64(1), value["total"])
+	assert.NoError(t, c.Remember("orders", &value, time.Minute, fn, "orders"))
+	assert.Equal(t, float64(1), value["total"])
+	assert.Equal(t, 1, calls)
+
+	c.Flush("orders")
+	assert.NoError(t, c.Remember("orders", &value, time.Minute, fn, "orders"))
+	assert.Equal(t, float64(2), value["total"])
+
+	// The errors are not cached
+	err := c.Remember("failed", &value, time.Minute, func() (interface{}, error) { return nil, fmt.Errorf("failed") })
+	assert.Error(t, err)
+	has, _ := c.Get("failed", &value)
+	assert.False(t, has)
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	m := newMemory()
+	m.Set(ctx, "expired", "v", -time.Second)
+	m.Set(ctx, "value", "v", time.Minute)
+	m.Set(ctx, "forever", "v", 0)
+
+	_, has, _ := m.Get(ctx, "expired")
+	assert.False(t, has)
+
+	value, has, _ := m.Get(ctx, "value")
+	assert.True(t, has)
+	assert.Equal(t, "v", value)
+
+	_, has, _ = m.Get(ctx, "forever")
+	assert.True(t, has)
+
+	m.Del(ctx, "value", "forever")
+	_, has, _ = m.Get(ctx, "value")
+	assert.False(t, has)
+	_, has, _ = m.Get(ctx, "forever")
+	assert.False(t, has)
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memory the cache of the process, the expired values are removed once a minute
+type memory struct {
+	sync.Mutex
+	values map[string]memoryValue
+	swept  time.Time
+}
+
+type memoryValue struct {
+	value   string
+	expires time.Time // Never expires if zero
+}
+
+func newMemory() *memory {
+	return &memory{values: map[string]memoryValue{}}
+}
+
+func (m *memory) Get(ctx context.Context, key string) (string, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	v, has := m.values[key]
+	if !has {
+		return "", false, nil
+	}
+	if v.expired(time.Now()) {
+		delete(m.values, key)
+		return "", false, nil
+	}
+	return v.value, true, nil
+}
+
+func (m *memory) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	m.Lock()
+	defer m.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.swept) > time.Minute {
+		for k, v := range m.values {
+			if v.expired(now) {
+				delete(m.values, k)
+			}
+		}
+		m.swept = now
+	}
+
+	v := memoryValue{value: value}
+	if ttl != 0 {
+		v.expires = now.Add(ttl)
+	}
+	m.values[key] = v
+	return nil
+}
+
+func (m *memory) Del(ctx context.Context, keys ...string) error {
+	m.Lock()
+	defer m.Unlock()
+	for _, key := range keys {
+		delete(m.values, key)
+	}
+	return nil
+}
+
+func (v memoryValue) expired(now time.Time) bool {
+	return !v.expires.IsZero() && now.After(v.expires)
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("cache", map[string]process.Handler{
+		"get":      processGet,
+		"set":      processSet,
+		"del":      processDel,
+		"remember": processRemember,
+		"flush":    processFlush,
+	})
+}
+
+// options the ttl in seconds and the tags of the value, e.g. 60 or {"ttl": 60, "tags": ["orders"]}
+type options struct {
+	ttl  time.Duration
+	tags []string
+}
+
+// processGet cache.Get("<key>"), null if the key is not found
+//
+//	const user = Process("cache.Get", "user:1")
+func processGet(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	var value interface{}
+	_, err := Get(process.ArgsString(0), &value)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return value
+}
+
+// processSet cache.Set("<key>", <value>, <ttl or options>?), the value never expires if the ttl is not set
+//
+//	Process("cache.Set", "user:1", user, { ttl: 60, tags: ["users"] })
+func processSet(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	opts := optionsOf(process, 2)
+	if err := Set(process.ArgsString(0), process.Args[1], opts.ttl, opts.tags...); err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return nil
+}
+
+// processDel cache.Del("<key>", ...)
+func processDel(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	keys := []string{}
+	for i := range process.Args {
+		keys = append(keys, process.ArgsString(i))
+	}
+	if err := Del(keys...); err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return nil
+}
+
+// processRemember cache.Remember("<key>", <ttl or options>, "<process>", ...args), the result of the process is cached if the key is not found
+//
+//	const orders = Process("cache.Remember", "orders:today", { ttl: 300, tags: ["orders"] }, "models.order.Get", { limit: 20 })
+func processRemember(p *process.Process) interface{} {
+	p.ValidateArgNums(3)
+	opts := optionsOf(p, 1)
+	name := p.ArgsString(2)
+
+	var value interface{}
+	err := Remember(p.ArgsString(0), &value, opts.ttl, func() (interface{}, error) {
+		handler, err := process.Of(name, p.Args[3:]...)
+		if err != nil {
+			return nil, err
+		}
+		return handler.WithSID(p.Sid).WithGlobal(p.Global).Exec()
+	}, opts.tags...)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return value
+}
+
+// processFlush cache.Flush("<tag>", ...), the values of the tags are invalidated
+//
+//	Process("cache.Flush", "orders")
+func processFlush(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	tags := []string{}
+	for i := range process.Args {
+		tags = append(tags, process.ArgsString(i))
+	}
+	if err := Flush(tags...); err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return nil
+}
+
+// optionsOf the options of the argument, the ttl in seconds or the options
+func optionsOf(p *process.Process, i int) options {
+	opts := options{}
+	if p.NumOfArgs() <= i || p.Args[i] == nil {
+		return opts
+	}
+
+	value := p.Args[i]
+	if m, ok := value.(map[string]interface{}); ok {
+		value = m["ttl"]
+		if tags, has := m["tags"]; has {
+			opts.tags = cast.ToStringSlice(tags)
+		}
+	}
+
+	seconds, err := cast.ToFloat64E(value)
+	if err != nil && value != nil {
+		exception.New("the ttl %v is invalid", 400, value).Throw()
+	}
+	opts.ttl = time.Duration(seconds * float64(time.Second))
+	return opts
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yaoapp/yao/config"
+)
+
+// rdb the cache of the redis, shared by the instances
+type rdb struct {
+	client *redis.Client
+}
+
+// newRedis the redis of the URL, the session redis if the URL is empty
+func newRedis(url string) (*rdb, error) {
+	if url == "" {
+		session := config.Conf.Session
+		url = fmt.Sprintf("redis://%s:%s@%s:%s/%s", session.Username, session.Password, session.Host, session.Port, session.DB)
+	}
+
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("the redis of the cache is invalid: %s", err.Error())
+	}
+	return &rdb{client: redis.NewClient(options)}, nil
+}
+
+func (r *rdb) Get(ctx context.Context, key string) (string, bool, error) {
+	value, err := r.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (r *rdb) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r *rdb) Del(ctx context.Context, keys ...string) error {
+	return r.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,164 @@
+package clickhouse
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options the options of the ClickHouse connector, the values start with $ENV. are read from the environment variables
+//
+//	{
+//	  "type": "clickhouse",
+//	  "options": { "host": "$ENV.CLICKHOUSE_HOST", "port": 8123, "db": "analytics", "user": "default", "password": "$ENV.CLICKHOUSE_PASSWORD" }
+//	}
+type Options struct {
+	Host     string      `json:"host,omitempty"`     // The host of the HTTP interface, 127.0.0.1 by default
+	Port     interface{} `json:"port,omitempty"`     // The port of the HTTP interface, 8123 by default
+	DB       string      `json:"db,omitempty"`       // The database, default by default
+	User     string      `json:"user,omitempty"`     // The user, default by default
+	Password string      `json:"password,omitempty"` // The password
+	Secure   bool        `json:"secure,omitempty"`   // The HTTP interface is served over TLS
+	Timeout  int         `json:"timeout,omitempty"`  // The timeout of the queries in seconds, 30 by default
+}
+
+// Connection the ClickHouse connection, the queries are sent to the HTTP interface
+type Connection struct {
+	ID      string
+	Options Options
+	url     string
+	client  *http.Client
+}
+
+// connections the ClickHouse connectors, tables the tables of the ClickHouse models
+var connections = map[string]*Connection{}
+var tables = map[string]*Table{}
+var lock sync.RWMutex
+
+var nameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Connect register the ClickHouse connector
+func Connect(id string, options Options) (*Connection, error) {
+	options.Host = env(options.Host)
+	options.DB = env(options.DB)
+	options.User = env(options.User)
+	options.Password = env(options.Password)
+
+	if options.Host == "" {
+		options.Host = "127.0.0.1"
+	}
+	if options.DB == "" {
+		options.DB = "default"
+	}
+	if options.User == "" {
+		options.User = "default"
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 30
+	}
+
+	port := env(fmt.Sprintf("%v", options.Port))
+	if options.Port == nil || port == "" {
+		port = "8123"
+	}
+
+	if !nameRe.MatchString(options.DB) {
+		return nil, fmt.Errorf("[%s] the database %s is invalid", id, options.DB)
+	}
+
+	scheme := "http"
+	if options.Secure {
+		scheme = "https"
+	}
+
+	conn := &Connection{
+		ID:      id,
+		Options: options,
+		url:     fmt.Sprintf("%s://%s:%s/", scheme, options.Host, port),
+		client:  &http.Client{Timeout: time.Duration(options.Timeout) * time.Second},
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	connections[id] = conn
+	return conn, nil
+}
+
+// Select the ClickHouse connector
+func Select(id string) (*Connection, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	conn, has := connections[id]
+	if !has {
+		return nil, fmt.Errorf("the ClickHouse connector %s does not exist", id)
+	}
+	return conn, nil
+}
+
+// Is the connector is a ClickHouse connector
+func Is(id string) bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	_, has := connections[id]
+	return has
+}
+
+// Register the table of the ClickHouse model
+func Register(table *Table) error {
+	err := table.validate()
+	if err != nil {
+		return fmt.Errorf("[%s] %s", table.ID, err.Error())
+	}
+
+	if !Is(table.Connector) {
+		return fmt.Errorf("[%s] the ClickHouse connector %s does not exist", table.ID, table.Connector)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	tables[table.ID] = table
+	return nil
+}
+
+// Model the table of the ClickHouse model
+func Model(id string) (*Table, bool) {
+	lock.RLock()
+	defer lock.RUnlock()
+	table, has := tables[id]
+	return table, has
+}
+
+// Models the tables of the ClickHouse models
+func Models() map[string]*Table {
+	lock.RLock()
+	defer lock.RUnlock()
+	res := map[string]*Table{}
+	for id, table := range tables {
+		res[id] = table
+	}
+	return res
+}
+
+// Unload remove the connectors and the tables
+func Unload() {
+	lock.Lock()
+	defer lock.Unlock()
+	connections = map[string]*Connection{}
+	tables = map[string]*Table{}
+}
+
+func env(value string) string {
+	if strings.HasPrefix(value, "$ENV.") {
+		return os.Getenv(strings.TrimPrefix(value, "$ENV."))
+	}
+	return value
+}
+
+// quote the identifier
+func quote(name string) string {
+	return "`" + name + "`"
+}
@@ -0,0 +1,218 @@
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnect(t *testing.T) {
+	defer Unload()
+	t.Setenv("TEST_CLICKHOUSE_PASSWORD", "secret")
+
+	conn, err := Connect("analytics", Options{Host: "ch.local", Port: float64(8443), DB: "events", Secure: true, Password: "$ENV.TEST_CLICKHOUSE_PASSWORD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "https://ch.local:8443/", conn.url)
+	assert.Equal(t, "secret", conn.Options.Password)
+	assert.Equal(t, "default", conn.Options.User)
+	assert.True(t, Is("analytics"))
+
+	conn, err = Connect("local", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "http://127.0.0.1:8123/", conn.url)
+	assert.Equal(t, "default", conn.Options.DB)
+
+	_, err = Connect("invalid", Options{DB: "events; DROP"})
+	assert.Error(t, err)
+}
+
+func TestBind(t *testing.T) {
+	sql, err := bind("SELECT * FROM t WHERE a = ? AND b IN ? AND c = '?' AND d = ?", []interface{}{
+		"it's", []interface{}{1, "x"}, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `SELECT * FROM t WHERE a = 'it\'s' AND b IN (1, 'x') AND c = '?' AND d = parseDateTime64BestEffort('2024-01-02T03:04:05.000Z', 3)`, sql)
+
+	_, err = bind("SELECT ?", []interface{}{1, 2})
+	assert.Error(t, err)
+	assert.Equal(t, `'a\\b'`, literal(`a\b`))
+	assert.Equal(t, "NULL", literal(nil))
+}
+
+func TestTable(t *testing.T) {
+	defer Unload()
+	Connect("analytics", Options{})
+
+	table := testTable()
+	err := Register(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t,
+		"CREATE TABLE IF NOT EXISTS `events` (`team` LowCardinality(String), `uid` String, `name` String, "+
+			"`tokens` Int64, `cost` Decimal(10, 2), `extra` Nullable(String), `created_at` DateTime64(3)) "+
+			"ENGINE = MergeTree PARTITION BY toYYYYMM(created_at) ORDER BY (`team`, `created_at`)",
+		table.createSQL(),
+	)
+
+	err = Register(&Table{ID: "pet", Name: "pets", Connector: "analytics", Columns: []Column{{Name: "id", Type: "ID"}}})
+	assert.Contains(t, err.Error(), "auto increment")
+
+	err = Register(&Table{ID: "pet", Name: "pets", Connector: "analytics", Columns: []Column{{Name: "a", Type: "string", Nullable: true}}, OrderBy: []string{"a"}})
+	assert.Contains(t, err.Error(), "nullable")
+
+	err = Register(&Table{ID: "pet", Name: "pets", Connector: "missing", Columns: []Column{{Name: "a", Type: "string"}}})
+	assert.Contains(t, err.Error(), "does not exist")
+}
+
+func TestAggregate(t *testing.T) {
+	defer Unload()
+	Connect("analytics", Options{})
+	table := testTable()
+	Register(table)
+
+	sql, args, err := table.aggregateSQL(Aggregate{
+		Dimensions: map[string]string{"date": "day(created_at)", "team": "team"},
+		Metrics:    map[string]string{"events": "count()", "users": "uniq(uid)", "tokens": "sum(tokens)"},
+		Wheres: []Where{
+			{Column: "created_at", OP: "ge", Value: "2024-01-01"},
+			{Wheres: []Where{{Column: "team", Value: "1"}, {Column: "team", OP: "in", Value: "2,3", Or: true}}},
+		},
+		Orders: []Order{{Column: "events", Desc: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t,
+		"SELECT toDate(`created_at`) AS `date`, `team` AS `team`, count() AS `events`, sum(`tokens`) AS `tokens`, uniq(`uid`) AS `users` "+
+			"FROM `events` WHERE `created_at` >= ? AND (`team` = ? OR `team` IN ?) "+
+			"GROUP BY `date`, `team` ORDER BY `events` DESC LIMIT 1000",
+		sql,
+	)
+	assert.Equal(t, []interface{}{"2024-01-01", "1", []interface{}{"2", "3"}}, args)
+
+	_, _, err = table.aggregateSQL(Aggregate{Metrics: map[string]string{"x": "sum(*)"}})
+	assert.Error(t, err)
+
+	_, _, err = table.aggregateSQL(Aggregate{Metrics: map[string]string{"x": "sleep(3)"}})
+	assert.Error(t, err)
+
+	_, _, err = table.aggregateSQL(Aggregate{Dimensions: map[string]string{"d": "minute(created_at)"}, Metrics: map[string]string{"x": "count()"}})
+	assert.Error(t, err)
+
+	_, _, err = table.aggregateSQL(Aggregate{Metrics: map[string]string{"x": "count()"}, Wheres: []Where{{Column: "password", Value: 1}}})
+	assert.Error(t, err)
+}
+
+func TestQuery(t *testing.T) {
+	defer Unload()
+	server, queries := testServer(t, `{"meta":[],"data":[{"team":"1","tokens":120,"cost":1.5}],"rows":1}`)
+	defer server.Close()
+
+	Connect("analytics", Options{Host: strings.TrimPrefix(strings.Split(server.URL, ":")[1], "//"), Port: strings.Split(server.URL, ":")[2]})
+	table := testTable()
+	Register(table)
+
+	rows, err := table.Get(context.Background(), Query{
+		Select: []string{"team", "tokens", "cost"},
+		Wheres: []Where{{Column: "team", Value: "1"}},
+		Orders: []Order{{Column: "created_at", Desc: true}},
+		Limit:  10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []map[string]interface{}{{"team": "1", "tokens": int64(120), "cost": 1.5}}, rows)
+
+	err = table.Insert(context.Background(), []map[string]interface{}{{"team": "1", "tokens": 10}, {"team": "2", "tokens": 20}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.Insert(context.Background(), []map[string]interface{}{{"password": "1"}})
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{
+		"SELECT `team`, `tokens`, `cost` FROM `events` WHERE `team` = '1' ORDER BY `created_at` DESC LIMIT 10 FORMAT JSON",
+		"INSERT INTO `events` FORMAT JSONEachRow\n{\"team\":\"1\",\"tokens\":10}\n{\"team\":\"2\",\"tokens\":20}\n",
+	}, *queries)
+}
+
+func TestMigrate(t *testing.T) {
+	defer Unload()
+	server, queries := testServer(t, `{"data":[{"name":"team"},{"name":"uid"}]}`)
+	defer server.Close()
+
+	Connect("analytics", Options{Host: strings.TrimPrefix(strings.Split(server.URL, ":")[1], "//"), Port: strings.Split(server.URL, ":")[2]})
+	table := &Table{ID: "event", Name: "events", Connector: "analytics", Columns: []Column{
+		{Name: "team", Type: "string"}, {Name: "uid", Type: "string"}, {Name: "tokens", Type: "bigInteger"},
+	}}
+	Register(table)
+
+	err := table.Migrate(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, *queries, 3)
+	assert.Contains(t, (*queries)[0], "CREATE TABLE IF NOT EXISTS `events`")
+	assert.Equal(t, "SELECT name FROM system.columns WHERE database = 'default' AND table = 'events' FORMAT JSON", (*queries)[1])
+	assert.Equal(t, "ALTER TABLE `events` ADD COLUMN IF NOT EXISTS `tokens` Int64", (*queries)[2])
+}
+
+func testTable() *Table {
+	return &Table{
+		ID:        "event",
+		Name:      "events",
+		Connector: "analytics",
+		Columns: []Column{
+			{Name: "team", Type: "enum"},
+			{Name: "uid", Type: "string"},
+			{Name: "name", Type: "string"},
+			{Name: "tokens", Type: "bigInteger"},
+			{Name: "cost", Type: "decimal"},
+			{Name: "extra", Type: "json", Nullable: true},
+			{Name: "created_at", Type: "timestampTz"},
+		},
+		OrderBy:     []string{"team", "created_at"},
+		PartitionBy: "toYYYYMM(created_at)",
+	}
+}
+
+// testServer the HTTP interface returns the response, the queries are the query param and the body of the requests
+func testServer(t *testing.T, response string) (*httptest.Server, *[]string) {
+	queries := []string{}
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		query := r.URL.Query().Get("query")
+		if query != "" {
+			query += "\n"
+		}
+
+		mu.Lock()
+		queries = append(queries, query+string(body))
+		mu.Unlock()
+
+		assert.Equal(t, "default", r.Header.Get("X-ClickHouse-User"))
+		if strings.HasSuffix(string(body), "FORMAT JSON") {
+			w.Write([]byte(response))
+		}
+	}))
+	return server, &queries
+}
@@ -0,0 +1,210 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Exec run the statement, the ? of the statement are bound with the args
+func (conn *Connection) Exec(ctx context.Context, stmt string, args ...interface{}) error {
+	sql, err := bind(stmt, args)
+	if err != nil {
+		return err
+	}
+
+	body, err := conn.do(ctx, nil, strings.NewReader(sql))
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// Query the rows of the query, the ? of the query are bound with the args.
+// The integers are int64 and the other numbers are float64.
+func (conn *Connection) Query(ctx context.Context, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	sql, err := bind(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{"output_format_json_quote_64bit_integers": {"0"}}
+	body, err := conn.do(ctx, params, strings.NewReader(sql+" FORMAT JSON"))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	res := struct {
+		Data []map[string]interface{} `json:"data"`
+	}{}
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	err = decoder.Decode(&res)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse %s the response %s", conn.ID, err.Error())
+	}
+
+	for _, row := range res.Data {
+		for key, value := range row {
+			if number, ok := value.(json.Number); ok {
+				row[key] = numberOf(number)
+			}
+		}
+	}
+
+	if res.Data == nil {
+		res.Data = []map[string]interface{}{}
+	}
+	return res.Data, nil
+}
+
+// Insert the rows into the table, the rows are sent in the JSONEachRow format
+func (conn *Connection) Insert(ctx context.Context, table string, rows []map[string]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+
+	params := url.Values{
+		"query":                  {fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", quote(table))},
+		"date_time_input_format": {"best_effort"},
+	}
+	body, err := conn.do(ctx, params, buf)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// Ping the server is available
+func (conn *Connection) Ping(ctx context.Context) error {
+	_, err := conn.Query(ctx, "SELECT 1")
+	return err
+}
+
+// do post the body to the HTTP interface, the error of the server is returned if the status is not 200
+func (conn *Connection) do(ctx context.Context, params url.Values, body io.Reader) (io.ReadCloser, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("database", conn.Options.DB)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, conn.url+"?"+params.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-ClickHouse-User", conn.Options.User)
+	if conn.Options.Password != "" {
+		req.Header.Set("X-ClickHouse-Key", conn.Options.Password)
+	}
+
+	resp, err := conn.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse %s %s", conn.ID, err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("clickhouse %s %d %s", conn.ID, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return resp.Body, nil
+}
+
+// numberOf the int64 of the integer, the float64 otherwise
+func numberOf(number json.Number) interface{} {
+	if value, err := number.Int64(); err == nil {
+		return value
+	}
+	if value, err := number.Float64(); err == nil {
+		return value
+	}
+	return number.String()
+}
+
+// bind replace the ? outside of the quotes with the literals of the args
+func bind(sql string, args []interface{}) (string, error) {
+	if len(args) == 0 {
+		return sql, nil
+	}
+
+	var b strings.Builder
+	n := 0
+	var quoted byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quoted != 0:
+			if c == '\\' && i+1 < len(sql) {
+				b.WriteByte(c)
+				i++
+				c = sql[i]
+			} else if c == quoted {
+				quoted = 0
+			}
+		case c == '\'' || c == '`' || c == '"':
+			quoted = c
+		case c == '?':
+			if n >= len(args) {
+				return "", fmt.Errorf("the query has more placeholders than the %d args", len(args))
+			}
+			b.WriteString(literal(args[n]))
+			n++
+			continue
+		}
+		b.WriteByte(c)
+	}
+
+	if n != len(args) {
+		return "", fmt.Errorf("the query has %d placeholders, but %d args", n, len(args))
+	}
+	return b.String(), nil
+}
+
+// literal the SQL literal of the value, the arrays are the tuples of the values
+func literal(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprintf("%v", v)
+	case json.Number:
+		return v.String()
+	case time.Time:
+		return fmt.Sprintf("parseDateTime64BestEffort('%s', 3)", v.UTC().Format("2006-01-02T15:04:05.000Z"))
+	case []interface{}:
+		values := []string{}
+		for _, item := range v {
+			values = append(values, literal(item))
+		}
+		return "(" + strings.Join(values, ", ") + ")"
+	case []string:
+		values := []string{}
+		for _, item := range v {
+			values = append(values, literal(item))
+		}
+		return "(" + strings.Join(values, ", ") + ")"
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + replacer.Replace(fmt.Sprintf("%v", value)) + "'"
+}
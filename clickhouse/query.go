@@ -0,0 +1,411 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Query the query of the rows of the table
+type Query struct {
+	Select []string
+	Wheres []Where
+	Orders []Order
+	Limit  int
+	Offset int
+}
+
+// Where the condition of the query, the conditions of the nested where are grouped
+type Where struct {
+	Column string
+	OP     string // eq, ne, gt, ge, lt, le, like, match, in, notin, null, notnull, eq by default
+	Value  interface{}
+	Or     bool
+	Wheres []Where
+}
+
+// Order the order of the query
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// Aggregate the aggregation of the rows pushed down to ClickHouse, the rows are grouped by the dimensions
+//
+//	Dimensions: { "date": "day(created_at)", "team": "team" }
+//	Metrics:    { "events": "count()", "users": "uniq(uid)", "tokens": "sum(tokens)" }
+type Aggregate struct {
+	Dimensions map[string]string // The aliases and the columns, <interval>(<column>) buckets the time column
+	Metrics    map[string]string // The aliases and the aggregate functions of the columns
+	Wheres     []Where
+	Orders     []Order // The orders of the aliases, the dimensions by default
+	Limit      int     // 1000 by default
+}
+
+var whereOps = map[string]string{"eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<=", "like": "LIKE"}
+
+// intervals the functions of the time buckets of the dimensions
+var intervals = map[string]string{
+	"hour":    "toStartOfHour",
+	"day":     "toDate",
+	"week":    "toMonday",
+	"month":   "toStartOfMonth",
+	"quarter": "toStartOfQuarter",
+	"year":    "toStartOfYear",
+}
+
+// aggregates the aggregate functions of the metrics
+var aggregates = map[string]string{
+	"count":     "count",
+	"sum":       "sum",
+	"avg":       "avg",
+	"min":       "min",
+	"max":       "max",
+	"uniq":      "uniq",
+	"uniqexact": "uniqExact",
+	"median":    "median",
+}
+
+var callRe = regexp.MustCompile(`^\s*([a-zA-Z]+)\(\s*(\*|[a-zA-Z_][a-zA-Z0-9_]*)?\s*\)\s*$`)
+
+// Get the rows of the query
+func (table *Table) Get(ctx context.Context, query Query) ([]map[string]interface{}, error) {
+	conn, err := Select(table.Connector)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []string{}
+	for _, name := range query.Select {
+		if _, has := table.columns[name]; !has {
+			return nil, fmt.Errorf("the column %s of the model %s does not exist", name, table.ID)
+		}
+		columns = append(columns, quote(name))
+	}
+	if len(columns) == 0 {
+		columns = append(columns, "*")
+	}
+
+	sql := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), quote(table.Name))
+	where, args, err := table.where(query.Wheres)
+	if err != nil {
+		return nil, err
+	}
+	if where != "" {
+		sql += " WHERE " + where
+	}
+
+	orders := []string{}
+	for _, order := range query.Orders {
+		if _, has := table.columns[order.Column]; !has {
+			return nil, fmt.Errorf("the column %s of the model %s does not exist", order.Column, table.ID)
+		}
+		orders = append(orders, orderSQL(order))
+	}
+	if len(orders) > 0 {
+		sql += " ORDER BY " + strings.Join(orders, ", ")
+	}
+
+	if query.Limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d", query.Limit)
+		if query.Offset > 0 {
+			sql += fmt.Sprintf(" OFFSET %d", query.Offset)
+		}
+	}
+	return conn.Query(ctx, sql, args...)
+}
+
+// Count the number of the rows matched by the wheres
+func (table *Table) Count(ctx context.Context, wheres []Where) (int, error) {
+	conn, err := Select(table.Connector)
+	if err != nil {
+		return 0, err
+	}
+
+	sql := fmt.Sprintf("SELECT count() AS total FROM %s", quote(table.Name))
+	where, args, err := table.where(wheres)
+	if err != nil {
+		return 0, err
+	}
+	if where != "" {
+		sql += " WHERE " + where
+	}
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	total, _ := rows[0]["total"].(int64)
+	return int(total), nil
+}
+
+// Insert the rows, the columns of the rows must be the columns of the table
+func (table *Table) Insert(ctx context.Context, rows []map[string]interface{}) error {
+	conn, err := Select(table.Connector)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		for name := range row {
+			if _, has := table.columns[name]; !has {
+				return fmt.Errorf("the column %s of the model %s does not exist", name, table.ID)
+			}
+		}
+	}
+	return conn.Insert(ctx, table.Name, rows)
+}
+
+// Aggregate the rows of the dimensions and the metrics, the aggregation is run by ClickHouse
+func (table *Table) Aggregate(ctx context.Context, agg Aggregate) ([]map[string]interface{}, error) {
+	conn, err := Select(table.Connector)
+	if err != nil {
+		return nil, err
+	}
+
+	sql, args, err := table.aggregateSQL(agg)
+	if err != nil {
+		return nil, err
+	}
+	return conn.Query(ctx, sql, args...)
+}
+
+// aggregateSQL the statement of the aggregation, the aliases are sorted
+func (table *Table) aggregateSQL(agg Aggregate) (string, []interface{}, error) {
+	if len(agg.Metrics) == 0 {
+		return "", nil, fmt.Errorf("the aggregation of the model %s has no metrics", table.ID)
+	}
+
+	aliases := map[string]bool{}
+	dimensions := []string{}
+	for _, alias := range sortedKeys(agg.Dimensions) {
+		expr, err := table.dimension(agg.Dimensions[alias])
+		if err != nil {
+			return "", nil, err
+		}
+		if !nameRe.MatchString(alias) {
+			return "", nil, fmt.Errorf("the dimension %s is invalid", alias)
+		}
+		dimensions = append(dimensions, fmt.Sprintf("%s AS %s", expr, quote(alias)))
+		aliases[alias] = true
+	}
+
+	metrics := []string{}
+	for _, alias := range sortedKeys(agg.Metrics) {
+		expr, err := table.metric(agg.Metrics[alias])
+		if err != nil {
+			return "", nil, err
+		}
+		if !nameRe.MatchString(alias) || aliases[alias] {
+			return "", nil, fmt.Errorf("the metric %s is invalid", alias)
+		}
+		metrics = append(metrics, fmt.Sprintf("%s AS %s", expr, quote(alias)))
+		aliases[alias] = true
+	}
+
+	sql := fmt.Sprintf("SELECT %s FROM %s", strings.Join(append(dimensions, metrics...), ", "), quote(table.Name))
+	where, args, err := table.where(agg.Wheres)
+	if err != nil {
+		return "", nil, err
+	}
+	if where != "" {
+		sql += " WHERE " + where
+	}
+
+	if len(dimensions) > 0 {
+		groups := []string{}
+		for _, alias := range sortedKeys(agg.Dimensions) {
+			groups = append(groups, quote(alias))
+		}
+		sql += " GROUP BY " + strings.Join(groups, ", ")
+	}
+
+	orders := []string{}
+	for _, order := range agg.Orders {
+		if !aliases[order.Column] {
+			return "", nil, fmt.Errorf("the order %s is not a dimension or a metric", order.Column)
+		}
+		orders = append(orders, orderSQL(order))
+	}
+	if len(orders) == 0 {
+		for _, alias := range sortedKeys(agg.Dimensions) {
+			orders = append(orders, quote(alias))
+		}
+	}
+	if len(orders) > 0 {
+		sql += " ORDER BY " + strings.Join(orders, ", ")
+	}
+
+	limit := agg.Limit
+	if limit <= 0 {
+		limit = 1000
+	}
+	sql += fmt.Sprintf(" LIMIT %d", limit)
+	return sql, args, nil
+}
+
+// dimension the expression of the dimension, the column or the time bucket of the column
+func (table *Table) dimension(value string) (string, error) {
+	if _, has := table.columns[value]; has {
+		return quote(value), nil
+	}
+
+	matches := callRe.FindStringSubmatch(value)
+	if matches == nil || matches[2] == "" || matches[2] == "*" {
+		return "", fmt.Errorf("the dimension %s is invalid, <column> or <interval>(<column>)", value)
+	}
+
+	fn, has := intervals[strings.ToLower(matches[1])]
+	if !has {
+		return "", fmt.Errorf("the interval %s of the dimension %s is invalid, hour, day, week, month, quarter or year", matches[1], value)
+	}
+
+	if _, has := table.columns[matches[2]]; !has {
+		return "", fmt.Errorf("the column %s of the model %s does not exist", matches[2], table.ID)
+	}
+	return fmt.Sprintf("%s(%s)", fn, quote(matches[2])), nil
+}
+
+// metric the expression of the metric, the aggregate function of the column
+func (table *Table) metric(value string) (string, error) {
+	matches := callRe.FindStringSubmatch(value)
+	if matches == nil {
+		return "", fmt.Errorf("the metric %s is invalid, <function>(<column>)", value)
+	}
+
+	fn, has := aggregates[strings.ToLower(matches[1])]
+	if !has {
+		return "", fmt.Errorf("the function %s of the metric %s is not supported", matches[1], value)
+	}
+
+	column := matches[2]
+	if column == "" || column == "*" {
+		if fn != "count" {
+			return "", fmt.Errorf("the metric %s requires a column", value)
+		}
+		return "count()", nil
+	}
+
+	if _, has := table.columns[column]; !has {
+		return "", fmt.Errorf("the column %s of the model %s does not exist", column, table.ID)
+	}
+	return fmt.Sprintf("%s(%s)", fn, quote(column)), nil
+}
+
+// where the condition of the wheres and the args of the placeholders
+func (table *Table) where(wheres []Where) (string, []interface{}, error) {
+	sql := ""
+	args := []interface{}{}
+	for i, where := range wheres {
+		cond := ""
+		if len(where.Wheres) > 0 {
+			nested, values, err := table.where(where.Wheres)
+			if err != nil {
+				return "", nil, err
+			}
+			cond = "(" + nested + ")"
+			args = append(args, values...)
+
+		} else {
+			if _, has := table.columns[where.Column]; !has {
+				return "", nil, fmt.Errorf("the column %s of the model %s does not exist", where.Column, table.ID)
+			}
+
+			column := quote(where.Column)
+			switch op := strings.ToLower(where.OP); op {
+			case "", "eq", "ne", "gt", "ge", "lt", "le", "like":
+				if op == "" {
+					op = "eq"
+				}
+				cond = fmt.Sprintf("%s %s ?", column, whereOps[op])
+				args = append(args, where.Value)
+
+			case "match":
+				cond = fmt.Sprintf("%s LIKE ?", column)
+				args = append(args, fmt.Sprintf("%%%v%%", where.Value))
+
+			case "in", "notin":
+				values := valuesOf(where.Value)
+				if len(values) == 0 {
+					cond = "1 = 0"
+					if op == "notin" {
+						cond = "1 = 1"
+					}
+					break
+				}
+				cond = fmt.Sprintf("%s IN ?", column)
+				if op == "notin" {
+					cond = fmt.Sprintf("%s NOT IN ?", column)
+				}
+				args = append(args, values)
+
+			case "null":
+				cond = fmt.Sprintf("%s IS NULL", column)
+
+			case "notnull":
+				cond = fmt.Sprintf("%s IS NOT NULL", column)
+
+			default:
+				return "", nil, fmt.Errorf("the where %s %s is not supported", where.Column, where.OP)
+			}
+		}
+
+		if i == 0 {
+			sql = cond
+			continue
+		}
+
+		if where.Or {
+			sql += " OR " + cond
+			continue
+		}
+		sql += " AND " + cond
+	}
+	return sql, args, nil
+}
+
+// valuesOf the values of the array, the string separated by comma or the value
+func valuesOf(value interface{}) []interface{} {
+	switch values := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return values
+	case []string:
+		res := []interface{}{}
+		for _, v := range values {
+			res = append(res, v)
+		}
+		return res
+	case string:
+		res := []interface{}{}
+		for _, v := range strings.Split(values, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				res = append(res, v)
+			}
+		}
+		return res
+	}
+	return []interface{}{value}
+}
+
+func orderSQL(order Order) string {
+	if order.Desc {
+		return quote(order.Column) + " DESC"
+	}
+	return quote(order.Column) + " ASC"
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,209 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Table the table of the ClickHouse model, the MergeTree table of the append-only rows.
+// The engine settings are the "clickhouse" of the model source:
+//
+//	"clickhouse": { "order_by": ["team", "created_at"], "partition_by": "toYYYYMM(created_at)", "ttl": "created_at + INTERVAL 1 YEAR" }
+type Table struct {
+	ID          string   `json:"-"`
+	Name        string   `json:"-"`
+	Connector   string   `json:"-"`
+	Columns     []Column `json:"-"`
+	OrderBy     []string `json:"order_by,omitempty"`     // The sorting key, tuple() by default
+	PartitionBy string   `json:"partition_by,omitempty"` // The partition expression
+	TTL         string   `json:"ttl,omitempty"`          // The TTL expression of the rows
+	columns     map[string]*Column
+}
+
+// Column the column of the ClickHouse model, the subset of the types of the model columns
+type Column struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Nullable  bool   `json:"nullable,omitempty"`
+	Precision int    `json:"precision,omitempty"`
+	Scale     int    `json:"scale,omitempty"`
+}
+
+// columnTypes the ClickHouse types of the model column types
+var columnTypes = map[string]string{
+	"string":               "String",
+	"char":                 "String",
+	"text":                 "String",
+	"mediumtext":           "String",
+	"longtext":             "String",
+	"json":                 "String",
+	"jsonb":                "String",
+	"ipaddress":            "String",
+	"macaddress":           "String",
+	"enum":                 "LowCardinality(String)",
+	"uuid":                 "UUID",
+	"tinyinteger":          "Int8",
+	"smallinteger":         "Int16",
+	"integer":              "Int32",
+	"biginteger":           "Int64",
+	"unsignedtinyinteger":  "UInt8",
+	"unsignedsmallinteger": "UInt16",
+	"unsignedinteger":      "UInt32",
+	"unsignedbiginteger":   "UInt64",
+	"year":                 "UInt16",
+	"float":                "Float32",
+	"double":               "Float64",
+	"boolean":              "Bool",
+	"date":                 "Date",
+	"datetime":             "DateTime",
+	"datetimetz":           "DateTime",
+	"timestamp":            "DateTime64(3)",
+	"timestamptz":          "DateTime64(3)",
+}
+
+// validate the names and the types of the columns, the keys of the engine settings are the columns
+func (table *Table) validate() error {
+	if !nameRe.MatchString(table.Name) {
+		return fmt.Errorf("the table %s is invalid", table.Name)
+	}
+
+	if len(table.Columns) == 0 {
+		return fmt.Errorf("the table %s has no columns", table.Name)
+	}
+
+	table.columns = map[string]*Column{}
+	for i := range table.Columns {
+		column := &table.Columns[i]
+		if !nameRe.MatchString(column.Name) {
+			return fmt.Errorf("the column %s is invalid", column.Name)
+		}
+
+		if _, err := column.sqlType(); err != nil {
+			return err
+		}
+		table.columns[column.Name] = column
+	}
+
+	for _, name := range table.OrderBy {
+		column, has := table.columns[name]
+		if !has {
+			return fmt.Errorf("the order_by column %s does not exist", name)
+		}
+		if column.Nullable {
+			return fmt.Errorf("the order_by column %s is nullable", name)
+		}
+	}
+	return nil
+}
+
+// Column the column of the table
+func (table *Table) Column(name string) (*Column, bool) {
+	column, has := table.columns[name]
+	return column, has
+}
+
+// sqlType the ClickHouse type of the column
+func (column *Column) sqlType() (string, error) {
+	name := strings.ToLower(column.Type)
+	typ := ""
+	switch name {
+	case "id", "increments", "tinyincrements", "smallincrements", "mediumincrements", "bigincrements":
+		return "", fmt.Errorf("the column %s is %s, the auto increment columns are not supported by ClickHouse, use uuid instead", column.Name, column.Type)
+
+	case "decimal", "unsigneddecimal":
+		precision, scale := column.Precision, column.Scale
+		if precision <= 0 {
+			precision, scale = 10, 2
+		}
+		typ = fmt.Sprintf("Decimal(%d, %d)", precision, scale)
+
+	default:
+		t, has := columnTypes[name]
+		if !has {
+			return "", fmt.Errorf("the column %s is %s, the type is not supported by ClickHouse", column.Name, column.Type)
+		}
+		typ = t
+	}
+
+	if !column.Nullable {
+		return typ, nil
+	}
+
+	if strings.HasPrefix(typ, "LowCardinality(") {
+		return "LowCardinality(Nullable(" + strings.TrimSuffix(strings.TrimPrefix(typ, "LowCardinality("), ")") + "))", nil
+	}
+	return "Nullable(" + typ + ")", nil
+}
+
+// createSQL the statement creates the table
+func (table *Table) createSQL() string {
+	columns := []string{}
+	for _, column := range table.Columns {
+		typ, _ := column.sqlType()
+		columns = append(columns, fmt.Sprintf("%s %s", quote(column.Name), typ))
+	}
+
+	orderBy := "tuple()"
+	if len(table.OrderBy) > 0 {
+		keys := []string{}
+		for _, name := range table.OrderBy {
+			keys = append(keys, quote(name))
+		}
+		orderBy = "(" + strings.Join(keys, ", ") + ")"
+	}
+
+	sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s) ENGINE = MergeTree", quote(table.Name), strings.Join(columns, ", "))
+	if table.PartitionBy != "" {
+		sql += " PARTITION BY " + table.PartitionBy
+	}
+	sql += " ORDER BY " + orderBy
+	if table.TTL != "" {
+		sql += " TTL " + table.TTL
+	}
+	return sql
+}
+
+// Migrate create the table, the columns added to the model are added to the table.
+// The types of the columns and the engine settings of the table created are not changed.
+func (table *Table) Migrate(ctx context.Context, reset bool) error {
+	conn, err := Select(table.Connector)
+	if err != nil {
+		return err
+	}
+
+	if reset {
+		err = conn.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", quote(table.Name)))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = conn.Exec(ctx, table.createSQL())
+	if err != nil {
+		return err
+	}
+
+	rows, err := conn.Query(ctx, "SELECT name FROM system.columns WHERE database = ? AND table = ?", conn.Options.DB, table.Name)
+	if err != nil {
+		return err
+	}
+
+	exists := map[string]bool{}
+	for _, row := range rows {
+		exists[fmt.Sprintf("%v", row["name"])] = true
+	}
+
+	for _, column := range table.Columns {
+		if exists[column.Name] {
+			continue
+		}
+
+		typ, _ := column.sqlType()
+		err = conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", quote(table.Name), quote(column.Name), typ))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
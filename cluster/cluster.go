@@ -0,0 +1,272 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+)
+
+// leaseName the name of the lease of the leader
+const leaseName = "leader"
+
+// Backend the lease of the leader shared by the instances
+type Backend interface {
+	// Acquire get or renew the lease of the owner, returns true if the owner holds the lease
+	Acquire(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error)
+	// Release release the lease held by the owner
+	Release(ctx context.Context, name string, owner string) error
+}
+
+// Status the status of the election of the instance
+type Status struct {
+	Backend string     `json:"backend,omitempty"` // The backend of the election, empty if the election is disabled
+	Owner   string     `json:"owner"`             // The instance, the hostname, the pid and the random id
+	Leader  bool       `json:"leader"`            // The instance is the leader
+	Since   *time.Time `json:"since,omitempty"`   // The time the instance is elected
+}
+
+type singleton struct {
+	name    string
+	start   func()
+	stop    func()
+	running bool
+}
+
+var (
+	lock       sync.Mutex
+	singletons = []*singleton{}
+	backend    Backend
+	name       string
+	owner      = ownerID()
+	renewed    time.Time
+	cancel     context.CancelFunc
+	done       chan struct{}
+)
+
+// The state of the election, read by the singletons
+var (
+	state    sync.RWMutex
+	leader   = false
+	electing = false
+	since    *time.Time
+)
+
+// Singleton register the subsystem running on the leader only, the subsystem is started when the instance is elected
+// and stopped when the leadership is lost. The subsystem is started at once if the instance is the leader.
+func Singleton(name string, start func(), stop func()) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	s := &singleton{name: name, start: start, stop: stop}
+	singletons = append(singletons, s)
+	if isLeader() {
+		s.run()
+	}
+}
+
+// Start the election of the configuration, every instance is the leader if the election is disabled
+func Start(cfg config.Cluster) error {
+	b, err := backendOf(cfg)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Duration(cfg.Lease) * time.Second
+	if ttl <= 0 {
+		ttl = 15 * time.Second
+	}
+	return start(b, cfg.Leader, ttl)
+}
+
+func start(b Backend, kind string, ttl time.Duration) error {
+	Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	backend = b
+	name = kind
+	if b == nil {
+		elect()
+		return nil
+	}
+
+	state.Lock()
+	electing = true
+	state.Unlock()
+
+	ctx, c := context.WithCancel(context.Background())
+	cancel = c
+	done = make(chan struct{})
+	go campaign(ctx, b, ttl, done)
+	log.Info("[Cluster] %s campaigns for the leader by %s", owner, kind)
+	return nil
+}
+
+// Stop stop the singletons and release the lease
+func Stop() {
+	lock.Lock()
+	c, d, b := cancel, done, backend
+	cancel, done = nil, nil
+	lock.Unlock()
+
+	if c != nil {
+		c()
+		<-d
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if isLeader() {
+		resign()
+	}
+
+	state.Lock()
+	electing = false
+	state.Unlock()
+
+	if b != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := b.Release(ctx, leaseName, owner); err != nil {
+			log.Error("[Cluster] release the lease: %s", err.Error())
+		}
+	}
+	backend = nil
+}
+
+// IsLeader the instance is the leader, true if the election is not started
+func IsLeader() bool {
+	state.RLock()
+	defer state.RUnlock()
+	return leader || !electing
+}
+
+// Info the status of the election of the instance
+func Info() Status {
+	state.RLock()
+	defer state.RUnlock()
+	return Status{Backend: name, Owner: owner, Leader: leader || !electing, Since: since}
+}
+
+// isLeader the instance is elected
+func isLeader() bool {
+	state.RLock()
+	defer state.RUnlock()
+	return leader
+}
+
+// campaign acquire or renew the lease every third of the ttl. The leadership is lost if the lease is not renewed
+// in the ttl, e.g. the backend is unreachable
+func campaign(ctx context.Context, b Backend, ttl time.Duration, done chan struct{}) {
+	defer close(done)
+	interval := ttl / 3
+	for {
+		acquire(ctx, b, ttl)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func acquire(ctx context.Context, b Backend, ttl time.Duration) {
+	c, cancel := context.WithTimeout(ctx, ttl/3)
+	ok, err := b.Acquire(c, leaseName, owner, ttl)
+	cancel()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err != nil {
+		log.Error("[Cluster] acquire the lease: %s", err.Error())
+		if isLeader() && time.Since(renewed) >= ttl {
+			resign()
+		}
+		return
+	}
+
+	if ok {
+		renewed = time.Now()
+		if !isLeader() {
+			elect()
+		}
+		return
+	}
+
+	if isLeader() {
+		resign()
+	}
+}
+
+// elect start the singletons, the lock is held by the caller
+func elect() {
+	now := time.Now()
+	state.Lock()
+	leader, since = true, &now
+	state.Unlock()
+
+	log.Info("[Cluster] %s is the leader", owner)
+	for _, s := range singletons {
+		s.run()
+	}
+}
+
+// resign stop the singletons, the lock is held by the caller
+func resign() {
+	state.Lock()
+	leader, since = false, nil
+	state.Unlock()
+
+	log.Info("[Cluster] %s is not the leader", owner)
+	for i := len(singletons) - 1; i >= 0; i-- {
+		singletons[i].halt()
+	}
+}
+
+func (s *singleton) run() {
+	if s.running {
+		return
+	}
+	s.running = true
+	if s.start != nil {
+		s.start()
+	}
+	log.Info("[Cluster] %s started on the leader", s.name)
+}
+
+func (s *singleton) halt() {
+	if !s.running {
+		return
+	}
+	s.running = false
+	if s.stop != nil {
+		s.stop()
+	}
+	log.Info("[Cluster] %s stopped", s.name)
+}
+
+// backendOf the backend of the election, nil if the election is disabled
+func backendOf(cfg config.Cluster) (Backend, error) {
+	switch cfg.Leader {
+	case "":
+		return nil, nil
+	case "db":
+		return newDB()
+	case "redis":
+		return newRedis(cfg.Redis)
+	}
+	return nil, fmt.Errorf("the leader election %s is not supported, db or redis", cfg.Leader)
+}
+
+func ownerID() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), uuid.New().String()[:8])
+}
@@ -0,0 +1,111 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memory the leases of the memory, shared by the instances of the test
+type memory struct {
+	mu     sync.Mutex
+	owners map[string]string
+	expire map[string]time.Time
+	down   bool
+}
+
+func (m *memory) Acquire(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.down {
+		return false, fmt.Errorf("the backend is down")
+	}
+	if current, has := m.owners[name]; has && current != owner && time.Now().Before(m.expire[name]) {
+		return false, nil
+	}
+	m.owners[name], m.expire[name] = owner, time.Now().Add(ttl)
+	return true, nil
+}
+
+func (m *memory) Release(ctx context.Context, name string, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.owners[name] == owner {
+		delete(m.owners, name)
+	}
+	return nil
+}
+
+func TestSingleton(t *testing.T) {
+	defer reset()
+
+	starts, stops := 0, 0
+	Singleton("schedules", func() { starts++ }, func() { stops++ })
+	assert.True(t, IsLeader())
+
+	// Every instance is the leader if the election is disabled
+	err := start(nil, "", time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, starts)
+	assert.Equal(t, "", Info().Backend)
+
+	Stop()
+	assert.Equal(t, 1, stops)
+}
+
+func TestElection(t *testing.T) {
+	defer reset()
+
+	b := &memory{owners: map[string]string{}, expire: map[string]time.Time{}}
+	b.owners[leaseName], b.expire[leaseName] = "other", time.Now().Add(time.Hour)
+
+	var starts, stops atomic.Int32
+	Singleton("schedules", func() { starts.Add(1) }, func() { stops.Add(1) })
+	err := start(b, "memory", 300*time.Millisecond)
+	assert.Nil(t, err)
+
+	// The lease is held by the other instance
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, IsLeader())
+	assert.Equal(t, int32(0), starts.Load())
+
+	// The other instance is gone, the lease is taken over
+	b.mu.Lock()
+	b.expire[leaseName] = time.Now()
+	b.mu.Unlock()
+	assert.Eventually(t, IsLeader, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), starts.Load())
+	assert.Equal(t, "memory", Info().Backend)
+	assert.NotNil(t, Info().Since)
+
+	// The leadership is lost if the lease is not renewed in the ttl
+	b.mu.Lock()
+	b.down = true
+	b.mu.Unlock()
+	assert.Eventually(t, func() bool { return !IsLeader() }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), stops.Load())
+
+	b.mu.Lock()
+	b.down = false
+	b.mu.Unlock()
+	assert.Eventually(t, IsLeader, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), starts.Load())
+
+	// The lease is released when the instance stops
+	Stop()
+	assert.Equal(t, int32(2), stops.Load())
+	assert.NotContains(t, b.owners, leaseName)
+	assert.True(t, IsLeader())
+}
+
+func reset() {
+	Stop()
+	lock.Lock()
+	singletons = []*singleton{}
+	lock.Unlock()
+}
@@ -0,0 +1,72 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+const leaseTable = "yao_cluster_lease"
+
+// db the leases of the default database, the expired lease is taken over by the other instances
+type db struct{}
+
+func newDB() (Backend, error) {
+	sch := capsule.Schema()
+	has, err := sch.HasTable(leaseTable)
+	if err != nil {
+		return nil, err
+	}
+
+	if !has {
+		err = sch.CreateTable(leaseTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("name", 200).Unique()
+			table.String("owner", 200)
+			table.TimestampTz("expired_at").Index()
+			table.TimestampTz("updated_at").Null()
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Trace("Create the cluster table: %s", leaseTable)
+	}
+	return &db{}, nil
+}
+
+// Acquire renew the lease of the owner or take over the expired lease, the lease is created if it does not exist
+func (d *db) Acquire(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	nums, err := newQuery().
+		Where("name", name).
+		Where(func(qb query.Query) {
+			qb.Where("owner", owner).OrWhere("expired_at", "<=", now)
+		}).
+		Update(map[string]interface{}{"owner": owner, "expired_at": now.Add(ttl), "updated_at": now})
+	if err != nil {
+		return false, err
+	}
+	if nums > 0 {
+		return true, nil
+	}
+
+	// The insert fails if another instance holds the lease
+	err = newQuery().Insert(map[string]interface{}{"name": name, "owner": owner, "expired_at": now.Add(ttl), "updated_at": now})
+	return err == nil, nil
+}
+
+// Release remove the lease held by the owner
+func (d *db) Release(ctx context.Context, name string, owner string) error {
+	_, err := newQuery().Where("name", name).Where("owner", owner).Delete()
+	return err
+}
+
+func newQuery() query.Query {
+	qb := capsule.Query()
+	qb.Table(leaseTable)
+	return qb
+}
@@ -0,0 +1,55 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yaoapp/yao/config"
+)
+
+// renewScript renew the lease held by the owner
+var renewScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) end return 0`)
+
+// releaseScript remove the lease held by the owner
+var releaseScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`)
+
+// rdb the leases of the redis, the lease expires by the TTL of the key
+type rdb struct {
+	client *redis.Client
+}
+
+// newRedis the redis of the URL, the session redis if the URL is empty
+func newRedis(url string) (Backend, error) {
+	if url == "" {
+		session := config.Conf.Session
+		url = fmt.Sprintf("redis://%s:%s@%s:%s/%s", session.Username, session.Password, session.Host, session.Port, session.DB)
+	}
+
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("the redis of the cluster is invalid: %s", err.Error())
+	}
+	return &rdb{client: redis.NewClient(options)}, nil
+}
+
+// Acquire set the lease if it does not exist or renew the lease of the owner
+func (r *rdb) Acquire(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	key := "yao:cluster:" + name
+	ok, err := r.client.SetNX(ctx, key, owner, ttl).Result()
+	if err != nil || ok {
+		return ok, err
+	}
+
+	renewed, err := renewScript.Run(ctx, r.client, []string{key}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return renewed == 1, nil
+}
+
+// Release remove the lease held by the owner
+func (r *rdb) Release(ctx context.Context, name string, owner string) error {
+	return releaseScript.Run(ctx, r.client, []string{"yao:cluster:" + name}, owner).Err()
+}
@@ -0,0 +1,281 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"gopkg.in/yaml.v3"
+)
+
+// Options the options of the agent benchmark
+type Options struct {
+	URL         string        // The address of the running instance, e.g. http://127.0.0.1:5099
+	Token       string        // The bearer token of the requests
+	Assistant   string        // The assistant id, the model of the chat completions
+	Concurrency int           // The number of the conversations at the same time
+	Requests    int           // The number of the turns, 100 by default if the duration is not set
+	Duration    time.Duration // Run until the duration is elapsed if set
+	Timeout     time.Duration // The timeout of a turn
+	Scenarios   []Scenario    // The message mix, the default scenarios if empty
+}
+
+// Scenario the synthetic conversation, the messages are sent turn by turn with the replies as the history.
+// The weight is the share of the scenario in the mix.
+type Scenario struct {
+	Name     string   `json:"name" yaml:"name"`
+	Weight   int      `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Messages []string `json:"messages" yaml:"messages"`
+}
+
+// Result the result of a turn
+type Result struct {
+	Scenario   string
+	Latency    time.Duration // The time to the end of the reply
+	FirstToken time.Duration // The time to the first chunk of the reply
+	Chunks     int
+	Err        error
+}
+
+// defaultScenarios the message mix used if the mix file is not set
+var defaultScenarios = []Scenario{
+	{Name: "greeting", Weight: 3, Messages: []string{"Hello", "What can you do?"}},
+	{Name: "question", Weight: 5, Messages: []string{"Summarize the latest orders in three sentences."}},
+	{Name: "task", Weight: 2, Messages: []string{"Find the orders of the customer Max", "Refund the latest one", "Send him an email about the refund"}},
+}
+
+// LoadScenarios load the message mix of the JSON or the YAML file
+func LoadScenarios(file string) ([]Scenario, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	scenarios := []Scenario{}
+	if err := yaml.Unmarshal(data, &scenarios); err != nil {
+		return nil, fmt.Errorf("the mix %s is invalid: %s", file, err.Error())
+	}
+
+	for i, scenario := range scenarios {
+		if len(scenario.Messages) == 0 {
+			return nil, fmt.Errorf("the scenario %d of the mix %s has no messages", i, file)
+		}
+		if scenario.Weight < 0 {
+			return nil, fmt.Errorf("the weight of the scenario %d of the mix %s is negative", i, file)
+		}
+	}
+	return scenarios, nil
+}
+
+// Run run the conversations against the instance, returns the report of the turns
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.URL == "" || opts.Assistant == "" {
+		return nil, fmt.Errorf("the url and the assistant are required")
+	}
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.Requests <= 0 && opts.Duration <= 0 {
+		opts.Requests = 100
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Minute
+	}
+	if len(opts.Scenarios) == 0 {
+		opts.Scenarios = defaultScenarios
+	}
+	for _, scenario := range opts.Scenarios {
+		if len(scenario.Messages) == 0 {
+			return nil, fmt.Errorf("the scenario %s has no messages", scenario.Name)
+		}
+	}
+
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	endpoint := strings.TrimRight(opts.URL, "/") + "/api/__yao/neo/v1/chat/completions"
+	client := &http.Client{Timeout: opts.Timeout}
+	remaining := int64(opts.Requests)
+
+	// take a turn of the budget, the duration limits the turns if the requests are not set
+	take := func() bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		return opts.Requests <= 0 || atomic.AddInt64(&remaining, -1) >= 0
+	}
+
+	results := make(chan Result, opts.Concurrency)
+	report := newReport()
+	collected := make(chan struct{})
+	go func() {
+		for result := range results {
+			report.add(result)
+		}
+		close(collected)
+	}()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				scenario := pick(opts.Scenarios)
+				history := []map[string]string{}
+				for _, message := range scenario.Messages {
+					if !take() {
+						return
+					}
+
+					history = append(history, map[string]string{"role": "user", "content": message})
+					result, reply := turn(ctx, client, endpoint, opts, history)
+					result.Scenario = scenario.Name
+
+					// The turns canceled by the end of the duration are not counted
+					if result.Err != nil && ctx.Err() != nil {
+						return
+					}
+
+					results <- result
+					if result.Err != nil {
+						break
+					}
+					history = append(history, map[string]string{"role": "assistant", "content": reply})
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(results)
+	<-collected
+
+	report.finish(time.Since(start))
+	return report, nil
+}
+
+// turn send the messages and read the streamed reply, returns the result and the text of the reply
+func turn(ctx context.Context, client *http.Client, endpoint string, opts Options, messages []map[string]string) (Result, string) {
+	result := Result{}
+	body, err := jsoniter.Marshal(map[string]interface{}{"model": opts.Assistant, "messages": messages, "stream": true})
+	if err != nil {
+		result.Err = err
+		return result, ""
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		result.Err = err
+		return result, ""
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Yao-Cache", "bypass")
+	if opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.Token)
+	}
+
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		result.Err = err
+		return result, ""
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		result.Err = fmt.Errorf("%d %s", res.StatusCode, strings.TrimSpace(string(raw)))
+		return result, ""
+	}
+
+	reply := strings.Builder{}
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := jsoniter.UnmarshalFromString(data, &chunk); err != nil {
+			continue
+		}
+
+		if chunk.Error != nil {
+			result.Err = fmt.Errorf("%s", chunk.Error.Message)
+			break
+		}
+
+		if result.Chunks == 0 {
+			result.FirstToken = time.Since(start)
+		}
+		result.Chunks++
+		if len(chunk.Choices) > 0 {
+			reply.WriteString(chunk.Choices[0].Delta.Content)
+		}
+	}
+
+	if err := scanner.Err(); err != nil && result.Err == nil {
+		result.Err = err
+	}
+	if result.Err == nil && result.Chunks == 0 {
+		result.Err = fmt.Errorf("the reply is empty")
+	}
+	result.Latency = time.Since(start)
+	return result, reply.String()
+}
+
+// pick pick the scenario by the weights, the scenarios without the weight are weighted 1
+func pick(scenarios []Scenario) Scenario {
+	total := 0
+	for _, scenario := range scenarios {
+		total += weightOf(scenario)
+	}
+
+	n := rand.Intn(total)
+	for _, scenario := range scenarios {
+		n -= weightOf(scenario)
+		if n < 0 {
+			return scenario
+		}
+	}
+	return scenarios[len(scenarios)-1]
+}
+
+func weightOf(scenario Scenario) int {
+	if scenario.Weight <= 0 {
+		return 1
+	}
+	return scenario.Weight
+}
@@ -0,0 +1,108 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var opts = Options{}
+var mix string
+var mockAddr string
+var mock = Mock{}
+
+var langs = map[string]string{
+	"Drive synthetic conversations against a running instance": "对运行中的实例发起模拟对话压测",
+}
+
+// L Language switch
+func L(words string) string {
+	var lang = os.Getenv("YAO_LANG")
+	if lang == "" {
+		return words
+	}
+
+	if trans, has := langs[words]; has {
+		return trans
+	}
+	return words
+}
+
+// AgentCmd command
+var AgentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: L("Drive synthetic conversations against a running instance"),
+	Long:  L("Drive synthetic conversations against a running instance"),
+	Example: `  yao bench agent -m assistant_123 -c 20 -n 500 -t xxx
+  yao bench agent -m assistant_123 -c 50 -d 5m --mix mix.yml --mock :5199 --latency 800ms --tool-probability 0.3`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// The mock connector, the host of the connector of the assistant should be the mock address
+		if mockAddr != "" {
+			server := &http.Server{Addr: mockAddr, Handler: &mock}
+			go func() {
+				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					fmt.Fprintln(os.Stderr, color.RedString("The mock connector: %s", err.Error()))
+					os.Exit(1)
+				}
+			}()
+			defer server.Close()
+			fmt.Println(color.WhiteString("The mock connector is listening on %s", mockAddr))
+
+			// Serve the mock only
+			if opts.Assistant == "" {
+				<-ctx.Done()
+				return
+			}
+		}
+
+		if mix != "" {
+			scenarios, err := LoadScenarios(mix)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+				os.Exit(1)
+			}
+			opts.Scenarios = scenarios
+		}
+
+		fmt.Println(color.WhiteString("Benchmark %s on %s with %d conversations", opts.Assistant, opts.URL, opts.Concurrency))
+		report, err := Run(ctx, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, color.RedString(err.Error()))
+			os.Exit(1)
+		}
+
+		fmt.Println()
+		report.Print(os.Stdout)
+		if report.Errors > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	flags := AgentCmd.PersistentFlags()
+	flags.StringVarP(&opts.URL, "url", "u", "http://127.0.0.1:5099", L("The address of the running instance"))
+	flags.StringVarP(&opts.Token, "token", "t", "", L("The bearer token"))
+	flags.StringVarP(&opts.Assistant, "assistant", "m", "", L("The assistant id"))
+	flags.IntVarP(&opts.Concurrency, "concurrency", "c", 10, L("The number of the conversations at the same time"))
+	flags.IntVarP(&opts.Requests, "requests", "n", 0, L("The number of the turns, 100 by default if the duration is not set"))
+	flags.DurationVarP(&opts.Duration, "duration", "d", 0, L("Run until the duration is elapsed, e.g. 5m"))
+	flags.DurationVar(&opts.Timeout, "timeout", 2*time.Minute, L("The timeout of a turn"))
+	flags.StringVar(&mix, "mix", "", L("The message mix file, the JSON or the YAML list of the scenarios {name, weight, messages}"))
+	flags.StringVar(&mockAddr, "mock", "", L("Start the mock connector on the address, e.g. :5199"))
+	flags.DurationVar(&mock.Latency, "latency", 500*time.Millisecond, L("The latency of the mock connector before the first chunk"))
+	flags.DurationVar(&mock.Jitter, "jitter", 200*time.Millisecond, L("The random latency added to the latency of the mock connector"))
+	flags.IntVar(&mock.Chunks, "chunks", 20, L("The chunks of the replies of the mock connector"))
+	flags.DurationVar(&mock.ChunkLatency, "chunk-latency", 20*time.Millisecond, L("The latency between the chunks of the mock connector"))
+	flags.Float64Var(&mock.ToolProbability, "tool-probability", 0.2, L("The probability of the mock connector calling a tool, 0 to 1"))
+}
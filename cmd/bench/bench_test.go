@@ -0,0 +1,90 @@
+package bench
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(&Mock{Latency: 10 * time.Millisecond, Chunks: 3, ChunkLatency: time.Millisecond})
+	defer server.Close()
+
+	report, err := Run(context.Background(), Options{URL: server.URL, Assistant: "writer", Concurrency: 4, Requests: 20})
+	assert.NoError(t, err)
+	assert.Equal(t, 20, report.Turns)
+	assert.Equal(t, 0, report.Errors)
+	assert.Greater(t, report.Throughput, float64(0))
+	assert.GreaterOrEqual(t, report.FirstToken.P50, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, report.Latency.P50, report.FirstToken.P50)
+
+	out := strings.Builder{}
+	report.Print(&out)
+	assert.Contains(t, out.String(), "Turns:       20 (0 errors)")
+
+	// The turns canceled by the end of the duration are not errors
+	report, err = Run(context.Background(), Options{URL: server.URL, Assistant: "writer", Concurrency: 2, Duration: 100 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.Greater(t, report.Turns, 0)
+	assert.Equal(t, 0, report.Errors)
+
+	failed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"the model writer does not exist"}}`, http.StatusNotFound)
+	}))
+	defer failed.Close()
+
+	report, err = Run(context.Background(), Options{URL: failed.URL, Assistant: "writer", Requests: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Errors)
+	assert.Len(t, report.Failures, 1)
+
+	_, err = Run(context.Background(), Options{URL: server.URL})
+	assert.Error(t, err)
+}
+
+func TestMock(t *testing.T) {
+	server := httptest.NewServer(&Mock{ToolProbability: 1})
+	defer server.Close()
+
+	res, err := server.Client().Post(server.URL+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"stream": true, "tools": [{"type": "function", "function": {"name": "scripts_order_Refund"}}]}`))
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	raw := strings.Builder{}
+	buf := make([]byte, 4096)
+	for {
+		n, err := res.Body.Read(buf)
+		raw.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+	assert.Contains(t, raw.String(), `"name":"scripts_order_Refund"`)
+	assert.Contains(t, raw.String(), `"finish_reason":"tool_calls"`)
+	assert.True(t, strings.HasSuffix(raw.String(), "data: [DONE]\n\n"))
+}
+
+func TestScenarios(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "mix.yml")
+	assert.NoError(t, os.WriteFile(file, []byte("- name: refund\n  weight: 1\n  messages: [Find the order 1, Refund it]\n"), 0644))
+	scenarios, err := LoadScenarios(file)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Find the order 1", "Refund it"}, scenarios[0].Messages)
+	assert.Equal(t, "refund", pick(scenarios).Name)
+
+	assert.NoError(t, os.WriteFile(file, []byte(`[{"name": "empty", "messages": []}]`), 0644))
+	_, err = LoadScenarios(file)
+	assert.Error(t, err)
+
+	p := percentiles([]time.Duration{5, 1, 4, 2, 3, 6, 7, 8, 9, 10})
+	assert.Equal(t, Percentiles{Min: 1, Mean: 5, P50: 5, P90: 9, P95: 10, P99: 10, Max: 10}, p)
+	assert.Equal(t, Percentiles{}, percentiles(nil))
+}
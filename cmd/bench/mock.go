@@ -0,0 +1,143 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Mock the mock connector, an OpenAI compatible chat completions server with the latencies simulated.
+// Point the host of the connector of the assistant to the mock to measure the pipeline without the LLM.
+type Mock struct {
+	Latency         time.Duration // The latency before the first chunk
+	Jitter          time.Duration // The random latency added to the latency, up to the jitter
+	Chunks          int           // The chunks of the reply
+	ChunkLatency    time.Duration // The latency between the chunks
+	ToolProbability float64       // The probability of calling a tool of the request, 0 to 1
+}
+
+type mockRequest struct {
+	Stream bool `json:"stream"`
+	Tools  []struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools"`
+}
+
+// ServeHTTP serve the chat completions requests
+func (mock *Mock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req mockRequest
+	if err := jsoniter.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"invalid request body","type":"invalid_request_error"}}`))
+		return
+	}
+
+	delay := mock.Latency
+	if mock.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(mock.Jitter)))
+	}
+	if !sleep(r, delay) {
+		return
+	}
+
+	tool := ""
+	if len(req.Tools) > 0 && rand.Float64() < mock.ToolProbability {
+		tool = req.Tools[rand.Intn(len(req.Tools))].Function.Name
+	}
+
+	chunks := mock.Chunks
+	if chunks <= 0 {
+		chunks = 1
+	}
+
+	id := fmt.Sprintf("chatcmpl-mock-%d", time.Now().UnixNano())
+	if !req.Stream {
+		message := map[string]interface{}{"role": "assistant", "content": words(chunks)}
+		reason := "stop"
+		if tool != "" {
+			message = map[string]interface{}{"role": "assistant", "tool_calls": []interface{}{toolCall(id, tool)}}
+			reason = "tool_calls"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		jsoniter.NewEncoder(w).Encode(map[string]interface{}{
+			"id": id, "object": "chat.completion", "model": "mock",
+			"choices": []interface{}{map[string]interface{}{"index": 0, "message": message, "finish_reason": reason}},
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	write := func(delta map[string]interface{}, reason interface{}) {
+		raw, _ := jsoniter.Marshal(map[string]interface{}{
+			"id": id, "object": "chat.completion.chunk", "model": "mock",
+			"choices": []interface{}{map[string]interface{}{"index": 0, "delta": delta, "finish_reason": reason}},
+		})
+		fmt.Fprintf(w, "data: %s\n\n", raw)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if tool != "" {
+		write(map[string]interface{}{"role": "assistant", "tool_calls": []interface{}{toolCall(id, tool)}}, nil)
+		write(map[string]interface{}{}, "tool_calls")
+	} else {
+		for i := 0; i < chunks; i++ {
+			if i > 0 && !sleep(r, mock.ChunkLatency) {
+				return
+			}
+			write(map[string]interface{}{"role": "assistant", "content": words(1)}, nil)
+		}
+		write(map[string]interface{}{}, "stop")
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func toolCall(id string, name string) map[string]interface{} {
+	return map[string]interface{}{
+		"index": 0, "id": "call_" + id, "type": "function",
+		"function": map[string]interface{}{"name": name, "arguments": "{}"},
+	}
+}
+
+// sleep wait for the duration, false if the request is canceled
+func sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+var mockWords = []string{"the", "order", "is", "refunded", "and", "the", "customer", "is", "notified", "today"}
+
+func words(n int) string {
+	res := make([]string, n)
+	for i := range res {
+		res[i] = mockWords[rand.Intn(len(mockWords))]
+	}
+	return strings.Join(res, " ") + " "
+}
@@ -0,0 +1,137 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report the report of the benchmark
+type Report struct {
+	Turns      int            `json:"turns"`
+	Errors     int            `json:"errors"`
+	Elapsed    time.Duration  `json:"elapsed"`
+	Throughput float64        `json:"throughput"` // The turns per second
+	Latency    Percentiles    `json:"latency"`
+	FirstToken Percentiles    `json:"first_token"`
+	Scenarios  map[string]int `json:"scenarios"` // The turns of the scenarios
+	Failures   map[string]int `json:"failures"`  // The count of the errors
+	latencies  []time.Duration
+	firsts     []time.Duration
+}
+
+// Percentiles the percentiles of the durations of the successful turns
+type Percentiles struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+func newReport() *Report {
+	return &Report{Scenarios: map[string]int{}, Failures: map[string]int{}}
+}
+
+// add add the result of the turn
+func (report *Report) add(result Result) {
+	report.Turns++
+	report.Scenarios[result.Scenario]++
+	if result.Err != nil {
+		report.Errors++
+		report.Failures[result.Err.Error()]++
+		return
+	}
+	report.latencies = append(report.latencies, result.Latency)
+	report.firsts = append(report.firsts, result.FirstToken)
+}
+
+// finish compute the throughput and the percentiles
+func (report *Report) finish(elapsed time.Duration) {
+	report.Elapsed = elapsed
+	if elapsed > 0 {
+		report.Throughput = float64(report.Turns-report.Errors) / elapsed.Seconds()
+	}
+	report.Latency = percentiles(report.latencies)
+	report.FirstToken = percentiles(report.firsts)
+}
+
+// percentiles the percentiles of the durations by the nearest rank
+func percentiles(durations []time.Duration) Percentiles {
+	if len(durations) == 0 {
+		return Percentiles{}
+	}
+
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+
+	rank := func(p float64) time.Duration {
+		i := int(p*float64(len(sorted))+0.999999) - 1
+		if i < 0 {
+			i = 0
+		}
+		return sorted[i]
+	}
+
+	return Percentiles{
+		Min:  sorted[0],
+		Mean: total / time.Duration(len(sorted)),
+		P50:  rank(0.50),
+		P90:  rank(0.90),
+		P95:  rank(0.95),
+		P99:  rank(0.99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+// Print print the report
+func (report *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "Turns:       %d (%d errors)\n", report.Turns, report.Errors)
+	fmt.Fprintf(w, "Elapsed:     %s\n", report.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "Throughput:  %.2f turns/s\n", report.Throughput)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%-12s %10s %10s %10s %10s %10s %10s %10s\n", "", "min", "mean", "p50", "p90", "p95", "p99", "max")
+	for _, row := range []struct {
+		name string
+		p    Percentiles
+	}{{"Latency", report.Latency}, {"First token", report.FirstToken}} {
+		fmt.Fprintf(w, "%-12s %10s %10s %10s %10s %10s %10s %10s\n", row.name,
+			ms(row.p.Min), ms(row.p.Mean), ms(row.p.P50), ms(row.p.P90), ms(row.p.P95), ms(row.p.P99), ms(row.p.Max))
+	}
+
+	if len(report.Scenarios) > 0 {
+		fmt.Fprintln(w)
+		for _, name := range sortedKeys(report.Scenarios) {
+			fmt.Fprintf(w, "Scenario %-20s %d turns\n", name, report.Scenarios[name])
+		}
+	}
+
+	if len(report.Failures) > 0 {
+		fmt.Fprintln(w)
+		for _, message := range sortedKeys(report.Failures) {
+			fmt.Fprintf(w, "Error %5d  %s\n", report.Failures[message], strings.SplitN(message, "\n", 2)[0])
+		}
+	}
+}
+
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
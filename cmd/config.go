@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/spf13/cobra"
+	"github.com/yaoapp/yao/config"
+)
+
+var configResolved bool
+var configJSON bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: L("Show the configuration"),
+	Long:  L("Show the configuration"),
+	Args:  cobra.MinimumNArgs(1),
+	CompletionOptions: cobra.CompletionOptions{
+		DisableDefaultCmd: true,
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(os.Stderr, L("One or more arguments are not correct"), args)
+		os.Exit(1)
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: L("Show the configuration, the secrets are masked"),
+	Long:  L("Show the settings of the .env files and the environment, or the effective configuration with --resolved"),
+	Example: `  yao config show
+  yao config show --resolved --profile staging
+  yao config show --resolved --json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Boot()
+		settings := config.Resolved(config.Conf, configResolved)
+
+		if configJSON {
+			raw, _ := jsoniter.MarshalIndent(map[string]interface{}{"profile": config.Profile, "settings": settings}, "", "  ")
+			fmt.Println(string(raw))
+			return
+		}
+
+		name := config.Profile
+		if name == "" {
+			name = "-"
+		}
+		fmt.Println(color.WhiteString(L("Profile: %s"), name))
+		for _, setting := range settings {
+			fmt.Println(color.GreenString("%-30s", setting.Name), color.WhiteString("%-40s", setting.Value), color.HiBlackString(setting.Source))
+		}
+	},
+}
+
+func init() {
+	configShowCmd.PersistentFlags().BoolVar(&configResolved, "resolved", false, L("Show the effective configuration with the defaults"))
+	configShowCmd.PersistentFlags().BoolVar(&configJSON, "json", false, L("Show the configuration as JSON"))
+	configCmd.AddCommand(configShowCmd)
+}
@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/plugin"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/engine"
+	"github.com/yaoapp/yao/share"
+	"golang.org/x/term"
+)
+
+// consoleMethods the model processes are completed with
+var consoleMethods = []string{"find", "get", "paginate", "create", "update", "save", "delete", "destroy", "insert", "updatewhere", "deletewhere", "destroywhere", "eachsave", "selectoption"}
+
+// consoleCommands the builtin commands of the console
+var consoleCommands = []string{".help", ".models", ".model", ".processes", ".output", ".exit"}
+
+const consoleHelp = `Call a process with the arguments, the JSON arguments are parsed, others are strings:
+
+  models.pet.Find 1 {"select": ["id", "name"]}
+  models.pet.Get {"wheres": [{"column": "status", "value": "checked"}], "limit": 5}
+  scripts.pet.Hello "world"
+
+Commands:
+
+  .models                 List the loaded models
+  .model <id>             Show the columns of the model
+  .processes [prefix]     List the registered processes
+  .output json|yaml|table Change the output format, json by default
+  .exit                   Exit the console, or press Ctrl+D
+
+Press Tab to complete the process names, Up and Down to browse the history.
+`
+
+var consoleCmd = &cobra.Command{
+	Use:   "console",
+	Short: L("Interactive console of the processes and the models"),
+	Long:  L("Interactive console of the processes and the models"),
+	Run: func(cmd *cobra.Command, args []string) {
+		defer share.SessionStop()
+		defer plugin.KillAll()
+
+		Boot()
+
+		config.Conf.Runtime.Mode = "standard"
+		cfg := config.Conf
+		cfg.Session.IsCLI = true
+
+		err := engine.Load(cfg, engine.LoadOption{Action: "console"})
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		console := &console{format: "json", names: consoleNames()}
+
+		// The input is piped, e.g. yao console < commands.txt
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			scanner := bufio.NewScanner(os.Stdin)
+			scanner.Buffer(make([]byte, 0, 64*1024), 10<<20)
+			for scanner.Scan() {
+				if !console.exec(os.Stdout, scanner.Text()) {
+					return
+				}
+			}
+			return
+		}
+
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+		defer term.Restore(fd, state)
+
+		terminal := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}, color.CyanString("yao> "))
+		terminal.AutoCompleteCallback = console.complete
+
+		fmt.Fprintf(terminal, "%s %s, %s\n", share.BUILDNAME, share.VERSION, L("type .help for the commands"))
+		for {
+			line, err := terminal.ReadLine()
+			if err != nil {
+				return
+			}
+
+			if !console.exec(terminal, line) {
+				return
+			}
+		}
+	},
+}
+
+type console struct {
+	format string
+	names  []string
+}
+
+// exec the line, returns false to exit the console
+func (console *console) exec(w io.Writer, line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "//") {
+		return true
+	}
+
+	if strings.HasPrefix(line, ".") {
+		return console.command(w, line)
+	}
+
+	name, args, err := consoleArgs(line)
+	if err != nil {
+		fmt.Fprintln(w, color.RedString(L("Arguments: %s"), err.Error()))
+		return true
+	}
+
+	res, err := consoleCall(name, args)
+	if err != nil {
+		fmt.Fprintln(w, color.RedString("%s", err.Error()))
+		return true
+	}
+
+	err = output(w, console.format, res)
+	if err != nil {
+		fmt.Fprintln(w, color.RedString("%s", err.Error()))
+	}
+	return true
+}
+
+// command run the builtin command
+func (console *console) command(w io.Writer, line string) bool {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ".exit", ".quit":
+		return false
+
+	case ".help":
+		fmt.Fprint(w, consoleHelp)
+
+	case ".models":
+		ids := make([]string, 0, len(model.Models))
+		for id := range model.Models {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		rows := []map[string]interface{}{}
+		for _, id := range ids {
+			mod := model.Models[id]
+			rows = append(rows, map[string]interface{}{"id": id, "name": mod.MetaData.Name, "table": mod.MetaData.Table.Name})
+		}
+		table(w, rows)
+
+	case ".model":
+		if len(fields) < 2 {
+			fmt.Fprintln(w, color.RedString(L("Usage: %s"), ".model <id>"))
+			return true
+		}
+
+		mod, has := model.Models[fields[1]]
+		if !has {
+			fmt.Fprintln(w, color.RedString(L("Model %s not found"), fields[1]))
+			return true
+		}
+
+		rows := []map[string]interface{}{}
+		for _, column := range mod.MetaData.Columns {
+			rows = append(rows, map[string]interface{}{"name": column.Name, "type": column.Type, "label": column.Label, "nullable": column.Nullable})
+		}
+		table(w, rows)
+
+	case ".processes":
+		prefix := ""
+		if len(fields) > 1 {
+			prefix = strings.ToLower(fields[1])
+		}
+		for _, name := range console.names {
+			if strings.HasPrefix(name, prefix) {
+				fmt.Fprintln(w, name)
+			}
+		}
+
+	case ".output":
+		if len(fields) < 2 || (fields[1] != "json" && fields[1] != "yaml" && fields[1] != "table") {
+			fmt.Fprintln(w, color.RedString(L("Usage: %s"), ".output json|yaml|table"))
+			return true
+		}
+		console.format = fields[1]
+
+	default:
+		fmt.Fprintln(w, color.RedString(L("Unknown command %s, type .help for the commands"), fields[0]))
+	}
+	return true
+}
+
+// complete the process name or the command at the cursor when the Tab key is pressed
+func (console *console) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' || strings.ContainsAny(line[:pos], " \t") {
+		return "", 0, false
+	}
+
+	prefix := strings.ToLower(line[:pos])
+	candidates := console.names
+	if strings.HasPrefix(prefix, ".") {
+		candidates = consoleCommands
+	}
+
+	matches := []string{}
+	for _, name := range candidates {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+
+	completed := commonPrefix(matches)
+	if len(completed) <= len(prefix) {
+		return "", 0, false
+	}
+	return completed + line[pos:], len(completed), true
+}
+
+// consoleNames the names of the registered processes and the model processes, sorted
+func consoleNames() []string {
+	names := []string{}
+	for name := range process.Handlers {
+		names = append(names, strings.ToLower(name))
+	}
+
+	for id := range model.Models {
+		for _, method := range consoleMethods {
+			names = append(names, fmt.Sprintf("models.%s.%s", strings.ToLower(id), method))
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// consoleArgs parse the line to the process name and the arguments, the JSON values are decoded, others are strings
+func consoleArgs(line string) (string, []interface{}, error) {
+	name, rest, _ := strings.Cut(line, " ")
+	args := []interface{}{}
+
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			return name, args, nil
+		}
+
+		// The objects, the arrays and the quoted strings may contain spaces
+		if strings.ContainsRune(`{["`, rune(rest[0])) {
+			decoder := json.NewDecoder(strings.NewReader(rest))
+			var v interface{}
+			err := decoder.Decode(&v)
+			if err != nil {
+				return "", nil, err
+			}
+			args = append(args, v)
+			rest = rest[decoder.InputOffset():]
+			continue
+		}
+
+		token := rest
+		if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+			token = rest[:i]
+		}
+		rest = rest[len(token):]
+
+		var v interface{}
+		if err := json.Unmarshal([]byte(token), &v); err == nil {
+			args = append(args, v)
+			continue
+		}
+		args = append(args, token)
+	}
+}
+
+// consoleCall run the process, the exceptions are returned as the errors
+func consoleCall(name string, args []interface{}) (res interface{}, err error) {
+	defer func() {
+		if ex := exception.Catch(recover()); ex != nil {
+			err = ex
+		}
+	}()
+
+	return process.NewWithContext(context.Background(), name, args...).Exec()
+}
+
+func commonPrefix(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/engine"
+	"github.com/yaoapp/yao/setup"
+)
+
+var initRegistry string
+var initList bool
+var initVars []string
+var initName string
+var initDBDriver string
+var initDBPrimary string
+
+var initCmd = &cobra.Command{
+	Use:   "init [template]",
+	Short: L("Initialize project"),
+	Long:  L("Initialize project"),
+	Example: `  yao init
+  yao init --list
+  yao init crm --name mycrm --db-driver mysql --db-primary "root:123456@tcp(127.0.0.1:3306)/crm?charset=utf8mb4&parseTime=True&loc=Local"
+  yao init knowledge-bot --var OPENAI_KEY=sk-xxx`,
+	Run: func(cmd *cobra.Command, args []string) {
+		Boot()
+
+		registry := initRegistry
+		if registry == "" {
+			registry = os.Getenv("YAO_TEMPLATE_REGISTRY")
+		}
+		if registry == "" {
+			registry = setup.DefaultRegistry
+		}
+
+		if initList {
+			templates, err := setup.Templates(registry)
+			if err != nil {
+				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+				os.Exit(1)
+			}
+			for _, tmpl := range templates {
+				fmt.Println(color.GreenString("%-20s", tmpl.Name), color.WhiteString(tmpl.Description))
+				for _, v := range tmpl.Variables {
+					fmt.Println(color.WhiteString("  --var %s=%s", v.Name, v.Default), color.HiBlackString(v.Description))
+				}
+			}
+			return
+		}
+
+		root := config.Conf.Root
+		if err := os.MkdirAll(root, os.ModePerm); err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		if !setup.IsEmptyDir(root) {
+			fmt.Println(color.RedString(L("The directory %s is not empty"), root))
+			os.Exit(1)
+		}
+
+		if setup.InYaoApp(root) {
+			fmt.Println(color.RedString(L("Please run the command in the root directory of project")))
+			os.Exit(1)
+		}
+
+		// The built-in demo
+		if len(args) == 0 {
+			if err := install(); err != nil {
+				fmt.Println(color.RedString(L("Install: %s"), err.Error()))
+				os.Exit(1)
+			}
+			fmt.Println(color.GreenString(L("✨DONE✨")))
+			return
+		}
+
+		vars, err := templateVars()
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		fmt.Println(color.WhiteString("Template: %s (%s)", args[0], registry))
+		if err := setup.InstallTemplate(root, registry, args[0], vars); err != nil {
+			fmt.Println(color.RedString(L("Install: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		// Migrate and run the setup hook of the template
+		Boot()
+		err = engine.Load(config.Conf, engine.LoadOption{Action: "init"})
+		if err != nil {
+			fmt.Println(color.RedString(L("Install: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		if err := setup.Initialize(root, config.Conf); err != nil {
+			fmt.Println(color.RedString(L("Install: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		fmt.Println(color.GreenString(L("✨DONE✨")))
+		fmt.Println(color.WhiteString(L("NEXT:")), color.GreenString("yao start"))
+	},
+}
+
+// templateVars the variables of the template, the flags of the built-in variables override the --var flags
+func templateVars() (map[string]string, error) {
+	vars := map[string]string{}
+	for _, v := range initVars {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("the variable %s is invalid, e.g. --var NAME=value", v)
+		}
+		vars[strings.TrimSpace(name)] = value
+	}
+
+	if initName != "" {
+		vars["APP_NAME"] = initName
+	}
+	if initDBDriver != "" {
+		vars["DB_DRIVER"] = initDBDriver
+	}
+	if initDBPrimary != "" {
+		vars["DB_PRIMARY"] = initDBPrimary
+	}
+	return vars, nil
+}
+
+func init() {
+	initCmd.PersistentFlags().StringVar(&initRegistry, "registry", "", L("The registry of the templates, the URL or the path of the index"))
+	initCmd.PersistentFlags().BoolVarP(&initList, "list", "l", false, L("List the templates of the registry"))
+	initCmd.PersistentFlags().StringArrayVar(&initVars, "var", []string{}, L("The variable of the template, e.g. --var NAME=value"))
+	initCmd.PersistentFlags().StringVar(&initName, "name", "", L("The name of the application"))
+	initCmd.PersistentFlags().StringVar(&initDBDriver, "db-driver", "", L("The driver of the default connector, sqlite3, mysql or postgres"))
+	initCmd.PersistentFlags().StringVar(&initDBPrimary, "db-primary", "", L("The DSN of the default connector"))
+}
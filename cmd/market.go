@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/engine"
+	"github.com/yaoapp/yao/market"
+)
+
+var marketRegistry string
+var marketVersion string
+var marketForce bool
+
+var marketListCmd = &cobra.Command{
+	Use:   "list",
+	Short: L("List the packages"),
+	Long:  L("List the packages installed, or the packages of the registry with --all"),
+	Run: func(cmd *cobra.Command, args []string) {
+		Boot()
+
+		installed, err := market.List(config.Conf.Root)
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		versions := map[string]string{}
+		for _, pkg := range installed {
+			versions[pkg.Name] = pkg.Version
+		}
+
+		if all, _ := cmd.Flags().GetBool("all"); !all {
+			for _, pkg := range installed {
+				fmt.Println(color.GreenString("%-24s", pkg.Name), color.WhiteString("%-10s", pkg.Version), color.HiBlackString("%d files", len(pkg.Files)))
+			}
+			return
+		}
+
+		packages, err := market.Packages(registryOf())
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		listed := map[string]bool{}
+		for _, pkg := range packages {
+			if listed[pkg.Name] {
+				continue
+			}
+			listed[pkg.Name] = true
+
+			state := ""
+			if version, has := versions[pkg.Name]; has {
+				state = fmt.Sprintf("(installed %s)", version)
+			}
+			fmt.Println(color.GreenString("%-24s", pkg.Name), color.WhiteString("%-10s", pkg.Version), color.WhiteString(pkg.Description), color.YellowString(state))
+		}
+	},
+}
+
+var marketInstallCmd = &cobra.Command{
+	Use:   "install <package>",
+	Short: L("Install the package"),
+	Long:  L("Install the package of the registry, migrate the models of the package and roll back if failed"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		inst := marketInstaller()
+		installed, err := inst.Install(args[0], marketVersion)
+		if err != nil {
+			fmt.Println(color.RedString(L("Install: %s"), err.Error()))
+			os.Exit(1)
+		}
+		fmt.Println(color.GreenString(L("%s %s is installed, %d files"), installed.Name, installed.Version, len(installed.Files)))
+	},
+}
+
+var marketUpgradeCmd = &cobra.Command{
+	Use:   "upgrade <package>",
+	Short: L("Upgrade the package"),
+	Long:  L("Upgrade the package to the latest version, migrate the models of the package and roll back if failed"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		inst := marketInstaller()
+		installed, err := inst.Upgrade(args[0], marketVersion)
+		if err != nil {
+			fmt.Println(color.RedString(L("Upgrade: %s"), err.Error()))
+			os.Exit(1)
+		}
+		fmt.Println(color.GreenString(L("%s is upgraded to %s"), installed.Name, installed.Version))
+	},
+}
+
+var marketRemoveCmd = &cobra.Command{
+	Use:   "remove <package>",
+	Short: L("Remove the package"),
+	Long:  L("Remove the files of the package, the tables of the models are kept"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		Boot()
+		inst := &market.Installer{Root: config.Conf.Root, Registry: registryOf()}
+		if err := inst.Remove(args[0]); err != nil {
+			fmt.Println(color.RedString(L("Remove: %s"), err.Error()))
+			os.Exit(1)
+		}
+		fmt.Println(color.GreenString(L("%s is removed"), args[0]))
+	},
+}
+
+// marketInstaller load the application for the migrations of the packages
+func marketInstaller() *market.Installer {
+	Boot()
+	if !marketForce && config.Conf.Mode == "production" {
+		fmt.Println(color.WhiteString(L("TRY:")), color.GreenString("--force"))
+		fmt.Println(color.RedString(L("Migrate is not allowed on production mode.")))
+		os.Exit(1)
+	}
+
+	err := engine.Load(config.Conf, engine.LoadOption{Action: "migrate"})
+	if err != nil {
+		fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+		os.Exit(1)
+	}
+	return &market.Installer{Root: config.Conf.Root, Registry: registryOf()}
+}
+
+// registryOf the registry of the flag, YAO_MARKET_REGISTRY or the default registry
+func registryOf() string {
+	if marketRegistry != "" {
+		return marketRegistry
+	}
+	if registry := os.Getenv("YAO_MARKET_REGISTRY"); registry != "" {
+		return registry
+	}
+	return market.DefaultRegistry
+}
+
+func init() {
+	marketCmd.PersistentFlags().StringVar(&marketRegistry, "registry", "", L("The registry of the packages, the URL or the path of the index"))
+	marketListCmd.Flags().Bool("all", false, L("List the packages of the registry"))
+	marketInstallCmd.Flags().StringVar(&marketVersion, "version", "", L("The version of the package, e.g. 1.2.0 or >=1.0.0 <2.0.0"))
+	marketInstallCmd.Flags().BoolVar(&marketForce, "force", false, L("Force migrate"))
+	marketUpgradeCmd.Flags().StringVar(&marketVersion, "version", "", L("The version of the package, e.g. 1.2.0 or >=1.0.0 <2.0.0"))
+	marketUpgradeCmd.Flags().BoolVar(&marketForce, "force", false, L("Force migrate"))
+}
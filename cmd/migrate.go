@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -9,8 +10,10 @@ import (
 	"github.com/yaoapp/gou/model"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/clickhouse"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/engine"
+	yaomodel "github.com/yaoapp/yao/model"
 	"github.com/yaoapp/yao/share"
 )
 
@@ -44,6 +47,17 @@ var migrateCmd = &cobra.Command{
 		}
 
 		if name != "" {
+			if table, has := clickhouse.Model(name); has {
+				fmt.Printf(color.WhiteString(L("Update schema model: %s (%s) "), name, table.Name) + "\t")
+				err := table.Migrate(context.Background(), resetModel)
+				if err != nil {
+					fmt.Printf(color.RedString(L("FAILURE\n%s"), err.Error()) + "\n")
+					return
+				}
+				fmt.Printf(color.GreenString(L("SUCCESS")) + "\n")
+				return
+			}
+
 			mod, has := model.Models[name]
 			if !has {
 				fmt.Println(color.RedString(L("Model: %s does not exits"), name))
@@ -65,12 +79,24 @@ var migrateCmd = &cobra.Command{
 				return
 			}
 
+			err = yaomodel.MigrateAssociations(name, resetModel)
+			if err != nil {
+				fmt.Printf(color.RedString(L("FAILURE\n%s"), err.Error()) + "\n")
+				return
+			}
+
+			err = yaomodel.MigrateVectors(name, resetModel)
+			if err != nil {
+				fmt.Printf(color.RedString(L("FAILURE\n%s"), err.Error()) + "\n")
+				return
+			}
+
 			fmt.Printf(color.GreenString(L("SUCCESS")) + "\n")
 			return
 		}
 
 		// Do Stuff Here
-		for _, mod := range model.Models {
+		for id, mod := range model.Models {
 			fmt.Printf(color.WhiteString(L("Update schema model: %s (%s) "), mod.Name, mod.MetaData.Table.Name) + "\t")
 
 			if resetModel {
@@ -86,6 +112,29 @@ var migrateCmd = &cobra.Command{
 				fmt.Printf(color.RedString(L("FAILURE\n%s"), err.Error()) + "\n")
 				continue
 			}
+
+			err = yaomodel.MigrateAssociations(id, resetModel)
+			if err != nil {
+				fmt.Printf(color.RedString(L("FAILURE\n%s"), err.Error()) + "\n")
+				continue
+			}
+
+			err = yaomodel.MigrateVectors(id, resetModel)
+			if err != nil {
+				fmt.Printf(color.RedString(L("FAILURE\n%s"), err.Error()) + "\n")
+				continue
+			}
+			fmt.Printf(color.GreenString(L("SUCCESS")) + "\n")
+		}
+
+		// The ClickHouse models
+		for id, table := range clickhouse.Models() {
+			fmt.Printf(color.WhiteString(L("Update schema model: %s (%s) "), id, table.Name) + "\t")
+			err := table.Migrate(context.Background(), resetModel)
+			if err != nil {
+				fmt.Printf(color.RedString(L("FAILURE\n%s"), err.Error()) + "\n")
+				continue
+			}
 			fmt.Printf(color.GreenString(L("SUCCESS")) + "\n")
 		}
 
@@ -8,21 +8,35 @@ import (
 	"strings"
 
 	"github.com/fatih/color"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/spf13/cobra"
+	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/gou/application/yaz"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/pack"
+	"github.com/yaoapp/yao/share"
 )
 
 var packOutput = ""
 var packLicense = ""
+var packBinary = false
+var packExcludes = []string{}
+var packVerify = ""
 
 var packCmd = &cobra.Command{
 	Use:   "pack",
 	Short: L("Package the application"),
 	Long:  L("Package the application into a single file"),
+	Example: `  yao pack
+  yao pack --binary --exclude public/uploads
+  yao pack --verify dist/app.yaz`,
 	Run: func(cmd *cobra.Command, args []string) {
 
+		if packVerify != "" {
+			verifyPackage(packVerify)
+			return
+		}
+
 		cfg := config.Conf
 		output, err := filepath.Abs(filepath.Join(cfg.Root, "dist"))
 		if err != nil {
@@ -67,13 +81,32 @@ var packCmd = &cobra.Command{
 			}
 		}
 
+		// Stage the files of the application with the manifest
+		stage, err := os.MkdirTemp("", "yao-pack-*")
+		if err != nil {
+			color.Red(err.Error())
+			os.Exit(1)
+		}
+		defer os.RemoveAll(stage)
+
+		excludes := append(append([]string{}, pack.Excludes...), packExcludes...)
+		if rel, err := filepath.Rel(cfg.Root, output); err == nil && !strings.HasPrefix(rel, "..") && rel != "." {
+			excludes = append(excludes, rel)
+		}
+
+		manifest, err := pack.Stage(cfg.Root, stage, fmt.Sprintf("%s %s", share.VERSION, share.PRVERSION), excludes)
+		if err != nil {
+			color.Red(err.Error())
+			os.Exit(1)
+		}
+
 		os.Remove(outputFile)
 		if packLicense != "" {
 			pack.SetCipher(packLicense)
-			err = yaz.PackTo(cfg.Root, outputFile, pack.Cipher)
+			err = yaz.PackTo(stage, outputFile, pack.Cipher)
 
 		} else {
-			err = yaz.CompressTo(cfg.Root, outputFile)
+			err = yaz.CompressTo(stage, outputFile)
 		}
 
 		if err != nil {
@@ -81,11 +114,68 @@ var packCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		// The manifest beside the package
+		raw, _ := jsoniter.MarshalIndent(manifest, "", "  ")
+		if err := os.WriteFile(filepath.Join(output, pack.ManifestFile), raw, 0644); err != nil {
+			color.Red(err.Error())
+			os.Exit(1)
+		}
+
 		color.Green("Packaged to %s", outputFile)
+		fmt.Println(color.WhiteString("Application: %s %s", manifest.Name, manifest.Version))
+		fmt.Println(color.WhiteString("Files:       %d", len(manifest.Files)))
+		fmt.Println(color.WhiteString("Digest:      %s", manifest.Digest))
+
+		if packBinary {
+			engine, err := os.Executable()
+			if err != nil {
+				color.Red(err.Error())
+				os.Exit(1)
+			}
+
+			name := manifest.Name
+			if name == "" {
+				name = filepath.Base(cfg.Root)
+			}
+			binary := filepath.Join(output, strings.ReplaceAll(strings.ToLower(name), " ", "-"))
+			if err := pack.Embed(engine, outputFile, binary); err != nil {
+				color.Red(err.Error())
+				os.Exit(1)
+			}
+			color.Green("Binary %s", binary)
+		}
 	},
 }
 
+// verifyPackage verify the files of the package with the manifest packed
+func verifyPackage(file string) {
+	if packLicense != "" {
+		pack.SetCipher(packLicense)
+	}
+
+	app, err := application.OpenFromYazFile(file, pack.Cipher)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+
+	manifest, err := pack.Verify(app)
+	if err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+
+	color.Green("%s is verified", file)
+	fmt.Println(color.WhiteString("Application: %s %s", manifest.Name, manifest.Version))
+	fmt.Println(color.WhiteString("Engine:      %s", manifest.Engine))
+	fmt.Println(color.WhiteString("Files:       %d", len(manifest.Files)))
+	fmt.Println(color.WhiteString("Digest:      %s", manifest.Digest))
+}
+
 func init() {
 	packCmd.PersistentFlags().StringVarP(&packOutput, "output", "o", "", L("Output Directory"))
 	packCmd.PersistentFlags().StringVarP(&packLicense, "license", "l", "", L("Pack with the license"))
+	packCmd.PersistentFlags().BoolVarP(&packBinary, "binary", "b", false, L("Embed the package in the binary"))
+	packCmd.PersistentFlags().StringArrayVar(&packExcludes, "exclude", []string{}, L("The files not packed, e.g. --exclude public/uploads"))
+	packCmd.PersistentFlags().StringVar(&packVerify, "verify", "", L("Verify the package with the manifest"))
 }
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/plugin"
+)
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: L("Manage the plugins"),
+	Long:  L("Manage the plugins"),
+	Args:  cobra.MinimumNArgs(1),
+	CompletionOptions: cobra.CompletionOptions{
+		DisableDefaultCmd: true,
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(os.Stderr, L("One or more arguments are not correct"), args)
+		os.Exit(1)
+	},
+}
+
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: L("List the plugins with the manifest"),
+	Long:  L("List the plugins with the manifest"),
+	Run: func(cmd *cobra.Command, args []string) {
+		Boot()
+		manifests := pluginManifests()
+		if len(manifests) == 0 {
+			fmt.Println(color.YellowString(L("No plugins found")))
+			return
+		}
+
+		for _, manifest := range manifests {
+			status := color.GreenString(L("ENABLED"))
+			if manifest.Disabled {
+				status = color.YellowString(L("DISABLED"))
+			} else if file, err := manifest.File(); err != nil {
+				status = color.RedString(err.Error())
+			} else if err := manifest.Verify(file); err != nil {
+				status = color.RedString(err.Error())
+			}
+
+			fmt.Println(color.WhiteString("%s %s", manifest.ID, manifest.Version), "\t", status)
+			if len(manifest.Processes) > 0 {
+				fmt.Println(color.CyanString("  plugins.%s.{%s}", manifest.ID, strings.Join(manifest.Processes, ",")))
+			}
+		}
+	},
+}
+
+var pluginEnableCmd = &cobra.Command{
+	Use:   "enable <id>",
+	Short: L("Enable the plugin, applied on the next start"),
+	Long:  L("Enable the plugin, applied on the next start"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		Boot()
+		setPluginDisabled(args[0], false)
+	},
+}
+
+var pluginDisableCmd = &cobra.Command{
+	Use:   "disable <id>",
+	Short: L("Disable the plugin, applied on the next start"),
+	Long:  L("Disable the plugin, applied on the next start"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		Boot()
+		setPluginDisabled(args[0], true)
+	},
+}
+
+func pluginManifests() []*plugin.Manifest {
+	root, err := plugin.Root(config.Conf)
+	if err != nil {
+		fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+		os.Exit(1)
+	}
+
+	manifests, err := plugin.Manifests(root)
+	if err != nil {
+		fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+		os.Exit(1)
+	}
+	return manifests
+}
+
+func setPluginDisabled(id string, disabled bool) {
+	for _, manifest := range pluginManifests() {
+		if manifest.ID != id {
+			continue
+		}
+
+		manifest.Disabled = disabled
+		if err := manifest.Save(); err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+		fmt.Println(color.GreenString(L("SUCCESS")))
+		return
+	}
+
+	fmt.Println(color.RedString(L("Fatal: %s"), fmt.Sprintf("plugin %s is not found", id)))
+	os.Exit(1)
+}
+
+func init() {
+	pluginCmd.AddCommand(pluginListCmd)
+	pluginCmd.AddCommand(pluginEnableCmd)
+	pluginCmd.AddCommand(pluginDisableCmd)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/cmd/bench"
 	"github.com/yaoapp/yao/cmd/sui"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/pack"
@@ -14,6 +15,7 @@ import (
 )
 
 var appPath string
+var profile string
 var yazFile string
 var licenseKey string
 
@@ -128,6 +130,34 @@ var suiCmd = &cobra.Command{
 	},
 }
 
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: L("Benchmark the application"),
+	Long:  L("Benchmark the application"),
+	Args:  cobra.MinimumNArgs(1),
+	CompletionOptions: cobra.CompletionOptions{
+		DisableDefaultCmd: true,
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(os.Stderr, L("One or more arguments are not correct"), args)
+		os.Exit(1)
+	},
+}
+
+var marketCmd = &cobra.Command{
+	Use:   "market",
+	Short: L("Install the packages of the registry"),
+	Long:  L("Install, upgrade and remove the packages of the registry"),
+	Args:  cobra.MinimumNArgs(1),
+	CompletionOptions: cobra.CompletionOptions{
+		DisableDefaultCmd: true,
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(os.Stderr, L("One or more arguments are not correct"), args)
+		os.Exit(1)
+	},
+}
+
 // Command initialize
 func init() {
 
@@ -138,8 +168,18 @@ func init() {
 	suiCmd.AddCommand(sui.BuildCmd)
 	suiCmd.AddCommand(sui.TransCmd)
 
+	// Bench
+	benchCmd.AddCommand(bench.AgentCmd)
+
+	// Market
+	marketCmd.AddCommand(marketListCmd)
+	marketCmd.AddCommand(marketInstallCmd)
+	marketCmd.AddCommand(marketUpgradeCmd)
+	marketCmd.AddCommand(marketRemoveCmd)
+
 	rootCmd.AddCommand(
 		versionCmd,
+		initCmd,
 		migrateCmd,
 		inspectCmd,
 		startCmd,
@@ -149,13 +189,22 @@ func init() {
 		// restoreCmd,
 		// socketCmd,
 		// websocketCmd,
-		// packCmd,
+		packCmd,
 		// studioCmd,
 		suiCmd,
+		storeCmd,
+		pluginCmd,
+		typesCmd,
+		testCmd,
+		consoleCmd,
+		benchCmd,
+		marketCmd,
+		configCmd,
 		// upgradeCmd,
 	)
 	// rootCmd.SetHelpCommand(helpCmd)
 	rootCmd.PersistentFlags().StringVarP(&appPath, "app", "a", "", L("Application directory"))
+	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "p", "", L("The profile of the configuration, the .env.<profile> file overrides the .env file"))
 	rootCmd.PersistentFlags().StringVarP(&yazFile, "file", "f", "", L("Application package file"))
 	rootCmd.PersistentFlags().StringVarP(&licenseKey, "key", "k", "", L("Application license key"))
 }
@@ -180,6 +229,9 @@ func Boot() {
 		root = r
 	}
 
+	if profile != "" {
+		os.Setenv("YAO_PROFILE", profile)
+	}
 	config.Conf = config.LoadFrom(filepath.Join(root, ".env"))
 
 	if share.BUILDIN {
@@ -187,6 +239,19 @@ func Boot() {
 		config.Conf.AppSource = "::binary"
 	}
 
+	// The package embedded by yao pack --binary
+	if !share.BUILDIN && yazFile == "" {
+		if exe, err := os.Executable(); err == nil {
+			file, err := pack.Embedded(exe)
+			if err != nil {
+				exception.New("Package error %s", 500, err.Error()).Throw()
+			}
+			if file != "" {
+				yazFile = file
+			}
+		}
+	}
+
 	if yazFile != "" {
 		os.Setenv("YAO_APP_SOURCE", yazFile)
 		config.Conf.AppSource = yazFile
@@ -2,8 +2,13 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/fatih/color"
 	jsoniter "github.com/json-iterator/go"
@@ -17,15 +22,25 @@ import (
 	ischedule "github.com/yaoapp/yao/schedule"
 	"github.com/yaoapp/yao/share"
 	itask "github.com/yaoapp/yao/task"
+	"gopkg.in/yaml.v3"
 )
 
 var runSilent = false
+var runOutput = ""
 
 var runCmd = &cobra.Command{
 	Use:   "run",
 	Short: L("Execute process"),
 	Long:  L("Execute process"),
 	Run: func(cmd *cobra.Command, args []string) {
+		// The exit code is set after the deferred cleanups
+		failed := false
+		defer func() {
+			if failed {
+				os.Exit(1)
+			}
+		}()
+
 		defer share.SessionStop()
 		defer plugin.KillAll()
 
@@ -42,6 +57,16 @@ var runCmd = &cobra.Command{
 
 		Boot()
 
+		// The result is written in the output format only
+		if runOutput != "" {
+			runSilent = true
+			if runOutput != "json" && runOutput != "yaml" && runOutput != "table" {
+				fmt.Fprintf(os.Stderr, L("the output format %s is not supported, json, yaml or table\n"), runOutput)
+				failed = true
+				return
+			}
+		}
+
 		// Set Runtime Mode
 		config.Conf.Runtime.Mode = "standard"
 
@@ -74,13 +99,28 @@ var runCmd = &cobra.Command{
 		}
 
 		pargs := []interface{}{}
+		stdin := -1
+		spread := false
 		for i, arg := range args {
 			if i == 0 {
 				continue
 			}
 
-			// Parse the arguments
-			if strings.HasPrefix(arg, "::") {
+			// Parse the arguments, - reads the argument from stdin, -... spreads the array read from stdin as the arguments
+			if arg == "-" || arg == "-..." {
+				if stdin >= 0 {
+					color.Red(L("Arguments: %s\n"), "only one argument could be read from stdin")
+					failed = true
+					return
+				}
+				stdin = len(pargs)
+				spread = arg == "-..."
+				pargs = append(pargs, nil)
+				if !runSilent {
+					color.White("args[%d]: <stdin>\n", i-1)
+				}
+
+			} else if strings.HasPrefix(arg, "::") {
 				arg := strings.TrimPrefix(arg, "::")
 				var v interface{}
 				err := jsoniter.Unmarshal([]byte(arg), &v)
@@ -118,48 +158,225 @@ var runCmd = &cobra.Command{
 		ischedule.Start()
 		defer ischedule.Stop()
 
-		process := process.NewWithContext(context.Background(), name, pargs...)
-		res, err := process.Exec()
-		if err != nil {
-			if !runSilent {
-				color.Red(L("Process: %s\n"), fmt.Sprintf("%s", strings.TrimPrefix(err.Error(), "Exception|404:")))
+		if stdin < 0 {
+			failed = !execProcess(name, pargs) && runOutput != ""
+			return
+		}
+
+		// Each JSON value of stdin runs the process once, the results are written as they are ready
+		decoder := json.NewDecoder(os.Stdin)
+		for {
+			var v interface{}
+			err := decoder.Decode(&v)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, L("Arguments: %s\n"), err.Error())
+				failed = true
 				return
 			}
-			fmt.Printf("%s\n", err.Error())
-			return
+
+			sargs, err := stdinArgs(pargs, stdin, v, spread)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, L("Arguments: %s\n"), err.Error())
+				failed = true
+				return
+			}
+
+			if !execProcess(name, sargs) {
+				failed = true
+			}
+		}
+	},
+}
+
+// stdinArgs the arguments with the value read from stdin, the value is an argument, or the arguments if spread
+func stdinArgs(args []interface{}, index int, value interface{}, spread bool) ([]interface{}, error) {
+	values := []interface{}{value}
+	if spread {
+		arr, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("the value read from stdin is not an array, it could not be spread")
+		}
+		values = arr
+	}
+
+	res := make([]interface{}, 0, len(args)+len(values))
+	res = append(res, args[:index]...)
+	res = append(res, values...)
+	res = append(res, args[index+1:]...)
+	return res, nil
+}
+
+// execProcess run the process and print the result, returns false if the process fails
+func execProcess(name string, pargs []interface{}) bool {
+	process := process.NewWithContext(context.Background(), name, pargs...)
+	res, err := process.Exec()
+	if err != nil {
+		if runOutput != "" {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			return false
 		}
 
 		if !runSilent {
-			color.White("--------------------------------------\n")
-			color.White(L("%s Response\n"), name)
-			color.White("--------------------------------------\n")
-			helper.Dump(res)
-			color.White("--------------------------------------\n")
-			color.Green(L("✨DONE✨\n"))
-			return
+			color.Red(L("Process: %s\n"), fmt.Sprintf("%s", strings.TrimPrefix(err.Error(), "Exception|404:")))
+			return false
+		}
+		fmt.Printf("%s\n", err.Error())
+		return false
+	}
+
+	if runOutput != "" {
+		err = output(os.Stdout, runOutput, res)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			return false
 		}
+		return true
+	}
 
-		// Silent mode output
-		switch res.(type) {
+	if !runSilent {
+		color.White("--------------------------------------\n")
+		color.White(L("%s Response\n"), name)
+		color.White("--------------------------------------\n")
+		helper.Dump(res)
+		color.White("--------------------------------------\n")
+		color.Green(L("✨DONE✨\n"))
+		return true
+	}
 
-		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
-			fmt.Printf("%v\n", res)
-			return
+	// Silent mode output
+	switch res.(type) {
 
-		case string, []byte:
-			fmt.Printf("%s\n", res)
-			return
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		fmt.Printf("%v\n", res)
+		return true
 
-		default:
-			txt, err := jsoniter.Marshal(res)
-			if err != nil {
-				fmt.Printf("%s\n", err.Error())
+	case string, []byte:
+		fmt.Printf("%s\n", res)
+		return true
+
+	default:
+		txt, err := jsoniter.Marshal(res)
+		if err != nil {
+			fmt.Printf("%s\n", err.Error())
+		}
+		fmt.Printf("%s\n", txt)
+	}
+	return true
+}
+
+// output write the result in the format json, yaml or table
+func output(w io.Writer, format string, res interface{}) error {
+	switch format {
+	case "json":
+		txt, err := jsoniter.MarshalIndent(res, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", txt)
+		return err
+
+	case "yaml":
+		// The result is converted to the JSON types first, e.g. the structs are written with the json tags
+		var v interface{}
+		txt, err := jsoniter.Marshal(res)
+		if err != nil {
+			return err
+		}
+		err = jsoniter.Unmarshal(txt, &v)
+		if err != nil {
+			return err
+		}
+		return yaml.NewEncoder(w).Encode(v)
+
+	case "table":
+		return table(w, res)
+	}
+
+	return fmt.Errorf("the output format %s is not supported, json, yaml or table", format)
+}
+
+// table write the rows as a table, an object is written as the key value pairs
+func table(w io.Writer, res interface{}) error {
+	var v interface{}
+	txt, err := jsoniter.Marshal(res)
+	if err != nil {
+		return err
+	}
+	err = jsoniter.Unmarshal(txt, &v)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	switch v := v.(type) {
+	case []interface{}:
+		columns := []string{}
+		seen := map[string]bool{}
+		for _, row := range v {
+			if row, ok := row.(map[string]interface{}); ok {
+				keys := make([]string, 0, len(row))
+				for key := range row {
+					if !seen[key] {
+						keys = append(keys, key)
+						seen[key] = true
+					}
+				}
+				sort.Strings(keys)
+				columns = append(columns, keys...)
 			}
-			fmt.Printf("%s\n", txt)
 		}
-	},
+
+		if len(columns) == 0 {
+			for _, row := range v {
+				fmt.Fprintln(tw, cell(row))
+			}
+			break
+		}
+
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+		for _, row := range v {
+			m, _ := row.(map[string]interface{})
+			cells := make([]string, 0, len(columns))
+			for _, column := range columns {
+				cells = append(cells, cell(m[column]))
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(tw, "%s\t%s\n", key, cell(v[key]))
+		}
+
+	default:
+		fmt.Fprintln(tw, cell(v))
+	}
+	return tw.Flush()
+}
+
+// cell the text of the value in the table
+func cell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		txt, _ := jsoniter.Marshal(v)
+		return string(txt)
+	}
+	return fmt.Sprintf("%v", v)
 }
 
 func init() {
 	runCmd.PersistentFlags().BoolVarP(&runSilent, "silent", "s", false, L("Silent mode"))
+	runCmd.PersistentFlags().StringVarP(&runOutput, "output", "o", "", L("Output format json, yaml or table, the result only"))
 }
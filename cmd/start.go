@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -20,6 +21,7 @@ import (
 	"github.com/yaoapp/gou/task"
 	"github.com/yaoapp/gou/websocket"
 	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/cluster"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/engine"
 	ischedule "github.com/yaoapp/yao/schedule"
@@ -27,6 +29,8 @@ import (
 	"github.com/yaoapp/yao/setup"
 	"github.com/yaoapp/yao/share"
 	itask "github.com/yaoapp/yao/task"
+	"github.com/yaoapp/yao/telegram"
+	"github.com/yaoapp/yao/watcher"
 )
 
 var startDebug = false
@@ -45,6 +49,10 @@ var startCmd = &cobra.Command{
 		interrupt := make(chan os.Signal, 1)
 		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 
+		// recive reload signal, e.g. kill -HUP <pid>
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+
 		Boot()
 
 		// Setup
@@ -198,9 +206,19 @@ var startCmd = &cobra.Command{
 		itask.Start()
 		defer itask.Stop()
 
-		// Start Schedules
-		ischedule.Start()
-		defer ischedule.Stop()
+		// Start the schedules and the Telegram bots on the leader only
+		cluster.Singleton("schedules", ischedule.Start, ischedule.Stop)
+		cluster.Singleton("telegram", telegram.Start, telegram.Stop)
+		err = cluster.Start(config.Conf.Cluster)
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+		defer cluster.Stop()
+
+		// Start watching the data directories
+		watcher.Start()
+		defer watcher.Stop()
 
 		// Start HTTP Server
 		srv, err := service.Start(config.Conf)
@@ -256,7 +274,17 @@ var startCmd = &cobra.Command{
 					fmt.Println("Signal:", v)
 				}
 
+			case <-reload:
+				err := service.Reload(srv)
+				if err != nil {
+					fmt.Println(color.RedString(L("Reload: %s"), err.Error()))
+					break
+				}
+				fmt.Println(color.GreenString(L("✨Reload Completed")))
+
 			case <-interrupt:
+				// The readiness fails, the requests are drained before the server stops
+				service.Drain(time.Duration(config.Conf.Health.ShutdownDelay) * time.Second)
 				watchDone <- 1
 				return
 			}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/engine"
+	"github.com/yaoapp/yao/neo"
+	"github.com/yaoapp/yao/neo/store"
+)
+
+var storeRollback int = 0
+var storeStatus bool = false
+
+var storeCmd = &cobra.Command{
+	Use:   "store",
+	Short: L("Neo store tables"),
+	Long:  L("Neo store tables"),
+	Args:  cobra.MinimumNArgs(1),
+	CompletionOptions: cobra.CompletionOptions{
+		DisableDefaultCmd: true,
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(os.Stderr, L("One or more arguments are not correct"), args)
+		os.Exit(1)
+	},
+}
+
+var storeMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: L("Update the schema of the neo store tables"),
+	Long:  L("Update the schema of the neo store tables"),
+	Run: func(cmd *cobra.Command, args []string) {
+		Boot()
+
+		// The pending migrations are applied when the store is loaded
+		err := engine.Load(config.Conf, engine.LoadOption{Action: "store.migrate"})
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		if neo.Neo == nil || neo.Neo.Store == nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), "the neo store is not loaded"))
+			os.Exit(1)
+		}
+
+		migrator, ok := neo.Neo.Store.(store.Migrator)
+		if !ok {
+			fmt.Println(color.RedString(L("Fatal: %s"), "the neo store does not support migrations"))
+			os.Exit(1)
+		}
+
+		if storeRollback > 0 {
+			migrations, err := migrator.Rollback(storeRollback)
+			for _, migration := range migrations {
+				fmt.Println(color.WhiteString(L("Rollback migration: %d %s"), migration.Version, migration.Name), "\t", color.GreenString(L("SUCCESS")))
+			}
+			if err != nil {
+				fmt.Println(color.RedString(L("FAILURE\n%s"), err.Error()))
+				os.Exit(1)
+			}
+			return
+		}
+
+		if !storeStatus {
+			migrations, err := migrator.Migrate()
+			for _, migration := range migrations {
+				fmt.Println(color.WhiteString(L("Apply migration: %d %s"), migration.Version, migration.Name), "\t", color.GreenString(L("SUCCESS")))
+			}
+			if err != nil {
+				fmt.Println(color.RedString(L("FAILURE\n%s"), err.Error()))
+				os.Exit(1)
+			}
+		}
+
+		migrations, err := migrator.Migrations()
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		for _, migration := range migrations {
+			status := color.YellowString(L("PENDING"))
+			if migration.Applied {
+				status = color.GreenString(L("APPLIED"))
+			}
+			fmt.Println(color.WhiteString("%4d %s", migration.Version, migration.Name), "\t", status)
+		}
+	},
+}
+
+var storeReencryptCmd = &cobra.Command{
+	Use:   "reencrypt",
+	Short: L("Encrypt the encrypted columns of the neo store with the current key"),
+	Long:  L("Encrypt the encrypted columns of the neo store with the current key"),
+	Run: func(cmd *cobra.Command, args []string) {
+		Boot()
+
+		err := engine.Load(config.Conf, engine.LoadOption{Action: "store.reencrypt"})
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		if neo.Neo == nil || neo.Neo.Store == nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), "the neo store is not loaded"))
+			os.Exit(1)
+		}
+
+		reencrypter, ok := neo.Neo.Store.(store.Reencrypter)
+		if !ok {
+			fmt.Println(color.RedString(L("Fatal: %s"), "the neo store does not support encryption"))
+			os.Exit(1)
+		}
+
+		count, err := reencrypter.Reencrypt()
+		fmt.Println(color.WhiteString(L("Re-encrypted rows: %d"), count))
+		if err != nil {
+			fmt.Println(color.RedString(L("FAILURE\n%s"), err.Error()))
+			os.Exit(1)
+		}
+		fmt.Println(color.GreenString(L("SUCCESS")))
+	},
+}
+
+func init() {
+	storeMigrateCmd.PersistentFlags().IntVarP(&storeRollback, "rollback", "", 0, L("Rollback the last applied migrations"))
+	storeMigrateCmd.PersistentFlags().BoolVarP(&storeStatus, "status", "", false, L("Show the status of the migrations"))
+	storeCmd.AddCommand(storeMigrateCmd)
+	storeCmd.AddCommand(storeReencryptCmd)
+}
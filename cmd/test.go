@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/yaoapp/gou/plugin"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/engine"
+	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/unit"
+)
+
+var testRun string = ""
+var testJUnit string = ""
+var testNoRollback bool = false
+
+var testCmd = &cobra.Command{
+	Use:   "test [patterns...]",
+	Short: L("Run the *_test.ts and *_test.js scripts"),
+	Long:  L("Run the *_test.ts and *_test.js scripts"),
+	Run: func(cmd *cobra.Command, args []string) {
+		defer share.SessionStop()
+		defer plugin.KillAll()
+
+		Boot()
+
+		config.Conf.Runtime.Mode = "standard"
+		cfg := config.Conf
+		cfg.Session.IsCLI = true
+
+		err := engine.Load(cfg, engine.LoadOption{Action: "test"})
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		report, err := unit.Run(cfg, unit.Option{
+			Patterns: args,
+			Run:      testRun,
+			Rollback: !testNoRollback,
+			OnCase: func(suite *unit.Suite, c *unit.Case) {
+				switch c.Status {
+				case unit.StatusPassed:
+					fmt.Println(color.GreenString("PASS"), color.WhiteString("%s %s", suite.Name, c.Name), color.CyanString("(%s)", c.Time))
+				case unit.StatusSkipped:
+					fmt.Println(color.YellowString("SKIP"), color.WhiteString("%s %s", suite.Name, c.Name), color.YellowString("%s", c.Message))
+				default:
+					fmt.Println(color.RedString("FAIL"), color.WhiteString("%s %s", suite.Name, c.Name))
+					fmt.Println("    ", color.RedString("%s", c.Message))
+				}
+			},
+		})
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		for _, suite := range report.Suites {
+			if suite.Error != "" {
+				fmt.Println(color.RedString("FAIL"), color.WhiteString("%s", suite.Name))
+				fmt.Println("    ", color.RedString("%s", suite.Error))
+			}
+		}
+
+		if testJUnit != "" {
+			file, err := os.Create(testJUnit)
+			if err != nil {
+				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+				os.Exit(1)
+			}
+			err = report.JUnit(file)
+			file.Close()
+			if err != nil {
+				fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+				os.Exit(1)
+			}
+		}
+
+		summary := fmt.Sprintf(L("%d passed, %d failed, %d skipped in %d files (%s)"), report.Passed, report.Failed, report.Skipped, len(report.Suites), report.Time)
+		if report.Failed > 0 {
+			fmt.Println(color.RedString("%s", summary))
+			os.Exit(1)
+		}
+		fmt.Println(color.GreenString("%s", summary))
+	},
+}
+
+func init() {
+	testCmd.PersistentFlags().StringVarP(&testRun, "run", "r", "", L("Run the tests matching the regular expression"))
+	testCmd.PersistentFlags().StringVarP(&testJUnit, "junit", "j", "", L("Write the JUnit XML report to the file"))
+	testCmd.PersistentFlags().BoolVarP(&testNoRollback, "no-rollback", "", false, L("Keep the rows written by the tests"))
+}
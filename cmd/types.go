@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/dts"
+	"github.com/yaoapp/yao/engine"
+)
+
+var typesOutput string = ""
+
+var typesCmd = &cobra.Command{
+	Use:   "types",
+	Short: L("TypeScript type definitions"),
+	Long:  L("TypeScript type definitions"),
+	Args:  cobra.MinimumNArgs(1),
+	CompletionOptions: cobra.CompletionOptions{
+		DisableDefaultCmd: true,
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(os.Stderr, L("One or more arguments are not correct"), args)
+		os.Exit(1)
+	},
+}
+
+var typesGenCmd = &cobra.Command{
+	Use:   "gen",
+	Short: L("Generate the type definitions of the models, the processes and the assistant hooks"),
+	Long:  L("Generate the type definitions of the models, the processes and the assistant hooks"),
+	Run: func(cmd *cobra.Command, args []string) {
+		Boot()
+
+		err := engine.Load(config.Conf, engine.LoadOption{Action: "types.gen"})
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		output := typesOutput
+		if output == "" {
+			output = filepath.Join(config.Conf.Root, dts.File)
+		}
+
+		err = os.MkdirAll(filepath.Dir(output), os.ModePerm)
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		err = os.WriteFile(output, []byte(dts.Generate()), 0644)
+		if err != nil {
+			fmt.Println(color.RedString(L("Fatal: %s"), err.Error()))
+			os.Exit(1)
+		}
+
+		fmt.Println(color.WhiteString(L("Type definitions: %s"), output), "\t", color.GreenString(L("SUCCESS")))
+	},
+}
+
+func init() {
+	typesGenCmd.PersistentFlags().StringVarP(&typesOutput, "output", "o", "", L("The output file, default is <app>/types/yao.d.ts"))
+	typesCmd.AddCommand(typesGenCmd)
+}
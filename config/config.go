@@ -10,7 +10,6 @@ import (
 	"github.com/caarlos0/env/v6"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/joho/godotenv"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/log"
 	"github.com/yaoapp/yao/crypto"
@@ -62,8 +61,10 @@ func LoadFrom(envfile string) Config {
 		return cfg
 	}
 
-	// load from env
-	godotenv.Overload(file)
+	// load from env, the .env.<profile> file overrides the .env file
+	if err := loadEnv(file); err != nil {
+		exception.New("Can't read config %s", 500, err.Error()).Throw()
+	}
 	cfg := Load()
 	ReloadLog()
 	return cfg
@@ -72,6 +73,10 @@ func LoadFrom(envfile string) Config {
 // Load the config
 func Load() Config {
 	cfg := Config{}
+	if err := resolveSecrets(); err != nil {
+		exception.New("Can't read config %s", 500, err.Error()).Throw()
+	}
+
 	if err := env.Parse(&cfg); err != nil {
 		exception.New("Can't read config %s", 500, err.Error()).Throw()
 	}
@@ -65,3 +65,43 @@ func TestLoadFrom(t *testing.T) {
 	assert.Equal(t, cfg.DB.Primary[0], os.Getenv("YAO_DB_PRIMARY"))
 	// assert.Equal(t, cfg.DB.Secondary[0], os.Getenv("YAO_DB_SECONDARY"))
 }
+
+func TestProfile(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "jwt_secret")
+	assert.NoError(t, os.WriteFile(secret, []byte("the-jwt-secret\n"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("YAO_PORT=5099\nYAO_HOST=127.0.0.1\nYAO_JWT_SECRET=dev\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".env.staging"), []byte(
+		"YAO_PORT=5199\nYAO_JWT_SECRET=secret:file:"+secret+"\nYAO_DB_PRIMARY=secret:env:TEST_DB_PRIMARY\n"), 0644))
+
+	t.Setenv("YAO_PROFILE", "staging")
+	t.Setenv("YAO_PORT", "")
+	t.Setenv("YAO_HOST", "")
+	t.Setenv("YAO_JWT_SECRET", "")
+	t.Setenv("YAO_DB_PRIMARY", "")
+	t.Setenv("TEST_DB_PRIMARY", "root:123456@tcp(127.0.0.1:3306)/yao")
+
+	cfg := LoadFrom(filepath.Join(dir, ".env"))
+	assert.Equal(t, "staging", Profile)
+	assert.Equal(t, 5199, cfg.Port)
+	assert.Equal(t, "127.0.0.1", cfg.Host)
+	assert.Equal(t, "the-jwt-secret", cfg.JWTSecret)
+	assert.Equal(t, []string{"root:123456@tcp(127.0.0.1:3306)/yao"}, cfg.DB.Primary)
+
+	settings := map[string]Setting{}
+	for _, setting := range Resolved(cfg, true) {
+		settings[setting.Name] = setting
+	}
+	assert.Equal(t, Setting{Name: "YAO_PORT", Value: "5199", Source: ".env.staging"}, settings["YAO_PORT"])
+	assert.Equal(t, Setting{Name: "YAO_HOST", Value: "127.0.0.1", Source: ".env"}, settings["YAO_HOST"])
+	assert.Equal(t, Setting{Name: "YAO_JWT_SECRET", Value: Masked, Source: "secret:file"}, settings["YAO_JWT_SECRET"])
+	assert.Equal(t, Masked, settings["YAO_DB_PRIMARY"].Value)
+	assert.Equal(t, "default", settings["YAO_RUNTIME_MODE"].Source)
+
+	for _, setting := range Resolved(cfg, false) {
+		assert.NotEqual(t, "default", setting.Source)
+	}
+
+	t.Setenv("YAO_PROFILE", "prod")
+	assert.Panics(t, func() { LoadFrom(filepath.Join(dir, ".env")) })
+}
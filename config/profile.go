@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// Profile the profile of the configuration, the .env.<profile> file overrides the .env file, e.g. YAO_PROFILE=staging
+var Profile string
+
+// SecretPrefix the prefix of the secret references, e.g. YAO_DB_PRIMARY=secret:file:/run/secrets/db_primary
+const SecretPrefix = "secret:"
+
+// sources the sources of the environment variables, the file or the secret provider
+var sources = map[string]string{}
+
+// secrets the environment variables resolved from the secret references
+var secrets = map[string]bool{}
+
+var providers = map[string]func(ref string) (string, error){
+	"file": secretFile,
+	"env":  secretEnv,
+}
+
+var envLock sync.Mutex
+
+// RegisterSecret register the provider of the secret references, e.g. secret:vault:db/primary
+func RegisterSecret(scheme string, provider func(ref string) (string, error)) {
+	envLock.Lock()
+	defer envLock.Unlock()
+	providers[scheme] = provider
+}
+
+// loadEnv load the .env file and the .env.<profile> file of YAO_PROFILE
+func loadEnv(envfile string) error {
+	envLock.Lock()
+	defer envLock.Unlock()
+
+	if err := overload(envfile); err != nil {
+		return err
+	}
+
+	Profile = os.Getenv("YAO_PROFILE")
+	if Profile != "" {
+		file := fmt.Sprintf("%s.%s", envfile, Profile)
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("the profile %s does not exist, %s is required", Profile, file)
+		}
+		return overload(file)
+	}
+	return nil
+}
+
+// overload set the environment variables of the file, the variables set are overridden
+func overload(file string) error {
+	values, err := godotenv.Read(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("%s: %s", filepath.Base(file), err.Error())
+	}
+
+	for name, value := range values {
+		os.Setenv(name, value)
+		sources[name] = filepath.Base(file)
+	}
+	return nil
+}
+
+// resolveSecrets replace the secret references of the environment variables with the secrets
+func resolveSecrets() error {
+	envLock.Lock()
+	defer envLock.Unlock()
+
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(value, SecretPrefix) {
+			continue
+		}
+
+		scheme, ref, _ := strings.Cut(strings.TrimPrefix(value, SecretPrefix), ":")
+		provider, has := providers[scheme]
+		if !has {
+			return fmt.Errorf("%s: the secret provider %s does not exist", name, scheme)
+		}
+
+		secret, err := provider(ref)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err.Error())
+		}
+
+		os.Setenv(name, secret)
+		sources[name] = SecretPrefix + scheme
+		secrets[name] = true
+	}
+	return nil
+}
+
+// secretFile the content of the file, e.g. the docker or the kubernetes secrets mounted
+func secretFile(ref string) (string, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// secretEnv the value of the other environment variable
+func secretEnv(ref string) (string, error) {
+	value, has := os.LookupEnv(ref)
+	if !has {
+		return "", fmt.Errorf("the environment variable %s is not set", ref)
+	}
+	return value, nil
+}
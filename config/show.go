@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Masked the value of the secrets shown
+const Masked = "******"
+
+// sensitive the environment variables masked, the secret references are masked too
+var sensitive = regexp.MustCompile(`(SECRET|PASSWORD|TOKEN|AESKEY|STRIPE_KEY|DB_PRIMARY|DB_SECONDARY|CLUSTER_REDIS|CACHE_REDIS|PUBSUB_REDIS)`)
+
+// Setting the effective setting of the configuration
+type Setting struct {
+	Name   string `json:"name"`   // The environment variable, e.g. YAO_DB_DRIVER
+	Value  string `json:"value"`  // The value, the secrets are masked
+	Source string `json:"source"` // The source, the .env file, the secret provider, the environment or the default
+}
+
+// Resolved the effective settings of the configuration, the secrets are masked. The settings not set and without the
+// defaults are skipped if all is false
+func Resolved(cfg Config, all bool) []Setting {
+	envLock.Lock()
+	defer envLock.Unlock()
+
+	settings := []Setting{}
+	walkSettings(reflect.ValueOf(cfg), func(field reflect.StructField, value reflect.Value) {
+		name := field.Tag.Get("env")
+		source, has := sources[name]
+		if !has {
+			source = "default"
+			if _, set := os.LookupEnv(name); set {
+				source = "environment"
+			}
+		}
+
+		if source == "default" && !all {
+			return
+		}
+
+		settings = append(settings, Setting{Name: name, Value: mask(name, format(field, value)), Source: source})
+	})
+	return settings
+}
+
+// walkSettings walk the fields of the config with the environment variables
+func walkSettings(v reflect.Value, cb func(field reflect.StructField, value reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			walkSettings(v.Field(i), cb)
+			continue
+		}
+
+		if field.Tag.Get("env") != "" {
+			cb(field, v.Field(i))
+		}
+	}
+}
+
+// format the value as the environment variable
+func format(field reflect.StructField, value reflect.Value) string {
+	if value.Kind() != reflect.Slice {
+		return fmt.Sprintf("%v", value.Interface())
+	}
+
+	separator := field.Tag.Get("envSeparator")
+	if separator == "" {
+		separator = ","
+	}
+
+	values := []string{}
+	for i := 0; i < value.Len(); i++ {
+		values = append(values, fmt.Sprintf("%v", value.Index(i).Interface()))
+	}
+	return strings.Join(values, separator)
+}
+
+func mask(name string, value string) string {
+	if value == "" {
+		return value
+	}
+	if secrets[name] || sensitive.MatchString(name) {
+		return Masked
+	}
+	return value
+}
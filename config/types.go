@@ -13,6 +13,11 @@ type Config struct {
 	Port          int      `json:"port,omitempty" env:"YAO_PORT" envDefault:"5099"`                 // The server port
 	Cert          string   `json:"cert,omitempty" env:"YAO_CERT"`                                   // The HTTPS certificate path
 	Key           string   `json:"key,omitempty" env:"YAO_KEY"`                                     // The HTTPS certificate key path
+	Compress      bool     `json:"compress,omitempty" env:"YAO_COMPRESS" envDefault:"true"`         // Compress the API responses by the Accept-Encoding
+	CompressMin   int      `json:"compress_min,omitempty" env:"YAO_COMPRESS_MIN" envDefault:"1024"` // The responses smaller than the size in bytes are not compressed
+	BodyLimit     int      `json:"body_limit,omitempty" env:"YAO_BODY_LIMIT" envDefault:"0"`        // The max request body size in MB, 0 is unlimited
+	UploadLimit   int      `json:"upload_limit,omitempty" env:"YAO_UPLOAD_LIMIT" envDefault:"0"`    // The max multipart request body size in MB, 0 is unlimited
+	UploadMemory  int      `json:"upload_memory,omitempty" env:"YAO_UPLOAD_MEMORY" envDefault:"8"`  // The multipart files larger than the size in MB are streamed to the temporary files
 	Log           string   `json:"log,omitempty" env:"YAO_LOG"`                                     // The log file path
 	LogMode       string   `json:"log_mode,omitempty" env:"YAO_LOG_MODE" envDefault:"TEXT"`         // The log mode TEXT|JSON
 	LogMaxSize    int      `json:"log_max_size,omitempty" env:"YAO_LOG_MAX_SIZE" envDefault:"100"`  // The max log size in MB, the default is 100
@@ -25,6 +30,69 @@ type Config struct {
 	Session       Session  `json:"session,omitempty"`                                         // Session Config
 	Studio        Studio   `json:"studio,omitempty"`                                          // Studio config
 	Runtime       Runtime  `json:"runtime,omitempty"`                                         // Runtime config
+	SMTP          SMTP     `json:"smtp,omitempty"`                                            // The mail server of the default mailer
+	Stripe        Stripe   `json:"stripe,omitempty"`                                          // The Stripe account of the billing
+	Metrics       Metrics  `json:"metrics,omitempty"`                                         // The time-series store of the metrics
+	Trace         Trace    `json:"trace,omitempty"`                                           // The traces of the agent turns
+	Health        Health   `json:"health,omitempty"`                                          // The liveness and the readiness probes
+	Cluster       Cluster  `json:"cluster,omitempty"`                                         // The leader election of the replicas
+	Cache         Cache    `json:"cache,omitempty"`                                           // The cache of the application
+	PubSub        PubSub   `json:"pubsub,omitempty"`                                          // The messages broadcast to the instances
+}
+
+// PubSub the messages broadcast to the subscribers on every instance, the messages are delivered at least once
+type PubSub struct {
+	Driver string `json:"pubsub_driver,omitempty" env:"YAO_PUBSUB_DRIVER" envDefault:"memory"`  // The backend of the messages, memory or redis, the memory messages are not shared by the instances
+	Redis  string `json:"pubsub_redis,omitempty" env:"YAO_PUBSUB_REDIS"`                        // The redis URL, e.g. redis://:password@127.0.0.1:6379/3, the session redis by default
+	MaxLen int    `json:"pubsub_max_len,omitempty" env:"YAO_PUBSUB_MAX_LEN" envDefault:"10000"` // The messages kept in the stream of a topic
+}
+
+// Cache the cache of the application shared by the cache processes and the widgets, the redis cache is shared by the instances
+type Cache struct {
+	Driver string `json:"cache_driver,omitempty" env:"YAO_CACHE_DRIVER" envDefault:"memory"`     // The backend of the cache, memory or redis
+	Redis  string `json:"cache_redis,omitempty" env:"YAO_CACHE_REDIS"`                           // The redis URL, e.g. redis://:password@127.0.0.1:6379/2, the session redis by default
+	Prefix string `json:"cache_prefix,omitempty" env:"YAO_CACHE_PREFIX" envDefault:"yao:cache:"` // The prefix of the keys
+}
+
+// Cluster the leader election of the replicas, the schedules, the cleanups and the bot pollers run on the leader only
+type Cluster struct {
+	Leader string `json:"cluster_leader,omitempty" env:"YAO_CLUSTER_LEADER"`               // The backend of the election, db or redis, every instance is the leader if not set
+	Lease  int    `json:"cluster_lease,omitempty" env:"YAO_CLUSTER_LEASE" envDefault:"15"` // The seconds the leadership is kept without the renewal
+	Redis  string `json:"cluster_redis,omitempty" env:"YAO_CLUSTER_REDIS"`                 // The redis URL, e.g. redis://:password@127.0.0.1:6379/1, the session redis by default
+}
+
+// Health the probes of the server, GET /healthz the liveness and GET /readyz the readiness
+type Health struct {
+	Connectors    []string `json:"ready_connectors,omitempty" env:"YAO_READY_CONNECTORS" envSeparator:"|"` // The connectors required by the readiness, e.g. mysql|redis
+	Skip          []string `json:"ready_skip,omitempty" env:"YAO_READY_SKIP" envSeparator:"|"`             // The checks skipped, e.g. migrations
+	Timeout       int      `json:"ready_timeout,omitempty" env:"YAO_READY_TIMEOUT" envDefault:"3"`         // The timeout of the checks in seconds
+	ShutdownDelay int      `json:"shutdown_delay,omitempty" env:"YAO_SHUTDOWN_DELAY" envDefault:"0"`       // The seconds the readiness fails before the server stops, e.g. 10 for the rolling updates
+}
+
+// Trace the traces of the agent turns, the spans of the turns, the LLM requests, the hooks and the tools are stored
+type Trace struct {
+	Disabled  bool `json:"trace_disabled,omitempty" env:"YAO_TRACE_DISABLED"`                  // The spans are not stored if true
+	Retention int  `json:"trace_retention,omitempty" env:"YAO_TRACE_RETENTION" envDefault:"7"` // The days the spans are kept, 0 keeps forever
+}
+
+// Metrics the time-series store of the metrics, the points are stored in the monthly tables
+type Metrics struct {
+	Retention int `json:"metrics_retention,omitempty" env:"YAO_METRICS_RETENTION" envDefault:"90"` // The days the points are kept, the monthly tables ended before are dropped, 0 keeps forever
+}
+
+// SMTP the mail server of the default mailer, used if mailers/default.yao does not exist
+type SMTP struct {
+	Host     string `json:"smtp_host,omitempty" env:"YAO_SMTP_HOST"`                  // The mail server host, the default mailer is disabled if not set
+	Port     int    `json:"smtp_port,omitempty" env:"YAO_SMTP_PORT" envDefault:"587"` // The mail server port
+	Username string `json:"smtp_username,omitempty" env:"YAO_SMTP_USERNAME"`          // The username of the PLAIN authentication
+	Password string `json:"smtp_password,omitempty" env:"YAO_SMTP_PASSWORD"`          // The password of the PLAIN authentication
+	From     string `json:"smtp_from,omitempty" env:"YAO_SMTP_FROM"`                  // The sender address, e.g. Yao <noreply@example.com>
+}
+
+// Stripe the Stripe account of the billing, the plans are the plans/*.yao files
+type Stripe struct {
+	Key           string `json:"stripe_key,omitempty" env:"YAO_STRIPE_KEY"`                       // The secret key, e.g. sk_live_xxx, the billing is disabled if not set
+	WebhookSecret string `json:"stripe_webhook_secret,omitempty" env:"YAO_STRIPE_WEBHOOK_SECRET"` // The signing secret of the webhook endpoint, e.g. whsec_xxx
 }
 
 // Studio the studio config
@@ -40,6 +108,12 @@ type Database struct {
 	Primary   []string `json:"primary,omitempty" env:"YAO_DB_PRIMARY" envSeparator:"|" envDefault:"./db/yao.db"` // 主库连接DSN
 	Secondary []string `json:"secondary,omitempty" env:"YAO_DB_SECONDARY" envSeparator:"|"`                      // 从库连接DSN
 	AESKey    string   `json:"aeskey,omitempty" env:"YAO_DB_AESKEY"`                                             // 加密存储KEY
+
+	MaxOpen     int `json:"max_open,omitempty" env:"YAO_DB_MAX_OPEN"`           // The max open connections, unlimited by default
+	MaxIdle     int `json:"max_idle,omitempty" env:"YAO_DB_MAX_IDLE"`           // The max idle connections, 2 by default
+	MaxLifetime int `json:"max_lifetime,omitempty" env:"YAO_DB_MAX_LIFETIME"`   // The max lifetime of the connections in seconds, unlimited by default
+	MaxIdleTime int `json:"max_idle_time,omitempty" env:"YAO_DB_MAX_IDLE_TIME"` // The max idle time of the connections in seconds, unlimited by default
+	SlowQuery   int `json:"slow_query,omitempty" env:"YAO_DB_SLOW_QUERY"`       // The queries slower than the milliseconds are logged, the plans are logged in the development mode
 }
 
 // Session 会话服务器
@@ -51,6 +125,7 @@ type Session struct {
 	Password string `json:"password,omitempty" env:"YAO_SESSION_PASSWORD"`                // The redis password
 	Username string `json:"username,omitempty" env:"YAO_SESSION_USERNAME"`                // The redis username
 	DB       string `json:"db,omitempty" env:"YAO_SESSION_DB" envDefault:"1"`             // The redis username
+	TTL      int    `json:"ttl,omitempty" env:"YAO_SESSION_TTL" envDefault:"28800"`       // The seconds the login session is kept, the expiration of the login token
 	IsCLI    bool   `json:"iscli,omitempty" env:"YAO_SESSION_ISCLI" envDefault:"false"`   // Command Line Start
 }
 
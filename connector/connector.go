@@ -6,6 +6,8 @@ import (
 
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/gou/connector/database"
+	"github.com/yaoapp/yao/clickhouse"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/share"
 )
@@ -18,7 +20,23 @@ func Load(cfg config.Config) error {
 		if isdir {
 			return nil
 		}
-		_, err := connector.Load(file, share.ID(root, file))
+		id := share.ID(root, file)
+		is, err := loadClickHouse(file, id)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+		if is {
+			return nil
+		}
+
+		conn, err := connector.Load(file, id)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		err = loadPool(file, id, conn)
 		if err != nil {
 			messages = append(messages, err.Error())
 		}
@@ -35,6 +53,65 @@ func Load(cfg config.Config) error {
 	return nil
 }
 
+// loadPool set the pool of the database connector, the pool of the connector source
+//
+//	{ "type": "mysql", "options": {...}, "pool": { "max_open": 50, "slow_query": 500 } }
+func loadPool(file string, id string, conn connector.Connector) error {
+	source, err := application.App.Read(file)
+	if err != nil {
+		return err
+	}
+
+	dsl := struct {
+		Pool *share.DBPool `json:"pool,omitempty"`
+	}{}
+	err = application.Parse(file, source, &dsl)
+	if err != nil {
+		return err
+	}
+
+	if dsl.Pool == nil {
+		return nil
+	}
+
+	db, ok := conn.(*database.Xun)
+	if !ok || db.Manager == nil {
+		return fmt.Errorf("[%s] the pool is only supported by the database connectors", id)
+	}
+
+	err = dsl.Pool.Apply(id, db.Manager)
+	if err != nil {
+		return fmt.Errorf("[%s] pool %s", id, err.Error())
+	}
+	return nil
+}
+
+// loadClickHouse load the ClickHouse connector, the ClickHouse connectors are not the connectors of gou
+//
+//	{ "type": "clickhouse", "options": { "host": "127.0.0.1", "port": 8123, "db": "analytics" } }
+func loadClickHouse(file string, id string) (bool, error) {
+	source, err := application.App.Read(file)
+	if err != nil {
+		return false, err
+	}
+
+	dsl := struct {
+		Type    string             `json:"type"`
+		Options clickhouse.Options `json:"options,omitempty"`
+	}{}
+	err = application.Parse(file, source, &dsl)
+	if err != nil {
+		return false, err
+	}
+
+	if !strings.EqualFold(dsl.Type, "clickhouse") {
+		return false, nil
+	}
+
+	_, err = clickhouse.Connect(id, dsl.Options)
+	return true, err
+}
+
 // Unload Connector
 func Unload() error {
 	messages := []string{}
@@ -44,7 +121,9 @@ func Unload() error {
 			messages = append(messages, err.Error())
 		}
 		delete(connector.Connectors, id)
+		share.DBUnregister(id)
 	}
+	clickhouse.Unload()
 	if len(messages) > 0 {
 		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
 	}
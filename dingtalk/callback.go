@@ -0,0 +1,191 @@
+package dingtalk
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/wework"
+)
+
+// received the ids of the messages received in the last 10 minutes, the retries of DingTalk are answered once
+var received = map[string]time.Time{}
+var receivedLock sync.Mutex
+
+// SetRoutes the robot messages and the event subscription of the bots, the urls of the bot dingtalks/support.yao are <path>/support
+//
+//	POST <path>/:id         The robot messages, signed by the app secret, the reply is sent to the session webhook
+//	POST <path>/:id/events  The event subscription, encrypted by the aes_key, the check_url event is answered
+func SetRoutes(router *gin.Engine, path string) {
+	router.POST(path+"/:id", handleMessage)
+	router.POST(path+"/:id/events", handleEvent)
+}
+
+func handleMessage(c *gin.Context) {
+	bot, err := Select(c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"code": 404, "message": err.Error()})
+		return
+	}
+
+	if err := bot.verify(c.GetHeader("timestamp"), c.GetHeader("sign"), time.Now()); err != nil {
+		c.JSON(401, gin.H{"code": 401, "message": err.Error()})
+		return
+	}
+
+	msg := &Message{}
+	if err := jsoniter.NewDecoder(io.LimitReader(c.Request.Body, 1<<20)).Decode(msg); err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": "the message is invalid"})
+		return
+	}
+
+	// The message is answered in the background, the reply is sent to the session webhook
+	if !duplicated(msg.MsgID) {
+		go reply(bot, msg)
+	}
+	c.JSON(200, gin.H{})
+}
+
+func handleEvent(c *gin.Context) {
+	bot, err := Select(c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"code": 404, "message": err.Error()})
+		return
+	}
+
+	if bot.AESKey == "" {
+		c.JSON(404, gin.H{"code": 404, "message": "the event subscription of the bot is not configured"})
+		return
+	}
+
+	payload := struct {
+		Encrypt string `json:"encrypt"`
+	}{}
+	if err := jsoniter.NewDecoder(io.LimitReader(c.Request.Body, 1<<20)).Decode(&payload); err != nil || payload.Encrypt == "" {
+		c.JSON(400, gin.H{"code": 400, "message": "the event is invalid"})
+		return
+	}
+
+	signature := c.Query("msg_signature")
+	if signature == "" {
+		signature = c.Query("signature")
+	}
+	timestamp := c.Query("timestamp")
+	if timestamp == "" {
+		timestamp = c.Query("timeStamp")
+	}
+	nonce := c.Query("nonce")
+	if !hmac.Equal([]byte(wework.Signature(bot.Token, timestamp, nonce, payload.Encrypt)), []byte(signature)) {
+		c.JSON(401, gin.H{"code": 401, "message": "the signature is invalid"})
+		return
+	}
+
+	res, err := wework.Decrypt(bot.AESKey, payload.Encrypt, false)
+	if err != nil || res["receiveid"] != bot.AppKey {
+		c.JSON(400, gin.H{"code": 400, "message": "the event is invalid"})
+		return
+	}
+
+	data := map[string]interface{}{}
+	if err := jsoniter.UnmarshalFromString(res["message"].(string), &data); err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": "the event is invalid"})
+		return
+	}
+
+	// The events except the check_url are passed to the process
+	if data["EventType"] != "check_url" && bot.Process != "" {
+		go func() {
+			if _, err := bot.call("event", data); err != nil {
+				log.Error("[DingTalk] %s event %v: %s", bot.ID, data["EventType"], err.Error())
+			}
+		}()
+	}
+
+	// The "success" is encrypted and signed, DingTalk retries the event otherwise
+	encrypted, err := wework.Encrypt(bot.AESKey, bot.AppKey, "success")
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	c.JSON(200, gin.H{
+		"msg_signature": wework.Signature(bot.Token, now, nonce, encrypted),
+		"timeStamp":     now,
+		"nonce":         nonce,
+		"encrypt":       encrypted,
+	})
+}
+
+// verify the sign of the robot message, the timestamp in milliseconds is valid in an hour
+func (bot *Bot) verify(timestamp string, sign string, now time.Time) error {
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("the timestamp is invalid")
+	}
+
+	if math.Abs(float64(now.UnixMilli()-ms)) > float64(time.Hour.Milliseconds()) {
+		return fmt.Errorf("the timestamp is expired")
+	}
+
+	if sign == "" || !hmac.Equal([]byte(sign), []byte(Sign(bot.AppSecret, timestamp))) {
+		return fmt.Errorf("the sign is invalid")
+	}
+	return nil
+}
+
+// reply answer the message and send the reply to the session webhook
+func reply(bot *Bot, msg *Message) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error("[DingTalk] %s %s: %v", bot.ID, msg.SenderID, err)
+		}
+	}()
+
+	text, err := bot.Answer(msg)
+	if err != nil {
+		log.Error("[DingTalk] %s %s: %s", bot.ID, msg.SenderID, err.Error())
+		return
+	}
+
+	if text == "" {
+		return
+	}
+
+	err = bot.Reply(msg, text)
+	if err != nil {
+		log.Error("[DingTalk] %s reply to %s: %s", bot.ID, msg.SenderID, err.Error())
+	}
+}
+
+// duplicated the message is received before or not
+func duplicated(id string) bool {
+	if id == "" {
+		return false
+	}
+
+	receivedLock.Lock()
+	defer receivedLock.Unlock()
+
+	now := time.Now()
+	if at, has := received[id]; has && now.Sub(at) < 10*time.Minute {
+		return true
+	}
+
+	if len(received) > 1000 {
+		for key, at := range received {
+			if now.Sub(at) >= 10*time.Minute {
+				delete(received, key)
+			}
+		}
+	}
+	received[id] = now
+	return false
+}
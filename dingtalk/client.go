@@ -0,0 +1,62 @@
+package dingtalk
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// Reply send the text to the session webhook of the message, the markdown message if the card of the bot is on
+func (bot *Bot) Reply(msg *Message, text string) error {
+	if !strings.HasPrefix(msg.SessionWebhook, bot.API+"/") {
+		return fmt.Errorf("the session webhook %s is not of %s", msg.SessionWebhook, bot.API)
+	}
+
+	if msg.SessionWebhookExpiredTime > 0 && time.Now().UnixMilli() > msg.SessionWebhookExpiredTime {
+		return fmt.Errorf("the session webhook is expired")
+	}
+
+	// Mention the sender in the groups
+	at := ""
+	if msg.ConversationType == "2" {
+		at = msg.SenderStaffID
+	}
+
+	message := Markdown(bot.Name, text, at)
+	if !bot.Card {
+		message = map[string]interface{}{"msgtype": "text", "text": map[string]interface{}{"content": text}}
+		if at != "" {
+			message["at"] = map[string]interface{}{"atUserIds": []string{at}}
+		}
+	}
+
+	payload, err := jsoniter.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(msg.SessionWebhook, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	res := struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}{}
+	if err := jsoniter.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("the session webhook responds %d", resp.StatusCode)
+	}
+
+	if res.ErrCode != 0 {
+		return fmt.Errorf("the session webhook %d: %s", res.ErrCode, res.ErrMsg)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package dingtalk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/neo/assistant"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	"github.com/yaoapp/yao/share"
+)
+
+// Bot the DingTalk robot of the dingtalks/*.yao, the messages of the robot are answered by the assistant or the process
+//
+//	{
+//	  "name": "Support",
+//	  "app_key": "$ENV.DINGTALK_APP_KEY",
+//	  "app_secret": "$ENV.DINGTALK_APP_SECRET",
+//	  "token": "$ENV.DINGTALK_TOKEN",
+//	  "aes_key": "$ENV.DINGTALK_AES_KEY",
+//	  "assistant": "support",
+//	  "card": true
+//	}
+type Bot struct {
+	ID        string `json:"-"`
+	Name      string `json:"name,omitempty"`
+	AppKey    string `json:"app_key"`
+	AppSecret string `json:"app_secret"`          // The secret of the app, for the sign of the robot messages
+	Token     string `json:"token,omitempty"`     // The token of the event subscription
+	AESKey    string `json:"aes_key,omitempty"`   // The aes_key of the event subscription
+	Assistant string `json:"assistant,omitempty"` // The assistant answering the text messages
+	Process   string `json:"process,omitempty"`   // The process called with the message or the event instead of the assistant, returns the reply
+	Card      bool   `json:"card,omitempty"`      // Reply with the markdown message, the text message if false
+	API       string `json:"api,omitempty"`       // https://oapi.dingtalk.com by default, the session webhooks are on it
+}
+
+// Message the message of the robot
+type Message struct {
+	MsgID                     string `json:"msgId"`
+	MsgType                   string `json:"msgtype"`
+	Text                      Text   `json:"text"`
+	ConversationID            string `json:"conversationId"`
+	ConversationType          string `json:"conversationType"` // 1 single, 2 group
+	SenderID                  string `json:"senderId"`
+	SenderStaffID             string `json:"senderStaffId,omitempty"`
+	SenderNick                string `json:"senderNick,omitempty"`
+	SessionWebhook            string `json:"sessionWebhook"`
+	SessionWebhookExpiredTime int64  `json:"sessionWebhookExpiredTime"`
+	RobotCode                 string `json:"robotCode,omitempty"`
+}
+
+// Text the text of the message
+type Text struct {
+	Content string `json:"content"`
+}
+
+// Bots the loaded bots
+var Bots = map[string]*Bot{}
+var lock sync.RWMutex
+
+// Load the dingtalks/*.yao bots
+func Load(cfg config.Config) error {
+	bots := map[string]*Bot{}
+	messages := []string{}
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("dingtalks", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		bot := Bot{}
+		err = application.Parse(file, bytes, &bot)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		bot.ID = share.ID(root, file)
+		if err := bot.validate(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		bots[bot.ID] = &bot
+		return nil
+	}, exts...)
+
+	lock.Lock()
+	Bots = bots
+	lock.Unlock()
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+	return err
+}
+
+// Select the bot
+func Select(id string) (*Bot, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	bot, has := Bots[id]
+	if !has {
+		return nil, fmt.Errorf("the dingtalk bot %s does not exist", id)
+	}
+	return bot, nil
+}
+
+// Answer the reply of the message, empty if the message is not answered
+func (bot *Bot) Answer(msg *Message) (string, error) {
+	if bot.Process != "" {
+		return bot.call("message", msg.Map())
+	}
+
+	// The assistant answers the text messages only
+	content := strings.TrimSpace(msg.Text.Content)
+	if msg.MsgType != "text" || content == "" {
+		return "", nil
+	}
+
+	ast, err := assistant.Get(bot.Assistant)
+	if err != nil {
+		return "", err
+	}
+
+	// The user chats with the assistant in a single chat, the members of the group share the chat of the group
+	sid := fmt.Sprintf("dingtalk:%s:%s", bot.ID, msg.SenderID)
+	cid := sid
+	if msg.ConversationType == "2" {
+		cid = fmt.Sprintf("dingtalk:%s:%s", bot.ID, msg.ConversationID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	return ast.Reply(chatctx.Context{Context: ctx, Sid: sid, ChatID: cid, AssistantID: ast.ID}, content)
+}
+
+// call the process of the bot, process(bot, "message"|"event", data)
+func (bot *Bot) call(kind string, data map[string]interface{}) (string, error) {
+	p, err := process.Of(bot.Process, bot.ID, kind, data)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := p.Exec()
+	if err != nil {
+		return "", err
+	}
+
+	if res == nil {
+		return "", nil
+	}
+	return fmt.Sprintf("%v", res), nil
+}
+
+// Map the message as map, the args of the process
+func (msg *Message) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                msg.MsgID,
+		"type":              msg.MsgType,
+		"content":           strings.TrimSpace(msg.Text.Content),
+		"conversation_id":   msg.ConversationID,
+		"conversation_type": msg.ConversationType,
+		"sender_id":         msg.SenderID,
+		"sender_staff_id":   msg.SenderStaffID,
+		"sender_nick":       msg.SenderNick,
+	}
+}
+
+// Sign the sign of the robot message, base64 of the hmac_sha256 of "<timestamp>\n<app_secret>"
+func Sign(secret string, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Markdown the markdown message of the text, the sender is mentioned in the groups
+func Markdown(title string, text string, at string) map[string]interface{} {
+	if title == "" {
+		title = text
+		if len([]rune(title)) > 20 {
+			title = string([]rune(title)[:20])
+		}
+	}
+
+	message := map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]interface{}{"title": title, "text": text},
+	}
+	if at != "" {
+		message["at"] = map[string]interface{}{"atUserIds": []string{at}}
+	}
+	return message
+}
+
+func (bot *Bot) validate() error {
+	for _, value := range []*string{&bot.AppKey, &bot.AppSecret, &bot.Token, &bot.AESKey} {
+		if strings.HasPrefix(*value, "$ENV.") {
+			*value = os.Getenv(strings.TrimPrefix(*value, "$ENV."))
+		}
+	}
+
+	if bot.AppKey == "" || bot.AppSecret == "" {
+		return fmt.Errorf("the app_key and app_secret are required")
+	}
+
+	if bot.AESKey != "" && (bot.Token == "" || len(bot.AESKey) != 43) {
+		return fmt.Errorf("the token and the aes_key of 43 characters are required by the event subscription")
+	}
+
+	if bot.Assistant == "" && bot.Process == "" {
+		return fmt.Errorf("the assistant or the process is required")
+	}
+
+	if bot.API == "" {
+		bot.API = "https://oapi.dingtalk.com"
+	}
+	bot.API = strings.TrimRight(bot.API, "/")
+	return nil
+}
@@ -0,0 +1,127 @@
+package dingtalk
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/wework"
+)
+
+func testBot(api string) *Bot {
+	return &Bot{
+		ID:        "support",
+		Name:      "Support",
+		AppKey:    "dingabc",
+		AppSecret: "secret",
+		Token:     "token",
+		AESKey:    "RhH75tStMzrH8bMxkTw8BrBfr0ZWULL5himUaRWCs7H",
+		Process:   "scripts.dingtalk.Reply",
+		API:       api,
+	}
+}
+
+func TestVerify(t *testing.T) {
+	bot := testBot("")
+	now := time.UnixMilli(1700000000000)
+	assert.NoError(t, bot.verify("1700000000000", Sign("secret", "1700000000000"), now))
+	assert.Error(t, bot.verify("1700000000000", Sign("other", "1700000000000"), now))
+	assert.Error(t, bot.verify("1700000000000", Sign("secret", "1700000000000"), now.Add(2*time.Hour)))
+	assert.Error(t, bot.verify("", "", now))
+}
+
+func TestMarkdown(t *testing.T) {
+	message := Markdown("Support", "**Done**", "staff1")
+	assert.Equal(t, "markdown", message["msgtype"])
+	assert.Equal(t, "Support", message["markdown"].(map[string]interface{})["title"])
+	assert.Equal(t, []string{"staff1"}, message["at"].(map[string]interface{})["atUserIds"])
+	assert.Equal(t, "12345678901234567890", Markdown("", "123456789012345678901234", "")["markdown"].(map[string]interface{})["title"])
+}
+
+func TestHandleMessage(t *testing.T) {
+	defer func(bots map[string]*Bot) { Bots = bots }(Bots)
+	Bots = map[string]*Bot{"support": testBot("http://127.0.0.1:0")}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetRoutes(router, "/api/__yao/dingtalk")
+
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	body := `{"msgId":"msg1","msgtype":"text","text":{"content":" hello"},"conversationType":"1","senderId":"u1"}`
+	req := httptest.NewRequest("POST", "/api/__yao/dingtalk/support", strings.NewReader(body))
+	req.Header.Set("timestamp", timestamp)
+	req.Header.Set("sign", Sign("secret", timestamp))
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+
+	req = httptest.NewRequest("POST", "/api/__yao/dingtalk/support", strings.NewReader(body))
+	req.Header.Set("timestamp", timestamp)
+	req.Header.Set("sign", "invalid")
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, 401, res.Code)
+}
+
+func TestHandleEvent(t *testing.T) {
+	defer func(bots map[string]*Bot) { Bots = bots }(Bots)
+	bot := testBot("http://127.0.0.1:0")
+	Bots = map[string]*Bot{"support": bot}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetRoutes(router, "/api/__yao/dingtalk")
+
+	encrypted, _ := wework.Encrypt(bot.AESKey, bot.AppKey, `{"EventType":"check_url"}`)
+	query := url.Values{"timestamp": {"1700000000000"}, "nonce": {"n1"}}
+	query.Set("signature", wework.Signature(bot.Token, "1700000000000", "n1", encrypted))
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("POST", "/api/__yao/dingtalk/support/events?"+query.Encode(), strings.NewReader(`{"encrypt":"`+encrypted+`"}`)))
+	assert.Equal(t, 200, res.Code)
+
+	answer := map[string]string{}
+	jsoniter.Unmarshal(res.Body.Bytes(), &answer)
+	assert.Equal(t, wework.Signature(bot.Token, answer["timeStamp"], "n1", answer["encrypt"]), answer["msg_signature"])
+	plain, err := wework.Decrypt(bot.AESKey, answer["encrypt"], false)
+	assert.NoError(t, err)
+	assert.Equal(t, "success", plain["message"])
+
+	query.Set("signature", "invalid")
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("POST", "/api/__yao/dingtalk/support/events?"+query.Encode(), strings.NewReader(`{"encrypt":"`+encrypted+`"}`)))
+	assert.Equal(t, 401, res.Code)
+}
+
+func TestReply(t *testing.T) {
+	received := map[string]interface{}{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		jsoniter.Unmarshal(body, &received)
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer srv.Close()
+
+	bot := testBot(srv.URL)
+	bot.Card = true
+	msg := &Message{ConversationType: "2", SenderStaffID: "staff1", SessionWebhook: srv.URL + "/robot/sendBySession?session=s1"}
+	assert.NoError(t, bot.Reply(msg, "**Done**"))
+	assert.Equal(t, "markdown", received["msgtype"])
+	assert.Equal(t, []interface{}{"staff1"}, received["at"].(map[string]interface{})["atUserIds"])
+
+	bot.Card = false
+	received = map[string]interface{}{}
+	assert.NoError(t, bot.Reply(&Message{SessionWebhook: srv.URL + "/robot/sendBySession"}, "Done"))
+	assert.Equal(t, "Done", received["text"].(map[string]interface{})["content"])
+	assert.Nil(t, received["at"])
+
+	assert.Error(t, bot.Reply(&Message{SessionWebhook: "https://example.com/robot"}, "Done"))
+	assert.Error(t, bot.Reply(&Message{SessionWebhook: srv.URL + "/robot", SessionWebhookExpiredTime: 1}, "Done"))
+}
@@ -0,0 +1,195 @@
+package dts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/yao/neo/assistant"
+	"github.com/yaoapp/yao/neo/message"
+)
+
+// File the generated file under the application root
+const File = "types/yao.d.ts"
+
+// columnTypes the TypeScript types of the model column types, string by default
+var columnTypes = map[string]string{
+	"tinyInteger": "number", "tinyIncrements": "number", "unsignedTinyInteger": "number",
+	"smallInteger": "number", "smallIncrements": "number", "unsignedSmallInteger": "number",
+	"integer": "number", "increments": "number", "unsignedInteger": "number",
+	"bigInteger": "number", "bigIncrements": "number", "unsignedBigInteger": "number",
+	"id": "number", "ID": "number", "year": "number",
+	"decimal": "number", "unsignedDecimal": "number",
+	"float": "number", "unsignedFloat": "number",
+	"double": "number", "unsignedDouble": "number",
+	"boolean": "boolean",
+	"json":    "any", "JSON": "any", "jsonb": "any", "JSONB": "any",
+}
+
+// modelProcesses the processes of the models, the name, the arguments and the result
+var modelProcesses = [][3]string{
+	{"Find", "id: number | string, query?: QueryParam", "%s"},
+	{"Get", "query?: QueryParam", "%s[]"},
+	{"Paginate", "query: QueryParam, page: number, pagesize: number", "Paginate<%s>"},
+	{"Create", "row: Partial<%s>", "number"},
+	{"Update", "id: number | string, row: Partial<%s>", "null"},
+	{"Save", "row: Partial<%s>", "number"},
+	{"Delete", "id: number | string", "null"},
+	{"Destroy", "id: number | string", "null"},
+}
+
+const header = `// Code generated by yao types gen. DO NOT EDIT.
+// The types of the loaded models, the processes and the neo assistant hooks.
+
+declare interface QueryParam {
+  select?: string[];
+  wheres?: { column?: string; value?: any; op?: string; method?: string; wheres?: any[] }[];
+  orders?: { column: string; option?: "asc" | "desc" }[];
+  withs?: Record<string, any>;
+  limit?: number;
+  page?: number;
+  pagesize?: number;
+}
+
+declare interface Paginate<T> {
+  data: T[];
+  pagesize: number;
+  pagecnt: number;
+  pagesize_next?: number;
+  page: number;
+  next: number;
+  prev: number;
+  total: number;
+}
+`
+
+// neoContext the context of the assistant hooks, see neo/context Context.Map
+const neoContext = `declare interface NeoContext {
+  sid: string;
+  chat_id?: string;
+  assistant_id?: string;
+  stack?: string;
+  pathname?: string;
+  formdata?: Record<string, any>;
+  field?: Record<string, any>;
+  namespace?: string;
+  config?: Record<string, any>;
+  signal?: any;
+}
+
+/** The hooks of the assistant script, e.g. assistants/<id>/src/index.ts */
+declare type NeoInit = (context: NeoContext, input: NeoMessage[], writer: any) => NeoInitResponse | string | null | undefined;
+declare type NeoStream = (context: NeoContext, input: NeoMessage[], output: NeoData[], writer: any) => NeoStreamResponse | null | undefined;
+declare type NeoDone = (context: NeoContext, input: NeoMessage[], output: NeoData[], writer: any) => NeoDoneResponse | null | undefined;
+declare type NeoFail = (context: NeoContext, input: NeoMessage[], output: NeoData[], error: string, writer: any) => NeoFailResponse | null | undefined;
+`
+
+// Generate the type definitions of the loaded application
+func Generate() string {
+	parts := []string{header, Models(), Processes(), Neo()}
+	return strings.Join(parts, "\n") + "\n"
+}
+
+// Models the interfaces of the models and the typed model processes
+func Models() string {
+	ids := make([]string, 0, len(model.Models))
+	for id := range model.Models {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	decls := []string{}
+	overloads := []string{}
+	for _, id := range ids {
+		mod := model.Models[id]
+		name := "Model" + Pascal(id)
+
+		lines := []string{fmt.Sprintf("/** %s (%s) */", id, mod.MetaData.Table.Name), fmt.Sprintf("declare interface %s {", name)}
+		for _, column := range mod.MetaData.Columns {
+			typ := ColumnType(column.Type, column.Option)
+			if column.Nullable {
+				typ += " | null"
+			}
+
+			comment := column.Label
+			if comment == "" {
+				comment = column.Comment
+			}
+			if comment != "" {
+				lines = append(lines, fmt.Sprintf("  /** %s */", comment))
+			}
+			lines = append(lines, fmt.Sprintf("  %s?: %s;", column.Name, typ))
+		}
+		lines = append(lines, "  [key: string]: any;", "}")
+		decls = append(decls, strings.Join(lines, "\n"))
+
+		for _, p := range modelProcesses {
+			args := strings.ReplaceAll(p[1], "%s", name)
+			overloads = append(overloads, fmt.Sprintf("declare function Process(name: \"models.%s.%s\", %s): %s;", id, p[0], args, fmt.Sprintf(p[2], name)))
+		}
+	}
+
+	return strings.Join(decls, "\n\n") + "\n\n" + strings.Join(overloads, "\n") + "\n"
+}
+
+// Processes the names of the registered processes
+func Processes() string {
+	names := make([]string, 0, len(process.Handlers))
+	for name := range process.Handlers {
+		names = append(names, fmt.Sprintf("%q", name))
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		names = []string{"string"}
+	}
+
+	return fmt.Sprintf(`/** The registered processes, the scripts, the models and the plugins are called with the prefix, e.g. scripts.<id>.<method> */
+declare type ProcessName =
+  | %s;
+
+declare function Process(name: ProcessName | string, ...args: any[]): any;
+`, strings.Join(names, "\n  | "))
+}
+
+// Neo the types of the neo assistant hooks
+func Neo() string {
+	ifs := NewInterfaces()
+	ifs.Add("NeoMessage", message.Message{})
+	ifs.Add("NeoData", message.Data{})
+	ifs.Add("NeoInitResponse", assistant.ResHookInit{})
+	ifs.Add("NeoStreamResponse", assistant.ResHookStream{})
+	ifs.Add("NeoDoneResponse", assistant.ResHookDone{})
+	ifs.Add("NeoFailResponse", assistant.ResHookFail{})
+	return ifs.String() + "\n\n" + neoContext
+}
+
+// ColumnType the TypeScript type of the model column type
+func ColumnType(typ string, options []string) string {
+	if typ == "enum" && len(options) > 0 {
+		values := make([]string, 0, len(options))
+		for _, option := range options {
+			values = append(values, fmt.Sprintf("%q", option))
+		}
+		return strings.Join(values, " | ")
+	}
+
+	if ts, has := columnTypes[typ]; has {
+		return ts
+	}
+	return "string"
+}
+
+// Pascal the pascal case of the id, e.g. user.pet_owner => UserPetOwner
+func Pascal(id string) string {
+	words := strings.FieldsFunc(id, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, "")
+}
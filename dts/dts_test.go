@@ -0,0 +1,45 @@
+package dts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testItem struct {
+	Name     string                 `json:"name"`
+	Tags     []string               `json:"tags,omitempty"`
+	Props    map[string]interface{} `json:"props,omitempty"`
+	Next     *testItem              `json:"next"`
+	Raw      []byte                 `json:"raw"`
+	Created  time.Time              `json:"created_at"`
+	Ignored  string                 `json:"-"`
+	internal string
+}
+
+func TestInterfaces(t *testing.T) {
+	ifs := NewInterfaces()
+	assert.Equal(t, "Item", ifs.Add("Item", testItem{}))
+	assert.Equal(t, `declare interface Item {
+  name: string;
+  tags?: string[];
+  props?: Record<string, any>;
+  next?: Item;
+  raw: string;
+  created_at: string;
+}`, ifs.String())
+}
+
+func TestColumnType(t *testing.T) {
+	assert.Equal(t, "number", ColumnType("bigIncrements", nil))
+	assert.Equal(t, "boolean", ColumnType("boolean", nil))
+	assert.Equal(t, "any", ColumnType("json", nil))
+	assert.Equal(t, "string", ColumnType("datetime", nil))
+	assert.Equal(t, `"enabled" | "disabled"`, ColumnType("enum", []string{"enabled", "disabled"}))
+}
+
+func TestPascal(t *testing.T) {
+	assert.Equal(t, "UserPetOwner", Pascal("user.pet_owner"))
+	assert.Equal(t, "Pet", Pascal("pet"))
+}
@@ -0,0 +1,130 @@
+package dts
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Interfaces the TypeScript interfaces of the Go structs, the struct fields are named by the json tags
+type Interfaces struct {
+	names map[reflect.Type]string
+	defs  map[string]string
+}
+
+// NewInterfaces create the interfaces
+func NewInterfaces() *Interfaces {
+	return &Interfaces{names: map[reflect.Type]string{}, defs: map[string]string{}}
+}
+
+// Add the struct as the named interface, the nested structs are added with the Go type names
+func (ifs *Interfaces) Add(name string, v interface{}) string {
+	typ := reflect.TypeOf(v)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	ifs.names[typ] = name
+	return ifs.TypeOf(typ)
+}
+
+// TypeOf the TypeScript type of the Go type
+func (ifs *Interfaces) TypeOf(typ reflect.Type) string {
+	if typ == timeType {
+		return "string"
+	}
+
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return ifs.TypeOf(typ.Elem())
+
+	case reflect.Bool:
+		return "boolean"
+
+	case reflect.String:
+		return "string"
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+
+	case reflect.Slice, reflect.Array:
+		// The bytes are encoded as base64 strings
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		return ifs.TypeOf(typ.Elem()) + "[]"
+
+	case reflect.Map:
+		return fmt.Sprintf("Record<%s, %s>", ifs.TypeOf(typ.Key()), ifs.TypeOf(typ.Elem()))
+
+	case reflect.Struct:
+		return ifs.structOf(typ)
+	}
+
+	return "any"
+}
+
+func (ifs *Interfaces) structOf(typ reflect.Type) string {
+	name, has := ifs.names[typ]
+	if !has {
+		name = typ.Name()
+		if name == "" {
+			name = "Anonymous"
+		}
+		ifs.names[typ] = name
+	}
+
+	if _, has := ifs.defs[name]; has {
+		return name
+	}
+
+	// Placeholder for the recursive types
+	ifs.defs[name] = ""
+
+	lines := []string{fmt.Sprintf("interface %s {", name)}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		key, opts, _ := strings.Cut(tag, ",")
+		if key == "" {
+			key = field.Name
+		}
+
+		optional := ""
+		if strings.Contains(opts, "omitempty") || field.Type.Kind() == reflect.Ptr {
+			optional = "?"
+		}
+		lines = append(lines, fmt.Sprintf("  %s%s: %s;", key, optional, ifs.TypeOf(field.Type)))
+	}
+	lines = append(lines, "}")
+	ifs.defs[name] = strings.Join(lines, "\n")
+	return name
+}
+
+// String the declarations of the interfaces sorted by the names
+func (ifs *Interfaces) String() string {
+	names := make([]string, 0, len(ifs.defs))
+	for name := range ifs.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	defs := make([]string, 0, len(names))
+	for _, name := range names {
+		defs = append(defs, "declare "+ifs.defs[name])
+	}
+	return strings.Join(defs, "\n\n")
+}
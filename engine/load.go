@@ -11,31 +11,50 @@ import (
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/yao/aigc"
+	"github.com/yaoapp/yao/analytics"
 	"github.com/yaoapp/yao/api"
+	"github.com/yaoapp/yao/billing"
+	"github.com/yaoapp/yao/cache"
 	"github.com/yaoapp/yao/cert"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/connector"
 	"github.com/yaoapp/yao/data"
+	"github.com/yaoapp/yao/dingtalk"
+	"github.com/yaoapp/yao/event"
+	"github.com/yaoapp/yao/feishu"
 	"github.com/yaoapp/yao/flow"
 	"github.com/yaoapp/yao/fs"
 	"github.com/yaoapp/yao/i18n"
 	"github.com/yaoapp/yao/importer"
+	"github.com/yaoapp/yao/mailer"
 	"github.com/yaoapp/yao/moapi"
 	"github.com/yaoapp/yao/model"
 	"github.com/yaoapp/yao/neo"
+	"github.com/yaoapp/yao/notification"
 	"github.com/yaoapp/yao/pack"
 	"github.com/yaoapp/yao/pipe"
 	"github.com/yaoapp/yao/plugin"
+	"github.com/yaoapp/yao/policy"
+	"github.com/yaoapp/yao/pubsub"
 	"github.com/yaoapp/yao/query"
 	"github.com/yaoapp/yao/runtime"
 	"github.com/yaoapp/yao/schedule"
 	"github.com/yaoapp/yao/script"
 	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/slack"
+	"github.com/yaoapp/yao/sms"
 	"github.com/yaoapp/yao/socket"
 	"github.com/yaoapp/yao/store"
 	sui "github.com/yaoapp/yao/sui/api"
 	"github.com/yaoapp/yao/task"
+	"github.com/yaoapp/yao/telegram"
+	"github.com/yaoapp/yao/timeseries"
+	"github.com/yaoapp/yao/trace"
+	"github.com/yaoapp/yao/wasm"
+	"github.com/yaoapp/yao/watcher"
+	"github.com/yaoapp/yao/webhook"
 	"github.com/yaoapp/yao/websocket"
+	"github.com/yaoapp/yao/wework"
 	"github.com/yaoapp/yao/widget"
 	"github.com/yaoapp/yao/widgets"
 )
@@ -153,7 +172,11 @@ func Load(cfg config.Config, options LoadOption) (err error) {
 		printErr(cfg.Mode, "Plugin", err)
 	}
 
-	// Load WASM Application (experimental)
+	// Load WASM Modules (experimental)
+	err = wasm.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "WASM", err)
+	}
 
 	// Load build-in widgets (table / form / chart / ...)
 	err = widgets.Load(cfg)
@@ -246,6 +269,114 @@ func Load(cfg config.Config, options LoadOption) (err error) {
 		}
 	}
 
+	// Load the mailers and the mail templates
+	err = mailer.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Mailer", err)
+	}
+
+	// Load the SMS providers
+	err = sms.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "SMS", err)
+	}
+
+	// Load the plans of the billing
+	err = billing.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Billing", err)
+	}
+
+	// Load the notification templates
+	err = notification.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Notification", err)
+	}
+
+	// Load the cache, the redis cache is shared by the instances
+	err = cache.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Cache", err)
+	}
+
+	// Load the backend of the messages broadcast to the instances
+	err = pubsub.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "PubSub", err)
+	}
+
+	// Load the event subscriptions
+	err = event.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Event", err)
+	}
+
+	// Load the incoming webhooks, and deliver the events to the registered webhooks
+	err = webhook.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Webhook", err)
+	}
+
+	// Load the time-series store of the metrics
+	err = timeseries.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "TimeSeries", err)
+	}
+
+	// Store the traces of the agent turns
+	err = trace.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Trace", err)
+	}
+
+	// Collect the chats, the messages and the tokens into the daily rollups
+	err = analytics.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Analytics", err)
+	}
+
+	// Load the WeCom bots
+	err = wework.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "WeWork", err)
+	}
+
+	// Load the DingTalk bots
+	err = dingtalk.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "DingTalk", err)
+	}
+
+	// Load the Feishu bots
+	err = feishu.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Feishu", err)
+	}
+
+	// Load the Slack apps
+	err = slack.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Slack", err)
+	}
+
+	// Load the Telegram bots
+	err = telegram.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Telegram", err)
+	}
+
+	// Load the watchers of the data directories
+	err = watcher.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Watcher", err)
+	}
+
+	// Load process policies, the registered processes are guarded
+	err = policy.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Policy", err)
+	}
+
 	// Execute AfterLoad Process if exists
 	if share.App.AfterLoad != "" && !options.IgnoredAfterLoad {
 		p, err := process.Of(share.App.AfterLoad, options)
@@ -329,6 +460,12 @@ func Reload(cfg config.Config, options LoadOption) (err error) {
 		printErr(cfg.Mode, "Cert", err)
 	}
 
+	// Load Connectors
+	err = connector.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Connector", err)
+	}
+
 	// Load FileSystem
 	err = fs.Load(cfg)
 	if err != nil {
@@ -377,7 +514,11 @@ func Reload(cfg config.Config, options LoadOption) (err error) {
 		printErr(cfg.Mode, "Plugin", err)
 	}
 
-	// Load WASM Application (experimental)
+	// Load WASM Modules (experimental)
+	err = wasm.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "WASM", err)
+	}
 
 	// Load build-in widgets (table / form / chart / ...)
 	err = widgets.Load(cfg)
@@ -433,6 +574,114 @@ func Reload(cfg config.Config, options LoadOption) (err error) {
 		printErr(cfg.Mode, "Neo", err)
 	}
 
+	// Load the mailers and the mail templates
+	err = mailer.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Mailer", err)
+	}
+
+	// Load the SMS providers
+	err = sms.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "SMS", err)
+	}
+
+	// Load the plans of the billing
+	err = billing.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Billing", err)
+	}
+
+	// Load the notification templates
+	err = notification.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Notification", err)
+	}
+
+	// Load the cache, the redis cache is shared by the instances
+	err = cache.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Cache", err)
+	}
+
+	// Load the backend of the messages broadcast to the instances
+	err = pubsub.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "PubSub", err)
+	}
+
+	// Load the event subscriptions
+	err = event.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Event", err)
+	}
+
+	// Load the incoming webhooks, and deliver the events to the registered webhooks
+	err = webhook.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Webhook", err)
+	}
+
+	// Load the time-series store of the metrics
+	err = timeseries.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "TimeSeries", err)
+	}
+
+	// Store the traces of the agent turns
+	err = trace.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Trace", err)
+	}
+
+	// Collect the chats, the messages and the tokens into the daily rollups
+	err = analytics.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Analytics", err)
+	}
+
+	// Load the WeCom bots
+	err = wework.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "WeWork", err)
+	}
+
+	// Load the DingTalk bots
+	err = dingtalk.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "DingTalk", err)
+	}
+
+	// Load the Feishu bots
+	err = feishu.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Feishu", err)
+	}
+
+	// Load the Slack apps
+	err = slack.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Slack", err)
+	}
+
+	// Load the Telegram bots
+	err = telegram.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Telegram", err)
+	}
+
+	// Load the watchers of the data directories
+	err = watcher.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Watcher", err)
+	}
+
+	// Load process policies, the registered processes are guarded
+	err = policy.Load(cfg)
+	if err != nil {
+		printErr(cfg.Mode, "Policy", err)
+	}
+
 	// Execute AfterLoad Process if exists
 	if share.App.AfterLoad != "" && !options.IgnoredAfterLoad {
 		options.IsReload = true
@@ -0,0 +1,204 @@
+package event
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+// Load the events/*.yao subscriptions, the subscriptions loaded before are replaced
+func Load(cfg config.Config) error {
+	subscriptions := map[string]*Subscription{}
+	messages := []string{}
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("events", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		sub := Subscription{}
+		err = application.Parse(file, bytes, &sub)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		sub.ID = share.ID(root, file)
+		if err := sub.validate(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		subscriptions[sub.ID] = &sub
+		return nil
+	}, exts...)
+
+	lock.Lock()
+	for _, id := range declared {
+		unsubscribe(id)
+	}
+	declared = []int64{}
+	for _, sub := range subscriptions {
+		if sub.Disabled {
+			continue
+		}
+		declared = append(declared, subscribe(sub.On, sub.run))
+	}
+	Subscriptions = subscriptions
+	lock.Unlock()
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+	return err
+}
+
+// Subscribe the events matching the patterns, e.g. chat.created, assistant.* or *, returns the id of the subscriber
+func Subscribe(handler Handler, patterns ...string) int64 {
+	lock.Lock()
+	defer lock.Unlock()
+	return subscribe(patterns, handler)
+}
+
+// Unsubscribe remove the subscriber of the id
+func Unsubscribe(id int64) {
+	lock.Lock()
+	defer lock.Unlock()
+	unsubscribe(id)
+}
+
+// Publish the event, the handlers of the matched subscribers are called in the background
+func Publish(name string, data interface{}) *Event {
+	event := &Event{ID: uuid.New().String(), Name: name, Time: time.Now(), Data: data}
+
+	lock.RLock()
+	handlers := []Handler{}
+	for _, sub := range subscribers {
+		if sub.match(name) {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	lock.RUnlock()
+
+	for _, handler := range handlers {
+		go dispatch(handler, event)
+	}
+	return event
+}
+
+// Match the name of the event matches the pattern or not, the * matches a segment of the name
+func Match(pattern string, name string) bool {
+	if pattern == "*" || pattern == name {
+		return true
+	}
+	matched, err := path.Match(strings.ReplaceAll(pattern, ".", "/"), strings.ReplaceAll(name, ".", "/"))
+	return err == nil && matched
+}
+
+// Map the event as map, the default args of the subscription process
+func (event *Event) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"id":   event.ID,
+		"name": event.Name,
+		"time": event.Time,
+		"data": event.Data,
+	}
+}
+
+func subscribe(patterns []string, handler Handler) int64 {
+	seq++
+	subscribers = append(subscribers, &subscriber{id: seq, patterns: patterns, handler: handler})
+	return seq
+}
+
+func unsubscribe(id int64) {
+	for i, sub := range subscribers {
+		if sub.id == id {
+			subscribers = append(subscribers[:i:i], subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+func dispatch(handler Handler, event *Event) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error("[Event] %s %s: %v", event.Name, event.ID, err)
+		}
+	}()
+	handler(event)
+}
+
+func (sub *subscriber) match(name string) bool {
+	for _, pattern := range sub.patterns {
+		if Match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (sub *Subscription) validate() error {
+	if sub.Process == "" {
+		return fmt.Errorf("the process is required")
+	}
+
+	if len(sub.On) == 0 {
+		return fmt.Errorf("the events to subscribe are required")
+	}
+
+	for _, pattern := range sub.On {
+		if _, err := path.Match(strings.ReplaceAll(pattern, ".", "/"), ""); err != nil {
+			return fmt.Errorf("the event pattern %s is invalid", pattern)
+		}
+	}
+	return nil
+}
+
+// run call the process of the subscription with the event
+func (sub *Subscription) run(event *Event) {
+	p, err := process.Of(sub.Process, sub.Bind(event)...)
+	if err != nil {
+		log.Error("[Event] %s %s: %s", sub.ID, event.Name, err.Error())
+		return
+	}
+
+	_, err = p.Exec()
+	if err != nil {
+		log.Error("[Event] %s %s: %s", sub.ID, event.Name, err.Error())
+	}
+}
+
+// Bind the args of the subscription, the {{ field }} of the args are replaced by the fields of the event
+func (sub *Subscription) Bind(event *Event) []interface{} {
+	if len(sub.Args) == 0 {
+		return []interface{}{event.Map()}
+	}
+
+	data := maps.Of(event.Map()).Dot()
+	args := []interface{}{}
+	for _, arg := range sub.Args {
+		if text, ok := arg.(string); ok && strings.Contains(text, "{{") {
+			arg = helper.Bind(text, data)
+		}
+		args = append(args, arg)
+	}
+	return args
+}
@@ -0,0 +1,65 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	assert.True(t, Match("*", ChatCreated))
+	assert.True(t, Match("chat.created", ChatCreated))
+	assert.True(t, Match("assistant.*", AssistantUpdated))
+	assert.True(t, Match("*.failed", JobFailed))
+	assert.False(t, Match("assistant.*", ChatCreated))
+	assert.False(t, Match("job.*", "job.run.failed"))
+	assert.False(t, Match("chat", ChatCreated))
+}
+
+func TestPublish(t *testing.T) {
+	received := make(chan *Event, 4)
+	id := Subscribe(func(event *Event) { received <- event }, "assistant.*", JobFailed)
+	defer Unsubscribe(id)
+
+	panicked := Subscribe(func(event *Event) { panic("the handler panics") }, "*")
+	defer Unsubscribe(panicked)
+
+	Publish(ChatCreated, map[string]interface{}{"chat_id": "c1"})
+	published := Publish(AssistantUpdated, map[string]interface{}{"assistant_id": "a1"})
+
+	select {
+	case event := <-received:
+		assert.Equal(t, published.ID, event.ID)
+		assert.Equal(t, AssistantUpdated, event.Name)
+		assert.Equal(t, "a1", event.Data.(map[string]interface{})["assistant_id"])
+	case <-time.After(time.Second):
+		t.Fatal("the event is not received")
+	}
+
+	Unsubscribe(id)
+	Publish(JobFailed, nil)
+	select {
+	case event := <-received:
+		t.Fatalf("the event %s is received after unsubscribed", event.Name)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscriptionBind(t *testing.T) {
+	event := &Event{ID: "e1", Name: AssistantUpdated, Data: map[string]interface{}{"assistant_id": "a1"}}
+
+	args := (&Subscription{}).Bind(event)
+	assert.Len(t, args, 1)
+	assert.Equal(t, "e1", args[0].(map[string]interface{})["id"])
+
+	args = (&Subscription{Args: []interface{}{"{{ name }}", "{{ data.assistant_id }}", 1}}).Bind(event)
+	assert.Equal(t, []interface{}{AssistantUpdated, "a1", 1}, args)
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	assert.Error(t, (&Subscription{On: []string{"chat.*"}}).validate())
+	assert.Error(t, (&Subscription{Process: "flows.notify"}).validate())
+	assert.Error(t, (&Subscription{Process: "flows.notify", On: []string{"chat.["}}).validate())
+	assert.NoError(t, (&Subscription{Process: "flows.notify", On: []string{"chat.*"}}).validate())
+}
@@ -0,0 +1,27 @@
+package event
+
+import (
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("event", map[string]process.Handler{
+		"publish": processPublish,
+	})
+}
+
+// processPublish event.Publish(name, data?)
+func processPublish(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	name := process.ArgsString(0)
+	if name == "" {
+		exception.New("the name of the event is required", 400).Throw()
+	}
+
+	var data interface{}
+	if process.NumOfArgs() > 1 {
+		data = process.Args[1]
+	}
+	return Publish(name, data)
+}
@@ -0,0 +1,57 @@
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+// The events published by the subsystems
+const (
+	ChatCreated      = "chat.created"
+	MessageSaved     = "message.saved"
+	AssistantUpdated = "assistant.updated"
+	JobFailed        = "job.failed"
+	UsageRecorded    = "usage.recorded"
+)
+
+// Event the event published to the bus
+type Event struct {
+	ID   string      `json:"id"`
+	Name string      `json:"name"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Subscription the subscription of the events/*.yao, the process is called with the event if the args are not set
+//
+//	{
+//	  "name": "Notify the owner",
+//	  "on": ["assistant.*", "job.failed"],
+//	  "process": "flows.notify",
+//	  "args": ["{{ name }}", "{{ data.assistant_id }}"]
+//	}
+type Subscription struct {
+	ID       string        `json:"-"`
+	Name     string        `json:"name,omitempty"`
+	On       []string      `json:"on"`
+	Process  string        `json:"process"`
+	Args     []interface{} `json:"args,omitempty"`
+	Disabled bool          `json:"disabled,omitempty"`
+}
+
+// Handler the handler of the subscriber
+type Handler func(event *Event)
+
+type subscriber struct {
+	id       int64
+	patterns []string
+	handler  Handler
+}
+
+// Subscriptions the loaded subscriptions
+var Subscriptions = map[string]*Subscription{}
+
+var subscribers = []*subscriber{}
+var declared = []int64{}
+var seq int64 = 0
+var lock sync.RWMutex
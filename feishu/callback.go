@@ -0,0 +1,185 @@
+package feishu
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+)
+
+// event the payload of the event subscription, schema 2.0, or the url verification
+type event struct {
+	Encrypt   string `json:"encrypt,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Challenge string `json:"challenge,omitempty"`
+	Token     string `json:"token,omitempty"`
+	Header    struct {
+		EventID   string `json:"event_id"`
+		EventType string `json:"event_type"`
+		Token     string `json:"token"`
+	} `json:"header"`
+	Event struct {
+		Sender struct {
+			SenderID struct {
+				OpenID string `json:"open_id"`
+			} `json:"sender_id"`
+			SenderType string `json:"sender_type"`
+		} `json:"sender"`
+		Message struct {
+			MessageID   string `json:"message_id"`
+			ChatID      string `json:"chat_id"`
+			ChatType    string `json:"chat_type"`
+			MessageType string `json:"message_type"`
+			Content     string `json:"content"`
+		} `json:"message"`
+	} `json:"event"`
+}
+
+// received the ids of the events received in the last 10 minutes, the retries of Feishu are answered once
+var received = map[string]time.Time{}
+var receivedLock sync.Mutex
+
+// SetRoutes the event subscription of the bots, the request url of the bot feishus/support.yao is <path>/support
+//
+//	POST <path>/:id  The url verification and the im.message.receive_v1 events, the reply is sent by the API
+func SetRoutes(router *gin.Engine, path string) {
+	router.POST(path+"/:id", handleEvent)
+}
+
+func handleEvent(c *gin.Context) {
+	bot, err := Select(c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"code": 404, "message": err.Error()})
+		return
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	payload, status, err := bot.parse(c.GetHeader("X-Lark-Request-Timestamp"), c.GetHeader("X-Lark-Request-Nonce"), c.GetHeader("X-Lark-Signature"), raw)
+	if err != nil {
+		c.JSON(status, gin.H{"code": status, "message": err.Error()})
+		return
+	}
+
+	if payload.Type == "url_verification" {
+		c.JSON(200, gin.H{"challenge": payload.Challenge})
+		return
+	}
+
+	if payload.Header.EventType != "im.message.receive_v1" || payload.Event.Sender.SenderType == "app" {
+		c.JSON(200, gin.H{})
+		return
+	}
+
+	msg := &Message{
+		EventID:   payload.Header.EventID,
+		MessageID: payload.Event.Message.MessageID,
+		ChatID:    payload.Event.Message.ChatID,
+		ChatType:  payload.Event.Message.ChatType,
+		Type:      payload.Event.Message.MessageType,
+		Sender:    payload.Event.Sender.SenderID.OpenID,
+	}
+	if msg.Type == "text" {
+		msg.Text = text(payload.Event.Message.Content)
+	}
+
+	// Feishu retries the event not answered in 3 seconds, the message is answered in the background
+	if !duplicated(msg.EventID) {
+		go reply(bot, msg)
+	}
+	c.JSON(200, gin.H{})
+}
+
+// parse verify the signature and the token of the event, the encrypted event is decrypted
+func (bot *Bot) parse(timestamp string, nonce string, signature string, raw []byte) (*event, int, error) {
+	payload := &event{}
+	if err := jsoniter.Unmarshal(raw, payload); err != nil {
+		return nil, 400, errInvalid
+	}
+
+	if bot.EncryptKey != "" {
+		if signature != "" && Signature(timestamp, nonce, bot.EncryptKey, raw) != signature {
+			return nil, 401, errSignature
+		}
+
+		if payload.Encrypt == "" {
+			return nil, 400, errInvalid
+		}
+
+		plain, err := Decrypt(bot.EncryptKey, payload.Encrypt)
+		if err != nil {
+			return nil, 400, errInvalid
+		}
+
+		payload = &event{}
+		if err := jsoniter.Unmarshal(plain, payload); err != nil {
+			return nil, 400, errInvalid
+		}
+	}
+
+	token := payload.Header.Token
+	if payload.Type == "url_verification" {
+		token = payload.Token
+	}
+
+	if token != bot.VerificationToken {
+		return nil, 401, errToken
+	}
+	return payload, 200, nil
+}
+
+// reply answer the message and reply to it
+func reply(bot *Bot, msg *Message) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error("[Feishu] %s %s: %v", bot.ID, msg.Sender, err)
+		}
+	}()
+
+	text, err := bot.Answer(msg)
+	if err != nil {
+		log.Error("[Feishu] %s %s: %s", bot.ID, msg.Sender, err.Error())
+		return
+	}
+
+	if text == "" {
+		return
+	}
+
+	err = bot.Reply(msg.MessageID, text)
+	if err != nil {
+		log.Error("[Feishu] %s reply to %s: %s", bot.ID, msg.MessageID, err.Error())
+	}
+}
+
+// duplicated the event is received before or not
+func duplicated(id string) bool {
+	if id == "" {
+		return false
+	}
+
+	receivedLock.Lock()
+	defer receivedLock.Unlock()
+
+	now := time.Now()
+	if at, has := received[id]; has && now.Sub(at) < 10*time.Minute {
+		return true
+	}
+
+	if len(received) > 1000 {
+		for key, at := range received {
+			if now.Sub(at) >= 10*time.Minute {
+				delete(received, key)
+			}
+		}
+	}
+	received[id] = now
+	return false
+}
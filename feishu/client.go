@@ -0,0 +1,138 @@
+package feishu
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// tokens the tenant access tokens of the bots
+var tokens = map[string]accessToken{}
+var tokenLock sync.Mutex
+
+type accessToken struct {
+	value   string
+	expires time.Time
+}
+
+type apiResponse struct {
+	Code              int    `json:"code"`
+	Msg               string `json:"msg"`
+	TenantAccessToken string `json:"tenant_access_token,omitempty"`
+	Expire            int    `json:"expire,omitempty"`
+}
+
+// Reply to the message, with the interactive card if the card of the bot is on
+func (bot *Bot) Reply(messageID string, text string) error {
+	return bot.send("/open-apis/im/v1/messages/"+url.PathEscape(messageID)+"/reply", bot.content(text))
+}
+
+// Send the message to the user of the open_id, or to the chat if the id starts with oc_
+func (bot *Bot) Send(id string, text string) error {
+	idType := "open_id"
+	if strings.HasPrefix(id, "oc_") {
+		idType = "chat_id"
+	}
+
+	message := bot.content(text)
+	message["receive_id"] = id
+	return bot.send("/open-apis/im/v1/messages?receive_id_type="+idType, message)
+}
+
+// content the message of the text, the interactive card if the card of the bot is on
+func (bot *Bot) content(text string) map[string]interface{} {
+	if bot.Card {
+		content, _ := jsoniter.MarshalToString(Card(bot.Name, text))
+		return map[string]interface{}{"msg_type": "interactive", "content": content}
+	}
+	content, _ := jsoniter.MarshalToString(map[string]interface{}{"text": text})
+	return map[string]interface{}{"msg_type": "text", "content": content}
+}
+
+// send the message, the tenant access token is refreshed once if it is invalid
+func (bot *Bot) send(path string, message map[string]interface{}) error {
+	payload, err := jsoniter.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	for retry := 0; ; retry++ {
+		token, err := bot.TenantAccessToken(retry > 0)
+		if err != nil {
+			return err
+		}
+
+		res := apiResponse{}
+		err = bot.call(path, token, payload, &res)
+		if err != nil {
+			return err
+		}
+
+		switch res.Code {
+		case 0:
+			return nil
+		case 99991661, 99991663, 99991668: // The tenant access token is invalid or expired
+			if retry == 0 {
+				continue
+			}
+		}
+		return fmt.Errorf("feishu %s %d: %s", path, res.Code, res.Msg)
+	}
+}
+
+// TenantAccessToken the tenant access token of the app, cached until 5 minutes before it expires
+func (bot *Bot) TenantAccessToken(refresh bool) (string, error) {
+	tokenLock.Lock()
+	defer tokenLock.Unlock()
+
+	if token, has := tokens[bot.AppID]; has && !refresh && time.Now().Before(token.expires) {
+		return token.value, nil
+	}
+
+	payload, _ := jsoniter.Marshal(map[string]string{"app_id": bot.AppID, "app_secret": bot.AppSecret})
+	res := apiResponse{}
+	err := bot.call("/open-apis/auth/v3/tenant_access_token/internal", "", payload, &res)
+	if err != nil {
+		return "", err
+	}
+
+	if res.Code != 0 || res.TenantAccessToken == "" {
+		return "", fmt.Errorf("feishu tenant_access_token %d: %s", res.Code, res.Msg)
+	}
+
+	expires := time.Duration(res.Expire)*time.Second - 5*time.Minute
+	tokens[bot.AppID] = accessToken{value: res.TenantAccessToken, expires: time.Now().Add(expires)}
+	return res.TenantAccessToken, nil
+}
+
+func (bot *Bot) call(path string, token string, payload []byte, res interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, bot.API+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The errors of the API are the JSON of the code and the msg, with the 4xx status
+	err = jsoniter.NewDecoder(resp.Body).Decode(res)
+	if err != nil {
+		return fmt.Errorf("feishu %s responds %d", path, resp.StatusCode)
+	}
+	return nil
+}
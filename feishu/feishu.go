@@ -0,0 +1,268 @@
+package feishu
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/neo/assistant"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	"github.com/yaoapp/yao/share"
+)
+
+// Bot the Feishu (Lark) app of the feishus/*.yao, the messages of the events are answered by the assistant or the process
+//
+//	{
+//	  "name": "Support",
+//	  "app_id": "$ENV.FEISHU_APP_ID",
+//	  "app_secret": "$ENV.FEISHU_APP_SECRET",
+//	  "verification_token": "$ENV.FEISHU_VERIFICATION_TOKEN",
+//	  "encrypt_key": "$ENV.FEISHU_ENCRYPT_KEY",
+//	  "assistant": "support",
+//	  "card": true
+//	}
+type Bot struct {
+	ID                string `json:"-"`
+	Name              string `json:"name,omitempty"`
+	AppID             string `json:"app_id"`
+	AppSecret         string `json:"app_secret"`
+	VerificationToken string `json:"verification_token"`    // The token of the events
+	EncryptKey        string `json:"encrypt_key,omitempty"` // The events are encrypted and signed if it is set
+	Assistant         string `json:"assistant,omitempty"`   // The assistant answering the text messages
+	Process           string `json:"process,omitempty"`     // The process called with the event instead of the assistant, returns the reply
+	Card              bool   `json:"card,omitempty"`        // Reply with the interactive card of markdown, the text message if false
+	API               string `json:"api,omitempty"`         // https://open.feishu.cn by default, https://open.larksuite.com for Lark
+}
+
+// Message the text message of the im.message.receive_v1 event
+type Message struct {
+	EventID   string `json:"event_id"`
+	MessageID string `json:"message_id"`
+	ChatID    string `json:"chat_id"`
+	ChatType  string `json:"chat_type"` // p2p or group
+	Type      string `json:"type"`
+	Text      string `json:"text,omitempty"`
+	Sender    string `json:"sender"` // The open_id of the sender
+}
+
+// Bots the loaded bots
+var Bots = map[string]*Bot{}
+var lock sync.RWMutex
+
+// The errors of the events
+var (
+	errInvalid   = errors.New("the event is invalid")
+	errSignature = errors.New("the signature is invalid")
+	errToken     = errors.New("the verification token is invalid")
+)
+
+// mentionRe the mentions of the group messages, e.g. @_user_1
+var mentionRe = regexp.MustCompile(`@_user_\d+\s*`)
+
+// Load the feishus/*.yao bots
+func Load(cfg config.Config) error {
+	bots := map[string]*Bot{}
+	messages := []string{}
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("feishus", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		bot := Bot{}
+		err = application.Parse(file, bytes, &bot)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		bot.ID = share.ID(root, file)
+		if err := bot.validate(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		bots[bot.ID] = &bot
+		return nil
+	}, exts...)
+
+	lock.Lock()
+	Bots = bots
+	lock.Unlock()
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+	return err
+}
+
+// Select the bot
+func Select(id string) (*Bot, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	bot, has := Bots[id]
+	if !has {
+		return nil, fmt.Errorf("the feishu bot %s does not exist", id)
+	}
+	return bot, nil
+}
+
+// Answer the reply of the message, empty if the message is not answered
+func (bot *Bot) Answer(msg *Message) (string, error) {
+	if bot.Process != "" {
+		p, err := process.Of(bot.Process, bot.ID, msg.Map())
+		if err != nil {
+			return "", err
+		}
+
+		res, err := p.Exec()
+		if err != nil {
+			return "", err
+		}
+
+		if res == nil {
+			return "", nil
+		}
+		return fmt.Sprintf("%v", res), nil
+	}
+
+	// The assistant answers the text messages only
+	if msg.Type != "text" || msg.Text == "" {
+		return "", nil
+	}
+
+	ast, err := assistant.Get(bot.Assistant)
+	if err != nil {
+		return "", err
+	}
+
+	// The user chats with the assistant in a single chat, the members of the group share the chat of the group
+	sid := fmt.Sprintf("feishu:%s:%s", bot.ID, msg.Sender)
+	cid := sid
+	if msg.ChatType == "group" {
+		cid = fmt.Sprintf("feishu:%s:%s", bot.ID, msg.ChatID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	return ast.Reply(chatctx.Context{Context: ctx, Sid: sid, ChatID: cid, AssistantID: ast.ID}, msg.Text)
+}
+
+// Map the message as map, the args of the process
+func (msg *Message) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"event_id":   msg.EventID,
+		"message_id": msg.MessageID,
+		"chat_id":    msg.ChatID,
+		"chat_type":  msg.ChatType,
+		"type":       msg.Type,
+		"text":       msg.Text,
+		"sender":     msg.Sender,
+	}
+}
+
+// Decrypt the encrypted event, AES-256-CBC with the sha256 of the encrypt key, the IV is the first block of the data
+func Decrypt(encryptKey string, encrypted string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("the size of the encrypted event is invalid")
+	}
+
+	key := sha256.Sum256([]byte(encryptKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	iv, data := data[:aes.BlockSize], data[aes.BlockSize:]
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	padding := int(plain[len(plain)-1])
+	if padding < 1 || padding > aes.BlockSize {
+		return nil, fmt.Errorf("the padding of the encrypted event is invalid")
+	}
+	return plain[:len(plain)-padding], nil
+}
+
+// Signature the X-Lark-Signature of the event, sha256 of the timestamp, nonce, encrypt key and the body
+func Signature(timestamp string, nonce string, encryptKey string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(timestamp + nonce + encryptKey))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Card the interactive card of the markdown text
+func Card(title string, text string) map[string]interface{} {
+	card := map[string]interface{}{
+		"config":   map[string]interface{}{"wide_screen_mode": true},
+		"elements": []interface{}{map[string]interface{}{"tag": "markdown", "content": text}},
+	}
+	if title != "" {
+		card["header"] = map[string]interface{}{
+			"title": map[string]interface{}{"tag": "plain_text", "content": title},
+		}
+	}
+	return card
+}
+
+// text the text of the message content, the mentions are removed
+func text(content string) string {
+	data := struct {
+		Text string `json:"text"`
+	}{}
+	jsoniter.UnmarshalFromString(content, &data)
+	return strings.TrimSpace(mentionRe.ReplaceAllString(data.Text, ""))
+}
+
+func (bot *Bot) validate() error {
+	for _, value := range []*string{&bot.AppID, &bot.AppSecret, &bot.VerificationToken, &bot.EncryptKey} {
+		if strings.HasPrefix(*value, "$ENV.") {
+			*value = os.Getenv(strings.TrimPrefix(*value, "$ENV."))
+		}
+	}
+
+	if bot.AppID == "" || bot.AppSecret == "" {
+		return fmt.Errorf("the app_id and app_secret are required")
+	}
+
+	if bot.VerificationToken == "" {
+		return fmt.Errorf("the verification_token is required")
+	}
+
+	if bot.Assistant == "" && bot.Process == "" {
+		return fmt.Errorf("the assistant or the process is required")
+	}
+
+	if bot.API == "" {
+		bot.API = "https://open.feishu.cn"
+	}
+	bot.API = strings.TrimRight(bot.API, "/")
+	return nil
+}
@@ -0,0 +1,134 @@
+package feishu
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func encrypt(key string, plain string) string {
+	sum := sha256.Sum256([]byte(key))
+	block, _ := aes.NewCipher(sum[:])
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	data := append([]byte(plain), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	iv := []byte("0123456789abcdef")
+	out := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, data)
+	return base64.StdEncoding.EncodeToString(append(iv, out...))
+}
+
+func testBot(api string) *Bot {
+	return &Bot{ID: "support", Name: "Support", AppID: "cli_a1", AppSecret: "secret", VerificationToken: "token", Process: "scripts.feishu.Reply", API: api}
+}
+
+func TestDecrypt(t *testing.T) {
+	plain, err := Decrypt("key", encrypt("key", `{"challenge":"c1"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"challenge":"c1"}`, string(plain))
+
+	_, err = Decrypt("key", base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+}
+
+func TestText(t *testing.T) {
+	assert.Equal(t, "hello", text(`{"text":"@_user_1 hello"}`))
+	assert.Equal(t, "", text(`invalid`))
+}
+
+func TestCard(t *testing.T) {
+	card := Card("Support", "**Done**")
+	assert.Equal(t, "Support", card["header"].(map[string]interface{})["title"].(map[string]interface{})["content"])
+	assert.Equal(t, "**Done**", card["elements"].([]interface{})[0].(map[string]interface{})["content"])
+	assert.NotContains(t, Card("", "text"), "header")
+}
+
+func TestParse(t *testing.T) {
+	bot := testBot("")
+	payload, status, err := bot.parse("", "", "", []byte(`{"type":"url_verification","challenge":"c1","token":"token"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "c1", payload.Challenge)
+
+	_, status, _ = bot.parse("", "", "", []byte(`{"schema":"2.0","header":{"token":"other"}}`))
+	assert.Equal(t, 401, status)
+
+	_, status, _ = bot.parse("", "", "", []byte(`invalid`))
+	assert.Equal(t, 400, status)
+
+	bot.EncryptKey = "key"
+	raw := []byte(`{"encrypt":"` + encrypt("key", `{"schema":"2.0","header":{"event_id":"e1","event_type":"im.message.receive_v1","token":"token"}}`) + `"}`)
+	payload, status, err = bot.parse("1700000000", "n1", Signature("1700000000", "n1", "key", raw), raw)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "e1", payload.Header.EventID)
+
+	_, status, _ = bot.parse("1700000000", "n1", "invalid", raw)
+	assert.Equal(t, 401, status)
+
+	_, status, _ = bot.parse("", "", "", []byte(`{"schema":"2.0","header":{"token":"token"}}`))
+	assert.Equal(t, 400, status)
+}
+
+func TestHandleEvent(t *testing.T) {
+	defer func(bots map[string]*Bot) { Bots = bots }(Bots)
+	Bots = map[string]*Bot{"support": testBot("http://127.0.0.1:0")}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetRoutes(router, "/api/__yao/feishu")
+
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("POST", "/api/__yao/feishu/support", strings.NewReader(`{"type":"url_verification","challenge":"c1","token":"token"}`)))
+	assert.Equal(t, 200, res.Code)
+	assert.JSONEq(t, `{"challenge":"c1"}`, res.Body.String())
+
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("POST", "/api/__yao/feishu/unknown", strings.NewReader(`{}`)))
+	assert.Equal(t, 404, res.Code)
+
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("POST", "/api/__yao/feishu/support", strings.NewReader(`{"schema":"2.0","header":{"event_type":"im.chat.updated_v1","token":"token"}}`)))
+	assert.Equal(t, 200, res.Code)
+}
+
+func TestReply(t *testing.T) {
+	tokens = map[string]accessToken{}
+	issued := 0
+	replied := map[string]interface{}{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/open-apis/auth/v3/tenant_access_token/internal":
+			issued++
+			w.Write([]byte(`{"code":0,"tenant_access_token":"t` + string(rune('0'+issued)) + `","expire":7200}`))
+
+		case "/open-apis/im/v1/messages/om_1/reply":
+			// The first token is expired
+			if r.Header.Get("Authorization") == "Bearer t1" {
+				w.Write([]byte(`{"code":99991663,"msg":"token expired"}`))
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			jsoniter.Unmarshal(body, &replied)
+			w.Write([]byte(`{"code":0,"msg":"success"}`))
+		}
+	}))
+	defer srv.Close()
+
+	bot := testBot(srv.URL)
+	bot.Card = true
+	assert.NoError(t, bot.Reply("om_1", "**Done**"))
+	assert.Equal(t, 2, issued)
+	assert.Equal(t, "interactive", replied["msg_type"])
+	assert.Contains(t, replied["content"], `"content":"**Done**"`)
+}
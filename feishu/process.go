@@ -0,0 +1,43 @@
+package feishu
+
+import (
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("yao.feishu", map[string]process.Handler{
+		"send":  processSend,
+		"reply": processReply,
+	})
+}
+
+// processSend yao.feishu.Send(bot, open_id|chat_id, text)
+func processSend(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	bot, err := Select(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+
+	err = bot.Send(process.ArgsString(1), process.ArgsString(2))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return nil
+}
+
+// processReply yao.feishu.Reply(bot, message_id, text)
+func processReply(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	bot, err := Select(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+
+	err = bot.Reply(process.ArgsString(1), process.ArgsString(2))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// sendSendGrid send the mail by the v3 mail send API of SendGrid
+func (m *Mailer) sendSendGrid(msg *message) error {
+	personalization := map[string]interface{}{}
+	for _, field := range []string{"to", "cc", "bcc"} {
+		if addresses := msg.addresses[field]; len(addresses) > 0 {
+			personalization[field] = sendGridAddresses(addresses)
+		}
+	}
+
+	content := []interface{}{}
+	if msg.mail.Text != "" {
+		content = append(content, map[string]string{"type": "text/plain", "value": msg.mail.Text})
+	}
+	if msg.mail.HTML != "" {
+		content = append(content, map[string]string{"type": "text/html", "value": msg.mail.HTML})
+	}
+
+	payload := map[string]interface{}{
+		"personalizations": []interface{}{personalization},
+		"from":             sendGridAddress(msg.from),
+		"subject":          msg.mail.Subject,
+		"content":          content,
+		"headers":          map[string]string{"Message-ID": msg.id},
+	}
+
+	for name, value := range msg.mail.Headers {
+		payload["headers"].(map[string]string)[name] = value
+	}
+
+	if msg.replyTo != nil {
+		payload["reply_to"] = sendGridAddress(msg.replyTo)
+	}
+
+	if len(msg.files) > 0 {
+		attachments := []interface{}{}
+		for _, f := range msg.files {
+			attachment := map[string]string{
+				"content":     base64.StdEncoding.EncodeToString(f.data),
+				"type":        f.ContentType,
+				"filename":    f.Name,
+				"disposition": "attachment",
+			}
+			if f.ContentID != "" {
+				attachment["disposition"] = "inline"
+				attachment["content_id"] = f.ContentID
+			}
+			attachments = append(attachments, attachment)
+		}
+		payload["attachments"] = attachments
+	}
+
+	body, err := jsoniter.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, m.API+"/v3/mail/send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.Key)
+	return do(req, "sendgrid")
+}
+
+// sendMailgun send the MIME message by the messages.mime API of Mailgun, the DKIM signature is kept
+func (m *Mailer) sendMailgun(msg *message) error {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("to", strings.Join(msg.recipients, ","))
+	part, err := form.CreateFormFile("message", "message.mime")
+	if err != nil {
+		return err
+	}
+	part.Write(msg.raw)
+	form.Close()
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/v3/%s/messages.mime", m.API, url.PathEscape(m.Domain)), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.SetBasicAuth("api", m.Key)
+	return do(req, "mailgun")
+}
+
+// do the request of the API, the body of the response is the error if the status is not 2xx
+func do(req *http.Request, name string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s responds %d: %s", name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func sendGridAddress(address *mail.Address) map[string]string {
+	res := map[string]string{"email": address.Address}
+	if address.Name != "" {
+		res["name"] = address.Name
+	}
+	return res
+}
+
+func sendGridAddresses(addresses []*mail.Address) []interface{} {
+	res := []interface{}{}
+	for _, address := range addresses {
+		res = append(res, sendGridAddress(address))
+	}
+	return res
+}
@@ -0,0 +1,147 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dkimHeaders the headers signed by default
+var dkimHeaders = []string{"From", "To", "Cc", "Reply-To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type"}
+
+var wspRe = regexp.MustCompile(`[ \t]+`)
+
+// signer the RSA key of the DKIM, PKCS#1 or PKCS#8 of PEM
+func (dkim *DKIM) signer() (*rsa.PrivateKey, error) {
+	if dkim.Domain == "" || dkim.Selector == "" {
+		return nil, fmt.Errorf("the domain and the selector are required")
+	}
+
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(dkim.PrivateKey, `\n`, "\n")))
+	if block == nil {
+		return nil, fmt.Errorf("the private key is not PEM")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+// Sign the message with the DKIM-Signature of rsa-sha256, the header and the body are canonicalized by relaxed
+func (dkim *DKIM) Sign(raw []byte) ([]byte, error) {
+	key, err := dkim.signer()
+	if err != nil {
+		return nil, err
+	}
+
+	header, body := raw, []byte{}
+	if i := bytes.Index(raw, []byte("\r\n\r\n")); i >= 0 {
+		header, body = raw[:i+2], raw[i+4:]
+	}
+
+	bh := sha256.Sum256(relaxedBody(body))
+	fields := parseHeader(header)
+
+	names := dkim.Headers
+	if len(names) == 0 {
+		names = dkimHeaders
+	}
+
+	signed := []string{}
+	hash := sha256.New()
+	for _, name := range names {
+		if value, has := fields[strings.ToLower(name)]; has {
+			hash.Write([]byte(relaxedHeader(name, value) + "\r\n"))
+			signed = append(signed, strings.ToLower(name))
+		}
+	}
+
+	value := fmt.Sprintf("v=1; a=rsa-sha256; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		dkim.Domain, dkim.Selector, time.Now().Unix(), strings.Join(signed, ":"), base64.StdEncoding.EncodeToString(bh[:]))
+	hash.Write([]byte(relaxedHeader("DKIM-Signature", value)))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	res := []byte("DKIM-Signature: " + value + base64.StdEncoding.EncodeToString(signature) + "\r\n")
+	return append(res, raw...), nil
+}
+
+// parseHeader the unfolded fields of the header, the name is lower case, the first one is used if the field is repeated
+func parseHeader(header []byte) map[string]string {
+	fields := map[string]string{}
+	lines := strings.Split(strings.TrimRight(string(header), "\r\n"), "\r\n")
+	name, value := "", ""
+	flush := func() {
+		if name == "" {
+			return
+		}
+		if _, has := fields[name]; !has {
+			fields[name] = value
+		}
+	}
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			value += line
+			continue
+		}
+
+		flush()
+		i := strings.Index(line, ":")
+		if i < 0 {
+			name = ""
+			continue
+		}
+		name, value = strings.ToLower(strings.TrimSpace(line[:i])), line[i+1:]
+	}
+	flush()
+	return fields
+}
+
+// relaxedHeader the header of the relaxed canonicalization, without the CRLF
+func relaxedHeader(name string, value string) string {
+	value = strings.ReplaceAll(strings.ReplaceAll(value, "\r\n", ""), "\n", "")
+	value = strings.TrimSpace(wspRe.ReplaceAllString(value, " "))
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + value
+}
+
+// relaxedBody the body of the relaxed canonicalization
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(wspRe.ReplaceAllString(line, " "), " ")
+	}
+
+	// The empty lines at the end of the body are ignored
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
@@ -0,0 +1,72 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelaxed(t *testing.T) {
+	assert.Equal(t, "subject:Hello World", relaxedHeader("Subject ", " Hello \t World \r\n"))
+	assert.Equal(t, " C\r\nD E\r\n", string(relaxedBody([]byte(" C \r\nD \t E\r\n\r\n\r\n"))))
+	assert.Equal(t, "", string(relaxedBody([]byte("\r\n\r\n"))))
+}
+
+func TestSign(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	dkim := &DKIM{Domain: "example.com", Selector: "yao", PrivateKey: string(private)}
+
+	msg, err := build(&Mail{From: "noreply@example.com", To: []string{"max@example.com"}, Subject: "Hi", Text: "Hi  there \n\n"})
+	assert.NoError(t, err)
+
+	raw, err := dkim.Sign(msg.raw)
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(raw, []byte("DKIM-Signature: v=1; a=rsa-sha256; c=relaxed/relaxed; d=example.com; s=yao; ")))
+
+	// Verify the signature by the public key
+	i := bytes.Index(raw, []byte("\r\n"))
+	signature := string(raw[len("DKIM-Signature: "):i])
+	header, body := raw[i+2:], []byte{}
+	if j := bytes.Index(header, []byte("\r\n\r\n")); j >= 0 {
+		header, body = header[:j+2], header[j+4:]
+	}
+
+	tags := map[string]string{}
+	for _, tag := range strings.Split(signature, "; ") {
+		kv := strings.SplitN(tag, "=", 2)
+		tags[kv[0]] = kv[1]
+	}
+	assert.Equal(t, "from:to:subject:date:message-id:mime-version:content-type", tags["h"])
+
+	bh := sha256.Sum256(relaxedBody(body))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(bh[:]), tags["bh"])
+
+	fields := parseHeader(header)
+	hash := sha256.New()
+	for _, name := range strings.Split(tags["h"], ":") {
+		hash.Write([]byte(relaxedHeader(name, fields[name]) + "\r\n"))
+	}
+	hash.Write([]byte(relaxedHeader("DKIM-Signature", strings.TrimSuffix(signature, tags["b"]))))
+
+	sig, _ := base64.StdEncoding.DecodeString(tags["b"])
+	assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash.Sum(nil), sig))
+
+	// PKCS#8 with the escaped line breaks of the environment variables
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+	escaped := strings.ReplaceAll(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})), "\n", `\n`)
+	_, err = (&DKIM{Domain: "example.com", Selector: "yao", PrivateKey: escaped}).signer()
+	assert.NoError(t, err)
+}
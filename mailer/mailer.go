@@ -0,0 +1,257 @@
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+// Load the mailers/*.yao mailers and the mails/*.yao templates, the MJML of the templates are compiled
+func Load(cfg config.Config) error {
+	mailers := map[string]*Mailer{}
+	templates := map[string]*Template{}
+	messages := []string{}
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("mailers", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		m := Mailer{}
+		err = application.Parse(file, bytes, &m)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		m.ID = share.ID(root, file)
+		if err := m.validate(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		mailers[m.ID] = &m
+		return nil
+	}, exts...)
+	if err != nil {
+		messages = append(messages, err.Error())
+	}
+
+	err = application.App.Walk("mails", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		tpl := Template{}
+		err = application.Parse(file, bytes, &tpl)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		tpl.ID = share.ID(root, file)
+		if err := tpl.compile(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		templates[tpl.ID] = &tpl
+		return nil
+	}, exts...)
+	if err != nil {
+		messages = append(messages, err.Error())
+	}
+
+	lock.Lock()
+	Mailers = mailers
+	Templates = templates
+	lock.Unlock()
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+	return nil
+}
+
+// Select the mailer, the mailer "default" is the mail server of YAO_SMTP_HOST if it is not defined
+func Select(id string) (*Mailer, error) {
+	if id == "" {
+		id = "default"
+	}
+
+	lock.RLock()
+	m, has := Mailers[id]
+	lock.RUnlock()
+	if has {
+		return m, nil
+	}
+
+	if id == "default" && config.Conf.SMTP.Host != "" {
+		setting := config.Conf.SMTP
+		return &Mailer{
+			ID:       "default",
+			Type:     TypeSMTP,
+			From:     setting.From,
+			Host:     setting.Host,
+			Port:     setting.Port,
+			Username: setting.Username,
+			Password: setting.Password,
+		}, nil
+	}
+
+	if id == "default" {
+		return nil, fmt.Errorf("the default mailer is not configured, add mailers/default.yao or set YAO_SMTP_HOST")
+	}
+	return nil, fmt.Errorf("the mailer %s does not exist", id)
+}
+
+// SelectTemplate the mail template
+func SelectTemplate(id string) (*Template, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	tpl, has := Templates[id]
+	if !has {
+		return nil, fmt.Errorf("the mail template %s does not exist", id)
+	}
+	return tpl, nil
+}
+
+// Send the mail, returns the Message-ID of the mail.
+// The subject, text and html not given are rendered by the template, the sender is the one of the template or the mailer if it is not given.
+func Send(m Mail) (string, error) {
+	if m.Template != "" {
+		tpl, err := SelectTemplate(m.Template)
+		if err != nil {
+			return "", err
+		}
+
+		subject, text, html := tpl.Render(m.Data)
+		if m.Subject == "" {
+			m.Subject = subject
+		}
+		if m.Text == "" {
+			m.Text = text
+		}
+		if m.HTML == "" {
+			m.HTML = html
+		}
+		if m.Mailer == "" {
+			m.Mailer = tpl.Mailer
+		}
+		if m.From == "" {
+			m.From = tpl.From
+		}
+	}
+
+	mailer, err := Select(m.Mailer)
+	if err != nil {
+		return "", err
+	}
+
+	if m.From == "" {
+		m.From = mailer.From
+	}
+
+	msg, err := build(&m)
+	if err != nil {
+		return "", err
+	}
+
+	// The mails of sendgrid are built and signed by the API
+	if mailer.DKIM != nil && mailer.Type != TypeSendGrid {
+		msg.raw, err = mailer.DKIM.Sign(msg.raw)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := mailer.deliver(msg); err != nil {
+		return "", err
+	}
+	return msg.id, nil
+}
+
+// deliver the message by the provider of the mailer
+func (m *Mailer) deliver(msg *message) error {
+	switch m.Type {
+	case TypeSMTP:
+		return m.sendSMTP(msg)
+	case TypeSendGrid:
+		return m.sendSendGrid(msg)
+	case TypeMailgun:
+		return m.sendMailgun(msg)
+	}
+	return fmt.Errorf("the mailer type %s is not supported", m.Type)
+}
+
+func (m *Mailer) validate() error {
+	for _, value := range []*string{&m.Username, &m.Password, &m.Key, &m.Host, &m.From} {
+		if strings.HasPrefix(*value, "$ENV.") {
+			*value = os.Getenv(strings.TrimPrefix(*value, "$ENV."))
+		}
+	}
+
+	if m.From != "" {
+		if _, err := mail.ParseAddress(m.From); err != nil {
+			return fmt.Errorf("the from %s is invalid: %s", m.From, err.Error())
+		}
+	}
+
+	switch m.Type {
+	case TypeSMTP:
+		if m.Host == "" {
+			return fmt.Errorf("the host is required")
+		}
+		if m.Port == 0 {
+			m.Port = 587
+		}
+
+	case TypeSendGrid:
+		if m.Key == "" {
+			return fmt.Errorf("the key is required")
+		}
+		if m.API == "" {
+			m.API = "https://api.sendgrid.com"
+		}
+
+	case TypeMailgun:
+		if m.Key == "" || m.Domain == "" {
+			return fmt.Errorf("the key and the domain are required")
+		}
+		if m.API == "" {
+			m.API = "https://api.mailgun.net"
+		}
+
+	default:
+		return fmt.Errorf("the type %s is not supported, smtp, sendgrid or mailgun", m.Type)
+	}
+	m.API = strings.TrimRight(m.API, "/")
+
+	if m.DKIM != nil {
+		if strings.HasPrefix(m.DKIM.PrivateKey, "$ENV.") {
+			m.DKIM.PrivateKey = os.Getenv(strings.TrimPrefix(m.DKIM.PrivateKey, "$ENV."))
+		}
+		if _, err := m.DKIM.signer(); err != nil {
+			return fmt.Errorf("the dkim is invalid: %s", err.Error())
+		}
+	}
+	return nil
+}
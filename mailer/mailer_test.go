@@ -0,0 +1,220 @@
+package mailer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	tpl := &Template{Subject: "Welcome to {{ team.name }}", Text: "Hi {{ name }}{{ missing }}", HTML: "<p>Hi {{ name }}</p>"}
+	subject, text, html := tpl.Render(map[string]interface{}{"name": "<Max>", "team": map[string]interface{}{"name": "Yao"}})
+	assert.Equal(t, "Welcome to Yao", subject)
+	assert.Equal(t, "Hi <Max>", text)
+	assert.Equal(t, "<p>Hi &lt;Max&gt;</p>", html)
+}
+
+func TestBuild(t *testing.T) {
+	_, err := build(&Mail{From: "noreply@example.com", Text: "Hi"})
+	assert.Error(t, err)
+
+	_, err = build(&Mail{From: "invalid", To: []string{"max@example.com"}, Text: "Hi"})
+	assert.Error(t, err)
+
+	msg, err := build(&Mail{
+		From:        "Yao <noreply@example.com>",
+		To:          []string{"Max <max@example.com>"},
+		Bcc:         []string{"audit@example.com"},
+		Subject:     "任务完成",
+		Text:        "The job is done",
+		HTML:        "<p>The job is done</p><img src=\"cid:logo\">",
+		Headers:     map[string]string{"x-job": "1"},
+		Attachments: []Attachment{{Name: "report.txt", Content: base64.StdEncoding.EncodeToString([]byte("done"))}, {Name: "logo.png", Content: "iVBORw0K", ContentID: "logo"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"max@example.com", "audit@example.com"}, msg.recipients)
+	assert.True(t, strings.HasSuffix(msg.id, "@example.com>"))
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(msg.raw)))
+	assert.NoError(t, err)
+	assert.Equal(t, "\"Max\" <max@example.com>", parsed.Header.Get("To"))
+	assert.Equal(t, "", parsed.Header.Get("Bcc"))
+	assert.Equal(t, "1", parsed.Header.Get("X-Job"))
+
+	subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	assert.Equal(t, "任务完成", subject)
+
+	// multipart/mixed (multipart/related (multipart/alternative, logo.png), report.txt)
+	mediaType, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.Equal(t, "multipart/mixed", mediaType)
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+
+	related, err := reader.NextPart()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(related.Header.Get("Content-Type"), "multipart/related"))
+
+	attachment, err := reader.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "report.txt", attachment.FileName())
+	assert.Equal(t, "base64", attachment.Header.Get("Content-Transfer-Encoding"))
+
+	// The single part is the body of the message
+	msg, err = build(&Mail{From: "noreply@example.com", To: []string{"max@example.com"}, Text: "Hi\n"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(msg.raw), "Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nHi\r\n")
+
+	_, err = build(&Mail{From: "noreply@example.com", To: []string{"max@example.com"}, Text: "Hi", Attachments: []Attachment{{Name: "a.txt", Content: "%%"}}})
+	assert.Error(t, err)
+}
+
+func TestSend(t *testing.T) {
+	defer func(mailers map[string]*Mailer, templates map[string]*Template) {
+		Mailers, Templates = mailers, templates
+	}(Mailers, Templates)
+
+	received := map[string]interface{}{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/mail/send", r.URL.Path)
+		assert.Equal(t, "Bearer SG.key", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		jsoniter.Unmarshal(body, &received)
+		w.WriteHeader(202)
+	}))
+	defer srv.Close()
+
+	Mailers = map[string]*Mailer{"default": {ID: "default", Type: TypeSendGrid, From: "Yao <noreply@example.com>", Key: "SG.key", API: srv.URL}}
+	Templates = map[string]*Template{"welcome": {ID: "welcome", Subject: "Welcome {{ name }}", Text: "Hi {{ name }}"}}
+
+	id, err := Send(Mail{To: []string{"max@example.com"}, Template: "welcome", Data: map[string]interface{}{"name": "Max"}})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+	assert.Equal(t, "Welcome Max", received["subject"])
+	assert.Equal(t, "noreply@example.com", received["from"].(map[string]interface{})["email"])
+	assert.Equal(t, "Hi Max", received["content"].([]interface{})[0].(map[string]interface{})["value"])
+
+	_, err = Send(Mail{To: []string{"max@example.com"}, Template: "none"})
+	assert.Error(t, err)
+
+	_, err = Send(Mail{Mailer: "none", To: []string{"max@example.com"}, Text: "Hi"})
+	assert.Error(t, err)
+}
+
+func TestSendMailgun(t *testing.T) {
+	raw := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/mg.example.com/messages.mime", r.URL.Path)
+		user, key, _ := r.BasicAuth()
+		assert.Equal(t, "api", user)
+		assert.Equal(t, "key", key)
+		assert.Equal(t, "max@example.com", r.FormValue("to"))
+		file, _, err := r.FormFile("message")
+		if assert.NoError(t, err) {
+			data, _ := io.ReadAll(file)
+			raw = string(data)
+		}
+		w.Write([]byte(`{"message":"Queued. Thank you."}`))
+	}))
+	defer srv.Close()
+
+	m := &Mailer{Type: TypeMailgun, Key: "key", Domain: "mg.example.com", API: srv.URL}
+	msg, err := build(&Mail{From: "noreply@example.com", To: []string{"max@example.com"}, Subject: "Hi", Text: "Hi"})
+	assert.NoError(t, err)
+	assert.NoError(t, m.deliver(msg))
+	assert.Contains(t, raw, "Subject: Hi\r\n")
+
+	forbidden := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte("Forbidden"))
+	}))
+	defer forbidden.Close()
+
+	m.API = forbidden.URL
+	assert.EqualError(t, m.deliver(msg), "mailgun responds 401: Forbidden")
+}
+
+func TestSendSMTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	data := make(chan string, 1)
+	done := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		commands := []string{}
+		reader := bufio.NewReader(conn)
+		conn.Write([]byte("220 localhost ESMTP\r\n"))
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			commands = append(commands, line)
+
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				conn.Write([]byte("250-localhost\r\n250 8BITMIME\r\n"))
+			case line == "DATA":
+				conn.Write([]byte("354 go ahead\r\n"))
+				body := ""
+				for {
+					l, err := reader.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					body += l
+				}
+				data <- body
+				conn.Write([]byte("250 queued\r\n"))
+			case line == "QUIT":
+				done <- commands
+				conn.Write([]byte("221 bye\r\n"))
+				return
+			default:
+				conn.Write([]byte("250 ok\r\n"))
+			}
+		}
+	}()
+
+	port, _ := strconv.Atoi(strings.Split(listener.Addr().String(), ":")[1])
+	m := &Mailer{Type: TypeSMTP, Host: "127.0.0.1", Port: port}
+	msg, err := build(&Mail{From: "noreply@example.com", To: []string{"max@example.com"}, Cc: []string{"ops@example.com"}, Subject: "Hi", Text: "Hi"})
+	assert.NoError(t, err)
+	assert.NoError(t, m.deliver(msg))
+
+	assert.Contains(t, <-data, "Subject: Hi\r\n")
+	commands := <-done
+	assert.Contains(t, commands, "MAIL FROM:<noreply@example.com> BODY=8BITMIME")
+	assert.Contains(t, commands, "RCPT TO:<ops@example.com>")
+}
+
+func TestValidate(t *testing.T) {
+	m := &Mailer{Type: TypeSMTP, Host: "smtp.example.com"}
+	assert.NoError(t, m.validate())
+	assert.Equal(t, 587, m.Port)
+
+	assert.Error(t, (&Mailer{Type: TypeMailgun, Key: "key"}).validate())
+	assert.Error(t, (&Mailer{Type: "ses"}).validate())
+	assert.Error(t, (&Mailer{Type: TypeSMTP, Host: "smtp.example.com", From: "invalid"}).validate())
+	assert.Error(t, (&Mailer{Type: TypeSMTP, Host: "smtp.example.com", DKIM: &DKIM{Domain: "example.com", Selector: "yao", PrivateKey: "invalid"}}).validate())
+}
@@ -0,0 +1,291 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yaoapp/gou/fs"
+)
+
+// message the MIME message of the mail
+type message struct {
+	id         string
+	from       *mail.Address
+	recipients []string // The addresses of the to, cc and bcc
+	addresses  map[string][]*mail.Address
+	replyTo    *mail.Address
+	raw        []byte
+	mail       *Mail
+	files      []file
+}
+
+// file the content of the attachment
+type file struct {
+	Attachment
+	data []byte
+}
+
+// build the MIME message of the mail, the text and the HTML are the alternatives, the attachments are read
+func build(m *Mail) (*message, error) {
+	if len(m.To) == 0 {
+		return nil, fmt.Errorf("the recipient is required")
+	}
+
+	if m.Text == "" && m.HTML == "" {
+		return nil, fmt.Errorf("the text or the html is required")
+	}
+
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, fmt.Errorf("the from %s is invalid: %s", m.From, err.Error())
+	}
+
+	msg := &message{from: from, mail: m, recipients: []string{}, addresses: map[string][]*mail.Address{}}
+	headers := []string{"From: " + from.String()}
+	for _, field := range []struct {
+		name  string
+		value []string
+	}{{"To", m.To}, {"Cc", m.Cc}, {"Bcc", m.Bcc}} {
+		if len(field.value) == 0 {
+			continue
+		}
+
+		addresses, err := mail.ParseAddressList(strings.Join(field.value, ","))
+		if err != nil {
+			return nil, fmt.Errorf("the %s %s is invalid: %s", strings.ToLower(field.name), strings.Join(field.value, ","), err.Error())
+		}
+
+		values := []string{}
+		msg.addresses[strings.ToLower(field.name)] = addresses
+		for _, address := range addresses {
+			msg.recipients = append(msg.recipients, address.Address)
+			values = append(values, address.String())
+		}
+
+		// The Bcc is the recipients of the envelope only
+		if field.name != "Bcc" {
+			headers = append(headers, field.name+": "+strings.Join(values, ", "))
+		}
+	}
+
+	if m.ReplyTo != "" {
+		replyTo, err := mail.ParseAddress(m.ReplyTo)
+		if err != nil {
+			return nil, fmt.Errorf("the reply_to %s is invalid: %s", m.ReplyTo, err.Error())
+		}
+		msg.replyTo = replyTo
+		headers = append(headers, "Reply-To: "+replyTo.String())
+	}
+
+	msg.id = messageID(from.Address)
+	headers = append(headers,
+		"Subject: "+mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date: "+time.Now().Format(time.RFC1123Z),
+		"Message-ID: "+msg.id,
+		"MIME-Version: 1.0",
+	)
+
+	names := []string{}
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		headers = append(headers, textproto.CanonicalMIMEHeaderKey(name)+": "+mime.QEncoding.Encode("utf-8", m.Headers[name]))
+	}
+
+	for _, attachment := range m.Attachments {
+		f, err := read(attachment)
+		if err != nil {
+			return nil, err
+		}
+		msg.files = append(msg.files, f)
+	}
+
+	contentType, body, err := msg.body()
+	if err != nil {
+		return nil, err
+	}
+	headers = append(headers, "Content-Type: "+contentType)
+
+	var buf bytes.Buffer
+	for _, header := range headers {
+		buf.WriteString(header + "\r\n")
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	msg.raw = buf.Bytes()
+	return msg, nil
+}
+
+// body the content type and the body of the message
+// multipart/mixed (multipart/related (multipart/alternative (text, html), inline files), files)
+func (msg *message) body() (string, []byte, error) {
+	parts := []part{}
+	if msg.mail.Text != "" {
+		parts = append(parts, textPart("text/plain; charset=utf-8", msg.mail.Text))
+	}
+	if msg.mail.HTML != "" {
+		parts = append(parts, textPart("text/html; charset=utf-8", msg.mail.HTML))
+	}
+
+	content := parts[0]
+	if len(parts) > 1 {
+		content = multipartOf("alternative", parts)
+	}
+
+	inline, attached := []part{content}, []part{}
+	for _, f := range msg.files {
+		if f.ContentID != "" && msg.mail.HTML != "" {
+			inline = append(inline, filePart(f, "inline"))
+			continue
+		}
+		attached = append(attached, filePart(f, "attachment"))
+	}
+
+	if len(inline) > 1 {
+		content = multipartOf("related", inline)
+	}
+
+	if len(attached) > 0 {
+		content = multipartOf("mixed", append([]part{content}, attached...))
+	}
+
+	if content.err != nil {
+		return "", nil, content.err
+	}
+
+	// The transfer encoding of the single part is the header of the message
+	if encoding := content.header.Get("Content-Transfer-Encoding"); encoding != "" {
+		return content.header.Get("Content-Type") + "\r\nContent-Transfer-Encoding: " + encoding, content.body, nil
+	}
+	return content.header.Get("Content-Type"), content.body, nil
+}
+
+// part the part of the multipart message
+type part struct {
+	header textproto.MIMEHeader
+	body   []byte
+	err    error
+}
+
+func textPart(contentType string, text string) part {
+	var buf bytes.Buffer
+	w := quotedprintable.NewWriter(&buf)
+	w.Write([]byte(strings.ReplaceAll(text, "\r\n", "\n")))
+	w.Close()
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	return part{header: header, body: buf.Bytes()}
+}
+
+func filePart(f file, disposition string) part {
+	contentType, params, err := mime.ParseMediaType(f.ContentType)
+	if err != nil {
+		contentType, params = "application/octet-stream", map[string]string{}
+	}
+	params["name"] = f.Name
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(contentType, params))
+	header.Set("Content-Transfer-Encoding", "base64")
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": f.Name}))
+	if f.ContentID != "" {
+		header.Set("Content-ID", "<"+f.ContentID+">")
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(f.data)
+	var buf bytes.Buffer
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded)
+	return part{header: header, body: buf.Bytes()}
+}
+
+func multipartOf(subtype string, parts []part) part {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		if p.err != nil {
+			return part{err: p.err}
+		}
+
+		pw, err := w.CreatePart(p.header)
+		if err != nil {
+			return part{err: err}
+		}
+		pw.Write(p.body)
+	}
+	w.Close()
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", fmt.Sprintf("multipart/%s; boundary=%s", subtype, w.Boundary()))
+	return part{header: header, body: buf.Bytes()}
+}
+
+// read the content of the attachment, the file of the data file system or the base64 content
+func read(attachment Attachment) (file, error) {
+	f := file{Attachment: attachment}
+	if f.Name == "" {
+		f.Name = filepath.Base(f.Path)
+	}
+
+	if f.Name == "" || f.Name == "." {
+		return f, fmt.Errorf("the name of the attachment is required")
+	}
+
+	if f.ContentType == "" {
+		f.ContentType = mime.TypeByExtension(filepath.Ext(f.Name))
+		if f.ContentType == "" {
+			f.ContentType = "application/octet-stream"
+		}
+	}
+
+	if f.Path == "" {
+		data, err := base64.StdEncoding.DecodeString(f.Content)
+		if err != nil {
+			return f, fmt.Errorf("the content of the attachment %s is invalid: %s", f.Name, err.Error())
+		}
+		f.data = data
+		return f, nil
+	}
+
+	data, err := fs.Get("data")
+	if err != nil {
+		return f, err
+	}
+
+	f.data, err = data.ReadFile(f.Path)
+	if err != nil {
+		return f, fmt.Errorf("read the attachment %s: %s", f.Path, err.Error())
+	}
+	return f, nil
+}
+
+// messageID the Message-ID of the domain of the sender
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(id), domain)
+}
@@ -0,0 +1,291 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+)
+
+// mjNode the element of the MJML, the inner of the ending tags is the raw HTML
+type mjNode struct {
+	name     string
+	attrs    map[string]string
+	children []*mjNode
+	inner    string
+}
+
+// endingTags the MJML tags of which the content is the HTML
+var endingTags = map[string]bool{"mj-text": true, "mj-button": true, "mj-raw": true, "mj-title": true, "mj-preview": true, "mj-style": true}
+
+// The defaults of the components, the attributes of the MJML documentation
+var mjDefaults = map[string]map[string]string{
+	"mj-body":    {"width": "600px"},
+	"mj-section": {"padding": "20px 0", "text-align": "center"},
+	"mj-column":  {},
+	"mj-text":    {"align": "left", "color": "#000000", "font-family": "Ubuntu, Helvetica, Arial, sans-serif", "font-size": "13px", "line-height": "1", "padding": "10px 25px"},
+	"mj-button":  {"align": "center", "background-color": "#414141", "border-radius": "3px", "color": "#ffffff", "font-family": "Ubuntu, Helvetica, Arial, sans-serif", "font-size": "13px", "inner-padding": "10px 25px", "padding": "10px 25px"},
+	"mj-image":   {"align": "center", "padding": "10px 25px"},
+	"mj-divider": {"border-color": "#000000", "border-style": "solid", "border-width": "4px", "padding": "10px 25px"},
+	"mj-spacer":  {"height": "20px"},
+	"mj-raw":     {},
+}
+
+// MJML compile the MJML to the HTML of the mail, the subset of the components is supported:
+// mj-head (mj-title, mj-preview, mj-style), mj-body, mj-wrapper, mj-section, mj-column, mj-text, mj-button, mj-image, mj-divider, mj-spacer and mj-raw.
+func MJML(source string) (string, error) {
+	root, err := parseMJML(source)
+	if err != nil {
+		return "", err
+	}
+
+	if root.name != "mjml" {
+		return "", fmt.Errorf("the root of the MJML must be mjml")
+	}
+
+	title, preview, style := "", "", ""
+	var body *mjNode
+	for _, child := range root.children {
+		switch child.name {
+		case "mj-head":
+			for _, head := range child.children {
+				switch head.name {
+				case "mj-title":
+					title = strings.TrimSpace(head.inner)
+				case "mj-preview":
+					preview = strings.TrimSpace(head.inner)
+				case "mj-style":
+					style += head.inner
+				}
+			}
+		case "mj-body":
+			body = child
+		}
+	}
+
+	if body == nil {
+		return "", fmt.Errorf("the mj-body is required")
+	}
+
+	attrs := withDefaults(body)
+	var buf bytes.Buffer
+	buf.WriteString("<!doctype html>\n<html><head>")
+	buf.WriteString(`<meta http-equiv="Content-Type" content="text/html; charset=UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1">`)
+	fmt.Fprintf(&buf, "<title>%s</title>", html.EscapeString(title))
+	buf.WriteString(`<style type="text/css">body{margin:0;padding:0;-webkit-text-size-adjust:100%;-ms-text-size-adjust:100%;}table,td{border-collapse:collapse;}img{border:0;outline:none;text-decoration:none;}@media only screen and (max-width:480px){.mj-column{width:100%!important;max-width:100%;display:block!important;}}`)
+	buf.WriteString(style)
+	buf.WriteString("</style></head>")
+	fmt.Fprintf(&buf, `<body style="word-spacing:normal;%s">`, css("background-color", attrs["background-color"]))
+	if preview != "" {
+		fmt.Fprintf(&buf, `<div style="display:none;font-size:1px;line-height:1px;max-height:0px;max-width:0px;opacity:0;overflow:hidden;">%s</div>`, html.EscapeString(preview))
+	}
+
+	fmt.Fprintf(&buf, `<table align="center" border="0" cellpadding="0" cellspacing="0" role="presentation" width="100%%" style="%smargin:0 auto;">`, css("max-width", attrs["width"]))
+	for _, child := range body.children {
+		if err := renderSection(&buf, child); err != nil {
+			return "", err
+		}
+	}
+	buf.WriteString("</table></body></html>")
+	return buf.String(), nil
+}
+
+// parseMJML the tree of the MJML, the HTML of the ending tags is kept as it is
+func parseMJML(source string) (*mjNode, error) {
+	decoder := xml.NewDecoder(strings.NewReader(source))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var root *mjNode
+	stack := []*mjNode{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("the MJML is invalid: %s", err.Error())
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &mjNode{name: t.Name.Local, attrs: map[string]string{}}
+			for _, attr := range t.Attr {
+				node.attrs[attr.Name.Local] = attr.Value
+			}
+
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, fmt.Errorf("the MJML has more than one root")
+				}
+				root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+
+			if endingTags[node.name] {
+				start := decoder.InputOffset()
+				if err := decoder.Skip(); err != nil {
+					return nil, fmt.Errorf("the MJML is invalid: %s", err.Error())
+				}
+				inner := source[start:decoder.InputOffset()]
+				if i := strings.LastIndex(inner, "</"); i >= 0 {
+					inner = inner[:i]
+				}
+				node.inner = inner
+				continue
+			}
+			stack = append(stack, node)
+
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("the MJML is empty")
+	}
+	return root, nil
+}
+
+// renderSection the section of the body, the sections of the wrapper share the background of it
+func renderSection(buf *bytes.Buffer, node *mjNode) error {
+	attrs := withDefaults(node)
+	switch node.name {
+	case "mj-wrapper":
+		fmt.Fprintf(buf, `<tr><td style="%s%s"><table border="0" cellpadding="0" cellspacing="0" role="presentation" width="100%%">`, css("background-color", attrs["background-color"]), css("padding", attrs["padding"]))
+		for _, child := range node.children {
+			if err := renderSection(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</table></td></tr>")
+		return nil
+
+	case "mj-raw":
+		buf.WriteString(node.inner)
+		return nil
+
+	case "mj-section":
+	default:
+		return fmt.Errorf("the %s is not supported in the mj-body", node.name)
+	}
+
+	fmt.Fprintf(buf, `<tr><td style="direction:ltr;%s%s%s"><table border="0" cellpadding="0" cellspacing="0" role="presentation" width="100%%"><tr>`,
+		css("background-color", attrs["background-color"]), css("padding", attrs["padding"]), css("text-align", attrs["text-align"]))
+
+	columns := []*mjNode{}
+	for _, child := range node.children {
+		if child.name != "mj-column" {
+			return fmt.Errorf("the %s is not supported in the mj-section", child.name)
+		}
+		columns = append(columns, child)
+	}
+
+	for _, column := range columns {
+		if err := renderColumn(buf, column, len(columns)); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("</tr></table></td></tr>")
+	return nil
+}
+
+// renderColumn the column of the section, the columns without the width share the width of the section
+func renderColumn(buf *bytes.Buffer, node *mjNode, count int) error {
+	attrs := withDefaults(node)
+	width := attrs["width"]
+	if width == "" {
+		width = fmt.Sprintf("%g%%", math.Floor(10000/float64(count))/100)
+	}
+
+	valign := valueOr(attrs["vertical-align"], "top")
+	fmt.Fprintf(buf, `<td class="mj-column" valign="%s" style="%s%s%s%s"><table border="0" cellpadding="0" cellspacing="0" role="presentation" width="100%%">`,
+		html.EscapeString(valign), css("vertical-align", valign), css("width", width), css("background-color", attrs["background-color"]), css("padding", attrs["padding"]))
+
+	for _, child := range node.children {
+		if err := renderContent(buf, child); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("</table></td>")
+	return nil
+}
+
+// renderContent the content of the column
+func renderContent(buf *bytes.Buffer, node *mjNode) error {
+	attrs := withDefaults(node)
+	align := html.EscapeString(attrs["align"])
+	switch node.name {
+	case "mj-text":
+		fmt.Fprintf(buf, `<tr><td align="%s" style="%s"><div style="%s%s%s%s%s%s">%s</div></td></tr>`, align, css("padding", attrs["padding"]),
+			css("font-family", attrs["font-family"]), css("font-size", attrs["font-size"]), css("font-weight", attrs["font-weight"]),
+			css("line-height", attrs["line-height"]), css("text-align", attrs["align"]), css("color", attrs["color"]), node.inner)
+
+	case "mj-button":
+		fmt.Fprintf(buf, `<tr><td align="%s" style="%s"><table border="0" cellpadding="0" cellspacing="0" role="presentation" style="border-collapse:separate;"><tr><td align="center" bgcolor="%s" style="border:none;%s%s"><a href="%s" target="_blank" style="display:inline-block;text-decoration:none;%s%s%s%s%s">%s</a></td></tr></table></td></tr>`,
+			align, css("padding", attrs["padding"]), html.EscapeString(attrs["background-color"]), css("border-radius", attrs["border-radius"]), css("background", attrs["background-color"]),
+			html.EscapeString(attrs["href"]), css("background", attrs["background-color"]), css("color", attrs["color"]), css("font-family", attrs["font-family"]),
+			css("font-size", attrs["font-size"]), css("padding", attrs["inner-padding"]), node.inner)
+
+	case "mj-image":
+		width := ""
+		if w := strings.TrimSuffix(attrs["width"], "px"); w != "" {
+			width = fmt.Sprintf(` width="%s"`, html.EscapeString(w))
+		}
+		img := fmt.Sprintf(`<img alt="%s" src="%s"%s style="border:0;display:block;outline:none;text-decoration:none;height:auto;width:100%%;%s">`,
+			html.EscapeString(attrs["alt"]), html.EscapeString(attrs["src"]), width, css("max-width", attrs["width"]))
+		if href := attrs["href"]; href != "" {
+			img = fmt.Sprintf(`<a href="%s" target="_blank">%s</a>`, html.EscapeString(href), img)
+		}
+		fmt.Fprintf(buf, `<tr><td align="%s" style="%s">%s</td></tr>`, align, css("padding", attrs["padding"]), img)
+
+	case "mj-divider":
+		fmt.Fprintf(buf, `<tr><td align="center" style="%s"><p style="border-top:%s %s %s;font-size:1px;margin:0px auto;width:100%%;"></p></td></tr>`,
+			css("padding", attrs["padding"]), html.EscapeString(attrs["border-style"]), html.EscapeString(attrs["border-width"]), html.EscapeString(attrs["border-color"]))
+
+	case "mj-spacer":
+		fmt.Fprintf(buf, `<tr><td style="%s%s">&#8202;</td></tr>`, css("height", attrs["height"]), css("line-height", attrs["height"]))
+
+	case "mj-raw":
+		buf.WriteString(node.inner)
+
+	default:
+		return fmt.Errorf("the %s is not supported in the mj-column", node.name)
+	}
+	return nil
+}
+
+// withDefaults the attributes of the node with the defaults of the component
+func withDefaults(node *mjNode) map[string]string {
+	attrs := map[string]string{}
+	for key, value := range mjDefaults[node.name] {
+		attrs[key] = value
+	}
+	for key, value := range node.attrs {
+		attrs[key] = value
+	}
+	return attrs
+}
+
+// css the declaration of the style, empty if the value is empty
+func css(name string, value string) string {
+	if value == "" {
+		return ""
+	}
+	return name + ":" + html.EscapeString(value) + ";"
+}
+
+func valueOr(value string, defaults string) string {
+	if value == "" {
+		return defaults
+	}
+	return value
+}
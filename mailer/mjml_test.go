@@ -0,0 +1,68 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMJML(t *testing.T) {
+	html, err := MJML(`<mjml>
+  <mj-head>
+    <mj-title>Welcome</mj-title>
+    <mj-preview>Hi {{ name }}</mj-preview>
+  </mj-head>
+  <mj-body background-color="#f4f4f4">
+    <mj-section background-color="#ffffff">
+      <mj-column>
+        <mj-image src="https://example.com/logo.png" width="100px" href="https://example.com"></mj-image>
+        <mj-text font-size="20px" color="#333">Hi {{ name }},<br>welcome to <b>Yao</b></mj-text>
+        <mj-button href="{{ link }}" background-color="#3c82f6">Get started</mj-button>
+      </mj-column>
+    </mj-section>
+    <mj-section>
+      <mj-column><mj-divider border-width="1px" /></mj-column>
+      <mj-column><mj-spacer height="10px" /><mj-text>&copy; Yao</mj-text></mj-column>
+      <mj-column width="20%"><mj-raw><p>raw</p></mj-raw></mj-column>
+    </mj-section>
+  </mj-body>
+</mjml>`)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(html, "<!doctype html>"))
+	assert.Contains(t, html, "<title>Welcome</title>")
+	assert.Contains(t, html, ">Hi {{ name }}</div>")
+	assert.Contains(t, html, `<body style="word-spacing:normal;background-color:#f4f4f4;">`)
+	assert.Contains(t, html, `font-size:20px;`)
+	assert.Contains(t, html, `>Hi {{ name }},<br>welcome to <b>Yao</b></div>`)
+	assert.Contains(t, html, `<a href="{{ link }}" target="_blank"`)
+	assert.Contains(t, html, `>Get started</a>`)
+	assert.Contains(t, html, `<a href="https://example.com" target="_blank"><img alt="" src="https://example.com/logo.png" width="100"`)
+	assert.Contains(t, html, `border-top:solid 1px #000000;`)
+	assert.Contains(t, html, `>&copy; Yao</div>`)
+	assert.Contains(t, html, `<p>raw</p>`)
+	assert.Contains(t, html, `width:33.33%;`)
+	assert.Contains(t, html, `width:20%;`)
+	assert.Contains(t, html, `max-width:600px;`)
+
+	// The template binds the fields of the compiled HTML
+	_, _, bound := (&Template{HTML: html}).Render(map[string]interface{}{"name": "Max", "link": "https://example.com/start?a=1&b=2"})
+	assert.Contains(t, bound, `href="https://example.com/start?a=1&amp;b=2"`)
+}
+
+func TestMJMLInvalid(t *testing.T) {
+	_, err := MJML(`<mj-body></mj-body>`)
+	assert.Error(t, err)
+
+	_, err = MJML(`<mjml><mj-head></mj-head></mjml>`)
+	assert.Error(t, err)
+
+	_, err = MJML(`<mjml><mj-body><mj-column></mj-column></mj-body></mjml>`)
+	assert.EqualError(t, err, "the mj-column is not supported in the mj-body")
+
+	_, err = MJML(`<mjml><mj-body><mj-section><mj-column><mj-carousel></mj-carousel></mj-column></mj-section></mj-body></mjml>`)
+	assert.EqualError(t, err, "the mj-carousel is not supported in the mj-column")
+
+	_, err = MJML(``)
+	assert.Error(t, err)
+}
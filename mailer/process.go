@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("mailer", map[string]process.Handler{
+		"send":   processSend,
+		"render": processRender,
+	})
+}
+
+// processSend mailer.Send({"to": [...], "template": "welcome", "data": {...}, "attachments": [...]}), returns the Message-ID
+func processSend(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	bytes, err := jsoniter.Marshal(process.Args[0])
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+
+	m := Mail{}
+	err = jsoniter.Unmarshal(bytes, &m)
+	if err != nil {
+		exception.New("the mail is invalid: %s", 400, err.Error()).Throw()
+	}
+
+	id, err := Send(m)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return id
+}
+
+// processRender mailer.Render(template, data?), returns the subject, the text and the html
+func processRender(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	tpl, err := SelectTemplate(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+
+	data := map[string]interface{}{}
+	if process.NumOfArgs() > 1 {
+		data = process.ArgsMap(1)
+	}
+
+	subject, text, html := tpl.Render(data)
+	return map[string]interface{}{"subject": subject, "text": text, "html": html}
+}
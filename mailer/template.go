@@ -0,0 +1,79 @@
+package mailer
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/kun/maps"
+)
+
+// fieldRe the {{ field }} of the templates, the field is the dot path of the data
+var fieldRe = regexp.MustCompile(`\{\{\s*([\w.\-]+)\s*\}\}`)
+
+// compile read the files of the html and the mjml, the MJML is compiled to the HTML
+func (tpl *Template) compile() error {
+	if tpl.Subject == "" {
+		return fmt.Errorf("the subject is required")
+	}
+
+	var err error
+	tpl.HTML, err = source(tpl.HTML, ".html")
+	if err != nil {
+		return err
+	}
+
+	if tpl.MJML == "" {
+		return nil
+	}
+
+	mjml, err := source(tpl.MJML, ".mjml")
+	if err != nil {
+		return err
+	}
+
+	tpl.HTML, err = MJML(mjml)
+	return err
+}
+
+// source the content of the file of the application if the value is the name of the file with the extension
+func source(value string, ext string) (string, error) {
+	if !strings.HasSuffix(value, ext) || strings.Contains(value, "<") {
+		return value, nil
+	}
+
+	bytes, err := application.App.Read(value)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// Render the subject, text and html of the template by the data, the values of the HTML are escaped
+func (tpl *Template) Render(data map[string]interface{}) (string, string, string) {
+	values := maps.Of(data).Dot()
+	return bind(tpl.Subject, values, false), bind(tpl.Text, values, false), bind(tpl.HTML, values, true)
+}
+
+// bind replace the {{ field }} of the text by the values, empty if the field does not exist
+func bind(text string, values map[string]interface{}, escape bool) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+
+	return fieldRe.ReplaceAllStringFunc(text, func(field string) string {
+		name := fieldRe.FindStringSubmatch(field)[1]
+		value, has := values[name]
+		if !has || value == nil {
+			return ""
+		}
+
+		res := fmt.Sprintf("%v", value)
+		if escape {
+			return html.EscapeString(res)
+		}
+		return res
+	})
+}
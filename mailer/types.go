@@ -0,0 +1,96 @@
+package mailer
+
+import "sync"
+
+// The types of the mailers
+const (
+	TypeSMTP     = "smtp"
+	TypeSendGrid = "sendgrid"
+	TypeMailgun  = "mailgun"
+)
+
+// Mailer the mail provider of the mailers/*.yao, the mailer "default" sends the mails without the mailer.
+// The mail server of YAO_SMTP_HOST is the default mailer if mailers/default.yao does not exist.
+//
+//	{
+//	  "type": "smtp",
+//	  "from": "Yao <noreply@example.com>",
+//	  "host": "smtp.example.com",
+//	  "port": 465,
+//	  "username": "$ENV.SMTP_USERNAME",
+//	  "password": "$ENV.SMTP_PASSWORD",
+//	  "dkim": { "domain": "example.com", "selector": "yao", "private_key": "$ENV.DKIM_PRIVATE_KEY" }
+//	}
+type Mailer struct {
+	ID       string `json:"-"`
+	Name     string `json:"name,omitempty"`
+	Type     string `json:"type"`           // smtp, sendgrid or mailgun
+	From     string `json:"from,omitempty"` // The default sender, e.g. Yao <noreply@example.com>
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"` // 587 by default, the implicit TLS is used for 465
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Key      string `json:"key,omitempty"`    // The API key of sendgrid and mailgun
+	Domain   string `json:"domain,omitempty"` // The sending domain of mailgun
+	API      string `json:"api,omitempty"`    // The base url of the API, e.g. https://api.eu.mailgun.net
+	DKIM     *DKIM  `json:"dkim,omitempty"`   // The DKIM signature of the smtp and mailgun mails
+}
+
+// DKIM the DKIM signing key, the RSA private key of PEM
+type DKIM struct {
+	Domain     string   `json:"domain"`
+	Selector   string   `json:"selector"`
+	PrivateKey string   `json:"private_key"`
+	Headers    []string `json:"headers,omitempty"` // The signed headers, From, To, Subject, Date, Message-ID and the MIME headers by default
+}
+
+// Template the mail template of the mails/*.yao, the {{ field }} of the texts are replaced by the data, escaped in the HTML.
+// The html and the mjml are the file of the application if they end with .html or .mjml, e.g. mails/welcome.mjml
+//
+//	{
+//	  "subject": "Welcome to {{ team }}",
+//	  "mjml": "mails/welcome.mjml",
+//	  "text": "Hi {{ name }}, welcome to {{ team }}."
+//	}
+type Template struct {
+	ID      string `json:"-"`
+	Mailer  string `json:"mailer,omitempty"` // The mailer sending the mails of the template, the default mailer if it is empty
+	From    string `json:"from,omitempty"`
+	Subject string `json:"subject"`
+	Text    string `json:"text,omitempty"`
+	HTML    string `json:"html,omitempty"`
+	MJML    string `json:"mjml,omitempty"` // Compiled to the HTML on load, the html is ignored if it is set
+}
+
+// Mail the mail sent by the mailer, the subject, text and html are rendered by the template if the template is set
+type Mail struct {
+	Mailer      string                 `json:"mailer,omitempty"`
+	From        string                 `json:"from,omitempty"`
+	To          []string               `json:"to"`
+	Cc          []string               `json:"cc,omitempty"`
+	Bcc         []string               `json:"bcc,omitempty"`
+	ReplyTo     string                 `json:"reply_to,omitempty"`
+	Subject     string                 `json:"subject,omitempty"`
+	Text        string                 `json:"text,omitempty"`
+	HTML        string                 `json:"html,omitempty"`
+	Template    string                 `json:"template,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	Attachments []Attachment           `json:"attachments,omitempty"`
+	Headers     map[string]string      `json:"headers,omitempty"`
+}
+
+// Attachment the file of the mail, the file of the data file system or the content of base64
+type Attachment struct {
+	Name        string `json:"name"`
+	Path        string `json:"path,omitempty"`    // The path of the data file system
+	Content     string `json:"content,omitempty"` // The base64 content, used if the path is empty
+	ContentType string `json:"content_type,omitempty"`
+	ContentID   string `json:"cid,omitempty"` // The inline attachment referenced by cid: of the HTML
+}
+
+// Mailers the loaded mailers
+var Mailers = map[string]*Mailer{}
+
+// Templates the loaded templates
+var Templates = map[string]*Template{}
+var lock sync.RWMutex
@@ -0,0 +1,84 @@
+package market
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/yao/config"
+)
+
+// SetRoutes the packages of the registry and the packages installed, for the admins. The application should be reloaded
+// after the packages installed, upgraded or removed.
+//
+//	GET    <path>/packages?registry=<registry>    The packages of the registry
+//	GET    <path>                                 The packages installed
+//	POST   <path>/:name  {"version": ">=1.0.0"}   Install the package
+//	PUT    <path>/:name  {"version": ">=1.0.0"}   Upgrade the package
+//	DELETE <path>/:name                           Remove the package
+func SetRoutes(router *gin.Engine, path string, guards ...gin.HandlerFunc) {
+	handlers := func(handler gin.HandlerFunc) []gin.HandlerFunc {
+		return append(append([]gin.HandlerFunc{}, guards...), handler)
+	}
+	router.GET(path+"/packages", handlers(handlePackages)...)
+	router.GET(path, handlers(handleInstalled)...)
+	router.POST(path+"/:name", handlers(handleInstall)...)
+	router.PUT(path+"/:name", handlers(handleUpgrade)...)
+	router.DELETE(path+"/:name", handlers(handleRemove)...)
+}
+
+func handlePackages(c *gin.Context) {
+	packages, err := Packages(registryOf(c.Query("registry")))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(200, packages)
+}
+
+func handleInstalled(c *gin.Context) {
+	installed, err := List(config.Conf.Root)
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(200, installed)
+}
+
+func handleInstall(c *gin.Context) {
+	handleApply(c, (*Installer).Install)
+}
+
+func handleUpgrade(c *gin.Context) {
+	handleApply(c, (*Installer).Upgrade)
+}
+
+func handleApply(c *gin.Context, apply func(inst *Installer, name string, versions string) (*Installed, error)) {
+	var body struct {
+		Version  string `json:"version"`
+		Registry string `json:"registry"`
+	}
+	c.ShouldBindJSON(&body)
+
+	inst, err := current(body.Registry)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	installed, err := apply(inst, c.Param("name"), body.Version)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(200, installed)
+}
+
+func handleRemove(c *gin.Context) {
+	inst, err := current("")
+	if err == nil {
+		err = inst.Remove(c.Param("name"))
+	}
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "removed"})
+}
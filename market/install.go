@@ -0,0 +1,323 @@
+package market
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/yao/share"
+)
+
+// Installer install, upgrade and remove the packages of the registry, the changes are rolled back if failed
+type Installer struct {
+	Root     string
+	Registry string
+	Engine   string                                                // The version of the engine, share.VERSION by default
+	Migrate  func(pkg *Package, from string, files []string) error // Migrate the package installed, Migrate by default
+}
+
+// protected the files of the application the packages can not write
+var protected = []string{LockFile, "app.yao", "app.json", "app.jsonc", ".env"}
+
+var installing sync.Mutex
+
+// Install install the package matched by the versions, the packages required should be installed
+func (inst *Installer) Install(name string, versions string) (*Installed, error) {
+	installing.Lock()
+	defer installing.Unlock()
+
+	lock, err := readLock(inst.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	if installed, has := lock[name]; has {
+		return nil, fmt.Errorf("the package %s %s is installed, upgrade it instead", name, installed.Version)
+	}
+
+	pkg, err := inst.resolve(name, versions)
+	if err != nil {
+		return nil, err
+	}
+	return inst.apply(lock, pkg, nil)
+}
+
+// Upgrade upgrade the package to the latest version matched by the versions, the files removed by the version are deleted
+func (inst *Installer) Upgrade(name string, versions string) (*Installed, error) {
+	installing.Lock()
+	defer installing.Unlock()
+
+	lock, err := readLock(inst.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	installed, has := lock[name]
+	if !has {
+		return nil, fmt.Errorf("the package %s is not installed", name)
+	}
+
+	pkg, err := inst.resolve(name, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	if compare(pkg.Version, installed.Version) <= 0 {
+		return nil, fmt.Errorf("the package %s %s is up to date", name, installed.Version)
+	}
+	return inst.apply(lock, pkg, installed)
+}
+
+// Remove remove the files of the package, the package required by the others can not be removed.
+// The tables of the models are kept.
+func (inst *Installer) Remove(name string) error {
+	installing.Lock()
+	defer installing.Unlock()
+
+	lock, err := readLock(inst.Root)
+	if err != nil {
+		return err
+	}
+
+	installed, has := lock[name]
+	if !has {
+		return fmt.Errorf("the package %s is not installed", name)
+	}
+
+	for _, other := range lock {
+		for _, dep := range other.Requires {
+			if dep == name {
+				return fmt.Errorf("the package %s is required by %s", name, other.Name)
+			}
+		}
+	}
+
+	tx, err := begin(inst.Root)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range installed.Files {
+		if err := tx.remove(file); err != nil {
+			tx.rollback()
+			return err
+		}
+	}
+
+	delete(lock, name)
+	if err := tx.save(LockFile); err == nil {
+		err = writeLock(inst.Root, lock)
+	}
+	if err != nil {
+		tx.rollback()
+		return err
+	}
+	return tx.commit()
+}
+
+// resolve the package of the registry
+func (inst *Installer) resolve(name string, versions string) (*Package, error) {
+	packages, err := Packages(inst.registry())
+	if err != nil {
+		return nil, err
+	}
+	return Resolve(packages, name, versions)
+}
+
+// apply write the files of the package, migrate the package and update the lock. The previous is the version installed.
+func (inst *Installer) apply(lock map[string]*Installed, pkg *Package, previous *Installed) (*Installed, error) {
+	if err := inst.check(lock, pkg); err != nil {
+		return nil, err
+	}
+
+	data, err := fetch(resolve(inst.registry(), pkg.URL))
+	if err != nil {
+		return nil, err
+	}
+
+	if pkg.Checksum != "" {
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), pkg.Checksum) {
+			return nil, fmt.Errorf("the checksum of the package %s %s does not match", pkg.Name, pkg.Version)
+		}
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("the package %s is not a zip file: %s", pkg.Name, err.Error())
+	}
+
+	// The files owned by the version installed are overwritten
+	owned := map[string]bool{}
+	from := ""
+	if previous != nil {
+		from = previous.Version
+		for _, file := range previous.Files {
+			owned[file] = true
+		}
+	}
+
+	tx, err := begin(inst.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := inst.extract(tx, reader, owned)
+	if err != nil {
+		tx.rollback()
+		return nil, err
+	}
+
+	written := map[string]bool{}
+	for _, file := range files {
+		written[file] = true
+	}
+	for file := range owned {
+		if written[file] {
+			continue
+		}
+		if err := tx.remove(file); err != nil {
+			tx.rollback()
+			return nil, err
+		}
+	}
+
+	installed := &Installed{Name: pkg.Name, Version: pkg.Version, Registry: inst.registry(), Files: files, InstalledAt: time.Now()}
+	for dep := range pkg.Requires {
+		installed.Requires = append(installed.Requires, dep)
+	}
+	sort.Strings(installed.Requires)
+
+	lock[pkg.Name] = installed
+	if err := tx.save(LockFile); err == nil {
+		err = writeLock(inst.Root, lock)
+	}
+	if err != nil {
+		tx.rollback()
+		return nil, err
+	}
+
+	migrate := inst.Migrate
+	if migrate == nil {
+		migrate = Migrate
+	}
+	if err := migrate(pkg, from, files); err != nil {
+		tx.rollback()
+		return nil, fmt.Errorf("migrate the package %s %s: %s, the changes are rolled back", pkg.Name, pkg.Version, err.Error())
+	}
+	return installed, tx.commit()
+}
+
+// check the engine and the packages required
+func (inst *Installer) check(lock map[string]*Installed, pkg *Package) error {
+	if pkg.Engine != "" {
+		match, err := versionRange(pkg.Engine)
+		if err != nil {
+			return err
+		}
+		if !match(inst.engine()) {
+			return fmt.Errorf("the package %s %s requires the engine %s, the engine is %s", pkg.Name, pkg.Version, pkg.Engine, inst.engine())
+		}
+	}
+
+	for dep, versions := range pkg.Requires {
+		installed, has := lock[dep]
+		if !has {
+			return fmt.Errorf("the package %s %s requires %s %s, install it first", pkg.Name, pkg.Version, dep, versions)
+		}
+
+		match, err := versionRange(versions)
+		if err != nil {
+			return err
+		}
+		if !match(installed.Version) {
+			return fmt.Errorf("the package %s %s requires %s %s, the version installed is %s", pkg.Name, pkg.Version, dep, versions, installed.Version)
+		}
+	}
+	return nil
+}
+
+// extract write the files of the zip, the single top directory of the zip is removed.
+// The files of the application not owned by the package are not overwritten.
+func (inst *Installer) extract(tx *transaction, reader *zip.Reader, owned map[string]bool) ([]string, error) {
+	prefix := topDir(reader.File)
+	files := []string{}
+	for _, file := range reader.File {
+		name := strings.TrimPrefix(file.Name, prefix)
+		if name == "" || file.FileInfo().IsDir() {
+			continue
+		}
+
+		name = filepath.ToSlash(filepath.Clean(filepath.FromSlash(name)))
+		if name == ".." || strings.HasPrefix(name, "../") || filepath.IsAbs(name) {
+			return nil, fmt.Errorf("the file %s of the package is outside of the application", file.Name)
+		}
+
+		for _, p := range protected {
+			if name == p {
+				return nil, fmt.Errorf("the file %s of the application can not be written by the package", name)
+			}
+		}
+
+		if _, err := os.Stat(filepath.Join(inst.Root, filepath.FromSlash(name))); err == nil && !owned[name] {
+			return nil, fmt.Errorf("the file %s exists in the application", name)
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, maxPackageSize))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tx.write(name, content); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func (inst *Installer) registry() string {
+	if inst.Registry == "" {
+		return DefaultRegistry
+	}
+	return inst.Registry
+}
+
+func (inst *Installer) engine() string {
+	if inst.Engine == "" {
+		return share.VERSION
+	}
+	return inst.Engine
+}
+
+// topDir the single top directory of the files, empty if the files are not in the same directory
+func topDir(files []*zip.File) string {
+	prefix := ""
+	for _, file := range files {
+		parts := strings.SplitN(file.Name, "/", 2)
+		if len(parts) < 2 {
+			return ""
+		}
+		if prefix != "" && prefix != parts[0]+"/" {
+			return ""
+		}
+		prefix = parts[0] + "/"
+	}
+	return prefix
+}
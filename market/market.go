@@ -0,0 +1,229 @@
+package market
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// DefaultRegistry the default registry of the packages, the registry is the JSON index of the packages
+const DefaultRegistry = "https://mirrors.letsinfra.com/packages/index.json"
+
+// LockFile the packages installed in the application, the files of the packages are removed by the lock
+const LockFile = "packages.lock.json"
+
+// maxPackageSize the max size of the zip of the package
+const maxPackageSize = 200 << 20
+
+// Package the package of the registry, the zip of the package is extracted to the root of the application.
+// e.g. {"name": "crm-contacts", "version": "1.2.0", "url": "crm-contacts-1.2.0.zip", "requires": {"crm-auth": ">=1.0.0"}, "migrate": "scripts.crm.contacts.Migrate"}
+type Package struct {
+	Name        string            `json:"name"`
+	Version     string            `json:"version"`
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	URL         string            `json:"url"`                // The zip of the package, relative to the registry if not absolute
+	Checksum    string            `json:"sha256,omitempty"`   // The SHA-256 of the zip, verified if set
+	Engine      string            `json:"engine,omitempty"`   // The versions of the engine supported, e.g. >=0.10.4
+	Requires    map[string]string `json:"requires,omitempty"` // The packages required and the versions, e.g. {"crm-auth": ">=1.0.0 <2.0.0"}
+	Migrate     string            `json:"migrate,omitempty"`  // The process run after the models migrated, the args are the version installed before and the version
+}
+
+// Installed the package installed in the application
+type Installed struct {
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	Registry    string    `json:"registry,omitempty"`
+	Requires    []string  `json:"requires,omitempty"`
+	Files       []string  `json:"files"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// Packages the packages of the registry, the registry is the URL or the path of the index. The versions of a package are
+// sorted by the version, the latest first
+func Packages(registry string) ([]Package, error) {
+	data, err := fetch(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	var index struct {
+		Packages []Package `json:"packages"`
+	}
+	if err := jsoniter.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("the registry %s is invalid: %s", registry, err.Error())
+	}
+
+	sort.SliceStable(index.Packages, func(i, j int) bool {
+		a, b := index.Packages[i], index.Packages[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return compare(a.Version, b.Version) > 0
+	})
+	return index.Packages, nil
+}
+
+// Resolve the latest version of the package matched by the versions, e.g. 1.2.0, >=1.0.0 <2.0.0 or empty for the latest
+func Resolve(packages []Package, name string, versions string) (*Package, error) {
+	match, err := versionRange(versions)
+	if err != nil {
+		return nil, err
+	}
+
+	exists := false
+	for i := range packages {
+		if packages[i].Name != name {
+			continue
+		}
+		exists = true
+		if match(packages[i].Version) {
+			return &packages[i], nil
+		}
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("the package %s does not exist in the registry", name)
+	}
+	return nil, fmt.Errorf("the package %s %s does not exist in the registry", name, versions)
+}
+
+// List the packages installed in the application
+func List(root string) ([]Installed, error) {
+	lock, err := readLock(root)
+	if err != nil {
+		return nil, err
+	}
+
+	installed := make([]Installed, 0, len(lock))
+	for _, pkg := range lock {
+		installed = append(installed, *pkg)
+	}
+	sort.Slice(installed, func(i, j int) bool { return installed[i].Name < installed[j].Name })
+	return installed, nil
+}
+
+// readLock the lock of the application, empty if the lock does not exist
+func readLock(root string) (map[string]*Installed, error) {
+	lock := map[string]*Installed{}
+	data, err := os.ReadFile(filepath.Join(root, LockFile))
+	if os.IsNotExist(err) {
+		return lock, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var content struct {
+		Packages map[string]*Installed `json:"packages"`
+	}
+	if err := jsoniter.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("the %s is invalid: %s", LockFile, err.Error())
+	}
+	if content.Packages != nil {
+		lock = content.Packages
+	}
+	return lock, nil
+}
+
+// writeLock write the lock of the application, the lock is removed if no package is installed
+func writeLock(root string, lock map[string]*Installed) error {
+	file := filepath.Join(root, LockFile)
+	if len(lock) == 0 {
+		err := os.Remove(file)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	data, err := jsoniter.MarshalIndent(map[string]interface{}{"packages": lock}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// versionRange the matcher of the versions, any version is matched if empty
+func versionRange(versions string) (func(version string) bool, error) {
+	versions = strings.TrimSpace(versions)
+	if versions == "" || versions == "*" || versions == "latest" {
+		return func(string) bool { return true }, nil
+	}
+
+	// The exact version, e.g. 1.2 or v1.2.0
+	if exact, err := semver.ParseTolerant(versions); err == nil {
+		return func(version string) bool { return compare(version, exact.String()) == 0 }, nil
+	}
+
+	match, err := semver.ParseRange(versions)
+	if err != nil {
+		return nil, fmt.Errorf("the version %s is invalid: %s", versions, err.Error())
+	}
+	return func(version string) bool {
+		v, err := semver.ParseTolerant(version)
+		return err == nil && match(v)
+	}, nil
+}
+
+// compare compare the versions, the invalid versions are the lowest
+func compare(a string, b string) int {
+	va, erra := semver.ParseTolerant(a)
+	vb, errb := semver.ParseTolerant(b)
+	switch {
+	case erra != nil && errb != nil:
+		return strings.Compare(a, b)
+	case erra != nil:
+		return -1
+	case errb != nil:
+		return 1
+	}
+	return va.Compare(vb)
+}
+
+// resolve the URL of the package relative to the registry
+func resolve(registry string, ref string) string {
+	if strings.HasPrefix(registry, "http://") || strings.HasPrefix(registry, "https://") {
+		base, err := url.Parse(registry)
+		if err != nil {
+			return ref
+		}
+		u, err := base.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		return u.String()
+	}
+
+	if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") || filepath.IsAbs(ref) {
+		return ref
+	}
+	return filepath.Join(filepath.Dir(registry), ref)
+}
+
+// fetch read the URL or the local file
+func fetch(location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.ReadFile(location)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	res, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", location, res.Status)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, maxPackageSize))
+}
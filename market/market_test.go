@@ -0,0 +1,210 @@
+package market
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstall(t *testing.T) {
+	registry := prepareRegistry(t, []Package{
+		{Name: "crm-auth", Version: "1.0.0"},
+		{Name: "crm-contacts", Version: "1.0.0", Requires: map[string]string{"crm-auth": ">=1.0.0"}, Migrate: "scripts.crm.contacts.Migrate"},
+		{Name: "crm-contacts", Version: "1.1.0", Requires: map[string]string{"crm-auth": ">=1.0.0"}, Engine: ">=0.10.4"},
+		{Name: "crm-contacts", Version: "2.0.0", Requires: map[string]string{"crm-auth": ">=2.0.0"}},
+		{Name: "crm-next", Version: "1.0.0", Engine: ">=1.0.0"},
+	}, map[string]map[string]string{
+		"crm-auth-1.0.0.zip": {"crm-auth/models/crm/user.mod.yao": `{"name": "user"}`},
+		"crm-contacts-1.0.0.zip": {
+			"crm-contacts/models/crm/contact.mod.yao": `{"name": "contact"}`,
+			"crm-contacts/scripts/crm/contacts.ts":    "function Migrate(from, to) {}",
+			"crm-contacts/tables/crm/contact.tab.yao": `{"name": "contact"}`,
+		},
+		"crm-contacts-1.1.0.zip": {
+			"crm-contacts/models/crm/contact.mod.yao": `{"name": "contact", "version": "1.1.0"}`,
+			"crm-contacts/scripts/crm/contacts.ts":    "function Migrate(from, to) {}",
+		},
+		"crm-contacts-2.0.0.zip": {"crm-contacts/models/crm/contact.mod.yao": `{}`},
+		"crm-next-1.0.0.zip":     {"crm-next/models/next.mod.yao": `{}`},
+	})
+
+	root := t.TempDir()
+	migrated := []string{}
+	inst := &Installer{Root: root, Registry: registry, Engine: "0.10.4", Migrate: func(pkg *Package, from string, files []string) error {
+		migrated = append(migrated, fmt.Sprintf("%s %s->%s %v", pkg.Name, from, pkg.Version, files))
+		return nil
+	}}
+
+	// The packages required
+	_, err := inst.Install("crm-contacts", "1.0.0")
+	assert.ErrorContains(t, err, "requires crm-auth >=1.0.0, install it first")
+	_, err = inst.Install("crm-next", "")
+	assert.ErrorContains(t, err, "requires the engine >=1.0.0")
+
+	_, err = inst.Install("crm-auth", "")
+	assert.NoError(t, err)
+
+	installed, err := inst.Install("crm-contacts", "<2.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.0", installed.Version)
+	assert.Equal(t, []string{"crm-auth"}, installed.Requires)
+
+	_, err = inst.Install("crm-contacts", "")
+	assert.ErrorContains(t, err, "is installed")
+
+	assert.NoError(t, inst.Remove("crm-contacts"))
+	assert.NoFileExists(t, filepath.Join(root, "models", "crm", "contact.mod.yao"))
+	assert.FileExists(t, filepath.Join(root, "models", "crm", "user.mod.yao"))
+
+	installed, err = inst.Install("crm-contacts", "1.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"models/crm/contact.mod.yao", "scripts/crm/contacts.ts", "tables/crm/contact.tab.yao"}, installed.Files)
+
+	// The files removed by the version are deleted
+	installed, err = inst.Upgrade("crm-contacts", "<2.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.0", installed.Version)
+	assert.NoDirExists(t, filepath.Join(root, "tables"))
+	data, _ := os.ReadFile(filepath.Join(root, "models", "crm", "contact.mod.yao"))
+	assert.Contains(t, string(data), "1.1.0")
+
+	_, err = inst.Upgrade("crm-contacts", "")
+	assert.ErrorContains(t, err, "requires crm-auth >=2.0.0, the version installed is 1.0.0")
+	_, err = inst.Upgrade("crm-contacts", "1.1.0")
+	assert.ErrorContains(t, err, "is up to date")
+
+	assert.Equal(t, []string{
+		"crm-auth ->1.0.0 [models/crm/user.mod.yao]",
+		"crm-contacts ->1.1.0 [models/crm/contact.mod.yao scripts/crm/contacts.ts]",
+		"crm-contacts ->1.0.0 [models/crm/contact.mod.yao scripts/crm/contacts.ts tables/crm/contact.tab.yao]",
+		"crm-contacts 1.0.0->1.1.0 [models/crm/contact.mod.yao scripts/crm/contacts.ts]",
+	}, migrated)
+
+	err = inst.Remove("crm-auth")
+	assert.ErrorContains(t, err, "required by crm-contacts")
+
+	list, err := List(root)
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "crm-auth", list[0].Name)
+}
+
+func TestInstallRollback(t *testing.T) {
+	registry := prepareRegistry(t, []Package{
+		{Name: "crm-auth", Version: "1.0.0"},
+		{Name: "crm-auth", Version: "1.1.0"},
+		{Name: "crm-env", Version: "1.0.0"},
+	}, map[string]map[string]string{
+		"crm-auth-1.0.0.zip": {"models/crm/user.mod.yao": `{"name": "user"}`, "scripts/crm/auth.ts": "1.0.0"},
+		"crm-auth-1.1.0.zip": {"models/crm/user.mod.yao": `{"name": "user", "version": "1.1.0"}`, "scripts/crm/login.ts": "1.1.0"},
+		"crm-env-1.0.0.zip":  {"scripts/crm/env.ts": "", ".env": "YAO_ENV=development"},
+	})
+
+	root := t.TempDir()
+	failed := false
+	inst := &Installer{Root: root, Registry: registry, Migrate: func(pkg *Package, from string, files []string) error {
+		if failed {
+			return fmt.Errorf("the column name exists")
+		}
+		return nil
+	}}
+
+	_, err := inst.Install("crm-env", "")
+	assert.ErrorContains(t, err, "the file .env of the application can not be written")
+	assert.NoDirExists(t, filepath.Join(root, "scripts"))
+
+	// The files of the application are not overwritten
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "scripts", "crm"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "scripts", "crm", "auth.ts"), []byte("mine"), 0644))
+	_, err = inst.Install("crm-auth", "1.0.0")
+	assert.ErrorContains(t, err, "the file scripts/crm/auth.ts exists in the application")
+	assert.NoDirExists(t, filepath.Join(root, "models"))
+	assert.NoError(t, os.Remove(filepath.Join(root, "scripts", "crm", "auth.ts")))
+
+	_, err = inst.Install("crm-auth", "1.0.0")
+	assert.NoError(t, err)
+
+	// The upgrade failed is rolled back
+	failed = true
+	_, err = inst.Upgrade("crm-auth", "")
+	assert.ErrorContains(t, err, "the changes are rolled back")
+
+	data, _ := os.ReadFile(filepath.Join(root, "models", "crm", "user.mod.yao"))
+	assert.Equal(t, `{"name": "user"}`, string(data))
+	assert.FileExists(t, filepath.Join(root, "scripts", "crm", "auth.ts"))
+	assert.NoFileExists(t, filepath.Join(root, "scripts", "crm", "login.ts"))
+
+	list, err := List(root)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.0", list[0].Version)
+
+	// The install failed is rolled back
+	assert.NoError(t, inst.Remove("crm-auth"))
+	assert.NoFileExists(t, filepath.Join(root, LockFile))
+	_, err = inst.Install("crm-auth", "")
+	assert.Error(t, err)
+	assert.NoDirExists(t, filepath.Join(root, "models"))
+	assert.NoFileExists(t, filepath.Join(root, LockFile))
+}
+
+func TestResolve(t *testing.T) {
+	packages := []Package{{Name: "a", Version: "1.10.0"}, {Name: "a", Version: "v1.9.0"}, {Name: "a", Version: "2.0.0-beta.1"}}
+	registry := filepath.Join(t.TempDir(), "index.json")
+	raw, _ := jsoniter.Marshal(map[string]interface{}{"packages": packages})
+	assert.NoError(t, os.WriteFile(registry, raw, 0644))
+
+	sorted, err := Packages(registry)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0.0-beta.1", sorted[0].Version)
+
+	pkg, err := Resolve(sorted, "a", ">=1.0.0 <2.0.0-0")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.10.0", pkg.Version)
+
+	pkg, err = Resolve(sorted, "a", "1.9")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.9.0", pkg.Version)
+
+	_, err = Resolve(sorted, "a", "3.0.0")
+	assert.ErrorContains(t, err, "the package a 3.0.0 does not exist")
+	_, err = Resolve(sorted, "b", "")
+	assert.ErrorContains(t, err, "the package b does not exist")
+	_, err = Resolve(sorted, "a", ">=x")
+	assert.Error(t, err)
+}
+
+// prepareRegistry write the zips of the packages and the index, the checksums are set
+func prepareRegistry(t *testing.T, packages []Package, zips map[string]map[string]string) string {
+	dir := t.TempDir()
+	for i, pkg := range packages {
+		name := fmt.Sprintf("%s-%s.zip", pkg.Name, pkg.Version)
+		file, err := os.Create(filepath.Join(dir, name))
+		assert.NoError(t, err)
+
+		w := zip.NewWriter(file)
+		for path, content := range zips[name] {
+			f, err := w.Create(path)
+			assert.NoError(t, err)
+			f.Write([]byte(content))
+		}
+		assert.NoError(t, w.Close())
+		file.Close()
+
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		sum := sha256.Sum256(data)
+		packages[i].URL = name
+		packages[i].Checksum = hex.EncodeToString(sum[:])
+	}
+
+	raw, _ := jsoniter.Marshal(map[string]interface{}{"packages": packages})
+	registry := filepath.Join(dir, "index.json")
+	assert.NoError(t, os.WriteFile(registry, raw, 0644))
+	return registry
+}
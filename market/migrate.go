@@ -0,0 +1,63 @@
+package market
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/yao/config"
+	yaomodel "github.com/yaoapp/yao/model"
+	"github.com/yaoapp/yao/script"
+	"github.com/yaoapp/yao/share"
+)
+
+// Migrate reload the models, migrate the models of the package and run the migrate process of the package.
+// The process is called with the version installed before, empty if the package is new, and the version installed.
+func Migrate(pkg *Package, from string, files []string) error {
+	models := []string{}
+	for _, file := range files {
+		if strings.HasPrefix(file, "models/") && strings.Contains(file, ".mod.") {
+			models = append(models, share.ID("models", file))
+		}
+	}
+
+	if len(models) > 0 {
+		if err := yaomodel.Load(config.Conf); err != nil {
+			return err
+		}
+
+		for _, id := range models {
+			mod, has := model.Models[id]
+			if !has {
+				continue // The ClickHouse models are migrated by yao migrate
+			}
+
+			if err := mod.Migrate(false); err != nil {
+				return fmt.Errorf("the model %s: %s", id, err.Error())
+			}
+			if err := yaomodel.MigrateAssociations(id, false); err != nil {
+				return fmt.Errorf("the model %s: %s", id, err.Error())
+			}
+			if err := yaomodel.MigrateVectors(id, false); err != nil {
+				return fmt.Errorf("the model %s: %s", id, err.Error())
+			}
+		}
+	}
+
+	if pkg.Migrate == "" {
+		return nil
+	}
+
+	// The process may be the script of the package
+	if err := script.Load(config.Conf); err != nil {
+		return err
+	}
+
+	p, err := process.Of(pkg.Migrate, from, pkg.Version)
+	if err != nil {
+		return err
+	}
+	_, err = p.Exec()
+	return err
+}
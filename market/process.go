@@ -0,0 +1,114 @@
+package market
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/config"
+)
+
+func init() {
+	process.RegisterGroup("market", map[string]process.Handler{
+		"packages":  processPackages,
+		"installed": processInstalled,
+		"install":   processInstall,
+		"upgrade":   processUpgrade,
+		"remove":    processRemove,
+	})
+}
+
+// processPackages market.Packages("<registry>"?), the packages of the registry
+func processPackages(process *process.Process) interface{} {
+	registry := ""
+	if process.NumOfArgs() > 0 {
+		registry = process.ArgsString(0)
+	}
+
+	packages, err := Packages(registryOf(registry))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return packages
+}
+
+// processInstalled market.Installed(), the packages installed in the application
+func processInstalled(process *process.Process) interface{} {
+	installed, err := List(config.Conf.Root)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return installed
+}
+
+// processInstall market.Install("<name>", "<versions>"?, "<registry>"?), the application should be reloaded after installed
+func processInstall(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	inst, err := current(optionalArg(process, 2))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+
+	installed, err := inst.Install(process.ArgsString(0), optionalArg(process, 1))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return installed
+}
+
+// processUpgrade market.Upgrade("<name>", "<versions>"?, "<registry>"?), the application should be reloaded after upgraded
+func processUpgrade(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	inst, err := current(optionalArg(process, 2))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+
+	installed, err := inst.Upgrade(process.ArgsString(0), optionalArg(process, 1))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return installed
+}
+
+// processRemove market.Remove("<name>")
+func processRemove(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	inst, err := current("")
+	if err == nil {
+		err = inst.Remove(process.ArgsString(0))
+	}
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return nil
+}
+
+// current the installer of the application, the packages are installed in the root of the application
+func current(registry string) (*Installer, error) {
+	if source := config.Conf.AppSource; source != "" && source != config.Conf.Root {
+		if stat, err := os.Stat(source); err != nil || !stat.IsDir() {
+			return nil, fmt.Errorf("the packages can not be installed in the application packaged")
+		}
+	}
+	return &Installer{Root: config.Conf.Root, Registry: registryOf(registry)}, nil
+}
+
+// registryOf the registry given, YAO_MARKET_REGISTRY or the default registry
+func registryOf(registry string) string {
+	if registry == "" {
+		registry = os.Getenv("YAO_MARKET_REGISTRY")
+	}
+	if registry == "" {
+		registry = DefaultRegistry
+	}
+	return registry
+}
+
+func optionalArg(process *process.Process, i int) string {
+	if process.NumOfArgs() <= i || process.Args[i] == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", process.Args[i])
+}
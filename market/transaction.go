@@ -0,0 +1,114 @@
+package market
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// transaction the changes of the files of the application, the files changed are backed up and restored by the rollback
+type transaction struct {
+	root   string
+	backup string
+	saved  map[string]bool // The files changed, true if the file existed
+	order  []string
+}
+
+func begin(root string) (*transaction, error) {
+	backup, err := os.MkdirTemp("", "yao-market-*")
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{root: root, backup: backup, saved: map[string]bool{}}, nil
+}
+
+// save back up the file before the change, the file is backed up once
+func (tx *transaction) save(name string) error {
+	if _, has := tx.saved[name]; has {
+		return nil
+	}
+
+	data, err := os.ReadFile(tx.path(name))
+	if os.IsNotExist(err) {
+		tx.saved[name] = false
+		tx.order = append(tx.order, name)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	file := filepath.Join(tx.backup, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+	tx.saved[name] = true
+	tx.order = append(tx.order, name)
+	return nil
+}
+
+func (tx *transaction) write(name string, content []byte) error {
+	if err := tx.save(name); err != nil {
+		return err
+	}
+	file := tx.path(name)
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(file, content, 0644)
+}
+
+func (tx *transaction) remove(name string) error {
+	if err := tx.save(name); err != nil {
+		return err
+	}
+	err := os.Remove(tx.path(name))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	tx.prune(name)
+	return nil
+}
+
+// rollback restore the files backed up and remove the files created
+func (tx *transaction) rollback() {
+	defer os.RemoveAll(tx.backup)
+	for i := len(tx.order) - 1; i >= 0; i-- {
+		name := tx.order[i]
+		if !tx.saved[name] {
+			os.Remove(tx.path(name))
+			tx.prune(name)
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(tx.backup, filepath.FromSlash(name)))
+		if err != nil {
+			continue
+		}
+		file := tx.path(name)
+		os.MkdirAll(filepath.Dir(file), os.ModePerm)
+		os.WriteFile(file, data, 0644)
+	}
+}
+
+func (tx *transaction) commit() error {
+	return os.RemoveAll(tx.backup)
+}
+
+func (tx *transaction) path(name string) string {
+	return filepath.Join(tx.root, filepath.FromSlash(name))
+}
+
+// prune remove the empty directories of the file, the root is kept
+func (tx *transaction) prune(name string) {
+	root := filepath.Clean(tx.root)
+	dir := filepath.Dir(tx.path(name))
+	for dir != root && strings.HasPrefix(dir, root+string(os.PathSeparator)) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,609 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// The types of the associations
+const (
+	BelongsToMany = "belongsToMany"
+	MorphOne      = "morphOne"
+	MorphMany     = "morphMany"
+	MorphTo       = "morphTo"
+)
+
+// Association the many-to-many and the polymorphic relations of the model, eager loaded with the withs like the relations
+//
+//	"associations": {
+//	  "tags":     { "type": "belongsToMany", "model": "tag", "pivot": "pet_tag", "foreign": "pet_id", "key": "tag_id" },
+//	  "comments": { "type": "morphMany", "model": "comment", "morph": "commentable" },
+//	  "cover":    { "type": "morphOne", "model": "image", "morph": "imageable" }
+//	}
+//
+//	comment.mod.yao
+//	"associations": {
+//	  "commentable": { "type": "morphTo", "morph": "commentable", "models": ["pet", "user"] }
+//	}
+type Association struct {
+	Name    string   `json:"-"`
+	Type    string   `json:"type"`
+	Model   string   `json:"model,omitempty"`   // The related model
+	Pivot   string   `json:"pivot,omitempty"`   // belongsToMany: the pivot table, the names of the models in order by default, e.g. pet_tag
+	Foreign string   `json:"foreign,omitempty"` // belongsToMany: the column of the model in the pivot table, <model>_id by default
+	Key     string   `json:"key,omitempty"`     // belongsToMany: the column of the related model in the pivot table, <related>_id by default
+	Morph   string   `json:"morph,omitempty"`   // morph*: the columns <morph>_type and <morph>_id of the related rows or of the row (morphTo)
+	Models  []string `json:"models,omitempty"`  // morphTo: the models of the <morph>_type
+	owner   string
+}
+
+// associations the associations of the models
+var associations = map[string]map[string]*Association{}
+var associationLock sync.RWMutex
+
+func init() {
+	for _, method := range []string{"find", "get", "paginate"} {
+		name := "models." + method
+		if handler, has := process.Handlers[name]; has {
+			process.Handlers[name] = associating(method, handler)
+		}
+	}
+
+	process.Register("models.attach", processAttach)
+	process.Register("models.detach", processDetach)
+	process.Register("models.sync", processSync)
+}
+
+// loadAssociations load the associations of the model source
+func loadAssociations(file string, id string) error {
+	source, err := application.App.Read(file)
+	if err != nil {
+		return err
+	}
+
+	dsl := struct {
+		Associations map[string]*Association `json:"associations,omitempty"`
+	}{}
+	err = application.Parse(file, source, &dsl)
+	if err != nil {
+		return err
+	}
+
+	associationLock.Lock()
+	defer associationLock.Unlock()
+	delete(associations, id)
+	if len(dsl.Associations) == 0 {
+		return nil
+	}
+
+	for name, assoc := range dsl.Associations {
+		assoc.Name, assoc.owner = name, id
+		if err := assoc.validate(); err != nil {
+			return fmt.Errorf("[%s] associations.%s %s", id, name, err.Error())
+		}
+	}
+
+	associations[id] = dsl.Associations
+	return nil
+}
+
+// MigrateAssociations create the pivot tables of the model, the tables are dropped first if reset
+func MigrateAssociations(id string, reset bool) error {
+	associationLock.RLock()
+	assocs := associations[id]
+	associationLock.RUnlock()
+
+	sch := capsule.Schema()
+	for _, assoc := range assocs {
+		if assoc.Type != BelongsToMany {
+			continue
+		}
+
+		owner, related, err := assoc.models()
+		if err != nil {
+			return err
+		}
+
+		if reset {
+			if err := sch.DropTableIfExists(assoc.Pivot); err != nil {
+				return err
+			}
+		}
+
+		has, err := sch.HasTable(assoc.Pivot)
+		if err != nil {
+			return err
+		}
+
+		if has {
+			continue
+		}
+
+		err = sch.CreateTable(assoc.Pivot, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("slot", 500).Unique() // <foreign>|<key>
+			keyColumn(table, assoc.Foreign, owner)
+			keyColumn(table, assoc.Key, related)
+			table.TimestampTz("created_at").Null()
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the pivot table: %s", assoc.Pivot)
+	}
+	return nil
+}
+
+// keyColumn the column of the primary key of the model in the pivot table
+func keyColumn(table schema.Blueprint, name string, mod *model.Model) {
+	if column, has := mod.Columns[mod.PrimaryKey]; has {
+		switch strings.ToLower(column.Type) {
+		case "string", "char", "uuid":
+			table.String(name, 200).Index()
+			return
+		}
+	}
+	table.BigInteger(name).Index()
+}
+
+func (assoc *Association) validate() error {
+	name := func(id string) string { return strings.ReplaceAll(id, ".", "_") }
+
+	switch assoc.Type {
+	case BelongsToMany:
+		if assoc.Model == "" {
+			return fmt.Errorf("the model is required")
+		}
+
+		if assoc.Pivot == "" {
+			names := []string{name(assoc.owner), name(assoc.Model)}
+			sort.Strings(names)
+			assoc.Pivot = strings.Join(names, "_")
+		}
+
+		if assoc.Foreign == "" {
+			assoc.Foreign = name(assoc.owner) + "_id"
+		}
+
+		if assoc.Key == "" {
+			assoc.Key = name(assoc.Model) + "_id"
+		}
+
+		if assoc.Foreign == assoc.Key {
+			return fmt.Errorf("the foreign and the key of the pivot table %s are the same", assoc.Pivot)
+		}
+
+	case MorphOne, MorphMany:
+		if assoc.Model == "" || assoc.Morph == "" {
+			return fmt.Errorf("the model and the morph are required")
+		}
+
+	case MorphTo:
+		if assoc.Morph == "" || len(assoc.Models) == 0 {
+			return fmt.Errorf("the morph and the models are required")
+		}
+
+	default:
+		return fmt.Errorf("the type %s is not supported, %s, %s, %s or %s", assoc.Type, BelongsToMany, MorphOne, MorphMany, MorphTo)
+	}
+	return nil
+}
+
+func associationsOf(id string) map[string]*Association {
+	associationLock.RLock()
+	defer associationLock.RUnlock()
+	return associations[id]
+}
+
+// associating query the rows then load the associations of the withs
+func associating(method string, handler process.Handler) process.Handler {
+	return func(p *process.Process) interface{} {
+		assocs := associationsOf(p.ID)
+		if len(assocs) == 0 {
+			return handler(p)
+		}
+
+		i := 0
+		if method == "find" {
+			i = 1
+		}
+
+		if p.NumOfArgs() <= i {
+			return handler(p)
+		}
+
+		param := p.ArgsQueryParams(i, types.QueryParam{})
+		withs := []*Association{}
+		for name, assoc := range assocs {
+			if _, has := param.Withs[name]; has {
+				withs = append(withs, assoc)
+				delete(param.Withs, name)
+			}
+		}
+
+		if len(withs) == 0 {
+			return handler(p)
+		}
+
+		owner, has := model.Models[p.ID]
+		if !has {
+			return handler(p)
+		}
+
+		// The columns of the associations are selected
+		if len(param.Select) > 0 {
+			for _, assoc := range withs {
+				columns := []string{owner.PrimaryKey}
+				if assoc.Type == MorphTo {
+					columns = []string{assoc.Morph + "_type", assoc.Morph + "_id"}
+				}
+				for _, column := range columns {
+					if !selected(param.Select, column) {
+						param.Select = append(param.Select, column)
+					}
+				}
+			}
+		}
+
+		p.Args[i] = param
+		res := handler(p)
+		rows := rowsOf(res)
+		for _, assoc := range withs {
+			err := assoc.load(p, owner, rows)
+			if err != nil {
+				exception.New(err.Error(), 500).Throw()
+			}
+		}
+		return res
+	}
+}
+
+// load set the related rows of the association to the rows
+func (assoc *Association) load(p *process.Process, owner *model.Model, rows []map[string]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	ids := []interface{}{}
+	for _, row := range rows {
+		ids = append(ids, row[owner.PrimaryKey])
+	}
+
+	switch assoc.Type {
+	case BelongsToMany:
+		_, related, err := assoc.models()
+		if err != nil {
+			return err
+		}
+
+		pivots, err := assoc.pivot().Select(assoc.Foreign, assoc.Key).WhereIn(assoc.Foreign, ids).Get()
+		if err != nil {
+			return err
+		}
+
+		keys := []interface{}{}
+		for _, pivot := range pivots {
+			keys = append(keys, pivot[assoc.Key])
+		}
+
+		index, err := assoc.index(p, assoc.Model, related.PrimaryKey, keys)
+		if err != nil {
+			return err
+		}
+
+		values := map[string][]interface{}{}
+		for _, pivot := range pivots {
+			if value, has := index[fmt.Sprintf("%v", pivot[assoc.Key])]; has {
+				foreign := fmt.Sprintf("%v", pivot[assoc.Foreign])
+				values[foreign] = append(values[foreign], value)
+			}
+		}
+
+		for _, row := range rows {
+			row[assoc.Name] = values[fmt.Sprintf("%v", row[owner.PrimaryKey])]
+			if row[assoc.Name] == nil {
+				row[assoc.Name] = []interface{}{}
+			}
+		}
+
+	case MorphOne, MorphMany:
+		res, err := assoc.get(p, assoc.Model, types.QueryParam{
+			Wheres: []types.QueryWhere{
+				{Column: assoc.Morph + "_type", Value: assoc.owner},
+				{Column: assoc.Morph + "_id", OP: "in", Value: ids},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		values := map[string][]interface{}{}
+		for _, value := range res {
+			id := fmt.Sprintf("%v", value[assoc.Morph+"_id"])
+			values[id] = append(values[id], maps.MapStrAny(value))
+		}
+
+		for _, row := range rows {
+			related := values[fmt.Sprintf("%v", row[owner.PrimaryKey])]
+			if assoc.Type == MorphOne {
+				row[assoc.Name] = nil
+				if len(related) > 0 {
+					row[assoc.Name] = related[0]
+				}
+				continue
+			}
+
+			if related == nil {
+				related = []interface{}{}
+			}
+			row[assoc.Name] = related
+		}
+
+	case MorphTo:
+		keys := map[string][]interface{}{}
+		for _, row := range rows {
+			name := fmt.Sprintf("%v", row[assoc.Morph+"_type"])
+			keys[name] = append(keys[name], row[assoc.Morph+"_id"])
+		}
+
+		indexes := map[string]map[string]interface{}{}
+		for _, name := range assoc.Models {
+			if len(keys[name]) == 0 {
+				continue
+			}
+
+			related, has := model.Models[name]
+			if !has {
+				return fmt.Errorf("the model %s of the association %s does not exist", name, assoc.Name)
+			}
+
+			index, err := assoc.index(p, name, related.PrimaryKey, keys[name])
+			if err != nil {
+				return err
+			}
+			indexes[name] = index
+		}
+
+		for _, row := range rows {
+			row[assoc.Name] = nil
+			if index, has := indexes[fmt.Sprintf("%v", row[assoc.Morph+"_type"])]; has {
+				row[assoc.Name] = index[fmt.Sprintf("%v", row[assoc.Morph+"_id"])]
+			}
+		}
+	}
+	return nil
+}
+
+// index the rows of the related model by the primary keys
+func (assoc *Association) index(p *process.Process, name string, primary string, keys []interface{}) (map[string]interface{}, error) {
+	index := map[string]interface{}{}
+	if len(keys) == 0 {
+		return index, nil
+	}
+
+	res, err := assoc.get(p, name, types.QueryParam{Wheres: []types.QueryWhere{{Column: primary, OP: "in", Value: keys}}})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range res {
+		index[fmt.Sprintf("%v", value[primary])] = maps.MapStrAny(value)
+	}
+	return index, nil
+}
+
+// get the rows of the related model, the hooks and the computed fields of the related model are applied
+func (assoc *Association) get(p *process.Process, name string, param types.QueryParam) ([]map[string]interface{}, error) {
+	get, err := process.Of(fmt.Sprintf("models.%s.get", name), param)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := get.WithSID(p.Sid).WithGlobal(p.Global).Exec()
+	if err != nil {
+		return nil, err
+	}
+	return rowsOf(res), nil
+}
+
+// models the model and the related model of the many-to-many association
+func (assoc *Association) models() (*model.Model, *model.Model, error) {
+	owner, has := model.Models[assoc.owner]
+	if !has {
+		return nil, nil, fmt.Errorf("the model %s does not exist", assoc.owner)
+	}
+
+	related, has := model.Models[assoc.Model]
+	if !has {
+		return nil, nil, fmt.Errorf("the model %s of the association %s does not exist", assoc.Model, assoc.Name)
+	}
+	return owner, related, nil
+}
+
+func (assoc *Association) pivot() query.Query {
+	qb := capsule.Query()
+	qb.Table(assoc.Pivot)
+	return qb
+}
+
+// attach insert the pivot rows of the keys not attached, returns the keys attached
+func (assoc *Association) attach(id interface{}, keys []interface{}) ([]interface{}, error) {
+	attached, err := assoc.attached(id)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []map[string]interface{}{}
+	res := []interface{}{}
+	now := time.Now()
+	for _, key := range keys {
+		slot := fmt.Sprintf("%v|%v", id, key)
+		if attached[slot] {
+			continue
+		}
+		attached[slot] = true
+		res = append(res, key)
+		values = append(values, map[string]interface{}{"slot": slot, assoc.Foreign: id, assoc.Key: key, "created_at": now})
+	}
+
+	if len(values) > 0 {
+		if err := assoc.pivot().Insert(values); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// detach remove the pivot rows of the keys, all of the keys of the row if the keys are nil
+func (assoc *Association) detach(id interface{}, keys []interface{}) (int64, error) {
+	qb := assoc.pivot().Where(assoc.Foreign, id)
+	if keys != nil {
+		if len(keys) == 0 {
+			return 0, nil
+		}
+		qb.WhereIn(assoc.Key, keys)
+	}
+	return qb.Delete()
+}
+
+// attached the slots of the pivot rows of the row
+func (assoc *Association) attached(id interface{}) (map[string]bool, error) {
+	rows, err := assoc.pivot().Select("slot").Where(assoc.Foreign, id).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	slots := map[string]bool{}
+	for _, row := range rows {
+		slots[fmt.Sprintf("%v", row["slot"])] = true
+	}
+	return slots, nil
+}
+
+// selected the column is selected
+func selected(columns []interface{}, name string) bool {
+	for _, column := range columns {
+		if fmt.Sprintf("%v", column) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// manyToMany the many-to-many association of the process, the args are (:primary, :association, ...)
+func manyToMany(p *process.Process) (*Association, interface{}) {
+	p.ValidateArgNums(2)
+	name := p.ArgsString(1)
+	assoc, has := associationsOf(p.ID)[name]
+	if !has || assoc.Type != BelongsToMany {
+		exception.New("the many-to-many association %s of the model %s does not exist", 400, name, p.ID).Throw()
+	}
+	return assoc, p.Args[0]
+}
+
+// keysOf the keys of the arg, the array or the string separated by comma
+func keysOf(value interface{}) []interface{} {
+	switch values := value.(type) {
+	case []interface{}:
+		return values
+	case nil:
+		return nil
+	case string:
+		keys := []interface{}{}
+		for _, key := range strings.Split(values, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	}
+	return []interface{}{value}
+}
+
+// processAttach models.<model>.Attach (:primary, :association, :keys), attach the related rows, returns the keys attached
+func processAttach(p *process.Process) interface{} {
+	p.ValidateArgNums(3)
+	assoc, id := manyToMany(p)
+	res, err := assoc.attach(id, keysOf(p.Args[2]))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return res
+}
+
+// processDetach models.<model>.Detach (:primary, :association, [:keys]), detach the related rows, all of them without the keys
+func processDetach(p *process.Process) interface{} {
+	assoc, id := manyToMany(p)
+	var keys []interface{}
+	if p.NumOfArgs() > 2 {
+		keys = keysOf(p.Args[2])
+	}
+
+	res, err := assoc.detach(id, keys)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return res
+}
+
+// processSync models.<model>.Sync (:primary, :association, :keys), the related rows are the keys exactly
+func processSync(p *process.Process) interface{} {
+	p.ValidateArgNums(3)
+	assoc, id := manyToMany(p)
+	keys := keysOf(p.Args[2])
+	if keys == nil {
+		keys = []interface{}{}
+	}
+
+	attached, err := assoc.attached(id)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	kept := map[string]bool{}
+	for _, key := range keys {
+		kept[fmt.Sprintf("%v|%v", id, key)] = true
+	}
+
+	detached := []interface{}{}
+	rows, err := assoc.pivot().Select(assoc.Key, "slot").Where(assoc.Foreign, id).Get()
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	for _, row := range rows {
+		if !kept[fmt.Sprintf("%v", row["slot"])] {
+			detached = append(detached, row[assoc.Key])
+		}
+	}
+
+	if _, err := assoc.detach(id, detached); err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	added := []interface{}{}
+	for _, key := range keys {
+		if !attached[fmt.Sprintf("%v|%v", id, key)] {
+			added = append(added, key)
+		}
+	}
+
+	res, err := assoc.attach(id, added)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return map[string]interface{}{"attached": res, "detached": detached}
+}
@@ -0,0 +1,434 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+	"github.com/yaoapp/yao/share"
+)
+
+// historyTable the changes of the fields of the models
+const historyTable = "yao_model_history"
+
+// Audit the audit of the model, the users of the changes and the changes of the fields
+//
+//	"audit": { "columns": true, "history": true, "fields": ["name", "status"] }
+type Audit struct {
+	Columns   bool     `json:"columns,omitempty"`    // The created_by and the updated_by are set with the user of the session
+	CreatedBy string   `json:"created_by,omitempty"` // The column of the user created the row, created_by by default
+	UpdatedBy string   `json:"updated_by,omitempty"` // The column of the user updated the row, updated_by by default
+	Session   string   `json:"session,omitempty"`    // The key of the user of the session, user_id by default
+	History   bool     `json:"history,omitempty"`    // The changes of the fields are recorded
+	Fields    []string `json:"fields,omitempty"`     // The fields recorded, all of the fields by default
+	fields    map[string]bool
+}
+
+// audits the audits of the models
+var audits = map[string]*Audit{}
+var auditLock sync.RWMutex
+
+// historyReady the history table is created
+var historyReady = false
+
+func init() {
+	for _, method := range []string{"create", "save", "update", "updatewhere", "delete", "destroy", "deletewhere", "destroywhere"} {
+		name := "models." + method
+		if handler, has := process.Handlers[name]; has {
+			process.Handlers[name] = auditing(method, handler)
+		}
+	}
+	process.Register("models.history", processHistory)
+}
+
+// loadAudit load the audit of the model source
+func loadAudit(file string, id string, mod *model.Model) error {
+	source, err := application.App.Read(file)
+	if err != nil {
+		return err
+	}
+
+	dsl := struct {
+		Audit *Audit `json:"audit,omitempty"`
+	}{}
+	err = application.Parse(file, source, &dsl)
+	if err != nil {
+		return err
+	}
+
+	auditLock.Lock()
+	defer auditLock.Unlock()
+	delete(audits, id)
+	if dsl.Audit == nil || (!dsl.Audit.Columns && !dsl.Audit.History) {
+		return nil
+	}
+
+	audit := dsl.Audit
+	if audit.CreatedBy == "" {
+		audit.CreatedBy = "created_by"
+	}
+	if audit.UpdatedBy == "" {
+		audit.UpdatedBy = "updated_by"
+	}
+	if audit.Session == "" {
+		audit.Session = "user_id"
+	}
+
+	if audit.Columns {
+		for _, column := range []string{audit.CreatedBy, audit.UpdatedBy} {
+			if _, has := mod.Columns[column]; !has {
+				return fmt.Errorf("[%s] audit the column %s does not exist", id, column)
+			}
+		}
+	}
+
+	audit.fields = map[string]bool{}
+	for _, field := range audit.Fields {
+		audit.fields[field] = true
+	}
+
+	if audit.History && !historyReady && capsule.Global != nil {
+		if err := migrateHistory(); err != nil {
+			return fmt.Errorf("[%s] audit %s", id, err.Error())
+		}
+	}
+
+	audits[id] = audit
+	return nil
+}
+
+func auditOf(id string) *Audit {
+	auditLock.RLock()
+	defer auditLock.RUnlock()
+	return audits[id]
+}
+
+// migrateHistory create the history table
+func migrateHistory() error {
+	sch := capsule.Schema()
+	has, err := sch.HasTable(historyTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		err = sch.CreateTable(historyTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("model", 200).Index()
+			table.String("row_id", 200).Index()
+			table.String("action", 20)
+			table.String("field", 200).SetDefault("")
+			table.Text("old").Null()
+			table.Text("new").Null()
+			table.String("user", 200).SetDefault("").Index()
+			table.TimestampTz("created_at").Null().Index()
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the model history table: %s", historyTable)
+	}
+
+	historyReady = true
+	return nil
+}
+
+// auditing set the users of the rows and record the changes of the fields
+func auditing(method string, handler process.Handler) process.Handler {
+	return func(p *process.Process) interface{} {
+		audit := auditOf(p.ID)
+		if audit == nil {
+			return handler(p)
+		}
+
+		mod := model.Select(p.ID)
+		user := audit.user(p.Sid)
+
+		switch method {
+		case "create", "save":
+			p.ValidateArgNums(1)
+			row := any.Of(p.Args[0]).MapStr()
+			id, has := row[mod.PrimaryKey]
+			if method == "create" || !has || id == nil {
+				audit.stamp(row, user, true)
+				p.Args[0] = map[string]interface{}(row)
+				res := handler(p)
+				audit.record(p.ID, "create", []rowChange{{id: res, new: row}}, user)
+				return res
+			}
+
+			audit.stamp(row, user, false)
+			p.Args[0] = map[string]interface{}(row)
+			return audit.update(p, mod, handler, types.QueryParam{Wheres: []types.QueryWhere{{Column: mod.PrimaryKey, Value: id}}}, row, user)
+
+		case "update":
+			p.ValidateArgNums(2)
+			row := any.Of(p.Args[1]).MapStr()
+			audit.stamp(row, user, false)
+			p.Args[1] = map[string]interface{}(row)
+			return audit.update(p, mod, handler, types.QueryParam{Wheres: []types.QueryWhere{{Column: mod.PrimaryKey, Value: p.Args[0]}}}, row, user)
+
+		case "updatewhere":
+			p.ValidateArgNums(2)
+			row := any.Of(p.Args[1]).MapStr()
+			audit.stamp(row, user, false)
+			p.Args[1] = map[string]interface{}(row)
+			return audit.update(p, mod, handler, p.ArgsQueryParams(0, types.QueryParam{}), row, user)
+
+		case "delete", "destroy":
+			p.ValidateArgNums(1)
+			return audit.delete(p, mod, handler, types.QueryParam{Wheres: []types.QueryWhere{{Column: mod.PrimaryKey, Value: p.Args[0]}}}, user)
+
+		case "deletewhere", "destroywhere":
+			p.ValidateArgNums(1)
+			return audit.delete(p, mod, handler, p.ArgsQueryParams(0, types.QueryParam{}), user)
+		}
+		return handler(p)
+	}
+}
+
+// rowChange the row before and after the change
+type rowChange struct {
+	id  interface{}
+	old map[string]interface{}
+	new map[string]interface{}
+}
+
+// update record the changes of the rows matched by the param
+func (audit *Audit) update(p *process.Process, mod *model.Model, handler process.Handler, param types.QueryParam, row map[string]interface{}, user interface{}) interface{} {
+	if !audit.History {
+		return handler(p)
+	}
+
+	rows := audit.before(mod, param)
+	res := handler(p)
+
+	changes := []rowChange{}
+	for _, old := range rows {
+		changes = append(changes, rowChange{id: old[mod.PrimaryKey], old: old, new: row})
+	}
+	audit.record(p.ID, "update", changes, user)
+	return res
+}
+
+// delete record the rows matched by the param removed
+func (audit *Audit) delete(p *process.Process, mod *model.Model, handler process.Handler, param types.QueryParam, user interface{}) interface{} {
+	if !audit.History {
+		return handler(p)
+	}
+
+	rows := audit.before(mod, param)
+	res := handler(p)
+
+	changes := []rowChange{}
+	for _, old := range rows {
+		changes = append(changes, rowChange{id: old[mod.PrimaryKey], old: old})
+	}
+	audit.record(p.ID, "delete", changes, user)
+	return res
+}
+
+// before the rows before the change
+func (audit *Audit) before(mod *model.Model, param types.QueryParam) []map[string]interface{} {
+	param.Select, param.Withs, param.Orders = nil, nil, nil
+	rows, err := mod.Get(param)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return rowsOf(rows)
+}
+
+// stamp set the user of the session to the audit columns of the row
+func (audit *Audit) stamp(row map[string]interface{}, user interface{}, created bool) {
+	if !audit.Columns || user == nil {
+		return
+	}
+
+	if created {
+		row[audit.CreatedBy] = user
+	}
+	row[audit.UpdatedBy] = user
+}
+
+// user the user of the session, nil without the session or for the guests
+func (audit *Audit) user(sid string) interface{} {
+	if sid == "" || share.IsGuest(sid) {
+		return nil
+	}
+
+	user, err := session.Global().ID(sid).Get(audit.Session)
+	if err != nil {
+		log.Error("[Model] audit the user of the session: %s", err.Error())
+		return nil
+	}
+	return user
+}
+
+// record insert the changes of the fields into the history table, the errors are logged
+func (audit *Audit) record(id string, action string, changes []rowChange, user interface{}) {
+	if !audit.History || !historyReady || len(changes) == 0 {
+		return
+	}
+
+	values := []map[string]interface{}{}
+	now := time.Now()
+	by := ""
+	if user != nil {
+		by = fmt.Sprintf("%v", user)
+	}
+
+	for _, change := range changes {
+		for _, field := range audit.diff(action, change) {
+			value := map[string]interface{}{
+				"model":      id,
+				"row_id":     fmt.Sprintf("%v", change.id),
+				"action":     action,
+				"field":      field,
+				"old":        nil,
+				"new":        nil,
+				"user":       by,
+				"created_at": now,
+			}
+
+			if change.old != nil {
+				value["old"] = historyValue(change.old[field])
+			}
+			if change.new != nil {
+				value["new"] = historyValue(change.new[field])
+			}
+			values = append(values, value)
+		}
+	}
+
+	if len(values) == 0 {
+		return
+	}
+
+	if err := historyQuery().Insert(values); err != nil {
+		log.Error("[Model] %s audit the history: %s", id, err.Error())
+	}
+}
+
+// diff the fields recorded of the change, the fields of the row changed
+func (audit *Audit) diff(action string, change rowChange) []string {
+	fields := []string{}
+	data := change.new
+	if action == "delete" {
+		data = change.old
+	}
+
+	for field, value := range data {
+		if len(audit.fields) > 0 && !audit.fields[field] {
+			continue
+		}
+
+		if audit.Columns && (field == audit.CreatedBy || field == audit.UpdatedBy) {
+			continue
+		}
+
+		if action == "update" && historyValue(change.old[field]) == historyValue(value) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// historyValue the JSON of the value
+func historyValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	raw, err := jsoniter.MarshalToString(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return raw
+}
+
+// History the changes of the fields of the row, the latest first
+func History(id string, row interface{}, page int, size int) (interface{}, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 20
+	}
+
+	if !historyReady {
+		return paginated([]map[string]interface{}{}, 0, page, size), nil
+	}
+
+	qb := historyQuery().Where("model", id).Where("row_id", fmt.Sprintf("%v", row))
+	total, err := qb.Clone().Count()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := qb.
+		Select("id", "action", "field", "old", "new", "user", "created_at").
+		OrderBy("id", "desc").
+		Offset((page - 1) * size).
+		Limit(size).
+		Get()
+	if err != nil {
+		return nil, err
+	}
+
+	data := []map[string]interface{}{}
+	for _, row := range rows {
+		value := map[string]interface{}{}
+		for key, v := range row {
+			value[key] = v
+		}
+
+		for _, key := range []string{"old", "new"} {
+			if raw, ok := value[key].(string); ok {
+				var v interface{}
+				if err := jsoniter.UnmarshalFromString(raw, &v); err == nil {
+					value[key] = v
+				}
+			}
+		}
+		data = append(data, value)
+	}
+	return paginated(data, int(total), page, size), nil
+}
+
+func historyQuery() query.Query {
+	qb := capsule.Query()
+	qb.Table(historyTable)
+	return qb
+}
+
+// processHistory models.<model>.History (:primary, [:page], [:pagesize]), the changes of the fields of the row.
+// The row must be matched by the policy of the session
+func processHistory(p *process.Process) interface{} {
+	p.ValidateArgNums(1)
+	if auditOf(p.ID) == nil {
+		exception.New("the history of the model %s is not recorded", 400, p.ID).Throw()
+	}
+
+	if wheres := policyWheres(p); len(wheres) > 0 {
+		allowed(p, p.Args[0], wheres)
+	}
+
+	res, err := History(p.ID, p.Args[0], p.ArgsInt(1, 1), p.ArgsInt(2, 20))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return res
+}
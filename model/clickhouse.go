@@ -0,0 +1,218 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/clickhouse"
+)
+
+// clickhouseMethods the processes of the ClickHouse models, the rows are append-only
+var clickhouseMethods = []string{
+	"get", "paginate", "create", "insert",
+	"find", "save", "update", "updatewhere", "delete", "destroy", "deletewhere", "destroywhere", "eachsave", "eachsaveafterdelete",
+}
+
+func init() {
+	for _, method := range clickhouseMethods {
+		name := "models." + method
+		if handler, has := process.Handlers[name]; has {
+			process.Handlers[name] = clickhousing(method, handler)
+		}
+	}
+}
+
+// loadClickHouse load the model of the ClickHouse connector, the ClickHouse models are not the models of gou.
+// Returns false if the connector of the model is not a ClickHouse connector.
+//
+//	{ "connector": "analytics", "table": { "name": "events" }, "columns": [...], "clickhouse": { "order_by": ["team", "created_at"] } }
+func loadClickHouse(file string, id string) (bool, error) {
+	source, err := application.App.Read(file)
+	if err != nil {
+		return false, err
+	}
+
+	dsl := struct {
+		Connector string `json:"connector,omitempty"`
+		Table     struct {
+			Name string `json:"name,omitempty"`
+		} `json:"table,omitempty"`
+		Columns []clickhouse.Column `json:"columns,omitempty"`
+		Option  struct {
+			Timestamps bool `json:"timestamps,omitempty"`
+		} `json:"option,omitempty"`
+		ClickHouse *clickhouse.Table `json:"clickhouse,omitempty"`
+	}{}
+	err = application.Parse(file, source, &dsl)
+	if err != nil {
+		return false, err
+	}
+
+	if dsl.Connector == "" || !clickhouse.Is(dsl.Connector) {
+		return false, nil
+	}
+
+	table := dsl.ClickHouse
+	if table == nil {
+		table = &clickhouse.Table{}
+	}
+	table.ID, table.Name, table.Connector, table.Columns = id, dsl.Table.Name, dsl.Connector, dsl.Columns
+
+	if dsl.Option.Timestamps {
+		if _, has := columnOf(table.Columns, "created_at"); !has {
+			table.Columns = append(table.Columns, clickhouse.Column{Name: "created_at", Type: "timestampTz"})
+		}
+	}
+
+	return true, clickhouse.Register(table)
+}
+
+func columnOf(columns []clickhouse.Column, name string) (clickhouse.Column, bool) {
+	for _, column := range columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return clickhouse.Column{}, false
+}
+
+// clickhousing run the processes of the ClickHouse models, the updates and the deletes are not supported
+func clickhousing(method string, handler process.Handler) process.Handler {
+	return func(p *process.Process) interface{} {
+		table, has := clickhouse.Model(p.ID)
+		if !has {
+			return handler(p)
+		}
+
+		ctx := context.Background()
+		switch method {
+		case "get":
+			query, err := clickhouseQuery(p.ArgsQueryParams(0, types.QueryParam{}))
+			if err != nil {
+				exception.New(err.Error(), 400).Throw()
+			}
+
+			rows, err := table.Get(ctx, query)
+			if err != nil {
+				exception.New(err.Error(), 500).Throw()
+			}
+			return rows
+
+		case "paginate":
+			query, err := clickhouseQuery(p.ArgsQueryParams(0, types.QueryParam{}))
+			if err != nil {
+				exception.New(err.Error(), 400).Throw()
+			}
+
+			page, size := p.ArgsInt(1, 1), p.ArgsInt(2, 20)
+			if page < 1 {
+				page = 1
+			}
+			if size < 1 {
+				size = 20
+			}
+
+			total, err := table.Count(ctx, query.Wheres)
+			if err != nil {
+				exception.New(err.Error(), 500).Throw()
+			}
+
+			query.Limit, query.Offset = size, (page-1)*size
+			rows, err := table.Get(ctx, query)
+			if err != nil {
+				exception.New(err.Error(), 500).Throw()
+			}
+			return paginated(rows, total, page, size)
+
+		case "create":
+			p.ValidateArgNums(1)
+			row := any.Of(p.Args[0]).MapStr()
+			err := table.Insert(ctx, []map[string]interface{}{clickhouseRow(table, row)})
+			if err != nil {
+				exception.New(err.Error(), 500).Throw()
+			}
+			return nil
+
+		case "insert":
+			p.ValidateArgNums(2)
+			columns := []string{}
+			for _, column := range p.ArgsArray(0) {
+				columns = append(columns, fmt.Sprintf("%v", column))
+			}
+
+			rows := []map[string]interface{}{}
+			for _, values := range p.ArgsArray(1) {
+				items, ok := values.([]interface{})
+				if !ok || len(items) != len(columns) {
+					exception.New("the values of the row %v are not the values of the columns %v", 400, values, columns).Throw()
+				}
+
+				row := map[string]interface{}{}
+				for i, column := range columns {
+					row[column] = items[i]
+				}
+				rows = append(rows, clickhouseRow(table, row))
+			}
+
+			err := table.Insert(ctx, rows)
+			if err != nil {
+				exception.New(err.Error(), 500).Throw()
+			}
+			return nil
+		}
+
+		exception.New("the ClickHouse model %s is append-only, %s is not supported", 400, p.ID, method).Throw()
+		return nil
+	}
+}
+
+// clickhouseRow the created_at of the timestamps is set if it is not set
+func clickhouseRow(table *clickhouse.Table, row map[string]interface{}) map[string]interface{} {
+	if _, has := table.Column("created_at"); has && row["created_at"] == nil {
+		row["created_at"] = time.Now()
+	}
+	return row
+}
+
+// clickhouseQuery the query of the query param, the withs are not supported
+func clickhouseQuery(param types.QueryParam) (clickhouse.Query, error) {
+	if len(param.Withs) > 0 {
+		return clickhouse.Query{}, fmt.Errorf("the relations of the ClickHouse models are not supported")
+	}
+
+	query := clickhouse.Query{Limit: param.Limit}
+	for _, column := range param.Select {
+		query.Select = append(query.Select, fmt.Sprintf("%v", column))
+	}
+
+	for _, order := range param.Orders {
+		query.Orders = append(query.Orders, clickhouse.Order{
+			Column: fmt.Sprintf("%v", order.Column),
+			Desc:   strings.EqualFold(fmt.Sprintf("%v", order.Option), "desc"),
+		})
+	}
+
+	query.Wheres = clickhouseWheres(param.Wheres)
+	return query, nil
+}
+
+func clickhouseWheres(wheres []types.QueryWhere) []clickhouse.Where {
+	res := []clickhouse.Where{}
+	for _, where := range wheres {
+		res = append(res, clickhouse.Where{
+			Column: fmt.Sprintf("%v", where.Column),
+			OP:     where.OP,
+			Value:  where.Value,
+			Or:     strings.HasPrefix(strings.ToLower(where.Method), "or"),
+			Wheres: clickhouseWheres(where.Wheres),
+		})
+	}
+	return res
+}
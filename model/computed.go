@@ -0,0 +1,663 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal"
+	"github.com/yaoapp/xun/dbal/query"
+)
+
+// Computed the computed field of the model, the value is never stored.
+// The fields of the SQL expressions could be selected, filtered and sorted, the fields of the processes could be selected only.
+//
+//	"computed": {
+//	  "days": { "label": "Days", "type": "integer", "expression": "DATEDIFF(NOW(), created_at)" },
+//	  "score": { "label": "Score", "process": "scripts.pet.Score" }
+//	}
+type Computed struct {
+	Name       string `json:"-"`
+	Label      string `json:"label,omitempty"`
+	Type       string `json:"type,omitempty"`
+	Expression string `json:"expression,omitempty"` // The SQL expression of the columns of the model
+	Process    string `json:"process,omitempty"`    // The process called with the row, returns the value
+}
+
+// computedModel the computed fields of a model
+type computedModel struct {
+	id          string
+	table       string
+	primary     string
+	softDeletes bool
+	columns     map[string]bool
+	fields      map[string]*Computed
+}
+
+// computedQuery the query param without the computed fields
+type computedQuery struct {
+	param  types.QueryParam
+	fields []*Computed // The computed fields of the rows
+	raw    bool        // The rows are filtered or sorted by the computed fields, queried by the SQL of the expressions
+	orders []types.QueryOrder
+}
+
+var computedName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// computedOps the SQL operators of the query wheres
+var computedOps = map[string]string{
+	"": "=", "eq": "=", "gt": ">", "ge": ">=", "lt": "<", "le": "<=", "ne": "<>",
+	"like": "LIKE", "match": "LIKE", "in": "IN", "null": "IS NULL", "notnull": "IS NOT NULL",
+}
+
+// computeds the computed fields of the models
+var computeds = map[string]*computedModel{}
+var computedLock sync.RWMutex
+
+// getHandler the models.get handler without the computed fields
+var getHandler process.Handler
+
+func init() {
+	getHandler = process.Handlers["models.get"]
+	for _, method := range []string{"find", "get", "paginate"} {
+		name := "models." + method
+		if handler, has := process.Handlers[name]; has {
+			process.Handlers[name] = computing(method, handler)
+		}
+	}
+}
+
+// loadComputed load the computed fields of the model source
+func loadComputed(file string, id string, mod *model.Model) error {
+	source, err := application.App.Read(file)
+	if err != nil {
+		return err
+	}
+
+	dsl := struct {
+		Computed map[string]*Computed `json:"computed,omitempty"`
+	}{}
+	err = application.Parse(file, source, &dsl)
+	if err != nil {
+		return err
+	}
+
+	computedLock.Lock()
+	defer computedLock.Unlock()
+	delete(computeds, id)
+	if len(dsl.Computed) == 0 {
+		return nil
+	}
+
+	m := &computedModel{
+		id:          id,
+		table:       mod.MetaData.Table.Name,
+		primary:     mod.PrimaryKey,
+		softDeletes: mod.MetaData.Option.SoftDeletes,
+		columns:     map[string]bool{},
+		fields:      map[string]*Computed{},
+	}
+	for name := range mod.Columns {
+		m.columns[name] = true
+	}
+
+	for name, field := range dsl.Computed {
+		if !computedName.MatchString(name) {
+			return fmt.Errorf("[%s] the name of the computed field %s is invalid", id, name)
+		}
+
+		if _, has := mod.Columns[name]; has {
+			return fmt.Errorf("[%s] the computed field %s is a column", id, name)
+		}
+
+		if (field.Expression == "") == (field.Process == "") {
+			return fmt.Errorf("[%s] the computed field %s requires an expression or a process", id, name)
+		}
+
+		if strings.Contains(field.Expression, ";") {
+			return fmt.Errorf("[%s] the expression of the computed field %s is invalid", id, name)
+		}
+
+		field.Name = name
+		m.fields[name] = field
+	}
+
+	computeds[id] = m
+	return nil
+}
+
+func computedOf(id string) *computedModel {
+	computedLock.RLock()
+	defer computedLock.RUnlock()
+	return computeds[id]
+}
+
+// computing query the rows with the computed fields
+func computing(method string, handler process.Handler) process.Handler {
+	return func(p *process.Process) interface{} {
+		m := computedOf(p.ID)
+		if m == nil {
+			return handler(p)
+		}
+
+		i := 0
+		if method == "find" {
+			i = 1
+		}
+
+		for p.NumOfArgs() <= i {
+			p.Args = append(p.Args, nil)
+		}
+
+		q, err := m.prepare(p.ArgsQueryParams(i, types.QueryParam{}))
+		if err != nil {
+			exception.New(err.Error(), 400).Throw()
+		}
+		p.Args[i] = q.param
+
+		if q.raw {
+			res, err := m.search(p, method, q)
+			if err != nil {
+				exception.New(err.Error(), 500).Throw()
+			}
+			return res
+		}
+
+		res := handler(p)
+		err = m.compute(p, rowsOf(res), q.fields)
+		if err != nil {
+			exception.New(err.Error(), 500).Throw()
+		}
+		return res
+	}
+}
+
+// prepare replace the computed fields of the selects, the query param filtered or sorted by the computed fields is queried by the SQL
+func (m *computedModel) prepare(param types.QueryParam) (*computedQuery, error) {
+	q := &computedQuery{param: param, fields: []*Computed{}}
+
+	if len(param.Select) == 0 {
+		for _, field := range m.fields {
+			if field.Expression != "" {
+				q.fields = append(q.fields, field)
+			}
+		}
+	} else {
+		selects := []interface{}{}
+		primary := false
+		for _, column := range param.Select {
+			name := fmt.Sprintf("%v", column)
+			if field, has := m.fields[name]; has {
+				q.fields = append(q.fields, field)
+				continue
+			}
+			primary = primary || name == m.primary
+			selects = append(selects, column)
+		}
+
+		if !primary {
+			selects = append(selects, m.primary)
+		}
+		q.param.Select = selects
+	}
+
+	filtered, err := m.filtered(param.Wheres)
+	if err != nil {
+		return nil, err
+	}
+	q.raw = filtered
+
+	for _, order := range param.Orders {
+		if _, has := m.fields[order.Column]; has && order.Rel == "" {
+			q.raw = true
+		}
+	}
+
+	if !q.raw {
+		return q, nil
+	}
+
+	// The rows are queried by the SQL, the wheres and the orders of the relations are not supported
+	if relation := relationOf(param.Wheres); relation != "" {
+		return nil, fmt.Errorf("the wheres of the relation %s could not be used with the computed fields", relation)
+	}
+
+	for _, order := range param.Orders {
+		if order.Rel != "" {
+			return nil, fmt.Errorf("the orders of the relation %s could not be sorted with the computed fields", order.Rel)
+		}
+		if field, has := m.fields[order.Column]; has && field.Expression == "" {
+			return nil, fmt.Errorf("the computed field %s of the model %s could not be sorted", field.Name, m.id)
+		}
+	}
+	q.orders = param.Orders
+	q.param.Orders = nil
+	return q, nil
+}
+
+// filtered the wheres have the computed fields, the computed fields of the processes could not be filtered
+func (m *computedModel) filtered(wheres []types.QueryWhere) (bool, error) {
+	res := false
+	for _, where := range wheres {
+		nested, err := m.filtered(where.Wheres)
+		if err != nil {
+			return false, err
+		}
+		res = res || nested
+
+		field, has := m.fields[fmt.Sprintf("%v", where.Column)]
+		if !has || where.Rel != "" {
+			continue
+		}
+
+		if field.Expression == "" {
+			return false, fmt.Errorf("the computed field %s of the model %s could not be filtered", field.Name, m.id)
+		}
+		res = true
+	}
+	return res, nil
+}
+
+// relationOf the first relation of the wheres, empty if none
+func relationOf(wheres []types.QueryWhere) string {
+	for _, where := range wheres {
+		if where.Rel != "" {
+			return where.Rel
+		}
+		if relation := relationOf(where.Wheres); relation != "" {
+			return relation
+		}
+	}
+	return ""
+}
+
+// search query the rows filtered and sorted by the SQL of the expressions, the primary keys of the page are selected,
+// then the rows of the keys are selected without the computed fields
+func (m *computedModel) search(p *process.Process, method string, q *computedQuery) (interface{}, error) {
+	page, size := 1, 0
+	if method == "paginate" {
+		page, size = p.ArgsInt(1, 1), p.ArgsInt(2, 20)
+		if page < 1 {
+			page = 1
+		}
+		if size < 1 {
+			size = 20
+		}
+	}
+
+	qb := m.query()
+	if m.softDeletes {
+		qb.WhereNull("deleted_at")
+	}
+
+	if method == "find" {
+		qb.Where(m.primary, p.Args[0])
+	}
+
+	for _, where := range q.param.Wheres {
+		err := m.where(qb, where)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	total := 0
+	if method == "paginate" {
+		count, err := qb.Clone().Count()
+		if err != nil {
+			return nil, err
+		}
+		total = int(count)
+	}
+
+	for _, order := range q.orders {
+		direction := "ASC"
+		if strings.ToLower(order.Option) == "desc" {
+			direction = "DESC"
+		}
+
+		if field, has := m.fields[order.Column]; has {
+			qb.OrderByRaw(fmt.Sprintf("(%s) %s", field.Expression, direction))
+			continue
+		}
+
+		if !m.columns[order.Column] {
+			return nil, fmt.Errorf("the column %s of the model %s does not exist", order.Column, m.id)
+		}
+		qb.OrderBy(order.Column, direction)
+	}
+
+	switch {
+	case size > 0:
+		qb.Offset((page - 1) * size).Limit(size)
+	case method == "find":
+		qb.Limit(1)
+	case q.param.Limit > 0:
+		qb.Limit(q.param.Limit)
+	}
+
+	keys, err := qb.Select(m.primary).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []interface{}{}
+	for _, row := range keys {
+		ids = append(ids, row[m.primary])
+	}
+
+	data := []maps.MapStrAny{}
+	if len(ids) > 0 {
+		param := q.param
+		param.Wheres = []types.QueryWhere{{Column: m.primary, OP: "in", Value: ids}}
+		param.Limit = 0
+		rows, err := m.get(p, param)
+		if err != nil {
+			return nil, err
+		}
+
+		index := map[string]maps.MapStrAny{}
+		for _, row := range rows {
+			index[fmt.Sprintf("%v", row[m.primary])] = maps.MapStrAny(row)
+		}
+
+		for _, id := range ids {
+			if row, has := index[fmt.Sprintf("%v", id)]; has {
+				data = append(data, row)
+			}
+		}
+
+		err = m.compute(p, rowsOf(data), q.fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch method {
+	case "find":
+		if len(data) == 0 {
+			exception.New("%v not found", 404, p.Args[0]).Throw()
+		}
+		return data[0], nil
+
+	case "paginate":
+		return paginated(data, total, page, size), nil
+	}
+	return data, nil
+}
+
+// where add the where of the query param to the query, the wheres of the computed fields are the conditions of the expressions
+func (m *computedModel) where(qb query.Query, where types.QueryWhere) error {
+	or := strings.HasPrefix(strings.ToLower(where.Method), "or")
+	if len(where.Wheres) > 0 {
+		var err error
+		group := func(qb query.Query) {
+			for _, nested := range where.Wheres {
+				if e := m.where(qb, nested); e != nil && err == nil {
+					err = e
+				}
+			}
+		}
+
+		if or {
+			qb.OrWhere(group)
+		} else {
+			qb.Where(group)
+		}
+		return err
+	}
+
+	column := fmt.Sprintf("%v", where.Column)
+	if field, has := m.fields[column]; has {
+		stmt, bindings, err := field.condition(where.OP, where.Value)
+		if err != nil {
+			return err
+		}
+
+		if or {
+			qb.OrWhereRaw(stmt, bindings...)
+		} else {
+			qb.WhereRaw(stmt, bindings...)
+		}
+		return nil
+	}
+
+	if !m.columns[column] {
+		return fmt.Errorf("the column %s of the model %s does not exist", column, m.id)
+	}
+
+	op := strings.ToLower(where.OP)
+	switch op {
+	case "in":
+		if or {
+			qb.OrWhereIn(column, keysOf(where.Value))
+		} else {
+			qb.WhereIn(column, keysOf(where.Value))
+		}
+
+	case "null":
+		if or {
+			qb.OrWhereNull(column)
+		} else {
+			qb.WhereNull(column)
+		}
+
+	case "notnull":
+		if or {
+			qb.OrWhereNotNull(column)
+		} else {
+			qb.WhereNotNull(column)
+		}
+
+	default:
+		operator, has := computedOps[op]
+		if !has {
+			return fmt.Errorf("the where %s %s is not supported", column, where.OP)
+		}
+
+		value := where.Value
+		if op == "match" {
+			value = fmt.Sprintf("%%%v%%", value)
+		}
+
+		if or {
+			qb.OrWhere(column, strings.ToLower(operator), value)
+		} else {
+			qb.Where(column, strings.ToLower(operator), value)
+		}
+	}
+	return nil
+}
+
+// paginated the page of the rows like the models.paginate
+func paginated(data interface{}, total int, page int, size int) maps.MapStrAny {
+	pagecnt := (total + size - 1) / size
+	next, prev := -1, -1
+	if page < pagecnt {
+		next = page + 1
+	}
+	if page > 1 {
+		prev = page - 1
+	}
+
+	return maps.MapStrAny{
+		"data":     data,
+		"total":    total,
+		"page":     page,
+		"pagesize": size,
+		"pagecnt":  pagecnt,
+		"next":     next,
+		"prev":     prev,
+	}
+}
+
+// compute set the values of the computed fields of the rows
+func (m *computedModel) compute(p *process.Process, rows []map[string]interface{}, fields []*Computed) error {
+	if len(rows) == 0 || len(fields) == 0 {
+		return nil
+	}
+
+	expressions := []interface{}{m.primary}
+	for _, field := range fields {
+		if field.Expression != "" {
+			expressions = append(expressions, dbal.Raw(fmt.Sprintf("(%s) AS %s", field.Expression, field.Name)))
+		}
+	}
+
+	if len(expressions) > 1 {
+		ids := []interface{}{}
+		for _, row := range rows {
+			ids = append(ids, row[m.primary])
+		}
+
+		values, err := m.query().Select(expressions...).WhereIn(m.primary, ids).Get()
+		if err != nil {
+			return err
+		}
+
+		index := map[string]map[string]interface{}{}
+		for _, value := range values {
+			index[fmt.Sprintf("%v", value[m.primary])] = value
+		}
+
+		for _, row := range rows {
+			value := index[fmt.Sprintf("%v", row[m.primary])]
+			for _, field := range fields {
+				if field.Expression != "" {
+					row[field.Name] = value[field.Name]
+				}
+			}
+		}
+	}
+
+	for _, field := range fields {
+		if field.Process == "" {
+			continue
+		}
+
+		for _, row := range rows {
+			row[field.Name] = nil
+			compute, err := process.Of(field.Process, row)
+			if err != nil {
+				return err
+			}
+
+			value, err := compute.WithSID(p.Sid).WithGlobal(p.Global).Exec()
+			if err != nil {
+				log.Error("[Model] %s computed field %s: %s", m.id, field.Name, err.Error())
+				continue
+			}
+			row[field.Name] = value
+		}
+	}
+	return nil
+}
+
+// get the rows of the model without the computed fields
+func (m *computedModel) get(p *process.Process, param types.QueryParam) ([]map[string]interface{}, error) {
+	if getHandler == nil {
+		return nil, fmt.Errorf("the process models.get is not found")
+	}
+
+	get, err := process.Of(fmt.Sprintf("models.%s.get", m.id), param)
+	if err != nil {
+		return nil, err
+	}
+	return rowsOf(getHandler(get.WithSID(p.Sid).WithGlobal(p.Global))), nil
+}
+
+// condition the SQL condition of the where of the computed field
+func (field *Computed) condition(op string, value interface{}) (string, []interface{}, error) {
+	operator, has := computedOps[strings.ToLower(op)]
+	if !has {
+		return "", nil, fmt.Errorf("the operator %s of the computed field %s is not supported", op, field.Name)
+	}
+
+	expression := fmt.Sprintf("(%s)", field.Expression)
+	switch operator {
+	case "IS NULL", "IS NOT NULL":
+		return fmt.Sprintf("%s %s", expression, operator), []interface{}{}, nil
+
+	case "IN":
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{}
+			for _, v := range strings.Split(fmt.Sprintf("%v", value), ",") {
+				values = append(values, strings.TrimSpace(v))
+			}
+		}
+		if len(values) == 0 {
+			return "1 = 0", []interface{}{}, nil
+		}
+		marks := strings.TrimSuffix(strings.Repeat("?,", len(values)), ",")
+		return fmt.Sprintf("%s IN (%s)", expression, marks), values, nil
+	}
+
+	if strings.ToLower(op) == "match" {
+		value = fmt.Sprintf("%%%v%%", value)
+	}
+	return fmt.Sprintf("%s %s ?", expression, operator), []interface{}{value}, nil
+}
+
+func (m *computedModel) query() query.Query {
+	qb := capsule.Query()
+	qb.Table(m.table)
+	return qb
+}
+
+// rowsOf the rows of the result of find, get or paginate, the rows share the maps of the result
+func rowsOf(res interface{}) []map[string]interface{} {
+	switch values := res.(type) {
+	case maps.MapStrAny:
+		if data, has := values["data"]; has {
+			return rowsOf(data)
+		}
+		return []map[string]interface{}{values}
+	case map[string]interface{}:
+		if data, has := values["data"]; has {
+			return rowsOf(data)
+		}
+		return []map[string]interface{}{values}
+	case []map[string]interface{}:
+		return values
+	case []maps.MapStrAny:
+		rows := []map[string]interface{}{}
+		for _, row := range values {
+			rows = append(rows, row)
+		}
+		return rows
+	case []interface{}:
+		rows := []map[string]interface{}{}
+		for _, value := range values {
+			rows = append(rows, rowsOf(value)...)
+		}
+		return rows
+	}
+
+	// The other types of the maps and the slices, e.g. []maps.MapStr
+	value := reflect.ValueOf(res)
+	mapType := reflect.TypeOf(map[string]interface{}{})
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().ConvertibleTo(mapType) {
+			return rowsOf(value.Convert(mapType).Interface())
+		}
+	case reflect.Slice:
+		rows := []map[string]interface{}{}
+		for i := 0; i < value.Len(); i++ {
+			rows = append(rows, rowsOf(value.Index(i).Interface())...)
+		}
+		return rows
+	}
+	return []map[string]interface{}{}
+}
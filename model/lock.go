@@ -0,0 +1,351 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal"
+	"github.com/yaoapp/xun/dbal/query"
+)
+
+// Lock the locking of the model, the rows updated with a stale version are rejected.
+// The forms and the tables send the version of the row read, the version is increased by the updates.
+//
+//	"lock": { "version": "version" }
+type Lock struct {
+	Version string `json:"version,omitempty"` // The version column, an integer column
+}
+
+// locks the locks of the models
+var locks = map[string]*Lock{}
+var lockLock sync.RWMutex
+
+func init() {
+	for _, method := range []string{"create", "save", "update", "updatewhere"} {
+		name := "models." + method
+		if handler, has := process.Handlers[name]; has {
+			process.Handlers[name] = locking(method, handler)
+		}
+	}
+	process.Register("models.forupdate", processForUpdate)
+}
+
+// loadLock load the lock of the model source
+func loadLock(file string, id string, mod *model.Model) error {
+	source, err := application.App.Read(file)
+	if err != nil {
+		return err
+	}
+
+	dsl := struct {
+		Lock *Lock `json:"lock,omitempty"`
+	}{}
+	err = application.Parse(file, source, &dsl)
+	if err != nil {
+		return err
+	}
+
+	lockLock.Lock()
+	defer lockLock.Unlock()
+	delete(locks, id)
+	if dsl.Lock == nil || dsl.Lock.Version == "" {
+		return nil
+	}
+
+	if _, has := mod.Columns[dsl.Lock.Version]; !has {
+		return fmt.Errorf("[%s] lock the version column %s does not exist", id, dsl.Lock.Version)
+	}
+
+	locks[id] = dsl.Lock
+	return nil
+}
+
+func lockOf(id string) *Lock {
+	lockLock.RLock()
+	defer lockLock.RUnlock()
+	return locks[id]
+}
+
+// locking check the versions of the rows updated, the rows of the processes of the sessions must be updated with the version
+func locking(method string, handler process.Handler) process.Handler {
+	return func(p *process.Process) interface{} {
+		lock := lockOf(p.ID)
+		if lock == nil {
+			return handler(p)
+		}
+
+		mod := model.Select(p.ID)
+		switch method {
+		case "create", "save":
+			p.ValidateArgNums(1)
+			row := any.Of(p.Args[0]).MapStr()
+			id, has := row[mod.PrimaryKey]
+			if method == "create" || !has || id == nil {
+				if row[lock.Version] == nil {
+					row[lock.Version] = 1
+				}
+				p.Args[0] = map[string]interface{}(row)
+				return handler(p)
+			}
+			p.Args[0] = map[string]interface{}(row)
+			lock.update(p, mod, handler, id, row)
+			return id
+
+		case "update":
+			p.ValidateArgNums(2)
+			row := any.Of(p.Args[1]).MapStr()
+			p.Args[1] = map[string]interface{}(row)
+			lock.update(p, mod, handler, p.Args[0], row)
+			return nil
+
+		case "updatewhere":
+			p.ValidateArgNums(2)
+			row := any.Of(p.Args[1]).MapStr()
+			delete(row, lock.Version)
+			p.Args[1] = map[string]interface{}(row)
+
+			param := p.ArgsQueryParams(0, types.QueryParam{})
+			param.Select, param.Withs, param.Orders = []interface{}{mod.PrimaryKey}, nil, nil
+			rows, err := mod.Get(param)
+			if err != nil {
+				exception.New(err.Error(), 500).Throw()
+			}
+
+			res := handler(p)
+			ids := []interface{}{}
+			for _, row := range rowsOf(rows) {
+				ids = append(ids, row[mod.PrimaryKey])
+			}
+			lock.increase(mod, ids)
+			return res
+		}
+		return handler(p)
+	}
+}
+
+// update the row with the version, the row is updated only if the version is the version of the row stored.
+// The row of the trusted process without the version is updated and the version is increased.
+func (lock *Lock) update(p *process.Process, mod *model.Model, handler process.Handler, id interface{}, row map[string]interface{}) {
+	version, has := row[lock.Version]
+	if !has || version == nil {
+		if p.Sid != "" {
+			exception.New("%s %v the %s is required", 400, p.ID, id, lock.Version).Throw()
+		}
+		handler(p)
+		lock.increase(mod, []interface{}{id})
+		return
+	}
+
+	param, data := lock.claim(mod.PrimaryKey, id, row)
+	updatewhere, err := process.Of(fmt.Sprintf("models.%s.updatewhere", p.ID), param, data)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	res, err := updatewhere.WithSID(p.Sid).WithGlobal(p.Global).Exec()
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	if any.Of(res).CInt() == 0 {
+		exception.New("%s %v has been changed or removed, the %s %v is stale", 409, p.ID, id, lock.Version, version).Throw()
+	}
+}
+
+// claim the query param of the row with the version and the data with the version increased
+func (lock *Lock) claim(primary string, id interface{}, row map[string]interface{}) (types.QueryParam, map[string]interface{}) {
+	version := any.Of(row[lock.Version]).CInt()
+	param := types.QueryParam{
+		Wheres: []types.QueryWhere{
+			{Column: primary, Value: id},
+			{Column: lock.Version, Value: version},
+		},
+	}
+
+	data := map[string]interface{}{}
+	for key, value := range row {
+		if key == primary {
+			continue
+		}
+		data[key] = value
+	}
+	data[lock.Version] = version + 1
+	return param, data
+}
+
+// increase the versions of the rows
+func (lock *Lock) increase(mod *model.Model, ids []interface{}) {
+	if len(ids) == 0 {
+		return
+	}
+
+	qb := capsule.Query()
+	qb.Table(mod.MetaData.Table.Name)
+	_, err := qb.WhereIn(mod.PrimaryKey, ids).Update(map[string]interface{}{
+		lock.Version: dbal.Raw(fmt.Sprintf("%s + 1", lock.Version)),
+	})
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+}
+
+// ForUpdate lock the row with SELECT ... FOR UPDATE in a transaction, the process is called with the row
+// and the row is updated with the data returned in the same transaction. Returns the row updated.
+// The process must not update the row itself, the row is locked until the transaction is committed.
+// The rows are read and written with the fields handling of the model, the data is checked as the update process does, see written.
+func ForUpdate(p *process.Process, id interface{}, name string, args ...interface{}) (map[string]interface{}, error) {
+	mod := model.Select(p.ID)
+
+	// The row of the session must be matched by the policy
+	wheres := policyWheres(p)
+	if len(wheres) > 0 {
+		if err := guarded(403, func() { allowed(p, id, wheres) }); err != nil {
+			return nil, err
+		}
+	}
+
+	lock := lockOf(p.ID)
+	audit := auditOf(p.ID)
+	var user interface{}
+	if audit != nil {
+		user = audit.user(p.Sid)
+	}
+	var old, changed map[string]interface{}
+
+	err := capsule.Query().Transaction(func(qb query.Query) error {
+		row, err := qb.New().Table(mod.MetaData.Table.Name).Where(mod.PrimaryKey, id).LockForUpdate().First()
+		if err != nil {
+			return err
+		}
+		if len(row) == 0 {
+			return lockError{code: 404, message: fmt.Sprintf("%s %v does not exist", p.ID, id)}
+		}
+
+		// The row locked is not changed by the others, it is read by the model to decode the fields
+		values, err := mod.Find(id, types.QueryParam{})
+		if err != nil {
+			return err
+		}
+		old = map[string]interface{}(values)
+
+		handler, err := process.Of(name, append([]interface{}{old}, args...)...)
+		if err != nil {
+			return err
+		}
+		res, err := handler.WithSID(p.Sid).WithGlobal(p.Global).Exec()
+		if err != nil {
+			return err
+		}
+
+		changed = map[string]interface{}{}
+		if res != nil {
+			for key, value := range any.Of(res).MapStr() {
+				if key != mod.PrimaryKey {
+					changed[key] = value
+				}
+			}
+		}
+
+		if len(changed) == 0 {
+			return nil
+		}
+
+		changed, err = written(p, mod, wheres, audit, user, changed)
+		if err != nil {
+			return err
+		}
+
+		data := maps.MapStr{}
+		for key, value := range changed {
+			data[key] = value
+		}
+		mod.FliterIn(data)
+		if mod.MetaData.Option.Timestamps {
+			data["updated_at"] = dbal.Raw("CURRENT_TIMESTAMP")
+		}
+		if lock != nil {
+			data[lock.Version] = dbal.Raw(fmt.Sprintf("%s + 1", lock.Version))
+		}
+		_, err = qb.New().Table(mod.MetaData.Table.Name).Where(mod.PrimaryKey, id).Update(map[string]interface{}(data))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The row updated is read after the transaction is committed
+	updated, err := mod.Find(id, types.QueryParam{})
+	if err != nil {
+		return nil, err
+	}
+
+	if audit != nil && len(changed) > 0 {
+		audit.record(p.ID, "update", []rowChange{{id: id, old: old, new: changed}}, user)
+	}
+	return map[string]interface{}(updated), nil
+}
+
+// written the data written by ForUpdate: the columns of the equal rules of the policy are the values of the session,
+// the fields are validated by the model and the updated_by is the user of the session
+func written(p *process.Process, mod *model.Model, wheres []types.QueryWhere, audit *Audit, user interface{}, data map[string]interface{}) (res map[string]interface{}, err error) {
+	if len(wheres) > 0 {
+		if err := guarded(403, func() { data = owned(p, data, wheres) }); err != nil {
+			return nil, err
+		}
+	}
+
+	if errs := mod.Validate(maps.MapStr(data)); len(errs) > 0 {
+		return nil, lockError{code: 400, message: fmt.Sprintf("%s.%s %s", p.ID, errs[0].Column, strings.Join(errs[0].Messages, ", "))}
+	}
+
+	if audit != nil {
+		audit.stamp(data, user, false)
+	}
+	return data, nil
+}
+
+// lockError the error of ForUpdate with the status code
+type lockError struct {
+	code    int
+	message string
+}
+
+func (err lockError) Error() string {
+	return err.message
+}
+
+// guarded the error of the status code if the check throws
+func guarded(code int, check func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = lockError{code: code, message: exception.Catch(r).Error()}
+		}
+	}()
+	check()
+	return nil
+}
+
+// processForUpdate models.<model>.ForUpdate (:primary, :process, ...args), update the row locked with the data returned by the process
+func processForUpdate(p *process.Process) interface{} {
+	p.ValidateArgNums(2)
+	row, err := ForUpdate(p, p.Args[0], p.ArgsString(1), p.Args[2:]...)
+	if err != nil {
+		code := 500
+		var lockErr lockError
+		if errors.As(err, &lockErr) {
+			code = lockErr.code
+		}
+		exception.New(err.Error(), code).Throw()
+	}
+	return row
+}
@@ -23,7 +23,59 @@ func Load(cfg config.Config) error {
 		if isdir {
 			return nil
 		}
-		_, err := model.Load(file, share.ID(root, file))
+		id := share.ID(root, file)
+		is, err := loadClickHouse(file, id)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return err
+		}
+		if is {
+			return nil
+		}
+
+		mod, err := model.Load(file, id)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return err
+		}
+
+		err = loadComputed(file, id, mod)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return err
+		}
+
+		err = loadAssociations(file, id)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return err
+		}
+
+		err = loadPolicy(file, id)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return err
+		}
+
+		err = loadAudit(file, id, mod)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return err
+		}
+
+		err = loadLock(file, id, mod)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return err
+		}
+
+		err = loadCascade(file, id, mod)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return err
+		}
+
+		err = loadVectors(file, id, mod)
 		if err != nil {
 			messages = append(messages, err.Error())
 		}
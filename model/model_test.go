@@ -6,7 +6,12 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/maps"
 	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
 	"github.com/yaoapp/yao/test"
 )
 
@@ -32,3 +37,345 @@ func check(t *testing.T) {
 	assert.True(t, ids["user.pet"])
 	assert.True(t, ids["tests.user"])
 }
+
+func TestComputed(t *testing.T) {
+	m := &computedModel{id: "pet", table: "pet", primary: "id", columns: map[string]bool{"id": true, "name": true}, fields: map[string]*Computed{
+		"days":  {Name: "days", Expression: "DATEDIFF(NOW(), created_at)"},
+		"score": {Name: "score", Process: "scripts.pet.Score"},
+	}}
+
+	q, err := m.prepare(types.QueryParam{Select: []interface{}{"name", "days", "score"}, Orders: []types.QueryOrder{{Column: "name"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"name", "id"}, q.param.Select)
+	assert.Len(t, q.fields, 2)
+	assert.False(t, q.raw)
+
+	// The rows are sorted by the expressions of the computed fields
+	q, err = m.prepare(types.QueryParam{Orders: []types.QueryOrder{{Column: "days", Option: "desc"}, {Column: "id"}}})
+	assert.NoError(t, err)
+	assert.True(t, q.raw)
+	assert.Nil(t, q.param.Orders)
+	assert.Equal(t, "days", q.fields[0].Name)
+
+	// The rows are filtered by the expressions, the wheres are kept for the SQL
+	wheres := []types.QueryWhere{{Column: "name", Value: "cat"}, {Wheres: []types.QueryWhere{{Column: "days", OP: "gt", Value: 3}}}}
+	q, err = m.prepare(types.QueryParam{Wheres: wheres})
+	assert.NoError(t, err)
+	assert.True(t, q.raw)
+	assert.Equal(t, wheres, q.param.Wheres)
+
+	_, err = m.prepare(types.QueryParam{Wheres: []types.QueryWhere{{Column: "days", Value: 1}, {Rel: "owner", Column: "name", Value: "max"}}})
+	assert.Error(t, err)
+
+	_, err = m.prepare(types.QueryParam{Orders: []types.QueryOrder{{Column: "score"}}})
+	assert.Error(t, err)
+	_, err = m.prepare(types.QueryParam{Wheres: []types.QueryWhere{{Column: "score", Value: 1}}})
+	assert.Error(t, err)
+
+	stmt, bindings, err := m.fields["days"].condition("ge", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "(DATEDIFF(NOW(), created_at)) >= ?", stmt)
+	assert.Equal(t, []interface{}{3}, bindings)
+
+	stmt, bindings, err = m.fields["days"].condition("in", "1,2")
+	assert.NoError(t, err)
+	assert.Equal(t, "(DATEDIFF(NOW(), created_at)) IN (?,?)", stmt)
+	assert.Equal(t, []interface{}{"1", "2"}, bindings)
+
+	stmt, _, err = m.fields["days"].condition("notnull", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "(DATEDIFF(NOW(), created_at)) IS NOT NULL", stmt)
+
+	_, _, err = m.fields["days"].condition("between", nil)
+	assert.Error(t, err)
+
+	rows := rowsOf(maps.MapStrAny{"data": []maps.MapStrAny{{"id": 1}, {"id": 2}}, "total": 2})
+	assert.Len(t, rows, 2)
+	rows[0]["days"] = 3
+	assert.Len(t, rowsOf([]interface{}{map[string]interface{}{"id": 1}}), 1)
+}
+
+func TestAssociation(t *testing.T) {
+	tags := &Association{Name: "tags", Type: BelongsToMany, Model: "tag", owner: "pet"}
+	assert.NoError(t, tags.validate())
+	assert.Equal(t, "pet_tag", tags.Pivot)
+	assert.Equal(t, "pet_id", tags.Foreign)
+	assert.Equal(t, "tag_id", tags.Key)
+
+	pets := &Association{Name: "pets", Type: BelongsToMany, Model: "pet", owner: "tag"}
+	assert.NoError(t, pets.validate())
+	assert.Equal(t, "pet_tag", pets.Pivot)
+
+	assert.Error(t, (&Association{Type: MorphMany, Model: "comment"}).validate())
+	assert.Error(t, (&Association{Type: MorphTo, Morph: "commentable"}).validate())
+	assert.Error(t, (&Association{Type: "hasManyThrough"}).validate())
+	assert.NoError(t, (&Association{Type: MorphTo, Morph: "commentable", Models: []string{"pet"}}).validate())
+
+	assert.Equal(t, []interface{}{"1", "2"}, keysOf("1, 2,"))
+	assert.Equal(t, []interface{}{1}, keysOf(1))
+	assert.Nil(t, keysOf(nil))
+	assert.True(t, selected([]interface{}{"id", "name"}, "id"))
+}
+
+func TestPolicy(t *testing.T) {
+	policy := &Policy{Rules: []string{"owner_id = $user.id", "team_id IN $user.teams", "status in (enabled, checked)", "level >= 2"}, Bypass: "$user.is_admin"}
+	assert.NoError(t, policy.parse())
+
+	wheres, err := policy.wheres(maps.Of(map[string]interface{}{"user": map[string]interface{}{"id": 1, "teams": []interface{}{2, 3}}}).Dot())
+	assert.NoError(t, err)
+	assert.Equal(t, []types.QueryWhere{
+		{Column: "owner_id", OP: "eq", Value: 1},
+		{Column: "team_id", OP: "in", Value: []interface{}{2, 3}},
+		{Column: "status", OP: "in", Value: []interface{}{"enabled", "checked"}},
+		{Column: "level", OP: "ge", Value: float64(2)},
+	}, wheres)
+
+	// The rows are not matched without the values of the session
+	_, err = policy.wheres(maps.Of(map[string]interface{}{"user": map[string]interface{}{"teams": []interface{}{2}}}).Dot())
+	assert.Error(t, err)
+
+	wheres, err = policy.wheres(maps.Of(map[string]interface{}{"user": map[string]interface{}{"is_admin": true}}).Dot())
+	assert.NoError(t, err)
+	assert.Nil(t, wheres)
+
+	// The guests are denied without the guest rules
+	_, err = policy.guestWheres()
+	assert.Error(t, err)
+	policy.Guest = []string{"status = published"}
+	assert.NoError(t, policy.parse())
+	wheres, err = policy.guestWheres()
+	assert.NoError(t, err)
+	assert.Equal(t, []types.QueryWhere{{Column: "status", OP: "eq", Value: "published"}}, wheres)
+	assert.Error(t, (&Policy{Rules: []string{"owner_id = $user.id"}, Guest: []string{"owner_id = $user.id"}}).parse())
+
+	assert.Error(t, (&Policy{Rules: []string{"owner_id $user.id"}}).parse())
+	assert.Error(t, (&Policy{Rules: []string{"owner_id = $user.id"}, Bypass: "is_admin"}).parse())
+}
+
+func TestPolicyBypass(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	Load(config.Conf)
+
+	policy := &Policy{Guest: []string{"status = checked"}}
+	assert.NoError(t, policy.parse())
+	policyLock.Lock()
+	policies["pet"] = policy
+	policyLock.Unlock()
+	defer func() {
+		policyLock.Lock()
+		delete(policies, "pet")
+		policyLock.Unlock()
+	}()
+	assert.True(t, HasPolicy("pet"))
+
+	var args []interface{}
+	run := func(method string, values ...interface{}) {
+		policing(method, func(p *process.Process) interface{} { args = p.Args; return nil })(&process.Process{ID: "pet", Sid: share.GuestSID(), Args: values})
+	}
+
+	// The rows inserted are owned
+	run("insert", []interface{}{"name"}, []interface{}{[]interface{}{"Cookie"}})
+	assert.Equal(t, []interface{}{"name", "status"}, args[0])
+	assert.Equal(t, []interface{}{[]interface{}{"Cookie", "checked"}}, args[1])
+	assert.Panics(t, func() {
+		run("insert", []interface{}{"name", "status"}, []interface{}{[]interface{}{"Cookie", "cured"}})
+	})
+
+	// The rows created are owned, the rows updated are matched
+	run("eachsave", []interface{}{map[string]interface{}{"name": "Cookie"}}, map[string]interface{}{"kind": "cat"})
+	assert.Equal(t, "checked", args[0].([]interface{})[0].(map[string]interface{})["status"])
+	assert.Equal(t, "checked", args[1].(map[string]interface{})["status"])
+	assert.Panics(t, func() { run("eachsave", []interface{}{map[string]interface{}{"id": -1, "name": "Cookie"}}) })
+	assert.Panics(t, func() { run("eachsaveafterdelete", []interface{}{-1}, []interface{}{}) })
+
+	run("upsert", map[string]interface{}{"name": "__unit_test_policy"}, "name", []interface{}{"name"})
+	assert.Equal(t, "checked", args[0].(map[string]interface{})["status"])
+	assert.Panics(t, func() { run("upsert", map[string]interface{}{"name": "__unit_test_policy", "status": "cured"}, "name") })
+
+	// The rows updated are not moved out of the policy
+	for _, method := range []string{"update", "updatewhere"} {
+		err := func() (err error) {
+			defer func() { err = exception.Catch(recover()) }()
+			run(method, -1, map[string]interface{}{"status": "cured"})
+			return nil
+		}()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "pet.status no permission")
+	}
+	assert.Panics(t, func() { run("save", map[string]interface{}{"id": -1, "status": "cured"}) })
+
+	// The options are scoped, the related rows of the rows not matched are not changed
+	assert.NotPanics(t, func() { run("selectoption", "Cookie") })
+	assert.Panics(t, func() { run("attach", -1, "tags", []interface{}{1}) })
+	assert.Panics(t, func() { run("sync", -1, "tags", []interface{}{1}) })
+
+	// The history of the rows not matched is not read
+	auditLock.Lock()
+	audits["pet"] = &Audit{}
+	auditLock.Unlock()
+	defer func() {
+		auditLock.Lock()
+		delete(audits, "pet")
+		auditLock.Unlock()
+	}()
+	assert.Panics(t, func() { processHistory(&process.Process{ID: "pet", Sid: share.GuestSID(), Args: []interface{}{-1}}) })
+
+	// The rows locked are written with the checks of the update
+	pet := model.Select("pet")
+	audit := &Audit{Columns: true, UpdatedBy: "updated_by"}
+	wheres := []types.QueryWhere{{Column: "status", OP: "eq", Value: "checked"}}
+	guest := &process.Process{ID: "pet", Sid: share.GuestSID()}
+	data, err := written(guest, pet, wheres, audit, 1, map[string]interface{}{"name": "Cookie"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "Cookie", "status": "checked", "updated_by": 1}, data)
+	_, err = written(guest, pet, wheres, audit, 1, map[string]interface{}{"status": "cured"})
+	assert.Error(t, err)
+	assert.Equal(t, 403, err.(lockError).code)
+
+	// The internal processes are trusted
+	policing("selectoption", func(p *process.Process) interface{} { args = p.Args; return nil })(&process.Process{ID: "pet", Args: []interface{}{"Cookie"}})
+	assert.Equal(t, []interface{}{"Cookie"}, args)
+}
+
+func TestAudit(t *testing.T) {
+	audit := &Audit{Columns: true, CreatedBy: "created_by", UpdatedBy: "updated_by", History: true, fields: map[string]bool{}}
+
+	row := map[string]interface{}{"name": "Cookie"}
+	audit.stamp(row, 1, true)
+	assert.Equal(t, map[string]interface{}{"name": "Cookie", "created_by": 1, "updated_by": 1}, row)
+
+	// The rows are not stamped without the session
+	row = map[string]interface{}{"name": "Cookie"}
+	audit.stamp(row, nil, false)
+	assert.Equal(t, map[string]interface{}{"name": "Cookie"}, row)
+
+	change := rowChange{
+		id:  1,
+		old: map[string]interface{}{"id": 1, "name": "Cookie", "cost": 105, "extra": map[string]interface{}{"a": 1}},
+		new: map[string]interface{}{"name": "Cookie", "cost": 106, "extra": map[string]interface{}{"a": 1}, "updated_by": 2},
+	}
+	assert.Equal(t, []string{"cost"}, audit.diff("update", change))
+	assert.ElementsMatch(t, []string{"id", "name", "cost", "extra"}, audit.diff("delete", change))
+
+	audit.fields = map[string]bool{"name": true}
+	assert.Empty(t, audit.diff("update", change))
+	assert.Equal(t, []string{"name"}, audit.diff("create", rowChange{new: change.new}))
+
+	assert.Equal(t, `{"a":1}`, historyValue(map[string]interface{}{"a": 1}))
+	assert.Nil(t, historyValue(nil))
+	assert.Len(t, rowsOf([]maps.MapStr{{"id": 1}, {"id": 2}}), 2)
+}
+
+func TestLock(t *testing.T) {
+	lock := &Lock{Version: "version"}
+	param, data := lock.claim("id", 1, map[string]interface{}{"id": 1, "name": "Cookie", "version": 3})
+	assert.Equal(t, []types.QueryWhere{{Column: "id", Value: 1}, {Column: "version", Value: 3}}, param.Wheres)
+	assert.Equal(t, map[string]interface{}{"name": "Cookie", "version": 4}, data)
+
+	// The version of the form is a string
+	param, data = lock.claim("id", 1, map[string]interface{}{"version": "3"})
+	assert.Equal(t, 3, param.Wheres[1].Value)
+	assert.Equal(t, 4, data["version"])
+}
+
+func TestCascade(t *testing.T) {
+	rule := cascadeRule{relation: "pets", action: CascadeDelete, model: "pet", key: "user_id", foreign: "id"}
+	rows := []map[string]interface{}{{"id": 1}, {"id": nil}, {"id": 3}}
+	assert.Equal(t, []interface{}{1, 3}, rule.keys(rows))
+	assert.Empty(t, rule.keys([]map[string]interface{}{}))
+	assert.Equal(t, ">=", whereOps["ge"])
+}
+
+func TestClickHouseQuery(t *testing.T) {
+	query, err := clickhouseQuery(types.QueryParam{
+		Select: []interface{}{"team", "tokens"},
+		Wheres: []types.QueryWhere{
+			{Column: "team", Value: 1},
+			{Wheres: []types.QueryWhere{{Column: "name", OP: "match", Value: "chat"}, {Column: "name", Value: "embed", Method: "orwhere"}}},
+		},
+		Orders: []types.QueryOrder{{Column: "created_at", Option: "desc"}},
+		Limit:  10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"team", "tokens"}, query.Select)
+	assert.Equal(t, "team", query.Wheres[0].Column)
+	assert.Len(t, query.Wheres[1].Wheres, 2)
+	assert.True(t, query.Wheres[1].Wheres[1].Or)
+	assert.True(t, query.Orders[0].Desc)
+	assert.Equal(t, 10, query.Limit)
+
+	_, err = clickhouseQuery(types.QueryParam{Withs: map[string]types.With{"team": {}}})
+	assert.Error(t, err)
+}
+
+func TestPostgresCondition(t *testing.T) {
+	stmt, bindings, err := postgresCondition("fts", "content", "text", map[string]interface{}{"query": "yao -java", "config": "english"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `to_tsvector(?::regconfig, "content"::text) @@ websearch_to_tsquery(?::regconfig, ?)`, stmt)
+	assert.Equal(t, []interface{}{"english", "english", "yao -java"}, bindings)
+
+	stmt, bindings, _ = postgresCondition("contains", "extra", "json", map[string]interface{}{"tags": []interface{}{"yao"}})
+	assert.Equal(t, `"extra"::jsonb @> ?::jsonb`, stmt)
+	assert.Equal(t, []interface{}{`{"tags":["yao"]}`}, bindings)
+
+	stmt, bindings, _ = postgresCondition("overlaps", "tags", "json", "yao, gou")
+	assert.Equal(t, `jsonb_exists_any("tags"::jsonb, ARRAY[?, ?]::text[])`, stmt)
+	assert.Equal(t, []interface{}{"yao", "gou"}, bindings)
+
+	stmt, _, _ = postgresCondition("overlaps", "tags", "string", []interface{}{})
+	assert.Equal(t, "1 = 0", stmt)
+
+	_, _, err = postgresCondition("fts", "content", "text", "")
+	assert.Error(t, err)
+
+	assert.True(t, hasPostgresOps([]types.QueryWhere{{Column: "id"}, {Wheres: []types.QueryWhere{{Column: "extra", OP: "HasKey"}}}}))
+	assert.False(t, hasPostgresOps([]types.QueryWhere{{Column: "id", OP: "in"}}))
+}
+
+func TestVector(t *testing.T) {
+	vector := &Vector{Name: "embedding", Dimension: 3, Metric: "cosine", Index: "hnsw"}
+	m := &vectorModel{id: "doc", table: "docs", primary: "id", driver: "postgres", columns: map[string]*Vector{"embedding": vector}}
+	assert.Equal(t, []string{
+		"CREATE EXTENSION IF NOT EXISTS vector",
+		`ALTER TABLE "docs" ADD COLUMN IF NOT EXISTS "embedding" vector(3)`,
+		`CREATE INDEX IF NOT EXISTS "docs_embedding_vector" ON "docs" USING hnsw ("embedding" vector_cosine_ops)`,
+	}, m.migrateSQL(false))
+
+	literal, err := vector.literal([]interface{}{0.1, 2, float32(0.5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "[0.1,2,0.5]", literal)
+
+	_, err = vector.literal([]interface{}{0.1, 0.2})
+	assert.Error(t, err)
+	_, err = vector.literal([]interface{}{0.1, "x", 0.2})
+	assert.Error(t, err)
+
+	stmts, bindings, err := m.writeSQL(1, map[string]interface{}{"embedding": []float64{1, 2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{`UPDATE "docs" SET "embedding" = ?::vector WHERE "id" = ?`}, stmts)
+	assert.Equal(t, [][]interface{}{{"[1,2,3]", 1}}, bindings)
+
+	stmt, args := m.nearestSQL(vector, "[1,2,3]", 10, 0.3)
+	assert.Equal(t, `SELECT "id", "embedding" <=> ?::vector AS distance FROM "docs" WHERE "embedding" IS NOT NULL AND "embedding" <=> ?::vector <= ? ORDER BY "embedding" <=> ?::vector LIMIT 10`, stmt)
+	assert.Equal(t, []interface{}{"[1,2,3]", "[1,2,3]", 0.3, "[1,2,3]"}, args)
+
+	m.driver = "sqlite3"
+	assert.Equal(t, []string{`DROP TABLE IF EXISTS "vss_docs"`, `CREATE VIRTUAL TABLE IF NOT EXISTS "vss_docs" USING vss0(embedding(3))`}, m.migrateSQL(true))
+
+	stmts, _, _ = m.writeSQL(1, map[string]interface{}{"embedding": nil})
+	assert.Equal(t, []string{`DELETE FROM "vss_docs" WHERE rowid = ?`}, stmts)
+
+	stmt, args = m.nearestSQL(vector, "[1,2,3]", 10, 0)
+	assert.Equal(t, `SELECT rowid, distance FROM "vss_docs" WHERE vss_search(embedding, vss_search_params(?, ?))`, stmt)
+	assert.Equal(t, []interface{}{"[1,2,3]", 10}, args)
+}
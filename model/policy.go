@@ -0,0 +1,490 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/yao/share"
+)
+
+// Policy the row-level policy of the model, the rows of the processes of the sessions are the rows matched by the rules.
+// The values of the rules are the values of the session, e.g. $user.id is the id of the user signed in.
+// The anonymous requests are denied unless the guest rules are set.
+//
+//	"policy": {
+//	  "rules": ["owner_id = $user.id", "team_id in $user.teams"],
+//	  "guest": ["status = published"],
+//	  "bypass": "$user.is_admin"
+//	}
+type Policy struct {
+	Rules  []string `json:"rules,omitempty"`
+	Guest  []string `json:"guest,omitempty"`  // The rules of the anonymous requests, the values are the literals
+	Bypass string   `json:"bypass,omitempty"` // The rules are not applied if the value of the session is true
+	rules  []policyRule
+	guest  []policyRule
+}
+
+type policyRule struct {
+	column   string
+	op       string
+	value    interface{} // The value or the path of the session
+	variable bool
+}
+
+var policyRe = regexp.MustCompile(`(?i)^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=|!=|<>|>=|<=|>|<|\bin\b)\s*(.+?)\s*$`)
+
+var policyOps = map[string]string{"=": "eq", "!=": "ne", "<>": "ne", ">": "gt", ">=": "ge", "<": "lt", "<=": "le", "in": "in"}
+
+// policies the policies of the models
+var policies = map[string]*Policy{}
+var policyLock sync.RWMutex
+
+func init() {
+	for _, method := range []string{
+		"find", "get", "paginate", "updatewhere", "deletewhere", "destroywhere",
+		"update", "delete", "destroy", "save", "create", "insert", "upsert", "eachsave", "eachsaveafterdelete",
+		"selectoption", "attach", "detach", "sync",
+	} {
+		name := "models." + method
+		if handler, has := process.Handlers[name]; has {
+			process.Handlers[name] = policing(method, handler)
+		}
+	}
+}
+
+// loadPolicy load the policy of the model source
+func loadPolicy(file string, id string) error {
+	source, err := application.App.Read(file)
+	if err != nil {
+		return err
+	}
+
+	dsl := struct {
+		Policy *Policy `json:"policy,omitempty"`
+	}{}
+	err = application.Parse(file, source, &dsl)
+	if err != nil {
+		return err
+	}
+
+	policyLock.Lock()
+	defer policyLock.Unlock()
+	delete(policies, id)
+	if dsl.Policy == nil || (len(dsl.Policy.Rules) == 0 && len(dsl.Policy.Guest) == 0) {
+		return nil
+	}
+
+	err = dsl.Policy.parse()
+	if err != nil {
+		return fmt.Errorf("[%s] policy %s", id, err.Error())
+	}
+
+	policies[id] = dsl.Policy
+	return nil
+}
+
+func policyOf(id string) *Policy {
+	policyLock.RLock()
+	defer policyLock.RUnlock()
+	return policies[id]
+}
+
+// HasPolicy the model has the row-level policy
+func HasPolicy(id string) bool {
+	return policyOf(id) != nil
+}
+
+// PolicyWheres the wheres of the policy of the model for the session, nil without the policy, the session or if bypassed
+func PolicyWheres(id string, sid string) []types.QueryWhere {
+	return policyWheres(&process.Process{ID: id, Sid: sid})
+}
+
+// parse the rules, <column> <op> <value>, the value is a literal or the path of the session starts with $
+func (policy *Policy) parse() error {
+	rules, err := parseRules(policy.Rules)
+	if err != nil {
+		return err
+	}
+	policy.rules = rules
+
+	guest, err := parseRules(policy.Guest)
+	if err != nil {
+		return err
+	}
+	for _, rule := range guest {
+		if rule.variable {
+			return fmt.Errorf("the guest rule of %s is not a literal, the guests have no session", rule.column)
+		}
+	}
+	policy.guest = guest
+
+	if policy.Bypass != "" && !strings.HasPrefix(policy.Bypass, "$") {
+		return fmt.Errorf("the bypass %s is not a value of the session, e.g. $user.is_admin", policy.Bypass)
+	}
+	return nil
+}
+
+func parseRules(values []string) ([]policyRule, error) {
+	rules := []policyRule{}
+	for _, rule := range values {
+		matches := policyRe.FindStringSubmatch(rule)
+		if matches == nil {
+			return nil, fmt.Errorf("the rule %s is invalid, <column> <op> <value>", rule)
+		}
+
+		r := policyRule{column: matches[1], op: policyOps[strings.ToLower(matches[2])]}
+		value := matches[3]
+		switch {
+		case strings.HasPrefix(value, "$"):
+			r.value, r.variable = strings.TrimPrefix(value, "$"), true
+		case len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0]:
+			r.value = value[1 : len(value)-1]
+		default:
+			if number, err := strconv.ParseFloat(value, 64); err == nil {
+				r.value = number
+			} else {
+				r.value = value
+			}
+		}
+
+		if r.op == "in" && !r.variable {
+			values := []interface{}{}
+			for _, v := range strings.Split(strings.Trim(fmt.Sprintf("%v", r.value), "[]()"), ",") {
+				values = append(values, strings.Trim(strings.TrimSpace(v), `"'`))
+			}
+			r.value = values
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// guestWheres the wheres of the guest rules, the guests are denied without the guest rules
+func (policy *Policy) guestWheres() ([]types.QueryWhere, error) {
+	if len(policy.guest) == 0 {
+		return nil, fmt.Errorf("no permission for the guests")
+	}
+
+	wheres := []types.QueryWhere{}
+	for _, rule := range policy.guest {
+		wheres = append(wheres, types.QueryWhere{Column: rule.column, OP: rule.op, Value: rule.value})
+	}
+	return wheres, nil
+}
+
+// wheres the wheres of the rules with the values of the session, nil if the rules are bypassed
+func (policy *Policy) wheres(data maps.MapStr) ([]types.QueryWhere, error) {
+	if policy.Bypass != "" && any.Of(data.Get(strings.TrimPrefix(policy.Bypass, "$"))).CBool() {
+		return nil, nil
+	}
+
+	wheres := []types.QueryWhere{}
+	for _, rule := range policy.rules {
+		value := rule.value
+		if rule.variable {
+			if !data.Has(rule.value.(string)) || data.Get(rule.value.(string)) == nil {
+				return nil, fmt.Errorf("the session has no %s", rule.value)
+			}
+			value = data.Get(rule.value.(string))
+		}
+		wheres = append(wheres, types.QueryWhere{Column: rule.column, OP: rule.op, Value: value})
+	}
+	return wheres, nil
+}
+
+// policing apply the policy of the model to the processes of the sessions, the internal processes without the session are trusted
+func policing(method string, handler process.Handler) process.Handler {
+	return func(p *process.Process) interface{} {
+		wheres := policyWheres(p)
+		if len(wheres) == 0 {
+			return handler(p)
+		}
+
+		switch method {
+		case "find":
+			p.ValidateArgNums(1)
+			scoped(p, 1, wheres)
+
+		case "get", "paginate", "deletewhere", "destroywhere":
+			scoped(p, 0, wheres)
+
+		case "updatewhere":
+			p.ValidateArgNums(2)
+			p.Args[1] = owned(p, map[string]interface{}(any.Of(p.Args[1]).MapStr()), wheres)
+			scoped(p, 0, wheres)
+
+		case "update":
+			p.ValidateArgNums(2)
+			p.Args[1] = owned(p, map[string]interface{}(any.Of(p.Args[1]).MapStr()), wheres)
+			allowed(p, p.Args[0], wheres)
+
+		case "delete", "destroy", "attach", "detach", "sync":
+			p.ValidateArgNums(1)
+			allowed(p, p.Args[0], wheres)
+
+		case "save", "create":
+			p.ValidateArgNums(1)
+			p.Args[0] = saved(p, p.Args[0], method == "save", wheres)
+
+		case "insert":
+			p.ValidateArgNums(2)
+			p.Args[0], p.Args[1] = inserted(p, p.Args[0], p.Args[1], wheres)
+
+		case "upsert":
+			p.ValidateArgNums(2)
+			row := map[string]interface{}(any.Of(p.Args[0]).MapStr())
+			if id := existing(p, row, p.Args[1]); id != nil {
+				allowed(p, id, wheres)
+			}
+			p.Args[0] = owned(p, row, wheres)
+
+		case "eachsave", "eachsaveafterdelete":
+			i := 0
+			if method == "eachsaveafterdelete" {
+				p.ValidateArgNums(2)
+				for _, id := range keysOf(p.Args[0]) {
+					allowed(p, id, wheres)
+				}
+				i = 1
+			}
+
+			p.ValidateArgNums(i + 1)
+			rows := []interface{}{}
+			for _, row := range any.Of(p.Args[i]).CArray() {
+				rows = append(rows, saved(p, row, true, wheres))
+			}
+			p.Args[i] = rows
+
+			// The values of each row are merged into the rows
+			if p.NumOfArgs() > i+1 && p.Args[i+1] != nil {
+				p.Args[i+1] = owned(p, map[string]interface{}(any.Of(p.Args[i+1]).MapStr()), wheres)
+			}
+
+		case "selectoption":
+			// The options are selected without the query param, the query of the options is scoped here
+			return selectOption(p, wheres)
+
+		default:
+			exception.New("%s.%s is not allowed by the policy", 403, p.ID, method).Throw()
+		}
+
+		return handler(p)
+	}
+}
+
+// saved the row of the primary key is matched by the policy if updated, the row saved is owned
+func saved(p *process.Process, value interface{}, update bool, wheres []types.QueryWhere) map[string]interface{} {
+	row := owned(p, map[string]interface{}(any.Of(value).MapStr()), wheres)
+	mod := model.Select(p.ID)
+	if id, has := row[mod.PrimaryKey]; has && id != nil && update {
+		allowed(p, id, wheres)
+	}
+	return row
+}
+
+// selectOption the options of the rows matched by the policy, as the selectoption process does.
+// models.<model>.SelectOption (:keyword, [:name], [:value], [:limit])
+func selectOption(p *process.Process, wheres []types.QueryWhere) interface{} {
+	p.ValidateArgNums(1)
+	mod := model.Select(p.ID)
+	name := p.ArgsString(1, "name")
+	value := p.ArgsString(2, "id")
+	param := types.QueryParam{
+		Select: []interface{}{name, value},
+		Wheres: wheres,
+		Limit:  p.ArgsInt(3, 20),
+	}
+
+	if keyword := p.ArgsString(0); keyword != "" {
+		param.Wheres = append(param.Wheres, types.QueryWhere{Column: name, OP: "match", Value: keyword})
+	}
+
+	rows, err := mod.Get(param)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return rows
+}
+
+// inserted the columns of the equal rules of the rows inserted are the values of the session
+func inserted(p *process.Process, columnsValue interface{}, rowsValue interface{}, wheres []types.QueryWhere) ([]interface{}, []interface{}) {
+	columns := any.Of(columnsValue).CArray()
+	rows := [][]interface{}{}
+	for _, row := range any.Of(rowsValue).CArray() {
+		rows = append(rows, any.Of(row).CArray())
+	}
+
+	for _, where := range wheres {
+		if where.OP != "eq" {
+			continue
+		}
+
+		column := fmt.Sprintf("%v", where.Column)
+		index := -1
+		for i, name := range columns {
+			if fmt.Sprintf("%v", name) == column {
+				index = i
+			}
+		}
+
+		if index < 0 {
+			columns = append(columns, column)
+			for i := range rows {
+				rows[i] = append(rows[i], where.Value)
+			}
+			continue
+		}
+
+		for _, row := range rows {
+			if index >= len(row) {
+				exception.New("%s the row has no %s", 400, p.ID, column).Throw()
+			}
+			if row[index] != nil && fmt.Sprintf("%v", row[index]) != fmt.Sprintf("%v", where.Value) {
+				exception.New("%s.%s no permission", 403, p.ID, column).Throw()
+			}
+			row[index] = where.Value
+		}
+	}
+
+	res := []interface{}{}
+	for _, row := range rows {
+		res = append(res, row)
+	}
+	return columns, res
+}
+
+// existing the primary key of the row of the unique columns, nil if the row does not exist
+func existing(p *process.Process, row map[string]interface{}, uniqueBy interface{}) interface{} {
+	mod := model.Select(p.ID)
+	columns := []interface{}{uniqueBy}
+	if _, ok := uniqueBy.(string); !ok {
+		columns = any.Of(uniqueBy).CArray()
+	}
+
+	param := types.QueryParam{Select: []interface{}{mod.PrimaryKey}, Limit: 1}
+	for _, column := range columns {
+		value, has := row[fmt.Sprintf("%v", column)]
+		if !has {
+			return nil
+		}
+		param.Wheres = append(param.Wheres, types.QueryWhere{Column: fmt.Sprintf("%v", column), Value: value})
+	}
+
+	rows, err := mod.Get(param)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	return rows[0][mod.PrimaryKey]
+}
+
+// policyWheres the wheres of the policy of the session of the process, nil without the policy or if bypassed.
+// The processes without the session are the internal calls, every HTTP request has a session, see share.GuestSID
+func policyWheres(p *process.Process) []types.QueryWhere {
+	policy := policyOf(p.ID)
+	if policy == nil || p.Sid == "" {
+		return nil
+	}
+
+	if share.IsGuest(p.Sid) {
+		wheres, err := policy.guestWheres()
+		if err != nil {
+			exception.New("%s %s", 403, p.ID, err.Error()).Throw()
+		}
+		return wheres
+	}
+
+	data, err := session.Global().ID(p.Sid).Dump()
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	wheres, err := policy.wheres(maps.Of(data).Dot())
+	if err != nil {
+		exception.New("%s %s", 403, p.ID, err.Error()).Throw()
+	}
+	return wheres
+}
+
+// scoped the rows of the query param are the rows matched by the policy, the wheres of the query param are grouped
+func scoped(p *process.Process, i int, wheres []types.QueryWhere) {
+	for p.NumOfArgs() <= i {
+		p.Args = append(p.Args, nil)
+	}
+
+	param := p.ArgsQueryParams(i, types.QueryParam{})
+	if len(param.Wheres) > 0 {
+		wheres = append(wheres, types.QueryWhere{Wheres: param.Wheres})
+	}
+	param.Wheres = wheres
+	p.Args[i] = param
+}
+
+// allowed the row of the primary key is matched by the policy
+func allowed(p *process.Process, id interface{}, wheres []types.QueryWhere) {
+	mod := model.Select(p.ID)
+	param := types.QueryParam{
+		Select: []interface{}{mod.PrimaryKey},
+		Wheres: append([]types.QueryWhere{{Column: mod.PrimaryKey, Value: id}}, wheres...),
+		Limit:  1,
+	}
+
+	rows, err := mod.Get(param)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	if len(rows) == 0 {
+		exception.New("%s %v no permission", 403, p.ID, id).Throw()
+	}
+}
+
+// owned the columns of the equal rules of the row written are the values of the session,
+// the values of the columns of the in rules are the values of the session
+func owned(p *process.Process, row map[string]interface{}, wheres []types.QueryWhere) map[string]interface{} {
+	for _, where := range wheres {
+		if where.OP == "in" {
+			column := fmt.Sprintf("%v", where.Column)
+			if value, has := row[column]; has && value != nil && !contains(where.Value, value) {
+				exception.New("%s.%s no permission", 403, p.ID, column).Throw()
+			}
+			continue
+		}
+
+		if where.OP != "eq" {
+			continue
+		}
+
+		column := fmt.Sprintf("%v", where.Column)
+		value, has := row[column]
+		if has && value != nil && fmt.Sprintf("%v", value) != fmt.Sprintf("%v", where.Value) {
+			exception.New("%s.%s no permission", 403, p.ID, column).Throw()
+		}
+		row[column] = where.Value
+	}
+	return row
+}
+
+// contains the values of the in rule contain the value
+func contains(values interface{}, value interface{}) bool {
+	for _, v := range any.Of(values).CArray() {
+		if fmt.Sprintf("%v", v) == fmt.Sprintf("%v", value) {
+			return true
+		}
+	}
+	return false
+}
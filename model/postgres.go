@@ -0,0 +1,177 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/config"
+)
+
+// The PostgreSQL operators of the query wheres, the wheres are replaced by the primary keys of the rows matched
+//
+//	{ "column": "content", "op": "fts", "value": "yao -java" }                                   to_tsvector('simple', content) @@ websearch_to_tsquery('simple', ?)
+//	{ "column": "content", "op": "fts", "value": { "query": "yao", "config": "english" } }      the text search config of the query
+//	{ "column": "extra", "op": "contains", "value": { "tags": ["yao"] } }                         extra @> ?::jsonb
+//	{ "column": "extra", "op": "contained", "value": { "a": 1, "b": 2 } }                         extra <@ ?::jsonb
+//	{ "column": "extra", "op": "haskey", "value": "tags" }                                        jsonb_exists(extra, ?)
+//	{ "column": "tags", "op": "overlaps", "value": ["yao", "gou"] }                               tags::text[] && ARRAY[?, ?]::text[], jsonb_exists_any of the json columns
+var postgresOps = map[string]bool{"fts": true, "contains": true, "contained": true, "haskey": true, "overlaps": true}
+
+func init() {
+	for _, method := range []string{"find", "get", "paginate", "updatewhere", "deletewhere", "destroywhere"} {
+		name := "models." + method
+		if handler, has := process.Handlers[name]; has {
+			process.Handlers[name] = postgresing(method, handler)
+		}
+	}
+}
+
+// postgresing replace the wheres of the PostgreSQL operators of the query param
+func postgresing(method string, handler process.Handler) process.Handler {
+	return func(p *process.Process) interface{} {
+		i := 0
+		if method == "find" {
+			i = 1
+		}
+
+		if p.NumOfArgs() <= i || p.Args[i] == nil {
+			return handler(p)
+		}
+
+		param := p.ArgsQueryParams(i, types.QueryParam{})
+		if !hasPostgresOps(param.Wheres) {
+			return handler(p)
+		}
+
+		mod := model.Select(p.ID)
+		wheres, err := postgresWheres(mod, param.Wheres)
+		if err != nil {
+			exception.New("%s %s", 400, p.ID, err.Error()).Throw()
+		}
+
+		param.Wheres = wheres
+		p.Args[i] = param
+		return handler(p)
+	}
+}
+
+func hasPostgresOps(wheres []types.QueryWhere) bool {
+	for _, where := range wheres {
+		if postgresOps[strings.ToLower(where.OP)] || hasPostgresOps(where.Wheres) {
+			return true
+		}
+	}
+	return false
+}
+
+// postgresWheres the wheres of the PostgreSQL operators are replaced by the primary keys of the rows matched
+func postgresWheres(mod *model.Model, wheres []types.QueryWhere) ([]types.QueryWhere, error) {
+	res := []types.QueryWhere{}
+	for _, where := range wheres {
+		if len(where.Wheres) > 0 {
+			nested, err := postgresWheres(mod, where.Wheres)
+			if err != nil {
+				return nil, err
+			}
+			where.Wheres = nested
+		}
+
+		op := strings.ToLower(where.OP)
+		if !postgresOps[op] {
+			res = append(res, where)
+			continue
+		}
+
+		if config.Conf.DB.Driver != "postgres" || (mod.MetaData.Connector != "" && mod.MetaData.Connector != "default") {
+			return nil, fmt.Errorf("the operator %s is only supported by the PostgreSQL default database", where.OP)
+		}
+
+		if where.Rel != "" {
+			return nil, fmt.Errorf("the operator %s of the relation %s is not supported", where.OP, where.Rel)
+		}
+
+		column := fmt.Sprintf("%v", where.Column)
+		col, has := mod.Columns[column]
+		if !has {
+			return nil, fmt.Errorf("the column %s does not exist", column)
+		}
+
+		stmt, bindings, err := postgresCondition(op, column, strings.ToLower(col.Type), where.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		qb := capsule.Query()
+		qb.Table(mod.MetaData.Table.Name)
+		rows, err := qb.Select(mod.PrimaryKey).WhereRaw(stmt, bindings...).Get()
+		if err != nil {
+			return nil, err
+		}
+
+		// IN (NULL) matches nothing
+		ids := []interface{}{nil}
+		for _, row := range rows {
+			ids = append(ids, row[mod.PrimaryKey])
+		}
+
+		where.Column, where.OP, where.Value = mod.PrimaryKey, "in", ids
+		res = append(res, where)
+	}
+	return res, nil
+}
+
+// postgresCondition the SQL condition of the operator, the column is a column of the model
+func postgresCondition(op string, column string, typ string, value interface{}) (string, []interface{}, error) {
+	name := fmt.Sprintf(`"%s"`, column)
+	switch op {
+	case "fts":
+		query, cfg := fmt.Sprintf("%v", value), "simple"
+		if values, ok := value.(map[string]interface{}); ok {
+			query = fmt.Sprintf("%v", values["query"])
+			if c, has := values["config"]; has {
+				cfg = fmt.Sprintf("%v", c)
+			}
+		}
+		if value == nil || strings.TrimSpace(query) == "" {
+			return "", nil, fmt.Errorf("the query of the fts %s is required", column)
+		}
+		return fmt.Sprintf("to_tsvector(?::regconfig, %s::text) @@ websearch_to_tsquery(?::regconfig, ?)", name), []interface{}{cfg, cfg, query}, nil
+
+	case "contains", "contained":
+		raw, err := jsoniter.MarshalToString(value)
+		if err != nil {
+			return "", nil, err
+		}
+		operator := "@>"
+		if op == "contained" {
+			operator = "<@"
+		}
+		return fmt.Sprintf("%s::jsonb %s ?::jsonb", name, operator), []interface{}{raw}, nil
+
+	case "haskey":
+		return fmt.Sprintf("jsonb_exists(%s::jsonb, ?)", name), []interface{}{fmt.Sprintf("%v", value)}, nil
+
+	case "overlaps":
+		values := []interface{}{}
+		for _, v := range keysOf(value) {
+			values = append(values, any.Of(v).CString())
+		}
+		if len(values) == 0 {
+			return "1 = 0", []interface{}{}, nil
+		}
+
+		marks := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+		if typ == "json" || typ == "jsonb" {
+			return fmt.Sprintf("jsonb_exists_any(%s::jsonb, ARRAY[%s]::text[])", name, marks), values, nil
+		}
+		return fmt.Sprintf("%s::text[] && ARRAY[%s]::text[]", name, marks), values, nil
+	}
+	return "", nil, fmt.Errorf("the operator %s is not supported", op)
+}
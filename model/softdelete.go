@@ -0,0 +1,368 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+)
+
+// The actions of the cascade
+const (
+	CascadeDelete   = "delete"   // The related rows are deleted with the row, soft deleted if the related model soft deletes
+	CascadeRestrict = "restrict" // The row is not deleted if it has the related rows
+	CascadeNullify  = "nullify"  // The keys of the related rows are set to null
+)
+
+// cascadeRule the cascade action of the hasOne or the hasMany relation
+type cascadeRule struct {
+	relation string
+	action   string
+	model    string
+	key      string // The column of the related model
+	foreign  string // The column of the model
+}
+
+// cascades the cascade rules of the models, the rules of the restrict action are first
+var cascades = map[string][]cascadeRule{}
+var cascadeLock sync.RWMutex
+
+func init() {
+	for _, method := range []string{"delete", "destroy", "deletewhere", "destroywhere"} {
+		name := "models." + method
+		if handler, has := process.Handlers[name]; has {
+			process.Handlers[name] = cascading(method, handler)
+		}
+	}
+	process.Register("models.restore", processRestore)
+	process.Register("models.restorewhere", processRestoreWhere)
+	process.Register("models.trashed", processTrashed)
+}
+
+// loadCascade load the cascade rules of the model source, the keys are the names of the relations
+//
+//	"cascade": { "pets": "delete", "orders": "restrict", "avatar": "nullify" }
+func loadCascade(file string, id string, mod *model.Model) error {
+	source, err := application.App.Read(file)
+	if err != nil {
+		return err
+	}
+
+	dsl := struct {
+		Cascade map[string]string `json:"cascade,omitempty"`
+	}{}
+	err = application.Parse(file, source, &dsl)
+	if err != nil {
+		return err
+	}
+
+	cascadeLock.Lock()
+	defer cascadeLock.Unlock()
+	delete(cascades, id)
+	if len(dsl.Cascade) == 0 {
+		return nil
+	}
+
+	rules := []cascadeRule{}
+	for name, action := range dsl.Cascade {
+		relation, has := mod.MetaData.Relations[name]
+		if !has {
+			return fmt.Errorf("[%s] cascade the relation %s does not exist", id, name)
+		}
+
+		if relation.Type != "hasOne" && relation.Type != "hasMany" {
+			return fmt.Errorf("[%s] cascade the relation %s is %s, hasOne or hasMany is supported", id, name, relation.Type)
+		}
+
+		action = strings.ToLower(action)
+		if action != CascadeDelete && action != CascadeRestrict && action != CascadeNullify {
+			return fmt.Errorf("[%s] cascade the action %s of the relation %s is invalid, delete, restrict or nullify", id, action, name)
+		}
+
+		rule := cascadeRule{relation: name, action: action, model: relation.Model, key: relation.Key, foreign: relation.Foreign}
+		if action == CascadeRestrict {
+			rules = append([]cascadeRule{rule}, rules...)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	cascades[id] = rules
+	return nil
+}
+
+func cascadesOf(id string) []cascadeRule {
+	cascadeLock.RLock()
+	defer cascadeLock.RUnlock()
+	return cascades[id]
+}
+
+// cascading apply the cascade rules to the related rows before the rows are deleted.
+// The rules of the restrict action are checked before the related rows are changed.
+func cascading(method string, handler process.Handler) process.Handler {
+	return func(p *process.Process) interface{} {
+		rules := cascadesOf(p.ID)
+		if len(rules) == 0 {
+			return handler(p)
+		}
+
+		p.ValidateArgNums(1)
+		mod := model.Select(p.ID)
+		param := types.QueryParam{Wheres: []types.QueryWhere{{Column: mod.PrimaryKey, Value: p.Args[0]}}}
+		if strings.HasSuffix(method, "where") {
+			param = p.ArgsQueryParams(0, types.QueryParam{})
+		}
+
+		columns := []interface{}{mod.PrimaryKey}
+		for _, rule := range rules {
+			if !selected(columns, rule.foreign) {
+				columns = append(columns, rule.foreign)
+			}
+		}
+		param.Select, param.Withs, param.Orders = columns, nil, nil
+
+		// The cascade rules are applied to the rows matched by the policy of the session only
+		if wheres := policyWheres(p); wheres != nil {
+			if len(param.Wheres) > 0 {
+				wheres = append(wheres, types.QueryWhere{Wheres: param.Wheres})
+			}
+			param.Wheres = wheres
+		}
+
+		res, err := mod.Get(param)
+		if err != nil {
+			exception.New(err.Error(), 500).Throw()
+		}
+
+		rows := rowsOf(res)
+		if len(rows) == 0 {
+			return handler(p)
+		}
+
+		destroy := strings.HasPrefix(method, "destroy")
+		for _, rule := range rules {
+			err := rule.apply(p, rows, destroy)
+			if err != nil {
+				exception.New(err.Error(), 500).Throw()
+			}
+		}
+		return handler(p)
+	}
+}
+
+// apply the action of the rule to the related rows of the rows
+func (rule cascadeRule) apply(p *process.Process, rows []map[string]interface{}, destroy bool) error {
+	keys := rule.keys(rows)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	param := types.QueryParam{Wheres: []types.QueryWhere{{Column: rule.key, OP: "in", Value: keys}}}
+	switch rule.action {
+	case CascadeRestrict:
+		related := model.Select(rule.model)
+		res, err := related.Get(types.QueryParam{Select: []interface{}{related.PrimaryKey}, Wheres: param.Wheres, Limit: 1})
+		if err != nil {
+			return err
+		}
+		if len(res) > 0 {
+			exception.New("%s has the related rows of %s, the rows can not be deleted", 409, p.ID, rule.relation).Throw()
+		}
+		return nil
+
+	case CascadeNullify:
+		return rule.exec(p, "updatewhere", param, map[string]interface{}{rule.key: nil})
+	}
+
+	if destroy {
+		return rule.exec(p, "destroywhere", param)
+	}
+	return rule.exec(p, "deletewhere", param)
+}
+
+// keys the values of the foreign column of the rows, the null values are ignored
+func (rule cascadeRule) keys(rows []map[string]interface{}) []interface{} {
+	keys := []interface{}{}
+	for _, row := range rows {
+		if value := row[rule.foreign]; value != nil {
+			keys = append(keys, value)
+		}
+	}
+	return keys
+}
+
+// exec run the process of the related model with the session of the process
+func (rule cascadeRule) exec(p *process.Process, method string, args ...interface{}) error {
+	handler, err := process.Of(fmt.Sprintf("models.%s.%s", rule.model, method), args...)
+	if err != nil {
+		return err
+	}
+	_, err = handler.WithSID(p.Sid).WithGlobal(p.Global).Exec()
+	return err
+}
+
+// Restore restore the rows soft deleted matched by the wheres, the related rows of the cascade rules
+// deleted with the rows are restored too. Returns the number of the rows restored.
+func Restore(p *process.Process, wheres []types.QueryWhere) (int64, error) {
+	mod := model.Select(p.ID)
+	if !mod.MetaData.Option.SoftDeletes {
+		return 0, fmt.Errorf("the model %s does not soft delete", p.ID)
+	}
+
+	qb, err := trashedQuery(p, mod, wheres)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := qb.Clone().Get()
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := qb.Update(map[string]interface{}{"deleted_at": nil})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, rule := range cascadesOf(p.ID) {
+		if rule.action != CascadeDelete {
+			continue
+		}
+
+		related := model.Select(rule.model)
+		if !related.MetaData.Option.SoftDeletes {
+			continue
+		}
+
+		// The related rows deleted with the row or later
+		for _, row := range rows {
+			value := row[rule.foreign]
+			if value == nil {
+				continue
+			}
+
+			wheres := []types.QueryWhere{
+				{Column: rule.key, Value: value},
+				{Column: "deleted_at", OP: "ge", Value: row["deleted_at"]},
+			}
+			_, err := Restore(&process.Process{ID: rule.model, Sid: p.Sid, Global: p.Global}, wheres)
+			if err != nil {
+				return affected, err
+			}
+		}
+	}
+
+	return affected, nil
+}
+
+// Trashed the rows soft deleted, the latest first
+func Trashed(p *process.Process, page int, size int) (interface{}, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 20
+	}
+
+	mod := model.Select(p.ID)
+	if !mod.MetaData.Option.SoftDeletes {
+		return nil, fmt.Errorf("the model %s does not soft delete", p.ID)
+	}
+
+	qb, err := trashedQuery(p, mod, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := qb.Clone().Count()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := qb.OrderBy("deleted_at", "desc").Offset((page - 1) * size).Limit(size).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	data := []map[string]interface{}{}
+	for _, row := range rows {
+		data = append(data, map[string]interface{}(row))
+	}
+	return paginated(data, int(total), page, size), nil
+}
+
+// trashedQuery the query of the rows soft deleted matched by the wheres and the policy of the session
+func trashedQuery(p *process.Process, mod *model.Model, wheres []types.QueryWhere) (query.Query, error) {
+	wheres = append(policyWheres(p), wheres...)
+
+	qb := capsule.Query()
+	qb.Table(mod.MetaData.Table.Name)
+	qb.WhereNotNull("deleted_at")
+	for _, where := range wheres {
+		if len(where.Wheres) > 0 || strings.HasPrefix(strings.ToLower(where.Method), "or") {
+			return nil, fmt.Errorf("the nested and the or wheres of the rows soft deleted are not supported")
+		}
+
+		column := fmt.Sprintf("%v", where.Column)
+		if _, has := mod.Columns[column]; !has && column != "deleted_at" {
+			return nil, fmt.Errorf("the column %s of the model %s does not exist", column, p.ID)
+		}
+
+		switch where.OP {
+		case "in":
+			qb.WhereIn(column, keysOf(where.Value))
+		case "null":
+			qb.WhereNull(column)
+		case "notnull":
+			qb.WhereNotNull(column)
+		default:
+			op, has := whereOps[where.OP]
+			if !has {
+				return nil, fmt.Errorf("the where %s %s is not supported", column, where.OP)
+			}
+			qb.Where(column, op, where.Value)
+		}
+	}
+	return qb, nil
+}
+
+// whereOps the operators of the query param wheres
+var whereOps = map[string]string{"": "=", "eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<="}
+
+// processRestore models.<model>.Restore (:primary), restore the row soft deleted
+func processRestore(p *process.Process) interface{} {
+	p.ValidateArgNums(1)
+	mod := model.Select(p.ID)
+	affected, err := Restore(p, []types.QueryWhere{{Column: mod.PrimaryKey, Value: p.Args[0]}})
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return affected
+}
+
+// processRestoreWhere models.<model>.RestoreWhere (:query), restore the rows soft deleted matched by the wheres
+func processRestoreWhere(p *process.Process) interface{} {
+	p.ValidateArgNums(1)
+	param := p.ArgsQueryParams(0, types.QueryParam{})
+	affected, err := Restore(p, param.Wheres)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return affected
+}
+
+// processTrashed models.<model>.Trashed ([:page], [:pagesize]), the rows soft deleted
+func processTrashed(p *process.Process) interface{} {
+	res, err := Trashed(p, p.ArgsInt(0, 1), p.ArgsInt(1, 20))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return res
+}
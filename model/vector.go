@@ -0,0 +1,513 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/config"
+)
+
+// Vector the vector column of the model, the embeddings are stored alongside the rows.
+// PostgreSQL stores the vectors in the pgvector column of the table,
+// SQLite stores the vectors in the sqlite-vss virtual table vss_<table>, the rowid is the primary key.
+//
+//	"vectors": { "embedding": { "dimension": 1536, "metric": "cosine", "index": "hnsw" } }
+type Vector struct {
+	Name      string `json:"-"`
+	Dimension int    `json:"dimension"`
+	Metric    string `json:"metric,omitempty"` // cosine, l2 or ip, cosine by default. SQLite supports l2 only
+	Index     string `json:"index,omitempty"`  // hnsw or ivfflat of pgvector, no index by default
+}
+
+// vectorModel the vector columns of the model
+type vectorModel struct {
+	id      string
+	table   string
+	primary string
+	driver  string
+	columns map[string]*Vector
+}
+
+// vectorDistances the distance operators of pgvector
+var vectorDistances = map[string]string{"cosine": "<=>", "l2": "<->", "ip": "<#>"}
+
+// vectorOps the operator classes of the pgvector indexes
+var vectorOps = map[string]string{"cosine": "vector_cosine_ops", "l2": "vector_l2_ops", "ip": "vector_ip_ops"}
+
+var vectors = map[string]*vectorModel{}
+var vectorLock sync.RWMutex
+
+func init() {
+	for _, method := range []string{"find", "get", "paginate", "create", "save", "update", "updatewhere"} {
+		name := "models." + method
+		if handler, has := process.Handlers[name]; has {
+			process.Handlers[name] = vectoring(method, handler)
+		}
+	}
+	process.Register("models.nearest", processNearest)
+}
+
+// loadVectors load the vector columns of the model source
+func loadVectors(file string, id string, mod *model.Model) error {
+	source, err := application.App.Read(file)
+	if err != nil {
+		return err
+	}
+
+	dsl := struct {
+		Vectors map[string]*Vector `json:"vectors,omitempty"`
+	}{}
+	err = application.Parse(file, source, &dsl)
+	if err != nil {
+		return err
+	}
+
+	vectorLock.Lock()
+	defer vectorLock.Unlock()
+	delete(vectors, id)
+	if len(dsl.Vectors) == 0 {
+		return nil
+	}
+
+	driver := config.Conf.DB.Driver
+	if driver != "postgres" && driver != "sqlite3" {
+		return fmt.Errorf("[%s] the vector columns are only supported by PostgreSQL and SQLite", id)
+	}
+
+	if mod.MetaData.Connector != "" && mod.MetaData.Connector != "default" {
+		return fmt.Errorf("[%s] the vector columns are only supported by the default database", id)
+	}
+
+	m := &vectorModel{id: id, table: mod.MetaData.Table.Name, primary: mod.PrimaryKey, driver: driver, columns: map[string]*Vector{}}
+	for name, vector := range dsl.Vectors {
+		if !computedName.MatchString(name) {
+			return fmt.Errorf("[%s] the name of the vector column %s is invalid", id, name)
+		}
+
+		if _, has := mod.Columns[name]; has {
+			return fmt.Errorf("[%s] the vector column %s is a column", id, name)
+		}
+
+		if vector.Dimension < 1 || vector.Dimension > 16000 {
+			return fmt.Errorf("[%s] the dimension of the vector column %s must be between 1 and 16000", id, name)
+		}
+
+		if vector.Metric == "" {
+			vector.Metric = "cosine"
+		}
+		vector.Metric = strings.ToLower(vector.Metric)
+		if _, has := vectorDistances[vector.Metric]; !has {
+			return fmt.Errorf("[%s] the metric %s of the vector column %s is invalid, cosine, l2 or ip", id, vector.Metric, name)
+		}
+
+		if driver == "sqlite3" && vector.Metric != "l2" {
+			return fmt.Errorf("[%s] the metric of the vector column %s must be l2 on SQLite", id, name)
+		}
+
+		vector.Index = strings.ToLower(vector.Index)
+		if vector.Index != "" && vector.Index != "hnsw" && vector.Index != "ivfflat" {
+			return fmt.Errorf("[%s] the index %s of the vector column %s is invalid, hnsw or ivfflat", id, vector.Index, name)
+		}
+
+		vector.Name = name
+		m.columns[name] = vector
+	}
+
+	vectors[id] = m
+	return nil
+}
+
+func vectorOf(id string) *vectorModel {
+	vectorLock.RLock()
+	defer vectorLock.RUnlock()
+	return vectors[id]
+}
+
+// MigrateVectors create the vector columns of the model, the vectors of SQLite are dropped first if reset.
+// The pgvector extension or the sqlite-vss extension must be installed.
+func MigrateVectors(id string, reset bool) error {
+	m := vectorOf(id)
+	if m == nil {
+		return nil
+	}
+
+	stmts := m.migrateSQL(reset)
+	db := capsule.Query().DB(true)
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("%s %s", stmt, err.Error())
+		}
+	}
+	log.Trace("Migrate the vector columns: %s", m.table)
+	return nil
+}
+
+// migrateSQL the statements of the migration, the table of PostgreSQL is dropped by the reset of the model
+func (m *vectorModel) migrateSQL(reset bool) []string {
+	stmts := []string{}
+	if m.driver == "sqlite3" {
+		if reset {
+			stmts = append(stmts, fmt.Sprintf(`DROP TABLE IF EXISTS "%s"`, m.vss()))
+		}
+
+		columns := []string{}
+		for _, vector := range m.sorted() {
+			columns = append(columns, fmt.Sprintf("%s(%d)", vector.Name, vector.Dimension))
+		}
+		return append(stmts, fmt.Sprintf(`CREATE VIRTUAL TABLE IF NOT EXISTS "%s" USING vss0(%s)`, m.vss(), strings.Join(columns, ", ")))
+	}
+
+	stmts = append(stmts, "CREATE EXTENSION IF NOT EXISTS vector")
+	for _, vector := range m.sorted() {
+		stmts = append(stmts, fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN IF NOT EXISTS "%s" vector(%d)`, m.table, vector.Name, vector.Dimension))
+		if vector.Index != "" {
+			stmts = append(stmts, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s_%s_vector" ON "%s" USING %s ("%s" %s)`,
+				m.table, vector.Name, m.table, vector.Index, vector.Name, vectorOps[vector.Metric]))
+		}
+	}
+	return stmts
+}
+
+func (m *vectorModel) vss() string {
+	return "vss_" + m.table
+}
+
+func (m *vectorModel) sorted() []*Vector {
+	res := []*Vector{}
+	for _, vector := range m.columns {
+		res = append(res, vector)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// vectoring write the vectors of the rows and replace the near wheres of the query param
+func vectoring(method string, handler process.Handler) process.Handler {
+	return func(p *process.Process) interface{} {
+		m := vectorOf(p.ID)
+		if m == nil {
+			return handler(p)
+		}
+
+		switch method {
+		case "find", "get", "paginate":
+			i := 0
+			if method == "find" {
+				i = 1
+			}
+
+			if p.NumOfArgs() <= i || p.Args[i] == nil {
+				return handler(p)
+			}
+
+			param := p.ArgsQueryParams(i, types.QueryParam{})
+			wheres, err := m.wheres(param.Wheres)
+			if err != nil {
+				exception.New("%s %s", 400, p.ID, err.Error()).Throw()
+			}
+			param.Wheres = wheres
+			p.Args[i] = param
+			return handler(p)
+
+		case "create", "save":
+			p.ValidateArgNums(1)
+			row := any.Of(p.Args[0]).MapStr()
+			values := m.take(row)
+			p.Args[0] = map[string]interface{}(row)
+
+			res := handler(p)
+			id := res
+			if method == "save" && row[m.primary] != nil {
+				id = row[m.primary]
+			}
+			m.write(id, values)
+			return res
+
+		case "update":
+			p.ValidateArgNums(2)
+			row := any.Of(p.Args[1]).MapStr()
+			values := m.take(row)
+			p.Args[1] = map[string]interface{}(row)
+
+			res := handler(p)
+			m.write(p.Args[0], values)
+			return res
+
+		case "updatewhere":
+			p.ValidateArgNums(2)
+			row := any.Of(p.Args[1]).MapStr()
+			for name := range m.columns {
+				if _, has := row[name]; has {
+					exception.New("%s the vector column %s can not be updated by updatewhere", 400, p.ID, name).Throw()
+				}
+			}
+		}
+		return handler(p)
+	}
+}
+
+// take remove the vectors of the row, returns the vectors
+func (m *vectorModel) take(row map[string]interface{}) map[string]interface{} {
+	values := map[string]interface{}{}
+	for name := range m.columns {
+		if value, has := row[name]; has {
+			values[name] = value
+			delete(row, name)
+		}
+	}
+	return values
+}
+
+// write the vectors of the row, the nil vector clears the vector of the row
+func (m *vectorModel) write(id interface{}, values map[string]interface{}) {
+	if len(values) == 0 || id == nil {
+		return
+	}
+
+	stmts, bindings, err := m.writeSQL(id, values)
+	if err != nil {
+		exception.New("%s %s", 400, m.id, err.Error()).Throw()
+	}
+
+	db := capsule.Query().DB(true)
+	for i, stmt := range stmts {
+		if _, err := db.Exec(db.Rebind(stmt), bindings[i]...); err != nil {
+			exception.New("%s %v %s", 500, m.id, id, err.Error()).Throw()
+		}
+	}
+}
+
+// writeSQL the statements of the vectors of the row. The vectors of SQLite are replaced.
+func (m *vectorModel) writeSQL(id interface{}, values map[string]interface{}) ([]string, [][]interface{}, error) {
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	literals := map[string]interface{}{}
+	for _, name := range names {
+		if values[name] == nil {
+			literals[name] = nil
+			continue
+		}
+
+		literal, err := m.columns[name].literal(values[name])
+		if err != nil {
+			return nil, nil, err
+		}
+		literals[name] = literal
+	}
+
+	if m.driver == "sqlite3" {
+		stmts := []string{fmt.Sprintf(`DELETE FROM "%s" WHERE rowid = ?`, m.vss())}
+		bindings := [][]interface{}{{id}}
+
+		// The vss0 rows are written with all the vectors
+		columns, marks, args := []string{"rowid"}, []string{"?"}, []interface{}{id}
+		for _, name := range names {
+			if literals[name] == nil {
+				continue
+			}
+			columns = append(columns, name)
+			marks = append(marks, "?")
+			args = append(args, literals[name])
+		}
+		if len(columns) > 1 {
+			stmts = append(stmts, fmt.Sprintf(`INSERT INTO "%s" (%s) VALUES (%s)`, m.vss(), strings.Join(columns, ", "), strings.Join(marks, ", ")))
+			bindings = append(bindings, args)
+		}
+		return stmts, bindings, nil
+	}
+
+	sets, args := []string{}, []interface{}{}
+	for _, name := range names {
+		sets = append(sets, fmt.Sprintf(`"%s" = ?::vector`, name))
+		args = append(args, literals[name])
+	}
+	args = append(args, id)
+	return []string{fmt.Sprintf(`UPDATE "%s" SET %s WHERE "%s" = ?`, m.table, strings.Join(sets, ", "), m.primary)}, [][]interface{}{args}, nil
+}
+
+// literal the text of the vector, [0.1,0.2,...], the values are the numbers of the dimension
+func (vector *Vector) literal(value interface{}) (string, error) {
+	values, ok := value.([]interface{})
+	if !ok {
+		switch v := value.(type) {
+		case []float64:
+			for _, f := range v {
+				values = append(values, f)
+			}
+		case []float32:
+			for _, f := range v {
+				values = append(values, f)
+			}
+		default:
+			return "", fmt.Errorf("the vector %s must be an array of numbers", vector.Name)
+		}
+	}
+
+	if len(values) != vector.Dimension {
+		return "", fmt.Errorf("the vector %s has %d dimensions, %d expected", vector.Name, len(values), vector.Dimension)
+	}
+
+	items := []string{}
+	for _, v := range values {
+		switch v.(type) {
+		case float64, float32, int, int64, int32:
+			items = append(items, fmt.Sprintf("%v", any.Of(v).CFloat64()))
+		default:
+			return "", fmt.Errorf("the vector %s must be an array of numbers", vector.Name)
+		}
+	}
+	return "[" + strings.Join(items, ",") + "]", nil
+}
+
+// nearest the primary keys and the distances of the nearest rows of the vector, the rows within the distance if the distance > 0
+func (m *vectorModel) nearest(name string, value interface{}, limit int, distance float64) ([]interface{}, map[string]float64, error) {
+	vector, has := m.columns[name]
+	if !has {
+		return nil, nil, fmt.Errorf("the vector column %s does not exist", name)
+	}
+
+	literal, err := vector.literal(value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if limit < 1 {
+		limit = 20
+	}
+
+	stmt, bindings := m.nearestSQL(vector, literal, limit, distance)
+	db := capsule.Query().DB()
+	rows, err := db.Queryx(db.Rebind(stmt), bindings...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	ids := []interface{}{}
+	distances := map[string]float64{}
+	for rows.Next() {
+		var id interface{}
+		var d float64
+		if err := rows.Scan(&id, &d); err != nil {
+			return nil, nil, err
+		}
+
+		if b, ok := id.([]byte); ok {
+			id = string(b)
+		}
+
+		if distance > 0 && d > distance {
+			continue
+		}
+		ids = append(ids, id)
+		distances[fmt.Sprintf("%v", id)] = d
+	}
+	return ids, distances, rows.Err()
+}
+
+// nearestSQL the statement of the nearest rows ordered by the distance
+func (m *vectorModel) nearestSQL(vector *Vector, literal string, limit int, distance float64) (string, []interface{}) {
+	if m.driver == "sqlite3" {
+		return fmt.Sprintf(`SELECT rowid, distance FROM "%s" WHERE vss_search(%s, vss_search_params(?, ?))`, m.vss(), vector.Name),
+			[]interface{}{literal, limit}
+	}
+
+	expr := fmt.Sprintf(`"%s" %s ?::vector`, vector.Name, vectorDistances[vector.Metric])
+	stmt := fmt.Sprintf(`SELECT "%s", %s AS distance FROM "%s" WHERE "%s" IS NOT NULL`, m.primary, expr, m.table, vector.Name)
+	bindings := []interface{}{literal}
+	if distance > 0 {
+		stmt += fmt.Sprintf(" AND %s <= ?", expr)
+		bindings = append(bindings, literal, distance)
+	}
+	return stmt + fmt.Sprintf(" ORDER BY %s LIMIT %d", expr, limit), append(bindings, literal)
+}
+
+// wheres the near wheres are replaced by the primary keys of the nearest rows
+//
+//	{ "column": "embedding", "op": "near", "value": { "vector": [...], "distance": 0.3, "limit": 100 } }
+func (m *vectorModel) wheres(wheres []types.QueryWhere) ([]types.QueryWhere, error) {
+	res := []types.QueryWhere{}
+	for _, where := range wheres {
+		if len(where.Wheres) > 0 {
+			nested, err := m.wheres(where.Wheres)
+			if err != nil {
+				return nil, err
+			}
+			where.Wheres = nested
+		}
+
+		if !strings.EqualFold(where.OP, "near") {
+			res = append(res, where)
+			continue
+		}
+
+		value := any.Of(where.Value).MapStr()
+		limit := 100
+		if value.Has("limit") {
+			limit = any.Of(value.Get("limit")).CInt()
+		}
+
+		ids, _, err := m.nearest(fmt.Sprintf("%v", where.Column), value.Get("vector"), limit, any.Of(value.Get("distance")).CFloat64())
+		if err != nil {
+			return nil, err
+		}
+
+		// IN (NULL) matches nothing
+		where.Column, where.OP, where.Value = m.primary, "in", append([]interface{}{nil}, ids...)
+		res = append(res, where)
+	}
+	return res, nil
+}
+
+// processNearest models.<model>.Nearest (:column, :vector, :limit, :param), the rows of the query param ordered by the distance.
+// The distance of the rows is the __distance field.
+func processNearest(p *process.Process) interface{} {
+	p.ValidateArgNums(2)
+	m := vectorOf(p.ID)
+	if m == nil {
+		exception.New("%s has no vector columns", 400, p.ID).Throw()
+	}
+
+	limit := p.ArgsInt(2, 20)
+	ids, distances, err := m.nearest(p.ArgsString(0), p.Args[1], limit, 0)
+	if err != nil {
+		exception.New("%s %s", 400, p.ID, err.Error()).Throw()
+	}
+
+	param := p.ArgsQueryParams(3, types.QueryParam{})
+	param.Wheres = append(param.Wheres, types.QueryWhere{Column: m.primary, OP: "in", Value: append([]interface{}{nil}, ids...)})
+	param.Limit = 0
+
+	get, err := process.Of(fmt.Sprintf("models.%s.get", p.ID), param)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	res, err := get.WithSID(p.Sid).WithGlobal(p.Global).Exec()
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	rows := rowsOf(res)
+	for _, row := range rows {
+		row["__distance"] = distances[fmt.Sprintf("%v", row[m.primary])]
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return any.Of(rows[i]["__distance"]).CFloat64() < any.Of(rows[j]["__distance"]).CFloat64()
+	})
+	return rows
+}
@@ -1,23 +1,34 @@
 package neo
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/url"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/api"
 	"github.com/yaoapp/gou/connector"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/yao/helper"
+	"github.com/yaoapp/yao/neo/assistant"
 	chatctx "github.com/yaoapp/yao/neo/context"
+	"github.com/yaoapp/yao/neo/crawler"
 	"github.com/yaoapp/yao/neo/message"
+	neorag "github.com/yaoapp/yao/neo/rag"
 	"github.com/yaoapp/yao/neo/store"
+	"github.com/yaoapp/yao/openai"
+	"github.com/yaoapp/yao/policy"
+	"github.com/yaoapp/yao/share"
 )
 
 // API registers the Neo API endpoints
@@ -42,8 +53,30 @@ func (neo *DSL) API(router *gin.Engine, path string) error {
 	router.OPTIONS(path+"/generate/title", neo.optionsHandler)
 	router.OPTIONS(path+"/generate/prompts", neo.optionsHandler)
 	router.OPTIONS(path+"/dangerous/clear_chats", neo.optionsHandler)
+	router.OPTIONS(path+"/utility/connectors/health", neo.optionsHandler)
+	router.OPTIONS(path+"/utility/connectors/health/:id", neo.optionsHandler)
+	router.OPTIONS(path+"/utility/connectors/:id/models", neo.optionsHandler)
+	router.OPTIONS(path+"/utility/store/cleanup", neo.optionsHandler)
 	router.OPTIONS(path+"/assistants", neo.optionsHandler)
 	router.OPTIONS(path+"/assistants/:id", neo.optionsHandler)
+	router.OPTIONS(path+"/assistants/:id/prompts/preview", neo.optionsHandler)
+	router.OPTIONS(path+"/knowledge/crawl", neo.optionsHandler)
+	router.OPTIONS(path+"/knowledge/upload", neo.optionsHandler)
+	router.OPTIONS(path+"/knowledge/collections", neo.optionsHandler)
+	router.OPTIONS(path+"/knowledge/collections/:name", neo.optionsHandler)
+	router.OPTIONS(path+"/memories", neo.optionsHandler)
+	router.OPTIONS(path+"/memories/:id", neo.optionsHandler)
+	router.OPTIONS(path+"/profile", neo.optionsHandler)
+	router.OPTIONS(path+"/assistants/:id/workflows/:name/run", neo.optionsHandler)
+	router.OPTIONS(path+"/workflows/runs", neo.optionsHandler)
+	router.OPTIONS(path+"/workflows/runs/:id", neo.optionsHandler)
+	router.OPTIONS(path+"/workflows/runs/:id/resume", neo.optionsHandler)
+	router.OPTIONS(path+"/approvals", neo.optionsHandler)
+	router.OPTIONS(path+"/approvals/:id", neo.optionsHandler)
+	router.OPTIONS(path+"/v1/chat/completions", neo.optionsHandler)
+	router.OPTIONS(path+"/v1/messages", neo.optionsHandler)
+	router.OPTIONS(path+"/user/export", neo.optionsHandler)
+	router.OPTIONS(path+"/user/deletion", neo.optionsHandler)
 
 	// Chat endpoint
 	// Example:
@@ -75,15 +108,26 @@ func (neo *DSL) API(router *gin.Engine, path string) error {
 	// curl -X POST 'http://localhost:5099/api/__yao/neo/assistants' \
 	//   -H 'Content-Type: application/json' \
 	//   -d '{"name": "My Assistant", "type": "chat", "tags": ["tag1", "tag2"], "mentionable": true, "avatar": "path/to/avatar.png", "token": "xxx"}'
+	// Update the assistant only if it is not changed since it was read, 409 with the latest version if it is changed:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/assistants' \
+	//   -H 'Content-Type: application/json' \
+	//   -H 'If-Match: "<the ETag header of the assistant detail>"' \
+	//   -d '{"assistant_id": "assistant_123", "name": "My Assistant", "token": "xxx"}'
 	router.POST(path+"/assistants", append(middlewares, neo.handleAssistantSave)...)
 
 	// Delete assistant example:
 	// curl -X DELETE 'http://localhost:5099/api/__yao/neo/assistants/assistant_123?token=xxx'
 	router.DELETE(path+"/assistants/:id", append(middlewares, neo.handleAssistantDelete)...)
 
+	// Preview the rendered prompts of an assistant example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/assistants/assistant_123/prompts/preview?token=xxx' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"chat_id": "chat_123", "variables": {"user": {"name": "Max"}}}'
+	router.POST(path+"/assistants/:id/prompts/preview", append(middlewares, neo.handleAssistantPromptsPreview)...)
+
 	// Chat management endpoints
 	// List chats example:
-	// curl -X GET 'http://localhost:5099/api/__yao/neo/chats?page=1&pagesize=20&keywords=search+term&order=desc&token=xxx'
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/chats?page=1&pagesize=20&keywords=search+term&order=desc&timezone=Asia/Shanghai&locale=zh-CN&token=xxx'
 	router.GET(path+"/chats", append(middlewares, neo.handleChatList)...)
 
 	// Get chat details example:
@@ -151,6 +195,158 @@ func (neo *DSL) API(router *gin.Engine, path string) error {
 	// curl -X GET 'http://localhost:5099/api/__yao/neo/utility/connectors?token=xxx'
 	router.GET(path+"/utility/connectors", append(middlewares, neo.handleConnectors)...)
 
+	// List the models served by a connector with the provider and the capabilities, e.g. the models pulled by Ollama example:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/utility/connectors/ollama/models?token=xxx'
+	router.GET(path+"/utility/connectors/:id/models", append(middlewares, neo.handleConnectorModels)...)
+
+	// Connectors health example, the error rate, the latency and the cooldown of each connector:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/utility/connectors/health?token=xxx'
+	router.GET(path+"/utility/connectors/health", append(middlewares, neo.handleConnectorsHealth)...)
+
+	// Reset the health of a connector, ends the cooldown example:
+	// curl -X DELETE 'http://localhost:5099/api/__yao/neo/utility/connectors/health/gpt-4o?token=xxx'
+	router.DELETE(path+"/utility/connectors/health/:id", append(middlewares, neo.handleConnectorsHealthReset)...)
+
+	// Store cleanup metrics example, the runs, the deleted rows and the next run of the instance:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/utility/store/cleanup?token=xxx'
+	router.GET(path+"/utility/store/cleanup", append(middlewares, neo.handleStoreCleanup)...)
+
+	// Knowledge endpoints
+	// Crawl web pages into a knowledge collection example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/knowledge/crawl?token=xxx' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"collection": "docs", "seeds": ["https://yaoapps.com/docs"], "max_depth": 2, "max_pages": 50}'
+	router.POST(path+"/knowledge/crawl", append(middlewares, neo.handleKnowledgeCrawl)...)
+
+	// Upload a document (PDF, DOCX, PPTX, XLSX, Markdown, HTML...) into a knowledge collection example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/knowledge/upload?collection=docs&token=xxx' \
+	//   -F 'file=@/path/to/manual.pdf'
+	router.POST(path+"/knowledge/upload", append(middlewares, neo.handleKnowledgeUpload)...)
+
+	// List knowledge collections example:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/knowledge/collections?token=xxx'
+	router.GET(path+"/knowledge/collections", append(middlewares, neo.handleCollectionList)...)
+
+	// Get knowledge collection settings example:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/knowledge/collections/docs?token=xxx'
+	router.GET(path+"/knowledge/collections/:name", append(middlewares, neo.handleCollectionDetail)...)
+
+	// Create/Update knowledge collection settings example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/knowledge/collections?token=xxx' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"name": "docs", "chunk": {"size": 512, "overlap": 64, "splitter": "markdown"}, "metadata": [{"field": "version", "pattern": "Version: (\\S+)"}]}'
+	router.POST(path+"/knowledge/collections", append(middlewares, neo.handleCollectionSave)...)
+
+	// Delete knowledge collection settings example:
+	// curl -X DELETE 'http://localhost:5099/api/__yao/neo/knowledge/collections/docs?token=xxx'
+	router.DELETE(path+"/knowledge/collections/:name", append(middlewares, neo.handleCollectionDelete)...)
+
+	// Memory endpoints
+	// List memories example:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/memories?assistant_id=assistant_123&keywords=tea&limit=20&token=xxx'
+	router.GET(path+"/memories", append(middlewares, neo.handleMemoryList)...)
+
+	// Add memories example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/memories?token=xxx' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"assistant_id": "assistant_123", "memories": ["Likes green tea"], "ttl": 86400}'
+	router.POST(path+"/memories", append(middlewares, neo.handleMemorySave)...)
+
+	// Delete a memory example:
+	// curl -X DELETE 'http://localhost:5099/api/__yao/neo/memories/memory_123?token=xxx'
+	router.DELETE(path+"/memories/:id", append(middlewares, neo.handleMemoryDelete)...)
+
+	// Clear memories example:
+	// curl -X DELETE 'http://localhost:5099/api/__yao/neo/memories?assistant_id=assistant_123&token=xxx'
+	router.DELETE(path+"/memories", append(middlewares, neo.handleMemoryClear)...)
+
+	// Profile endpoints
+	// Get the preferences of the user example:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/profile?token=xxx'
+	router.GET(path+"/profile", append(middlewares, neo.handleProfileDetail)...)
+
+	// Update the preferences of the user example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/profile?token=xxx' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"language": "zh-CN", "tone": "friendly", "timezone": "Asia/Shanghai", "instructions": "Call me Max."}'
+	router.POST(path+"/profile", append(middlewares, neo.handleProfileSave)...)
+
+	// Workflow endpoints
+	// Run a workflow of an assistant example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/assistants/assistant_123/workflows/refund/run?token=xxx' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"input": {"order_id": 1}}'
+	router.POST(path+"/assistants/:id/workflows/:name/run", append(middlewares, neo.handleWorkflowRun)...)
+
+	// List workflow runs example:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/workflows/runs?assistant_id=assistant_123&status=waiting&limit=20&token=xxx'
+	router.GET(path+"/workflows/runs", append(middlewares, neo.handleWorkflowRunList)...)
+
+	// Get a workflow run example:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/workflows/runs/run_123?token=xxx'
+	router.GET(path+"/workflows/runs/:id", append(middlewares, neo.handleWorkflowRunDetail)...)
+
+	// Resume a workflow run, approve the waiting run or retry the failed run example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/workflows/runs/run_123/resume?token=xxx' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"approved": true, "comment": "Looks good"}'
+	router.POST(path+"/workflows/runs/:id/resume", append(middlewares, neo.handleWorkflowRunResume)...)
+
+	// Tool approval endpoints
+	// List approvals example:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/approvals?status=pending&assistant_id=assistant_123&chat_id=chat_123&limit=20&token=xxx'
+	router.GET(path+"/approvals", append(middlewares, neo.handleApprovalList)...)
+
+	// Get an approval example:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/approvals/approval_123?token=xxx'
+	router.GET(path+"/approvals/:id", append(middlewares, neo.handleApprovalDetail)...)
+
+	// Accept or reject an approval, the accepted tool is executed and the output is streamed example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/approvals/approval_123?token=xxx' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"approved": true, "comment": "Go ahead"}'
+	router.POST(path+"/approvals/:id", append(middlewares, neo.handleApprovalDecide)...)
+
+	// Replay the turn of a trace, the reply is streamed and the id of the new trace is the X-Yao-Trace header.
+	// The tools, the processes and the hooks return the outputs recorded if mock is true example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/traces/trace_123/replay?mock=true&token=xxx'
+	router.POST(path+"/traces/:id/replay", append(middlewares, neo.handleTraceReplay)...)
+
+	// OpenAI compatible endpoints, the model is the assistant ID
+	// Chat completions example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/v1/chat/completions' \
+	//   -H 'Authorization: Bearer xxx' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"model": "assistant_123", "messages": [{"role": "user", "content": "Hello"}], "stream": true}'
+	router.POST(path+"/v1/chat/completions", append(middlewares, neo.handleCompletions)...)
+
+	// Anthropic compatible endpoints, the model is the assistant ID
+	// Messages example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/v1/messages' \
+	//   -H 'Authorization: Bearer xxx' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"model": "assistant_123", "max_tokens": 1024, "messages": [{"role": "user", "content": "Hello"}], "stream": true}'
+	router.POST(path+"/v1/messages", append(middlewares, neo.handleMessages)...)
+
+	// User data endpoints
+	// Export all the data of the user as a zip archive example:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/user/export?token=xxx' -o export.zip
+	router.GET(path+"/user/export", append(middlewares, neo.handleUserExport)...)
+
+	// Get the scheduled deletion of the user data example:
+	// curl -X GET 'http://localhost:5099/api/__yao/neo/user/deletion?token=xxx'
+	router.GET(path+"/user/deletion", append(middlewares, neo.handleUserDeletionDetail)...)
+
+	// Schedule the deletion of all the user data after the grace period (30 days by default) example:
+	// curl -X POST 'http://localhost:5099/api/__yao/neo/user/deletion?token=xxx' \
+	//   -H 'Content-Type: application/json' \
+	//   -d '{"days": 30}'
+	router.POST(path+"/user/deletion", append(middlewares, neo.handleUserDeletionSchedule)...)
+
+	// Cancel the scheduled deletion example:
+	// curl -X DELETE 'http://localhost:5099/api/__yao/neo/user/deletion?token=xxx'
+	router.DELETE(path+"/user/deletion", append(middlewares, neo.handleUserDeletionCancel)...)
+
 	// Dangerous operations
 	// Dangerous operations
 	// Clear all chats example:
@@ -160,6 +356,14 @@ func (neo *DSL) API(router *gin.Engine, path string) error {
 	return nil
 }
 
+// withCacheControl skip reading the response cache of the assistant if the request has the Cache-Control: no-cache or the X-Yao-Cache: bypass header
+func withCacheControl(c *gin.Context) {
+	control := strings.ToLower(c.GetHeader("Cache-Control"))
+	if strings.Contains(control, "no-cache") || strings.Contains(control, "no-store") || strings.EqualFold(c.GetHeader("X-Yao-Cache"), "bypass") {
+		c.Request = c.Request.WithContext(assistant.WithoutCache(c.Request.Context()))
+	}
+}
+
 // handleStatus handles the status request
 func (neo *DSL) handleStatus(c *gin.Context) {
 	c.Status(200)
@@ -191,6 +395,8 @@ func (neo *DSL) handleUpload(c *gin.Context) {
 
 // handleChat handles the chat request
 func (neo *DSL) handleChat(c *gin.Context) {
+	withCacheControl(c)
+
 	// Set headers for SSE
 	c.Header("Content-Type", "text/event-stream;charset=utf-8")
 	c.Header("Cache-Control", "no-cache")
@@ -224,7 +430,7 @@ func (neo *DSL) handleChat(c *gin.Context) {
 // handleChatList handles the chat list request
 func (neo *DSL) handleChatList(c *gin.Context) {
 	sid := c.GetString("__sid")
-	if sid == "" {
+	if sid == "" || share.IsGuest(sid) {
 		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
 		c.Done()
 		return
@@ -234,6 +440,8 @@ func (neo *DSL) handleChatList(c *gin.Context) {
 	filter := store.ChatFilter{
 		Keywords: c.Query("keywords"),
 		Order:    c.Query("order"),
+		TimeZone: c.Query("timezone"),
+		Locale:   c.Query("locale"),
 	}
 
 	// Parse page and pagesize
@@ -263,7 +471,7 @@ func (neo *DSL) handleChatList(c *gin.Context) {
 // handleChatHistory handles the chat history request
 func (neo *DSL) handleChatHistory(c *gin.Context) {
 	sid := c.GetString("__sid")
-	if sid == "" {
+	if sid == "" || share.IsGuest(sid) {
 		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
 		c.Done()
 		return
@@ -284,7 +492,7 @@ func (neo *DSL) handleChatHistory(c *gin.Context) {
 // handleDownload handles the download request
 func (neo *DSL) handleDownload(c *gin.Context) {
 	sid := c.GetString("__sid")
-	if sid == "" {
+	if sid == "" || share.IsGuest(sid) {
 		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
 		c.Done()
 		return
@@ -361,7 +569,8 @@ func (neo *DSL) corsMiddleware(allowsMap map[string]bool) gin.HandlerFunc {
 		// Set CORS headers
 		c.Header("Access-Control-Allow-Origin", origin)
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Accept, Origin, Cache-Control, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Accept, Origin, Cache-Control, X-Yao-Cache, If-Match, X-Requested-With")
+		c.Header("Access-Control-Expose-Headers", "ETag")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 
 		if c.Request.Method == "OPTIONS" {
@@ -379,7 +588,7 @@ func (neo *DSL) optionsHandler(c *gin.Context) {
 	if origin != "" {
 		c.Header("Access-Control-Allow-Origin", origin)
 		c.Header("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, Accept")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, Accept, Cache-Control, X-Yao-Cache, If-Match")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Max-Age", "86400") // 24 hours
 	}
@@ -441,7 +650,7 @@ func (neo *DSL) defaultGuard(c *gin.Context) {
 // handleChatDetail handles getting a single chat's details
 func (neo *DSL) handleChatDetail(c *gin.Context) {
 	sid := c.GetString("__sid")
-	if sid == "" {
+	if sid == "" || share.IsGuest(sid) {
 		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
 		c.Done()
 		return
@@ -468,7 +677,7 @@ func (neo *DSL) handleChatDetail(c *gin.Context) {
 // handleMentions handles getting mentions for a chat
 func (neo *DSL) handleMentions(c *gin.Context) {
 	sid := c.GetString("__sid")
-	if sid == "" {
+	if sid == "" || share.IsGuest(sid) {
 		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
 		c.Done()
 		return
@@ -512,7 +721,7 @@ func (neo *DSL) handleMentions(c *gin.Context) {
 // handleChatUpdate handles updating a chat's details
 func (neo *DSL) handleChatUpdate(c *gin.Context) {
 	sid := c.GetString("__sid")
-	if sid == "" {
+	if sid == "" || share.IsGuest(sid) {
 		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
 		c.Done()
 		return
@@ -570,7 +779,7 @@ func (neo *DSL) handleChatUpdate(c *gin.Context) {
 // handleChatDelete handles deleting a single chat
 func (neo *DSL) handleChatDelete(c *gin.Context) {
 	sid := c.GetString("__sid")
-	if sid == "" {
+	if sid == "" || share.IsGuest(sid) {
 		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
 		c.Done()
 		return
@@ -597,7 +806,7 @@ func (neo *DSL) handleChatDelete(c *gin.Context) {
 // handleChatsDeleteAll handles deleting all chats for a user
 func (neo *DSL) handleChatsDeleteAll(c *gin.Context) {
 	sid := c.GetString("__sid")
-	if sid == "" {
+	if sid == "" || share.IsGuest(sid) {
 		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
 		c.Done()
 		return
@@ -625,7 +834,7 @@ type generateResponse struct {
 
 // validate checks common validation rules
 func (r *generateResponse) validate() bool {
-	if r.sid == "" {
+	if r.sid == "" || share.IsGuest(r.sid) {
 		if strings.Contains(r.c.GetHeader("Accept"), "text/event-stream") {
 			r.c.Header("Content-Type", "text/event-stream;charset=utf-8")
 			r.c.Header("Cache-Control", "no-cache")
@@ -966,6 +1175,7 @@ func (neo *DSL) handleAssistantDetail(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", fmt.Sprintf(`"%s"`, store.AssistantETag(response.Data[0])))
 	c.JSON(200, map[string]interface{}{"data": response.Data[0]})
 	c.Done()
 }
@@ -979,6 +1189,35 @@ func (neo *DSL) handleAssistantSave(c *gin.Context) {
 		return
 	}
 
+	// Update the assistant with the precondition
+	if match := strings.Trim(strings.TrimPrefix(c.GetHeader("If-Match"), "W/"), `"`); match != "" && match != "*" {
+		if _, has := assistant["assistant_id"]; !has {
+			c.JSON(400, gin.H{"message": "assistant_id is required with If-Match", "code": 400})
+			c.Done()
+			return
+		}
+
+		etag, err := neo.Store.UpdateAssistant(assistant, match)
+		if conflict, ok := err.(*store.ConflictError); ok {
+			if conflict.Latest != nil {
+				c.Header("ETag", fmt.Sprintf(`"%s"`, store.AssistantETag(conflict.Latest)))
+			}
+			c.JSON(409, gin.H{"message": conflict.Error(), "code": 409, "data": conflict.Latest})
+			c.Done()
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+			c.Done()
+			return
+		}
+
+		c.Header("ETag", fmt.Sprintf(`"%s"`, etag))
+		c.JSON(200, gin.H{"message": "ok", "data": assistant})
+		c.Done()
+		return
+	}
+
 	id, err := neo.Store.SaveAssistant(assistant)
 	if err != nil {
 		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
@@ -995,6 +1234,46 @@ func (neo *DSL) handleAssistantSave(c *gin.Context) {
 	c.Done()
 }
 
+// handleAssistantPromptsPreview handles rendering the prompts of an assistant without chatting
+func (neo *DSL) handleAssistantPromptsPreview(c *gin.Context) {
+	assistantID := c.Param("id")
+	if assistantID == "" {
+		c.JSON(400, gin.H{"message": "assistant id is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	var body struct {
+		ChatID    string                 `json:"chat_id"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(400, gin.H{"message": "invalid request body", "code": 400})
+			c.Done()
+			return
+		}
+	}
+
+	ast, err := assistant.Get(assistantID)
+	if err != nil {
+		c.JSON(404, gin.H{"message": err.Error(), "code": 404})
+		c.Done()
+		return
+	}
+
+	ctx := chatctx.New(c.GetString("__sid"), body.ChatID, "")
+	prompts, err := ast.PreviewPrompts(ctx, body.Variables)
+	if err != nil {
+		c.JSON(400, gin.H{"message": err.Error(), "code": 400})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": prompts})
+	c.Done()
+}
+
 // handleAssistantDelete handles deleting an assistant
 func (neo *DSL) handleAssistantDelete(c *gin.Context) {
 	assistantID := c.Param("id")
@@ -1041,10 +1320,75 @@ func (neo *DSL) handleConnectors(c *gin.Context) {
 	c.Done()
 }
 
+// handleConnectorModels handles listing the models served by the connector
+func (neo *DSL) handleConnectorModels(c *gin.Context) {
+	ai, err := openai.New(c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"message": err.Error(), "code": 404})
+		c.Done()
+		return
+	}
+
+	models, ext := ai.Models()
+	if ext != nil {
+		c.JSON(500, gin.H{"message": ext.Message, "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": gin.H{
+		"provider":     ai.Provider(),
+		"local":        ai.Local(),
+		"model":        ai.Model(),
+		"capabilities": ai.Capabilities(),
+		"models":       models,
+	}})
+	c.Done()
+}
+
+// handleConnectorsHealth handles listing the health of the connectors, the connectors have not been requested are healthy
+func (neo *DSL) handleConnectorsHealth(c *gin.Context) {
+	healths := openai.Healths()
+	tracked := map[string]bool{}
+	for _, health := range healths {
+		tracked[health.Connector] = true
+	}
+
+	for id, conn := range connector.Connectors {
+		if (conn.Is(connector.OPENAI) || conn.Is(connector.MOAPI)) && !tracked[id] {
+			healths = append(healths, openai.HealthOf(id))
+		}
+	}
+	sort.Slice(healths, func(i, j int) bool { return healths[i].Connector < healths[j].Connector })
+
+	c.JSON(200, gin.H{"data": healths})
+	c.Done()
+}
+
+// handleConnectorsHealthReset handles resetting the health of the connector
+func (neo *DSL) handleConnectorsHealthReset(c *gin.Context) {
+	openai.ResetHealth(c.Param("id"))
+	c.JSON(200, gin.H{"message": "ok"})
+	c.Done()
+}
+
+// handleStoreCleanup handles getting the cleanup metrics of the store
+func (neo *DSL) handleStoreCleanup(c *gin.Context) {
+	cleaner, ok := neo.Store.(store.Cleaner)
+	if !ok {
+		c.JSON(400, gin.H{"message": "the store does not support the scheduled cleanup", "code": 400})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": cleaner.CleanupStats()})
+	c.Done()
+}
+
 // handleAssistantTags handles getting all assistant tags
 func (neo *DSL) handleAssistantTags(c *gin.Context) {
 	sid := c.GetString("__sid")
-	if sid == "" {
+	if sid == "" || share.IsGuest(sid) {
 		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
 		c.Done()
 		return
@@ -1060,3 +1404,861 @@ func (neo *DSL) handleAssistantTags(c *gin.Context) {
 	c.JSON(200, gin.H{"data": tags})
 	c.Done()
 }
+
+// handleKnowledgeCrawl handles crawling web pages into a knowledge collection
+func (neo *DSL) handleKnowledgeCrawl(c *gin.Context) {
+	var body struct {
+		crawler.Setting
+		Collection string `json:"collection"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(400, gin.H{"message": "invalid request body", "code": 400})
+		c.Done()
+		return
+	}
+
+	if body.Collection == "" {
+		c.JSON(400, gin.H{"message": "collection is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	// The internal addresses are never crawled by the API requests
+	body.Setting.AllowPrivate = false
+
+	res, err := neo.Crawl(c.Request.Context(), body.Collection, body.Setting)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": res})
+	c.Done()
+}
+
+// handleKnowledgeUpload handles uploading a document into a knowledge collection
+func (neo *DSL) handleKnowledgeUpload(c *gin.Context) {
+	collection := c.Query("collection")
+	if collection == "" {
+		collection = c.PostForm("collection")
+	}
+
+	if collection == "" {
+		c.JSON(400, gin.H{"message": "collection is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	tmpfile, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(400, gin.H{"message": err.Error(), "code": 400})
+		c.Done()
+		return
+	}
+
+	reader, err := tmpfile.Open()
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	res, err := neo.Ingest(c.Request.Context(), collection, tmpfile.Filename, tmpfile.Header.Get("Content-Type"), content)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": res})
+	c.Done()
+}
+
+// handleCollectionList handles listing the knowledge collections
+func (neo *DSL) handleCollectionList(c *gin.Context) {
+	collections, err := neo.Collections()
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": collections})
+	c.Done()
+}
+
+// handleCollectionDetail handles getting the knowledge collection settings, the defaults are returned if not saved
+func (neo *DSL) handleCollectionDetail(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		c.JSON(400, gin.H{"message": "collection name is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	collection, err := neo.Collection(name)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": collection})
+	c.Done()
+}
+
+// handleCollectionSave handles creating or updating the knowledge collection settings
+func (neo *DSL) handleCollectionSave(c *gin.Context) {
+	var collection neorag.Collection
+	if err := c.BindJSON(&collection); err != nil {
+		c.JSON(400, gin.H{"message": "invalid request body", "code": 400})
+		c.Done()
+		return
+	}
+
+	if err := collection.Validate(); err != nil {
+		c.JSON(400, gin.H{"message": err.Error(), "code": 400})
+		c.Done()
+		return
+	}
+
+	if err := neo.SaveCollection(collection); err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "ok", "data": collection.Name})
+	c.Done()
+}
+
+// handleCollectionDelete handles deleting the knowledge collection settings
+func (neo *DSL) handleCollectionDelete(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		c.JSON(400, gin.H{"message": "collection name is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	if err := neo.DeleteCollection(name); err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "ok"})
+	c.Done()
+}
+
+// handleMemoryList handles listing the memories of the user
+func (neo *DSL) handleMemoryList(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	filter := store.MemoryFilter{
+		AssistantID: c.Query("assistant_id"),
+		Keywords:    c.Query("keywords"),
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		if n, err := strconv.Atoi(limit); err == nil {
+			filter.Limit = n
+		}
+	}
+
+	memories, err := neo.Store.GetMemories(sid, filter)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": memories})
+	c.Done()
+}
+
+// handleMemorySave handles adding memories of the user manually
+func (neo *DSL) handleMemorySave(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	var body struct {
+		AssistantID string   `json:"assistant_id"`
+		Memories    []string `json:"memories"`
+		TTL         int      `json:"ttl"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(400, gin.H{"message": "invalid request body", "code": 400})
+		c.Done()
+		return
+	}
+
+	if len(body.Memories) == 0 {
+		c.JSON(400, gin.H{"message": "memories are required", "code": 400})
+		c.Done()
+		return
+	}
+
+	ids, err := neo.Store.SaveMemories(sid, body.AssistantID, body.Memories, body.TTL)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "ok", "data": ids})
+	c.Done()
+}
+
+// handleMemoryDelete handles deleting a memory of the user
+func (neo *DSL) handleMemoryDelete(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	memoryID := c.Param("id")
+	if memoryID == "" {
+		c.JSON(400, gin.H{"message": "memory id is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	err := neo.Store.DeleteMemory(sid, memoryID)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "ok"})
+	c.Done()
+}
+
+// handleMemoryClear handles deleting all the memories of the user, of the assistant if given
+func (neo *DSL) handleMemoryClear(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	nums, err := neo.Store.DeleteMemories(sid, c.Query("assistant_id"))
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "ok", "data": nums})
+	c.Done()
+}
+
+// handleProfileDetail handles getting the preferences of the user
+func (neo *DSL) handleProfileDetail(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	profile, err := neo.Store.GetProfile(sid)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": profile})
+	c.Done()
+}
+
+// handleProfileSave handles updating the preferences of the user
+func (neo *DSL) handleProfileSave(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	var profile map[string]interface{}
+	if err := c.BindJSON(&profile); err != nil {
+		c.JSON(400, gin.H{"message": "invalid request body", "code": 400})
+		c.Done()
+		return
+	}
+
+	if timezone, ok := profile["timezone"].(string); ok && timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			c.JSON(400, gin.H{"message": fmt.Sprintf("invalid timezone %s", timezone), "code": 400})
+			c.Done()
+			return
+		}
+	}
+
+	err := neo.Store.SaveProfile(sid, profile)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "ok"})
+	c.Done()
+}
+
+// handleWorkflowRun handles running a workflow of the assistant
+func (neo *DSL) handleWorkflowRun(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	var body struct {
+		Input interface{} `json:"input"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(400, gin.H{"message": "invalid request body", "code": 400})
+			c.Done()
+			return
+		}
+	}
+
+	ast, err := assistant.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"message": err.Error(), "code": 404})
+		c.Done()
+		return
+	}
+
+	// The run is detached from the request, so it is not aborted when the client disconnects
+	run, err := ast.RunWorkflow(context.WithoutCancel(c.Request.Context()), sid, c.Param("name"), body.Input)
+	if err != nil {
+		c.JSON(400, gin.H{"message": err.Error(), "code": 400})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": run})
+	c.Done()
+}
+
+// handleWorkflowRunList handles listing the workflow runs of the user
+func (neo *DSL) handleWorkflowRunList(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	filter := store.WorkflowRunFilter{
+		AssistantID: c.Query("assistant_id"),
+		Status:      c.Query("status"),
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		if n, err := strconv.Atoi(limit); err == nil {
+			filter.Limit = n
+		}
+	}
+
+	runs, err := neo.Store.GetWorkflowRuns(sid, filter)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": runs})
+	c.Done()
+}
+
+// handleWorkflowRunDetail handles getting a workflow run of the user
+func (neo *DSL) handleWorkflowRunDetail(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	run, err := neo.Store.GetWorkflowRun(sid, c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"message": err.Error(), "code": 404})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": run})
+	c.Done()
+}
+
+// handleWorkflowRunResume handles resuming a workflow run, the waiting run requires the approval
+func (neo *DSL) handleWorkflowRunResume(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	var approval *assistant.WorkflowApproval
+	if c.Request.ContentLength > 0 {
+		approval = &assistant.WorkflowApproval{}
+		if err := c.BindJSON(approval); err != nil {
+			c.JSON(400, gin.H{"message": "invalid request body", "code": 400})
+			c.Done()
+			return
+		}
+	}
+
+	data, err := neo.Store.GetWorkflowRun(sid, c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"message": err.Error(), "code": 404})
+		c.Done()
+		return
+	}
+
+	ast, err := assistant.Get(fmt.Sprintf("%v", data["assistant_id"]))
+	if err != nil {
+		c.JSON(404, gin.H{"message": err.Error(), "code": 404})
+		c.Done()
+		return
+	}
+
+	// The waiting run is decided by an approver other than the user, see the approvals.
+	// The run is detached from the request, so it is not aborted when the client disconnects
+	if approval != nil {
+		approval.Approver = sid
+	}
+	run, err := ast.ResumeWorkflow(context.WithoutCancel(c.Request.Context()), sid, c.Param("id"), approval)
+	if err != nil {
+		c.JSON(400, gin.H{"message": err.Error(), "code": 400})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": run})
+	c.Done()
+}
+
+// handleApprovalList handles listing the tool approvals
+func (neo *DSL) handleApprovalList(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	// The approvals requested by the user and the approvals the roles of the user could decide
+	filter := store.ApprovalFilter{
+		Roles:       policy.Roles(sid),
+		AssistantID: c.Query("assistant_id"),
+		ChatID:      c.Query("chat_id"),
+		Status:      c.Query("status"),
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		if n, err := strconv.Atoi(limit); err == nil {
+			filter.Limit = n
+		}
+	}
+
+	approvals, err := neo.Store.GetApprovals(sid, filter)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": approvals})
+	c.Done()
+}
+
+// handleApprovalDetail handles getting a tool approval
+func (neo *DSL) handleApprovalDetail(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	// The approvals are scoped as the list, the context of the requester is not exposed
+	approvals, err := neo.Store.GetApprovals(sid, store.ApprovalFilter{ApprovalID: c.Param("id"), Roles: policy.Roles(sid), Limit: 1})
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	if len(approvals) == 0 {
+		c.JSON(404, gin.H{"message": fmt.Sprintf("approval %s not found", c.Param("id")), "code": 404})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": approvals[0]})
+	c.Done()
+}
+
+// handleApprovalDecide handles accepting or rejecting a tool approval
+func (neo *DSL) handleApprovalDecide(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	var body struct {
+		Approved bool                   `json:"approved"`
+		Comment  string                 `json:"comment"`
+		Data     map[string]interface{} `json:"data"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(400, gin.H{"message": "invalid request body", "code": 400})
+		c.Done()
+		return
+	}
+
+	// Set headers for SSE, the output of the tool is streamed
+	c.Header("Content-Type", "text/event-stream;charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	_, err := assistant.DecideApproval(c, sid, c.Param("id"), body.Approved, body.Comment, body.Data)
+	if err != nil {
+		message.New().Error(err.Error()).Done().Write(c.Writer)
+	}
+	c.Done()
+}
+
+// handleTraceReplay handles replaying the turn of a trace
+func (neo *DSL) handleTraceReplay(c *gin.Context) {
+	mock, _ := strconv.ParseBool(c.Query("mock"))
+
+	// Set headers for SSE, the reply is streamed
+	c.Header("Content-Type", "text/event-stream;charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	_, err := assistant.Replay(c, c.Param("id"), mock)
+	if err != nil {
+		message.New().Error(err.Error()).Done().Write(c.Writer)
+	}
+	c.Done()
+}
+
+// handleCompletions handles the OpenAI compatible chat completions request
+func (neo *DSL) handleCompletions(c *gin.Context) {
+	withCacheControl(c)
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		sid = uuid.New().String()
+	}
+
+	var body map[string]interface{}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(400, assistant.CompletionsError("invalid request body", "invalid_request_error"))
+		c.Done()
+		return
+	}
+
+	model, _ := body["model"].(string)
+	if model == "" {
+		c.JSON(400, assistant.CompletionsError("model is required", "invalid_request_error"))
+		c.Done()
+		return
+	}
+
+	raw, _ := body["messages"].([]interface{})
+	if len(raw) == 0 {
+		c.JSON(400, assistant.CompletionsError("messages is required", "invalid_request_error"))
+		c.Done()
+		return
+	}
+
+	messages := make([]map[string]interface{}, 0, len(raw))
+	for _, item := range raw {
+		msg, ok := item.(map[string]interface{})
+		if !ok {
+			c.JSON(400, assistant.CompletionsError("messages must be an array of objects", "invalid_request_error"))
+			c.Done()
+			return
+		}
+		messages = append(messages, msg)
+	}
+
+	ast, err := assistant.Get(model)
+	if err != nil {
+		c.JSON(404, assistant.CompletionsError(fmt.Sprintf("the model %s does not exist", model), "invalid_request_error"))
+		c.Done()
+		return
+	}
+
+	ctx := chatctx.New(sid, "", "")
+	ctx.AssistantID = ast.ID
+	ctx.Context = c.Request.Context()
+
+	stream, _ := body["stream"].(bool)
+	if !stream {
+		res, err := ast.Completions(ctx, messages, body, nil)
+		if err != nil {
+			c.JSON(500, assistant.CompletionsError(err.Error(), "server_error"))
+			c.Done()
+			return
+		}
+		c.JSON(200, res)
+		c.Done()
+		return
+	}
+
+	// Set headers for SSE
+	c.Header("Content-Type", "text/event-stream;charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	_, err = ast.Completions(ctx, messages, body, func(data []byte) int {
+		if _, err := c.Writer.Write(data); err != nil {
+			return 0 // break
+		}
+		c.Writer.Flush()
+		return 1 // continue
+	})
+	if err != nil {
+		c.Writer.Write(assistant.CompletionsEvent(assistant.CompletionsError(err.Error(), "server_error")))
+		c.Writer.Flush()
+	}
+	c.Done()
+}
+
+// handleMessages handles the Anthropic compatible messages request
+func (neo *DSL) handleMessages(c *gin.Context) {
+	withCacheControl(c)
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		sid = uuid.New().String()
+	}
+
+	var body map[string]interface{}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(400, assistant.MessagesError("invalid request body", "invalid_request_error"))
+		c.Done()
+		return
+	}
+
+	model, _ := body["model"].(string)
+	if model == "" {
+		c.JSON(400, assistant.MessagesError("model is required", "invalid_request_error"))
+		c.Done()
+		return
+	}
+
+	ast, err := assistant.Get(model)
+	if err != nil {
+		c.JSON(404, assistant.MessagesError(fmt.Sprintf("the model %s does not exist", model), "not_found_error"))
+		c.Done()
+		return
+	}
+
+	ctx := chatctx.New(sid, "", "")
+	ctx.AssistantID = ast.ID
+	ctx.Context = c.Request.Context()
+
+	stream, _ := body["stream"].(bool)
+	if !stream {
+		res, err := ast.Messages(ctx, body, nil)
+		if err != nil {
+			c.JSON(500, assistant.MessagesError(err.Error(), "api_error"))
+			c.Done()
+			return
+		}
+		c.JSON(200, res)
+		c.Done()
+		return
+	}
+
+	// Set headers for SSE
+	c.Header("Content-Type", "text/event-stream;charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	_, err = ast.Messages(ctx, body, func(event []byte) int {
+		if _, err := c.Writer.Write(event); err != nil {
+			return 0 // break
+		}
+		c.Writer.Flush()
+		return 1 // continue
+	})
+	if err != nil {
+		c.Writer.Write(assistant.MessagesEvent("error", assistant.MessagesError(err.Error(), "api_error")))
+		c.Writer.Flush()
+	}
+	c.Done()
+}
+
+// handleUserExport handles exporting all the data of the user as a zip archive
+func (neo *DSL) handleUserExport(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	data, err := neo.Store.ExportUserData(sid)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	// One JSON file for each kind of the data
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+	for _, name := range names {
+		raw, err := jsoniter.MarshalIndent(data[name], "", "  ")
+		if err != nil {
+			c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+			c.Done()
+			return
+		}
+
+		w, err := archive.Create(name + ".json")
+		if err == nil {
+			_, err = w.Write(raw)
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+			c.Done()
+			return
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	filename := fmt.Sprintf("neo-export-%s.zip", time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(200, "application/zip", buf.Bytes())
+	c.Done()
+}
+
+// handleUserDeletionDetail handles getting the scheduled deletion of the user data
+func (neo *DSL) handleUserDeletionDetail(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	deletion, err := neo.Store.GetUserDeletion(sid)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"data": deletion})
+	c.Done()
+}
+
+// handleUserDeletionSchedule handles scheduling the hard deletion of all the user data after the grace period
+func (neo *DSL) handleUserDeletionSchedule(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	var body struct {
+		Days *int `json:"days"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(400, gin.H{"message": "invalid request body", "code": 400})
+			c.Done()
+			return
+		}
+	}
+
+	days := 30
+	if body.Days != nil {
+		if *body.Days < 0 {
+			c.JSON(400, gin.H{"message": "days must not be negative", "code": 400})
+			c.Done()
+			return
+		}
+		days = *body.Days
+	}
+
+	scheduledAt, err := neo.Store.ScheduleUserDeletion(sid, time.Duration(days)*24*time.Hour)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "ok", "data": gin.H{"scheduled_at": scheduledAt}})
+	c.Done()
+}
+
+// handleUserDeletionCancel handles canceling the scheduled deletion of the user data
+func (neo *DSL) handleUserDeletionCancel(c *gin.Context) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.JSON(400, gin.H{"message": "sid is required", "code": 400})
+		c.Done()
+		return
+	}
+
+	err := neo.Store.CancelUserDeletion(sid)
+	if err != nil {
+		c.JSON(500, gin.H{"message": err.Error(), "code": 500})
+		c.Done()
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "ok"})
+	c.Done()
+}
@@ -10,8 +10,10 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/fs"
 	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/log"
 	chatctx "github.com/yaoapp/yao/neo/context"
 	chatMessage "github.com/yaoapp/yao/neo/message"
+	"github.com/yaoapp/yao/trace"
 )
 
 // Get get the assistant by id
@@ -46,11 +48,36 @@ func GetByConnector(connector string, name string) (*Assistant, error) {
 
 // Execute implements the execute functionality
 func (ast *Assistant) Execute(c *gin.Context, ctx chatctx.Context, input string, options map[string]interface{}) error {
+	return ast.execute(c, ctx, input, options, 0)
+}
+
+// execute run the assistant, hops is the number of handoffs before this assistant.
+// The turn is traced, the turns of the handoffs are the children of the turn.
+func (ast *Assistant) execute(c *gin.Context, ctx chatctx.Context, input string, options map[string]interface{}, hops int) (err error) {
+	var span *trace.Span
+	ctx.Context, span = trace.Start(ctx.Context, trace.KindTurn, ast.ID, map[string]interface{}{"input": input, "options": options})
+	if span.ParentID == "" {
+		span.Chat(ctx.Sid, ctx.ChatID, ast.ID)
+	}
+	span.Set("hops", hops)
+	defer func() { span.End(nil, err) }()
+
 	messages, err := ast.withHistory(ctx, input)
 	if err != nil {
 		return err
 	}
 
+	// Transfer the conversation to the other assistant if a route matches
+	if hops < maxHandoffs {
+		if handoff := ast.route(c.Request.Context(), messages, input); handoff != nil {
+			return ast.handoff(c, ctx, handoff, input, options, hops)
+		}
+	}
+	count := len(messages)
+	messages = ast.withProfile(ctx, messages)
+	messages = ast.withMemories(ctx, messages, input)
+	messages = ast.withWindow(ctx, messages, len(messages)-count)
+
 	options = ast.withOptions(options)
 
 	// Run init hook
@@ -80,7 +107,7 @@ func (ast *Assistant) Execute(c *gin.Context, ctx chatctx.Context, input string,
 
 	// Handle next action
 	if res != nil && res.Next != nil {
-		return res.Next.Execute(c, ctx)
+		return ast.next(c, ctx, res.Next)
 	}
 
 	// Update options if provided
@@ -116,14 +143,26 @@ func (next *NextAction) Execute(c *gin.Context, ctx chatctx.Context) error {
 			args = v
 		}
 
+		_, span := trace.Start(ctx.Context, trace.KindProcess, name, args)
+		if recorded, ok := trace.ReplayOf(ctx.Context).Take(trace.KindProcess, name, args); ok {
+			span.Set("mocked", true).Set("diverged", recorded.Diverged)
+			span.End(recorded.Output, recorded.Err)
+			if recorded.Err != nil {
+				return fmt.Errorf("execute process error: %s", recorded.Err.Error())
+			}
+			return nil
+		}
+
 		// Add context and writer to args
-		args = append(args, ctx, c.Writer)
+		args = append(append([]interface{}{}, args...), ctx, c.Writer)
 		p, err := process.Of(name, args...)
 		if err != nil {
+			span.End(nil, err)
 			return fmt.Errorf("get process error: %s", err.Error())
 		}
 
 		err = p.Execute()
+		span.End(nil, err)
 		if err != nil {
 			return fmt.Errorf("execute process error: %s", err.Error())
 		}
@@ -177,12 +216,20 @@ func (ast *Assistant) handleChatStream(c *gin.Context, ctx chatctx.Context, mess
 
 	// Chat with AI in background
 	go func() {
+		_, span := trace.Start(ctx.Context, trace.KindLLM, ast.Connector, map[string]interface{}{"messages": messages, "options": options})
 		err := ast.streamChat(c, ctx, messages, options, clientBreak, done, contents)
+		span.End(recordedContents(contents), err)
 		if err != nil {
 			chatMessage.New().Error(err).Done().Write(c.Writer)
 		}
 
-		ast.saveChatHistory(ctx, messages, contents)
+		// The replay does not change the chat and the memories
+		if trace.ReplayOf(ctx.Context) == nil {
+			ast.saveChatHistory(ctx, messages, contents)
+			if len(messages) > 0 {
+				go ast.writeMemories(ctx, messages[len(messages)-1].Text, contents)
+			}
+		}
 		done <- true
 	}()
 
@@ -196,6 +243,23 @@ func (ast *Assistant) handleChatStream(c *gin.Context, ctx chatctx.Context, mess
 	}
 }
 
+// recordedContents the contents recorded by the trace, the bytes are the texts
+func recordedContents(contents *chatMessage.Contents) []map[string]interface{} {
+	res := []map[string]interface{}{}
+	for _, data := range contents.Data {
+		item := map[string]interface{}{"type": data.Type, "text": string(data.Bytes)}
+		if data.ID != "" {
+			item["id"] = data.ID
+		}
+		if data.Function != "" {
+			item["function"] = data.Function
+			item["arguments"] = string(data.Arguments)
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
 // streamChat handles the streaming chat interaction
 func (ast *Assistant) streamChat(
 	c *gin.Context,
@@ -240,7 +304,7 @@ func (ast *Assistant) streamChat(
 				if err == nil && res != nil {
 
 					if res.Next != nil {
-						err = res.Next.Execute(c, ctx)
+						err = ast.next(c, ctx, res.Next)
 						if err != nil {
 							chatMessage.New().Error(err.Error()).Done().Write(c.Writer)
 						}
@@ -283,7 +347,7 @@ func (ast *Assistant) streamChat(
 					}
 
 					if res.Next != nil {
-						err := res.Next.Execute(c, ctx)
+						err := ast.next(c, ctx, res.Next)
 						if err != nil {
 							chatMessage.New().Error(err.Error()).Done().Write(c.Writer)
 						}
@@ -352,8 +416,8 @@ func (ast *Assistant) withOptions(options map[string]interface{}) map[string]int
 		}
 	}
 
-	// Add functions
-	if ast.Functions != nil {
+	// Add functions, the local models without the tools support reject the request with tools
+	if ast.Functions != nil && (ast.openai == nil || ast.openai.Capabilities().Tools) {
 		options["tools"] = ast.Functions
 		if options["tool_choice"] == nil {
 			options["tool_choice"] = "auto"
@@ -363,14 +427,22 @@ func (ast *Assistant) withOptions(options map[string]interface{}) map[string]int
 	return options
 }
 
-func (ast *Assistant) withPrompts(messages []chatMessage.Message) []chatMessage.Message {
+func (ast *Assistant) withPrompts(ctx chatctx.Context, messages []chatMessage.Message) []chatMessage.Message {
 	if ast.Prompts != nil {
+		data := ast.promptData(ctx)
 		for _, prompt := range ast.Prompts {
 			name := ast.Name
 			if prompt.Name != "" {
 				name = prompt.Name
 			}
-			messages = append(messages, *chatMessage.New().Map(map[string]interface{}{"role": prompt.Role, "content": prompt.Content, "name": name}))
+
+			// Keep the prompt as it is if the template is invalid
+			content, err := ast.renderPrompt(prompt.Content, data)
+			if err != nil {
+				log.Warn("[Neo] assistant %s prompt template: %s", ast.ID, err.Error())
+				content = prompt.Content
+			}
+			messages = append(messages, *chatMessage.New().Map(map[string]interface{}{"role": prompt.Role, "content": content, "name": name}))
 		}
 	}
 	return messages
@@ -378,7 +450,7 @@ func (ast *Assistant) withPrompts(messages []chatMessage.Message) []chatMessage.
 
 func (ast *Assistant) withHistory(ctx chatctx.Context, input string) ([]chatMessage.Message, error) {
 	messages := []chatMessage.Message{}
-	messages = ast.withPrompts(messages)
+	messages = ast.withPrompts(ctx, messages)
 	if storage != nil {
 		history, err := storage.GetHistory(ctx.Sid, ctx.ChatID)
 		if err != nil {
@@ -407,7 +479,7 @@ func (ast *Assistant) Chat(ctx context.Context, messages []chatMessage.Message,
 		return fmt.Errorf("request messages error: %s", err.Error())
 	}
 
-	_, ext := ast.openai.ChatCompletionsWith(ctx, requestMessages, option, cb)
+	_, ext := ast.chatCompletions(ctx, requestMessages, option, cb)
 	if ext != nil {
 		return fmt.Errorf("openai chat completions with error: %s", ext.Message)
 	}
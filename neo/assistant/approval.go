@@ -0,0 +1,289 @@
+package assistant
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/log"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	chatMessage "github.com/yaoapp/yao/neo/message"
+	"github.com/yaoapp/yao/policy"
+	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/trace"
+)
+
+// The status of the tool approval
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// DefaultApprovers the roles of the approvers of the tools without the approvers
+var DefaultApprovers = []string{"admin"}
+
+// ToolPolicy the execution policy of the tool
+type ToolPolicy struct {
+	Name      string   `json:"name" yaml:"name"`                               // The process name, supports the wildcard, e.g. models.*.Delete
+	Approval  bool     `json:"approval,omitempty" yaml:"approval,omitempty"`   // Require the human approval before the execution
+	Approvers []string `json:"approvers,omitempty" yaml:"approvers,omitempty"` // The roles of the approvers, the roles of the session are read by the field of the policies, default is admin
+	Message   string   `json:"message,omitempty" yaml:"message,omitempty"`     // The message shown to the approver, the tool and the args are available, e.g. Delete the user {{ args[0] }}?
+}
+
+// toolPolicy the first policy matches the tool, nil if not found
+func (ast *Assistant) toolPolicy(name string) *ToolPolicy {
+	for i, policy := range ast.ToolPolicies {
+		if policy.Name == name {
+			return &ast.ToolPolicies[i]
+		}
+		if matched, err := path.Match(policy.Name, name); err == nil && matched {
+			return &ast.ToolPolicies[i]
+		}
+	}
+	return nil
+}
+
+// requiresApproval whether the tool requires the human approval
+func (ast *Assistant) requiresApproval(name string) bool {
+	policy := ast.toolPolicy(name)
+	return policy != nil && policy.Approval
+}
+
+// approvers the roles of the approvers of the tool
+func (ast *Assistant) approvers(name string) []string {
+	policy := ast.toolPolicy(name)
+	if policy == nil || len(policy.Approvers) == 0 {
+		return DefaultApprovers
+	}
+	return policy.Approvers
+}
+
+// checkApprover check the session could decide the approval, the approver is a signed-in user other than the requester
+// and has one of the roles of the approvers
+func checkApprover(sid string, requester string, approvers []string) error {
+	if sid == "" || share.IsGuest(sid) {
+		return fmt.Errorf("the approver is not signed in")
+	}
+
+	if sid == requester {
+		return fmt.Errorf("the approval could not be decided by the requester")
+	}
+
+	for _, role := range policy.Roles(sid) {
+		for _, approver := range approvers {
+			if role == approver {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("the approval could only be decided by the roles %s", strings.Join(approvers, ", "))
+}
+
+// next execute the next action of the hooks, the process requires the approval is paused until the approver decides
+func (ast *Assistant) next(c *gin.Context, ctx chatctx.Context, next *NextAction) error {
+	if next.Action == "process" && next.Payload != nil {
+		name, _ := next.Payload["name"].(string)
+		if ast.requiresApproval(name) {
+			args, _ := next.Payload["args"].([]interface{})
+			return ast.requestApproval(c, ctx, name, args)
+		}
+	}
+	return next.Execute(c, ctx)
+}
+
+// requestApproval save the pending approval and end the turn with an approval request
+func (ast *Assistant) requestApproval(c *gin.Context, ctx chatctx.Context, name string, args []interface{}) error {
+	if storage == nil {
+		return fmt.Errorf("tool %s requires the approval, but the storage is not set", name)
+	}
+
+	text := ast.approvalMessage(name, args)
+	id, err := storage.SaveApproval(ctx.Sid, map[string]interface{}{
+		"assistant_id": ast.ID,
+		"chat_id":      ctx.ChatID,
+		"tool":         name,
+		"args":         args,
+		"context":      ctx.Map(),
+		"message":      text,
+		"approvers":    ast.approvers(name),
+	})
+	if err != nil {
+		return fmt.Errorf("save the approval of %s error: %s", name, err.Error())
+	}
+
+	msg := chatMessage.New().Assistant(ast.ID, ast.Name, ast.Avatar)
+	msg.Type = "approval"
+	msg.Text = text
+	msg.Props = map[string]interface{}{"approval_id": id, "tool": name, "args": args, "status": ApprovalPending}
+	msg.Done().Write(c.Writer)
+	ast.saveMessage(ctx, msg)
+	return nil
+}
+
+// approvalMessage the message shown to the approver
+func (ast *Assistant) approvalMessage(name string, args []interface{}) string {
+	policy := ast.toolPolicy(name)
+	if policy == nil || policy.Message == "" {
+		raw, _ := jsoniter.MarshalToString(args)
+		return fmt.Sprintf("Run %s with %s?", name, raw)
+	}
+
+	text, err := workflowString(policy.Message, map[string]interface{}{"tool": name, "args": args})
+	if err != nil {
+		log.Warn("[Neo] assistant %s approval message of %s: %s", ast.ID, name, err.Error())
+		return policy.Message
+	}
+	return text
+}
+
+// DecideApproval accept or reject the pending approval of the tool, the approver is checked by the roles of the approvers.
+// The accepted tool is executed with the context of the requester, the output is written to the writer of the approver.
+// The decision and the result are recorded in the chat history of the requester, then the turn of the requester is resumed
+// with the result, so the assistant replies to it. The approval of a workflow run resumes the run with the decision and the data.
+func DecideApproval(c *gin.Context, sid string, approvalID string, approved bool, comment string, data map[string]interface{}) (map[string]interface{}, error) {
+	if storage == nil {
+		return nil, fmt.Errorf("storage is not set")
+	}
+
+	approval, err := storage.GetApproval(approvalID)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := jsoniter.MarshalToString(approval["context"])
+	if err != nil {
+		return nil, err
+	}
+	ctx := chatctx.New("", "", raw)
+
+	approvers := DefaultApprovers
+	switch v := approval["approvers"].(type) {
+	case []string:
+		if len(v) > 0 {
+			approvers = v
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			approvers = []string{}
+			for _, role := range v {
+				approvers = append(approvers, fmt.Sprintf("%v", role))
+			}
+		}
+	}
+
+	if err := checkApprover(sid, ctx.Sid, approvers); err != nil {
+		return nil, err
+	}
+
+	err = storage.DecideApproval(approvalID, sid, approved, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, err := Get(fmt.Sprintf("%v", approval["assistant_id"]))
+	if err != nil {
+		return nil, err
+	}
+
+	if info, ok := approval["context"].(map[string]interface{}); ok && info["run_id"] != nil {
+		return ast.decideWorkflow(c, ctx.Sid, fmt.Sprintf("%v", info["run_id"]), approvalID, &WorkflowApproval{Approved: approved, Comment: comment, Data: data, Approver: sid})
+	}
+
+	tool := fmt.Sprintf("%v", approval["tool"])
+	res := map[string]interface{}{"approval_id": approvalID, "tool": tool, "status": ApprovalRejected}
+	msg := chatMessage.New().Assistant(ast.ID, ast.Name, ast.Avatar)
+	msg.Type = "approval"
+	msg.Text = fmt.Sprintf("%s is rejected", tool)
+	if approved {
+		res["status"] = ApprovalApproved
+		msg.Text = fmt.Sprintf("%s is approved", tool)
+
+		args, _ := approval["args"].([]interface{})
+		result, err := ast.executeTool(c, ctx, tool, args)
+		if err != nil {
+			res["error"] = err.Error()
+			msg.Text = fmt.Sprintf("%s is approved, but failed: %s", tool, err.Error())
+		} else {
+			res["result"] = result
+		}
+	}
+
+	if comment != "" {
+		msg.Text = fmt.Sprintf("%s. %s", msg.Text, comment)
+	}
+	msg.Props = res
+	msg.Done().Write(c.Writer)
+	ast.saveMessage(ctx, msg)
+
+	if err := ast.resume(c, ctx, res, msg.Text); err != nil {
+		log.Error("[Neo] assistant %s resume the turn of the approval %s: %s", ast.ID, approvalID, err.Error())
+	}
+	return res, nil
+}
+
+// decideWorkflow resume the workflow run paused by the approval, the run is detached from the request,
+// so it is not aborted when the approver disconnects
+func (ast *Assistant) decideWorkflow(c *gin.Context, sid string, runID string, approvalID string, approval *WorkflowApproval) (map[string]interface{}, error) {
+	run, err := ast.ResumeWorkflow(context.WithoutCancel(c.Request.Context()), sid, runID, approval)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]interface{}{"approval_id": approvalID, "run_id": run.ID, "status": ApprovalRejected, "run": run.Status}
+	if approval.Approved {
+		res["status"] = ApprovalApproved
+	}
+
+	msg := chatMessage.New().Assistant(ast.ID, ast.Name, ast.Avatar)
+	msg.Type = "approval"
+	msg.Text = fmt.Sprintf("workflow %s is %s", run.Workflow, run.Status)
+	msg.Props = res
+	msg.Done().Write(c.Writer)
+	return res, nil
+}
+
+// resume continue the turn paused by the approval, the decision and the result of the tool are the input of the turn
+func (ast *Assistant) resume(c *gin.Context, ctx chatctx.Context, res map[string]interface{}, text string) error {
+	if ast.openai == nil {
+		return nil
+	}
+
+	input := text
+	if result, has := res["result"]; has && result != nil {
+		raw, err := jsoniter.MarshalToString(result)
+		if err != nil {
+			return err
+		}
+		input = fmt.Sprintf("%s, the result: %s", text, raw)
+	}
+
+	messages, err := ast.withHistory(ctx, input)
+	if err != nil {
+		return err
+	}
+	return ast.handleChatStream(c, ctx, messages, ast.withOptions(nil))
+}
+
+// executeTool execute the process, the context and the writer are appended to the args as the process action does
+func (ast *Assistant) executeTool(c *gin.Context, ctx chatctx.Context, name string, args []interface{}) (res interface{}, err error) {
+	_, span := trace.Start(ctx.Context, trace.KindTool, name, args)
+	defer func() { span.End(res, err) }()
+
+	// The replay returns the output recorded
+	if recorded, ok := trace.ReplayOf(ctx.Context).Take(trace.KindTool, name, args); ok {
+		span.Set("mocked", true).Set("diverged", recorded.Diverged)
+		return recorded.Output, recorded.Err
+	}
+
+	args = append(append([]interface{}{}, args...), ctx, c.Writer)
+	p, err := process.Of(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	return p.WithSID(ctx.Sid).Exec()
+}
@@ -0,0 +1,146 @@
+package assistant
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/session"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	"github.com/yaoapp/yao/share"
+)
+
+func TestToolPolicy(t *testing.T) {
+	ast := &Assistant{ID: "test", ToolPolicies: []ToolPolicy{
+		{Name: "models.*.Delete", Approval: true, Message: "Delete {{ args[0] }} with {{ tool }}?"},
+		{Name: "scripts.mail.Send", Approval: true},
+		{Name: "models.user.Find"},
+	}}
+
+	assert.Equal(t, DefaultApprovers, ast.approvers("models.user.Delete"))
+	assert.True(t, ast.requiresApproval("models.user.Delete"))
+	assert.True(t, ast.requiresApproval("scripts.mail.Send"))
+	assert.False(t, ast.requiresApproval("models.user.Find"))
+	assert.False(t, ast.requiresApproval("models.user.Save"))
+
+	assert.Equal(t, "Delete 1 with models.user.Delete?", ast.approvalMessage("models.user.Delete", []interface{}{1}))
+	assert.Equal(t, `Run scripts.mail.Send with ["max@example.com"]?`, ast.approvalMessage("scripts.mail.Send", []interface{}{"max@example.com"}))
+}
+
+func TestToolApproval(t *testing.T) {
+	SetStorage(&mockStore{data: map[string]map[string]interface{}{}})
+	defer SetStorage(nil)
+
+	ast := &Assistant{ID: "approval.test", Name: "Approval", ToolPolicies: []ToolPolicy{{Name: "scripts.mail.Send", Approval: true}}}
+	loaded.Put(ast)
+	defer loaded.Remove(ast.ID)
+
+	c, w := testGinContext()
+	ctx := chatctx.New("requester", "chat_1", "")
+	err := ast.next(c, ctx, &NextAction{Action: "process", Payload: map[string]interface{}{"name": "scripts.mail.Send", "args": []interface{}{"max@example.com"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, w.Body.String(), `"type":"approval"`)
+
+	approval, err := storage.GetApproval("approval_0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ApprovalPending, approval["status"])
+	assert.Equal(t, "chat_1", approval["chat_id"])
+
+	// The approver is a signed-in user with the roles of the approvers, other than the requester
+	session.Global().Expire(time.Minute).ID("approver").Set("roles", []string{"admin"})
+	session.Global().Expire(time.Minute).ID("requester").Set("roles", []string{"admin"})
+	session.Global().Expire(time.Minute).ID("user").Set("roles", []string{"user"})
+
+	_, err = DecideApproval(c, "requester", "approval_0", true, "", nil)
+	assert.Error(t, err)
+	_, err = DecideApproval(c, "user", "approval_0", true, "", nil)
+	assert.Error(t, err)
+	_, err = DecideApproval(c, share.GuestSID(), "approval_0", true, "", nil)
+	assert.Error(t, err)
+	_, err = DecideApproval(c, "", "approval_0", true, "", nil)
+	assert.Error(t, err)
+	assert.Equal(t, ApprovalPending, approval["status"])
+
+	// Reject
+	c, w = testGinContext()
+	res, err := DecideApproval(c, "approver", "approval_0", false, "not now", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ApprovalRejected, res["status"])
+	assert.Contains(t, w.Body.String(), "not now")
+
+	// Already decided
+	_, err = DecideApproval(c, "approver", "approval_0", true, "", nil)
+	assert.Error(t, err)
+}
+
+func TestWorkflowToolApproval(t *testing.T) {
+	SetStorage(&mockStore{data: map[string]map[string]interface{}{}})
+	defer SetStorage(nil)
+
+	process.Register("unit.test.neo.approval.echo", func(process *process.Process) interface{} {
+		return process.Args
+	})
+
+	ast := &Assistant{
+		ID:           "test",
+		ToolPolicies: []ToolPolicy{{Name: "unit.test.neo.approval.*", Approval: true}},
+		Flows: []map[string]interface{}{{
+			"name": "send",
+			"steps": []interface{}{
+				map[string]interface{}{"id": "echo", "type": "tool", "process": "unit.test.neo.approval.echo", "args": []interface{}{"{{ input }}"}},
+			},
+		}},
+	}
+
+	loaded.Put(ast)
+	defer loaded.Remove(ast.ID)
+
+	run, err := ast.RunWorkflow(context.Background(), "sid", "send", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WorkflowWaiting, run.Status, run.Error)
+	assert.Equal(t, "echo", run.Step)
+
+	// The run is decided by the approval of the approver, the requester could not decide it
+	session.Global().Expire(time.Minute).ID("approver").Set("roles", []string{"admin"})
+	approvalID := run.State["echo"].(map[string]interface{})["approval_id"].(string)
+	c, _ := testGinContext()
+	_, err = DecideApproval(c, "sid", approvalID, true, "", nil)
+	assert.Error(t, err)
+
+	res, err := DecideApproval(c, "approver", approvalID, true, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WorkflowCompleted, res["run"])
+
+	data, err := storage.GetWorkflowRun("sid", run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err = workflowRunOf(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WorkflowCompleted, run.Status, run.Error)
+	assert.Equal(t, []interface{}{"hello"}, run.State["echo"])
+}
+
+func testGinContext() (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/", strings.NewReader(""))
+	return c, w
+}
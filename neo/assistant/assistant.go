@@ -120,24 +120,26 @@ func (ast *Assistant) Map() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"assistant_id": ast.ID,
-		"type":         ast.Type,
-		"name":         ast.Name,
-		"readonly":     ast.Readonly,
-		"avatar":       ast.Avatar,
-		"connector":    ast.Connector,
-		"path":         ast.Path,
-		"built_in":     ast.BuiltIn,
-		"sort":         ast.Sort,
-		"description":  ast.Description,
-		"options":      ast.Options,
-		"prompts":      ast.Prompts,
-		"functions":    ast.Functions,
-		"tags":         ast.Tags,
-		"mentionable":  ast.Mentionable,
-		"automated":    ast.Automated,
-		"created_at":   timeToMySQLFormat(ast.CreatedAt),
-		"updated_at":   timeToMySQLFormat(ast.UpdatedAt),
+		"assistant_id":  ast.ID,
+		"type":          ast.Type,
+		"name":          ast.Name,
+		"readonly":      ast.Readonly,
+		"avatar":        ast.Avatar,
+		"connector":     ast.Connector,
+		"path":          ast.Path,
+		"built_in":      ast.BuiltIn,
+		"sort":          ast.Sort,
+		"description":   ast.Description,
+		"options":       ast.Options,
+		"prompts":       ast.Prompts,
+		"functions":     ast.Functions,
+		"routes":        ast.Routes,
+		"tool_policies": ast.ToolPolicies,
+		"tags":          ast.Tags,
+		"mentionable":   ast.Mentionable,
+		"automated":     ast.Automated,
+		"created_at":    timeToMySQLFormat(ast.CreatedAt),
+		"updated_at":    timeToMySQLFormat(ast.UpdatedAt),
 	}
 }
 
@@ -198,6 +200,44 @@ func (ast *Assistant) Clone() *Assistant {
 		copy(clone.Prompts, ast.Prompts)
 	}
 
+	// Deep copy routes
+	if ast.Routes != nil {
+		clone.Routes = make([]Route, len(ast.Routes))
+		copy(clone.Routes, ast.Routes)
+	}
+
+	// Deep copy tool policies
+	if ast.ToolPolicies != nil {
+		clone.ToolPolicies = make([]ToolPolicy, len(ast.ToolPolicies))
+		copy(clone.ToolPolicies, ast.ToolPolicies)
+	}
+
+	// Copy context window
+	if ast.Context != nil {
+		window := *ast.Context
+		clone.Context = &window
+	}
+
+	// Copy memory setting
+	if ast.Memory != nil {
+		memory := *ast.Memory
+		clone.Memory = &memory
+	}
+
+	// Copy cache setting
+	if ast.Cache != nil {
+		cache := *ast.Cache
+		clone.Cache = &cache
+	}
+
+	// Deep copy partials
+	if ast.Partials != nil {
+		clone.Partials = make(map[string]string, len(ast.Partials))
+		for k, v := range ast.Partials {
+			clone.Partials[k] = v
+		}
+	}
+
 	// Deep copy flows
 	if ast.Flows != nil {
 		clone.Flows = make([]map[string]interface{}, len(ast.Flows))
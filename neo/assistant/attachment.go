@@ -14,6 +14,8 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/fs"
 	"github.com/yaoapp/gou/rag/driver"
+	"github.com/yaoapp/yao/neo/document"
+	neorag "github.com/yaoapp/yao/neo/rag"
 )
 
 // AllowedFileTypes the allowed file types
@@ -122,8 +124,10 @@ func (ast *Assistant) handleRAG(ctx context.Context, file *File, reader io.Reade
 		return nil
 	}
 
-	// Only handle text-based files
-	if !strings.HasPrefix(file.ContentType, "text/") {
+	// Only handle the structured documents and the text-based files, the plain text files are uploaded as they are
+	typ := document.TypeOf(file.Filename, file.ContentType)
+	structured := typ != "" && typ != "txt"
+	if !structured && !strings.HasPrefix(file.ContentType, "text/") {
 		return nil
 	}
 
@@ -148,18 +152,15 @@ func (ast *Assistant) handleRAG(ctx context.Context, file *File, reader io.Reade
 		}
 	}
 
-	// Check if index exists
-	exists, err := rag.Engine.HasIndex(ctx, indexName)
+	// Create index if not exists
+	err := neorag.EnsureIndex(ctx, rag.Engine, indexName)
 	if err != nil {
-		return fmt.Errorf("check index error: %s", err.Error())
+		return err
 	}
 
-	// Create index if not exists
-	if !exists {
-		err = rag.Engine.CreateIndex(ctx, driver.IndexConfig{Name: indexName})
-		if err != nil {
-			return fmt.Errorf("create index error: %s", err.Error())
-		}
+	// Parse the structured document and index the chunks by sections
+	if structured {
+		return ast.indexDocument(ctx, file, indexName)
 	}
 
 	// Reset reader again after checking index
@@ -195,6 +196,53 @@ func (ast *Assistant) handleRAG(ctx context.Context, file *File, reader io.Reade
 	return nil
 }
 
+// indexDocument parses the uploaded document and indexes the chunks split by sections
+func (ast *Assistant) indexDocument(ctx context.Context, file *File, indexName string) error {
+	data, err := fs.Get("data")
+	if err != nil {
+		return fmt.Errorf("get filesystem error: %s", err.Error())
+	}
+
+	content, err := data.ReadFile(file.ID)
+	if err != nil {
+		return fmt.Errorf("read file error: %s", err.Error())
+	}
+
+	doc, err := document.Parse(file.Filename, file.ContentType, content)
+	if err != nil {
+		return err
+	}
+
+	// The settings of the collection named by the assistant id
+	collection := neorag.Collection{Name: ast.ID}
+	if collectionOf != nil {
+		collection, err = collectionOf(ast.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if collection.Chunk.Size == 0 {
+		collection.Chunk.Size = 1024 // Default chunk size
+		if collection.Chunk.Overlap == 0 {
+			collection.Chunk.Overlap = 256 // Default overlap
+		}
+	}
+
+	docID := fmt.Sprintf("file_%x", sha256.Sum256([]byte(file.ID)))[:21]
+	ids, err := neorag.IndexDocument(ctx, rag.Engine, indexName, docID, doc, collection, map[string]interface{}{
+		"file_id":      file.ID,
+		"content_type": file.ContentType,
+		"assistant_id": ast.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	file.DocIDs = ids
+	return nil
+}
+
 // handleVision handles the file with Vision if available
 func (ast *Assistant) handleVision(ctx context.Context, file *File, option map[string]interface{}) error {
 
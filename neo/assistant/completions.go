@@ -0,0 +1,137 @@
+package assistant
+
+import (
+	"bytes"
+	"fmt"
+
+	jsoniter "github.com/json-iterator/go"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	chatMessage "github.com/yaoapp/yao/neo/message"
+)
+
+// Completions answer the OpenAI compatible chat completions request, the model of the request is the assistant ID.
+// The prompts, the profile and the memories of the assistant are prepended to the messages of the client,
+// the tools of the client take precedence over the functions of the assistant, and the tool calls are returned to the client.
+// The response is streamed to the cb if it is set, the model of the chunks is replaced with the assistant ID.
+func (ast *Assistant) Completions(ctx chatctx.Context, messages []map[string]interface{}, options map[string]interface{}, cb func(data []byte) int) (interface{}, error) {
+	if ast.openai == nil {
+		return nil, fmt.Errorf("openai is not initialized")
+	}
+
+	system := ast.withPrompts(ctx, []chatMessage.Message{})
+	system = ast.withProfile(ctx, system)
+	system = ast.withMemories(ctx, system, completionsInput(messages))
+	requestMessages, err := ast.requestMessages(ctx, system)
+	if err != nil {
+		return nil, fmt.Errorf("request messages error: %s", err.Error())
+	}
+	requestMessages = append(requestMessages, messages...)
+
+	// The options of the client override the options of the assistant
+	payload := ast.withOptions(nil)
+	for key, value := range options {
+		switch key {
+		case "model", "messages", "stream":
+			continue
+		}
+		payload[key] = value
+	}
+	if _, has := options["tools"]; has && options["tool_choice"] == nil {
+		delete(payload, "tool_choice")
+	}
+
+	if cb == nil {
+		res, ext := ast.chatCompletions(ctx, requestMessages, payload, nil)
+		if ext != nil {
+			return nil, fmt.Errorf("openai chat completions with error: %s", ext.Message)
+		}
+		if data, ok := res.(map[string]interface{}); ok {
+			data["model"] = ast.ID
+		}
+		return res, nil
+	}
+
+	_, ext := ast.chatCompletions(ctx, requestMessages, payload, func(data []byte) int {
+		chunk, done := completionsChunk(data, ast.ID)
+		if chunk == nil {
+			return 1 // continue
+		}
+		if cb(chunk) == 0 || done {
+			return 0 // break
+		}
+		return 1 // continue
+	})
+	if ext != nil {
+		return nil, fmt.Errorf("openai chat completions with error: %s", ext.Message)
+	}
+	return nil, nil
+}
+
+// completionsChunk convert the line of the upstream stream to the server-sent event of the client, nil if the line is skipped.
+// The line is not a data line means the upstream returns an error, it is converted to an error event and ends the stream.
+func completionsChunk(data []byte, model string) ([]byte, bool) {
+	line := bytes.TrimSpace(data)
+	if len(line) == 0 {
+		return nil, false
+	}
+
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return CompletionsEvent(CompletionsError(string(line), "upstream_error")), true
+	}
+
+	line = bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+	if string(line) == "[DONE]" {
+		return []byte("data: [DONE]\n\n"), true
+	}
+
+	var chunk map[string]interface{}
+	if err := jsoniter.Unmarshal(line, &chunk); err != nil {
+		return CompletionsEvent(CompletionsError(string(line), "upstream_error")), true
+	}
+
+	if _, has := chunk["error"]; has {
+		return CompletionsEvent(chunk), true
+	}
+
+	chunk["model"] = model
+	return CompletionsEvent(chunk), false
+}
+
+// completionsInput the text of the last user message, used to pick the relevant memories
+func completionsInput(messages []map[string]interface{}) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i]["role"] != "user" {
+			continue
+		}
+
+		switch content := messages[i]["content"].(type) {
+		case string:
+			return content
+
+		case []interface{}:
+			for _, part := range content {
+				if part, ok := part.(map[string]interface{}); ok && part["type"] == "text" {
+					if text, ok := part["text"].(string); ok {
+						return text
+					}
+				}
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// CompletionsError the error of the OpenAI compatible response
+func CompletionsError(message string, typ string) map[string]interface{} {
+	return map[string]interface{}{"error": map[string]interface{}{"message": message, "type": typ}}
+}
+
+// CompletionsEvent the server-sent event of the OpenAI compatible stream
+func CompletionsEvent(data interface{}) []byte {
+	raw, err := jsoniter.Marshal(data)
+	if err != nil {
+		raw, _ = jsoniter.Marshal(CompletionsError(err.Error(), "server_error"))
+	}
+	return []byte(fmt.Sprintf("data: %s\n\n", raw))
+}
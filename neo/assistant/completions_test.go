@@ -0,0 +1,41 @@
+package assistant
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompletionsChunk(t *testing.T) {
+	chunk, done := completionsChunk([]byte(`data: {"id":"1","model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Hi"}}]}`), "test")
+	assert.False(t, done)
+	assert.True(t, strings.HasPrefix(string(chunk), "data: ") && strings.HasSuffix(string(chunk), "\n\n"))
+	assert.JSONEq(t, `{"id":"1","model":"test","choices":[{"index":0,"delta":{"content":"Hi"}}]}`, strings.TrimSpace(strings.TrimPrefix(string(chunk), "data: ")))
+
+	chunk, done = completionsChunk([]byte(""), "test")
+	assert.False(t, done)
+	assert.Nil(t, chunk)
+
+	chunk, done = completionsChunk([]byte("data: [DONE]"), "test")
+	assert.True(t, done)
+	assert.Equal(t, "data: [DONE]\n\n", string(chunk))
+
+	chunk, done = completionsChunk([]byte(`{"error":{"message":"Invalid key"}}`), "test")
+	assert.True(t, done)
+	assert.Contains(t, string(chunk), `"type":"upstream_error"`)
+}
+
+func TestCompletionsInput(t *testing.T) {
+	assert.Equal(t, "", completionsInput(nil))
+	assert.Equal(t, "Hello", completionsInput([]map[string]interface{}{
+		{"role": "user", "content": "Hello"},
+		{"role": "assistant", "content": "Hi"},
+	}))
+	assert.Equal(t, "What is this?", completionsInput([]map[string]interface{}{
+		{"role": "user", "content": []interface{}{
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/a.png"}},
+			map[string]interface{}{"type": "text", "text": "What is this?"},
+		}},
+	}))
+}
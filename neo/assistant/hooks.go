@@ -9,6 +9,7 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	chatctx "github.com/yaoapp/yao/neo/context"
 	"github.com/yaoapp/yao/neo/message"
+	"github.com/yaoapp/yao/trace"
 )
 
 // HookInit initialize the assistant
@@ -244,7 +245,7 @@ func (ast *Assistant) createTimeoutContext(c *gin.Context) (context.Context, con
 }
 
 // Call the script method
-func (ast *Assistant) call(ctx context.Context, method string, context chatctx.Context, args ...any) (interface{}, error) {
+func (ast *Assistant) call(ctx context.Context, method string, context chatctx.Context, args ...any) (res interface{}, err error) {
 	if ast.Script == nil {
 		return nil, nil
 	}
@@ -260,6 +261,17 @@ func (ast *Assistant) call(ctx context.Context, method string, context chatctx.C
 		return nil, fmt.Errorf(HookErrorMethodNotFound)
 	}
 
+	// The stream hook is called by every chunk, it is not traced
+	if method != "Stream" {
+		_, span := trace.Start(context.Context, trace.KindHook, method, nil)
+		defer func() { span.End(res, err) }()
+
+		if recorded, ok := trace.ReplayOf(context.Context).Take(trace.KindHook, method, nil); ok {
+			span.Set("mocked", true)
+			return recorded.Output, recorded.Err
+		}
+	}
+
 	// Call the method directly in the current thread
 	args = append([]interface{}{context.Map()}, args...)
 	if scriptCtx != nil {
@@ -12,6 +12,7 @@ import (
 	"github.com/yaoapp/gou/fs"
 	"github.com/yaoapp/gou/rag/driver"
 	v8 "github.com/yaoapp/gou/runtime/v8"
+	neorag "github.com/yaoapp/yao/neo/rag"
 	"github.com/yaoapp/yao/neo/store"
 	neovision "github.com/yaoapp/yao/neo/vision"
 	"github.com/yaoapp/yao/openai"
@@ -25,6 +26,7 @@ var storage store.Store = nil
 var rag *RAG = nil
 var vision *neovision.Vision = nil
 var defaultConnector string = "" // default connector
+var collectionOf func(name string) (neorag.Collection, error) = nil
 
 // LoadBuiltIn load the built-in assistants
 func LoadBuiltIn() error {
@@ -127,6 +129,12 @@ func SetConnector(c string) {
 	defaultConnector = c
 }
 
+// SetCollection set the getter of the knowledge collection settings,
+// the documents uploaded to the assistant are chunked by the settings of the collection named by the assistant id
+func SetCollection(getter func(name string) (neorag.Collection, error)) {
+	collectionOf = getter
+}
+
 // SetRAG set the RAG engine
 // e: the RAG engine
 // u: the RAG file uploader
@@ -238,6 +246,18 @@ func LoadPath(path string) (*Assistant, error) {
 		updatedAt = ts
 	}
 
+	// prompt partials
+	partialsdir := filepath.Join(path, "partials")
+	if has, _ := app.Exists(partialsdir); has {
+		partials, ts, err := loadPartials(partialsdir)
+		if err != nil {
+			return nil, err
+		}
+		updatedAt = max(updatedAt, ts)
+		data["partials"] = partials
+		data["updated_at"] = updatedAt
+	}
+
 	// load script
 	scriptfile := filepath.Join(path, "src", "index.ts")
 	if has, _ := app.Exists(scriptfile); has {
@@ -354,6 +374,19 @@ func loadMap(data map[string]interface{}) (*Assistant, error) {
 		assistant.Prompts = prompts
 	}
 
+	// partials
+	if v, has := data["partials"]; has {
+		switch vv := v.(type) {
+		case map[string]string:
+			assistant.Partials = vv
+		case map[string]interface{}:
+			assistant.Partials = map[string]string{}
+			for name, partial := range vv {
+				assistant.Partials[name] = fmt.Sprint(partial)
+			}
+		}
+	}
+
 	// functions
 	if funcs, has := data["functions"]; has {
 		switch vv := funcs.(type) {
@@ -373,6 +406,101 @@ func loadMap(data map[string]interface{}) (*Assistant, error) {
 		}
 	}
 
+	// routes
+	if v, has := data["routes"]; has && v != nil {
+		switch vv := v.(type) {
+		case []Route:
+			assistant.Routes = vv
+		default:
+			raw, err := jsoniter.Marshal(vv)
+			if err != nil {
+				return nil, err
+			}
+			var routes []Route
+			err = jsoniter.Unmarshal(raw, &routes)
+			if err != nil {
+				return nil, err
+			}
+			assistant.Routes = routes
+		}
+	}
+
+	// tool policies
+	if v, has := data["tool_policies"]; has && v != nil {
+		switch vv := v.(type) {
+		case []ToolPolicy:
+			assistant.ToolPolicies = vv
+		default:
+			raw, err := jsoniter.Marshal(vv)
+			if err != nil {
+				return nil, err
+			}
+			var policies []ToolPolicy
+			err = jsoniter.Unmarshal(raw, &policies)
+			if err != nil {
+				return nil, err
+			}
+			assistant.ToolPolicies = policies
+		}
+	}
+
+	// context window
+	if v, has := data["context"]; has && v != nil {
+		switch vv := v.(type) {
+		case *ContextWindow:
+			assistant.Context = vv
+		default:
+			raw, err := jsoniter.Marshal(vv)
+			if err != nil {
+				return nil, err
+			}
+			var window ContextWindow
+			err = jsoniter.Unmarshal(raw, &window)
+			if err != nil {
+				return nil, err
+			}
+			assistant.Context = &window
+		}
+	}
+
+	// memory
+	if v, has := data["memory"]; has && v != nil {
+		switch vv := v.(type) {
+		case *MemorySetting:
+			assistant.Memory = vv
+		default:
+			raw, err := jsoniter.Marshal(vv)
+			if err != nil {
+				return nil, err
+			}
+			var memory MemorySetting
+			err = jsoniter.Unmarshal(raw, &memory)
+			if err != nil {
+				return nil, err
+			}
+			assistant.Memory = &memory
+		}
+	}
+
+	// cache
+	if v, has := data["cache"]; has && v != nil {
+		switch vv := v.(type) {
+		case *CacheSetting:
+			assistant.Cache = vv
+		default:
+			raw, err := jsoniter.Marshal(vv)
+			if err != nil {
+				return nil, err
+			}
+			var cache CacheSetting
+			err = jsoniter.Unmarshal(raw, &cache)
+			if err != nil {
+				return nil, err
+			}
+			assistant.Cache = &cache
+		}
+	}
+
 	// script
 	if data["script"] != nil {
 		switch v := data["script"].(type) {
@@ -478,6 +606,42 @@ func loadPrompts(file string, root string) (string, int64, error) {
 	return string(prompts), ts.UnixNano(), nil
 }
 
+// loadPartials load the prompt partials, the file name without the extension is the partial name
+func loadPartials(dir string) (map[string]string, int64, error) {
+	app, err := fs.Get("app")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	files, err := app.ReadDir(dir, false)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	partials := map[string]string{}
+	updatedAt := int64(0)
+	for _, file := range files {
+		ext := filepath.Ext(file)
+		if ext != ".md" && ext != ".txt" && ext != ".tmpl" {
+			continue
+		}
+
+		ts, err := app.ModTime(file)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		content, err := app.ReadFile(file)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		partials[strings.TrimSuffix(filepath.Base(file), ext)] = string(content)
+		updatedAt = max(updatedAt, ts.UnixNano())
+	}
+	return partials, updatedAt, nil
+}
+
 func loadScript(file string, root string) (*v8.Script, int64, error) {
 
 	app, err := fs.Get("app")
@@ -527,7 +691,7 @@ func (ast *Assistant) initialize() error {
 	if v, ok := ast.Options["model"].(string); ok {
 		model = strings.TrimLeft(v, "moapi:")
 	}
-	if _, ok := VisionCapableModels[model]; ok {
+	if _, ok := VisionCapableModels[model]; ok || api.Capabilities().Vision {
 		ast.vision = true
 	}
 
@@ -3,6 +3,7 @@ package assistant
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yaoapp/yao/config"
@@ -380,9 +381,88 @@ func (m *mockStore) GetHistory(id string, chatID string) ([]map[string]interface
 func (m *mockStore) SaveAssistant(assistant map[string]interface{}) (interface{}, error) {
 	return nil, nil
 }
+func (m *mockStore) UpdateAssistant(assistant map[string]interface{}, etag string) (string, error) {
+	return "", nil
+}
 func (m *mockStore) SaveHistory(sid string, messages []map[string]interface{}, cid string, context map[string]interface{}) error {
 	return nil
 }
 func (m *mockStore) UpdateChatTitle(sid string, cid string, title string) error   { return nil }
 func (m *mockStore) DeleteAssistants(filter store.AssistantFilter) (int64, error) { return 0, nil }
 func (m *mockStore) GetAssistantTags() ([]string, error)                          { return []string{}, nil }
+func (m *mockStore) SaveCollection(collection map[string]interface{}) (interface{}, error) {
+	return nil, nil
+}
+func (m *mockStore) GetCollection(name string) (map[string]interface{}, error) { return nil, nil }
+func (m *mockStore) GetCollections() ([]map[string]interface{}, error)         { return nil, nil }
+func (m *mockStore) DeleteCollection(name string) error                        { return nil }
+func (m *mockStore) SaveMemories(sid string, assistantID string, memories []string, ttl int) ([]string, error) {
+	return nil, nil
+}
+func (m *mockStore) GetMemories(sid string, filter store.MemoryFilter) ([]map[string]interface{}, error) {
+	return nil, nil
+}
+func (m *mockStore) DeleteMemory(sid string, memoryID string) error { return nil }
+func (m *mockStore) DeleteMemories(sid string, assistantID string) (int64, error) {
+	return 0, nil
+}
+func (m *mockStore) GetProfile(sid string) (map[string]interface{}, error) { return nil, nil }
+func (m *mockStore) SaveProfile(sid string, profile map[string]interface{}) error {
+	return nil
+}
+func (m *mockStore) SaveWorkflowRun(sid string, run map[string]interface{}) error {
+	m.data["run:"+run["run_id"].(string)] = run
+	return nil
+}
+func (m *mockStore) GetWorkflowRun(sid string, runID string) (map[string]interface{}, error) {
+	if data, ok := m.data["run:"+runID]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("workflow run %s not found", runID)
+}
+func (m *mockStore) GetWorkflowRuns(sid string, filter store.WorkflowRunFilter) ([]map[string]interface{}, error) {
+	return nil, nil
+}
+func (m *mockStore) SaveApproval(sid string, approval map[string]interface{}) (string, error) {
+	id := fmt.Sprintf("approval_%d", len(m.data))
+	data := map[string]interface{}{"approval_id": id, "sid": sid, "status": "pending"}
+	for k, v := range approval {
+		data[k] = v
+	}
+	m.data["approval:"+id] = data
+	return id, nil
+}
+func (m *mockStore) GetApproval(approvalID string) (map[string]interface{}, error) {
+	if data, ok := m.data["approval:"+approvalID]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("approval %s not found", approvalID)
+}
+func (m *mockStore) GetApprovals(sid string, filter store.ApprovalFilter) ([]map[string]interface{}, error) {
+	return nil, nil
+}
+func (m *mockStore) ExportUserData(sid string) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+func (m *mockStore) ScheduleUserDeletion(sid string, grace time.Duration) (time.Time, error) {
+	return time.Now().Add(grace), nil
+}
+func (m *mockStore) CancelUserDeletion(sid string) error {
+	return nil
+}
+func (m *mockStore) GetUserDeletion(sid string) (map[string]interface{}, error) {
+	return nil, nil
+}
+func (m *mockStore) DecideApproval(approvalID string, sid string, approved bool, comment string) error {
+	data, ok := m.data["approval:"+approvalID]
+	if !ok || data["status"] != "pending" {
+		return fmt.Errorf("approval %s not found or already decided", approvalID)
+	}
+	data["status"] = "rejected"
+	if approved {
+		data["status"] = "approved"
+	}
+	data["approver"] = sid
+	data["comment"] = comment
+	return nil
+}
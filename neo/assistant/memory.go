@@ -0,0 +1,195 @@
+package assistant
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	chatMessage "github.com/yaoapp/yao/neo/message"
+	"github.com/yaoapp/yao/neo/store"
+	"github.com/yaoapp/yao/share"
+)
+
+// defaultMemoryPrompt the prompt used to extract the memories
+const defaultMemoryPrompt = `Extract the durable facts about the user from the conversation, such as preferences, background, goals and decisions.
+Skip the facts already known, the small talk and the facts only about this conversation.
+Reply with a JSON array of short sentences only, reply [] if there is nothing new.`
+
+// MemorySetting the long-term memory setting of the assistant
+type MemorySetting struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Limit   int    `json:"limit,omitempty" yaml:"limit,omitempty"`   // The number of memories added to the prompt, default is 10
+	TTL     int    `json:"ttl,omitempty" yaml:"ttl,omitempty"`       // Time To Live of the memories in seconds, 0 means never expire
+	Prompt  string `json:"prompt,omitempty" yaml:"prompt,omitempty"` // The prompt used to extract the memories
+}
+
+// withMemories add the memories relevant to the input after the prompts
+func (ast *Assistant) withMemories(ctx chatctx.Context, messages []chatMessage.Message, input string) []chatMessage.Message {
+	if !ast.memoryEnabled(ctx) {
+		return messages
+	}
+
+	memories, err := storage.GetMemories(ctx.Sid, store.MemoryFilter{AssistantID: ast.ID})
+	if err != nil {
+		log.Error("[Neo] assistant %s get memories: %s", ast.ID, err.Error())
+		return messages
+	}
+
+	relevant := relevantMemories(memories, input, ast.memoryLimit())
+	if len(relevant) == 0 {
+		return messages
+	}
+
+	memory := chatMessage.New().Map(map[string]interface{}{
+		"role":    "system",
+		"content": "Known facts about the user:\n- " + strings.Join(relevant, "\n- "),
+	})
+
+	start := min(len(ast.Prompts), len(messages))
+	res := make([]chatMessage.Message, 0, len(messages)+1)
+	res = append(res, messages[:start]...)
+	res = append(res, *memory)
+	return append(res, messages[start:]...)
+}
+
+// writeMemories extract the new memories from the turn and save them, runs after the chat is done
+func (ast *Assistant) writeMemories(ctx chatctx.Context, input string, contents *chatMessage.Contents) {
+	if !ast.memoryEnabled(ctx) || ast.openai == nil || input == "" {
+		return
+	}
+
+	reply := []string{}
+	for _, data := range contents.Data {
+		if data.Type == "text" {
+			reply = append(reply, string(data.Bytes))
+		}
+	}
+	if len(reply) == 0 {
+		return
+	}
+
+	known := []string{}
+	memories, err := storage.GetMemories(ctx.Sid, store.MemoryFilter{AssistantID: ast.ID})
+	if err == nil {
+		for _, memory := range memories {
+			known = append(known, fmt.Sprint(memory["content"]))
+		}
+	}
+
+	prompt := defaultMemoryPrompt
+	if ast.Memory.Prompt != "" {
+		prompt = ast.Memory.Prompt
+	}
+
+	content := fmt.Sprintf("Known facts:\n%s\n\nConversation:\nuser: %s\nassistant: %s", strings.Join(known, "\n"), input, strings.Join(reply, ""))
+	timeout, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	res, ext := ast.openai.ChatCompletionsWith(timeout, []map[string]interface{}{
+		{"role": "system", "content": prompt},
+		{"role": "user", "content": content},
+	}, nil, nil)
+	if ext != nil {
+		log.Error("[Neo] assistant %s extract memories: %s", ast.ID, ext.Message)
+		return
+	}
+
+	text, ext := ast.openai.GetContent(res)
+	if ext != nil {
+		log.Error("[Neo] assistant %s extract memories: %s", ast.ID, ext.Message)
+		return
+	}
+
+	facts := parseMemories(text)
+	if len(facts) == 0 {
+		return
+	}
+
+	_, err = storage.SaveMemories(ctx.Sid, ast.ID, facts, ast.Memory.TTL)
+	if err != nil {
+		log.Error("[Neo] assistant %s save memories: %s", ast.ID, err.Error())
+	}
+}
+
+func (ast *Assistant) memoryEnabled(ctx chatctx.Context) bool {
+	return ast.Memory != nil && ast.Memory.Enabled && storage != nil && ctx.Sid != "" && !share.IsGuest(ctx.Sid)
+}
+
+func (ast *Assistant) memoryLimit() int {
+	if ast.Memory == nil || ast.Memory.Limit <= 0 {
+		return 10
+	}
+	return ast.Memory.Limit
+}
+
+// relevantMemories rank the memories by the words shared with the input, the newer first if the same
+func relevantMemories(memories []map[string]interface{}, input string, limit int) []string {
+	words := memoryWords(input)
+	type scored struct {
+		content string
+		score   int
+	}
+
+	items := []scored{}
+	for _, memory := range memories {
+		content, ok := memory["content"].(string)
+		if !ok || content == "" {
+			continue
+		}
+
+		score := 0
+		for word := range memoryWords(content) {
+			if words[word] {
+				score++
+			}
+		}
+		items = append(items, scored{content: content, score: score})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].score > items[j].score })
+	res := []string{}
+	for i := 0; i < len(items) && i < limit; i++ {
+		res = append(res, items[i].content)
+	}
+	return res
+}
+
+// memoryWords the lower case words of the text, each CJK character is a word
+func memoryWords(text string) map[string]bool {
+	words := map[string]bool{}
+	for _, field := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		for _, r := range field {
+			if unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) {
+				words[string(r)] = true
+			}
+		}
+
+		if len([]rune(field)) > 2 {
+			words[field] = true
+		}
+	}
+	return words
+}
+
+// parseMemories parse the JSON array of the extraction reply
+func parseMemories(text string) []string {
+	start, end := strings.Index(text, "["), strings.LastIndex(text, "]")
+	if start < 0 || end <= start {
+		return nil
+	}
+
+	var facts []string
+	err := jsoniter.UnmarshalFromString(text[start:end+1], &facts)
+	if err != nil {
+		return nil
+	}
+	return facts
+}
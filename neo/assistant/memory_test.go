@@ -0,0 +1,26 @@
+package assistant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelevantMemories(t *testing.T) {
+	memories := []map[string]interface{}{
+		{"content": "Lives in Berlin"},
+		{"content": "Likes green tea"},
+		{"content": "喜欢喝茶"},
+		{"content": ""},
+	}
+
+	assert.Equal(t, []string{"Likes green tea", "Lives in Berlin"}, relevantMemories(memories, "Any tea recommendations?", 2))
+	assert.Equal(t, "喜欢喝茶", relevantMemories(memories, "推荐一种茶", 1)[0])
+	assert.Len(t, relevantMemories(memories, "hello", 10), 3)
+}
+
+func TestParseMemories(t *testing.T) {
+	assert.Equal(t, []string{"Likes tea", "Lives in Berlin"}, parseMemories("Here you go:\n```json\n[\"Likes tea\", \"Lives in Berlin\"]\n```"))
+	assert.Empty(t, parseMemories("[]"))
+	assert.Nil(t, parseMemories("nothing new"))
+}
@@ -0,0 +1,473 @@
+package assistant
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	chatctx "github.com/yaoapp/yao/neo/context"
+)
+
+// Messages answer the Anthropic compatible messages request, the model of the request is the assistant ID.
+// The request is converted to the chat completions request, and the response is converted back to the message,
+// or to the events of the Anthropic stream if the cb is set.
+func (ast *Assistant) Messages(ctx chatctx.Context, body map[string]interface{}, cb func(event []byte) int) (interface{}, error) {
+	messages, options, err := messagesRequest(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if cb == nil {
+		res, err := ast.Completions(ctx, messages, options, nil)
+		if err != nil {
+			return nil, err
+		}
+		data, ok := res.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected chat completions response %v", res)
+		}
+		return messagesResponse(data), nil
+	}
+
+	stream := &messagesStream{model: ast.ID}
+	_, err = ast.Completions(ctx, messages, options, func(data []byte) int {
+		for _, event := range stream.chunk(data) {
+			if cb(event) == 0 {
+				return 0 // break
+			}
+		}
+		if stream.stopped {
+			return 0 // break
+		}
+		return 1 // continue
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The upstream ends the stream without [DONE]
+	if !stream.stopped {
+		for _, event := range stream.stop() {
+			cb(event)
+		}
+	}
+	return nil, nil
+}
+
+// messagesRequest convert the Anthropic messages request to the messages and the options of the chat completions request
+func messagesRequest(body map[string]interface{}) ([]map[string]interface{}, map[string]interface{}, error) {
+	messages := []map[string]interface{}{}
+	if system := messagesText(body["system"]); system != "" {
+		messages = append(messages, map[string]interface{}{"role": "system", "content": system})
+	}
+
+	raw, _ := body["messages"].([]interface{})
+	if len(raw) == 0 {
+		return nil, nil, fmt.Errorf("messages is required")
+	}
+
+	for _, item := range raw {
+		msg, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("messages must be an array of objects")
+		}
+
+		role, _ := msg["role"].(string)
+		switch role {
+		case "user":
+			messages = append(messages, messagesUser(msg["content"])...)
+		case "assistant":
+			messages = append(messages, messagesAssistant(msg["content"]))
+		default:
+			return nil, nil, fmt.Errorf("unsupported role %s", role)
+		}
+	}
+
+	options := map[string]interface{}{}
+	for _, key := range []string{"max_tokens", "temperature", "top_p"} {
+		if value, has := body[key]; has {
+			options[key] = value
+		}
+	}
+
+	if stop, has := body["stop_sequences"]; has {
+		options["stop"] = stop
+	}
+
+	if raw, ok := body["tools"].([]interface{}); ok && len(raw) > 0 {
+		tools := []interface{}{}
+		for _, item := range raw {
+			tool, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			function := map[string]interface{}{"name": tool["name"], "parameters": tool["input_schema"]}
+			if description, has := tool["description"]; has {
+				function["description"] = description
+			}
+			tools = append(tools, map[string]interface{}{"type": "function", "function": function})
+		}
+		options["tools"] = tools
+	}
+
+	if choice, ok := body["tool_choice"].(map[string]interface{}); ok {
+		switch choice["type"] {
+		case "auto":
+			options["tool_choice"] = "auto"
+		case "any":
+			options["tool_choice"] = "required"
+		case "none":
+			options["tool_choice"] = "none"
+		case "tool":
+			options["tool_choice"] = map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": choice["name"]}}
+		}
+	}
+
+	return messages, options, nil
+}
+
+// messagesUser convert the user message, the tool results are converted to the tool messages ahead of the user message
+func messagesUser(content interface{}) []map[string]interface{} {
+	blocks, ok := content.([]interface{})
+	if !ok {
+		return []map[string]interface{}{{"role": "user", "content": messagesText(content)}}
+	}
+
+	res := []map[string]interface{}{}
+	texts := []string{}
+	parts := []interface{}{}
+	hasImage := false
+	for _, item := range blocks {
+		block, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch block["type"] {
+		case "text":
+			text, _ := block["text"].(string)
+			texts = append(texts, text)
+			parts = append(parts, map[string]interface{}{"type": "text", "text": text})
+
+		case "image":
+			source, _ := block["source"].(map[string]interface{})
+			url, _ := source["url"].(string)
+			if source["type"] == "base64" {
+				url = fmt.Sprintf("data:%v;base64,%v", source["media_type"], source["data"])
+			}
+			hasImage = true
+			parts = append(parts, map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": url}})
+
+		case "tool_result":
+			text := messagesText(block["content"])
+			if isError, _ := block["is_error"].(bool); isError {
+				text = "Error: " + text
+			}
+			res = append(res, map[string]interface{}{"role": "tool", "tool_call_id": block["tool_use_id"], "content": text})
+		}
+	}
+
+	if hasImage {
+		return append(res, map[string]interface{}{"role": "user", "content": parts})
+	}
+	if len(texts) > 0 {
+		return append(res, map[string]interface{}{"role": "user", "content": strings.Join(texts, "\n")})
+	}
+	return res
+}
+
+// messagesAssistant convert the assistant message, the tool uses are converted to the tool calls
+func messagesAssistant(content interface{}) map[string]interface{} {
+	blocks, ok := content.([]interface{})
+	if !ok {
+		return map[string]interface{}{"role": "assistant", "content": messagesText(content)}
+	}
+
+	texts := []string{}
+	toolCalls := []interface{}{}
+	for _, item := range blocks {
+		block, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch block["type"] {
+		case "text":
+			text, _ := block["text"].(string)
+			texts = append(texts, text)
+
+		case "tool_use":
+			arguments, err := jsoniter.MarshalToString(block["input"])
+			if err != nil || block["input"] == nil {
+				arguments = "{}"
+			}
+			toolCalls = append(toolCalls, map[string]interface{}{
+				"id":       block["id"],
+				"type":     "function",
+				"function": map[string]interface{}{"name": block["name"], "arguments": arguments},
+			})
+		}
+	}
+
+	msg := map[string]interface{}{"role": "assistant", "content": strings.Join(texts, "\n")}
+	if len(toolCalls) > 0 {
+		msg["tool_calls"] = toolCalls
+	}
+	return msg
+}
+
+// messagesText the text of the string content or the text blocks
+func messagesText(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+
+	case []interface{}:
+		texts := []string{}
+		for _, item := range v {
+			if block, ok := item.(map[string]interface{}); ok && block["type"] == "text" {
+				if text, ok := block["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+	return ""
+}
+
+// messagesResponse convert the chat completions response to the Anthropic message
+func messagesResponse(res map[string]interface{}) map[string]interface{} {
+	content := []interface{}{}
+	reason := "end_turn"
+
+	if choices, ok := res["choices"].([]interface{}); ok && len(choices) > 0 {
+		choice, _ := choices[0].(map[string]interface{})
+		message, _ := choice["message"].(map[string]interface{})
+		if text, ok := message["content"].(string); ok && text != "" {
+			content = append(content, map[string]interface{}{"type": "text", "text": text})
+		}
+
+		toolCalls, _ := message["tool_calls"].([]interface{})
+		for _, item := range toolCalls {
+			call, _ := item.(map[string]interface{})
+			function, _ := call["function"].(map[string]interface{})
+			content = append(content, map[string]interface{}{
+				"type":  "tool_use",
+				"id":    call["id"],
+				"name":  function["name"],
+				"input": messagesInput(function["arguments"]),
+			})
+		}
+
+		if finish, ok := choice["finish_reason"].(string); ok {
+			reason = messagesStopReason(finish)
+		}
+	}
+
+	return map[string]interface{}{
+		"id":            res["id"],
+		"type":          "message",
+		"role":          "assistant",
+		"model":         res["model"],
+		"content":       content,
+		"stop_reason":   reason,
+		"stop_sequence": nil,
+		"usage":         messagesUsage(res["usage"]),
+	}
+}
+
+// messagesInput parse the arguments of the tool call, empty object if the arguments is invalid
+func messagesInput(arguments interface{}) interface{} {
+	var input interface{} = map[string]interface{}{}
+	if raw, ok := arguments.(string); ok && raw != "" {
+		if err := jsoniter.UnmarshalFromString(raw, &input); err != nil {
+			return map[string]interface{}{}
+		}
+	}
+	return input
+}
+
+// messagesStopReason map the finish reason of the chat completions to the stop reason of the message
+func messagesStopReason(finish string) string {
+	switch finish {
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	}
+	return "end_turn"
+}
+
+// messagesUsage map the usage of the chat completions to the usage of the message
+func messagesUsage(usage interface{}) map[string]interface{} {
+	res := map[string]interface{}{"input_tokens": 0, "output_tokens": 0}
+	if usage, ok := usage.(map[string]interface{}); ok {
+		if v, has := usage["prompt_tokens"]; has {
+			res["input_tokens"] = v
+		}
+		if v, has := usage["completion_tokens"]; has {
+			res["output_tokens"] = v
+		}
+	}
+	return res
+}
+
+// messagesStream convert the chunks of the chat completions stream to the events of the Anthropic stream
+type messagesStream struct {
+	model   string
+	id      string
+	started bool
+	stopped bool
+	open    bool   // The current content block is open
+	block   string // The type of the current content block
+	index   int    // The index of the current content block
+	reason  string
+	usage   interface{}
+}
+
+// chunk convert the server-sent event of the chat completions stream
+func (s *messagesStream) chunk(data []byte) [][]byte {
+	line := bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(data), []byte("data:")))
+	if len(line) == 0 {
+		return nil
+	}
+
+	if string(line) == "[DONE]" {
+		return s.stop()
+	}
+
+	var chunk map[string]interface{}
+	if err := jsoniter.Unmarshal(line, &chunk); err != nil {
+		s.stopped = true
+		return [][]byte{MessagesEvent("error", MessagesError(string(line), "api_error"))}
+	}
+
+	if e, has := chunk["error"].(map[string]interface{}); has {
+		s.stopped = true
+		return [][]byte{MessagesEvent("error", MessagesError(fmt.Sprintf("%v", e["message"]), "api_error"))}
+	}
+
+	events := [][]byte{}
+	if !s.started {
+		s.started = true
+		s.index = -1
+		s.id, _ = chunk["id"].(string)
+		events = append(events, MessagesEvent("message_start", map[string]interface{}{
+			"type": "message_start",
+			"message": map[string]interface{}{
+				"id":            s.id,
+				"type":          "message",
+				"role":          "assistant",
+				"model":         s.model,
+				"content":       []interface{}{},
+				"stop_reason":   nil,
+				"stop_sequence": nil,
+				"usage":         messagesUsage(nil),
+			},
+		}))
+	}
+
+	if usage, has := chunk["usage"]; has && usage != nil {
+		s.usage = usage
+	}
+
+	choices, _ := chunk["choices"].([]interface{})
+	if len(choices) == 0 {
+		return events
+	}
+
+	choice, _ := choices[0].(map[string]interface{})
+	delta, _ := choice["delta"].(map[string]interface{})
+
+	if text, ok := delta["content"].(string); ok && text != "" {
+		if !s.open || s.block != "text" {
+			events = append(events, s.start(map[string]interface{}{"type": "text", "text": ""})...)
+		}
+		events = append(events, s.delta(map[string]interface{}{"type": "text_delta", "text": text}))
+	}
+
+	toolCalls, _ := delta["tool_calls"].([]interface{})
+	for _, item := range toolCalls {
+		call, _ := item.(map[string]interface{})
+		function, _ := call["function"].(map[string]interface{})
+		if id, ok := call["id"].(string); ok && id != "" {
+			events = append(events, s.start(map[string]interface{}{"type": "tool_use", "id": id, "name": function["name"], "input": map[string]interface{}{}})...)
+		}
+		if arguments, ok := function["arguments"].(string); ok && arguments != "" && s.open && s.block == "tool_use" {
+			events = append(events, s.delta(map[string]interface{}{"type": "input_json_delta", "partial_json": arguments}))
+		}
+	}
+
+	if finish, ok := choice["finish_reason"].(string); ok && finish != "" {
+		s.reason = messagesStopReason(finish)
+	}
+
+	return events
+}
+
+// start close the current content block and start a new one
+func (s *messagesStream) start(block map[string]interface{}) [][]byte {
+	events := s.close()
+	s.index++
+	s.open = true
+	s.block, _ = block["type"].(string)
+	return append(events, MessagesEvent("content_block_start", map[string]interface{}{
+		"type":          "content_block_start",
+		"index":         s.index,
+		"content_block": block,
+	}))
+}
+
+// delta the delta of the current content block
+func (s *messagesStream) delta(delta map[string]interface{}) []byte {
+	return MessagesEvent("content_block_delta", map[string]interface{}{"type": "content_block_delta", "index": s.index, "delta": delta})
+}
+
+// close close the current content block if it is open
+func (s *messagesStream) close() [][]byte {
+	if !s.open {
+		return nil
+	}
+	s.open = false
+	return [][]byte{MessagesEvent("content_block_stop", map[string]interface{}{"type": "content_block_stop", "index": s.index})}
+}
+
+// stop close the stream with the stop reason and the usage
+func (s *messagesStream) stop() [][]byte {
+	if s.stopped || !s.started {
+		s.stopped = true
+		return nil
+	}
+
+	s.stopped = true
+	reason := s.reason
+	if reason == "" {
+		reason = "end_turn"
+	}
+
+	usage := messagesUsage(s.usage)
+	events := s.close()
+	events = append(events, MessagesEvent("message_delta", map[string]interface{}{
+		"type":  "message_delta",
+		"delta": map[string]interface{}{"stop_reason": reason, "stop_sequence": nil},
+		"usage": map[string]interface{}{"output_tokens": usage["output_tokens"]},
+	}))
+	return append(events, MessagesEvent("message_stop", map[string]interface{}{"type": "message_stop"}))
+}
+
+// MessagesError the error of the Anthropic compatible response
+func MessagesError(message string, typ string) map[string]interface{} {
+	return map[string]interface{}{"type": "error", "error": map[string]interface{}{"type": typ, "message": message}}
+}
+
+// MessagesEvent the server-sent event of the Anthropic compatible stream
+func MessagesEvent(name string, data interface{}) []byte {
+	raw, err := jsoniter.Marshal(data)
+	if err != nil {
+		name = "error"
+		raw, _ = jsoniter.Marshal(MessagesError(err.Error(), "api_error"))
+	}
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", name, raw))
+}
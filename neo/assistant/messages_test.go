@@ -0,0 +1,110 @@
+package assistant
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessagesRequest(t *testing.T) {
+	messages, options, err := messagesRequest(map[string]interface{}{
+		"system":         []interface{}{map[string]interface{}{"type": "text", "text": "Be brief"}},
+		"max_tokens":     1024,
+		"stop_sequences": []interface{}{"END"},
+		"tools": []interface{}{map[string]interface{}{
+			"name": "weather", "description": "Get the weather",
+			"input_schema": map[string]interface{}{"type": "object"},
+		}},
+		"tool_choice": map[string]interface{}{"type": "tool", "name": "weather"},
+		"messages": []interface{}{
+			map[string]interface{}{"role": "user", "content": "Weather in Paris?"},
+			map[string]interface{}{"role": "assistant", "content": []interface{}{
+				map[string]interface{}{"type": "text", "text": "Checking"},
+				map[string]interface{}{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": map[string]interface{}{"city": "Paris"}},
+			}},
+			map[string]interface{}{"role": "user", "content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_1", "content": "Sunny"},
+				map[string]interface{}{"type": "text", "text": "Thanks"},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, messages, 5)
+	assert.Equal(t, map[string]interface{}{"role": "system", "content": "Be brief"}, messages[0])
+	assert.Equal(t, map[string]interface{}{"role": "user", "content": "Weather in Paris?"}, messages[1])
+	assert.Equal(t, "Checking", messages[2]["content"])
+	assert.Equal(t, `{"city":"Paris"}`, messages[2]["tool_calls"].([]interface{})[0].(map[string]interface{})["function"].(map[string]interface{})["arguments"])
+	assert.Equal(t, map[string]interface{}{"role": "tool", "tool_call_id": "toolu_1", "content": "Sunny"}, messages[3])
+	assert.Equal(t, map[string]interface{}{"role": "user", "content": "Thanks"}, messages[4])
+
+	assert.Equal(t, 1024, options["max_tokens"])
+	assert.Equal(t, []interface{}{"END"}, options["stop"])
+	assert.Equal(t, "weather", options["tools"].([]interface{})[0].(map[string]interface{})["function"].(map[string]interface{})["name"])
+	assert.Equal(t, map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "weather"}}, options["tool_choice"])
+
+	_, _, err = messagesRequest(map[string]interface{}{"messages": []interface{}{map[string]interface{}{"role": "system", "content": "Hi"}}})
+	assert.Error(t, err)
+}
+
+func TestMessagesResponse(t *testing.T) {
+	res := messagesResponse(map[string]interface{}{
+		"id":    "chatcmpl_1",
+		"model": "test",
+		"choices": []interface{}{map[string]interface{}{
+			"finish_reason": "tool_calls",
+			"message": map[string]interface{}{
+				"content": "Checking",
+				"tool_calls": []interface{}{map[string]interface{}{
+					"id": "call_1", "type": "function",
+					"function": map[string]interface{}{"name": "weather", "arguments": `{"city":"Paris"}`},
+				}},
+			},
+		}},
+		"usage": map[string]interface{}{"prompt_tokens": 10, "completion_tokens": 5},
+	})
+
+	assert.Equal(t, "message", res["type"])
+	assert.Equal(t, "tool_use", res["stop_reason"])
+	assert.Equal(t, map[string]interface{}{"input_tokens": 10, "output_tokens": 5}, res["usage"])
+	content := res["content"].([]interface{})
+	assert.Len(t, content, 2)
+	assert.Equal(t, map[string]interface{}{"city": "Paris"}, content[1].(map[string]interface{})["input"])
+}
+
+func TestMessagesStream(t *testing.T) {
+	stream := &messagesStream{model: "test"}
+	events := [][]byte{}
+	for _, line := range []string{
+		`data: {"id":"chatcmpl_1","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}]}`,
+		`data: {"id":"chatcmpl_1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"weather","arguments":""}}]}}]}`,
+		`data: {"id":"chatcmpl_1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":\"Paris\"}"}}]}}]}`,
+		`data: {"id":"chatcmpl_1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`data: [DONE]`,
+	} {
+		events = append(events, stream.chunk([]byte(line))...)
+	}
+
+	names := []string{}
+	for _, event := range events {
+		names = append(names, strings.TrimPrefix(strings.SplitN(string(event), "\n", 2)[0], "event: "))
+	}
+	assert.Equal(t, []string{
+		"message_start",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}, names)
+	assert.True(t, stream.stopped)
+	assert.Contains(t, string(events[5]), `"partial_json":"{\"city\":\"Paris\"}"`)
+	assert.Contains(t, string(events[7]), `"stop_reason":"tool_use"`)
+	assert.Nil(t, stream.stop())
+
+	stream = &messagesStream{model: "test"}
+	events = stream.chunk([]byte(`data: {"error":{"message":"Invalid key","type":"upstream_error"}}`))
+	assert.True(t, stream.stopped)
+	assert.Contains(t, string(events[0]), "event: error")
+}
@@ -0,0 +1,70 @@
+package assistant
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	chatMessage "github.com/yaoapp/yao/neo/message"
+	"github.com/yaoapp/yao/share"
+)
+
+// withProfile add the preferences of the user after the prompts
+func (ast *Assistant) withProfile(ctx chatctx.Context, messages []chatMessage.Message) []chatMessage.Message {
+	if storage == nil || ctx.Sid == "" || share.IsGuest(ctx.Sid) {
+		return messages
+	}
+
+	profile, err := storage.GetProfile(ctx.Sid)
+	if err != nil {
+		log.Error("[Neo] get profile %s: %s", ctx.Sid, err.Error())
+		return messages
+	}
+
+	content := profilePrompt(profile, time.Now())
+	if content == "" {
+		return messages
+	}
+
+	start := min(len(ast.Prompts), len(messages))
+	res := make([]chatMessage.Message, 0, len(messages)+1)
+	res = append(res, messages[:start]...)
+	res = append(res, *chatMessage.New().Map(map[string]interface{}{"role": "system", "content": content}))
+	return append(res, messages[start:]...)
+}
+
+// profilePrompt render the profile to a system prompt, returns empty if nothing is set
+func profilePrompt(profile map[string]interface{}, now time.Time) string {
+	value := func(field string) string {
+		if v, ok := profile[field].(string); ok {
+			return strings.TrimSpace(v)
+		}
+		return ""
+	}
+
+	lines := []string{}
+	if language := value("language"); language != "" {
+		lines = append(lines, fmt.Sprintf("- Reply in the language: %s", language))
+	}
+
+	if tone := value("tone"); tone != "" {
+		lines = append(lines, fmt.Sprintf("- Tone: %s", tone))
+	}
+
+	if timezone := value("timezone"); timezone != "" {
+		if loc, err := time.LoadLocation(timezone); err == nil {
+			lines = append(lines, fmt.Sprintf("- Timezone: %s, the current time is %s", timezone, now.In(loc).Format("2006-01-02 15:04 Monday")))
+		}
+	}
+
+	if instructions := value("instructions"); instructions != "" {
+		lines = append(lines, "- Custom instructions:\n"+instructions)
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return "User preferences:\n" + strings.Join(lines, "\n")
+}
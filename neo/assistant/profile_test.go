@@ -0,0 +1,27 @@
+package assistant
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfilePrompt(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC)
+	prompt := profilePrompt(map[string]interface{}{
+		"language":     "zh-CN",
+		"tone":         "friendly",
+		"timezone":     "Asia/Shanghai",
+		"instructions": "Call me Max.",
+	}, now)
+
+	assert.Equal(t, "User preferences:\n"+
+		"- Reply in the language: zh-CN\n"+
+		"- Tone: friendly\n"+
+		"- Timezone: Asia/Shanghai, the current time is 2025-01-02 11:04 Thursday\n"+
+		"- Custom instructions:\nCall me Max.", prompt)
+
+	assert.Equal(t, "", profilePrompt(map[string]interface{}{"language": " ", "timezone": nil}, now))
+	assert.Equal(t, "", profilePrompt(map[string]interface{}{"timezone": "Not/AZone"}, now))
+}
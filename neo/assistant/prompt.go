@@ -0,0 +1,142 @@
+package assistant
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/kun/log"
+	chatctx "github.com/yaoapp/yao/neo/context"
+)
+
+// promptFuncs the functions available in the prompt templates
+var promptFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"join": func(sep string, values interface{}) string {
+		parts := []string{}
+		switch vv := values.(type) {
+		case []string:
+			parts = vv
+		case []interface{}:
+			for _, v := range vv {
+				parts = append(parts, fmt.Sprint(v))
+			}
+		}
+		return strings.Join(parts, sep)
+	},
+	"default": func(value interface{}, given interface{}) interface{} {
+		if given == nil || given == "" {
+			return value
+		}
+		return given
+	},
+	"json": func(value interface{}) string {
+		raw, _ := jsoniter.MarshalToString(value)
+		return raw
+	},
+	"date": func(layout string) string {
+		return time.Now().Format(layout)
+	},
+}
+
+// PreviewPrompts render the prompts without chatting, the template errors are returned.
+// The variables are merged into the template data, use it to simulate the user, team or chat.
+func (ast *Assistant) PreviewPrompts(ctx chatctx.Context, vars map[string]interface{}) ([]Prompt, error) {
+	data := ast.promptData(ctx)
+	for key, value := range vars {
+		data[key] = value
+	}
+
+	prompts := make([]Prompt, len(ast.Prompts))
+	for i, prompt := range ast.Prompts {
+		content, err := ast.renderPrompt(prompt.Content, data)
+		if err != nil {
+			return nil, fmt.Errorf("prompt %d: %s", i, err.Error())
+		}
+		prompts[i] = Prompt{Role: prompt.Role, Name: prompt.Name, Content: content}
+	}
+	return prompts, nil
+}
+
+// renderPrompt render the prompt template, the partials of the assistant can be included with {{ template "name" . }}
+func (ast *Assistant) renderPrompt(content string, data map[string]interface{}) (string, error) {
+	if !strings.Contains(content, "{{") {
+		return content, nil
+	}
+
+	tmpl := template.New("prompt").Option("missingkey=zero").Funcs(promptFuncs)
+	for name, partial := range ast.Partials {
+		_, err := tmpl.New(name).Parse(partial)
+		if err != nil {
+			return "", fmt.Errorf("partial %s: %s", name, err.Error())
+		}
+	}
+
+	_, err := tmpl.Parse(content)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", err
+	}
+
+	// The missing keys of the maps are rendered as "<no value>"
+	return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+}
+
+// promptData the variables of the prompt templates
+//
+//	.user       the user of the session
+//	.team       the team of the session
+//	.session    all the session data
+//	.chat       {"id": "chat id"}
+//	.assistant  {"id", "name", "description"}
+//	.profile    the preferences of the user, language, tone, timezone, instructions
+//	.context    the chat context, pathname, formdata, config...
+//	.now        the current time in RFC3339
+func (ast *Assistant) promptData(ctx chatctx.Context) map[string]interface{} {
+	data := map[string]interface{}{
+		"user":      map[string]interface{}{},
+		"team":      map[string]interface{}{},
+		"session":   map[string]interface{}{},
+		"profile":   map[string]interface{}{},
+		"chat":      map[string]interface{}{"id": ctx.ChatID},
+		"assistant": map[string]interface{}{"id": ast.ID, "name": ast.Name, "description": ast.Description},
+		"context":   ctx.Map(),
+		"now":       time.Now().Format(time.RFC3339),
+	}
+
+	if ctx.Sid == "" {
+		return data
+	}
+
+	if storage != nil {
+		if profile, err := storage.GetProfile(ctx.Sid); err == nil {
+			data["profile"] = profile
+		}
+	}
+
+	ss, err := session.Global().ID(ctx.Sid).Dump()
+	if err != nil {
+		log.Warn("[Neo] prompt session %s: %s", ctx.Sid, err.Error())
+		return data
+	}
+
+	data["session"] = ss
+	if user, ok := ss["user"]; ok && user != nil {
+		data["user"] = user
+	}
+	if team, ok := ss["team"]; ok && team != nil {
+		data["team"] = team
+	}
+	return data
+}
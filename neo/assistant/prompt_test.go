@@ -0,0 +1,54 @@
+package assistant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	chatctx "github.com/yaoapp/yao/neo/context"
+)
+
+func TestPreviewPrompts(t *testing.T) {
+	ast := &Assistant{
+		ID:   "test",
+		Name: "Helper",
+		Prompts: []Prompt{
+			{Role: "system", Content: `You are {{ .assistant.name }}.{{ if .user.name }} The user is {{ .user.name }}.{{ end }}`},
+			{Role: "system", Content: `{{ template "rules" . }}`},
+			{Role: "system", Content: "Plain prompt"},
+		},
+		Partials: map[string]string{
+			"rules": `Rules:{{ range .rules }}
+- {{ upper . }}{{ end }}`,
+		},
+	}
+
+	ctx := chatctx.New("", "chat_1", "")
+	prompts, err := ast.PreviewPrompts(ctx, map[string]interface{}{
+		"user":  map[string]interface{}{"name": "Max"},
+		"rules": []string{"be brief", "be kind"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "You are Helper. The user is Max.", prompts[0].Content)
+	assert.Equal(t, "Rules:\n- BE BRIEF\n- BE KIND", prompts[1].Content)
+	assert.Equal(t, "Plain prompt", prompts[2].Content)
+
+	// Missing variables are empty
+	prompts, err = ast.PreviewPrompts(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "You are Helper.", prompts[0].Content)
+	assert.Equal(t, "Rules:", prompts[1].Content)
+
+	// Invalid template
+	ast.Prompts = []Prompt{{Role: "system", Content: "Hello {{ if }}"}}
+	_, err = ast.PreviewPrompts(ctx, nil)
+	assert.Error(t, err)
+
+	// The invalid template is kept as it is when chatting
+	messages := ast.withPrompts(ctx, nil)
+	assert.Equal(t, "Hello {{ if }}", messages[0].Text)
+}
@@ -0,0 +1,100 @@
+package assistant
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	chatMessage "github.com/yaoapp/yao/neo/message"
+	"github.com/yaoapp/yao/trace"
+)
+
+// Replay replay the turn of the trace, the chat completions are requested again with the messages and the options recorded,
+// the reply is streamed to the writer. The tools, the processes and the hooks return the outputs recorded if mock is true.
+// The replay is traced as a new trace, the chat history and the memories are not changed. Returns the id of the new trace.
+func Replay(c *gin.Context, traceID string, mock bool) (string, error) {
+	w, err := trace.Get(traceID)
+	if err != nil {
+		return "", err
+	}
+
+	turn, llm, err := replayOf(w)
+	if err != nil {
+		return "", err
+	}
+
+	ast, err := Get(turn.Name)
+	if err != nil {
+		return "", err
+	}
+
+	messages, options, err := replayInput(llm.Input)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := chatctx.New(turn.Sid, turn.ChatID, "")
+	ctx.AssistantID = ast.ID
+	ctx.Context = trace.WithReplay(c.Request.Context(), trace.NewReplay(w, mock))
+
+	var span *trace.Span
+	ctx.Context, span = trace.Start(ctx.Context, trace.KindTurn, ast.ID, turn.Input)
+	span.Chat(turn.Sid, turn.ChatID, ast.ID)
+	span.Set("replay_of", traceID).Set("mock", mock)
+	c.Header("X-Yao-Trace", span.TraceID)
+
+	err = ast.handleChatStream(c, ctx, messages, options)
+	span.End(nil, err)
+	return span.TraceID, err
+}
+
+// replayOf the turn and the chat completions request replayed, the first request of the trace
+func replayOf(w *trace.Waterfall) (*trace.Span, *trace.Span, error) {
+	turns := map[string]*trace.Span{}
+	for _, span := range w.Spans {
+		if span.Kind == trace.KindTurn {
+			turns[span.ID] = span.Span
+		}
+	}
+
+	for _, span := range w.Spans {
+		if span.Kind != trace.KindLLM {
+			continue
+		}
+		turn, ok := turns[span.ParentID]
+		if !ok {
+			return nil, nil, fmt.Errorf("the turn of the chat completions request %s does not exist", span.ID)
+		}
+		return turn, span.Span, nil
+	}
+	return nil, nil, fmt.Errorf("the trace %s has no chat completions request", w.TraceID)
+}
+
+// replayInput the messages and the options of the chat completions request recorded
+func replayInput(input interface{}) ([]chatMessage.Message, map[string]interface{}, error) {
+	recorded, ok := input.(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("the input of the chat completions request is not recorded")
+	}
+
+	if _, truncated := recorded["__truncated"]; truncated {
+		return nil, nil, fmt.Errorf("the input of the chat completions request is truncated")
+	}
+
+	raw, err := jsoniter.Marshal(recorded["messages"])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	messages := []chatMessage.Message{}
+	if err := jsoniter.Unmarshal(raw, &messages); err != nil {
+		return nil, nil, fmt.Errorf("the messages recorded are invalid: %s", err.Error())
+	}
+
+	options, _ := recorded["options"].(map[string]interface{})
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	return messages, options, nil
+}
@@ -0,0 +1,47 @@
+package assistant
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	"github.com/yaoapp/yao/trace"
+)
+
+func TestReplayInput(t *testing.T) {
+	at := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	w := &trace.Waterfall{TraceID: "t1", Spans: []trace.WaterfallSpan{
+		{Span: &trace.Span{ID: "turn", Kind: trace.KindTurn, Name: "writer", StartedAt: at}},
+		{Span: &trace.Span{ID: "handoff", ParentID: "turn", Kind: trace.KindTurn, Name: "billing", StartedAt: at}, Depth: 1},
+		{Span: &trace.Span{ID: "llm", ParentID: "handoff", Kind: trace.KindLLM, Name: "gpt-4o", StartedAt: at, Input: map[string]interface{}{
+			"messages": []interface{}{map[string]interface{}{"role": "user", "text": "Refund the order 1"}},
+			"options":  map[string]interface{}{"temperature": 0.2},
+		}}, Depth: 2},
+		{Span: &trace.Span{ID: "tool", ParentID: "llm", Kind: trace.KindTool, Name: "scripts.order.Refund", StartedAt: at.Add(time.Second), Input: []interface{}{float64(1)}, Output: "refunded"}, Depth: 3},
+	}}
+
+	turn, llm, err := replayOf(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "billing", turn.Name)
+	assert.Equal(t, "llm", llm.ID)
+
+	messages, options, err := replayInput(llm.Input)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "user", messages[0].Role)
+	assert.Equal(t, 0.2, options["temperature"])
+
+	_, _, err = replayInput(map[string]interface{}{"__truncated": "{"})
+	assert.Error(t, err)
+	_, _, err = replayOf(&trace.Waterfall{TraceID: "t2", Spans: w.Spans[:2]})
+	assert.Error(t, err)
+
+	// The tool returns the output recorded
+	c, _ := testGinContext()
+	ctx := chatctx.New("requester", "chat_1", "")
+	ctx.Context = trace.WithReplay(ctx.Context, trace.NewReplay(w, true))
+	res, err := (&Assistant{ID: "billing"}).executeTool(c, ctx, "scripts.order.Refund", []interface{}{1})
+	assert.NoError(t, err)
+	assert.Equal(t, "refunded", res)
+}
@@ -0,0 +1,146 @@
+package assistant
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	chatctx "github.com/yaoapp/yao/neo/context"
+)
+
+// replyHistory the max number of the history messages sent with the reply
+const replyHistory = 20
+
+// Reply answer the input without streaming, used by the messaging integrations, e.g. WeCom, DingTalk and Feishu.
+// The latest messages of the chat are sent with the input and the reply is saved to the chat, the tool calls of the reply are not executed.
+func (ast *Assistant) Reply(ctx chatctx.Context, input string) (string, error) {
+	return ast.ReplyStream(ctx, input, nil)
+}
+
+// ReplyStream answer the input as Reply, the text replied so far is passed to the cb while it is streamed, e.g. the updates of the Slack message.
+// The reply is not streamed if the cb is nil.
+func (ast *Assistant) ReplyStream(ctx chatctx.Context, input string, cb func(text string)) (string, error) {
+	messages := []map[string]interface{}{}
+	if storage != nil && ctx.ChatID != "" {
+		history, err := storage.GetHistory(ctx.Sid, ctx.ChatID)
+		if err != nil {
+			return "", err
+		}
+		if len(history) > replyHistory {
+			history = history[len(history)-replyHistory:]
+		}
+		for _, h := range history {
+			role, _ := h["role"].(string)
+			if role != "user" && role != "assistant" {
+				continue
+			}
+			messages = append(messages, map[string]interface{}{"role": role, "content": historyText(h["content"])})
+		}
+	}
+	messages = append(messages, map[string]interface{}{"role": "user", "content": input})
+
+	text := ""
+	if cb == nil {
+		res, err := ast.Completions(ctx, messages, nil, nil)
+		if err != nil {
+			return "", err
+		}
+
+		data, ok := res.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("unexpected chat completions response %v", res)
+		}
+		text = replyText(data)
+	} else {
+		var reply strings.Builder
+		var failed error
+		_, err := ast.Completions(ctx, messages, nil, func(data []byte) int {
+			delta, done, err := replyDelta(data)
+			if err != nil {
+				failed = err
+				return 0 // break
+			}
+			if delta != "" {
+				reply.WriteString(delta)
+				cb(reply.String())
+			}
+			if done {
+				return 0 // break
+			}
+			return 1 // continue
+		})
+		if err != nil {
+			return "", err
+		}
+		if failed != nil {
+			return "", failed
+		}
+		text = strings.TrimSpace(reply.String())
+	}
+
+	if storage != nil && ctx.ChatID != "" {
+		err := storage.SaveHistory(ctx.Sid, []map[string]interface{}{
+			{"role": "user", "content": input, "name": ctx.Sid},
+			{"role": "assistant", "content": text, "name": ctx.Sid, "assistant_id": ast.ID, "assistant_name": ast.Name, "assistant_avatar": ast.Avatar},
+		}, ctx.ChatID, ctx.Map())
+		if err != nil {
+			log.Error("[Neo] assistant %s save the reply of %s: %s", ast.ID, ctx.ChatID, err.Error())
+		}
+	}
+	return text, nil
+}
+
+// replyText the text of the chat completions response
+func replyText(res map[string]interface{}) string {
+	message := messagesResponse(res)
+	return strings.TrimSpace(messagesText(message["content"]))
+}
+
+// replyDelta the text of the chat completions chunk, done if the stream ends
+func replyDelta(data []byte) (string, bool, error) {
+	line := bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(data), []byte("data:")))
+	if len(line) == 0 {
+		return "", false, nil
+	}
+
+	if string(line) == "[DONE]" {
+		return "", true, nil
+	}
+
+	var chunk map[string]interface{}
+	if err := jsoniter.Unmarshal(line, &chunk); err != nil {
+		return "", true, fmt.Errorf("%s", line)
+	}
+
+	if e, has := chunk["error"].(map[string]interface{}); has {
+		return "", true, fmt.Errorf("%v", e["message"])
+	}
+
+	choices, _ := chunk["choices"].([]interface{})
+	if len(choices) == 0 {
+		return "", false, nil
+	}
+
+	choice, _ := choices[0].(map[string]interface{})
+	delta, _ := choice["delta"].(map[string]interface{})
+	text, _ := delta["content"].(string)
+	return text, false, nil
+}
+
+// historyText the text of the history message, the contents saved by the chat are the JSON of the blocks
+func historyText(content interface{}) string {
+	text, ok := content.(string)
+	if !ok {
+		return messagesText(content)
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(text), "[") {
+		var blocks []interface{}
+		if err := jsoniter.UnmarshalFromString(text, &blocks); err == nil {
+			return messagesText(blocks)
+		}
+	}
+	return text
+}
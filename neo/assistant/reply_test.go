@@ -0,0 +1,51 @@
+package assistant
+
+import (
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	chatMessage "github.com/yaoapp/yao/neo/message"
+)
+
+func TestReplyText(t *testing.T) {
+	text := replyText(map[string]interface{}{
+		"choices": []interface{}{map[string]interface{}{
+			"message":       map[string]interface{}{"role": "assistant", "content": " Hello \n"},
+			"finish_reason": "stop",
+		}},
+	})
+	assert.Equal(t, "Hello", text)
+	assert.Equal(t, "", replyText(map[string]interface{}{}))
+}
+
+func TestHistoryText(t *testing.T) {
+	contents := chatMessage.NewContents().NewText([]byte("Hello"))
+	contents.Data = append(contents.Data, chatMessage.Data{Type: "function", Function: "weather", Arguments: []byte(`{}`)})
+	raw, _ := jsoniter.MarshalToString(contents.Data)
+
+	assert.Equal(t, "Hello", historyText(raw))
+	assert.Equal(t, "[not json", historyText("[not json"))
+	assert.Equal(t, "Hi", historyText("Hi"))
+	assert.Equal(t, "A", historyText([]interface{}{map[string]interface{}{"type": "text", "text": "A"}}))
+}
+
+func TestReplyDelta(t *testing.T) {
+	text, done, err := replyDelta([]byte(`data: {"choices":[{"delta":{"content":"Hel"}}]}` + "\n\n"))
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, "Hel", text)
+
+	text, done, err = replyDelta([]byte("data: [DONE]\n\n"))
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, "", text)
+
+	_, done, err = replyDelta([]byte(`data: {"error":{"message":"rate limited","type":"upstream_error"}}`))
+	assert.True(t, done)
+	assert.EqualError(t, err, "rate limited")
+
+	text, _, err = replyDelta([]byte(`data: {"choices":[]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "", text)
+}
@@ -0,0 +1,197 @@
+package assistant
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	gouStore "github.com/yaoapp/gou/store"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+)
+
+// CacheSetting the response cache setting of the assistant.
+// Only the deterministic requests (the temperature is 0) are cached, the key is the assistant and the normalized request.
+type CacheSetting struct {
+	Store string `json:"store" yaml:"store"`                 // The store of the responses, e.g. a lru or redis store of the application
+	TTL   int    `json:"ttl,omitempty" yaml:"ttl,omitempty"` // Time To Live of the responses in seconds, default is 3600
+}
+
+// cachedResponse the cached response, the chunks of the stream or the response
+type cachedResponse struct {
+	Chunks   []string    `json:"chunks,omitempty"`
+	Response interface{} `json:"response,omitempty"`
+}
+
+type cacheBypassKey struct{}
+
+// WithoutCache skip reading the response cache, the fresh response is still cached, e.g. the request has the Cache-Control: no-cache header
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// chatCompletions the chat completions of the connector with the response cache, the cached chunks are replayed to the cb
+func (ast *Assistant) chatCompletions(ctx context.Context, messages []map[string]interface{}, options map[string]interface{}, cb func(data []byte) int) (interface{}, *exception.Exception) {
+	key := ast.cacheKey(messages, options, cb != nil)
+	if key == "" {
+		return ast.openai.ChatCompletionsWith(ctx, messages, options, cb)
+	}
+
+	pool, has := gouStore.Pools[ast.Cache.Store]
+	if !has {
+		log.Warn(`[Neo] assistant %s the cache store "%s" is not found`, ast.ID, ast.Cache.Store)
+		return ast.openai.ChatCompletionsWith(ctx, messages, options, cb)
+	}
+
+	if !cacheBypassed(ctx) {
+		if cached, ok := cacheGet(pool, key); ok {
+			if cb == nil {
+				return cached.Response, nil
+			}
+			for _, chunk := range cached.Chunks {
+				if cb([]byte(chunk)) == 0 {
+					break
+				}
+			}
+			return nil, nil
+		}
+	}
+
+	ttl := time.Duration(ast.Cache.TTL) * time.Second
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+
+	if cb == nil {
+		res, ext := ast.openai.ChatCompletionsWith(ctx, messages, options, nil)
+		if ext == nil {
+			cacheSet(pool, key, cachedResponse{Response: res}, ttl)
+		}
+		return res, ext
+	}
+
+	// Cache the completed stream only, the stream is broken by the client or the upstream error is skipped
+	chunks := []string{}
+	completed := false
+	failed := false
+	_, ext := ast.openai.ChatCompletionsWith(ctx, messages, options, func(data []byte) int {
+		line := strings.TrimSpace(string(data))
+		switch {
+		case line == "":
+		case !strings.HasPrefix(line, "data:"):
+			failed = true
+		case strings.Contains(line, "[DONE]"), strings.Contains(line, `"finish_reason":"`):
+			completed = true
+			chunks = append(chunks, string(data))
+		default:
+			chunks = append(chunks, string(data))
+		}
+		return cb(data)
+	})
+
+	if ext == nil && completed && !failed {
+		cacheSet(pool, key, cachedResponse{Chunks: chunks}, ttl)
+	}
+	return nil, ext
+}
+
+// cacheKey the key of the request, empty if the request is not cacheable
+func (ast *Assistant) cacheKey(messages []map[string]interface{}, options map[string]interface{}, stream bool) string {
+	if ast.Cache == nil || ast.Cache.Store == "" || !zeroTemperature(options["temperature"]) {
+		return ""
+	}
+
+	normalized := make([]map[string]interface{}, 0, len(messages))
+	for _, message := range messages {
+		msg := map[string]interface{}{}
+		for key, value := range message {
+			if text, ok := value.(string); ok && key == "content" {
+				value = normalizePrompt(text)
+			}
+			msg[key] = value
+		}
+		normalized = append(normalized, msg)
+	}
+
+	opts := map[string]interface{}{}
+	for key, value := range options {
+		if key != "messages" && key != "stream" {
+			opts[key] = value
+		}
+	}
+
+	// The keys of the maps are sorted, the key is stable
+	raw, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(map[string]interface{}{
+		"connector": ast.Connector,
+		"messages":  normalized,
+		"options":   opts,
+		"stream":    stream,
+	})
+	if err != nil {
+		log.Warn("[Neo] assistant %s cache key: %s", ast.ID, err.Error())
+		return ""
+	}
+	return fmt.Sprintf("neo:cache:%s:%x", ast.ID, sha256.Sum256(raw))
+}
+
+// normalizePrompt the prompt in lower case with the whitespaces collapsed
+func normalizePrompt(prompt string) string {
+	return strings.ToLower(strings.Join(strings.Fields(prompt), " "))
+}
+
+// zeroTemperature whether the temperature is set to 0
+func zeroTemperature(value interface{}) bool {
+	switch v := value.(type) {
+	case int:
+		return v == 0
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	case float32:
+		return v == 0
+	}
+	return false
+}
+
+func cacheGet(pool gouStore.Store, key string) (*cachedResponse, bool) {
+	value, has := pool.Get(key)
+	if !has {
+		return nil, false
+	}
+
+	var raw []byte
+	switch v := value.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return nil, false
+	}
+
+	var cached cachedResponse
+	if err := jsoniter.Unmarshal(raw, &cached); err != nil {
+		log.Warn("[Neo] the cached response %s is invalid: %s", key, err.Error())
+		return nil, false
+	}
+	return &cached, true
+}
+
+func cacheSet(pool gouStore.Store, key string, cached cachedResponse, ttl time.Duration) {
+	raw, err := jsoniter.MarshalToString(cached)
+	if err != nil {
+		log.Warn("[Neo] cache the response %s: %s", key, err.Error())
+		return
+	}
+
+	pool.Set(key, raw, ttl)
+}
@@ -0,0 +1,38 @@
+package assistant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheKey(t *testing.T) {
+	ast := &Assistant{ID: "test", Connector: "gpt-4o", Cache: &CacheSetting{Store: "cache"}}
+	messages := func(content string) []map[string]interface{} {
+		return []map[string]interface{}{
+			{"role": "system", "content": "You are a helpful assistant"},
+			{"role": "user", "content": content},
+		}
+	}
+
+	key := ast.cacheKey(messages("What are the opening hours?"), map[string]interface{}{"temperature": 0, "max_tokens": 100}, true)
+	assert.NotEmpty(t, key)
+	assert.Equal(t, key, ast.cacheKey(messages("  what are the OPENING\nhours? "), map[string]interface{}{"max_tokens": 100, "temperature": 0.0, "stream": true}, true))
+	assert.NotEqual(t, key, ast.cacheKey(messages("What are the opening hours?"), map[string]interface{}{"temperature": 0, "max_tokens": 100}, false))
+	assert.NotEqual(t, key, ast.cacheKey(messages("What are the closing hours?"), map[string]interface{}{"temperature": 0, "max_tokens": 100}, true))
+
+	// Not deterministic
+	assert.Empty(t, ast.cacheKey(messages("What are the opening hours?"), map[string]interface{}{"temperature": 0.7}, true))
+	assert.Empty(t, ast.cacheKey(messages("What are the opening hours?"), map[string]interface{}{}, true))
+
+	// Not enabled
+	ast.Cache = nil
+	assert.Empty(t, ast.cacheKey(messages("What are the opening hours?"), map[string]interface{}{"temperature": 0}, true))
+}
+
+func TestWithoutCache(t *testing.T) {
+	ctx := context.Background()
+	assert.False(t, cacheBypassed(ctx))
+	assert.True(t, cacheBypassed(WithoutCache(ctx)))
+}
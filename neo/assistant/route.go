@@ -0,0 +1,176 @@
+package assistant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	chatMessage "github.com/yaoapp/yao/neo/message"
+)
+
+// maxHandoffs the maximum number of handoffs in a single run, avoid the assistants transfer to each other endlessly
+const maxHandoffs = 3
+
+// routePrompt the prompt used by the dispatcher to choose the route
+const routePrompt = `You are a dispatcher. Choose the assistant best suited to handle the user's latest message.
+Assistants:
+%s
+
+Reply with JSON only: {"assistant_id": "the chosen assistant id, or empty if none fits", "reason": "a short reason"}`
+
+// Route a handoff route of the dispatcher assistant
+type Route struct {
+	AssistantID string `json:"assistant_id" yaml:"assistant_id"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"` // When to transfer, the dispatcher chooses the route by the description
+	Pattern     string `json:"pattern,omitempty" yaml:"pattern,omitempty"`         // Transfer directly if the input matches the regular expression
+}
+
+// Handoff the handoff event of the conversation
+type Handoff struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// route choose the route of the input, returns nil if the conversation stays with the assistant.
+// The routes with a matched pattern win, otherwise the dispatcher chooses by the descriptions.
+func (ast *Assistant) route(ctx context.Context, messages []chatMessage.Message, input string) *Handoff {
+	if len(ast.Routes) == 0 {
+		return nil
+	}
+
+	described := []string{}
+	for _, route := range ast.Routes {
+		if route.AssistantID == "" || route.AssistantID == ast.ID {
+			continue
+		}
+
+		if route.Pattern != "" {
+			re, err := regexp.Compile(route.Pattern)
+			if err != nil {
+				log.Error("[Neo] assistant %s route %s pattern: %s", ast.ID, route.AssistantID, err.Error())
+				continue
+			}
+			if re.MatchString(input) {
+				return &Handoff{From: ast.ID, To: route.AssistantID, Reason: fmt.Sprintf("matched %s", route.Pattern)}
+			}
+		}
+
+		if route.Description != "" {
+			described = append(described, fmt.Sprintf("- %s: %s", route.AssistantID, route.Description))
+		}
+	}
+
+	if len(described) == 0 || ast.openai == nil {
+		return nil
+	}
+
+	// The recent history helps the dispatcher to understand the input
+	conversation := []string{}
+	for _, message := range messages[max(len(messages)-6, 0):] {
+		if message.Role == "user" || message.Role == "assistant" {
+			conversation = append(conversation, fmt.Sprintf("%s: %s", message.Role, message.Text))
+		}
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	res, ext := ast.openai.ChatCompletionsWith(timeout, []map[string]interface{}{
+		{"role": "system", "content": fmt.Sprintf(routePrompt, strings.Join(described, "\n"))},
+		{"role": "user", "content": strings.Join(conversation, "\n\n")},
+	}, nil, nil)
+	if ext != nil {
+		log.Error("[Neo] assistant %s route: %s", ast.ID, ext.Message)
+		return nil
+	}
+
+	text, ext := ast.openai.GetContent(res)
+	if ext != nil {
+		log.Error("[Neo] assistant %s route: %s", ast.ID, ext.Message)
+		return nil
+	}
+	return ast.parseRoute(text)
+}
+
+// parseRoute parse the reply of the dispatcher, only the configured routes are accepted
+func (ast *Assistant) parseRoute(text string) *Handoff {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end <= start {
+		return nil
+	}
+
+	var choice struct {
+		AssistantID string `json:"assistant_id"`
+		Reason      string `json:"reason"`
+	}
+	if err := jsoniter.UnmarshalFromString(text[start:end+1], &choice); err != nil || choice.AssistantID == "" {
+		return nil
+	}
+
+	for _, route := range ast.Routes {
+		if route.AssistantID == choice.AssistantID && route.AssistantID != ast.ID {
+			return &Handoff{From: ast.ID, To: route.AssistantID, Reason: choice.Reason}
+		}
+	}
+	return nil
+}
+
+// handoff transfer the conversation to the target assistant.
+// The handoff event is sent to the client and recorded in the history, the target continues with the same chat history.
+func (ast *Assistant) handoff(c *gin.Context, ctx chatctx.Context, handoff *Handoff, input string, options map[string]interface{}, hops int) error {
+	target, err := Get(handoff.To)
+	if err != nil {
+		err = fmt.Errorf("handoff to %s error: %s", handoff.To, err.Error())
+		chatMessage.New().
+			Assistant(ast.ID, ast.Name, ast.Avatar).
+			Error(err).
+			Done().
+			Write(c.Writer)
+		return err
+	}
+
+	text := fmt.Sprintf("Transferred to %s", target.Name)
+	if handoff.Reason != "" {
+		text = fmt.Sprintf("%s: %s", text, handoff.Reason)
+	}
+
+	msg := chatMessage.New().Assistant(ast.ID, ast.Name, ast.Avatar)
+	msg.Type = "handoff"
+	msg.Text = text
+	msg.Props = map[string]interface{}{"from": handoff.From, "to": handoff.To, "to_name": target.Name, "reason": handoff.Reason}
+	msg.Write(c.Writer)
+
+	ast.saveMessage(ctx, msg)
+
+	log.Trace("[Neo] handoff %s => %s: %s", handoff.From, handoff.To, handoff.Reason)
+	ctx.AssistantID = target.ID
+	return target.execute(c, ctx, input, options, hops+1)
+}
+
+// saveMessage record the event message of the assistant in the chat history, e.g. the handoff and the approval
+func (ast *Assistant) saveMessage(ctx chatctx.Context, msg *chatMessage.Message) {
+	if storage == nil || ctx.Sid == "" {
+		return
+	}
+
+	err := storage.SaveHistory(ctx.Sid, []map[string]interface{}{
+		{
+			"role":             "assistant",
+			"content":          msg.Content(),
+			"name":             ctx.Sid,
+			"assistant_id":     ast.ID,
+			"assistant_name":   ast.Name,
+			"assistant_avatar": ast.Avatar,
+		},
+	}, ctx.ChatID, ctx.Map())
+	if err != nil {
+		log.Error("[Neo] save %s message of chat %s: %s", msg.Type, ctx.ChatID, err.Error())
+	}
+}
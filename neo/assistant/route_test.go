@@ -0,0 +1,52 @@
+package assistant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoute(t *testing.T) {
+	ast := &Assistant{
+		ID: "dispatcher",
+		Routes: []Route{
+			{AssistantID: "dispatcher", Pattern: ".*"},
+			{AssistantID: "billing", Pattern: `(?i)\b(invoice|refund)\b`, Description: "Billing questions"},
+			{AssistantID: "broken", Pattern: `([`},
+			{AssistantID: "support", Description: "Technical support"},
+		},
+	}
+
+	handoff := ast.route(context.Background(), nil, "I need a Refund for my order")
+	if handoff == nil {
+		t.Fatal("the billing route should match")
+	}
+	assert.Equal(t, "dispatcher", handoff.From)
+	assert.Equal(t, "billing", handoff.To)
+
+	// No pattern matches and no connector to choose by the descriptions
+	assert.Nil(t, ast.route(context.Background(), nil, "hello"))
+
+	// No routes
+	assert.Nil(t, (&Assistant{ID: "test"}).route(context.Background(), nil, "refund"))
+}
+
+func TestParseRoute(t *testing.T) {
+	ast := &Assistant{
+		ID:     "dispatcher",
+		Routes: []Route{{AssistantID: "billing"}, {AssistantID: "support"}},
+	}
+
+	handoff := ast.parseRoute("```json\n{\"assistant_id\": \"support\", \"reason\": \"login issue\"}\n```")
+	if handoff == nil {
+		t.Fatal("the support route should be chosen")
+	}
+	assert.Equal(t, "support", handoff.To)
+	assert.Equal(t, "login issue", handoff.Reason)
+
+	// Only the configured routes are accepted
+	assert.Nil(t, ast.parseRoute(`{"assistant_id": "unknown"}`))
+	assert.Nil(t, ast.parseRoute(`{"assistant_id": ""}`))
+	assert.Nil(t, ast.parseRoute("none"))
+}
@@ -105,29 +105,35 @@ type QueryParam struct {
 
 // Assistant the assistant
 type Assistant struct {
-	ID          string                   `json:"assistant_id"`          // Assistant ID
-	Type        string                   `json:"type,omitempty"`        // Assistant Type, default is assistant
-	Name        string                   `json:"name,omitempty"`        // Assistant Name
-	Avatar      string                   `json:"avatar,omitempty"`      // Assistant Avatar
-	Connector   string                   `json:"connector"`             // AI Connector
-	Path        string                   `json:"path,omitempty"`        // Assistant Path
-	BuiltIn     bool                     `json:"built_in,omitempty"`    // Whether this is a built-in assistant
-	Sort        int                      `json:"sort,omitempty"`        // Assistant Sort
-	Description string                   `json:"description,omitempty"` // Assistant Description
-	Tags        []string                 `json:"tags,omitempty"`        // Assistant Tags
-	Readonly    bool                     `json:"readonly,omitempty"`    // Whether this assistant is readonly
-	Mentionable bool                     `json:"mentionable,omitempty"` // Whether this assistant is mentionable
-	Automated   bool                     `json:"automated,omitempty"`   // Whether this assistant is automated
-	Options     map[string]interface{}   `json:"options,omitempty"`     // AI Options
-	Prompts     []Prompt                 `json:"prompts,omitempty"`     // AI Prompts
-	Functions   []Function               `json:"functions,omitempty"`   // Assistant Functions
-	Flows       []map[string]interface{} `json:"flows,omitempty"`       // Assistant Flows
-	Script      *v8.Script               `json:"-" yaml:"-"`            // Assistant Script
-	CreatedAt   int64                    `json:"created_at"`            // Creation timestamp
-	UpdatedAt   int64                    `json:"updated_at"`            // Last update timestamp
-	openai      *api.OpenAI              // OpenAI API
-	vision      bool                     // Whether this assistant supports vision
-	initHook    bool                     // Whether this assistant has an init hook
+	ID           string                   `json:"assistant_id"`            // Assistant ID
+	Type         string                   `json:"type,omitempty"`          // Assistant Type, default is assistant
+	Name         string                   `json:"name,omitempty"`          // Assistant Name
+	Avatar       string                   `json:"avatar,omitempty"`        // Assistant Avatar
+	Connector    string                   `json:"connector"`               // AI Connector
+	Path         string                   `json:"path,omitempty"`          // Assistant Path
+	BuiltIn      bool                     `json:"built_in,omitempty"`      // Whether this is a built-in assistant
+	Sort         int                      `json:"sort,omitempty"`          // Assistant Sort
+	Description  string                   `json:"description,omitempty"`   // Assistant Description
+	Tags         []string                 `json:"tags,omitempty"`          // Assistant Tags
+	Readonly     bool                     `json:"readonly,omitempty"`      // Whether this assistant is readonly
+	Mentionable  bool                     `json:"mentionable,omitempty"`   // Whether this assistant is mentionable
+	Automated    bool                     `json:"automated,omitempty"`     // Whether this assistant is automated
+	Options      map[string]interface{}   `json:"options,omitempty"`       // AI Options
+	Prompts      []Prompt                 `json:"prompts,omitempty"`       // AI Prompts
+	Partials     map[string]string        `json:"partials,omitempty"`      // Prompt template partials, name => template
+	Functions    []Function               `json:"functions,omitempty"`     // Assistant Functions
+	Flows        []map[string]interface{} `json:"flows,omitempty"`         // Assistant Flows
+	Routes       []Route                  `json:"routes,omitempty"`        // Handoff routes, transfer the conversation to the other assistants
+	ToolPolicies []ToolPolicy             `json:"tool_policies,omitempty"` // Tool execution policies, e.g. require the human approval
+	Context      *ContextWindow           `json:"context,omitempty"`       // Context window setting
+	Memory       *MemorySetting           `json:"memory,omitempty"`        // Long-term memory setting
+	Cache        *CacheSetting            `json:"cache,omitempty"`         // Response cache setting
+	Script       *v8.Script               `json:"-" yaml:"-"`              // Assistant Script
+	CreatedAt    int64                    `json:"created_at"`              // Creation timestamp
+	UpdatedAt    int64                    `json:"updated_at"`              // Last update timestamp
+	openai       *api.OpenAI              // OpenAI API
+	vision       bool                     // Whether this assistant supports vision
+	initHook     bool                     // Whether this assistant has an init hook
 }
 
 // VisionCapableModels list of LLM models that support vision capabilities
@@ -0,0 +1,238 @@
+package assistant
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	chatMessage "github.com/yaoapp/yao/neo/message"
+	"github.com/yaoapp/yao/trace"
+)
+
+// Context window strategies
+const (
+	WindowSliding = "sliding" // Drop the oldest history messages (default)
+	WindowSummary = "summary" // Summarize the history messages except the recent ones
+	WindowHybrid  = "hybrid"  // Keep the recent messages as many as the budget allows, summarize the rest
+)
+
+// messageOverhead the tokens of the role and the separators of each message
+const messageOverhead = 4
+
+// defaultSummaryPrompt the prompt used to summarize the history
+const defaultSummaryPrompt = "Summarize the following conversation concisely. Keep the facts, decisions, names and numbers that may be needed to continue the conversation. Reply with the summary only."
+
+// ContextWindow the context window setting of the assistant
+type ContextWindow struct {
+	MaxTokens     int    `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`         // The context budget in tokens, the history is never trimmed if not set
+	Reserved      int    `json:"reserved,omitempty" yaml:"reserved,omitempty"`             // The tokens reserved for the completion
+	Strategy      string `json:"strategy,omitempty" yaml:"strategy,omitempty"`             // sliding, summary or hybrid, default is sliding
+	KeepRecent    int    `json:"keep_recent,omitempty" yaml:"keep_recent,omitempty"`       // The number of recent history messages never summarized, default is 4
+	SummaryPrompt string `json:"summary_prompt,omitempty" yaml:"summary_prompt,omitempty"` // The prompt used to summarize the history
+}
+
+// WindowTrace the trace of the context window, records the dropped and summarized messages for debugging
+type WindowTrace struct {
+	Strategy string           `json:"strategy"`
+	Budget   int              `json:"budget"`
+	Before   int              `json:"before"` // Tokens before fitting
+	After    int              `json:"after"`  // Tokens after fitting
+	Dropped  []DroppedMessage `json:"dropped,omitempty"`
+	Summary  string           `json:"summary,omitempty"`
+	Error    string           `json:"error,omitempty"` // The summary error, the history is dropped instead
+}
+
+// DroppedMessage a history message removed from the context window
+type DroppedMessage struct {
+	Role       string `json:"role"`
+	Tokens     int    `json:"tokens"`
+	Preview    string `json:"preview,omitempty"`
+	Summarized bool   `json:"summarized,omitempty"` // The message is replaced by the summary
+}
+
+// withWindow fit the messages into the context window of the assistant.
+// The prompts, the injected messages after the prompts (the profile and the memories) and the user input are always kept,
+// only the history messages are trimmed or summarized. The trace of the window is recorded in the span of the turn.
+func (ast *Assistant) withWindow(ctx context.Context, messages []chatMessage.Message, injected int) []chatMessage.Message {
+	fitted, window := ast.fitWindow(ctx, messages, injected)
+	if window != nil {
+		if span := trace.FromContext(ctx); span != nil {
+			span.Set("window", window)
+		}
+		raw, _ := jsoniter.MarshalToString(window)
+		log.Trace("[Neo] assistant %s context window: %s", ast.ID, raw)
+	}
+	return fitted
+}
+
+// fitWindow fit the messages into the context window, returns the trace if the history is changed.
+// The history is never trimmed without the max tokens of the context setting.
+func (ast *Assistant) fitWindow(ctx context.Context, messages []chatMessage.Message, injected int) ([]chatMessage.Message, *WindowTrace) {
+	setting := ast.window()
+	budget := setting.MaxTokens - setting.Reserved
+	if setting.MaxTokens <= 0 || budget <= 0 {
+		return messages, nil
+	}
+
+	counts := make([]int, len(messages))
+	total := 0
+	for i, message := range messages {
+		counts[i] = ast.countTokens(message.Text) + messageOverhead
+		total += counts[i]
+	}
+
+	if total <= budget {
+		return messages, nil
+	}
+
+	// The prompts and the injected messages at the beginning and the user input at the end are kept
+	start := min(len(ast.Prompts)+injected, len(messages))
+	end := max(len(messages)-1, start)
+	trace := &WindowTrace{Strategy: setting.Strategy, Budget: budget, Before: total}
+	history := messages[start:end]
+	historyCounts := counts[start:end]
+	fixed := total
+	for _, count := range historyCounts {
+		fixed -= count
+	}
+
+	// Select the messages to summarize
+	summarize := 0
+	switch setting.Strategy {
+	case WindowSummary:
+		summarize = max(len(history)-setting.KeepRecent, 0)
+
+	case WindowHybrid:
+		// Keep the recent messages in 3/4 of the remaining budget, the rest is for the summary
+		available := (budget - fixed) * 3 / 4
+		summarize = len(history)
+		for i := len(history) - 1; i >= 0 && available-historyCounts[i] >= 0; i-- {
+			available -= historyCounts[i]
+			summarize = i
+		}
+	}
+
+	var summary *chatMessage.Message
+	if summarize > 0 {
+		text, err := ast.summarize(ctx, history[:summarize], setting.SummaryPrompt)
+		if err != nil {
+			trace.Error = err.Error()
+		} else {
+			for i, message := range history[:summarize] {
+				trace.Dropped = append(trace.Dropped, droppedOf(message, historyCounts[i], true))
+			}
+			trace.Summary = text
+			summary = chatMessage.New().Map(map[string]interface{}{
+				"role":    "system",
+				"content": "Summary of the earlier conversation:\n" + text,
+			})
+			history, historyCounts = history[summarize:], historyCounts[summarize:]
+			fixed += ast.countTokens(summary.Text) + messageOverhead
+		}
+	}
+
+	// Drop the oldest messages until the rest fits
+	remain := fixed
+	for _, count := range historyCounts {
+		remain += count
+	}
+
+	for len(history) > 0 && remain > budget {
+		trace.Dropped = append(trace.Dropped, droppedOf(history[0], historyCounts[0], false))
+		remain -= historyCounts[0]
+		history, historyCounts = history[1:], historyCounts[1:]
+	}
+	trace.After = remain
+
+	fitted := make([]chatMessage.Message, 0, start+len(history)+2)
+	fitted = append(fitted, messages[:start]...)
+	if summary != nil {
+		fitted = append(fitted, *summary)
+	}
+	fitted = append(fitted, history...)
+	fitted = append(fitted, messages[end:]...)
+	return fitted, trace
+}
+
+// window get the context window setting with the defaults
+func (ast *Assistant) window() ContextWindow {
+	setting := ContextWindow{}
+	if ast.Context != nil {
+		setting = *ast.Context
+	}
+
+	switch setting.Strategy {
+	case WindowSummary, WindowHybrid:
+	default:
+		setting.Strategy = WindowSliding
+	}
+
+	if setting.KeepRecent <= 0 {
+		setting.KeepRecent = 4
+	}
+
+	if setting.SummaryPrompt == "" {
+		setting.SummaryPrompt = defaultSummaryPrompt
+	}
+	return setting
+}
+
+// summarize summarize the messages with the connector of the assistant
+func (ast *Assistant) summarize(ctx context.Context, messages []chatMessage.Message, prompt string) (string, error) {
+	if ast.openai == nil {
+		return "", fmt.Errorf("openai is not initialized")
+	}
+
+	lines := []string{}
+	for _, message := range messages {
+		lines = append(lines, fmt.Sprintf("%s: %s", message.Role, message.Text))
+	}
+
+	res, ext := ast.openai.ChatCompletionsWith(ctx, []map[string]interface{}{
+		{"role": "system", "content": prompt},
+		{"role": "user", "content": strings.Join(lines, "\n\n")},
+	}, nil, nil)
+	if ext != nil {
+		return "", fmt.Errorf("summarize error: %s", ext.Message)
+	}
+
+	text, ext := ast.openai.GetContent(res)
+	if ext != nil {
+		return "", fmt.Errorf("summarize error: %s", ext.Message)
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// countTokens count the tokens with the tokenizer of the connector, estimate it if the model is not supported
+func (ast *Assistant) countTokens(text string) int {
+	if ast.openai != nil {
+		if count, err := ast.openai.Tiktoken(text); err == nil {
+			return count
+		}
+	}
+	return estimateTokens(text)
+}
+
+// estimateTokens estimate the tokens, about 4 ASCII characters or 1 non-ASCII character per token
+func estimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+			continue
+		}
+		other++
+	}
+	return (ascii+3)/4 + other
+}
+
+func droppedOf(message chatMessage.Message, tokens int, summarized bool) DroppedMessage {
+	preview := []rune(message.Text)
+	if len(preview) > 80 {
+		preview = append(preview[:80], '…')
+	}
+	return DroppedMessage{Role: message.Role, Tokens: tokens, Preview: string(preview), Summarized: summarized}
+}
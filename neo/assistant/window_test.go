@@ -0,0 +1,91 @@
+package assistant
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	chatMessage "github.com/yaoapp/yao/neo/message"
+)
+
+func TestFitWindowSliding(t *testing.T) {
+	ast := &Assistant{
+		ID:      "test",
+		Prompts: []Prompt{{Role: "system", Content: "You are a helper"}},
+		Context: &ContextWindow{MaxTokens: 60},
+	}
+
+	messages := windowMessages(ast, 6)
+	fitted, trace := ast.fitWindow(context.Background(), messages, 0)
+	if trace == nil {
+		t.Fatal("the history should be trimmed")
+	}
+
+	assert.Equal(t, WindowSliding, trace.Strategy)
+	assert.LessOrEqual(t, trace.After, 60)
+	assert.Greater(t, trace.Before, 60)
+	assert.NotEmpty(t, trace.Dropped)
+	assert.Equal(t, len(messages)-len(trace.Dropped), len(fitted))
+
+	// The prompts and the input are kept, the oldest history is dropped
+	assert.Equal(t, "You are a helper", fitted[0].Text)
+	assert.Equal(t, "the input", fitted[len(fitted)-1].Text)
+	assert.Equal(t, messages[len(messages)-2].Text, fitted[len(fitted)-2].Text)
+	assert.Equal(t, messages[1].Text[:20], trace.Dropped[0].Preview[:20])
+
+	// Fits the window
+	ast.Context.MaxTokens = 10000
+	fitted, trace = ast.fitWindow(context.Background(), messages, 0)
+	assert.Nil(t, trace)
+	assert.Len(t, fitted, len(messages))
+
+	// The injected messages after the prompts are kept
+	ast.Context.MaxTokens = 60
+	profile := *chatMessage.New().Map(map[string]interface{}{"role": "system", "content": "Reply in English"})
+	injected := append(append([]chatMessage.Message{messages[0], profile}), messages[1:]...)
+	fitted, trace = ast.fitWindow(context.Background(), injected, 1)
+	assert.NotNil(t, trace)
+	assert.Equal(t, "Reply in English", fitted[1].Text)
+
+	// The history is never trimmed without the max tokens
+	ast.Context.MaxTokens = 0
+	fitted, trace = ast.fitWindow(context.Background(), messages, 0)
+	assert.Nil(t, trace)
+	assert.Len(t, fitted, len(messages))
+}
+
+func TestFitWindowSummaryFallback(t *testing.T) {
+	ast := &Assistant{ID: "test", Context: &ContextWindow{MaxTokens: 40, Strategy: WindowSummary, KeepRecent: 2}}
+	messages := windowMessages(ast, 6)
+
+	// No connector, the history is dropped instead
+	fitted, trace := ast.fitWindow(context.Background(), messages, 0)
+	if trace == nil {
+		t.Fatal("the history should be trimmed")
+	}
+	assert.NotEmpty(t, trace.Error)
+	assert.Empty(t, trace.Summary)
+	assert.LessOrEqual(t, trace.After, 40)
+	assert.Equal(t, "the input", fitted[len(fitted)-1].Text)
+}
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, estimateTokens(""))
+	assert.Equal(t, 2, estimateTokens("hello"))
+	assert.Equal(t, 2, estimateTokens("你好"))
+}
+
+func windowMessages(ast *Assistant, n int) []chatMessage.Message {
+	messages := ast.withPrompts(chatctx.Context{}, []chatMessage.Message{})
+	for i := 0; i < n; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		text := strings.Repeat(string(rune('a'+i)), 40) + " message"
+		messages = append(messages, *chatMessage.New().Map(map[string]interface{}{"role": role, "content": text}))
+	}
+	return append(messages, *chatMessage.New().Map(map[string]interface{}{"role": "user", "content": "the input"}))
+}
@@ -0,0 +1,746 @@
+package assistant
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/event"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	chatMessage "github.com/yaoapp/yao/neo/message"
+)
+
+// The status of the workflow run
+const (
+	WorkflowRunning   = "running"
+	WorkflowWaiting   = "waiting" // waiting for the human approval
+	WorkflowCompleted = "completed"
+	WorkflowFailed    = "failed"
+	WorkflowRejected  = "rejected"
+)
+
+// The type of the workflow step
+const (
+	StepLLM       = "llm"
+	StepTool      = "tool"
+	StepCondition = "condition"
+	StepLoop      = "loop"
+	StepApproval  = "approval"
+)
+
+// maxWorkflowSteps the maximum number of the steps executed in a single run, avoid the endless goto
+const maxWorkflowSteps = 1000
+
+// workflowExprRe the expressions of the workflow, e.g. {{ input.name }}
+var workflowExprRe = regexp.MustCompile(`\{\{([\s\S]*?)\}\}`)
+
+// Workflow a multi-step plan of the assistant, defined in the flows of the assistant
+//
+//	{
+//	  "name": "refund",
+//	  "steps": [
+//	    { "id": "order", "type": "tool", "process": "models.order.Find", "args": ["{{ input.order_id }}", {}] },
+//	    { "id": "check", "type": "condition", "if": "{{ order.amount > 100 }}", "then": "approve", "else": "refund" },
+//	    { "id": "approve", "type": "approval", "message": "Refund {{ order.amount }}?", "approvers": ["finance"], "else": "end" },
+//	    { "id": "refund", "type": "tool", "process": "scripts.order.Refund", "args": ["{{ order.id }}"] },
+//	    { "id": "reply", "type": "llm", "prompt": "Tell the user the order {{ order.id }} is refunded" }
+//	  ]
+//	}
+type Workflow struct {
+	Name        string         `json:"name"`
+	Label       string         `json:"label,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Steps       []WorkflowStep `json:"steps"`
+}
+
+// WorkflowStep a step of the workflow.
+// The strings in the prompt, message, args, if and items are expressions enclosed in {{ }},
+// the input of the run and the outputs of the steps are available by the output names.
+type WorkflowStep struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`                // llm, tool, condition, loop, approval
+	Prompt    string         `json:"prompt,omitempty"`    // llm: the user message sent with the prompts of the assistant
+	Process   string         `json:"process,omitempty"`   // tool: the process name
+	Args      []interface{}  `json:"args,omitempty"`      // tool: the process arguments
+	If        string         `json:"if,omitempty"`        // condition: the expression
+	Then      string         `json:"then,omitempty"`      // condition, approval: the step to go if true or approved, default is the next step
+	Else      string         `json:"else,omitempty"`      // condition: the step to go if false, default is the next step. approval: the step to go if rejected, default is to stop
+	Items     string         `json:"items,omitempty"`     // loop: the expression of the items, the item and the index are available in the steps
+	Steps     []WorkflowStep `json:"steps,omitempty"`     // loop: the steps run for each item
+	Max       int            `json:"max,omitempty"`       // loop: the maximum number of the items, default is 100
+	Message   string         `json:"message,omitempty"`   // approval: the message shown to the approver
+	Approvers []string       `json:"approvers,omitempty"` // approval: the roles of the approvers, default is admin. tool: the approvers of the tool policy
+	Output    string         `json:"output,omitempty"`    // the name of the output, default is the step id
+	Goto      string         `json:"goto,omitempty"`      // the step to go after this step, "end" stops the workflow
+}
+
+// WorkflowRun the state of a workflow run, saved after each step to resume
+type WorkflowRun struct {
+	ID          string                 `json:"run_id"`
+	AssistantID string                 `json:"assistant_id"`
+	Workflow    string                 `json:"workflow"`
+	Status      string                 `json:"status"`
+	Step        string                 `json:"step,omitempty"` // the current step id
+	State       map[string]interface{} `json:"state"`          // the input and the outputs of the steps
+	Error       string                 `json:"error,omitempty"`
+}
+
+// WorkflowApproval the decision of the approver
+type WorkflowApproval struct {
+	Approved bool                   `json:"approved"`
+	Comment  string                 `json:"comment,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Approver string                 `json:"-"` // the session of the approver, other than the session started the run
+}
+
+// Workflow get the workflow of the assistant by name
+func (ast *Assistant) Workflow(name string) (*Workflow, error) {
+	for _, flow := range ast.Flows {
+		if flow["name"] != name {
+			continue
+		}
+
+		raw, err := jsoniter.Marshal(flow)
+		if err != nil {
+			return nil, err
+		}
+
+		var workflow Workflow
+		err = jsoniter.Unmarshal(raw, &workflow)
+		if err != nil {
+			return nil, fmt.Errorf("workflow %s: %s", name, err.Error())
+		}
+
+		err = workflow.Validate()
+		if err != nil {
+			return nil, err
+		}
+		return &workflow, nil
+	}
+	return nil, fmt.Errorf("workflow %s not found in assistant %s", name, ast.ID)
+}
+
+// Validate validate the workflow
+func (flow *Workflow) Validate() error {
+	if len(flow.Steps) == 0 {
+		return fmt.Errorf("workflow %s: steps are required", flow.Name)
+	}
+	return flow.validateSteps(flow.Steps, false)
+}
+
+func (flow *Workflow) validateSteps(steps []WorkflowStep, inLoop bool) error {
+	ids := map[string]bool{}
+	for _, step := range steps {
+		if step.ID == "" || step.ID == "end" {
+			return fmt.Errorf("workflow %s: step id is required and can't be end", flow.Name)
+		}
+		if ids[step.ID] {
+			return fmt.Errorf("workflow %s: step %s is duplicated", flow.Name, step.ID)
+		}
+		ids[step.ID] = true
+	}
+
+	for _, step := range steps {
+		switch step.Type {
+		case StepLLM:
+			if step.Prompt == "" {
+				return fmt.Errorf("workflow %s: step %s prompt is required", flow.Name, step.ID)
+			}
+
+		case StepTool:
+			if step.Process == "" {
+				return fmt.Errorf("workflow %s: step %s process is required", flow.Name, step.ID)
+			}
+
+		case StepCondition:
+			if step.If == "" {
+				return fmt.Errorf("workflow %s: step %s if is required", flow.Name, step.ID)
+			}
+
+		case StepLoop:
+			if step.Items == "" || len(step.Steps) == 0 {
+				return fmt.Errorf("workflow %s: step %s items and steps are required", flow.Name, step.ID)
+			}
+			err := flow.validateSteps(step.Steps, true)
+			if err != nil {
+				return err
+			}
+
+		case StepApproval:
+			// The run can only be paused and resumed at the top level
+			if inLoop {
+				return fmt.Errorf("workflow %s: step %s approval is not allowed in the loop", flow.Name, step.ID)
+			}
+
+		default:
+			return fmt.Errorf("workflow %s: step %s type %s is not supported", flow.Name, step.ID, step.Type)
+		}
+
+		for _, target := range []string{step.Then, step.Else, step.Goto} {
+			if target != "" && target != "end" && !ids[target] {
+				return fmt.Errorf("workflow %s: step %s goes to %s, which is not found", flow.Name, step.ID, target)
+			}
+		}
+	}
+	return nil
+}
+
+// RunWorkflow start a new run of the workflow, it runs until the workflow is completed, failed or waiting for the approval.
+// The errors of the steps are recorded in the run instead of returned.
+func (ast *Assistant) RunWorkflow(ctx context.Context, sid string, name string, input interface{}) (*WorkflowRun, error) {
+	flow, err := ast.Workflow(name)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &WorkflowRun{
+		ID:          uuid.New().String(),
+		AssistantID: ast.ID,
+		Workflow:    flow.Name,
+		Status:      WorkflowRunning,
+		Step:        flow.Steps[0].ID,
+		State:       map[string]interface{}{"input": input},
+	}
+	ast.saveWorkflowRun(sid, run)
+	ast.continueWorkflow(ctx, sid, flow, run, 0, false)
+	return run, nil
+}
+
+// ResumeWorkflow resume a saved run. The waiting run requires the approval of an approver other than the session started the run,
+// the failed run retries the failed step, the running run (e.g. interrupted by a restart) continues from the current step.
+func (ast *Assistant) ResumeWorkflow(ctx context.Context, sid string, runID string, approval *WorkflowApproval) (*WorkflowRun, error) {
+	if storage == nil {
+		return nil, fmt.Errorf("storage is not set")
+	}
+
+	data, err := storage.GetWorkflowRun(sid, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := workflowRunOf(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if run.AssistantID != ast.ID {
+		return nil, fmt.Errorf("workflow run %s is not of assistant %s", runID, ast.ID)
+	}
+
+	flow, err := ast.Workflow(run.Workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	index := flow.index(run.Step)
+	if index < 0 {
+		return nil, fmt.Errorf("workflow run %s step %s not found", runID, run.Step)
+	}
+
+	approved := false
+	switch run.Status {
+	case WorkflowWaiting:
+		if approval == nil {
+			return nil, fmt.Errorf("workflow run %s is waiting for the approval", runID)
+		}
+
+		step := flow.Steps[index]
+		if err := checkApprover(approval.Approver, sid, ast.stepApprovers(step)); err != nil {
+			return nil, fmt.Errorf("workflow run %s: %s", runID, err.Error())
+		}
+
+		// The tool requires the approval runs once approved
+		if step.Type == StepTool {
+			if !approval.Approved {
+				run.State[step.output()] = map[string]interface{}{"approved": false, "comment": approval.Comment}
+				run.Status = WorkflowRejected
+				ast.saveWorkflowRun(sid, run)
+				return run, nil
+			}
+			approved = true
+			break
+		}
+
+		run.State[step.output()] = map[string]interface{}{"approved": approval.Approved, "comment": approval.Comment, "data": approval.Data}
+		next := step.Then
+		if !approval.Approved {
+			if step.Else == "" {
+				run.Status = WorkflowRejected
+				ast.saveWorkflowRun(sid, run)
+				return run, nil
+			}
+			next = step.Else
+		}
+		index = flow.next(index, next)
+
+	case WorkflowFailed, WorkflowRunning:
+
+	default:
+		return nil, fmt.Errorf("workflow run %s is %s", runID, run.Status)
+	}
+
+	run.Status = WorkflowRunning
+	run.Error = ""
+	ast.continueWorkflow(ctx, sid, flow, run, index, approved)
+	return run, nil
+}
+
+// continueWorkflow run the top level steps from the index, the run is saved after each step.
+// approved means the tool of the first step is approved, otherwise the tool requires the approval pauses the run.
+func (ast *Assistant) continueWorkflow(ctx context.Context, sid string, flow *Workflow, run *WorkflowRun, index int, approved bool) {
+	executed := 0
+	for index < len(flow.Steps) {
+		step := flow.Steps[index]
+		run.Step = step.ID
+
+		if step.Type == StepTool && !approved && ast.requiresApproval(step.Process) {
+			args, err := workflowValue(step.Args, run.State)
+			if err != nil {
+				ast.failWorkflow(sid, run, ast.stepError(step, err))
+				return
+			}
+			ast.pauseWorkflow(sid, run, step, step.Process, args.([]interface{}), ast.approvalMessage(step.Process, args.([]interface{})))
+			return
+		}
+		approved = false
+
+		if step.Type == StepApproval {
+			message, err := workflowString(step.Message, run.State)
+			if err != nil {
+				ast.failWorkflow(sid, run, err)
+				return
+			}
+			ast.pauseWorkflow(sid, run, step, fmt.Sprintf("workflows.%s.%s", run.Workflow, step.ID), nil, message)
+			return
+		}
+
+		executed++
+		if executed > maxWorkflowSteps {
+			ast.failWorkflow(sid, run, fmt.Errorf("more than %d steps are executed", maxWorkflowSteps))
+			return
+		}
+
+		next, err := ast.runStep(ctx, sid, step, run.State)
+		if err != nil {
+			ast.failWorkflow(sid, run, err)
+			return
+		}
+
+		index = flow.next(index, next)
+		if index < len(flow.Steps) {
+			run.Step = flow.Steps[index].ID
+			ast.saveWorkflowRun(sid, run)
+		}
+	}
+
+	run.Status = WorkflowCompleted
+	run.Step = ""
+	ast.saveWorkflowRun(sid, run)
+}
+
+// pauseWorkflow pause the run until an approver decides, the approval is saved with the run id,
+// so the approvers find it in the approvals and the decision resumes the run
+func (ast *Assistant) pauseWorkflow(sid string, run *WorkflowRun, step WorkflowStep, tool string, args []interface{}, message string) {
+	output := map[string]interface{}{"message": message}
+	if storage != nil {
+		id, err := storage.SaveApproval(sid, map[string]interface{}{
+			"assistant_id": ast.ID,
+			"tool":         tool,
+			"args":         args,
+			"context":      map[string]interface{}{"sid": sid, "run_id": run.ID},
+			"message":      message,
+			"approvers":    ast.stepApprovers(step),
+		})
+		if err != nil {
+			ast.failWorkflow(sid, run, fmt.Errorf("step %s: save the approval error: %s", step.ID, err.Error()))
+			return
+		}
+		output["approval_id"] = id
+	}
+
+	run.State[step.output()] = output
+	run.Status = WorkflowWaiting
+	ast.saveWorkflowRun(sid, run)
+}
+
+// stepApprovers the roles of the approvers of the step, the tool step uses the approvers of the tool policy
+func (ast *Assistant) stepApprovers(step WorkflowStep) []string {
+	if step.Type == StepTool {
+		return ast.approvers(step.Process)
+	}
+
+	if len(step.Approvers) > 0 {
+		return step.Approvers
+	}
+	return DefaultApprovers
+}
+
+// runSteps run the steps in the loop
+func (ast *Assistant) runSteps(ctx context.Context, sid string, steps []WorkflowStep, state map[string]interface{}) (interface{}, error) {
+	var output interface{}
+	executed := 0
+	flow := &Workflow{Steps: steps}
+	for index := 0; index < len(steps); {
+		executed++
+		if executed > maxWorkflowSteps {
+			return nil, fmt.Errorf("more than %d steps are executed", maxWorkflowSteps)
+		}
+
+		// The run can't be paused in the loop
+		step := steps[index]
+		if step.Type == StepTool && ast.requiresApproval(step.Process) {
+			return nil, fmt.Errorf("step %s: %s requires the approval, which is not allowed in the loop", step.ID, step.Process)
+		}
+
+		next, err := ast.runStep(ctx, sid, step, state)
+		if err != nil {
+			return nil, err
+		}
+		output = state[step.output()]
+		index = flow.next(index, next)
+	}
+	return output, nil
+}
+
+// runStep run a step and save the output to the state, returns the step to go
+func (ast *Assistant) runStep(ctx context.Context, sid string, step WorkflowStep, state map[string]interface{}) (string, error) {
+	switch step.Type {
+	case StepLLM:
+		prompt, err := workflowString(step.Prompt, state)
+		if err != nil {
+			return "", ast.stepError(step, err)
+		}
+
+		reply, err := ast.workflowChat(ctx, sid, prompt)
+		if err != nil {
+			return "", ast.stepError(step, err)
+		}
+		state[step.output()] = reply
+		return step.Goto, nil
+
+	case StepTool:
+		args, err := workflowValue(step.Args, state)
+		if err != nil {
+			return "", ast.stepError(step, err)
+		}
+
+		p, err := process.Of(step.Process, args.([]interface{})...)
+		if err != nil {
+			return "", ast.stepError(step, err)
+		}
+
+		res, err := p.WithSID(sid).Exec()
+		if err != nil {
+			return "", ast.stepError(step, err)
+		}
+		state[step.output()] = res
+		return step.Goto, nil
+
+	case StepCondition:
+		value, err := workflowValue(step.If, state)
+		if err != nil {
+			return "", ast.stepError(step, err)
+		}
+
+		ok := workflowTruthy(value)
+		state[step.output()] = ok
+		if ok {
+			return step.Then, nil
+		}
+		return step.Else, nil
+
+	case StepLoop:
+		value, err := workflowValue(step.Items, state)
+		if err != nil {
+			return "", ast.stepError(step, err)
+		}
+
+		items, err := workflowItems(value)
+		if err != nil {
+			return "", ast.stepError(step, err)
+		}
+
+		max := step.Max
+		if max <= 0 {
+			max = 100
+		}
+		if len(items) > max {
+			return "", ast.stepError(step, fmt.Errorf("%d items exceed the max %d", len(items), max))
+		}
+
+		outputs := []interface{}{}
+		for i, item := range items {
+			state["item"] = item
+			state["index"] = i
+			output, err := ast.runSteps(ctx, sid, step.Steps, state)
+			if err != nil {
+				delete(state, "item")
+				delete(state, "index")
+				return "", ast.stepError(step, err)
+			}
+			outputs = append(outputs, output)
+		}
+
+		delete(state, "item")
+		delete(state, "index")
+		state[step.output()] = outputs
+		return step.Goto, nil
+	}
+
+	return "", fmt.Errorf("step %s type %s is not supported", step.ID, step.Type)
+}
+
+// workflowChat send the prompt with the prompts of the assistant, returns the reply
+func (ast *Assistant) workflowChat(ctx context.Context, sid string, prompt string) (string, error) {
+	if ast.openai == nil {
+		return "", fmt.Errorf("assistant %s connector is not set", ast.ID)
+	}
+
+	messages := ast.withPrompts(chatctx.Context{Sid: sid}, []chatMessage.Message{})
+	messages = append(messages, *chatMessage.New().Map(map[string]interface{}{"role": "user", "content": prompt, "name": sid}))
+	req, err := ast.requestMessages(ctx, messages)
+	if err != nil {
+		return "", err
+	}
+
+	// Copy the options, the request options are written to the map
+	options := map[string]interface{}{}
+	for key, value := range ast.Options {
+		options[key] = value
+	}
+
+	res, ext := ast.openai.ChatCompletionsWith(ctx, req, options, nil)
+	if ext != nil {
+		return "", fmt.Errorf("%s", ext.Message)
+	}
+
+	text, ext := ast.openai.GetContent(res)
+	if ext != nil {
+		return "", fmt.Errorf("%s", ext.Message)
+	}
+	return text, nil
+}
+
+func (ast *Assistant) stepError(step WorkflowStep, err error) error {
+	return fmt.Errorf("step %s: %s", step.ID, err.Error())
+}
+
+func (ast *Assistant) failWorkflow(sid string, run *WorkflowRun, err error) {
+	log.Error("[Neo] assistant %s workflow %s run %s: %s", ast.ID, run.Workflow, run.ID, err.Error())
+	run.Status = WorkflowFailed
+	run.Error = err.Error()
+	ast.saveWorkflowRun(sid, run)
+	event.Publish(event.JobFailed, map[string]interface{}{"type": "workflow", "id": run.ID, "assistant_id": ast.ID, "workflow": run.Workflow, "sid": sid, "error": run.Error})
+}
+
+func (ast *Assistant) saveWorkflowRun(sid string, run *WorkflowRun) {
+	if storage == nil {
+		return
+	}
+
+	err := storage.SaveWorkflowRun(sid, run.Map())
+	if err != nil {
+		log.Error("[Neo] assistant %s save workflow run %s: %s", ast.ID, run.ID, err.Error())
+	}
+}
+
+// Map convert the run to map
+func (run *WorkflowRun) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"run_id":       run.ID,
+		"assistant_id": run.AssistantID,
+		"workflow":     run.Workflow,
+		"status":       run.Status,
+		"step":         run.Step,
+		"state":        run.State,
+		"error":        run.Error,
+	}
+}
+
+func workflowRunOf(data map[string]interface{}) (*WorkflowRun, error) {
+	raw, err := jsoniter.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var run WorkflowRun
+	err = jsoniter.Unmarshal(raw, &run)
+	if err != nil {
+		return nil, err
+	}
+
+	if run.State == nil {
+		run.State = map[string]interface{}{}
+	}
+	return &run, nil
+}
+
+// index the index of the step, -1 if not found
+func (flow *Workflow) index(id string) int {
+	for i, step := range flow.Steps {
+		if step.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// next the index of the next step, the length of the steps means the end
+func (flow *Workflow) next(index int, target string) int {
+	switch target {
+	case "":
+		return index + 1
+	case "end":
+		return len(flow.Steps)
+	}
+
+	if i := flow.index(target); i >= 0 {
+		return i
+	}
+	return len(flow.Steps)
+}
+
+func (step WorkflowStep) output() string {
+	if step.Output != "" {
+		return step.Output
+	}
+	return step.ID
+}
+
+// workflowValue evaluate the expressions of the value. A string of a single expression is replaced with the value,
+// otherwise the expressions are replaced with their strings. The maps and the slices are evaluated recursively.
+func workflowValue(value interface{}, state map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		matches := workflowExprRe.FindAllString(v, -1)
+		if len(matches) == 0 {
+			return v, nil
+		}
+
+		trimmed := strings.TrimSpace(v)
+		if len(matches) == 1 {
+			if match := workflowExprRe.FindStringSubmatch(trimmed); match[0] == trimmed {
+				return workflowEval(match[1], state)
+			}
+		}
+
+		var err error
+		res := workflowExprRe.ReplaceAllStringFunc(v, func(stmt string) string {
+			if err != nil {
+				return ""
+			}
+			var value interface{}
+			value, err = workflowEval(workflowExprRe.FindStringSubmatch(stmt)[1], state)
+			if value == nil {
+				return ""
+			}
+			return fmt.Sprint(value)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			value, err := workflowValue(item, state)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = value
+		}
+		return res, nil
+
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			value, err := workflowValue(item, state)
+			if err != nil {
+				return nil, err
+			}
+			res[key] = value
+		}
+		return res, nil
+
+	}
+	return value, nil
+}
+
+// workflowString evaluate the expressions of the text and convert to string
+func workflowString(text string, state map[string]interface{}) (string, error) {
+	value, err := workflowValue(text, state)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", nil
+	}
+	return jsoniter.MarshalToString(value)
+}
+
+func workflowEval(stmt string, state map[string]interface{}) (interface{}, error) {
+	program, err := expr.Compile(strings.TrimSpace(stmt), expr.Env(state), expr.AllowUndefinedVariables())
+	if err != nil {
+		return nil, err
+	}
+	return expr.Run(program, state)
+}
+
+// workflowTruthy false, nil, 0, "", "false" and the empty collections are false
+func workflowTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != "" && v != "false" && v != "0"
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() > 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() != 0
+	}
+	return true
+}
+
+// workflowItems convert the value of the loop to items
+func workflowItems(value interface{}) ([]interface{}, error) {
+	if value == nil {
+		return []interface{}{}, nil
+	}
+
+	if items, ok := value.([]interface{}); ok {
+		return items, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("items should be an array, %T given", value)
+	}
+
+	items := make([]interface{}, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, nil
+}
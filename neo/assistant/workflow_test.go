@@ -0,0 +1,128 @@
+package assistant
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/session"
+)
+
+func TestWorkflowRun(t *testing.T) {
+	SetStorage(&mockStore{data: map[string]map[string]interface{}{}})
+	defer SetStorage(nil)
+
+	ast := &Assistant{ID: "test", Flows: []map[string]interface{}{testWorkflow()}}
+	ctx := context.Background()
+	session.Global().Expire(time.Minute).ID("approver").Set("roles", []string{"admin"})
+
+	run, err := ast.RunWorkflow(ctx, "sid", "review", map[string]interface{}{"items": []interface{}{1, 2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WorkflowWaiting, run.Status, run.Error)
+	assert.Equal(t, "approve", run.Step)
+	assert.Equal(t, []interface{}{false, true, true}, run.State["each"])
+	assert.Equal(t, "Approve 3 items?", run.State["approve"].(map[string]interface{})["message"])
+
+	// Approve
+	_, err = ast.ResumeWorkflow(ctx, "sid", run.ID, nil)
+	assert.Error(t, err)
+
+	// The approver is other than the session started the run
+	_, err = ast.ResumeWorkflow(ctx, "sid", run.ID, &WorkflowApproval{Approved: true, Approver: "sid"})
+	assert.Error(t, err)
+
+	run, err = ast.ResumeWorkflow(ctx, "sid", run.ID, &WorkflowApproval{Approved: true, Comment: "ok", Approver: "approver"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WorkflowCompleted, run.Status, run.Error)
+	assert.Equal(t, true, run.State["done"])
+
+	_, err = ast.ResumeWorkflow(ctx, "sid", run.ID, &WorkflowApproval{Approved: true, Approver: "approver"})
+	assert.Error(t, err)
+
+	// Reject
+	run, err = ast.RunWorkflow(ctx, "sid", "review", map[string]interface{}{"items": []interface{}{5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err = ast.ResumeWorkflow(ctx, "sid", run.ID, &WorkflowApproval{Approved: false, Approver: "approver"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WorkflowRejected, run.Status)
+
+	// Skip the loop and the approval
+	run, err = ast.RunWorkflow(ctx, "sid", "review", map[string]interface{}{"items": []interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WorkflowCompleted, run.Status, run.Error)
+	assert.Nil(t, run.State["each"])
+
+	// Failed step
+	run, err = ast.RunWorkflow(ctx, "sid", "review", map[string]interface{}{"items": "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WorkflowFailed, run.Status)
+	assert.Equal(t, "each", run.Step)
+	assert.Contains(t, run.Error, "step each")
+}
+
+func TestWorkflowValidate(t *testing.T) {
+	ast := &Assistant{ID: "test", Flows: []map[string]interface{}{
+		{"name": "empty"},
+		{"name": "goto", "steps": []interface{}{map[string]interface{}{"id": "a", "type": "condition", "if": "{{ true }}", "then": "b"}}},
+		{"name": "nested", "steps": []interface{}{map[string]interface{}{
+			"id": "a", "type": "loop", "items": "{{ input }}",
+			"steps": []interface{}{map[string]interface{}{"id": "b", "type": "approval"}},
+		}}},
+	}}
+
+	for _, name := range []string{"empty", "goto", "nested", "missing"} {
+		_, err := ast.Workflow(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestWorkflowValue(t *testing.T) {
+	state := map[string]interface{}{"input": map[string]interface{}{"name": "Max", "tags": []interface{}{"a", "b"}}}
+
+	value, err := workflowValue("{{ input.tags }}", state)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, value)
+
+	value, err = workflowValue("Hello {{ input.name }}, {{ len(input.tags) }} tags{{ input.missing }}", state)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello Max, 2 tags", value)
+
+	value, err = workflowValue(map[string]interface{}{"name": "{{ input.name }}", "args": []interface{}{1, "{{ 1 + 1 }}"}}, state)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "Max", "args": []interface{}{1, 2}}, value)
+
+	_, err = workflowValue("{{ input.name + }}", state)
+	assert.Error(t, err)
+
+	assert.False(t, workflowTruthy("false"))
+	assert.False(t, workflowTruthy([]interface{}{}))
+	assert.True(t, workflowTruthy(1.5))
+}
+
+func testWorkflow() map[string]interface{} {
+	return map[string]interface{}{
+		"name": "review",
+		"steps": []interface{}{
+			map[string]interface{}{"id": "check", "type": "condition", "if": "{{ len(input.items) > 0 }}", "else": "end"},
+			map[string]interface{}{
+				"id": "each", "type": "loop", "items": "{{ input.items }}",
+				"steps": []interface{}{map[string]interface{}{"id": "big", "type": "condition", "if": "{{ item > 1 }}"}},
+			},
+			map[string]interface{}{"id": "approve", "type": "approval", "message": "Approve {{ len(each) }} items?"},
+			map[string]interface{}{"id": "finish", "type": "condition", "if": "{{ approve.approved }}", "output": "done"},
+		},
+	}
+}
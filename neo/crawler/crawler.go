@@ -0,0 +1,331 @@
+package crawler
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxRedirects the maximum number of redirects of a request
+const maxRedirects = 10
+
+// New create a new crawler
+func New(setting Setting) (*Crawler, error) {
+	if len(setting.Seeds) == 0 {
+		return nil, fmt.Errorf("seeds is required")
+	}
+
+	// Set default values
+	if setting.MaxDepth <= 0 {
+		setting.MaxDepth = 2
+	}
+
+	if setting.MaxPages <= 0 {
+		setting.MaxPages = 100
+	}
+
+	if setting.Timeout <= 0 {
+		setting.Timeout = 10
+	}
+
+	if setting.MaxBytes <= 0 {
+		setting.MaxBytes = 5 * 1024 * 1024
+	}
+
+	if setting.UserAgent == "" {
+		setting.UserAgent = DefaultUserAgent
+	}
+
+	// The seed domains are allowed by default
+	if len(setting.AllowedDomains) == 0 {
+		for _, seed := range setting.Seeds {
+			u, err := url.Parse(seed)
+			if err != nil {
+				return nil, fmt.Errorf("invalid seed %s: %s", seed, err.Error())
+			}
+			setting.AllowedDomains = append(setting.AllowedDomains, u.Hostname())
+		}
+	}
+
+	crawler := &Crawler{setting: setting, robots: map[string]*Robots{}}
+	crawler.client = crawler.newClient()
+	return crawler, nil
+}
+
+// newClient the HTTP client of the crawler. The addresses are checked when dialing, so every hop of the redirects
+// and every resolved address are checked, and the redirects are checked by the domain rules too.
+func (crawler *Crawler) newClient() *http.Client {
+	dialer := &net.Dialer{Timeout: time.Duration(crawler.setting.Timeout) * time.Second}
+	if !crawler.setting.AllowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || private(ip) {
+				return fmt.Errorf("the address %s is not allowed", host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   time.Duration(crawler.setting.Timeout) * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("the redirect to %s is not allowed", req.URL.Redacted())
+			}
+			if !crawler.domainAllowed(req.URL.Hostname()) {
+				return fmt.Errorf("the redirect to the host %s is not allowed", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+}
+
+// private the address is a loopback, private, link-local or unspecified address
+func private(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// Setting get the crawler settings
+func (crawler *Crawler) Setting() Setting {
+	return crawler.setting
+}
+
+// Run crawl the pages from the seeds (breadth first), the handler is called for each fetched page
+func (crawler *Crawler) Run(ctx context.Context, handler Handler) (*Result, error) {
+	type item struct {
+		url   string
+		depth int
+	}
+
+	result := &Result{Errors: []string{}}
+	visited := map[string]bool{}
+	queue := []item{}
+	for _, seed := range crawler.setting.Seeds {
+		link := resolve(nil, seed)
+		if link == "" {
+			return nil, fmt.Errorf("invalid seed %s", seed)
+		}
+		queue = append(queue, item{url: link, depth: 0})
+	}
+
+	for len(queue) > 0 && result.Fetched < crawler.setting.MaxPages {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		current := queue[0]
+		queue = queue[1:]
+		if visited[current.url] {
+			continue
+		}
+		visited[current.url] = true
+
+		if !crawler.allowed(ctx, current.url) {
+			result.Skipped++
+			continue
+		}
+
+		if result.Fetched > 0 && crawler.setting.Delay > 0 {
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			case <-time.After(time.Duration(crawler.setting.Delay) * time.Millisecond):
+			}
+		}
+
+		page, err := crawler.Fetch(ctx, current.url)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", current.url, err.Error()))
+			continue
+		}
+
+		if page == nil {
+			result.Skipped++
+			continue
+		}
+
+		page.Depth = current.depth
+		result.Fetched++
+		if err := handler(page); err != nil {
+			return result, err
+		}
+
+		if current.depth >= crawler.setting.MaxDepth {
+			continue
+		}
+
+		for _, link := range page.Links {
+			if !visited[link] {
+				queue = append(queue, item{url: link, depth: current.depth + 1})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Fetch fetch a single page, return nil if the content type is not supported
+func (crawler *Crawler) Fetch(ctx context.Context, link string) (*Page, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", crawler.setting.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain,text/markdown;q=0.9")
+
+	resp, err := crawler.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	isHTML := contentType == "" || strings.Contains(contentType, "html")
+	if !isHTML && !strings.HasPrefix(contentType, "text/") {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, crawler.setting.MaxBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	// The final URL after redirects
+	base := resp.Request.URL
+	page := &Page{URL: base.String(), FetchedAt: time.Now(), Links: []string{}}
+	if !isHTML {
+		page.Markdown = strings.TrimSpace(string(body))
+	} else {
+		title, markdown, links, err := Extract(base, body)
+		if err != nil {
+			return nil, err
+		}
+		page.Title = title
+		page.Markdown = markdown
+		for _, link := range links {
+			if crawler.follow(link) {
+				page.Links = append(page.Links, link)
+			}
+		}
+	}
+
+	page.Hash = fmt.Sprintf("%x", sha256.Sum256([]byte(page.Title+"\n"+page.Markdown)))
+	return page, nil
+}
+
+// follow check if the link matches the domain and path rules
+func (crawler *Crawler) follow(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	if !crawler.domainAllowed(u.Hostname()) {
+		return false
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	for _, prefix := range crawler.setting.Exclude {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+
+	if len(crawler.setting.Include) == 0 {
+		return true
+	}
+
+	for _, prefix := range crawler.setting.Include {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// domainAllowed check if the host matches the allowed domains
+func (crawler *Crawler) domainAllowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range crawler.setting.AllowedDomains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "*."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed check the robots.txt rules of the link
+func (crawler *Crawler) allowed(ctx context.Context, link string) bool {
+	if crawler.setting.IgnoreRobots {
+		return true
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path = path + "?" + u.RawQuery
+	}
+	return crawler.robotsOf(ctx, u).Allowed(path)
+}
+
+// robotsOf get the robots.txt rules of the host, the rules are cached for the crawler lifetime
+func (crawler *Crawler) robotsOf(ctx context.Context, u *url.URL) *Robots {
+	origin := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+
+	crawler.mu.Lock()
+	robots, has := crawler.robots[origin]
+	crawler.mu.Unlock()
+	if has {
+		return robots
+	}
+
+	robots = &Robots{}
+	req, err := http.NewRequestWithContext(ctx, "GET", origin+"/robots.txt", nil)
+	if err == nil {
+		req.Header.Set("User-Agent", crawler.setting.UserAgent)
+		resp, err := crawler.client.Do(req)
+		if err == nil {
+			if resp.StatusCode == 200 {
+				robots = ParseRobots(io.LimitReader(resp.Body, 512*1024), crawler.setting.UserAgent)
+			}
+			resp.Body.Close()
+		}
+	}
+
+	crawler.mu.Lock()
+	crawler.robots[origin] = robots
+	crawler.mu.Unlock()
+	return robots
+}
@@ -0,0 +1,169 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Home</title></head><body>
+			<nav><a href="/nav">Nav</a></nav>
+			<main><h1>Welcome</h1><p>Hello <strong>world</strong></p>
+			<a href="/docs">Docs</a> <a href="/private/secret">Secret</a>
+			<a href="https://example.com/external">External</a></main></body></html>`)
+	})
+	mux.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Docs</title></head><body>
+			<h2>Install</h2><ul><li>One</li><li>Two</li></ul>
+			<pre><code>yao start</code></pre><a href="/docs/deep">Deep</a></body></html>`)
+	})
+	mux.HandleFunc("/docs/deep", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><p>Deep page</p></body></html>`)
+	})
+	mux.HandleFunc("/nav", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><p>Nav page</p></body></html>`)
+	})
+	mux.HandleFunc("/private/secret", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><p>Secret</p></body></html>`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCrawlerRun(t *testing.T) {
+	server := testServer()
+	defer server.Close()
+
+	crawler, err := New(Setting{Seeds: []string{server.URL + "/"}, AllowPrivate: true, MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages := map[string]*Page{}
+	res, err := crawler.Run(context.Background(), func(page *Page) error {
+		u, _ := url.Parse(page.URL)
+		pages[u.Path] = page
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Contains(t, pages, "/")
+	assert.Contains(t, pages, "/docs")
+	assert.Contains(t, pages, "/nav")
+	assert.NotContains(t, pages, "/private/secret") // robots.txt
+	assert.NotContains(t, pages, "/docs/deep")      // max depth
+	assert.Equal(t, 1, res.Skipped)
+
+	home := pages["/"]
+	assert.Equal(t, "Home", home.Title)
+	assert.Contains(t, home.Markdown, "# Welcome")
+	assert.Contains(t, home.Markdown, "Hello **world**")
+	assert.NotContains(t, home.Markdown, "Nav")
+	assert.NotEmpty(t, home.Hash)
+	for _, link := range home.Links {
+		assert.True(t, strings.HasPrefix(link, server.URL), link)
+	}
+
+	docs := pages["/docs"]
+	assert.Contains(t, docs.Markdown, "## Install")
+	assert.Contains(t, docs.Markdown, "- One")
+	assert.Contains(t, docs.Markdown, "```\nyao start\n```")
+}
+
+func TestCrawlerLimits(t *testing.T) {
+	server := testServer()
+	defer server.Close()
+
+	crawler, err := New(Setting{Seeds: []string{server.URL + "/"}, AllowPrivate: true, MaxDepth: 5, MaxPages: 2, IgnoreRobots: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	res, err := crawler.Run(context.Background(), func(page *Page) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 2, res.Fetched)
+
+	// Exclude rules
+	crawler, err = New(Setting{Seeds: []string{server.URL + "/"}, AllowPrivate: true, MaxDepth: 5, Exclude: []string{"/docs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = crawler.Run(context.Background(), func(page *Page) error {
+		assert.NotContains(t, page.URL, "/docs")
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestCrawlerRedirect(t *testing.T) {
+	internal := testServer()
+	defer internal.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL+"/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	// The redirects to the other hosts are rejected
+	u, _ := url.Parse(server.URL)
+	seed := fmt.Sprintf("http://localhost:%s/", u.Port())
+	crawler, err := New(Setting{Seeds: []string{seed}, AllowPrivate: true, IgnoreRobots: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = crawler.Fetch(context.Background(), seed)
+	assert.ErrorContains(t, err, "is not allowed")
+
+	// The private addresses are rejected by default
+	crawler, err = New(Setting{Seeds: []string{internal.URL + "/"}, IgnoreRobots: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = crawler.Fetch(context.Background(), internal.URL+"/")
+	assert.ErrorContains(t, err, "is not allowed")
+}
+
+func TestParseRobots(t *testing.T) {
+	content := `
+User-agent: *
+Disallow: /admin
+Allow: /admin/public
+
+User-agent: YaoCrawler
+Disallow: /tmp/*.json$
+`
+	robots := ParseRobots(strings.NewReader(content), "Other/1.0")
+	assert.False(t, robots.Allowed("/admin/users"))
+	assert.True(t, robots.Allowed("/admin/public/page"))
+	assert.True(t, robots.Allowed("/tmp/a.json"))
+
+	robots = ParseRobots(strings.NewReader(content), DefaultUserAgent)
+	assert.True(t, robots.Allowed("/admin/users"))
+	assert.False(t, robots.Allowed("/tmp/a/b.json"))
+	assert.True(t, robots.Allowed("/tmp/a.json.bak"))
+}
@@ -0,0 +1,295 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// removeSelectors the elements are not part of the page content
+var removeSelectors = "script, style, noscript, iframe, svg, canvas, form, nav, header, footer, aside, template"
+
+// contentSelectors the candidates of the main content, in priority order
+var contentSelectors = []string{"main", "article", "[role=main]", "#content", ".content", "body"}
+
+var reBlankLines = regexp.MustCompile(`\n{3,}`)
+var reSpaces = regexp.MustCompile(`[ \t\r\n]+`)
+
+// Extract parse the HTML and return the title, the markdown content and the links of the page
+func Extract(base *url.URL, content []byte) (string, string, []string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(content)))
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+	if title == "" {
+		title = strings.TrimSpace(doc.Find("h1").First().Text())
+	}
+
+	// Collect the links before removing the navigation elements
+	links := []string{}
+	seen := map[string]bool{}
+	doc.Find("a[href]").Each(func(i int, sel *goquery.Selection) {
+		href, _ := sel.Attr("href")
+		link := resolve(base, href)
+		if link != "" && !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	})
+
+	doc.Find(removeSelectors).Remove()
+
+	root := doc.Selection
+	for _, selector := range contentSelectors {
+		if sel := doc.Find(selector).First(); sel.Length() > 0 {
+			root = sel
+			break
+		}
+	}
+
+	md := &markdown{base: base}
+	for _, node := range root.Nodes {
+		md.children(node)
+	}
+
+	return title, md.String(), links, nil
+}
+
+// resolve the href to an absolute URL without the fragment, return empty string if not a http(s) link
+func resolve(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+
+	link := ref
+	if base != nil {
+		link = base.ResolveReference(ref)
+	}
+
+	if link.Scheme != "http" && link.Scheme != "https" {
+		return ""
+	}
+	link.Fragment = ""
+	return link.String()
+}
+
+// markdown the HTML to markdown writer
+type markdown struct {
+	base    *url.URL
+	builder strings.Builder
+	list    []string // the list stack, "ul" or "ol"
+	index   []int    // the ordered list counters
+	pre     bool
+}
+
+// String return the markdown content
+func (md *markdown) String() string {
+	text := reBlankLines.ReplaceAllString(md.builder.String(), "\n\n")
+	return strings.TrimSpace(text)
+}
+
+func (md *markdown) write(s string) {
+	md.builder.WriteString(s)
+}
+
+func (md *markdown) block() {
+	md.write("\n\n")
+}
+
+func (md *markdown) children(node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		md.node(child)
+	}
+}
+
+// inline render the children to a single line string
+func (md *markdown) inline(node *html.Node) string {
+	sub := &markdown{base: md.base}
+	sub.children(node)
+	return strings.TrimSpace(reSpaces.ReplaceAllString(sub.builder.String(), " "))
+}
+
+func (md *markdown) node(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		if md.pre {
+			md.write(node.Data)
+			return
+		}
+		md.write(reSpaces.ReplaceAllString(node.Data, " "))
+		return
+
+	case html.ElementNode:
+	default:
+		md.children(node)
+		return
+	}
+
+	switch node.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(node.Data[1] - '0')
+		md.block()
+		md.write(strings.Repeat("#", level) + " " + md.inline(node))
+		md.block()
+
+	case "p", "div", "section", "article", "main", "figure":
+		md.block()
+		md.children(node)
+		md.block()
+
+	case "br":
+		md.write("  \n")
+
+	case "hr":
+		md.block()
+		md.write("---")
+		md.block()
+
+	case "strong", "b":
+		if text := md.inline(node); text != "" {
+			md.write("**" + text + "**")
+		}
+
+	case "em", "i":
+		if text := md.inline(node); text != "" {
+			md.write("*" + text + "*")
+		}
+
+	case "code":
+		if md.pre {
+			md.children(node)
+			return
+		}
+		md.write("`" + md.inline(node) + "`")
+
+	case "pre":
+		md.block()
+		md.write("```\n")
+		md.pre = true
+		md.children(node)
+		md.pre = false
+		md.write("\n```")
+		md.block()
+
+	case "blockquote":
+		sub := &markdown{base: md.base}
+		sub.children(node)
+		md.block()
+		for _, line := range strings.Split(sub.String(), "\n") {
+			md.write("> " + line + "\n")
+		}
+		md.block()
+
+	case "a":
+		text := md.inline(node)
+		href := resolve(md.base, attr(node, "href"))
+		if href == "" || text == "" {
+			md.write(text)
+			return
+		}
+		md.write(fmt.Sprintf("[%s](%s)", text, href))
+
+	case "img":
+		if alt := attr(node, "alt"); alt != "" {
+			md.write(fmt.Sprintf("![%s](%s)", alt, resolve(md.base, attr(node, "src"))))
+		}
+
+	case "ul", "ol":
+		md.list = append(md.list, node.Data)
+		md.index = append(md.index, 0)
+		md.write("\n")
+		md.children(node)
+		md.list = md.list[:len(md.list)-1]
+		md.index = md.index[:len(md.index)-1]
+		if len(md.list) == 0 {
+			md.block()
+		}
+
+	case "li":
+		depth := len(md.list)
+		prefix := "- "
+		if depth > 0 && md.list[depth-1] == "ol" {
+			md.index[depth-1]++
+			prefix = fmt.Sprintf("%d. ", md.index[depth-1])
+		}
+		indent := ""
+		if depth > 1 {
+			indent = strings.Repeat("  ", depth-1)
+		}
+		md.write("\n" + indent + prefix)
+		md.children(node)
+
+	case "table":
+		md.block()
+		md.table(node)
+		md.block()
+
+	default:
+		md.children(node)
+	}
+}
+
+// table render the table as a markdown table, the first row is the header
+func (md *markdown) table(node *html.Node) {
+	rows := [][]string{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if child.Data == "tr" {
+				row := []string{}
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						row = append(row, strings.ReplaceAll(md.inline(cell), "|", "\\|"))
+					}
+				}
+				rows = append(rows, row)
+				continue
+			}
+			walk(child)
+		}
+	}
+	walk(node)
+
+	if len(rows) == 0 {
+		return
+	}
+
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		md.write("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			md.write("|" + strings.Repeat(" --- |", cols) + "\n")
+		}
+	}
+}
+
+func attr(node *html.Node, name string) string {
+	for _, a := range node.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
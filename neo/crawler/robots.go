@@ -0,0 +1,123 @@
+package crawler
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Robots the robots.txt rules which apply to the crawler
+type Robots struct {
+	rules []robotsRule
+}
+
+type robotsRule struct {
+	allow   bool
+	path    string
+	pattern *regexp.Regexp
+}
+
+// ParseRobots parse the robots.txt content, only keep the rules of the given user agent.
+// The rules of the most specific group win, the "*" group is used as fallback.
+func ParseRobots(reader io.Reader, userAgent string) *Robots {
+	agent := strings.ToLower(userAgent)
+	if i := strings.Index(agent, "/"); i > 0 {
+		agent = agent[:i]
+	}
+
+	groups := map[string][]robotsRule{}
+	current := []string{}
+	inRules := false
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A new group starts after a rule line
+			if inRules {
+				current = []string{}
+				inRules = false
+			}
+			current = append(current, strings.ToLower(value))
+
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue // Empty disallow means allow all
+			}
+			rule := robotsRule{allow: key == "allow", path: value, pattern: robotsPattern(value)}
+			for _, name := range current {
+				groups[name] = append(groups[name], rule)
+			}
+		}
+	}
+
+	// The most specific matched group
+	selected := ""
+	for name := range groups {
+		if name != "*" && strings.Contains(agent, name) && len(name) > len(selected) {
+			selected = name
+		}
+	}
+	if selected == "" {
+		selected = "*"
+	}
+
+	return &Robots{rules: groups[selected]}
+}
+
+// Allowed check if the path is allowed, the longest matched rule wins
+func (robots *Robots) Allowed(path string) bool {
+	if robots == nil || len(robots.rules) == 0 {
+		return true
+	}
+
+	if path == "" {
+		path = "/"
+	}
+
+	allowed := true
+	length := -1
+	for _, rule := range robots.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+
+		// Allow wins when the length is the same
+		if len(rule.path) > length || (len(rule.path) == length && rule.allow) {
+			length = len(rule.path)
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// robotsPattern convert the robots path to a regexp, support * and $ wildcards
+func robotsPattern(path string) *regexp.Regexp {
+	anchored := strings.HasSuffix(path, "$")
+	path = strings.TrimSuffix(path, "$")
+
+	parts := strings.Split(path, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr = expr + "$"
+	}
+	return regexp.MustCompile(expr)
+}
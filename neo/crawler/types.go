@@ -0,0 +1,55 @@
+package crawler
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent the default user agent of the crawler
+const DefaultUserAgent = "YaoCrawler/1.0 (+https://yaoapps.com)"
+
+// Setting the crawler settings
+type Setting struct {
+	Seeds          []string `json:"seeds" yaml:"seeds"`                                         // Seed URLs, the crawl starts from here
+	MaxDepth       int      `json:"max_depth,omitempty" yaml:"max_depth,omitempty"`             // Maximum link depth from the seeds, default is 2
+	MaxPages       int      `json:"max_pages,omitempty" yaml:"max_pages,omitempty"`             // Maximum number of pages to fetch, default is 100
+	AllowedDomains []string `json:"allowed_domains,omitempty" yaml:"allowed_domains,omitempty"` // Allowed domains, defaults to the seed domains
+	Include        []string `json:"include,omitempty" yaml:"include,omitempty"`                 // Only follow URLs whose path has one of these prefixes
+	Exclude        []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`                 // Never follow URLs whose path has one of these prefixes
+	UserAgent      string   `json:"user_agent,omitempty" yaml:"user_agent,omitempty"`           // User agent, used for the requests and the robots.txt rules
+	IgnoreRobots   bool     `json:"ignore_robots,omitempty" yaml:"ignore_robots,omitempty"`     // Whether to ignore the robots.txt rules
+	Timeout        int      `json:"timeout,omitempty" yaml:"timeout,omitempty"`                 // Request timeout in seconds, default is 10
+	Delay          int      `json:"delay,omitempty" yaml:"delay,omitempty"`                     // Delay between requests in milliseconds
+	MaxBytes       int64    `json:"max_bytes,omitempty" yaml:"max_bytes,omitempty"`             // Maximum response body size, default is 5M
+	AllowPrivate   bool     `json:"allow_private,omitempty" yaml:"allow_private,omitempty"`     // Whether to fetch the loopback, private and link-local addresses, the API requests never do
+}
+
+// Page a crawled page
+type Page struct {
+	URL       string    `json:"url"`
+	Title     string    `json:"title,omitempty"`
+	Markdown  string    `json:"markdown"`
+	Depth     int       `json:"depth"`
+	Hash      string    `json:"hash"`
+	Links     []string  `json:"links,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Result the crawl result
+type Result struct {
+	Fetched int      `json:"fetched"`          // Number of pages fetched
+	Skipped int      `json:"skipped"`          // Number of URLs skipped by the rules (robots, domains, content type)
+	Errors  []string `json:"errors,omitempty"` // Non-fatal errors
+}
+
+// Handler the page handler, return an error to stop the crawl
+type Handler func(page *Page) error
+
+// Crawler the web crawler
+type Crawler struct {
+	setting Setting
+	client  *http.Client
+	robots  map[string]*Robots
+	mu      sync.Mutex
+}
@@ -0,0 +1,274 @@
+package document
+
+import (
+	"strings"
+)
+
+// Chunks split the document to chunks with the splitter of the option.
+//
+// semantic: each section is a chunk, the large sections are split by paragraphs, and the large paragraphs by size.
+// markdown: the markdown blocks are merged up to the size across sections, the code blocks and tables are kept whole.
+// recursive: the plain text is split by paragraphs, lines, sentences and words recursively, the structure is ignored.
+//
+// The heading path is kept with the semantic and markdown chunks, so the chunk can be understood without the context.
+func (doc *Document) Chunks(option ChunkOption) []Chunk {
+	if option.Size <= 0 {
+		option.Size = 1024
+	}
+
+	if option.Overlap < 0 || option.Overlap >= option.Size {
+		option.Overlap = 0
+	}
+
+	switch option.Splitter {
+	case SplitterRecursive:
+		return doc.chunksRecursive(option)
+	case SplitterMarkdown:
+		return doc.chunksMarkdown(option)
+	default:
+		return doc.chunksSemantic(option)
+	}
+}
+
+// ValidSplitter check if the splitter type is supported, empty means the default splitter
+func ValidSplitter(splitter string) bool {
+	switch splitter {
+	case "", SplitterSemantic, SplitterRecursive, SplitterMarkdown:
+		return true
+	}
+	return false
+}
+
+func (doc *Document) chunksSemantic(option ChunkOption) []Chunk {
+	chunks := []Chunk{}
+	for _, section := range doc.Sections {
+		path := strings.Join(section.Path, " > ")
+		for _, content := range splitSection(section.Content, option) {
+			if strings.TrimSpace(content) == "" {
+				continue
+			}
+			chunks = append(chunks, Chunk{Index: len(chunks), Path: path, Content: content})
+		}
+
+		// The heading without content
+		if section.Content == "" && path != "" {
+			chunks = append(chunks, Chunk{Index: len(chunks), Path: path, Content: section.Title})
+		}
+	}
+	return chunks
+}
+
+func (doc *Document) chunksMarkdown(option ChunkOption) []Chunk {
+	chunks := []Chunk{}
+	current, path := "", ""
+	flush := func() {
+		if strings.TrimSpace(current) != "" {
+			chunks = append(chunks, Chunk{Index: len(chunks), Path: path, Content: strings.TrimSpace(current)})
+		}
+		current = ""
+	}
+
+	for _, section := range doc.Sections {
+		blocks := markdownBlocks(section.Content)
+		// Keep the heading with its first block
+		if section.Level > 0 {
+			heading := strings.Repeat("#", section.Level) + " " + section.Title
+			if len(blocks) == 0 {
+				blocks = []string{heading}
+			} else {
+				blocks[0] = heading + "\n\n" + blocks[0]
+			}
+		}
+
+		for _, block := range blocks {
+			if current != "" && len([]rune(current))+len([]rune(block))+2 > option.Size {
+				flush()
+			}
+
+			if current == "" {
+				current = block
+				path = strings.Join(section.Path, " > ")
+				continue
+			}
+			current = current + "\n\n" + block
+		}
+	}
+	flush()
+	return chunks
+}
+
+func (doc *Document) chunksRecursive(option ChunkOption) []Chunk {
+	chunks := []Chunk{}
+	pieces := splitRecursive(doc.Markdown(), []string{"\n\n", "\n", ". ", " "}, option.Size-option.Overlap)
+	for i, piece := range pieces {
+		if i > 0 && option.Overlap > 0 {
+			piece = overlapOf(pieces[i-1], option.Overlap) + " " + piece
+		}
+		if piece = strings.TrimSpace(piece); piece != "" {
+			chunks = append(chunks, Chunk{Index: len(chunks), Content: piece})
+		}
+	}
+	return chunks
+}
+
+// markdownBlocks split the markdown content by the empty lines, the fenced code blocks are kept whole
+func markdownBlocks(content string) []string {
+	blocks := []string{}
+	lines := []string{}
+	fenced := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+
+		if trimmed == "" && !fenced {
+			if len(lines) > 0 {
+				blocks = append(blocks, strings.Join(lines, "\n"))
+				lines = nil
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) > 0 {
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+	return blocks
+}
+
+// splitRecursive split the text by the separators in order, merge the small pieces up to the size
+func splitRecursive(text string, separators []string, size int) []string {
+	if len([]rune(text)) <= size {
+		return []string{text}
+	}
+
+	if len(separators) == 0 {
+		return splitSize(text, ChunkOption{Size: size})
+	}
+
+	sep := separators[0]
+	parts := strings.Split(text, sep)
+	if len(parts) == 1 {
+		return splitRecursive(text, separators[1:], size)
+	}
+
+	pieces := []string{}
+	current := ""
+	for _, part := range parts {
+		if len([]rune(part)) > size {
+			if current != "" {
+				pieces = append(pieces, current)
+				current = ""
+			}
+			pieces = append(pieces, splitRecursive(part, separators[1:], size)...)
+			continue
+		}
+
+		if current != "" && len([]rune(current))+len([]rune(sep))+len([]rune(part)) > size {
+			pieces = append(pieces, current)
+			current = ""
+		}
+
+		if current == "" {
+			current = part
+			continue
+		}
+		current = current + sep + part
+	}
+
+	if current != "" {
+		pieces = append(pieces, current)
+	}
+	return pieces
+}
+
+// Text the chunk text used for indexing, with the heading path as the first line
+func (chunk Chunk) Text() string {
+	if chunk.Path == "" {
+		return chunk.Content
+	}
+	return chunk.Path + "\n\n" + chunk.Content
+}
+
+// splitSection split the section content to pieces no larger than the size
+func splitSection(content string, option ChunkOption) []string {
+	if len([]rune(content)) <= option.Size {
+		return []string{content}
+	}
+
+	blocks := []string{}
+	for _, block := range strings.Split(content, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+
+		// The block is too large, split by size
+		if len([]rune(block)) > option.Size {
+			blocks = append(blocks, splitSize(block, option)...)
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+
+	pieces := []string{}
+	current, fresh := "", false
+	for _, block := range blocks {
+		if fresh && len([]rune(current))+len([]rune(block))+2 > option.Size {
+			pieces = append(pieces, current)
+			current, fresh = overlapOf(current, option.Overlap), false
+		}
+
+		if current == "" {
+			current = block
+		} else {
+			current = current + "\n\n" + block
+		}
+		fresh = true
+	}
+
+	if fresh {
+		pieces = append(pieces, current)
+	}
+	return pieces
+}
+
+// splitSize split the text by size, prefer to break at the line ends and the spaces
+func splitSize(text string, option ChunkOption) []string {
+	runes := []rune(text)
+	size := option.Size - option.Overlap
+	if size <= 0 {
+		size = option.Size
+	}
+
+	parts := []string{}
+	for start := 0; start < len(runes); {
+		end := min(start+size, len(runes))
+		if end < len(runes) {
+			for i := end; i > start+size/2; i-- {
+				if runes[i-1] == '\n' || runes[i-1] == ' ' {
+					end = i
+					break
+				}
+			}
+		}
+		parts = append(parts, strings.TrimSpace(string(runes[start:end])))
+		start = end
+	}
+	return parts
+}
+
+// overlapOf get the tail of the text as the overlap of the next piece
+func overlapOf(text string, overlap int) string {
+	if overlap <= 0 {
+		return ""
+	}
+
+	runes := []rune(text)
+	if len(runes) <= overlap {
+		return text
+	}
+	return strings.TrimSpace(string(runes[len(runes)-overlap:]))
+}
@@ -0,0 +1,262 @@
+package document
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/yaoapp/yao/neo/crawler"
+)
+
+// Types the supported document types, content type => type
+var Types = map[string]string{
+	"application/pdf": "pdf",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   "docx",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": "pptx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         "xlsx",
+	"text/markdown":   "md",
+	"text/x-markdown": "md",
+	"text/html":       "html",
+	"text/plain":      "txt",
+}
+
+// parsers the document parsers, type => parser
+var parsers = map[string]Parser{
+	"pdf":      ParsePDF,
+	"docx":     ParseDOCX,
+	"pptx":     ParsePPTX,
+	"xlsx":     ParseXLSX,
+	"md":       ParseMarkdown,
+	"markdown": ParseMarkdown,
+	"html":     ParseHTML,
+	"htm":      ParseHTML,
+	"txt":      ParseText,
+}
+
+// TypeOf get the document type by the content type, fallback to the file extension
+func TypeOf(filename string, contentType string) string {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if typ, has := Types[contentType]; has {
+		return typ
+	}
+
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	if _, has := parsers[ext]; has {
+		return ext
+	}
+	return ""
+}
+
+// Supported check if the document type is supported
+func Supported(filename string, contentType string) bool {
+	return TypeOf(filename, contentType) != ""
+}
+
+// Parse parse the document content
+func Parse(filename string, contentType string, content []byte) (*Document, error) {
+	typ := TypeOf(filename, contentType)
+	parser, has := parsers[typ]
+	if !has {
+		return nil, fmt.Errorf("document type %s is not supported", contentType)
+	}
+
+	doc, err := parser(content)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s error: %s", typ, err.Error())
+	}
+
+	if doc.Title == "" {
+		doc.Title = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	return doc, nil
+}
+
+// ParseMarkdown parse the markdown content, the sections are split by the ATX headings
+func ParseMarkdown(content []byte) (*Document, error) {
+	b := newBuilder("md")
+	fenced := false
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+
+		if !fenced && strings.HasPrefix(trimmed, "#") {
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			title := strings.TrimSpace(trimmed[level:])
+			if level <= 6 && title != "" && (len(trimmed) == level || trimmed[level] == ' ') {
+				b.heading(level, title)
+				continue
+			}
+		}
+		b.line(line)
+	}
+	return b.document(), nil
+}
+
+// ParseHTML parse the HTML content, the main content is converted to markdown first
+func ParseHTML(content []byte) (*Document, error) {
+	title, markdown, _, err := crawler.Extract(nil, content)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := ParseMarkdown([]byte(markdown))
+	if err != nil {
+		return nil, err
+	}
+	doc.Type = "html"
+	doc.Title = title
+	return doc, nil
+}
+
+// ParseText parse the plain text content as a single section
+func ParseText(content []byte) (*Document, error) {
+	b := newBuilder("txt")
+	b.paragraph(string(content))
+	return b.document(), nil
+}
+
+// Markdown render the document to markdown
+func (doc *Document) Markdown() string {
+	parts := []string{}
+	for _, section := range doc.Sections {
+		if section.Level > 0 {
+			parts = append(parts, strings.Repeat("#", section.Level)+" "+section.Title)
+		}
+		if section.Content != "" {
+			parts = append(parts, section.Content)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// builder build the document sections from the blocks of the parsers
+type builder struct {
+	doc     *Document
+	path    []string
+	levels  []int
+	current *Section
+	blocks  []string
+	lines   []string
+}
+
+func newBuilder(typ string) *builder {
+	return &builder{doc: &Document{Type: typ, Sections: []Section{}}}
+}
+
+// heading start a new section
+func (b *builder) heading(level int, title string) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return
+	}
+
+	b.flush()
+	if b.doc.Title == "" && level == 1 {
+		b.doc.Title = title
+	}
+
+	// Pop the headings with the same or deeper level
+	for len(b.levels) > 0 && b.levels[len(b.levels)-1] >= level {
+		b.levels = b.levels[:len(b.levels)-1]
+		b.path = b.path[:len(b.path)-1]
+	}
+	b.levels = append(b.levels, level)
+	b.path = append(b.path, title)
+
+	path := make([]string, len(b.path))
+	copy(path, b.path)
+	b.current = &Section{Title: title, Level: level, Path: path}
+}
+
+// line add a raw markdown line, the empty lines split the blocks
+func (b *builder) line(line string) {
+	if strings.TrimSpace(line) == "" {
+		b.endLines()
+		return
+	}
+	b.lines = append(b.lines, line)
+}
+
+// paragraph add a paragraph block
+func (b *builder) paragraph(text string) {
+	b.endLines()
+	text = strings.TrimSpace(text)
+	if text != "" {
+		b.blocks = append(b.blocks, text)
+	}
+}
+
+// table add a table block, the first row is the header
+func (b *builder) table(rows [][]string) {
+	b.endLines()
+	if table := markdownTable(rows); table != "" {
+		b.blocks = append(b.blocks, table)
+	}
+}
+
+func (b *builder) endLines() {
+	if len(b.lines) > 0 {
+		b.blocks = append(b.blocks, strings.Join(b.lines, "\n"))
+		b.lines = nil
+	}
+}
+
+func (b *builder) flush() {
+	b.endLines()
+	content := strings.TrimSpace(strings.Join(b.blocks, "\n\n"))
+	b.blocks = nil
+
+	section := b.current
+	if section == nil {
+		if content == "" {
+			return
+		}
+		section = &Section{}
+	}
+	section.Content = content
+	b.doc.Sections = append(b.doc.Sections, *section)
+	b.current = nil
+}
+
+func (b *builder) document() *Document {
+	b.flush()
+	return b.doc
+}
+
+// markdownTable render the rows to a markdown table
+func markdownTable(rows [][]string) string {
+	cols := 0
+	filtered := [][]string{}
+	for _, row := range rows {
+		empty := true
+		for i, cell := range row {
+			row[i] = strings.ReplaceAll(strings.Join(strings.Fields(cell), " "), "|", "\\|")
+			if row[i] != "" {
+				empty = false
+			}
+		}
+		if !empty {
+			filtered = append(filtered, row)
+			cols = max(cols, len(row))
+		}
+	}
+
+	if len(filtered) == 0 {
+		return ""
+	}
+
+	lines := []string{}
+	for i, row := range filtered {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", cols))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
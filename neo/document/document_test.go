@@ -0,0 +1,263 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	doc, err := Parse("guide.md", "text/markdown", []byte("# Guide\nIntro\n\n## Install\nRun it\n```\n# not a heading\n```\n## Usage\nUse it"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Guide", doc.Title)
+	assert.Len(t, doc.Sections, 3)
+	assert.Equal(t, []string{"Guide", "Install"}, doc.Sections[1].Path)
+	assert.Contains(t, doc.Sections[1].Content, "# not a heading")
+	assert.Equal(t, []string{"Guide", "Usage"}, doc.Sections[2].Path)
+}
+
+func TestParseDOCX(t *testing.T) {
+	body := `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Manual</w:t></w:r></w:p>
+<w:p><w:r><w:t>Welcome to </w:t></w:r><w:r><w:t>Yao</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="2"/></w:pPr><w:r><w:t>Prices</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Plan</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Price</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>Pro</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>10</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+<w:p><w:pPr><w:numPr/></w:pPr><w:r><w:t>Item</w:t></w:r></w:p>
+</w:body></w:document>`
+	styles := `<?xml version="1.0" encoding="UTF-8"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:style w:styleId="2"><w:name w:val="heading 2"/></w:style></w:styles>`
+
+	doc, err := Parse("manual.docx", "", zipFiles(t, map[string]string{"word/document.xml": body, "word/styles.xml": styles}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Manual", doc.Title)
+	assert.Len(t, doc.Sections, 2)
+	assert.Equal(t, "Welcome to Yao", doc.Sections[0].Content)
+	assert.Equal(t, []string{"Manual", "Prices"}, doc.Sections[1].Path)
+	assert.Contains(t, doc.Sections[1].Content, "| Plan | Price |\n| --- | --- |\n| Pro | 10 |")
+	assert.Contains(t, doc.Sections[1].Content, "- Item")
+}
+
+func TestParsePPTX(t *testing.T) {
+	slide := func(title, text string) string {
+		return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><p:cSld><p:spTree>
+<p:sp><p:nvSpPr><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>%s</a:t></a:r></a:p></p:txBody></p:sp>
+<p:sp><p:txBody><a:p><a:r><a:t>%s</a:t></a:r></a:p></p:txBody></p:sp>
+</p:spTree></p:cSld></p:sld>`, title, text)
+	}
+
+	doc, err := Parse("deck.pptx", "", zipFiles(t, map[string]string{
+		"ppt/slides/slide2.xml":  slide("Second", "World"),
+		"ppt/slides/slide1.xml":  slide("First", "Hello"),
+		"ppt/slides/slide10.xml": slide("", "Last"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, doc.Sections, 3)
+	assert.Equal(t, "First", doc.Sections[0].Title)
+	assert.Equal(t, "Hello", doc.Sections[0].Content)
+	assert.Equal(t, "Second", doc.Sections[1].Title)
+	assert.Equal(t, "Slide 3", doc.Sections[2].Title)
+}
+
+func TestParseXLSX(t *testing.T) {
+	file := excelize.NewFile()
+	file.SetSheetRow("Sheet1", "A1", &[]interface{}{"Name", "Age"})
+	file.SetSheetRow("Sheet1", "A2", &[]interface{}{"Tom", 18})
+	buf, err := file.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := Parse("data.xlsx", "", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, doc.Sections, 1)
+	assert.Equal(t, "Sheet1", doc.Sections[0].Title)
+	assert.Equal(t, "| Name | Age |\n| --- | --- |\n| Tom | 18 |", doc.Sections[0].Content)
+}
+
+func TestParsePDF(t *testing.T) {
+	content := "BT /F1 24 Tf 72 720 Td (Annual Report) Tj ET\n" +
+		"BT /F1 12 Tf 72 690 Td (Revenue grew ) Tj (this year.) Tj 0 -14 Td [(Costs) -300 (fell.)] TJ ET\n" +
+		"BT /F1 18 Tf 72 640 Td <4F75746C6F6F6B> Tj ET"
+
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	w.Write([]byte(content))
+	w.Close()
+
+	// The second page is defined first, the pages are read in the order of the page tree
+	pdf := testPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [4 0 R 3 0 R] /Count 2 /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 6 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		testPDFStream(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>", compressed.Len()), compressed.String()),
+		testPDFStream("<< >>", "BT /F1 12 Tf 72 620 Td (Stable \\(mostly\\).) Tj ET"),
+	)
+
+	doc, err := Parse("report.pdf", "application/pdf", []byte(pdf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Annual Report", doc.Title)
+	assert.Len(t, doc.Sections, 2)
+	assert.Equal(t, "Revenue grew this year.\nCosts fell.", doc.Sections[0].Content)
+	assert.Equal(t, []string{"Annual Report", "Outlook"}, doc.Sections[1].Path)
+	assert.Equal(t, "Stable (mostly).", doc.Sections[1].Content)
+
+	_, err = Parse("bad.pdf", "application/pdf", []byte("not a pdf"))
+	assert.Error(t, err)
+}
+
+func TestParsePDFCIDFont(t *testing.T) {
+	cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+		"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"1 beginbfchar <0003> <0021> endbfchar\n" +
+		"1 beginbfrange <0001> <0002> [<4F60> <597D>] endbfrange\n" +
+		"endcmap CMapName currentdict /CMap defineresource pop end end"
+
+	pages := func(font string) string {
+		return testPDF(
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+			testPDFStream("<< >>", "BT /F1 12 Tf 72 720 Td <000100020003> Tj ET"),
+			font,
+			testPDFStream("<< >>", cmap),
+		)
+	}
+
+	// The Identity-H font is decoded by the ToUnicode map
+	doc, err := Parse("hello.pdf", "application/pdf", []byte(pages("<< /Type /Font /Subtype /Type0 /BaseFont /ABCDEF+SimSun /Encoding /Identity-H /ToUnicode 6 0 R >>")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "你好!", doc.Sections[0].Content)
+
+	// The Identity-H font without the ToUnicode map can not be decoded
+	_, err = Parse("hello.pdf", "application/pdf", []byte(pages("<< /Type /Font /Subtype /Type0 /BaseFont /ABCDEF+SimSun /Encoding /Identity-H >>")))
+	assert.ErrorContains(t, err, "ABCDEF+SimSun")
+}
+
+// testPDF the PDF file of the objects, the objects are numbered from 1
+func testPDF(objects ...string) string {
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n")
+	for i, object := range objects {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF")
+	return b.String()
+}
+
+func testPDFStream(dict string, data string) string {
+	return dict + "\nstream\n" + data + "\nendstream"
+}
+
+func TestChunks(t *testing.T) {
+	paragraphs := []string{}
+	for i := 0; i < 10; i++ {
+		paragraphs = append(paragraphs, fmt.Sprintf("Paragraph %d %s", i, strings.Repeat("x", 40)))
+	}
+	md := "# Guide\n\n" + strings.Join(paragraphs, "\n\n") + "\n\n## Empty\n\n## Small\n\nTiny"
+
+	doc, err := ParseMarkdown([]byte(md))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := doc.Chunks(ChunkOption{Size: 120, Overlap: 20})
+	assert.Greater(t, len(chunks), 3)
+	for i, chunk := range chunks {
+		assert.Equal(t, i, chunk.Index)
+		assert.LessOrEqual(t, len([]rune(chunk.Content)), 120+20+2)
+	}
+
+	assert.Equal(t, "Guide", chunks[0].Path)
+	assert.True(t, strings.HasPrefix(chunks[0].Text(), "Guide\n\nParagraph 0"))
+
+	last := chunks[len(chunks)-1]
+	assert.Equal(t, "Guide > Small", last.Path)
+	assert.Equal(t, "Tiny", last.Content)
+	assert.Equal(t, "Guide > Empty", chunks[len(chunks)-2].Path)
+
+	// Large paragraph
+	doc, _ = ParseText([]byte(strings.Repeat("word ", 100)))
+	chunks = doc.Chunks(ChunkOption{Size: 50})
+	assert.Greater(t, len(chunks), 5)
+}
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestChunksSplitters(t *testing.T) {
+	md := "# Guide\n\nIntro text.\n\n## Code\n\n```\nline 1\n\nline 2\n```\n\n| a | b |\n| --- | --- |\n| 1 | 2 |\n\n## End\n\nBye."
+	doc, err := ParseMarkdown([]byte(md))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Markdown: the code block is kept whole, the headings are kept in the content
+	chunks := doc.Chunks(ChunkOption{Size: 30, Splitter: SplitterMarkdown})
+	found := false
+	for _, chunk := range chunks {
+		if strings.Contains(chunk.Content, "line 1") {
+			found = true
+			assert.Contains(t, chunk.Content, "line 1\n\nline 2\n```")
+			assert.Equal(t, "Guide > Code", chunk.Path)
+		}
+	}
+	assert.True(t, found)
+	assert.Equal(t, "# Guide\n\nIntro text.", chunks[0].Content)
+
+	chunks = doc.Chunks(ChunkOption{Size: 1000, Splitter: SplitterMarkdown})
+	assert.Len(t, chunks, 1)
+
+	// Recursive: the structure is ignored
+	chunks = doc.Chunks(ChunkOption{Size: 40, Overlap: 5, Splitter: SplitterRecursive})
+	assert.Greater(t, len(chunks), 2)
+	for _, chunk := range chunks {
+		assert.Empty(t, chunk.Path)
+		assert.LessOrEqual(t, len([]rune(chunk.Content)), 40+1)
+	}
+
+	assert.True(t, ValidSplitter(""))
+	assert.True(t, ValidSplitter(SplitterRecursive))
+	assert.False(t, ValidSplitter("unknown"))
+}
@@ -0,0 +1,216 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var reHeadingStyle = regexp.MustCompile(`^heading\s*([1-9])$`)
+
+// ParseDOCX parse the Word (docx) document, the heading styles and the tables are preserved
+func ParseDOCX(content []byte) (*Document, error) {
+	files, err := unzip(content)
+	if err != nil {
+		return nil, err
+	}
+
+	body, has := files["word/document.xml"]
+	if !has {
+		return nil, fmt.Errorf("word/document.xml not found")
+	}
+
+	styles := docxStyles(files["word/styles.xml"])
+	b := newBuilder("docx")
+
+	var (
+		text      strings.Builder
+		style     string
+		outline   = -1
+		list      = false
+		tableRows [][]string
+		row       []string
+		cell      []string
+		depth     = 0 // table depth
+	)
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				text.Reset()
+				style, outline, list = "", -1, false
+			case "pStyle":
+				style = xmlAttr(t, "val")
+			case "outlineLvl":
+				if v, err := strconv.Atoi(xmlAttr(t, "val")); err == nil {
+					outline = v
+				}
+			case "numPr":
+				list = true
+			case "t":
+				var value string
+				if err := decoder.DecodeElement(&value, &t); err == nil {
+					text.WriteString(value)
+				}
+			case "tab":
+				text.WriteString("\t")
+			case "br", "cr":
+				text.WriteString("\n")
+			case "tbl":
+				depth++
+				if depth == 1 {
+					tableRows = [][]string{}
+				}
+			case "tr":
+				if depth == 1 {
+					row = []string{}
+				}
+			case "tc":
+				if depth == 1 {
+					cell = []string{}
+				}
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				value := strings.TrimSpace(text.String())
+				if depth > 0 {
+					if value != "" {
+						cell = append(cell, value)
+					}
+					continue
+				}
+
+				level := docxHeadingLevel(style, styles, outline)
+				switch {
+				case level > 0:
+					b.heading(level, value)
+				case list:
+					b.line("- " + value)
+				default:
+					b.paragraph(value)
+				}
+			case "tc":
+				if depth == 1 {
+					row = append(row, strings.Join(cell, " "))
+				}
+			case "tr":
+				if depth == 1 {
+					tableRows = append(tableRows, row)
+				}
+			case "tbl":
+				if depth == 1 {
+					b.table(tableRows)
+				}
+				depth--
+			}
+		}
+	}
+
+	return b.document(), nil
+}
+
+// docxStyles read the style names, style id => lower case style name
+func docxStyles(content []byte) map[string]string {
+	styles := map[string]string{}
+	if content == nil {
+		return styles
+	}
+
+	var data struct {
+		Styles []struct {
+			ID   string `xml:"styleId,attr"`
+			Name struct {
+				Val string `xml:"val,attr"`
+			} `xml:"name"`
+		} `xml:"style"`
+	}
+
+	if err := xml.Unmarshal(content, &data); err != nil {
+		return styles
+	}
+
+	for _, style := range data.Styles {
+		styles[style.ID] = strings.ToLower(style.Name.Val)
+	}
+	return styles
+}
+
+// docxHeadingLevel get the heading level of the paragraph, 0 means not a heading
+func docxHeadingLevel(style string, styles map[string]string, outline int) int {
+	if style != "" {
+		names := []string{strings.ToLower(style)}
+		if name, has := styles[style]; has {
+			names = append(names, name)
+		}
+
+		for _, name := range names {
+			if name == "title" {
+				return 1
+			}
+			if m := reHeadingStyle.FindStringSubmatch(name); m != nil {
+				level, _ := strconv.Atoi(m[1])
+				return level
+			}
+		}
+	}
+
+	if outline >= 0 && outline < 9 {
+		return outline + 1
+	}
+	return 0
+}
+
+// unzip read all the files of the zip archive
+func unzip(content []byte) (map[string][]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		if !strings.HasSuffix(file.Name, ".xml") {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := io.ReadAll(io.LimitReader(rc, 64*1024*1024))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[file.Name] = data
+	}
+	return files, nil
+}
+
+func xmlAttr(t xml.StartElement, name string) string {
+	for _, attr := range t.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
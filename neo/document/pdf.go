@@ -0,0 +1,505 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfLine a text line of the PDF page
+type pdfLine struct {
+	text string
+	size float64
+}
+
+// pdfMaxMissing the maximum ratio of the codes not decoded, the document is rejected over the ratio
+const pdfMaxMissing = 0.1
+
+// ParsePDF parse the PDF document.
+// The pages are read in the order of the page tree, the text is decoded by the ToUnicode maps of the fonts,
+// the headings are detected by the font size. The document is rejected if the text of the fonts can not be decoded,
+// e.g. the CID fonts without ToUnicode, so the garbage text is never indexed.
+func ParsePDF(content []byte) (*Document, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("%PDF")) {
+		return nil, fmt.Errorf("invalid PDF file")
+	}
+
+	file, err := newPDFFile(content)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := &pdfExtractor{file: file, undecodable: map[string]bool{}}
+	pages := [][]pdfLine{}
+	for _, page := range file.pages() {
+		data, err := file.contents(page)
+		if err != nil {
+			return nil, fmt.Errorf("read the page %d error: %s", len(pages)+1, err.Error())
+		}
+
+		if lines := ext.text(data, page.resources); len(lines) > 0 {
+			pages = append(pages, lines)
+		}
+	}
+
+	if ext.missing > 0 && float64(ext.missing) > float64(ext.missing+ext.decoded)*pdfMaxMissing {
+		fonts := []string{}
+		for name := range ext.undecodable {
+			fonts = append(fonts, name)
+		}
+		sort.Strings(fonts)
+		return nil, fmt.Errorf("the text of the fonts %s can not be decoded, the fonts have no ToUnicode map", strings.Join(fonts, ", "))
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no text content found")
+	}
+
+	levels := pdfHeadingLevels(pages)
+	b := newBuilder("pdf")
+	for _, lines := range pages {
+		for _, line := range lines {
+			level := levels[math.Round(line.size*10)/10]
+			if level > 0 && len([]rune(line.text)) <= 120 {
+				b.heading(level, line.text)
+				continue
+			}
+			b.line(line.text)
+		}
+		b.line("") // Page break
+	}
+
+	return b.document(), nil
+}
+
+// pdfHeadingLevels map the font sizes to the heading levels.
+// The most used font size is the body size, the larger sizes are the headings.
+func pdfHeadingLevels(pages [][]pdfLine) map[float64]int {
+	counts := map[float64]int{}
+	for _, lines := range pages {
+		for _, line := range lines {
+			counts[math.Round(line.size*10)/10] += len([]rune(line.text))
+		}
+	}
+
+	body, most := 0.0, -1
+	for size, count := range counts {
+		if count > most || (count == most && size < body) {
+			body, most = size, count
+		}
+	}
+
+	sizes := []float64{}
+	for size := range counts {
+		if size >= body*1.15 {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(sizes)))
+
+	levels := map[float64]int{}
+	for i, size := range sizes {
+		levels[size] = min(i+1, 6)
+	}
+	return levels
+}
+
+// pdfExtractor extract the text lines of the content streams
+type pdfExtractor struct {
+	file        *pdfFile
+	lines       []pdfLine
+	current     strings.Builder
+	fontSize    float64
+	scale       float64
+	size        float64
+	lastY       float64
+	font        *pdfFont
+	depth       int
+	decoded     int             // The number of the runes decoded
+	missing     int             // The number of the codes not decoded
+	undecodable map[string]bool // The names of the fonts with the codes not decoded
+}
+
+// text extract the text lines of the content stream of the page
+func (ext *pdfExtractor) text(data []byte, resources pdfDict) []pdfLine {
+	ext.lines = []pdfLine{}
+	ext.current.Reset()
+	ext.fontSize, ext.scale, ext.size = 12.0, 1.0, 0.0
+	ext.lastY = math.NaN()
+	ext.font = nil
+	ext.run(data, resources)
+	ext.newline()
+	return ext.lines
+}
+
+func (ext *pdfExtractor) newline() {
+	text := strings.Join(strings.Fields(ext.current.String()), " ")
+	if text != "" {
+		ext.lines = append(ext.lines, pdfLine{text: text, size: ext.size})
+	}
+	ext.current.Reset()
+	ext.size = 0
+}
+
+// write write the text of the string shown with the current font
+func (ext *pdfExtractor) write(raw string) {
+	text, missing := ext.font.decode([]byte(raw))
+	if missing > 0 {
+		ext.missing += missing
+		ext.undecodable[ext.font.name] = true
+	}
+	ext.decoded += len([]rune(text))
+	ext.space(text)
+}
+
+// space write the text without decoding, e.g. the spaces
+func (ext *pdfExtractor) space(text string) {
+	ext.current.WriteString(text)
+	ext.size = math.Max(ext.size, ext.fontSize*ext.scale)
+}
+
+// run run the operators of the content stream, the form XObjects are run with their resources
+func (ext *pdfExtractor) run(data []byte, resources pdfDict) {
+	file := ext.file
+	fonts := file.dict(resources["Font"])
+	operands := []pdfToken{}
+	lexer := &pdfLexer{data: data}
+	for {
+		token, ok := lexer.next()
+		if !ok {
+			break
+		}
+
+		if token.kind != pdfOperator {
+			operands = append(operands, token)
+			continue
+		}
+
+		switch token.value {
+		case "BT":
+			ext.scale = 1.0
+
+		case "Tf":
+			if len(operands) >= 2 {
+				ext.fontSize = operands[len(operands)-1].number()
+				ext.font = nil
+				if name := operands[len(operands)-2]; name.kind == pdfName && fonts != nil {
+					if value, has := fonts[name.value[1:]]; has {
+						ext.font = file.font(value)
+					}
+				}
+			}
+
+		case "Tm":
+			if len(operands) >= 6 {
+				b, d := operands[len(operands)-5].number(), operands[len(operands)-3].number()
+				ext.scale = math.Max(math.Sqrt(b*b+d*d), 0.01)
+				y := operands[len(operands)-1].number()
+				if !math.IsNaN(ext.lastY) && math.Abs(y-ext.lastY) > 0.5 {
+					ext.newline()
+				}
+				ext.lastY = y
+			}
+
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if operands[len(operands)-1].number() != 0 {
+					ext.newline()
+				} else if operands[len(operands)-2].number() > 0 {
+					ext.space(" ")
+				}
+			}
+
+		case "T*":
+			ext.newline()
+
+		case "Tj":
+			if len(operands) >= 1 {
+				ext.write(operands[len(operands)-1].value)
+			}
+
+		case "'", "\"":
+			ext.newline()
+			if len(operands) >= 1 {
+				ext.write(operands[len(operands)-1].value)
+			}
+
+		case "TJ":
+			for _, item := range operands {
+				switch item.kind {
+				case pdfString:
+					ext.write(item.value)
+				case pdfNumber:
+					if item.number() <= -200 { // The word spacing is about a quarter of the em
+						ext.space(" ")
+					}
+				}
+			}
+
+		case "ET":
+			ext.newline()
+
+		case "Do":
+			if len(operands) >= 1 && operands[len(operands)-1].kind == pdfName && ext.depth < 8 {
+				ext.form(operands[len(operands)-1].value[1:], resources)
+			}
+
+		case "ID":
+			lexer.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+// form run the form XObject of the resources
+func (ext *pdfExtractor) form(name string, resources pdfDict) {
+	file := ext.file
+	xobjects := file.dict(resources["XObject"])
+	if xobjects == nil {
+		return
+	}
+
+	stream, ok := file.resolve(xobjects[name]).(*pdfStream)
+	if !ok || file.name(stream.dict["Subtype"]) != "Form" {
+		return
+	}
+
+	data, err := file.decode(stream)
+	if err != nil {
+		return
+	}
+
+	if res := file.dict(stream.dict["Resources"]); res != nil {
+		resources = res
+	}
+
+	font, fontSize := ext.font, ext.fontSize
+	ext.depth++
+	ext.run(data, resources)
+	ext.depth--
+	ext.font, ext.fontSize = font, fontSize
+}
+
+const (
+	pdfOperator = iota
+	pdfNumber
+	pdfString
+	pdfName
+	pdfOther
+)
+
+// pdfToken the token of the lexer, the value of the strings is the raw bytes decoded by the fonts
+type pdfToken struct {
+	kind  int
+	value string
+}
+
+func (token pdfToken) number() float64 {
+	v, _ := strconv.ParseFloat(token.value, 64)
+	return v
+}
+
+// pdfLexer the content stream lexer
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func (lexer *pdfLexer) next() (pdfToken, bool) {
+	data := lexer.data
+	for lexer.pos < len(data) {
+		c := data[lexer.pos]
+		switch {
+		case c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0:
+			lexer.pos++
+
+		case c == '%':
+			for lexer.pos < len(data) && data[lexer.pos] != '\n' && data[lexer.pos] != '\r' {
+				lexer.pos++
+			}
+
+		case c == '(':
+			return pdfToken{kind: pdfString, value: string(lexer.literal())}, true
+
+		case c == '<':
+			if lexer.pos+1 < len(data) && data[lexer.pos+1] == '<' {
+				lexer.pos += 2
+				return pdfToken{kind: pdfOther, value: "<<"}, true
+			}
+			return pdfToken{kind: pdfString, value: string(lexer.hex())}, true
+
+		case c == '>':
+			lexer.pos++
+			if lexer.pos < len(data) && data[lexer.pos] == '>' {
+				lexer.pos++
+			}
+			return pdfToken{kind: pdfOther, value: ">>"}, true
+
+		case c == '[' || c == ']' || c == '{' || c == '}':
+			lexer.pos++
+			return pdfToken{kind: pdfOther, value: string(c)}, true
+
+		case c == '/':
+			start := lexer.pos
+			lexer.pos++
+			lexer.word()
+			return pdfToken{kind: pdfName, value: string(data[start:lexer.pos])}, true
+
+		case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			start := lexer.pos
+			lexer.pos++
+			for lexer.pos < len(data) && (data[lexer.pos] == '.' || (data[lexer.pos] >= '0' && data[lexer.pos] <= '9')) {
+				lexer.pos++
+			}
+			return pdfToken{kind: pdfNumber, value: string(data[start:lexer.pos])}, true
+
+		default:
+			start := lexer.pos
+			lexer.word()
+			if lexer.pos == start {
+				lexer.pos++
+			}
+			return pdfToken{kind: pdfOperator, value: string(data[start:lexer.pos])}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+// word read to the next delimiter
+func (lexer *pdfLexer) word() {
+	for lexer.pos < len(lexer.data) && !strings.ContainsRune(" \t\r\n\f\x00()<>[]{}/%", rune(lexer.data[lexer.pos])) {
+		lexer.pos++
+	}
+}
+
+// literal read the literal string (...)
+func (lexer *pdfLexer) literal() []byte {
+	data := lexer.data
+	lexer.pos++ // (
+	depth := 1
+	out := []byte{}
+	for lexer.pos < len(data) {
+		c := data[lexer.pos]
+		lexer.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if lexer.pos >= len(data) {
+				return out
+			}
+			e := data[lexer.pos]
+			lexer.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					value := int(e - '0')
+					for n := 0; n < 2 && lexer.pos < len(data) && data[lexer.pos] >= '0' && data[lexer.pos] <= '7'; n++ {
+						value = value*8 + int(data[lexer.pos]-'0')
+						lexer.pos++
+					}
+					out = append(out, byte(value))
+					continue
+				}
+				out = append(out, e)
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// hex read the hex string <...>
+func (lexer *pdfLexer) hex() []byte {
+	lexer.pos++ // <
+	digits := []byte{}
+	for lexer.pos < len(lexer.data) && lexer.data[lexer.pos] != '>' {
+		c := lexer.data[lexer.pos]
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+		lexer.pos++
+	}
+	lexer.pos++ // >
+
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		v, _ := strconv.ParseUint(string(digits[i*2:i*2+2]), 16, 8)
+		out[i] = byte(v)
+	}
+	return out
+}
+
+// skipInlineImage skip the inline image data to the EI operator
+func (lexer *pdfLexer) skipInlineImage() {
+	i := bytes.Index(lexer.data[lexer.pos:], []byte("EI"))
+	for i >= 0 {
+		end := lexer.pos + i
+		if (end+2 >= len(lexer.data) || strings.ContainsRune(" \t\r\n", rune(lexer.data[end+2]))) &&
+			end > 0 && strings.ContainsRune(" \t\r\n", rune(lexer.data[end-1])) {
+			lexer.pos = end + 2
+			return
+		}
+		next := bytes.Index(lexer.data[end+2:], []byte("EI"))
+		if next < 0 {
+			break
+		}
+		i = end + 2 + next - lexer.pos
+	}
+	lexer.pos = len(lexer.data)
+}
+
+// pdfDecode decode the string bytes, support UTF-16BE (with BOM or the ASCII pattern) and Latin-1
+func pdfDecode(raw []byte) string {
+	utf16be := len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF
+	if utf16be {
+		raw = raw[2:]
+	} else if len(raw) >= 2 && len(raw)%2 == 0 {
+		utf16be = true
+		for i := 0; i < len(raw); i += 2 {
+			if raw[i] != 0 {
+				utf16be = false
+				break
+			}
+		}
+	}
+
+	if utf16be {
+		units := make([]uint16, len(raw)/2)
+		for i := range units {
+			units[i] = uint16(raw[i*2])<<8 | uint16(raw[i*2+1])
+		}
+		return string(utf16.Decode(units))
+	}
+
+	runes := make([]rune, len(raw))
+	for i, c := range raw {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
@@ -0,0 +1,355 @@
+package document
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfFont decode the strings shown with the font to the text.
+// The ToUnicode map is used first, the simple fonts fall back to the encoding and the glyph names,
+// the composite fonts fall back to the UCS-2 encodings. The other fonts are undecodable.
+type pdfFont struct {
+	name        string
+	composite   bool
+	ucs2        bool
+	cmap        *pdfCMap
+	differences map[byte]rune
+	undecodable bool
+}
+
+// font the font of the font dictionary, the fonts are cached by the references
+func (file *pdfFile) font(value interface{}) *pdfFont {
+	ref, isRef := value.(pdfRef)
+	if isRef {
+		if font, has := file.fonts[ref]; has {
+			return font
+		}
+	}
+
+	font := &pdfFont{differences: map[byte]rune{}}
+	dict := file.dict(value)
+	if dict != nil {
+		font.name = file.name(dict["BaseFont"])
+		font.composite = file.name(dict["Subtype"]) == "Type0"
+
+		if _, has := dict["ToUnicode"]; has {
+			if data, err := file.stream(dict["ToUnicode"]); err == nil {
+				font.cmap = parseCMap(data)
+			}
+		}
+
+		encoding := file.resolve(dict["Encoding"])
+		if font.composite {
+			name := file.name(encoding)
+			font.ucs2 = strings.Contains(name, "UCS2") || strings.Contains(name, "UTF16")
+			font.undecodable = font.cmap == nil && !font.ucs2
+		} else {
+			if enc := file.dict(encoding); enc != nil {
+				code := 0
+				for _, item := range file.array(enc["Differences"]) {
+					switch v := file.resolve(item).(type) {
+					case float64:
+						code = int(v)
+					case string:
+						if code >= 0 && code < 256 {
+							font.differences[byte(code)] = pdfGlyphRune(pdfNameOf(v))
+						}
+						code++
+					}
+				}
+			}
+
+			// The symbolic embedded fonts without the encoding use the built-in encoding of the font program
+			if font.cmap == nil && encoding == nil {
+				if desc := file.dict(dict["FontDescriptor"]); desc != nil && int(file.number(desc["Flags"]))&4 != 0 {
+					_, embedded := desc["FontFile2"]
+					_, embedded3 := desc["FontFile3"]
+					_, embedded1 := desc["FontFile"]
+					font.undecodable = embedded || embedded1 || embedded3
+				}
+			}
+		}
+	}
+
+	if isRef {
+		file.fonts[ref] = font
+	}
+	return font
+}
+
+// decode decode the string to the text, return the text and the number of the codes not decoded
+func (font *pdfFont) decode(raw []byte) (string, int) {
+	if font == nil {
+		return pdfDecode(raw), 0
+	}
+
+	if font.cmap != nil {
+		size := 1
+		if font.composite {
+			size = 2
+		}
+		return font.cmap.decode(raw, size)
+	}
+
+	if font.undecodable {
+		n := len(raw)
+		if font.composite {
+			n = (n + 1) / 2
+		}
+		return "", n
+	}
+
+	if font.ucs2 {
+		return pdfUTF16(raw), 0
+	}
+
+	var b strings.Builder
+	missing := 0
+	for _, c := range raw {
+		if r, has := font.differences[c]; has {
+			if r < 0 {
+				missing++
+				continue
+			}
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteRune(pdfWinAnsi(c))
+	}
+	return b.String(), missing
+}
+
+// pdfCMap the ToUnicode map
+type pdfCMap struct {
+	spaces []pdfCodeSpace
+	chars  map[uint32]string
+	ranges []pdfCodeRange
+}
+
+type pdfCodeSpace struct {
+	low  []byte
+	high []byte
+}
+
+type pdfCodeRange struct {
+	low   uint32
+	high  uint32
+	dst   []uint16 // The last unit is increased by the offset of the code
+	array []string // The destinations of the codes
+}
+
+// parseCMap parse the codespace ranges, bfchar and bfrange of the ToUnicode map
+func parseCMap(data []byte) *pdfCMap {
+	cmap := &pdfCMap{chars: map[uint32]string{}}
+	operands := []pdfToken{}
+	lexer := &pdfLexer{data: data}
+	for {
+		token, ok := lexer.next()
+		if !ok {
+			break
+		}
+
+		if token.kind != pdfOperator {
+			operands = append(operands, token)
+			continue
+		}
+
+		switch token.value {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				low, high := []byte(operands[i].value), []byte(operands[i+1].value)
+				if len(low) > 0 && len(low) == len(high) {
+					cmap.spaces = append(cmap.spaces, pdfCodeSpace{low: low, high: high})
+				}
+			}
+
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				dst := operands[i+1]
+				if dst.kind == pdfName {
+					if r := pdfGlyphRune(dst.value[1:]); r >= 0 {
+						cmap.chars[pdfCode([]byte(operands[i].value))] = string(r)
+					}
+					continue
+				}
+				cmap.chars[pdfCode([]byte(operands[i].value))] = pdfUTF16([]byte(dst.value))
+			}
+
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); {
+				r := pdfCodeRange{low: pdfCode([]byte(operands[i].value)), high: pdfCode([]byte(operands[i+1].value))}
+				i += 2
+				if operands[i].kind == pdfOther && operands[i].value == "[" {
+					i++
+					for i < len(operands) && !(operands[i].kind == pdfOther && operands[i].value == "]") {
+						r.array = append(r.array, pdfUTF16([]byte(operands[i].value)))
+						i++
+					}
+					i++
+				} else {
+					r.dst = pdfUnits([]byte(operands[i].value))
+					i++
+				}
+				if r.high >= r.low {
+					cmap.ranges = append(cmap.ranges, r)
+				}
+			}
+		}
+
+		if strings.HasPrefix(token.value, "end") || strings.HasPrefix(token.value, "begin") {
+			operands = operands[:0]
+		}
+	}
+	return cmap
+}
+
+// decode decode the codes of the string, the size is the code size without the codespace ranges
+func (cmap *pdfCMap) decode(raw []byte, size int) (string, int) {
+	var b strings.Builder
+	missing := 0
+	for pos := 0; pos < len(raw); {
+		n := cmap.size(raw[pos:], size)
+		code := pdfCode(raw[pos:min(pos+n, len(raw))])
+		pos += n
+
+		if text, has := cmap.lookup(code); has {
+			b.WriteString(text)
+			continue
+		}
+		missing++
+	}
+	return b.String(), missing
+}
+
+// size the size of the code at the beginning of the bytes by the codespace ranges
+func (cmap *pdfCMap) size(raw []byte, size int) int {
+	for _, space := range cmap.spaces {
+		n := len(space.low)
+		if n > len(raw) {
+			continue
+		}
+		matched := true
+		for i := 0; i < n; i++ {
+			if raw[i] < space.low[i] || raw[i] > space.high[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return n
+		}
+	}
+
+	if len(cmap.spaces) > 0 {
+		size = len(cmap.spaces[0].low)
+	}
+	return max(size, 1)
+}
+
+func (cmap *pdfCMap) lookup(code uint32) (string, bool) {
+	if text, has := cmap.chars[code]; has {
+		return text, true
+	}
+
+	for _, r := range cmap.ranges {
+		if code < r.low || code > r.high {
+			continue
+		}
+
+		offset := code - r.low
+		if r.array != nil {
+			if int(offset) < len(r.array) {
+				return r.array[offset], true
+			}
+			return "", false
+		}
+
+		if len(r.dst) == 0 {
+			return "", false
+		}
+		units := append([]uint16{}, r.dst...)
+		units[len(units)-1] += uint16(offset)
+		return string(utf16.Decode(units)), true
+	}
+	return "", false
+}
+
+// pdfCode the code of the bytes, big-endian
+func pdfCode(raw []byte) uint32 {
+	code := uint32(0)
+	for _, c := range raw {
+		code = code<<8 | uint32(c)
+	}
+	return code
+}
+
+// pdfUnits the UTF-16BE units of the bytes
+func pdfUnits(raw []byte) []uint16 {
+	units := make([]uint16, len(raw)/2)
+	for i := range units {
+		units[i] = uint16(raw[i*2])<<8 | uint16(raw[i*2+1])
+	}
+	return units
+}
+
+// pdfUTF16 decode the UTF-16BE bytes
+func pdfUTF16(raw []byte) string {
+	return string(utf16.Decode(pdfUnits(raw)))
+}
+
+// pdfWinAnsi the rune of the WinAnsiEncoding code, the codes out of 0x80-0x9F are the Latin-1 codes
+func pdfWinAnsi(c byte) rune {
+	if c >= 0x80 && c <= 0x9F {
+		if r := pdfWinAnsiRunes[c-0x80]; r != 0 {
+			return r
+		}
+	}
+	return rune(c)
+}
+
+var pdfWinAnsiRunes = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// pdfGlyphs the runes of the common glyph names
+var pdfGlyphs = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "parenleft": '(', "parenright": ')', "asterisk": '*', "plus": '+',
+	"comma": ',', "hyphen": '-', "period": '.', "slash": '/', "colon": ':', "semicolon": ';', "less": '<',
+	"equal": '=', "greater": '>', "question": '?', "at": '@', "bracketleft": '[', "backslash": '\\',
+	"bracketright": ']', "asciicircum": '^', "underscore": '_', "grave": '`', "braceleft": '{', "bar": '|',
+	"braceright": '}', "asciitilde": '~', "zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9', "quoteleft": '‘', "quoteright": '’',
+	"quotedblleft": '“', "quotedblright": '”', "quotesinglbase": '‚', "quotedblbase": '„', "endash": '–',
+	"emdash": '—', "bullet": '•', "ellipsis": '…', "dagger": '†', "daggerdbl": '‡', "trademark": '™',
+	"copyright": '©', "registered": '®', "degree": '°', "section": '§', "paragraph": '¶', "minus": '−',
+	"multiply": '×', "divide": '÷', "fi": 'ﬁ', "fl": 'ﬂ', "nbspace": ' ', "Euro": '€', "sterling": '£',
+	"yen": '¥', "cent": '¢',
+}
+
+// pdfGlyphRune the rune of the glyph name, -1 if the name is unknown, e.g. the glyph names of the subset fonts
+func pdfGlyphRune(name string) rune {
+	name = strings.SplitN(name, ".", 2)[0]
+	if r, has := pdfGlyphs[name]; has {
+		return r
+	}
+
+	if len(name) == 1 && ((name[0] >= 'a' && name[0] <= 'z') || (name[0] >= 'A' && name[0] <= 'Z')) {
+		return rune(name[0])
+	}
+
+	if strings.HasPrefix(name, "uni") && len(name) == 7 {
+		if v, err := strconv.ParseUint(name[3:], 16, 32); err == nil {
+			return rune(v)
+		}
+	}
+
+	if strings.HasPrefix(name, "u") && len(name) >= 5 && len(name) <= 7 {
+		if v, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return rune(v)
+		}
+	}
+	return -1
+}
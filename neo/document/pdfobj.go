@@ -0,0 +1,445 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// pdfMaxStream the maximum size of a decoded stream
+const pdfMaxStream = 64 * 1024 * 1024
+
+var (
+	pdfObjRe     = regexp.MustCompile(`\b(\d+)\s+(\d+)\s+obj\b`)
+	pdfTrailerRe = regexp.MustCompile(`trailer\s*<<`)
+)
+
+// pdfRef the indirect reference, e.g. 12 0 R
+type pdfRef struct {
+	num int
+	gen int
+}
+
+// pdfDict the dictionary, the keys are the names without the slash.
+// The values are float64, bool, nil, the names (string starts with /), the strings ([]byte), []interface{}, pdfDict or pdfRef
+type pdfDict map[string]interface{}
+
+// pdfStream the stream object, the data is not decoded
+type pdfStream struct {
+	dict pdfDict
+	data []byte
+}
+
+// pdfFile the objects of the PDF file
+type pdfFile struct {
+	objects map[int]interface{}
+	root    pdfRef
+	fonts   map[pdfRef]*pdfFont
+}
+
+// newPDFFile read the objects of the PDF file, the objects of the object streams included.
+// The objects are read in the file order, so the objects of the incremental updates replace the former ones.
+func newPDFFile(content []byte) (*pdfFile, error) {
+	file := &pdfFile{objects: map[int]interface{}{}, fonts: map[pdfRef]*pdfFont{}}
+	trailers := []pdfDict{}
+
+	offset := 0
+	for offset < len(content) {
+		loc := pdfObjRe.FindSubmatchIndex(content[offset:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(content[offset+loc[2] : offset+loc[3]]))
+		lexer := &pdfLexer{data: content, pos: offset + loc[1]}
+		offset += loc[1]
+
+		value, ok := lexer.object()
+		if !ok {
+			continue
+		}
+
+		dict, isDict := value.(pdfDict)
+		if isDict {
+			if data, end, has := pdfStreamData(content, lexer.pos, dict); has {
+				value = &pdfStream{dict: dict, data: data}
+				lexer.pos = end
+				if pdfNameOf(dict["Type"]) == "XRef" {
+					trailers = append(trailers, dict)
+				}
+			}
+		}
+		file.objects[num] = value
+		offset = lexer.pos
+	}
+
+	for _, loc := range pdfTrailerRe.FindAllIndex(content, -1) {
+		lexer := &pdfLexer{data: content, pos: loc[1] - 2}
+		if value, ok := lexer.object(); ok {
+			if dict, ok := value.(pdfDict); ok {
+				trailers = append(trailers, dict)
+			}
+		}
+	}
+
+	for _, trailer := range trailers {
+		if _, has := trailer["Encrypt"]; has {
+			return nil, fmt.Errorf("the encrypted PDF is not supported")
+		}
+		if root, ok := trailer["Root"].(pdfRef); ok {
+			file.root = root
+		}
+	}
+
+	// The objects of the object streams
+	for _, value := range file.objects {
+		stream, ok := value.(*pdfStream)
+		if !ok || pdfNameOf(stream.dict["Type"]) != "ObjStm" {
+			continue
+		}
+		file.readObjectStream(stream)
+	}
+
+	// The catalog without the trailer
+	if file.root.num == 0 {
+		for num, value := range file.objects {
+			if dict, ok := value.(pdfDict); ok && pdfNameOf(dict["Type"]) == "Catalog" {
+				file.root = pdfRef{num: num}
+				break
+			}
+		}
+	}
+	return file, nil
+}
+
+// readObjectStream read the objects compressed in the object stream, the objects defined directly are kept
+func (file *pdfFile) readObjectStream(stream *pdfStream) {
+	data, err := file.decode(stream)
+	if err != nil {
+		return
+	}
+
+	n, first := int(file.number(stream.dict["N"])), int(file.number(stream.dict["First"]))
+	if first <= 0 || first > len(data) {
+		return
+	}
+
+	header := &pdfLexer{data: data[:first]}
+	for i := 0; i < n; i++ {
+		num, ok1 := header.next()
+		off, ok2 := header.next()
+		if !ok1 || !ok2 {
+			return
+		}
+
+		id, _ := strconv.Atoi(num.value)
+		pos := first + int(off.number())
+		if _, has := file.objects[id]; has || pos >= len(data) {
+			continue
+		}
+
+		lexer := &pdfLexer{data: data, pos: pos}
+		if value, ok := lexer.object(); ok {
+			file.objects[id] = value
+		}
+	}
+}
+
+// pdfStreamData read the stream data after the dictionary, return the data and the end offset
+func pdfStreamData(content []byte, pos int, dict pdfDict) ([]byte, int, bool) {
+	for pos < len(content) && bytes.IndexByte([]byte(" \t\r\n\f\x00"), content[pos]) >= 0 {
+		pos++
+	}
+	if !bytes.HasPrefix(content[pos:], []byte("stream")) {
+		return nil, pos, false
+	}
+
+	// The stream data starts after EOL
+	start := pos + len("stream")
+	if start < len(content) && content[start] == '\r' {
+		start++
+	}
+	if start < len(content) && content[start] == '\n' {
+		start++
+	}
+
+	// Use the direct length if the endstream keyword follows
+	if length, ok := dict["Length"].(float64); ok && length >= 0 && start+int(length) <= len(content) {
+		end := start + int(length)
+		rest := bytes.TrimLeft(content[end:min(end+16, len(content))], " \t\r\n\f\x00")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			return content[start:end], end + bytes.Index(content[end:], []byte("endstream")) + len("endstream"), true
+		}
+	}
+
+	end := bytes.Index(content[start:], []byte("endstream"))
+	if end < 0 {
+		return content[start:], len(content), true
+	}
+	return bytes.TrimRight(content[start:start+end], "\r\n"), start + end + len("endstream"), true
+}
+
+// resolve follow the indirect references
+func (file *pdfFile) resolve(value interface{}) interface{} {
+	for i := 0; i < 16; i++ {
+		ref, ok := value.(pdfRef)
+		if !ok {
+			return value
+		}
+		value = file.objects[ref.num]
+	}
+	return nil
+}
+
+// dict the dictionary of the value, the dictionary of the stream for the streams
+func (file *pdfFile) dict(value interface{}) pdfDict {
+	switch v := file.resolve(value).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// array the array of the value, the single value is an array of one
+func (file *pdfFile) array(value interface{}) []interface{} {
+	switch v := file.resolve(value).(type) {
+	case []interface{}:
+		return v
+	case nil:
+		return nil
+	default:
+		return []interface{}{value}
+	}
+}
+
+func (file *pdfFile) number(value interface{}) float64 {
+	v, _ := file.resolve(value).(float64)
+	return v
+}
+
+func (file *pdfFile) name(value interface{}) string {
+	return pdfNameOf(file.resolve(value))
+}
+
+// pdfNameOf the name without the slash, empty if the value is not a name
+func pdfNameOf(value interface{}) string {
+	if name, ok := value.(string); ok && len(name) > 0 && name[0] == '/' {
+		return name[1:]
+	}
+	return ""
+}
+
+// stream the decoded data of the stream value
+func (file *pdfFile) stream(value interface{}) ([]byte, error) {
+	stream, ok := file.resolve(value).(*pdfStream)
+	if !ok {
+		return nil, fmt.Errorf("the object is not a stream")
+	}
+	return file.decode(stream)
+}
+
+// decode decode the stream data by the filters
+func (file *pdfFile) decode(stream *pdfStream) ([]byte, error) {
+	data := stream.data
+	parms := file.array(stream.dict["DecodeParms"])
+	for i, filter := range file.array(stream.dict["Filter"]) {
+		if i < len(parms) {
+			if parm := file.dict(parms[i]); parm != nil && file.number(parm["Predictor"]) > 1 {
+				return nil, fmt.Errorf("the predictor of the filter is not supported")
+			}
+		}
+
+		var err error
+		switch name := file.name(filter); name {
+		case "FlateDecode", "Fl":
+			var reader io.ReadCloser
+			reader, err = zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			decoded, rerr := io.ReadAll(io.LimitReader(reader, pdfMaxStream))
+			reader.Close()
+			if rerr != nil && len(decoded) == 0 {
+				return nil, rerr
+			}
+			data = decoded
+
+		case "ASCIIHexDecode", "AHx":
+			hexData := bytes.Map(func(r rune) rune {
+				if (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F') {
+					return r
+				}
+				return -1
+			}, bytes.SplitN(data, []byte(">"), 2)[0])
+			if len(hexData)%2 == 1 {
+				hexData = append(hexData, '0')
+			}
+			data = make([]byte, len(hexData)/2)
+			_, err = hex.Decode(data, hexData)
+
+		case "ASCII85Decode", "A85":
+			encoded := bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+			encoded = bytes.SplitN(encoded, []byte("~>"), 2)[0]
+			decoded := make([]byte, len(encoded)*4/5+4)
+			var n int
+			n, _, err = ascii85.Decode(decoded, encoded, true)
+			data = decoded[:n]
+
+		default:
+			return nil, fmt.Errorf("the filter %s is not supported", name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// pdfPage the page of the page tree, the resources are inherited from the parents
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages the pages in the order of the page tree
+func (file *pdfFile) pages() []pdfPage {
+	catalog := file.dict(file.root)
+	if catalog == nil {
+		return nil
+	}
+
+	pages := []pdfPage{}
+	visited := map[pdfRef]bool{}
+	var walk func(node interface{}, resources pdfDict, depth int)
+	walk = func(node interface{}, resources pdfDict, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+
+		dict := file.dict(node)
+		if dict == nil || depth > 64 {
+			return
+		}
+
+		if res := file.dict(dict["Resources"]); res != nil {
+			resources = res
+		}
+
+		kids, has := dict["Kids"]
+		if !has || file.name(dict["Type"]) == "Page" {
+			pages = append(pages, pdfPage{dict: dict, resources: resources})
+			return
+		}
+
+		for _, kid := range file.array(kids) {
+			walk(kid, resources, depth+1)
+		}
+	}
+	walk(catalog["Pages"], nil, 0)
+	return pages
+}
+
+// contents the decoded content streams of the page
+func (file *pdfFile) contents(page pdfPage) ([]byte, error) {
+	parts := [][]byte{}
+	for _, value := range file.array(page.dict["Contents"]) {
+		data, err := file.stream(value)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, data)
+	}
+	return bytes.Join(parts, []byte("\n")), nil
+}
+
+// object read the object at the position of the lexer
+func (lexer *pdfLexer) object() (interface{}, bool) {
+	token, ok := lexer.next()
+	if !ok {
+		return nil, false
+	}
+
+	switch token.kind {
+	case pdfNumber:
+		// The reference, <num> <gen> R
+		pos := lexer.pos
+		if gen, ok := lexer.next(); ok && gen.kind == pdfNumber {
+			if r, ok := lexer.next(); ok && r.kind == pdfOperator && r.value == "R" {
+				num, _ := strconv.Atoi(token.value)
+				g, _ := strconv.Atoi(gen.value)
+				return pdfRef{num: num, gen: g}, true
+			}
+		}
+		lexer.pos = pos
+		return token.number(), true
+
+	case pdfString:
+		return []byte(token.value), true
+
+	case pdfName:
+		return token.value, true
+
+	case pdfOperator:
+		switch token.value {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		case "null":
+			return nil, true
+		}
+		return nil, false
+	}
+
+	switch token.value {
+	case "<<":
+		dict := pdfDict{}
+		for {
+			key, ok := lexer.next()
+			if !ok {
+				return nil, false
+			}
+			if key.kind == pdfOther && key.value == ">>" {
+				return dict, true
+			}
+			if key.kind != pdfName {
+				return nil, false
+			}
+			value, ok := lexer.object()
+			if !ok {
+				return nil, false
+			}
+			dict[key.value[1:]] = value
+		}
+
+	case "[":
+		array := []interface{}{}
+		for {
+			pos := lexer.pos
+			item, ok := lexer.next()
+			if !ok {
+				return nil, false
+			}
+			if item.kind == pdfOther && item.value == "]" {
+				return array, true
+			}
+			lexer.pos = pos
+			value, ok := lexer.object()
+			if !ok {
+				return nil, false
+			}
+			array = append(array, value)
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,153 @@
+package document
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var reSlide = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// ParsePPTX parse the PowerPoint (pptx) document, each slide is a section titled by the slide title
+func ParsePPTX(content []byte) (*Document, error) {
+	files, err := unzip(content)
+	if err != nil {
+		return nil, err
+	}
+
+	type slide struct {
+		index int
+		data  []byte
+	}
+
+	slides := []slide{}
+	for name, data := range files {
+		if m := reSlide.FindStringSubmatch(name); m != nil {
+			index, _ := strconv.Atoi(m[1])
+			slides = append(slides, slide{index: index, data: data})
+		}
+	}
+
+	if len(slides) == 0 {
+		return nil, fmt.Errorf("no slides found")
+	}
+	sort.Slice(slides, func(i, j int) bool { return slides[i].index < slides[j].index })
+
+	b := newBuilder("pptx")
+	for i, s := range slides {
+		title, blocks, err := pptxSlide(s.data)
+		if err != nil {
+			return nil, fmt.Errorf("slide %d: %s", s.index, err.Error())
+		}
+
+		if title == "" {
+			title = fmt.Sprintf("Slide %d", i+1)
+		}
+
+		b.heading(1, title)
+		for _, block := range blocks {
+			if block.rows != nil {
+				b.table(block.rows)
+				continue
+			}
+			b.paragraph(block.text)
+		}
+	}
+
+	return b.document(), nil
+}
+
+type pptxBlock struct {
+	text string
+	rows [][]string
+}
+
+// pptxSlide read the title and the content blocks of the slide
+func pptxSlide(data []byte) (string, []pptxBlock, error) {
+	var (
+		title      string
+		blocks     = []pptxBlock{}
+		paragraphs []string
+		text       strings.Builder
+		isTitle    bool
+		inTable    bool
+		rows       [][]string
+		row        []string
+		cell       []string
+	)
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp":
+				isTitle = false
+				paragraphs = []string{}
+			case "ph":
+				typ := xmlAttr(t, "type")
+				isTitle = typ == "title" || typ == "ctrTitle"
+			case "p":
+				text.Reset()
+			case "t":
+				var value string
+				if err := decoder.DecodeElement(&value, &t); err == nil {
+					text.WriteString(value)
+				}
+			case "br":
+				text.WriteString("\n")
+			case "tbl":
+				inTable = true
+				rows = [][]string{}
+			case "tr":
+				row = []string{}
+			case "tc":
+				cell = []string{}
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				value := strings.TrimSpace(text.String())
+				if value == "" {
+					continue
+				}
+				if inTable {
+					cell = append(cell, value)
+					continue
+				}
+				paragraphs = append(paragraphs, value)
+			case "sp":
+				if isTitle && title == "" {
+					title = strings.Join(paragraphs, " ")
+					continue
+				}
+				if len(paragraphs) > 0 {
+					blocks = append(blocks, pptxBlock{text: strings.Join(paragraphs, "\n")})
+				}
+			case "tc":
+				row = append(row, strings.Join(cell, " "))
+			case "tr":
+				rows = append(rows, row)
+			case "tbl":
+				inTable = false
+				blocks = append(blocks, pptxBlock{rows: rows})
+			}
+		}
+	}
+
+	return title, blocks, nil
+}
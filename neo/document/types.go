@@ -0,0 +1,40 @@
+package document
+
+// Document the parsed document, the content is organized by sections
+type Document struct {
+	Title    string    `json:"title,omitempty"`
+	Type     string    `json:"type"`
+	Sections []Section `json:"sections"`
+}
+
+// Section a document section, starts with a heading (or the beginning of the document)
+type Section struct {
+	Title   string   `json:"title,omitempty"`
+	Level   int      `json:"level"`          // Heading level, 0 means the content before the first heading
+	Path    []string `json:"path,omitempty"` // The heading path, e.g. ["Guide", "Install"]
+	Content string   `json:"content"`        // The section content in markdown, tables are kept as markdown tables
+}
+
+// Chunk a piece of the document used for indexing
+type Chunk struct {
+	Index   int    `json:"index"`
+	Path    string `json:"path,omitempty"` // The heading path, e.g. "Guide > Install"
+	Content string `json:"content"`
+}
+
+// Splitter types
+const (
+	SplitterSemantic  = "semantic"  // Split by the document sections, then by paragraphs (default)
+	SplitterRecursive = "recursive" // Split the plain text recursively by paragraphs, lines, sentences and words
+	SplitterMarkdown  = "markdown"  // Split by the markdown blocks, the code blocks and tables are kept whole
+)
+
+// ChunkOption the chunk option
+type ChunkOption struct {
+	Size     int    `json:"size,omitempty" yaml:"size,omitempty"`         // Maximum chunk size in characters, default is 1024
+	Overlap  int    `json:"overlap,omitempty" yaml:"overlap,omitempty"`   // Overlap size in characters, default is 0
+	Splitter string `json:"splitter,omitempty" yaml:"splitter,omitempty"` // Splitter type, semantic, recursive or markdown, default is semantic
+}
+
+// Parser parse the content to a document
+type Parser func(content []byte) (*Document, error)
@@ -0,0 +1,33 @@
+package document
+
+import (
+	"bytes"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ParseXLSX parse the Excel (xlsx) document, each sheet is a section with a markdown table
+func ParseXLSX(content []byte) (*Document, error) {
+	file, err := excelize.OpenReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	b := newBuilder("xlsx")
+	for _, sheet := range file.GetSheetList() {
+		rows, err := file.GetRows(sheet)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rows) == 0 {
+			continue
+		}
+
+		b.heading(1, sheet)
+		b.table(rows)
+	}
+
+	return b.document(), nil
+}
@@ -0,0 +1,282 @@
+package neo
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/neo/crawler"
+	"github.com/yaoapp/yao/neo/document"
+	neorag "github.com/yaoapp/yao/neo/rag"
+)
+
+// CrawlResult the result of the knowledge crawl
+type CrawlResult struct {
+	crawler.Result
+	Collection string `json:"collection"`
+	Indexed    int    `json:"indexed"`   // Number of new or changed pages indexed
+	Unchanged  int    `json:"unchanged"` // Number of pages skipped because the content is not changed
+}
+
+// IngestResult the result of the knowledge ingest
+type IngestResult struct {
+	Collection string   `json:"collection"`
+	DocID      string   `json:"doc_id"`
+	Title      string   `json:"title,omitempty"`
+	Chunks     []string `json:"chunks"` // The indexed chunk ids
+}
+
+// CollectionIndex get the index name of the knowledge collection
+func (neo *DSL) CollectionIndex(collection string) string {
+	prefix := neo.RAGSetting.IndexPrefix
+	if neo.RAG != nil {
+		prefix = neo.RAG.Setting().IndexPrefix
+	}
+	return fmt.Sprintf("%scollection_%s", prefix, collection)
+}
+
+// Crawl crawl the web pages and index them into the knowledge collection.
+// The pages are indexed by URL, the unchanged pages are skipped, so it can be re-run incrementally.
+func (neo *DSL) Crawl(ctx context.Context, collection string, setting crawler.Setting) (*CrawlResult, error) {
+	if neo.RAG == nil {
+		return nil, fmt.Errorf("RAG is not enabled")
+	}
+
+	if collection == "" {
+		return nil, fmt.Errorf("collection is required")
+	}
+
+	c, err := crawler.New(setting)
+	if err != nil {
+		return nil, err
+	}
+
+	option, err := neo.Collection(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := neo.RAG.Engine()
+	index := neo.CollectionIndex(collection)
+	err = neorag.EnsureIndex(ctx, engine, index)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CrawlResult{Collection: collection}
+	res, err := c.Run(ctx, func(page *crawler.Page) error {
+		if page.Markdown == "" {
+			return nil
+		}
+
+		id := fmt.Sprintf("page_%x", sha256.Sum256([]byte(page.URL)))[:21]
+		first := fmt.Sprintf("%s_0", id)
+		has, err := engine.HasDocument(ctx, index, first)
+		if err != nil {
+			return err
+		}
+
+		// Skip the unchanged page
+		if has {
+			metadata, err := engine.GetMetadata(ctx, index, first)
+			if err != nil {
+				return err
+			}
+			if hash, ok := metadata["hash"].(string); ok && hash == page.Hash {
+				result.Unchanged++
+				return nil
+			}
+		}
+
+		doc, err := document.ParseMarkdown([]byte(page.Markdown))
+		if err != nil {
+			return err
+		}
+		doc.Title = page.Title
+
+		_, err = neorag.IndexDocument(ctx, engine, index, id, doc, option, map[string]interface{}{
+			"collection": collection,
+			"source":     "crawler",
+			"url":        page.URL,
+			"hash":       page.Hash,
+			"depth":      page.Depth,
+			"crawled_at": page.FetchedAt.Format(time.RFC3339),
+		})
+		if err != nil {
+			log.Error("[Neo] crawl index %s error: %s", page.URL, err.Error())
+			return err
+		}
+
+		result.Indexed++
+		return nil
+	})
+
+	if res != nil {
+		result.Result = *res
+	}
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Ingest parse the document and index it into the knowledge collection.
+// The document is indexed by the name, ingest the same name again replaces the previous version.
+func (neo *DSL) Ingest(ctx context.Context, collection string, name string, contentType string, content []byte) (*IngestResult, error) {
+	if neo.RAG == nil {
+		return nil, fmt.Errorf("RAG is not enabled")
+	}
+
+	if collection == "" {
+		return nil, fmt.Errorf("collection is required")
+	}
+
+	option, err := neo.Collection(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := document.Parse(name, contentType, content)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := neo.RAG.Engine()
+	index := neo.CollectionIndex(collection)
+	err = neorag.EnsureIndex(ctx, engine, index)
+	if err != nil {
+		return nil, err
+	}
+
+	id := fmt.Sprintf("file_%x", sha256.Sum256([]byte(name)))[:21]
+	ids, err := neorag.IndexDocument(ctx, engine, index, id, doc, option, map[string]interface{}{
+		"collection":   collection,
+		"source":       "file",
+		"name":         name,
+		"type":         doc.Type,
+		"content_type": contentType,
+		"ingested_at":  time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &IngestResult{Collection: collection, DocID: id, Title: doc.Title, Chunks: ids}, nil
+}
+
+// Collection get the settings of the knowledge collection.
+// The chunk option not set falls back to the upload setting of the RAG, so the collections not saved use the global default.
+func (neo *DSL) Collection(name string) (neorag.Collection, error) {
+	collection := neorag.Collection{Name: name}
+	if neo.Store != nil {
+		data, err := neo.Store.GetCollection(name)
+		if err != nil && !strings.Contains(err.Error(), "not found") {
+			return collection, err
+		}
+
+		if err == nil {
+			collection, err = collectionOf(data)
+			if err != nil {
+				return collection, err
+			}
+		}
+	}
+
+	if neo.RAG != nil {
+		upload := neo.RAG.Setting().Upload
+		if collection.Chunk.Size == 0 {
+			collection.Chunk.Size = upload.ChunkSize
+		}
+		if collection.Chunk.Overlap == 0 && collection.Chunk.Size > upload.ChunkOverlap {
+			collection.Chunk.Overlap = upload.ChunkOverlap
+		}
+	}
+	return collection, nil
+}
+
+// Collections get all the saved knowledge collections
+func (neo *DSL) Collections() ([]neorag.Collection, error) {
+	if neo.Store == nil {
+		return nil, fmt.Errorf("store is not initialized")
+	}
+
+	rows, err := neo.Store.GetCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	collections := []neorag.Collection{}
+	for _, row := range rows {
+		collection, err := collectionOf(row)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+	return collections, nil
+}
+
+// SaveCollection validate and save the knowledge collection settings.
+// The settings are applied to the documents indexed afterwards, re-index the documents to apply them to the existing ones.
+func (neo *DSL) SaveCollection(collection neorag.Collection) error {
+	if neo.Store == nil {
+		return fmt.Errorf("store is not initialized")
+	}
+
+	err := collection.Validate()
+	if err != nil {
+		return err
+	}
+
+	_, err = neo.Store.SaveCollection(map[string]interface{}{
+		"name":        collection.Name,
+		"description": collection.Description,
+		"options": map[string]interface{}{
+			"chunk":    collection.Chunk,
+			"metadata": collection.Metadata,
+		},
+	})
+	return err
+}
+
+// DeleteCollection delete the knowledge collection settings, the indexed documents are kept
+func (neo *DSL) DeleteCollection(name string) error {
+	if neo.Store == nil {
+		return fmt.Errorf("store is not initialized")
+	}
+	return neo.Store.DeleteCollection(name)
+}
+
+// collectionOf convert the stored collection to the collection settings
+func collectionOf(data map[string]interface{}) (neorag.Collection, error) {
+	collection := neorag.Collection{}
+	collection.Name, _ = data["name"].(string)
+	collection.Description, _ = data["description"].(string)
+	if data["options"] == nil {
+		return collection, nil
+	}
+
+	raw, err := jsoniter.Marshal(data["options"])
+	if err != nil {
+		return collection, err
+	}
+
+	var options struct {
+		Chunk    document.ChunkOption  `json:"chunk"`
+		Metadata []neorag.MetadataRule `json:"metadata"`
+	}
+	err = jsoniter.Unmarshal(raw, &options)
+	if err != nil {
+		return collection, fmt.Errorf("collection %s options error: %s", collection.Name, err.Error())
+	}
+
+	collection.Chunk = options.Chunk
+	collection.Metadata = options.Metadata
+	return collection, nil
+}
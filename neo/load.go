@@ -14,6 +14,7 @@ import (
 	"github.com/yaoapp/yao/neo/store"
 	"github.com/yaoapp/yao/neo/vision"
 	"github.com/yaoapp/yao/neo/vision/driver"
+	"github.com/yaoapp/yao/trace"
 )
 
 // Neo the neo AI assistant
@@ -47,6 +48,12 @@ func Load(cfg config.Config) error {
 		setting.StoreSetting.MaxSize = 100
 	}
 
+	// The logging of the prompts and the completions
+	err = trace.SetLogging(setting.Logging)
+	if err != nil {
+		return err
+	}
+
 	Neo = &setting
 
 	// Store Setting
@@ -155,6 +162,9 @@ func (neo *DSL) initAssistant() error {
 		)
 	}
 
+	// Assistant knowledge collections
+	assistant.SetCollection(Neo.Collection)
+
 	// Assistant Vision
 	if Neo.Vision != nil {
 		assistant.SetVision(Neo.Vision)
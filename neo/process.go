@@ -1,13 +1,19 @@
 package neo
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/fs"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/neo/assistant"
+	"github.com/yaoapp/yao/neo/crawler"
 	"github.com/yaoapp/yao/neo/message"
+	neorag "github.com/yaoapp/yao/neo/rag"
 	"github.com/yaoapp/yao/neo/store"
 )
 
@@ -27,6 +33,16 @@ func init() {
 		"assistant.delete": processAssistantDelete,
 		"assistant.search": processAssistantSearch,
 		"assistant.find":   processAssistantFind,
+		"knowledge.crawl":  processKnowledgeCrawl,
+		"knowledge.ingest": processKnowledgeIngest,
+
+		"knowledge.collection.save":   processCollectionSave,
+		"knowledge.collection.find":   processCollectionFind,
+		"knowledge.collection.list":   processCollectionList,
+		"knowledge.collection.delete": processCollectionDelete,
+
+		"workflow.run":    processWorkflowRun,
+		"workflow.resume": processWorkflowResume,
 	})
 }
 
@@ -203,3 +219,220 @@ func processAssistantFind(process *process.Process) interface{} {
 
 	return res.Data[0]
 }
+
+// processKnowledgeCrawl process the knowledge crawl request
+// Args[0] the collection name
+// Args[1] the crawler setting {"seeds": [...], "max_depth": 2, "max_pages": 100, ...}
+func processKnowledgeCrawl(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	collection := process.ArgsString(0)
+	data := process.ArgsMap(1)
+
+	var setting crawler.Setting
+	raw, err := jsoniter.Marshal(data)
+	if err != nil {
+		exception.New("Invalid crawler setting: %s", 400, err.Error()).Throw()
+	}
+
+	err = jsoniter.Unmarshal(raw, &setting)
+	if err != nil {
+		exception.New("Invalid crawler setting: %s", 400, err.Error()).Throw()
+	}
+
+	ctx := process.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	neo := GetNeo()
+	res, err := neo.Crawl(ctx, collection, setting)
+	if err != nil {
+		exception.New("Failed to crawl: %s", 500, err.Error()).Throw()
+	}
+
+	return res
+}
+
+// processKnowledgeIngest process the knowledge ingest request
+// Args[0] the collection name
+// Args[1] the file path of the data file system
+// Args[2] the content type, optional, detect by the file extension if not set
+func processKnowledgeIngest(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	collection := process.ArgsString(0)
+	name := process.ArgsString(1)
+	contentType := ""
+	if process.NumOfArgs() > 2 {
+		contentType = process.ArgsString(2)
+	}
+
+	data, err := fs.Get("data")
+	if err != nil {
+		exception.New("Failed to get the data file system: %s", 500, err.Error()).Throw()
+	}
+
+	content, err := data.ReadFile(name)
+	if err != nil {
+		exception.New("Failed to read %s: %s", 500, name, err.Error()).Throw()
+	}
+
+	if contentType == "" {
+		if v, err := data.MimeType(name); err == nil {
+			contentType = v
+		}
+	}
+
+	ctx := process.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	neo := GetNeo()
+	res, err := neo.Ingest(ctx, collection, name, contentType, content)
+	if err != nil {
+		exception.New("Failed to ingest %s: %s", 500, name, err.Error()).Throw()
+	}
+
+	return res
+}
+
+// processCollectionSave process the knowledge collection save request
+// Args[0] the collection settings {"name": "docs", "chunk": {"size": 512, "splitter": "markdown"}, "metadata": [...]}
+func processCollectionSave(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	data := process.ArgsMap(0)
+
+	var collection neorag.Collection
+	raw, err := jsoniter.Marshal(data)
+	if err != nil {
+		exception.New("Invalid collection: %s", 400, err.Error()).Throw()
+	}
+
+	err = jsoniter.Unmarshal(raw, &collection)
+	if err != nil {
+		exception.New("Invalid collection: %s", 400, err.Error()).Throw()
+	}
+
+	neo := GetNeo()
+	err = neo.SaveCollection(collection)
+	if err != nil {
+		exception.New("Failed to save collection: %s", 500, err.Error()).Throw()
+	}
+
+	return collection.Name
+}
+
+// processCollectionFind process the knowledge collection find request
+// Args[0] the collection name
+func processCollectionFind(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	name := process.ArgsString(0)
+
+	neo := GetNeo()
+	collection, err := neo.Collection(name)
+	if err != nil {
+		exception.New("Failed to find collection: %s", 500, err.Error()).Throw()
+	}
+
+	return collection
+}
+
+// processCollectionList process the knowledge collection list request
+func processCollectionList(process *process.Process) interface{} {
+	neo := GetNeo()
+	collections, err := neo.Collections()
+	if err != nil {
+		exception.New("Failed to list collections: %s", 500, err.Error()).Throw()
+	}
+
+	return collections
+}
+
+// processCollectionDelete process the knowledge collection delete request
+// Args[0] the collection name
+func processCollectionDelete(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	name := process.ArgsString(0)
+
+	neo := GetNeo()
+	err := neo.DeleteCollection(name)
+	if err != nil {
+		exception.New("Failed to delete collection: %s", 500, err.Error()).Throw()
+	}
+
+	return nil
+}
+
+// processWorkflowRun process the workflow run request, runs as the user of the process session
+// Args[0] the assistant id
+// Args[1] the workflow name
+// Args[2] the input (optional)
+func processWorkflowRun(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	ast, err := assistant.Get(process.ArgsString(0))
+	if err != nil {
+		exception.New("Failed to get assistant: %s", 404, err.Error()).Throw()
+	}
+
+	var input interface{}
+	if process.NumOfArgs() > 2 {
+		input = process.Args[2]
+	}
+
+	ctx := process.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	run, err := ast.RunWorkflow(ctx, process.Sid, process.ArgsString(1), input)
+	if err != nil {
+		exception.New("Failed to run workflow: %s", 400, err.Error()).Throw()
+	}
+
+	return run.Map()
+}
+
+// processWorkflowResume process the workflow resume request
+// Args[0] the run id
+// Args[1] the approval {"approved": true, "comment": "..."} (optional, required if the run is waiting)
+func processWorkflowResume(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	runID := process.ArgsString(0)
+
+	neo := GetNeo()
+	data, err := neo.Store.GetWorkflowRun(process.Sid, runID)
+	if err != nil {
+		exception.New("Failed to get workflow run: %s", 404, err.Error()).Throw()
+	}
+
+	ast, err := assistant.Get(fmt.Sprintf("%v", data["assistant_id"]))
+	if err != nil {
+		exception.New("Failed to get assistant: %s", 404, err.Error()).Throw()
+	}
+
+	var approval *assistant.WorkflowApproval
+	if process.NumOfArgs() > 1 && process.Args[1] != nil {
+		approval = &assistant.WorkflowApproval{}
+		raw, err := jsoniter.Marshal(process.Args[1])
+		if err != nil {
+			exception.New("Invalid approval: %s", 400, err.Error()).Throw()
+		}
+
+		err = jsoniter.Unmarshal(raw, approval)
+		if err != nil {
+			exception.New("Invalid approval: %s", 400, err.Error()).Throw()
+		}
+	}
+
+	ctx := process.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	run, err := ast.ResumeWorkflow(ctx, process.Sid, runID, approval)
+	if err != nil {
+		exception.New("Failed to resume workflow: %s", 400, err.Error()).Throw()
+	}
+
+	return run.Map()
+}
@@ -0,0 +1,125 @@
+package rag
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/spf13/cast"
+	"github.com/yaoapp/yao/neo/document"
+)
+
+// reserved the metadata fields written by the indexer, the rules can not override them
+var reserved = map[string]bool{"doc_id": true, "title": true, "path": true, "chunk": true, "chunks": true}
+
+// Validate validate the collection settings
+func (collection Collection) Validate() error {
+	if collection.Name == "" {
+		return fmt.Errorf("collection name is required")
+	}
+
+	if collection.Chunk.Size < 0 || collection.Chunk.Overlap < 0 {
+		return fmt.Errorf("chunk size and overlap must not be negative")
+	}
+
+	if collection.Chunk.Size > 0 && collection.Chunk.Overlap >= collection.Chunk.Size {
+		return fmt.Errorf("chunk overlap must be less than the chunk size")
+	}
+
+	if !document.ValidSplitter(collection.Chunk.Splitter) {
+		return fmt.Errorf("splitter %s is not supported", collection.Chunk.Splitter)
+	}
+
+	for _, rule := range collection.Metadata {
+		if rule.Field == "" {
+			return fmt.Errorf("metadata field is required")
+		}
+
+		if reserved[rule.Field] {
+			return fmt.Errorf("metadata field %s is reserved", rule.Field)
+		}
+
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("metadata %s pattern error: %s", rule.Field, err.Error())
+		}
+	}
+	return nil
+}
+
+// extractor the compiled metadata rules
+type extractor struct {
+	rules    []MetadataRule
+	patterns []*regexp.Regexp
+}
+
+func newExtractor(rules []MetadataRule) (*extractor, error) {
+	ext := &extractor{rules: rules, patterns: make([]*regexp.Regexp, len(rules))}
+	for i, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("metadata %s pattern error: %s", rule.Field, err.Error())
+		}
+		ext.patterns[i] = re
+	}
+	return ext, nil
+}
+
+// document extract the metadata of the document level rules
+func (ext *extractor) document(doc *document.Document, metadata map[string]interface{}) map[string]interface{} {
+	values := map[string]interface{}{}
+	content := ""
+	for i, rule := range ext.rules {
+		var source string
+		switch rule.Source {
+		case "chunk":
+			continue
+
+		case "", "content":
+			if content == "" {
+				content = doc.Markdown()
+			}
+			source = content
+
+		case "title":
+			source = doc.Title
+
+		default:
+			source = cast.ToString(metadata[rule.Source])
+		}
+
+		if value, ok := ext.match(i, source); ok {
+			values[rule.Field] = value
+		}
+	}
+	return values
+}
+
+// chunk extract the metadata of the chunk level rules
+func (ext *extractor) chunk(chunk document.Chunk) map[string]interface{} {
+	values := map[string]interface{}{}
+	for i, rule := range ext.rules {
+		if rule.Source != "chunk" {
+			continue
+		}
+
+		if value, ok := ext.match(i, chunk.Content); ok {
+			values[rule.Field] = value
+		}
+	}
+	return values
+}
+
+func (ext *extractor) match(i int, source string) (string, bool) {
+	matches := ext.patterns[i].FindStringSubmatch(source)
+	if len(matches) > 1 {
+		return matches[1], true
+	}
+
+	if len(matches) == 1 {
+		return matches[0], true
+	}
+
+	if ext.rules[i].Default != "" {
+		return ext.rules[i].Default, true
+	}
+	return "", false
+}
@@ -0,0 +1,100 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cast"
+	"github.com/yaoapp/gou/rag/driver"
+	"github.com/yaoapp/yao/neo/document"
+)
+
+// EnsureIndex create the index if it does not exist
+func EnsureIndex(ctx context.Context, engine driver.Engine, index string) error {
+	exists, err := engine.HasIndex(ctx, index)
+	if err != nil {
+		return fmt.Errorf("check index error: %s", err.Error())
+	}
+
+	if exists {
+		return nil
+	}
+
+	err = engine.CreateIndex(ctx, driver.IndexConfig{Name: index})
+	if err != nil {
+		return fmt.Errorf("create index error: %s", err.Error())
+	}
+	return nil
+}
+
+// IndexDocument split the document to chunks with the chunk option of the collection and index them.
+// The metadata rules of the collection are applied to each chunk.
+// The chunk ids are {docID}_{index}, the stale chunks of the previous version are removed.
+// Returns the indexed chunk ids.
+func IndexDocument(ctx context.Context, engine driver.Engine, index string, docID string, doc *document.Document, collection Collection, metadata map[string]interface{}) ([]string, error) {
+	chunks := doc.Chunks(collection.Chunk)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no content to index")
+	}
+
+	ext, err := newExtractor(collection.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	extracted := ext.document(doc, metadata)
+
+	// The number of chunks of the previous version
+	previous := 0
+	first := fmt.Sprintf("%s_0", docID)
+	has, err := engine.HasDocument(ctx, index, first)
+	if err != nil {
+		return nil, err
+	}
+
+	if has {
+		meta, err := engine.GetMetadata(ctx, index, first)
+		if err != nil {
+			return nil, err
+		}
+		previous = cast.ToInt(meta["chunks"])
+	}
+
+	ids := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		meta := map[string]interface{}{}
+		for k, v := range metadata {
+			meta[k] = v
+		}
+		for k, v := range extracted {
+			meta[k] = v
+		}
+		for k, v := range ext.chunk(chunk) {
+			meta[k] = v
+		}
+		meta["doc_id"] = docID
+		meta["title"] = doc.Title
+		meta["path"] = chunk.Path
+		meta["chunk"] = chunk.Index
+		meta["chunks"] = len(chunks)
+
+		ids[i] = fmt.Sprintf("%s_%d", docID, chunk.Index)
+		err := engine.IndexDoc(ctx, index, &driver.Document{
+			DocID:    ids[i],
+			Content:  chunk.Text(),
+			Metadata: meta,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("index chunk %d error: %s", chunk.Index, err.Error())
+		}
+	}
+
+	// Remove the stale chunks
+	for i := len(chunks); i < previous; i++ {
+		err := engine.DeleteDoc(ctx, index, fmt.Sprintf("%s_%d", docID, i))
+		if err != nil {
+			return nil, fmt.Errorf("delete stale chunk %d error: %s", i, err.Error())
+		}
+	}
+
+	return ids, nil
+}
@@ -1,5 +1,7 @@
 package rag
 
+import "github.com/yaoapp/yao/neo/document"
+
 // Setting RAG settings
 type Setting struct {
 	Engine      Engine     `json:"engine" yaml:"engine"`
@@ -27,3 +29,20 @@ type Upload struct {
 	ChunkSize    int      `json:"chunk_size" yaml:"chunk_size"`
 	ChunkOverlap int      `json:"chunk_overlap" yaml:"chunk_overlap"`
 }
+
+// Collection the knowledge collection settings, applied when indexing the documents of the collection
+type Collection struct {
+	Name        string               `json:"name" yaml:"name"`
+	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
+	Chunk       document.ChunkOption `json:"chunk" yaml:"chunk"`
+	Metadata    []MetadataRule       `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// MetadataRule extract a metadata field from the document with a regular expression.
+// The first capture group is used as the value, or the whole match if there is no group.
+type MetadataRule struct {
+	Field   string `json:"field" yaml:"field"`
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Source  string `json:"source,omitempty" yaml:"source,omitempty"`   // content (default), chunk, title or a metadata field name such as url
+	Default string `json:"default,omitempty" yaml:"default,omitempty"` // The value used when nothing matches
+}
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/dbal/query"
+)
+
+// defaultBatchSize the rows of each insert statement if the batch size is not set
+const defaultBatchSize = 500
+
+// batchSize the rows of each insert statement
+func (conv *Xun) batchSize() int {
+	if conv.setting.BatchSize > 0 {
+		return conv.setting.BatchSize
+	}
+	return defaultBatchSize
+}
+
+// insertBatch insert the rows of the table in batches, the rows must have the same columns.
+// The large runs, e.g. the agent runs with many tool events, are inserted with a few statements instead of one statement per row.
+// The qb is the query of the store or the query of the transaction.
+func (conv *Xun) insertBatch(qb query.Query, table string, rows []map[string]interface{}) error {
+	size := conv.batchSize()
+	for start := 0; start < len(rows); start += size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		err := qb.New().Table(table).Insert(rows[start:end])
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(rows) > size {
+		log.Trace("Insert %d rows to the table %s in batches of %d", len(rows), table, size)
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package store
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/dbal/schema"
+	"github.com/yaoapp/yao/cluster"
+)
+
+// cleanupLock the name of the lock of the cleanup
+const cleanupLock = "cleanup"
+
+// CleanupSetting the schedule of the cleanup of the expired rows
+type CleanupSetting struct {
+	Interval int  `json:"interval,omitempty" yaml:"interval,omitempty"` // Seconds between the cleanups, defaults to 3600
+	Jitter   int  `json:"jitter,omitempty" yaml:"jitter,omitempty"`     // Maximum random seconds added to the interval, defaults to 10% of the interval
+	Lock     int  `json:"lock,omitempty" yaml:"lock,omitempty"`         // Seconds the lock is held if the instance stops during the cleanup, defaults to the interval
+	Disable  bool `json:"disable,omitempty" yaml:"disable,omitempty"`   // Disable the scheduled cleanup, e.g. the cleanup runs on another instance
+}
+
+// CleanupStats the metrics of the cleanup of the store instance
+type CleanupStats struct {
+	Runs         int64            `json:"runs"`                    // Cleanups run by the instance
+	Skipped      int64            `json:"skipped"`                 // Cleanups skipped because another instance holds the lock
+	Errors       int64            `json:"errors"`                  // Cleanups failed
+	Deleted      map[string]int64 `json:"deleted"`                 // Deleted rows by kind: history, memory, user
+	LastRun      *time.Time       `json:"last_run,omitempty"`      // Start time of the last cleanup
+	LastDuration string           `json:"last_duration,omitempty"` // Duration of the last cleanup
+	LastError    string           `json:"last_error,omitempty"`    // Error of the last failed cleanup
+	NextRun      *time.Time       `json:"next_run,omitempty"`      // Scheduled time of the next cleanup
+}
+
+// Cleaner the store cleans the expired rows on a schedule
+type Cleaner interface {
+	// CleanupStats returns the metrics of the cleanup
+	CleanupStats() CleanupStats
+}
+
+// cleanup the state of the scheduled cleanup
+type cleanup struct {
+	owner string
+	mu    sync.Mutex
+	stats CleanupStats
+}
+
+func (conv *Xun) getLockTable() string {
+	return conv.setting.Prefix + "lock"
+}
+
+func (conv *Xun) initLockTable() error {
+	lockTable := conv.getLockTable()
+	has, err := conv.schema.HasTable(lockTable)
+	if err != nil {
+		return err
+	}
+
+	// Create the lock table
+	if !has {
+		err = conv.schema.CreateTable(lockTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("name", 200).Unique().Index() // lock name
+			table.String("owner", 200)                 // the instance holds the lock
+			table.TimestampTz("expired_at").Index()    // the lock is released after the time
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()")
+		})
+
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the lock table: %s", lockTable)
+	}
+
+	// Validate the table
+	tab, err := conv.schema.GetTable(lockTable)
+	if err != nil {
+		return err
+	}
+
+	fields := []string{"id", "name", "owner", "expired_at", "created_at"}
+	for _, field := range fields {
+		if !tab.HasColumn(field) {
+			return fmt.Errorf("%s is required", field)
+		}
+	}
+
+	return nil
+}
+
+// initCleanup start the scheduled cleanup
+func (conv *Xun) initCleanup() {
+	hostname, _ := os.Hostname()
+	conv.cleanup = &cleanup{
+		owner: fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), uuid.New().String()),
+		stats: CleanupStats{Deleted: map[string]int64{}},
+	}
+
+	setting := CleanupSetting{}
+	if conv.setting.Cleanup != nil {
+		setting = *conv.setting.Cleanup
+	}
+
+	if setting.Disable {
+		return
+	}
+
+	go conv.schedule(setting)
+}
+
+// schedule run the cleanup every interval with the jitter, the instances started together do not clean at the same time
+func (conv *Xun) schedule(setting CleanupSetting) {
+	interval := time.Hour
+	if setting.Interval > 0 {
+		interval = time.Duration(setting.Interval) * time.Second
+	}
+
+	jitter := interval / 10
+	if setting.Jitter > 0 {
+		jitter = time.Duration(setting.Jitter) * time.Second
+	}
+
+	lease := interval
+	if setting.Lock > 0 {
+		lease = time.Duration(setting.Lock) * time.Second
+	}
+
+	for {
+		delay := interval
+		if jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(jitter)))
+		}
+
+		next := time.Now().Add(delay)
+		conv.cleanup.mu.Lock()
+		conv.cleanup.stats.NextRun = &next
+		conv.cleanup.mu.Unlock()
+
+		time.Sleep(delay)
+		conv.runCleanup(lease)
+	}
+}
+
+// runCleanup clean the expired rows if the instance gets the lock
+func (conv *Xun) runCleanup(lease time.Duration) {
+	// The followers of the cluster skip the cleanup
+	if !cluster.IsLeader() {
+		conv.cleanup.mu.Lock()
+		conv.cleanup.stats.Skipped++
+		conv.cleanup.mu.Unlock()
+		return
+	}
+
+	locked, err := conv.lock(cleanupLock, lease)
+	if err != nil {
+		log.Error("Lock the cleanup of %s error: %s", conv.setting.Prefix, err.Error())
+		return
+	}
+
+	if !locked {
+		conv.cleanup.mu.Lock()
+		conv.cleanup.stats.Skipped++
+		conv.cleanup.mu.Unlock()
+		return
+	}
+	defer conv.unlock(cleanupLock)
+
+	start := time.Now()
+	deleted, err := conv.clean()
+
+	conv.cleanup.mu.Lock()
+	defer conv.cleanup.mu.Unlock()
+	stats := &conv.cleanup.stats
+	stats.Runs++
+	stats.LastRun = &start
+	stats.LastDuration = time.Since(start).String()
+	for kind, nums := range deleted {
+		stats.Deleted[kind] += nums
+	}
+
+	if err != nil {
+		stats.Errors++
+		stats.LastError = err.Error()
+		log.Error("Clean the store %s error: %s", conv.setting.Prefix, err.Error())
+	}
+}
+
+// CleanupStats the metrics of the cleanup of the instance
+func (conv *Xun) CleanupStats() CleanupStats {
+	if conv.cleanup == nil {
+		return CleanupStats{Deleted: map[string]int64{}}
+	}
+
+	conv.cleanup.mu.Lock()
+	defer conv.cleanup.mu.Unlock()
+	stats := conv.cleanup.stats
+	stats.Deleted = map[string]int64{}
+	for kind, nums := range conv.cleanup.stats.Deleted {
+		stats.Deleted[kind] = nums
+	}
+	return stats
+}
+
+// lock get the named lock shared by the instances, the expired lock is taken over
+func (conv *Xun) lock(name string, lease time.Duration) (bool, error) {
+	now := time.Now()
+	_, err := conv.query.New().
+		Table(conv.getLockTable()).
+		Where("name", name).
+		Where("expired_at", "<=", now).
+		Delete()
+	if err != nil {
+		return false, err
+	}
+
+	// The insert fails if another instance holds the lock
+	err = conv.query.New().
+		Table(conv.getLockTable()).
+		Insert(map[string]interface{}{
+			"name":       name,
+			"owner":      conv.cleanup.owner,
+			"expired_at": now.Add(lease),
+			"created_at": now,
+		})
+	if err != nil {
+		log.Trace("The lock %s of %s is held by another instance: %s", name, conv.setting.Prefix, err.Error())
+		return false, nil
+	}
+	return true, nil
+}
+
+// unlock release the named lock held by the instance
+func (conv *Xun) unlock(name string) {
+	_, err := conv.query.New().
+		Table(conv.getLockTable()).
+		Where("name", name).
+		Where("owner", conv.cleanup.owner).
+		Delete()
+	if err != nil {
+		log.Error("Unlock the %s of %s error: %s", name, conv.setting.Prefix, err.Error())
+	}
+}
+
+// clean delete the expired history and memories and the data of the users whose grace period is over,
+// returns the deleted rows by kind
+func (conv *Xun) clean() (map[string]int64, error) {
+	deleted := map[string]int64{}
+
+	nums, err := conv.deleteExpired(conv.getHistoryTable())
+	deleted["history"] = nums
+	if err != nil {
+		return deleted, err
+	}
+
+	// The memories expire by the TTL or the retention policies
+	nums, err = conv.deleteExpired(conv.getMemoryTable())
+	deleted["memory"] = nums
+	if err != nil {
+		return deleted, err
+	}
+
+	// Delete the data of the users whose grace period is over
+	nums, err = conv.purgeDeletions()
+	deleted["user"] = nums
+	return deleted, err
+}
+
+// deleteExpired delete the expired rows of the table in batches, the short statements do not lock the table for long
+func (conv *Xun) deleteExpired(table string) (int64, error) {
+	var total int64 = 0
+	now := time.Now()
+	for {
+		rows, err := conv.query.New().
+			Table(table).
+			Select("id").
+			Where("expired_at", "<=", now).
+			OrderBy("id", "asc").
+			Limit(conv.batchSize()).
+			Get()
+		if err != nil {
+			return total, err
+		}
+
+		if len(rows) == 0 {
+			break
+		}
+
+		ids := make([]interface{}, 0, len(rows))
+		for _, row := range rows {
+			ids = append(ids, row.Get("id"))
+		}
+
+		nums, err := conv.query.New().
+			Table(table).
+			WhereIn("id", ids).
+			Delete()
+		if err != nil {
+			return total, err
+		}
+		total += nums
+
+		if len(rows) < conv.batchSize() {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Trace("Clean the table: %s %d", table, total)
+	}
+	return total, nil
+}
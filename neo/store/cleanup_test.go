@@ -0,0 +1,82 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/test"
+)
+
+func TestXunCleanup(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_lock")
+
+	s, err := NewXun(Setting{
+		Connector: "default",
+		Prefix:    "__unit_test_conversation_",
+		TTL:       1,
+		BatchSize: 2,
+		Cleanup:   &CleanupSetting{Disable: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv := s.(*Xun)
+
+	sid := fmt.Sprintf("cleanup_%d", time.Now().UnixNano())
+	messages := []map[string]interface{}{}
+	for i := 0; i < 5; i++ {
+		messages = append(messages, map[string]interface{}{"role": "user", "name": "user1", "content": fmt.Sprintf("message %d", i)})
+	}
+
+	err = conv.SaveHistory(sid, messages, "cleanup-chat", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The expired rows are deleted in batches
+	time.Sleep(1100 * time.Millisecond)
+	deleted, err := conv.clean()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.GreaterOrEqual(t, deleted["history"], int64(5))
+
+	count, err := conv.query.New().Table(conv.getHistoryTable()).Where("sid", sid).Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(0), count)
+
+	// Only one instance holds the lock
+	locked, err := conv.lock("test", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, locked)
+
+	locked, err = conv.lock("test", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, locked)
+
+	conv.unlock("test")
+	locked, err = conv.lock("test", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, locked)
+	conv.unlock("test")
+
+	// The metrics of the run
+	conv.runCleanup(time.Minute)
+	stats := conv.CleanupStats()
+	assert.Equal(t, int64(1), stats.Runs)
+	assert.NotNil(t, stats.LastRun)
+}
@@ -0,0 +1,256 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+)
+
+// encryptedPrefix the prefix of the encrypted values, enc:v1:<key id>:<base64 nonce and ciphertext>
+const encryptedPrefix = "enc:v1:"
+
+// EncryptionSetting the field-level encryption of the assistant columns, e.g. the API credentials in the options.
+// The values are encrypted with AES-GCM by the key, the other keys decrypt the values encrypted before the key rotation.
+type EncryptionSetting struct {
+	Key     string            `json:"key" yaml:"key"`                             // ID of the key to encrypt with
+	Keys    map[string]string `json:"keys" yaml:"keys"`                           // Base64 AES keys (16, 24 or 32 bytes) by ID, e.g. {"2024": "$ENV.NEO_STORE_KEY_2024"}
+	Columns []string          `json:"columns,omitempty" yaml:"columns,omitempty"` // Encrypted assistant columns, defaults to ["options"]
+}
+
+// Reencrypter the store supports re-encrypting the encrypted columns after the key rotation
+type Reencrypter interface {
+	// Reencrypt encrypts the encrypted columns with the current key, returns the number of the rewritten rows
+	Reencrypt() (int, error)
+}
+
+// fieldCipher encrypt and decrypt the column values
+type fieldCipher struct {
+	key     string
+	aeads   map[string]cipher.AEAD
+	columns []string
+}
+
+func newFieldCipher(setting *EncryptionSetting) (*fieldCipher, error) {
+	c := &fieldCipher{key: setting.Key, aeads: map[string]cipher.AEAD{}, columns: setting.Columns}
+	if len(c.columns) == 0 {
+		c.columns = []string{"options"}
+	}
+
+	for id, value := range setting.Keys {
+		if strings.HasPrefix(value, "$ENV.") {
+			value = os.Getenv(strings.TrimPrefix(value, "$ENV."))
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %s is not base64: %s", id, err.Error())
+		}
+
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %s: %s", id, err.Error())
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %s: %s", id, err.Error())
+		}
+		c.aeads[id] = aead
+	}
+
+	if _, has := c.aeads[c.key]; !has {
+		return nil, fmt.Errorf("encryption key %s is not found", c.key)
+	}
+	return c, nil
+}
+
+// encrypt the plain text with the current key
+func (c *fieldCipher) encrypt(plain string) (string, error) {
+	aead := c.aeads[c.key]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plain), []byte(c.key))
+	return encryptedPrefix + c.key + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt the value encrypted with any of the keys
+func (c *fieldCipher) decrypt(value string) (string, error) {
+	id, data, ok := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if !ok {
+		return "", fmt.Errorf("the encrypted value is invalid")
+	}
+
+	aead, has := c.aeads[id]
+	if !has {
+		return "", fmt.Errorf("encryption key %s is not found", id)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("the encrypted value is invalid")
+	}
+
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// current whether the value is encrypted with the current key
+func (c *fieldCipher) current(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix+c.key+":")
+}
+
+func (conv *Xun) initEncryption() error {
+	if conv.setting.Encryption == nil {
+		return nil
+	}
+
+	c, err := newFieldCipher(conv.setting.Encryption)
+	if err != nil {
+		return err
+	}
+	conv.cipher = c
+	return nil
+}
+
+// encryptColumns encrypt the encrypted columns of the row, the values are the JSON strings of the columns.
+// The encrypted value is stored as a JSON string, so the JSON columns stay valid.
+func (conv *Xun) encryptColumns(row map[string]interface{}) error {
+	if conv.cipher == nil {
+		return nil
+	}
+
+	for _, column := range conv.cipher.columns {
+		raw, ok := row[column].(string)
+		if !ok || raw == "" || strings.HasPrefix(raw, `"`+encryptedPrefix) {
+			continue
+		}
+
+		encrypted, err := conv.cipher.encrypt(raw)
+		if err != nil {
+			return err
+		}
+
+		row[column], err = jsoniter.MarshalToString(encrypted)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decryptValue decrypt the parsed value of the JSON column, the value is returned as is if it is not encrypted
+func (conv *Xun) decryptValue(field string, value interface{}) interface{} {
+	text, ok := value.(string)
+	if !ok || !strings.HasPrefix(text, encryptedPrefix) {
+		return value
+	}
+
+	if conv.cipher == nil {
+		log.Warn("[Neo] the %s is encrypted but the store encryption is not set", field)
+		return value
+	}
+
+	plain, err := conv.cipher.decrypt(text)
+	if err != nil {
+		log.Error("[Neo] decrypt the %s: %s", field, err.Error())
+		return value
+	}
+
+	var parsed interface{}
+	if err := jsoniter.UnmarshalFromString(plain, &parsed); err != nil {
+		return plain
+	}
+	return parsed
+}
+
+// Reencrypt encrypt the encrypted columns of the assistants with the current key,
+// the values encrypted with the rotated keys and the plain values stored before the encryption is enabled are rewritten.
+// Returns the number of the rewritten assistants.
+func (conv *Xun) Reencrypt() (int, error) {
+	if conv.cipher == nil {
+		return 0, fmt.Errorf("the store encryption is not set")
+	}
+
+	columns := append([]string{"id"}, conv.cipher.columns...)
+	count := 0
+	lastID := 0
+	for {
+		rows, err := conv.query.New().
+			Table(conv.getAssistantTable()).
+			Select(columns...).
+			Where("id", ">", lastID).
+			OrderBy("id", "asc").
+			Limit(conv.batchSize()).
+			Get()
+		if err != nil {
+			return count, err
+		}
+
+		if len(rows) == 0 {
+			return count, nil
+		}
+
+		for _, row := range rows {
+			data := row.ToMap()
+			if _, err := fmt.Sscanf(fmt.Sprintf("%v", data["id"]), "%d", &lastID); err != nil {
+				return count, fmt.Errorf("invalid assistant id %v", data["id"])
+			}
+
+			values := map[string]interface{}{}
+			for _, column := range conv.cipher.columns {
+				raw, ok := data[column].(string)
+				if !ok || raw == "" {
+					continue
+				}
+
+				// The plain JSON of the column
+				var encrypted string
+				if jsoniter.UnmarshalFromString(raw, &encrypted) == nil && strings.HasPrefix(encrypted, encryptedPrefix) {
+					if conv.cipher.current(encrypted) {
+						continue
+					}
+					raw, err = conv.cipher.decrypt(encrypted)
+					if err != nil {
+						return count, fmt.Errorf("decrypt the %s of the assistant %d: %s", column, lastID, err.Error())
+					}
+				}
+				values[column] = raw
+			}
+
+			if len(values) == 0 {
+				continue
+			}
+
+			if err := conv.encryptColumns(values); err != nil {
+				return count, err
+			}
+
+			_, err := conv.query.New().
+				Table(conv.getAssistantTable()).
+				Where("id", lastID).
+				Update(values)
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldCipher(t *testing.T) {
+	old := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	key := base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+
+	c, err := newFieldCipher(&EncryptionSetting{Key: "old", Keys: map[string]string{"old": old}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"options"}, c.columns)
+
+	encrypted, err := c.encrypt(`{"api_key":"sk-xxx"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasPrefix(encrypted, "enc:v1:old:"))
+	assert.NotContains(t, encrypted, "sk-xxx")
+
+	// Rotate the key, the values encrypted by the old key are still readable
+	rotated, err := newFieldCipher(&EncryptionSetting{Key: "new", Keys: map[string]string{"old": old, "new": key}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := rotated.decrypt(encrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"api_key":"sk-xxx"}`, plain)
+	assert.False(t, rotated.current(encrypted))
+
+	// The old key is removed
+	removed, err := newFieldCipher(&EncryptionSetting{Key: "new", Keys: map[string]string{"new": key}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = removed.decrypt(encrypted)
+	assert.NotNil(t, err)
+
+	// Tampered
+	_, err = c.decrypt(encrypted[:len(encrypted)-4] + "AAAA")
+	assert.NotNil(t, err)
+
+	// Invalid settings
+	_, err = newFieldCipher(&EncryptionSetting{Key: "missing", Keys: map[string]string{"old": old}})
+	assert.NotNil(t, err)
+	_, err = newFieldCipher(&EncryptionSetting{Key: "short", Keys: map[string]string{"short": base64.StdEncoding.EncodeToString([]byte("short"))}})
+	assert.NotNil(t, err)
+}
+
+func TestXunEncryptColumns(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	c, err := newFieldCipher(&EncryptionSetting{Key: "k1", Keys: map[string]string{"k1": key}, Columns: []string{"options", "prompts"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv := &Xun{cipher: c}
+
+	row := map[string]interface{}{"name": "test", "options": `{"api_key":"sk-xxx"}`, "prompts": nil}
+	err = conv.encryptColumns(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "test", row["name"])
+	assert.Nil(t, row["prompts"])
+	assert.NotContains(t, row["options"], "sk-xxx")
+
+	// Stored as a JSON string, parsed and decrypted when it is read
+	var parsed interface{}
+	err = jsoniter.UnmarshalFromString(row["options"].(string), &parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]interface{}{"api_key": "sk-xxx"}, conv.decryptValue("options", parsed))
+
+	// Encrypted once
+	encrypted := row["options"]
+	err = conv.encryptColumns(row)
+	assert.Nil(t, err)
+	assert.Equal(t, encrypted, row["options"])
+
+	// The plain values are kept
+	assert.Equal(t, "plain", conv.decryptValue("options", "plain"))
+}
@@ -0,0 +1,22 @@
+package store
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// ConflictError the assistant was changed by others since the version of the client
+type ConflictError struct {
+	ID     string                 // The assistant ID
+	Latest map[string]interface{} // The latest version of the assistant
+}
+
+// Error the error message
+func (err *ConflictError) Error() string {
+	return fmt.Sprintf("assistant %s was changed by others, reload it and try again", err.ID)
+}
+
+// AssistantETag the version tag of the assistant, it changes when the assistant is updated
+func AssistantETag(assistant map[string]interface{}) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprintf("%v", assistant["updated_at"]))))[:16]
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"strings"
+	"time"
+)
+
+// chatGroups the date groups of the chats in order, the key is stable and the label is translated by the client
+var chatGroups = []struct {
+	Key   string
+	Label string
+}{
+	{Key: "today", Label: "Today"},
+	{Key: "yesterday", Label: "Yesterday"},
+	{Key: "this_week", Label: "This Week"},
+	{Key: "last_week", Label: "Last Week"},
+	{Key: "earlier", Label: "Even Earlier"},
+}
+
+// sundayRegions the regions the week starts on Sunday, from the CLDR week data, China follows the ISO week (GB/T 7408)
+var sundayRegions = map[string]bool{
+	"ag": true, "as": true, "au": true, "bd": true, "br": true, "bs": true, "bt": true, "bw": true, "bz": true,
+	"ca": true, "co": true, "dm": true, "do": true, "et": true, "gt": true, "gu": true, "hk": true, "hn": true,
+	"id": true, "il": true, "in": true, "jm": true, "jp": true, "ke": true, "kh": true, "kr": true, "la": true,
+	"mh": true, "mm": true, "mo": true, "mt": true, "mx": true, "mz": true, "ni": true, "np": true, "pa": true,
+	"pe": true, "ph": true, "pk": true, "pr": true, "pt": true, "py": true, "sa": true, "sg": true, "sv": true,
+	"th": true, "tt": true, "tw": true, "um": true, "us": true, "ve": true, "vi": true, "ws": true, "ye": true,
+	"za": true, "zw": true,
+}
+
+// saturdayRegions the regions the week starts on Saturday, from the CLDR week data
+var saturdayRegions = map[string]bool{
+	"ae": true, "af": true, "bh": true, "dj": true, "dz": true, "eg": true, "iq": true, "ir": true,
+	"jo": true, "kw": true, "ly": true, "om": true, "qa": true, "sd": true, "sy": true,
+}
+
+// languageWeekStarts the first day of the week of the locales without the region, Monday if not listed
+var languageWeekStarts = map[string]time.Weekday{
+	"en": time.Sunday,
+	"ja": time.Sunday,
+	"ko": time.Sunday,
+	"he": time.Sunday,
+	"ar": time.Saturday,
+	"fa": time.Saturday,
+}
+
+// rtlLanguages the languages written from right to left
+var rtlLanguages = map[string]bool{
+	"ar": true, "he": true, "fa": true, "ur": true, "ps": true, "yi": true, "dv": true, "ckb": true, "sd": true, "ug": true,
+}
+
+// parseLocale the language and the region of the locale in lower case, e.g. zh-Hant-HK => zh, hk
+func parseLocale(locale string) (string, string) {
+	parts := strings.Split(strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")), "-")
+	language := parts[0]
+	region := ""
+	for _, part := range parts[1:] {
+		if len(part) == 2 {
+			region = part
+			break
+		}
+	}
+	return language, region
+}
+
+// weekStart the first day of the week of the locale, Monday by default (ISO 8601)
+func weekStart(locale string) time.Weekday {
+	language, region := parseLocale(locale)
+	switch {
+	case sundayRegions[region]:
+		return time.Sunday
+	case saturdayRegions[region]:
+		return time.Saturday
+	case region != "":
+		return time.Monday
+	}
+
+	if day, has := languageWeekStarts[language]; has {
+		return day
+	}
+	return time.Monday
+}
+
+// textDirection the text direction of the locale, rtl or ltr
+func textDirection(locale string) string {
+	language, _ := parseLocale(locale)
+	if rtlLanguages[language] {
+		return "rtl"
+	}
+	return "ltr"
+}
+
+// chatGroupOf the group key of the chat, the day boundaries are the midnights in the location of the user
+func chatGroupOf(createdAt time.Time, now time.Time, loc *time.Location, start time.Weekday) string {
+	today := midnight(now, loc)
+	created := midnight(createdAt, loc)
+	yesterday := today.AddDate(0, 0, -1)
+	thisWeek := today.AddDate(0, 0, -((int(today.Weekday()) - int(start) + 7) % 7))
+	lastWeek := thisWeek.AddDate(0, 0, -7)
+
+	switch {
+	case !created.Before(today):
+		return "today"
+	case created.Equal(yesterday):
+		return "yesterday"
+	case !created.Before(thisWeek):
+		return "this_week"
+	case !created.Before(lastWeek):
+		return "last_week"
+	}
+	return "earlier"
+}
+
+// midnight the start of the day in the location
+func midnight(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeekStart(t *testing.T) {
+	assert.Equal(t, time.Monday, weekStart(""))
+	assert.Equal(t, time.Monday, weekStart("zh-CN"))
+	assert.Equal(t, time.Monday, weekStart("en-GB"))
+	assert.Equal(t, time.Monday, weekStart("fr"))
+	assert.Equal(t, time.Sunday, weekStart("en"))
+	assert.Equal(t, time.Sunday, weekStart("en_US"))
+	assert.Equal(t, time.Sunday, weekStart("zh-Hant-TW"))
+	assert.Equal(t, time.Sunday, weekStart("he-IL"))
+	assert.Equal(t, time.Saturday, weekStart("ar-EG"))
+	assert.Equal(t, time.Saturday, weekStart("fa"))
+	assert.Equal(t, time.Monday, weekStart("ar-MA"))
+}
+
+func TestTextDirection(t *testing.T) {
+	assert.Equal(t, "ltr", textDirection(""))
+	assert.Equal(t, "ltr", textDirection("en-US"))
+	assert.Equal(t, "rtl", textDirection("ar-EG"))
+	assert.Equal(t, "rtl", textDirection("he"))
+	assert.Equal(t, "rtl", textDirection("fa_IR"))
+}
+
+func TestChatGroupOf(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("the timezone database is not available")
+	}
+
+	// Wednesday 2024-01-10 08:00 in Shanghai, 2024-01-10 00:00 UTC
+	now := time.Date(2024, 1, 10, 8, 0, 0, 0, shanghai)
+
+	// 2024-01-09 20:00 UTC is today in Shanghai but yesterday in UTC
+	created := time.Date(2024, 1, 9, 20, 0, 0, 0, time.UTC)
+	assert.Equal(t, "today", chatGroupOf(created, now, shanghai, time.Monday))
+	assert.Equal(t, "yesterday", chatGroupOf(created, now, time.UTC, time.Monday))
+
+	assert.Equal(t, "yesterday", chatGroupOf(time.Date(2024, 1, 9, 9, 0, 0, 0, shanghai), now, shanghai, time.Monday))
+
+	// Monday 2024-01-08 is this week if the week starts on Monday or Sunday
+	monday := time.Date(2024, 1, 8, 12, 0, 0, 0, shanghai)
+	assert.Equal(t, "this_week", chatGroupOf(monday, now, shanghai, time.Monday))
+	assert.Equal(t, "this_week", chatGroupOf(monday, now, shanghai, time.Sunday))
+
+	// Sunday 2024-01-07 is this week if the week starts on Sunday or Saturday, last week if on Monday
+	sunday := time.Date(2024, 1, 7, 12, 0, 0, 0, shanghai)
+	assert.Equal(t, "last_week", chatGroupOf(sunday, now, shanghai, time.Monday))
+	assert.Equal(t, "this_week", chatGroupOf(sunday, now, shanghai, time.Sunday))
+	assert.Equal(t, "this_week", chatGroupOf(sunday, now, shanghai, time.Saturday))
+
+	// Saturday 2024-01-06 is last week if the week starts on Sunday
+	saturday := time.Date(2024, 1, 6, 12, 0, 0, 0, shanghai)
+	assert.Equal(t, "last_week", chatGroupOf(saturday, now, shanghai, time.Sunday))
+	assert.Equal(t, "this_week", chatGroupOf(saturday, now, shanghai, time.Saturday))
+
+	assert.Equal(t, "last_week", chatGroupOf(time.Date(2024, 1, 1, 0, 0, 0, 0, shanghai), now, shanghai, time.Monday))
+	assert.Equal(t, "earlier", chatGroupOf(time.Date(2023, 12, 31, 23, 0, 0, 0, shanghai), now, shanghai, time.Monday))
+}
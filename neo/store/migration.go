@@ -0,0 +1,305 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// Migration the versioned schema change of the store tables.
+// The tables are created with the latest schema, the migrations upgrade the tables created by the earlier versions,
+// so the up and the down of the migration must be idempotent and skip the missing tables.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(conv *Xun) error
+	Down    func(conv *Xun) error
+}
+
+// MigrationStatus the status of the migration
+type MigrationStatus struct {
+	Version   int         `json:"version"`
+	Name      string      `json:"name"`
+	Applied   bool        `json:"applied"`
+	AppliedAt interface{} `json:"applied_at,omitempty"`
+}
+
+// Migrator the store supports the versioned migrations
+type Migrator interface {
+	// Migrate applies the pending migrations, returns the applied migrations
+	Migrate() ([]MigrationStatus, error)
+
+	// Rollback reverts the last applied migrations, returns the reverted migrations
+	Rollback(steps int) ([]MigrationStatus, error)
+
+	// Migrations returns the status of the migrations
+	Migrations() ([]MigrationStatus, error)
+}
+
+// migrations the migrations of the store tables in order, append the new migrations to the end and never change the applied ones
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "add_assistant_automated_mentionable",
+		Up: func(conv *Xun) error {
+			return conv.addColumns(conv.getAssistantTable(), map[string]func(table schema.Blueprint){
+				"automated":   func(table schema.Blueprint) { table.Boolean("automated").SetDefault(true).Index() },
+				"mentionable": func(table schema.Blueprint) { table.Boolean("mentionable").SetDefault(true).Index() },
+			})
+		},
+		Down: func(conv *Xun) error {
+			return conv.dropColumns(conv.getAssistantTable(), "automated", "mentionable")
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add_assistant_routes",
+		Up: func(conv *Xun) error {
+			return conv.addColumns(conv.getAssistantTable(), map[string]func(table schema.Blueprint){
+				"routes": func(table schema.Blueprint) { table.JSON("routes").Null() },
+			})
+		},
+		Down: func(conv *Xun) error {
+			return conv.dropColumns(conv.getAssistantTable(), "routes")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add_assistant_tool_policies",
+		Up: func(conv *Xun) error {
+			return conv.addColumns(conv.getAssistantTable(), map[string]func(table schema.Blueprint){
+				"tool_policies": func(table schema.Blueprint) { table.JSON("tool_policies").Null() },
+			})
+		},
+		Down: func(conv *Xun) error {
+			return conv.dropColumns(conv.getAssistantTable(), "tool_policies")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add_history_original",
+		Up: func(conv *Xun) error {
+			return conv.addColumns(conv.getHistoryTable(), map[string]func(table schema.Blueprint){
+				"original": func(table schema.Blueprint) { table.Text("original").Null() },
+			})
+		},
+		Down: func(conv *Xun) error {
+			return conv.dropColumns(conv.getHistoryTable(), "original")
+		},
+	},
+}
+
+func (conv *Xun) getMigrationTable() string {
+	return conv.setting.Prefix + "migration"
+}
+
+func (conv *Xun) initMigrationTable() error {
+	migrationTable := conv.getMigrationTable()
+	has, err := conv.schema.HasTable(migrationTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		err = conv.schema.CreateTable(migrationTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.Integer("version").Unique().Index() // the migration version
+			table.String("name", 200)                 // the migration name
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()").Index()
+		})
+
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the migration table: %s", migrationTable)
+	}
+
+	return nil
+}
+
+// Migrate apply the pending migrations in order
+func (conv *Xun) Migrate() ([]MigrationStatus, error) {
+	applied, err := conv.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	done := []MigrationStatus{}
+	for _, migration := range sortedMigrations() {
+		if _, has := applied[migration.Version]; has {
+			continue
+		}
+
+		if err := migration.Up(conv); err != nil {
+			return done, fmt.Errorf("migration %d %s: %s", migration.Version, migration.Name, err.Error())
+		}
+
+		now := time.Now()
+		_, err := conv.query.New().
+			Table(conv.getMigrationTable()).
+			Insert(map[string]interface{}{
+				"version":    migration.Version,
+				"name":       migration.Name,
+				"created_at": now,
+			})
+		if err != nil {
+			return done, err
+		}
+
+		log.Trace("Apply the migration %d %s: %s", migration.Version, migration.Name, conv.setting.Prefix)
+		done = append(done, MigrationStatus{Version: migration.Version, Name: migration.Name, Applied: true, AppliedAt: now})
+	}
+
+	return done, nil
+}
+
+// Rollback revert the last applied migrations, steps is the number of the migrations
+func (conv *Xun) Rollback(steps int) ([]MigrationStatus, error) {
+	applied, err := conv.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := sortedMigrations()
+	done := []MigrationStatus{}
+	for i := len(sorted) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := sorted[i]
+		if _, has := applied[migration.Version]; !has {
+			continue
+		}
+
+		if migration.Down != nil {
+			if err := migration.Down(conv); err != nil {
+				return done, fmt.Errorf("rollback %d %s: %s", migration.Version, migration.Name, err.Error())
+			}
+		}
+
+		_, err := conv.query.New().
+			Table(conv.getMigrationTable()).
+			Where("version", migration.Version).
+			Delete()
+		if err != nil {
+			return done, err
+		}
+
+		log.Trace("Rollback the migration %d %s: %s", migration.Version, migration.Name, conv.setting.Prefix)
+		done = append(done, MigrationStatus{Version: migration.Version, Name: migration.Name})
+	}
+
+	return done, nil
+}
+
+// Migrations the status of the migrations
+func (conv *Xun) Migrations() ([]MigrationStatus, error) {
+	applied, err := conv.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	res := []MigrationStatus{}
+	for _, migration := range sortedMigrations() {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, has := applied[migration.Version]; has {
+			status.Applied = true
+			status.AppliedAt = appliedAt
+		}
+		res = append(res, status)
+	}
+	return res, nil
+}
+
+// appliedMigrations the applied versions and the applied time
+func (conv *Xun) appliedMigrations() (map[int]interface{}, error) {
+	if err := conv.initMigrationTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := conv.query.New().
+		Table(conv.getMigrationTable()).
+		Select("version", "created_at").
+		Get()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int]interface{}{}
+	for _, row := range rows {
+		version, err := strconv.Atoi(fmt.Sprintf("%v", row.Get("version")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %v", row.Get("version"))
+		}
+		applied[version] = row.Get("created_at")
+	}
+	return applied, nil
+}
+
+// addColumns add the missing columns to the table, the table is skipped if it does not exist
+func (conv *Xun) addColumns(name string, columns map[string]func(table schema.Blueprint)) error {
+	has, err := conv.schema.HasTable(name)
+	if err != nil || !has {
+		return err
+	}
+
+	tab, err := conv.schema.GetTable(name)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(columns))
+	for column := range columns {
+		names = append(names, column)
+	}
+	sort.Strings(names)
+
+	for _, column := range names {
+		if tab.HasColumn(column) {
+			continue
+		}
+
+		err = conv.schema.AlterTable(name, columns[column])
+		if err != nil {
+			return err
+		}
+		log.Trace("Add the %s column to the table: %s", column, name)
+	}
+	return nil
+}
+
+// dropColumns drop the existing columns of the table, the table is skipped if it does not exist
+func (conv *Xun) dropColumns(name string, columns ...string) error {
+	has, err := conv.schema.HasTable(name)
+	if err != nil || !has {
+		return err
+	}
+
+	tab, err := conv.schema.GetTable(name)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		if !tab.HasColumn(column) {
+			continue
+		}
+
+		err = conv.schema.AlterTable(name, func(table schema.Blueprint) {
+			table.DropColumn(column)
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Drop the %s column of the table: %s", column, name)
+	}
+	return nil
+}
+
+// sortedMigrations the migrations sorted by the version
+func sortedMigrations() []Migration {
+	sorted := append([]Migration{}, migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return sorted
+}
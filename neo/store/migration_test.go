@@ -0,0 +1,89 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/test"
+)
+
+func TestMigrationVersions(t *testing.T) {
+	versions := map[int]bool{}
+	for _, migration := range migrations {
+		assert.False(t, versions[migration.Version], "duplicate migration version %d", migration.Version)
+		assert.NotEmpty(t, migration.Name)
+		assert.NotNil(t, migration.Up)
+		versions[migration.Version] = true
+	}
+}
+
+func TestXunMigrations(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+
+	prefix := "__unit_test_migration_"
+	tables := []string{"history", "chat", "assistant", "collection", "memory", "profile", "workflow_run", "approval", "deletion", "lock", "migration"}
+	drop := func() {
+		for _, table := range tables {
+			capsule.Schema().DropTableIfExists(prefix + table)
+		}
+	}
+	drop()
+	defer drop()
+
+	s, err := NewXun(Setting{Connector: "default", Prefix: prefix})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, ok := s.(Migrator)
+	if !ok {
+		t.Fatal("the xun store is not a migrator")
+	}
+
+	// All migrations are applied when the store is created
+	status, err := migrator.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(migrations), len(status))
+	for _, migration := range status {
+		assert.True(t, migration.Applied)
+	}
+
+	// Rollback the last migration
+	reverted, err := migrator.Rollback(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(reverted))
+	assert.Equal(t, "add_assistant_tool_policies", reverted[0].Name)
+
+	tab, err := capsule.Schema().GetTable(prefix + "assistant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, tab.HasColumn("tool_policies"))
+
+	// Apply the pending migration
+	applied, err := migrator.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(applied))
+
+	tab, err = capsule.Schema().GetTable(prefix + "assistant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, tab.HasColumn("tool_policies"))
+
+	// Nothing is pending
+	applied, err = migrator.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(applied))
+}
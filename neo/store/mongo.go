@@ -1,5 +1,7 @@
 package store
 
+import "time"
+
 // Mongo represents a MongoDB-based conversation storage
 type Mongo struct{}
 
@@ -48,6 +50,11 @@ func (m *Mongo) SaveAssistant(assistant map[string]interface{}) (interface{}, er
 	return assistant["assistant_id"], nil
 }
 
+// UpdateAssistant updates an existing assistant if it is not changed since the etag
+func (m *Mongo) UpdateAssistant(assistant map[string]interface{}, etag string) (string, error) {
+	return "", nil
+}
+
 // DeleteAssistant deletes an assistant
 func (m *Mongo) DeleteAssistant(assistantID string) error {
 	return nil
@@ -72,3 +79,108 @@ func (mongo *Mongo) DeleteAssistants(filter AssistantFilter) (int64, error) {
 func (conv *Mongo) GetAssistantTags() ([]string, error) {
 	return []string{}, nil
 }
+
+// SaveCollection saves the knowledge collection settings
+func (m *Mongo) SaveCollection(collection map[string]interface{}) (interface{}, error) {
+	return collection["name"], nil
+}
+
+// GetCollection retrieves a single knowledge collection by name
+func (m *Mongo) GetCollection(name string) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+// GetCollections retrieves all knowledge collections
+func (m *Mongo) GetCollections() ([]map[string]interface{}, error) {
+	return []map[string]interface{}{}, nil
+}
+
+// DeleteCollection deletes a knowledge collection
+func (m *Mongo) DeleteCollection(name string) error {
+	return nil
+}
+
+// SaveMemories saves the long-term memories of a user
+func (m *Mongo) SaveMemories(sid string, assistantID string, memories []string, ttl int) ([]string, error) {
+	return []string{}, nil
+}
+
+// GetMemories retrieves the long-term memories of a user
+func (m *Mongo) GetMemories(sid string, filter MemoryFilter) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{}, nil
+}
+
+// DeleteMemory deletes a single memory
+func (m *Mongo) DeleteMemory(sid string, memoryID string) error {
+	return nil
+}
+
+// DeleteMemories deletes all the memories of a user
+func (m *Mongo) DeleteMemories(sid string, assistantID string) (int64, error) {
+	return 0, nil
+}
+
+// GetProfile retrieves the preference profile of a user
+func (m *Mongo) GetProfile(sid string) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+// SaveProfile saves the preference profile of a user
+func (m *Mongo) SaveProfile(sid string, profile map[string]interface{}) error {
+	return nil
+}
+
+// SaveWorkflowRun creates or updates a workflow run
+func (m *Mongo) SaveWorkflowRun(sid string, run map[string]interface{}) error {
+	return nil
+}
+
+// GetWorkflowRun retrieves a single workflow run
+func (m *Mongo) GetWorkflowRun(sid string, runID string) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+// GetWorkflowRuns retrieves the workflow runs of a user
+func (m *Mongo) GetWorkflowRuns(sid string, filter WorkflowRunFilter) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{}, nil
+}
+
+// SaveApproval creates a pending tool approval
+func (m *Mongo) SaveApproval(sid string, approval map[string]interface{}) (string, error) {
+	return "", nil
+}
+
+// GetApproval retrieves a single tool approval
+func (m *Mongo) GetApproval(approvalID string) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+// GetApprovals retrieves the tool approvals
+func (m *Mongo) GetApprovals(sid string, filter ApprovalFilter) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{}, nil
+}
+
+// DecideApproval accepts or rejects a pending tool approval
+func (m *Mongo) DecideApproval(approvalID string, sid string, approved bool, comment string) error {
+	return nil
+}
+
+// ExportUserData retrieves all the data of a user
+func (m *Mongo) ExportUserData(sid string) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+// ScheduleUserDeletion schedules the hard deletion of all the data of a user
+func (m *Mongo) ScheduleUserDeletion(sid string, grace time.Duration) (time.Time, error) {
+	return time.Now().Add(grace), nil
+}
+
+// CancelUserDeletion cancels the scheduled deletion of a user
+func (m *Mongo) CancelUserDeletion(sid string) error {
+	return nil
+}
+
+// GetUserDeletion retrieves the scheduled deletion of a user
+func (m *Mongo) GetUserDeletion(sid string) (map[string]interface{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedactionSetting the PII redaction of the messages before they are saved.
+// The content is saved with the PII replaced, e.g. [EMAIL], the original is saved encrypted if the store encryption is set.
+type RedactionSetting struct {
+	Types    []string          `json:"types,omitempty" yaml:"types,omitempty"`       // The PII types: email, credit_card, id_number, phone, all by default
+	Patterns map[string]string `json:"patterns,omitempty" yaml:"patterns,omitempty"` // The custom patterns, name => regular expression, e.g. {"order_id": "ORD-\\d{8}"}
+}
+
+// piiRule the pattern of the PII type and the validation of the matches
+type piiRule struct {
+	name     string
+	pattern  *regexp.Regexp
+	validate func(match string) bool
+}
+
+// piiRules the builtin PII rules, the longer numbers are matched before the phones
+var piiRules = []piiRule{
+	{name: "email", pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)},
+	{name: "credit_card", pattern: regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`), validate: luhn},
+	{name: "id_number", pattern: regexp.MustCompile(`\b\d{17}[\dXx]\b|\b\d{3}-\d{2}-\d{4}\b`)},
+	{name: "phone", pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .\-]?)?(?:\(\d{1,4}\)[ .\-]?)?\d{2,4}(?:[ .\-]?\d{2,4}){1,4}`), validate: phone},
+}
+
+var datePattern = regexp.MustCompile(`^\d{4}[\-/.]\d{1,2}[\-/.]\d{1,2}$`)
+
+// redactor replace the PII of the content
+type redactor struct {
+	rules []piiRule
+}
+
+func newRedactor(setting *RedactionSetting) (*redactor, error) {
+	r := &redactor{rules: []piiRule{}}
+	types := map[string]bool{}
+	for _, name := range setting.Types {
+		types[name] = true
+	}
+
+	for _, rule := range piiRules {
+		if len(types) == 0 || types[rule.name] {
+			r.rules = append(r.rules, rule)
+		}
+	}
+
+	names := make([]string, 0, len(setting.Patterns))
+	for name := range setting.Patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pattern, err := regexp.Compile(setting.Patterns[name])
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %s: %s", name, err.Error())
+		}
+		r.rules = append(r.rules, piiRule{name: name, pattern: pattern})
+	}
+	return r, nil
+}
+
+// redact the content, returns the redacted content and whether any PII is found
+func (r *redactor) redact(content string) (string, bool) {
+	found := false
+	for _, rule := range r.rules {
+		placeholder := "[" + strings.ToUpper(rule.name) + "]"
+		content = rule.pattern.ReplaceAllStringFunc(content, func(match string) string {
+			if rule.validate != nil && !rule.validate(match) {
+				return match
+			}
+			found = true
+			return placeholder
+		})
+	}
+	return content, found
+}
+
+// luhn the card number passes the Luhn checksum
+func luhn(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// phone the match has the digits of a phone number and is not a date
+func phone(match string) bool {
+	digits := 0
+	for _, c := range match {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	return digits >= 8 && digits <= 15 && !datePattern.MatchString(match)
+}
+
+func (conv *Xun) initRedaction() error {
+	if conv.setting.Redaction == nil {
+		return nil
+	}
+
+	r, err := newRedactor(conv.setting.Redaction)
+	if err != nil {
+		return err
+	}
+	conv.redactor = r
+	return nil
+}
+
+// redactContent redact the content of the message, the original is encrypted if the store encryption is set, or dropped
+func (conv *Xun) redactContent(content string) (string, interface{}, error) {
+	if conv.redactor == nil {
+		return content, nil, nil
+	}
+
+	redacted, found := conv.redactor.redact(content)
+	if !found || conv.cipher == nil {
+		return redacted, nil, nil
+	}
+
+	original, err := conv.cipher.encrypt(content)
+	if err != nil {
+		return "", nil, err
+	}
+	return redacted, original, nil
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	r, err := newRedactor(&RedactionSetting{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, found := r.redact("Contact me at alice.smith@example.co.uk or +1 (415) 555-0132.")
+	assert.True(t, found)
+	assert.Equal(t, "Contact me at [EMAIL] or [PHONE].", content)
+
+	content, found = r.redact("My card is 4111 1111 1111 1111 and my ID is 11010519491231002X, SSN 123-45-6789")
+	assert.True(t, found)
+	assert.Equal(t, "My card is [CREDIT_CARD] and my ID is [ID_NUMBER], SSN [ID_NUMBER]", content)
+
+	content, found = r.redact("Call 138 0013 8000 tomorrow")
+	assert.True(t, found)
+	assert.Equal(t, "Call [PHONE] tomorrow", content)
+
+	// Not PII
+	content, found = r.redact("The meeting is on 2024-01-10 at 10:30, order 42 costs 1999 dollars")
+	assert.False(t, found)
+	assert.Equal(t, "The meeting is on 2024-01-10 at 10:30, order 42 costs 1999 dollars", content)
+
+	// Not a valid card number
+	_, found = r.redact("Tracking number 4111 1111 1111 1112")
+	assert.False(t, found)
+}
+
+func TestRedactTypes(t *testing.T) {
+	r, err := newRedactor(&RedactionSetting{Types: []string{"email"}, Patterns: map[string]string{"order_id": `ORD-\d{8}`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, found := r.redact("Order ORD-20240110 of bob@example.com, phone 13800138000")
+	assert.True(t, found)
+	assert.Equal(t, "Order [ORDER_ID] of [EMAIL], phone 13800138000", content)
+
+	_, err = newRedactor(&RedactionSetting{Patterns: map[string]string{"invalid": `(`}})
+	assert.NotNil(t, err)
+}
+
+func TestXunRedactContent(t *testing.T) {
+	r, err := newRedactor(&RedactionSetting{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The original is dropped without the encryption
+	conv := &Xun{redactor: r}
+	content, original, err := conv.redactContent("Mail bob@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "Mail [EMAIL]", content)
+	assert.Nil(t, original)
+
+	// The original is encrypted
+	c, err := newFieldCipher(&EncryptionSetting{Key: "k1", Keys: map[string]string{"k1": base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv.cipher = c
+	content, original, err = conv.redactContent("Mail bob@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "Mail [EMAIL]", content)
+
+	plain, err := c.decrypt(original.(string))
+	assert.Nil(t, err)
+	assert.Equal(t, "Mail bob@example.com", plain)
+
+	// No PII, nothing is encrypted
+	content, original, err = conv.redactContent("Hello")
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello", content)
+	assert.Nil(t, original)
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/xun/dbal/query"
+)
+
+// assistantWrites the sticky key of the assistant writes, the assistants are shared by all the users
+const assistantWrites = "__assistants"
+
+// replica the read replica of the xun store, the reads are sent to the primary for a while after the writes,
+// so the user reads the own writes even if the replica lags behind the primary.
+type replica struct {
+	query  query.Query
+	sticky time.Duration
+	writes sync.Map // key => time.Time the last write
+}
+
+// initReplica connect the read replica if the read connector is set
+func (conv *Xun) initReplica() error {
+	if conv.setting.ReadConnector == "" {
+		return nil
+	}
+
+	conn, err := connector.Select(conv.setting.ReadConnector)
+	if err != nil {
+		return err
+	}
+
+	qb, err := conn.Query()
+	if err != nil {
+		return err
+	}
+
+	sticky := time.Duration(conv.setting.Sticky) * time.Second
+	if sticky <= 0 {
+		sticky = 5 * time.Second
+	}
+
+	conv.replica = &replica{query: qb, sticky: sticky}
+	return nil
+}
+
+// readQuery the query of the reads, the replica is used unless the key was written within the sticky window
+func (conv *Xun) readQuery(key string) query.Query {
+	if conv.replica == nil {
+		return conv.query.New()
+	}
+
+	if last, has := conv.replica.writes.Load(key); has {
+		if time.Since(last.(time.Time)) < conv.replica.sticky {
+			return conv.query.New()
+		}
+		conv.replica.writes.Delete(key)
+	}
+	return conv.replica.query.New()
+}
+
+// written mark the key is written, the following reads of the key are sent to the primary within the sticky window
+func (conv *Xun) written(key string) {
+	if conv.replica == nil {
+		return
+	}
+	conv.replica.writes.Store(key, time.Now())
+}
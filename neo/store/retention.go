@@ -0,0 +1,99 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yaoapp/gou/session"
+)
+
+// RetentionPolicy the retention of the data of a team or an assistant, overrides the store TTL.
+// The most specific policy is used, the team and the assistant policy, then the assistant policy, then the team policy.
+type RetentionPolicy struct {
+	Team      string `json:"team,omitempty" yaml:"team,omitempty"`           // Team ID, all the teams if empty
+	Assistant string `json:"assistant,omitempty" yaml:"assistant,omitempty"` // Assistant ID, all the assistants if empty
+	History   int    `json:"history,omitempty" yaml:"history,omitempty"`     // Seconds the messages are kept, the store TTL if 0
+	Memory    int    `json:"memory,omitempty" yaml:"memory,omitempty"`       // Seconds the memories are kept, the TTL of the memories if 0
+}
+
+// retention the most specific policy of the team and the assistant, nil if there is no policy
+func (conv *Xun) retention(teamID string, assistantID string) *RetentionPolicy {
+	var match *RetentionPolicy
+	score := -1
+	for i := range conv.setting.Retention {
+		policy := &conv.setting.Retention[i]
+		if policy.Team != "" && policy.Team != teamID {
+			continue
+		}
+		if policy.Assistant != "" && policy.Assistant != assistantID {
+			continue
+		}
+
+		// The assistant is more specific than the team
+		s := 0
+		if policy.Assistant != "" {
+			s += 2
+		}
+		if policy.Team != "" {
+			s++
+		}
+
+		if s > score {
+			match = policy
+			score = s
+		}
+	}
+	return match
+}
+
+// historyExpiredAt the expiration of the messages of the assistant
+func (conv *Xun) historyExpiredAt(teamID string, assistantID string, now time.Time) interface{} {
+	ttl := conv.setting.TTL
+	if policy := conv.retention(teamID, assistantID); policy != nil && policy.History > 0 {
+		ttl = policy.History
+	}
+
+	if ttl > 0 {
+		return now.Add(time.Duration(ttl) * time.Second)
+	}
+	return nil
+}
+
+// memoryTTL the TTL of the memories of the assistant, the policy is used if the ttl is not set
+func (conv *Xun) memoryTTL(teamID string, assistantID string, ttl int) int {
+	if ttl > 0 {
+		return ttl
+	}
+
+	if policy := conv.retention(teamID, assistantID); policy != nil && policy.Memory > 0 {
+		return policy.Memory
+	}
+	return 0
+}
+
+// getTeamID the team id of the session, empty if the retention policies are not set or the session has no team
+func (conv *Xun) getTeamID(sid string) (string, error) {
+	if len(conv.setting.Retention) == 0 {
+		return "", nil
+	}
+	return conv.teamOf(sid)
+}
+
+// teamOf the team id of the session, empty if the session has no team
+func (conv *Xun) teamOf(sid string) (string, error) {
+	field := "team_id"
+	if conv.setting.TeamField != "" {
+		field = conv.setting.TeamField
+	}
+
+	id, err := session.Global().ID(sid).Get(field)
+	if err != nil {
+		return "", err
+	}
+
+	if id == nil || id == "" {
+		return "", nil
+	}
+
+	return fmt.Sprintf("%v", id), nil
+}
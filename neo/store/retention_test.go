@@ -0,0 +1,71 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/test"
+)
+
+func TestRetention(t *testing.T) {
+	conv := &Xun{setting: Setting{
+		TTL: 3600,
+		Retention: []RetentionPolicy{
+			{Team: "team-1", History: 30 * 86400},
+			{Assistant: "assistant-1", History: 7 * 86400, Memory: 90 * 86400},
+			{Team: "team-1", Assistant: "assistant-1", History: 86400},
+		},
+	}}
+
+	assert.Nil(t, conv.retention("", ""))
+	assert.Equal(t, 30*86400, conv.retention("team-1", "").History)
+	assert.Equal(t, 30*86400, conv.retention("team-1", "assistant-2").History)
+	assert.Equal(t, 7*86400, conv.retention("team-2", "assistant-1").History)
+	assert.Equal(t, 86400, conv.retention("team-1", "assistant-1").History)
+
+	now := time.Now()
+	assert.Equal(t, now.Add(time.Hour), conv.historyExpiredAt("team-2", "assistant-2", now))
+	assert.Equal(t, now.Add(24*time.Hour), conv.historyExpiredAt("team-1", "assistant-1", now))
+
+	// The memory policy is used when the TTL of the memories is not set
+	assert.Equal(t, 90*86400, conv.memoryTTL("team-2", "assistant-1", 0))
+	assert.Equal(t, 60, conv.memoryTTL("team-2", "assistant-1", 60))
+	assert.Equal(t, 0, conv.memoryTTL("team-1", "", 0))
+
+	conv.setting.TTL = 0
+	assert.Nil(t, conv.historyExpiredAt("", "", now))
+}
+
+func TestXunRetention(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+
+	store, err := NewXun(Setting{
+		Connector: "default",
+		Prefix:    "__unit_test_conversation_",
+		Retention: []RetentionPolicy{{Assistant: "assistant-short", History: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sid := fmt.Sprintf("retention_%d", time.Now().UnixNano())
+	err = store.SaveHistory(sid, []map[string]interface{}{
+		{"role": "user", "name": "user1", "content": "kept"},
+		{"role": "assistant", "name": "short", "content": "expired", "assistant_id": "assistant-short"},
+	}, "retention-chat", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	history, err := store.GetHistory(sid, "retention-chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, history, 1)
+	assert.Equal(t, "kept", history[0]["content"])
+}
@@ -1,13 +1,25 @@
 package store
 
+import "time"
+
 // Setting represents the conversation configuration structure
 // Used to configure basic conversation parameters including connector, user field, table name, etc.
 type Setting struct {
-	Connector string `json:"connector,omitempty"`                          // Name of the connector used to specify data storage method
-	UserField string `json:"user_field,omitempty"`                         // User ID field name, defaults to "user_id"
-	Prefix    string `json:"prefix,omitempty"`                             // Database table name prefix
-	MaxSize   int    `json:"max_size,omitempty" yaml:"max_size,omitempty"` // Maximum storage size limit
-	TTL       int    `json:"ttl,omitempty" yaml:"ttl,omitempty"`           // Time To Live in seconds
+	Connector string `json:"connector,omitempty"`                              // Name of the connector used to specify data storage method
+	UserField string `json:"user_field,omitempty"`                             // User ID field name, defaults to "user_id"
+	TeamField string `json:"team_field,omitempty"`                             // Team ID field name of the retention policies, defaults to "team_id"
+	Prefix    string `json:"prefix,omitempty"`                                 // Database table name prefix
+	MaxSize   int    `json:"max_size,omitempty" yaml:"max_size,omitempty"`     // Maximum storage size limit
+	TTL       int    `json:"ttl,omitempty" yaml:"ttl,omitempty"`               // Time To Live in seconds
+	BatchSize int    `json:"batch_size,omitempty" yaml:"batch_size,omitempty"` // Rows of each insert statement, defaults to 500
+
+	ReadConnector string `json:"read_connector,omitempty" yaml:"read_connector,omitempty"` // Connector of the read replica, the chats and the assistants are read from the replica if set
+	Sticky        int    `json:"sticky,omitempty" yaml:"sticky,omitempty"`                 // Seconds the reads are sent to the primary after the writes, defaults to 5
+
+	Encryption *EncryptionSetting `json:"encryption,omitempty" yaml:"encryption,omitempty"` // Field-level encryption of the assistant columns
+	Redaction  *RedactionSetting  `json:"redaction,omitempty" yaml:"redaction,omitempty"`   // PII redaction of the messages before they are saved
+	Retention  []RetentionPolicy  `json:"retention,omitempty" yaml:"retention,omitempty"`   // Retention of the data by team and assistant, overrides the TTL
+	Cleanup    *CleanupSetting    `json:"cleanup,omitempty" yaml:"cleanup,omitempty"`       // Schedule of the cleanup of the expired rows, every hour by default
 }
 
 // ChatInfo represents the chat information structure
@@ -24,11 +36,14 @@ type ChatFilter struct {
 	Page     int    `json:"page,omitempty"`     // Page number, starting from 1
 	PageSize int    `json:"pagesize,omitempty"` // Number of items per page
 	Order    string `json:"order,omitempty"`    // Sort order: desc/asc
+	TimeZone string `json:"timezone,omitempty"` // IANA timezone of the date groups, e.g. Asia/Shanghai, the timezone of the profile by default
+	Locale   string `json:"locale,omitempty"`   // Locale of the week start and the text direction, e.g. ar-EG, the language of the profile by default
 }
 
 // ChatGroup represents the chat group structure
 // Groups chats by date
 type ChatGroup struct {
+	Key   string                   `json:"key"`   // Group key: today, yesterday, this_week, last_week, earlier
 	Label string                   `json:"label"` // Group label (typically a date)
 	Chats []map[string]interface{} `json:"chats"` // List of chats in this group
 }
@@ -41,6 +56,9 @@ type ChatGroupResponse struct {
 	PageSize int         `json:"pagesize"`  // Items per page
 	Total    int64       `json:"total"`     // Total number of records
 	LastPage int         `json:"last_page"` // Last page number
+	TimeZone string      `json:"timezone"`  // Timezone of the date groups
+	Locale   string      `json:"locale"`    // Locale of the date groups
+	Dir      string      `json:"dir"`       // Text direction of the locale: ltr/rtl
 }
 
 // AssistantFilter represents the assistant filter structure
@@ -70,6 +88,33 @@ type AssistantResponse struct {
 	Total    int64                    `json:"total"`    // Total number of items
 }
 
+// MemoryFilter represents the memory filter structure
+// Used for filtering when retrieving the memories of a user
+type MemoryFilter struct {
+	AssistantID string `json:"assistant_id,omitempty"` // Filter by assistant ID
+	Keywords    string `json:"keywords,omitempty"`     // Search in content
+	Limit       int    `json:"limit,omitempty"`        // Maximum number of memories, defaults to 100
+}
+
+// WorkflowRunFilter represents the workflow run filter structure
+// Used for filtering when retrieving the workflow runs of a user
+type WorkflowRunFilter struct {
+	AssistantID string `json:"assistant_id,omitempty"` // Filter by assistant ID
+	Status      string `json:"status,omitempty"`       // Filter by status, running, waiting, completed, failed, rejected
+	Limit       int    `json:"limit,omitempty"`        // Maximum number of runs, defaults to 100
+}
+
+// ApprovalFilter represents the approval filter structure
+// Used for filtering when retrieving the tool approvals
+type ApprovalFilter struct {
+	ApprovalID  string   `json:"approval_id,omitempty"`  // Filter by approval ID
+	Roles       []string `json:"roles,omitempty"`        // The roles of the user, the approvals the roles could decide are included
+	AssistantID string   `json:"assistant_id,omitempty"` // Filter by assistant ID
+	ChatID      string   `json:"chat_id,omitempty"`      // Filter by chat ID
+	Status      string   `json:"status,omitempty"`       // Filter by status, pending, approved, rejected
+	Limit       int      `json:"limit,omitempty"`        // Maximum number of approvals, defaults to 100
+}
+
 // Store defines the conversation storage interface
 // Provides basic operations required for conversation management
 type Store interface {
@@ -122,6 +167,12 @@ type Store interface {
 	// Returns: Potential error
 	SaveAssistant(assistant map[string]interface{}) (interface{}, error)
 
+	// UpdateAssistant updates an existing assistant if it is not changed since the etag
+	// assistant: Assistant information, the assistant_id is required
+	// etag: The AssistantETag of the version the changes are based on
+	// Returns: The etag of the updated assistant and potential error, *ConflictError with the latest version if the etag is outdated
+	UpdateAssistant(assistant map[string]interface{}, etag string) (string, error)
+
 	// DeleteAssistant deletes an assistant
 	// assistantID: Assistant ID
 	// Returns: Potential error
@@ -145,4 +196,124 @@ type Store interface {
 	// GetAssistantTags retrieves all unique tags from assistants
 	// Returns: List of tags and potential error
 	GetAssistantTags() ([]string, error)
+
+	// SaveCollection saves the knowledge collection settings
+	// collection: Collection information, the name is required
+	// Returns: Collection name and potential error
+	SaveCollection(collection map[string]interface{}) (interface{}, error)
+
+	// GetCollection retrieves a single knowledge collection by name
+	// name: Collection name
+	// Returns: Collection information and potential error
+	GetCollection(name string) (map[string]interface{}, error)
+
+	// GetCollections retrieves all knowledge collections
+	// Returns: Collection list and potential error
+	GetCollections() ([]map[string]interface{}, error)
+
+	// DeleteCollection deletes a knowledge collection
+	// name: Collection name
+	// Returns: Potential error
+	DeleteCollection(name string) error
+
+	// SaveMemories saves the long-term memories of a user
+	// sid: Session ID
+	// assistantID: Assistant ID
+	// memories: Memory contents
+	// ttl: Time To Live in seconds, 0 means never expire
+	// Returns: Memory IDs and potential error
+	SaveMemories(sid string, assistantID string, memories []string, ttl int) ([]string, error)
+
+	// GetMemories retrieves the long-term memories of a user, the expired memories are excluded
+	// sid: Session ID
+	// filter: Filter conditions
+	// Returns: Memory list, the newest first, and potential error
+	GetMemories(sid string, filter MemoryFilter) ([]map[string]interface{}, error)
+
+	// DeleteMemory deletes a single memory
+	// sid: Session ID
+	// memoryID: Memory ID
+	// Returns: Potential error
+	DeleteMemory(sid string, memoryID string) error
+
+	// DeleteMemories deletes all the memories of a user
+	// sid: Session ID
+	// assistantID: Assistant ID, deletes the memories of all assistants if empty
+	// Returns: Number of deleted records and potential error
+	DeleteMemories(sid string, assistantID string) (int64, error)
+
+	// GetProfile retrieves the preference profile of a user
+	// sid: Session ID
+	// Returns: Profile information, empty if not set, and potential error
+	GetProfile(sid string) (map[string]interface{}, error)
+
+	// SaveProfile saves the preference profile of a user, the fields not given are kept
+	// sid: Session ID
+	// profile: Profile information, language, tone, timezone, instructions
+	// Returns: Potential error
+	SaveProfile(sid string, profile map[string]interface{}) error
+
+	// SaveWorkflowRun creates or updates a workflow run
+	// sid: Session ID
+	// run: Run information, run_id is required
+	// Returns: Potential error
+	SaveWorkflowRun(sid string, run map[string]interface{}) error
+
+	// GetWorkflowRun retrieves a single workflow run
+	// sid: Session ID
+	// runID: Run ID
+	// Returns: Run information and potential error
+	GetWorkflowRun(sid string, runID string) (map[string]interface{}, error)
+
+	// GetWorkflowRuns retrieves the workflow runs of a user
+	// sid: Session ID
+	// filter: Filter conditions
+	// Returns: Run list, the latest updated first, and potential error
+	GetWorkflowRuns(sid string, filter WorkflowRunFilter) ([]map[string]interface{}, error)
+
+	// SaveApproval creates a pending tool approval
+	// sid: Session ID of the requester
+	// approval: Approval information, assistant_id, chat_id, tool, args, context, message, approvers (the roles of the approvers)
+	// Returns: Approval ID and potential error
+	SaveApproval(sid string, approval map[string]interface{}) (string, error)
+
+	// GetApproval retrieves a single tool approval, the approval is not scoped to the user, used to decide the approval
+	// approvalID: Approval ID
+	// Returns: Approval information and potential error
+	GetApproval(approvalID string) (map[string]interface{}, error)
+
+	// GetApprovals retrieves the tool approvals requested by the user and the approvals the roles of the filter could decide
+	// sid: Session ID
+	// filter: Filter conditions
+	// Returns: Approval list, the newest first, and potential error
+	GetApprovals(sid string, filter ApprovalFilter) ([]map[string]interface{}, error)
+
+	// DecideApproval accepts or rejects a pending tool approval
+	// approvalID: Approval ID
+	// sid: Session ID of the approver
+	// approved: Whether the tool is approved
+	// comment: Comment of the approver
+	// Returns: Potential error, the approval is not pending, not found or requested by the approver
+	DecideApproval(approvalID string, sid string, approved bool, comment string) error
+
+	// ExportUserData retrieves all the data of a user
+	// sid: Session ID
+	// Returns: The profile, the chats with the histories, the memories, the workflow runs, the approvals and potential error
+	ExportUserData(sid string) (map[string]interface{}, error)
+
+	// ScheduleUserDeletion schedules the hard deletion of all the data of a user
+	// sid: Session ID
+	// grace: The grace period before the deletion, the data is deleted at once if it is 0
+	// Returns: The deletion time and potential error
+	ScheduleUserDeletion(sid string, grace time.Duration) (time.Time, error)
+
+	// CancelUserDeletion cancels the scheduled deletion of a user
+	// sid: Session ID
+	// Returns: Potential error
+	CancelUserDeletion(sid string) error
+
+	// GetUserDeletion retrieves the scheduled deletion of a user
+	// sid: Session ID
+	// Returns: The scheduled deletion, nil if it is not scheduled, and potential error
+	GetUserDeletion(sid string) (map[string]interface{}, error)
 }
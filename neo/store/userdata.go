@@ -0,0 +1,262 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+func (conv *Xun) getDeletionTable() string {
+	return conv.setting.Prefix + "deletion"
+}
+
+func (conv *Xun) initDeletionTable() error {
+	deletionTable := conv.getDeletionTable()
+	has, err := conv.schema.HasTable(deletionTable)
+	if err != nil {
+		return err
+	}
+
+	// Create the deletion table
+	if !has {
+		err = conv.schema.CreateTable(deletionTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("sid", 255).Unique().Index() // user id
+			table.TimestampTz("scheduled_at").Index() // the data is deleted after the time
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()").Index()
+		})
+
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the deletion table: %s", deletionTable)
+	}
+
+	// Validate the table
+	tab, err := conv.schema.GetTable(deletionTable)
+	if err != nil {
+		return err
+	}
+
+	fields := []string{"id", "sid", "scheduled_at", "created_at"}
+	for _, field := range fields {
+		if !tab.HasColumn(field) {
+			return fmt.Errorf("%s is required", field)
+		}
+	}
+
+	return nil
+}
+
+// ExportUserData retrieves all the data of a user, the profile, the chats with the histories, the memories, the workflow runs and the approvals
+func (conv *Xun) ExportUserData(sid string) (map[string]interface{}, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	export := map[string]interface{}{"sid": userID, "exported_at": time.Now()}
+
+	profile, err := conv.GetProfile(sid)
+	if err != nil {
+		return nil, err
+	}
+	export["profile"] = profile
+
+	// Chats with the histories
+	chats, err := conv.userRows(conv.getChatTable(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	histories, err := conv.userRows(conv.getHistoryTable(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byChat := map[string][]map[string]interface{}{}
+	for _, history := range histories {
+		// The original of the redacted content belongs to the user
+		if original, ok := history["original"].(string); ok && original != "" && conv.cipher != nil {
+			if plain, err := conv.cipher.decrypt(original); err == nil {
+				history["original"] = plain
+			}
+		}
+		cid := fmt.Sprintf("%v", history["cid"])
+		byChat[cid] = append(byChat[cid], history)
+	}
+
+	for _, chat := range chats {
+		cid := fmt.Sprintf("%v", chat["chat_id"])
+		chat["history"] = byChat[cid]
+		if chat["history"] == nil {
+			chat["history"] = []map[string]interface{}{}
+		}
+	}
+	export["chats"] = chats
+
+	for name, table := range map[string]string{
+		"memories":      conv.getMemoryTable(),
+		"workflow_runs": conv.getWorkflowRunTable(),
+		"approvals":     conv.getApprovalTable(),
+	} {
+		rows, err := conv.userRows(table, userID)
+		if err != nil {
+			return nil, err
+		}
+		export[name] = rows
+	}
+
+	for _, run := range export["workflow_runs"].([]map[string]interface{}) {
+		conv.parseJSONFields(run, []string{"state"})
+	}
+	for _, approval := range export["approvals"].([]map[string]interface{}) {
+		conv.parseJSONFields(approval, []string{"args", "context"})
+	}
+	for _, history := range histories {
+		conv.parseJSONFields(history, []string{"context", "mentions"})
+	}
+
+	return export, nil
+}
+
+// userRows all the rows of the user in the table
+func (conv *Xun) userRows(table string, userID string) ([]map[string]interface{}, error) {
+	rows, err := conv.query.New().
+		Table(table).
+		Where("sid", userID).
+		OrderBy("id", "asc").
+		Get()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		data := row.ToMap()
+		delete(data, "id")
+		res = append(res, data)
+	}
+	return res, nil
+}
+
+// ScheduleUserDeletion schedules the hard deletion of all the data of a user after the grace period, returns the deletion time
+func (conv *Xun) ScheduleUserDeletion(sid string, grace time.Duration) (time.Time, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	scheduledAt := time.Now().Add(grace)
+	err = conv.transaction(func(qb query.Query) error {
+		_, err := qb.New().
+			Table(conv.getDeletionTable()).
+			Where("sid", userID).
+			Delete()
+		if err != nil {
+			return err
+		}
+
+		return qb.New().
+			Table(conv.getDeletionTable()).
+			Insert(map[string]interface{}{
+				"sid":          userID,
+				"scheduled_at": scheduledAt,
+				"created_at":   time.Now(),
+			})
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Delete now if there is no grace period
+	if grace <= 0 {
+		return scheduledAt, conv.purgeUser(userID)
+	}
+	return scheduledAt, nil
+}
+
+// CancelUserDeletion cancels the scheduled deletion of a user
+func (conv *Xun) CancelUserDeletion(sid string) error {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return err
+	}
+
+	_, err = conv.query.New().
+		Table(conv.getDeletionTable()).
+		Where("sid", userID).
+		Delete()
+	return err
+}
+
+// GetUserDeletion retrieves the scheduled deletion of a user, nil if the deletion is not scheduled
+func (conv *Xun) GetUserDeletion(sid string) (map[string]interface{}, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := conv.query.New().
+		Table(conv.getDeletionTable()).
+		Select("sid", "scheduled_at", "created_at").
+		Where("sid", userID).
+		First()
+	if err != nil {
+		return nil, err
+	}
+
+	if row == nil || row.Get("sid") == nil {
+		return nil, nil
+	}
+	return row.ToMap(), nil
+}
+
+// purgeDeletions delete the data of the users whose grace period is over, returns the number of the purged users
+func (conv *Xun) purgeDeletions() (int64, error) {
+	rows, err := conv.query.New().
+		Table(conv.getDeletionTable()).
+		Select("sid").
+		Where("scheduled_at", "<=", time.Now()).
+		Limit(conv.batchSize()).
+		Get()
+	if err != nil {
+		return 0, err
+	}
+
+	var nums int64 = 0
+	for _, row := range rows {
+		userID := fmt.Sprintf("%v", row.Get("sid"))
+		if err := conv.purgeUser(userID); err != nil {
+			log.Error("Purge the data of the user %s error: %s", userID, err.Error())
+			continue
+		}
+		log.Trace("Purge the data of the user: %s %s", conv.setting.Prefix, userID)
+		nums++
+	}
+	return nums, nil
+}
+
+// purgeUser delete all the data of the user and the scheduled deletion in one transaction
+func (conv *Xun) purgeUser(userID string) error {
+	return conv.transaction(func(qb query.Query) error {
+		for _, table := range []string{
+			conv.getHistoryTable(),
+			conv.getChatTable(),
+			conv.getMemoryTable(),
+			conv.getProfileTable(),
+			conv.getWorkflowRunTable(),
+			conv.getApprovalTable(),
+			conv.getDeletionTable(),
+		} {
+			_, err := qb.New().Table(table).Where("sid", userID).Delete()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/yaoapp/xun/capsule"
 	"github.com/yaoapp/xun/dbal/query"
 	"github.com/yaoapp/xun/dbal/schema"
+	"github.com/yaoapp/yao/event"
 )
 
 // Package conversation provides functionality for managing chat conversations and assistants.
@@ -26,9 +27,13 @@ import (
 // - Managing AI assistants with their configurations and metadata
 // - Supporting data expiration through TTL settings
 type Xun struct {
-	query   query.Query
-	schema  schema.Schema
-	setting Setting
+	query    query.Query
+	schema   schema.Schema
+	setting  Setting
+	replica  *replica
+	cipher   *fieldCipher
+	redactor *redactor
+	cleanup  *cleanup
 }
 
 // Public interface methods:
@@ -74,6 +79,23 @@ func NewXun(setting Setting) (Store, error) {
 		return nil, err
 	}
 
+	err = conv.initReplica()
+	if err != nil {
+		return nil, err
+	}
+
+	err = conv.initEncryption()
+	if err != nil {
+		return nil, err
+	}
+
+	err = conv.initRedaction()
+	if err != nil {
+		return nil, err
+	}
+
+	// Clean the expired rows on a schedule
+	conv.initCleanup()
 	return conv, nil
 }
 
@@ -91,20 +113,19 @@ func (conv *Xun) newQueryChat() query.Query {
 	return qb
 }
 
-func (conv *Xun) clean() {
-	nums, err := conv.newQuery().Where("expired_at", "<=", time.Now()).Delete()
-	if err != nil {
-		log.Error("Clean the conversation table error: %s", err.Error())
-		return
-	}
-
-	if nums > 0 {
-		log.Trace("Clean the conversation table: %s %d", conv.setting.Prefix, nums)
-	}
+// transaction run the statements of the multi-table operation in a database transaction,
+// the statements must be built with the query of the callback, e.g. qb.New().Table(...)
+func (conv *Xun) transaction(callback func(qb query.Query) error) error {
+	return conv.query.New().Transaction(callback)
 }
 
 // Rename Init to initialize to avoid conflicts
 func (conv *Xun) initialize() error {
+	// Upgrade the tables created by the earlier versions, the new tables are created with the latest schema
+	if _, err := conv.Migrate(); err != nil {
+		return err
+	}
+
 	// Initialize history table
 	if err := conv.initHistoryTable(); err != nil {
 		return err
@@ -120,6 +141,41 @@ func (conv *Xun) initialize() error {
 		return err
 	}
 
+	// Initialize collection table
+	if err := conv.initCollectionTable(); err != nil {
+		return err
+	}
+
+	// Initialize memory table
+	if err := conv.initMemoryTable(); err != nil {
+		return err
+	}
+
+	// Initialize profile table
+	if err := conv.initProfileTable(); err != nil {
+		return err
+	}
+
+	// Initialize workflow run table
+	if err := conv.initWorkflowRunTable(); err != nil {
+		return err
+	}
+
+	// Initialize approval table
+	if err := conv.initApprovalTable(); err != nil {
+		return err
+	}
+
+	// Initialize deletion table
+	if err := conv.initDeletionTable(); err != nil {
+		return err
+	}
+
+	// Initialize lock table
+	if err := conv.initLockTable(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -145,6 +201,7 @@ func (conv *Xun) initHistoryTable() error {
 			table.String("assistant_name", 200).Null()
 			table.String("assistant_avatar", 200).Null()
 			table.JSON("mentions").Null()
+			table.Text("original").Null() // the encrypted original of the redacted content
 			table.TimestampTz("created_at").SetDefaultRaw("NOW()").Index()
 			table.TimestampTz("updated_at").Null().Index()
 			table.TimestampTz("expired_at").Null().Index()
@@ -162,7 +219,7 @@ func (conv *Xun) initHistoryTable() error {
 		return err
 	}
 
-	fields := []string{"id", "sid", "cid", "uid", "role", "name", "content", "context", "assistant_id", "assistant_name", "assistant_avatar", "mentions", "created_at", "updated_at", "expired_at"}
+	fields := []string{"id", "sid", "cid", "uid", "role", "name", "content", "context", "assistant_id", "assistant_name", "assistant_avatar", "mentions", "original", "created_at", "updated_at", "expired_at"}
 	for _, field := range fields {
 		if !tab.HasColumn(field) {
 			return fmt.Errorf("%s is required", field)
@@ -235,6 +292,8 @@ func (conv *Xun) initAssistantTable() error {
 			table.JSON("options").Null()                              // assistant options
 			table.JSON("prompts").Null()                              // assistant prompts
 			table.JSON("flows").Null()                                // assistant flows
+			table.JSON("routes").Null()                               // assistant handoff routes
+			table.JSON("tool_policies").Null()                        // assistant tool execution policies
 			table.JSON("files").Null()                                // assistant files
 			table.JSON("functions").Null()                            // assistant functions
 			table.JSON("tags").Null()                                 // assistant tags
@@ -268,6 +327,224 @@ func (conv *Xun) initAssistantTable() error {
 	return nil
 }
 
+func (conv *Xun) initCollectionTable() error {
+	collectionTable := conv.getCollectionTable()
+	has, err := conv.schema.HasTable(collectionTable)
+	if err != nil {
+		return err
+	}
+
+	// Create the collection table
+	if !has {
+		err = conv.schema.CreateTable(collectionTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("name", 200).Unique().Index() // collection name
+			table.Text("description").Null()           // collection description
+			table.JSON("options").Null()               // collection options, chunk and metadata rules
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()").Index()
+			table.TimestampTz("updated_at").Null().Index()
+		})
+
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the collection table: %s", collectionTable)
+	}
+
+	// Validate the table
+	tab, err := conv.schema.GetTable(collectionTable)
+	if err != nil {
+		return err
+	}
+
+	fields := []string{"id", "name", "description", "options", "created_at", "updated_at"}
+	for _, field := range fields {
+		if !tab.HasColumn(field) {
+			return fmt.Errorf("%s is required", field)
+		}
+	}
+
+	return nil
+}
+
+func (conv *Xun) initMemoryTable() error {
+	memoryTable := conv.getMemoryTable()
+	has, err := conv.schema.HasTable(memoryTable)
+	if err != nil {
+		return err
+	}
+
+	// Create the memory table
+	if !has {
+		err = conv.schema.CreateTable(memoryTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("memory_id", 200).Unique().Index()
+			table.String("sid", 255).Index()                 // user id
+			table.String("assistant_id", 200).Null().Index() // assistant id
+			table.Text("content")                            // the memory content
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()").Index()
+			table.TimestampTz("updated_at").Null().Index()
+			table.TimestampTz("expired_at").Null().Index()
+		})
+
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the memory table: %s", memoryTable)
+	}
+
+	// Validate the table
+	tab, err := conv.schema.GetTable(memoryTable)
+	if err != nil {
+		return err
+	}
+
+	fields := []string{"id", "memory_id", "sid", "assistant_id", "content", "created_at", "updated_at", "expired_at"}
+	for _, field := range fields {
+		if !tab.HasColumn(field) {
+			return fmt.Errorf("%s is required", field)
+		}
+	}
+
+	return nil
+}
+
+func (conv *Xun) initProfileTable() error {
+	profileTable := conv.getProfileTable()
+	has, err := conv.schema.HasTable(profileTable)
+	if err != nil {
+		return err
+	}
+
+	// Create the profile table
+	if !has {
+		err = conv.schema.CreateTable(profileTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("sid", 255).Unique().Index() // user id
+			table.String("language", 50).Null()       // preferred reply language, e.g. zh-CN
+			table.String("tone", 200).Null()          // preferred tone, e.g. formal, friendly
+			table.String("timezone", 100).Null()      // IANA timezone, e.g. Asia/Shanghai
+			table.Text("instructions").Null()         // custom instructions
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()").Index()
+			table.TimestampTz("updated_at").Null().Index()
+		})
+
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the profile table: %s", profileTable)
+	}
+
+	// Validate the table
+	tab, err := conv.schema.GetTable(profileTable)
+	if err != nil {
+		return err
+	}
+
+	fields := []string{"id", "sid", "language", "tone", "timezone", "instructions", "created_at", "updated_at"}
+	for _, field := range fields {
+		if !tab.HasColumn(field) {
+			return fmt.Errorf("%s is required", field)
+		}
+	}
+
+	return nil
+}
+
+func (conv *Xun) initWorkflowRunTable() error {
+	runTable := conv.getWorkflowRunTable()
+	has, err := conv.schema.HasTable(runTable)
+	if err != nil {
+		return err
+	}
+
+	// Create the workflow run table
+	if !has {
+		err = conv.schema.CreateTable(runTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("run_id", 200).Unique().Index()
+			table.String("sid", 255).Index()          // user id
+			table.String("assistant_id", 200).Index() // assistant id
+			table.String("workflow", 200).Index()     // workflow name
+			table.String("status", 50).Index()        // running, waiting, completed, failed, rejected
+			table.String("step", 200).Null()          // the current step id
+			table.JSON("state").Null()                // the input and the outputs of the steps
+			table.Text("error").Null()                // the error of the failed step
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()").Index()
+			table.TimestampTz("updated_at").Null().Index()
+		})
+
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the workflow run table: %s", runTable)
+	}
+
+	// Validate the table
+	tab, err := conv.schema.GetTable(runTable)
+	if err != nil {
+		return err
+	}
+
+	fields := []string{"id", "run_id", "sid", "assistant_id", "workflow", "status", "step", "state", "error", "created_at", "updated_at"}
+	for _, field := range fields {
+		if !tab.HasColumn(field) {
+			return fmt.Errorf("%s is required", field)
+		}
+	}
+
+	return nil
+}
+
+func (conv *Xun) initApprovalTable() error {
+	approvalTable := conv.getApprovalTable()
+	has, err := conv.schema.HasTable(approvalTable)
+	if err != nil {
+		return err
+	}
+
+	// Create the approval table
+	if !has {
+		err = conv.schema.CreateTable(approvalTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("approval_id", 200).Unique().Index()
+			table.String("sid", 255).Index()                         // the requester user id
+			table.String("assistant_id", 200).Null().Index()         // assistant id
+			table.String("chat_id", 200).Null().Index()              // the chat of the tool call
+			table.String("tool", 200).Index()                        // the process name
+			table.JSON("args").Null()                                // the process arguments
+			table.JSON("context").Null()                             // the chat context, used to run the tool
+			table.Text("message").Null()                             // the message shown to the approver
+			table.String("status", 50).SetDefault("pending").Index() // pending, approved, rejected
+			table.String("approvers", 1000).Null()                   // the roles of the approvers, e.g. ,admin,ops,
+			table.String("approver", 255).Null()                     // the approver user id
+			table.Text("comment").Null()                             // the comment of the approver
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()").Index()
+			table.TimestampTz("updated_at").Null().Index()
+		})
+
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the approval table: %s", approvalTable)
+	}
+
+	// Validate the table
+	tab, err := conv.schema.GetTable(approvalTable)
+	if err != nil {
+		return err
+	}
+
+	fields := []string{"id", "approval_id", "sid", "assistant_id", "chat_id", "tool", "args", "context", "message", "status", "approvers", "approver", "comment", "created_at", "updated_at"}
+	for _, field := range fields {
+		if !tab.HasColumn(field) {
+			return fmt.Errorf("%s is required", field)
+		}
+	}
+
+	return nil
+}
+
 func (conv *Xun) getUserID(sid string) (string, error) {
 	field := "user_id"
 	if conv.setting.UserField != "" {
@@ -298,12 +575,33 @@ func (conv *Xun) getAssistantTable() string {
 	return conv.setting.Prefix + "assistant"
 }
 
+func (conv *Xun) getCollectionTable() string {
+	return conv.setting.Prefix + "collection"
+}
+
+func (conv *Xun) getMemoryTable() string {
+	return conv.setting.Prefix + "memory"
+}
+
+func (conv *Xun) getProfileTable() string {
+	return conv.setting.Prefix + "profile"
+}
+
+func (conv *Xun) getWorkflowRunTable() string {
+	return conv.setting.Prefix + "workflow_run"
+}
+
+func (conv *Xun) getApprovalTable() string {
+	return conv.setting.Prefix + "approval"
+}
+
 // UpdateChatTitle update the chat title
 func (conv *Xun) UpdateChatTitle(sid string, cid string, title string) error {
 	userID, err := conv.getUserID(sid)
 	if err != nil {
 		return err
 	}
+	defer conv.written(userID)
 
 	_, err = conv.newQueryChat().
 		Where("sid", userID).
@@ -334,7 +632,8 @@ func (conv *Xun) GetChats(sid string, filter ChatFilter) (*ChatGroupResponse, er
 	}
 
 	// Build base query
-	qb := conv.newQueryChat().
+	qb := conv.readQuery(userID).
+		Table(conv.getChatTable()).
 		Select("chat_id", "title", "created_at").
 		Where("sid", userID).
 		Where("chat_id", "!=", "")
@@ -366,20 +665,11 @@ func (conv *Xun) GetChats(sid string, filter ChatFilter) (*ChatGroupResponse, er
 		return nil, err
 	}
 
-	// Group chats by date
-	today := time.Now().Truncate(24 * time.Hour)
-	yesterday := today.AddDate(0, 0, -1)
-	thisWeekStart := today.AddDate(0, 0, -int(today.Weekday()))
-	lastWeekStart := thisWeekStart.AddDate(0, 0, -7)
-	lastWeekEnd := thisWeekStart.AddDate(0, 0, -1)
-
-	groups := map[string][]map[string]interface{}{
-		"Today":        {},
-		"Yesterday":    {},
-		"This Week":    {},
-		"Last Week":    {},
-		"Even Earlier": {},
-	}
+	// Group chats by date, the days and the weeks are in the timezone and the locale of the user
+	loc, locale := conv.chatLocale(sid, filter)
+	start := weekStart(locale)
+	now := time.Now()
+	groups := map[string][]map[string]interface{}{}
 
 	for _, row := range rows {
 		chatID := row.Get("chat_id")
@@ -410,29 +700,18 @@ func (conv *Xun) GetChats(sid string, filter ChatFilter) (*ChatGroupResponse, er
 			continue
 		}
 
-		createdDate := createdAt.Truncate(24 * time.Hour)
-
-		switch {
-		case createdDate.Equal(today):
-			groups["Today"] = append(groups["Today"], chat)
-		case createdDate.Equal(yesterday):
-			groups["Yesterday"] = append(groups["Yesterday"], chat)
-		case createdDate.After(thisWeekStart) && createdDate.Before(today):
-			groups["This Week"] = append(groups["This Week"], chat)
-		case createdDate.After(lastWeekStart) && createdDate.Before(lastWeekEnd.AddDate(0, 0, 1)):
-			groups["Last Week"] = append(groups["Last Week"], chat)
-		default:
-			groups["Even Earlier"] = append(groups["Even Earlier"], chat)
-		}
+		key := chatGroupOf(createdAt, now, loc, start)
+		groups[key] = append(groups[key], chat)
 	}
 
 	// Convert to ordered slice
 	result := []ChatGroup{}
-	for _, label := range []string{"Today", "Yesterday", "This Week", "Last Week", "Even Earlier"} {
-		if len(groups[label]) > 0 {
+	for _, group := range chatGroups {
+		if len(groups[group.Key]) > 0 {
 			result = append(result, ChatGroup{
-				Label: label,
-				Chats: groups[label],
+				Key:   group.Key,
+				Label: group.Label,
+				Chats: groups[group.Key],
 			})
 		}
 	}
@@ -443,9 +722,40 @@ func (conv *Xun) GetChats(sid string, filter ChatFilter) (*ChatGroupResponse, er
 		PageSize: filter.PageSize,
 		Total:    total,
 		LastPage: lastPage,
+		TimeZone: loc.String(),
+		Locale:   locale,
+		Dir:      textDirection(locale),
 	}, nil
 }
 
+// chatLocale the timezone and the locale of the chat groups, the filter overrides the profile of the user
+func (conv *Xun) chatLocale(sid string, filter ChatFilter) (*time.Location, string) {
+	timezone := filter.TimeZone
+	locale := filter.Locale
+	if timezone == "" || locale == "" {
+		profile, err := conv.GetProfile(sid)
+		if err != nil {
+			log.Warn("[Neo] get the profile of the chat groups: %s", err.Error())
+		}
+		if v, ok := profile["timezone"].(string); ok && timezone == "" {
+			timezone = v
+		}
+		if v, ok := profile["language"].(string); ok && locale == "" {
+			locale = v
+		}
+	}
+
+	loc := time.Local
+	if timezone != "" {
+		if l, err := time.LoadLocation(timezone); err == nil {
+			loc = l
+		} else {
+			log.Warn("[Neo] invalid timezone %s of the chat groups", timezone)
+		}
+	}
+	return loc, locale
+}
+
 // GetHistory get the history
 func (conv *Xun) GetHistory(sid string, cid string) ([]map[string]interface{}, error) {
 	userID, err := conv.getUserID(sid)
@@ -453,14 +763,17 @@ func (conv *Xun) GetHistory(sid string, cid string) ([]map[string]interface{}, e
 		return nil, err
 	}
 
-	qb := conv.newQuery().
+	qb := conv.readQuery(userID).
+		Table(conv.getHistoryTable()).
 		Select("role", "name", "content", "context", "assistant_id", "assistant_name", "assistant_avatar", "mentions", "uid", "created_at", "updated_at").
 		Where("sid", userID).
 		Where("cid", cid).
 		OrderBy("id", "desc")
 
-	if conv.setting.TTL > 0 {
-		qb.Where("expired_at", ">", time.Now())
+	if conv.setting.TTL > 0 || len(conv.setting.Retention) > 0 {
+		qb.Where(func(qb query.Query) {
+			qb.WhereNull("expired_at").OrWhere("expired_at", ">", time.Now())
+		})
 	}
 
 	limit := 20
@@ -505,6 +818,7 @@ func (conv *Xun) SaveHistory(sid string, messages []map[string]interface{}, cid
 	if err != nil {
 		return err
 	}
+	defer conv.written(userID)
 
 	// First ensure chat record exists
 	exists, err := conv.newQueryChat().
@@ -516,28 +830,23 @@ func (conv *Xun) SaveHistory(sid string, messages []map[string]interface{}, cid
 		return err
 	}
 
-	if !exists {
-		// Create new chat record
-		err = conv.newQueryChat().
-			Insert(map[string]interface{}{
-				"chat_id":    cid,
-				"sid":        userID,
-				"created_at": time.Now(),
-			})
+	// The retention of the messages depends on the team and the assistant
+	teamID, err := conv.getTeamID(sid)
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			return err
+	contextAssistantID := ""
+	if context != nil {
+		if id, ok := context["assistant_id"].(string); ok {
+			contextAssistantID = id
 		}
 	}
 
 	// Save message history
-	defer conv.clean()
-	var expiredAt interface{} = nil
 	values := []map[string]interface{}{}
-	if conv.setting.TTL > 0 {
-		expiredAt = time.Now().Add(time.Duration(conv.setting.TTL) * time.Second)
-	}
-
+	assistants := map[string]int{}
+	prompts := 0
 	now := time.Now()
 	for _, message := range messages {
 		// Type assertion safety checks
@@ -551,6 +860,11 @@ func (conv *Xun) SaveHistory(sid string, messages []map[string]interface{}, cid
 			return fmt.Errorf("invalid content type in message: %v", message["content"])
 		}
 
+		content, original, err := conv.redactContent(content)
+		if err != nil {
+			return err
+		}
+
 		var contextRaw interface{} = nil
 		if context != nil {
 			contextRaw, err = jsoniter.MarshalToString(context)
@@ -577,12 +891,13 @@ func (conv *Xun) SaveHistory(sid string, messages []map[string]interface{}, cid
 			"uid":              userID,
 			"context":          contextRaw,
 			"mentions":         mentionsRaw,
+			"original":         original,
 			"assistant_id":     nil,
 			"assistant_name":   nil,
 			"assistant_avatar": nil,
 			"created_at":       now,
 			"updated_at":       nil,
-			"expired_at":       expiredAt,
+			"expired_at":       nil,
 		}
 
 		if name, ok := message["name"].(string); ok {
@@ -590,8 +905,15 @@ func (conv *Xun) SaveHistory(sid string, messages []map[string]interface{}, cid
 		}
 
 		// Add assistant fields if present
-		if assistantID, ok := message["assistant_id"].(string); ok {
-			value["assistant_id"] = assistantID
+		assistantID := contextAssistantID
+		if id, ok := message["assistant_id"].(string); ok {
+			value["assistant_id"] = id
+			assistantID = id
+		}
+		value["expired_at"] = conv.historyExpiredAt(teamID, assistantID, now)
+		assistants[assistantID]++
+		if role == "user" {
+			prompts++
 		}
 		if assistantName, ok := message["assistant_name"].(string); ok {
 			value["assistant_name"] = assistantName
@@ -603,11 +925,39 @@ func (conv *Xun) SaveHistory(sid string, messages []map[string]interface{}, cid
 		values = append(values, value)
 	}
 
-	err = conv.newQuery().Insert(values)
+	// The chat and the history are saved together
+	err = conv.transaction(func(qb query.Query) error {
+		if !exists {
+			// Create new chat record
+			err := qb.New().
+				Table(conv.getChatTable()).
+				Insert(map[string]interface{}{
+					"chat_id":    cid,
+					"sid":        userID,
+					"created_at": time.Now(),
+				})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return conv.insertBatch(qb, conv.getHistoryTable(), values)
+	})
 	if err != nil {
 		return err
 	}
 
+	// The team of the events is resolved even if the retention policies are not set, e.g. for the analytics
+	team := teamID
+	if team == "" {
+		team, _ = conv.teamOf(sid)
+	}
+
+	if !exists {
+		event.Publish(event.ChatCreated, map[string]interface{}{"chat_id": cid, "sid": userID, "team": team, "assistant_id": contextAssistantID})
+	}
+	event.Publish(event.MessageSaved, map[string]interface{}{"chat_id": cid, "sid": userID, "team": team, "assistants": assistants, "messages": len(values), "prompts": prompts})
 	return nil
 }
 
@@ -619,7 +969,8 @@ func (conv *Xun) GetChat(sid string, cid string) (*ChatInfo, error) {
 	}
 
 	// Get chat info
-	qb := conv.newQueryChat().
+	qb := conv.readQuery(userID).
+		Table(conv.getChatTable()).
 		Select("chat_id", "title").
 		Where("sid", userID).
 		Where("chat_id", cid)
@@ -657,23 +1008,27 @@ func (conv *Xun) DeleteChat(sid string, cid string) error {
 	if err != nil {
 		return err
 	}
+	defer conv.written(userID)
 
-	// Delete history records first
-	_, err = conv.newQuery().
-		Where("sid", userID).
-		Where("cid", cid).
-		Delete()
-	if err != nil {
-		return err
-	}
+	// Delete the history and the chat together, the history is not orphaned if the chat is not deleted
+	return conv.transaction(func(qb query.Query) error {
+		_, err := qb.New().
+			Table(conv.getHistoryTable()).
+			Where("sid", userID).
+			Where("cid", cid).
+			Delete()
+		if err != nil {
+			return err
+		}
 
-	// Then delete the chat
-	_, err = conv.newQueryChat().
-		Where("sid", userID).
-		Where("chat_id", cid).
-		Limit(1).
-		Delete()
-	return err
+		_, err = qb.New().
+			Table(conv.getChatTable()).
+			Where("sid", userID).
+			Where("chat_id", cid).
+			Limit(1).
+			Delete()
+		return err
+	})
 }
 
 // DeleteAllChats deletes all chats and their histories for a user
@@ -682,20 +1037,24 @@ func (conv *Xun) DeleteAllChats(sid string) error {
 	if err != nil {
 		return err
 	}
+	defer conv.written(userID)
 
-	// Delete history records first
-	_, err = conv.newQuery().
-		Where("sid", userID).
-		Delete()
-	if err != nil {
-		return err
-	}
+	// Delete the histories and the chats together
+	return conv.transaction(func(qb query.Query) error {
+		_, err := qb.New().
+			Table(conv.getHistoryTable()).
+			Where("sid", userID).
+			Delete()
+		if err != nil {
+			return err
+		}
 
-	// Then delete all chats
-	_, err = conv.newQueryChat().
-		Where("sid", userID).
-		Delete()
-	return err
+		_, err = qb.New().
+			Table(conv.getChatTable()).
+			Where("sid", userID).
+			Delete()
+		return err
+	})
 }
 
 // processJSONField processes a field that should be stored as JSON string
@@ -723,7 +1082,7 @@ func (conv *Xun) parseJSONFields(data map[string]interface{}, fields []string) {
 			if strVal, ok := val.(string); ok && strVal != "" {
 				var parsed interface{}
 				if err := jsoniter.UnmarshalFromString(strVal, &parsed); err == nil {
-					data[field] = parsed
+					data[field] = conv.decryptValue(field, parsed)
 				}
 			}
 		}
@@ -732,6 +1091,7 @@ func (conv *Xun) parseJSONFields(data map[string]interface{}, fields []string) {
 
 // SaveAssistant saves assistant information
 func (conv *Xun) SaveAssistant(assistant map[string]interface{}) (interface{}, error) {
+	defer conv.written(assistantWrites)
 	// Validate required fields
 	requiredFields := []string{"name", "type", "connector"}
 	for _, field := range requiredFields {
@@ -750,7 +1110,7 @@ func (conv *Xun) SaveAssistant(assistant map[string]interface{}) (interface{}, e
 	}
 
 	// Process JSON fields
-	jsonFields := []string{"tags", "options", "prompts", "flows", "files", "functions", "permissions"}
+	jsonFields := []string{"tags", "options", "prompts", "flows", "routes", "tool_policies", "files", "functions", "permissions"}
 	for _, field := range jsonFields {
 		if val, ok := assistantCopy[field]; ok && val != nil {
 			// If it's a string, try to parse it first
@@ -768,15 +1128,6 @@ func (conv *Xun) SaveAssistant(assistant map[string]interface{}) (interface{}, e
 		assistantCopy["assistant_id"] = uuid.New().String()
 	}
 
-	// Check if assistant exists
-	exists, err := conv.query.New().
-		Table(conv.getAssistantTable()).
-		Where("assistant_id", assistantCopy["assistant_id"]).
-		Exists()
-	if err != nil {
-		return nil, err
-	}
-
 	// Convert JSON fields to strings for storage
 	for _, field := range jsonFields {
 		if val, ok := assistantCopy[field]; ok && val != nil {
@@ -788,29 +1139,143 @@ func (conv *Xun) SaveAssistant(assistant map[string]interface{}) (interface{}, e
 		}
 	}
 
-	// Update or insert
-	if exists {
-		_, err := conv.query.New().
+	err := conv.encryptColumns(assistantCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check and update or insert in one transaction, the concurrent saves of the same assistant do not insert twice
+	created := false
+	err = conv.transaction(func(qb query.Query) error {
+		exists, err := qb.New().
 			Table(conv.getAssistantTable()).
 			Where("assistant_id", assistantCopy["assistant_id"]).
-			Update(assistantCopy)
+			Exists()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		return assistantCopy["assistant_id"], nil
-	}
+		created = !exists
 
-	err = conv.query.New().
-		Table(conv.getAssistantTable()).
-		Insert(assistantCopy)
+		if exists {
+			// The etag of the assistant changes with the updated_at
+			if _, has := assistantCopy["updated_at"]; !has {
+				assistantCopy["updated_at"] = time.Now()
+			}
+
+			_, err := qb.New().
+				Table(conv.getAssistantTable()).
+				Where("assistant_id", assistantCopy["assistant_id"]).
+				Update(assistantCopy)
+			return err
+		}
+
+		return qb.New().
+			Table(conv.getAssistantTable()).
+			Insert(assistantCopy)
+	})
 	if err != nil {
 		return nil, err
 	}
+
+	event.Publish(event.AssistantUpdated, map[string]interface{}{"assistant_id": assistantCopy["assistant_id"], "name": assistant["name"], "created": created})
 	return assistantCopy["assistant_id"], nil
 }
 
+// UpdateAssistant updates an existing assistant if it is not changed since the etag, the fields not given are kept.
+// The row is updated only if the updated_at is still the one of the etag, so the concurrent updates do not overwrite each other.
+func (conv *Xun) UpdateAssistant(assistant map[string]interface{}, etag string) (string, error) {
+	assistantID := fmt.Sprintf("%v", assistant["assistant_id"])
+	if assistant["assistant_id"] == nil || assistantID == "" {
+		return "", fmt.Errorf("field assistant_id is required")
+	}
+
+	data := map[string]interface{}{}
+	jsonFields := map[string]bool{"tags": true, "options": true, "prompts": true, "flows": true, "routes": true, "tool_policies": true, "files": true, "functions": true, "permissions": true}
+	for field, value := range assistant {
+		switch field {
+		case "id", "assistant_id", "created_at", "updated_at":
+			continue
+		}
+
+		if jsonFields[field] && value != nil {
+			if _, ok := value.(string); !ok {
+				raw, err := jsoniter.MarshalToString(value)
+				if err != nil {
+					return "", fmt.Errorf("failed to marshal %s to JSON: %v", field, err)
+				}
+				value = raw
+			}
+		}
+		data[field] = value
+	}
+
+	err := conv.encryptColumns(data)
+	if err != nil {
+		return "", err
+	}
+
+	err = conv.transaction(func(qb query.Query) error {
+		row, err := qb.New().
+			Table(conv.getAssistantTable()).
+			Select("updated_at").
+			Where("assistant_id", assistantID).
+			First()
+		if err != nil {
+			return err
+		}
+
+		if row == nil || len(row.ToMap()) == 0 {
+			return fmt.Errorf("assistant %s not found", assistantID)
+		}
+
+		current := row.ToMap()
+		if AssistantETag(current) != etag {
+			return &ConflictError{ID: assistantID}
+		}
+
+		// Compare and swap the updated_at, the concurrent update between the read and the write is detected too
+		data["updated_at"] = time.Now()
+		update := qb.New().
+			Table(conv.getAssistantTable()).
+			Where("assistant_id", assistantID)
+		if current["updated_at"] == nil {
+			update.WhereNull("updated_at")
+		} else {
+			update.Where("updated_at", current["updated_at"])
+		}
+
+		nums, err := update.Update(data)
+		if err != nil {
+			return err
+		}
+
+		if nums == 0 {
+			return &ConflictError{ID: assistantID}
+		}
+		return nil
+	})
+	conv.written(assistantWrites)
+
+	// Attach the latest version to the conflict, the client merges the changes with it
+	if conflict, ok := err.(*ConflictError); ok {
+		conflict.Latest, _ = conv.GetAssistant(assistantID)
+		return "", conflict
+	}
+	if err != nil {
+		return "", err
+	}
+	event.Publish(event.AssistantUpdated, map[string]interface{}{"assistant_id": assistantID, "name": assistant["name"], "created": false})
+
+	latest, err := conv.GetAssistant(assistantID)
+	if err != nil {
+		return "", err
+	}
+	return AssistantETag(latest), nil
+}
+
 // DeleteAssistant deletes an assistant by assistant_id
 func (conv *Xun) DeleteAssistant(assistantID string) error {
+	defer conv.written(assistantWrites)
 	// Check if assistant exists
 	exists, err := conv.query.New().
 		Table(conv.getAssistantTable()).
@@ -833,7 +1298,7 @@ func (conv *Xun) DeleteAssistant(assistantID string) error {
 
 // GetAssistants retrieves assistants with pagination and filtering
 func (conv *Xun) GetAssistants(filter AssistantFilter) (*AssistantResponse, error) {
-	qb := conv.query.New().
+	qb := conv.readQuery(assistantWrites).
 		Table(conv.getAssistantTable())
 
 	// Apply tag filter if provided
@@ -932,7 +1397,7 @@ func (conv *Xun) GetAssistants(filter AssistantFilter) (*AssistantResponse, erro
 
 	// Convert rows to map slice and parse JSON fields
 	data := make([]map[string]interface{}, len(rows))
-	jsonFields := []string{"tags", "options", "prompts", "flows", "files", "functions", "permissions"}
+	jsonFields := []string{"tags", "options", "prompts", "flows", "routes", "tool_policies", "files", "functions", "permissions"}
 	for i, row := range rows {
 		data[i] = row
 		// Only parse JSON fields if they are selected or no select filter is provided
@@ -968,7 +1433,7 @@ func (conv *Xun) GetAssistants(filter AssistantFilter) (*AssistantResponse, erro
 
 // GetAssistant retrieves a single assistant by ID
 func (conv *Xun) GetAssistant(assistantID string) (map[string]interface{}, error) {
-	row, err := conv.query.New().
+	row, err := conv.readQuery(assistantWrites).
 		Table(conv.getAssistantTable()).
 		Where("assistant_id", assistantID).
 		First()
@@ -986,7 +1451,7 @@ func (conv *Xun) GetAssistant(assistantID string) (map[string]interface{}, error
 	}
 
 	// Parse JSON fields
-	jsonFields := []string{"tags", "options", "prompts", "flows", "files", "functions", "permissions"}
+	jsonFields := []string{"tags", "options", "prompts", "flows", "routes", "tool_policies", "files", "functions", "permissions"}
 	conv.parseJSONFields(data, jsonFields)
 
 	return data, nil
@@ -994,6 +1459,7 @@ func (conv *Xun) GetAssistant(assistantID string) (map[string]interface{}, error
 
 // DeleteAssistants deletes assistants based on filter conditions
 func (conv *Xun) DeleteAssistants(filter AssistantFilter) (int64, error) {
+	defer conv.written(assistantWrites)
 	qb := conv.query.New().
 		Table(conv.getAssistantTable())
 
@@ -1050,7 +1516,7 @@ func (conv *Xun) DeleteAssistants(filter AssistantFilter) (int64, error) {
 
 // GetAssistantTags retrieves all unique tags from assistants
 func (conv *Xun) GetAssistantTags() ([]string, error) {
-	q := conv.newQuery().Table(conv.getAssistantTable())
+	q := conv.readQuery(assistantWrites).Table(conv.getAssistantTable())
 	rows, err := q.Select("tags").GroupBy("tags").Get()
 	if err != nil {
 		return nil, err
@@ -1075,3 +1541,652 @@ func (conv *Xun) GetAssistantTags() ([]string, error) {
 	}
 	return tags, nil
 }
+
+// SaveCollection saves the knowledge collection settings, the collection is identified by name
+func (conv *Xun) SaveCollection(collection map[string]interface{}) (interface{}, error) {
+	name, ok := collection["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("field name is required")
+	}
+
+	data := map[string]interface{}{"name": name}
+	if description, ok := collection["description"]; ok {
+		data["description"] = description
+	}
+
+	if options, ok := collection["options"]; ok {
+		value, err := conv.processJSONField(options)
+		if err != nil {
+			return nil, err
+		}
+		data["options"] = value
+	}
+
+	exists, err := conv.query.New().
+		Table(conv.getCollectionTable()).
+		Where("name", name).
+		Exists()
+	if err != nil {
+		return nil, err
+	}
+
+	// Update or insert
+	if exists {
+		data["updated_at"] = time.Now()
+		_, err := conv.query.New().
+			Table(conv.getCollectionTable()).
+			Where("name", name).
+			Update(data)
+		if err != nil {
+			return nil, err
+		}
+		return name, nil
+	}
+
+	err = conv.query.New().
+		Table(conv.getCollectionTable()).
+		Insert(data)
+	if err != nil {
+		return nil, err
+	}
+	return name, nil
+}
+
+// GetCollection retrieves a single knowledge collection by name
+func (conv *Xun) GetCollection(name string) (map[string]interface{}, error) {
+	row, err := conv.query.New().
+		Table(conv.getCollectionTable()).
+		Where("name", name).
+		First()
+	if err != nil {
+		return nil, err
+	}
+
+	if row == nil {
+		return nil, fmt.Errorf("collection %s not found", name)
+	}
+
+	data := row.ToMap()
+	if len(data) == 0 {
+		return nil, fmt.Errorf("collection %s not found", name)
+	}
+
+	conv.parseJSONFields(data, []string{"options"})
+	return data, nil
+}
+
+// GetCollections retrieves all knowledge collections ordered by name
+func (conv *Xun) GetCollections() ([]map[string]interface{}, error) {
+	rows, err := conv.query.New().
+		Table(conv.getCollectionTable()).
+		OrderBy("name", "asc").
+		Get()
+	if err != nil {
+		return nil, err
+	}
+
+	collections := []map[string]interface{}{}
+	for _, row := range rows {
+		data := row.ToMap()
+		conv.parseJSONFields(data, []string{"options"})
+		collections = append(collections, data)
+	}
+	return collections, nil
+}
+
+// DeleteCollection deletes a knowledge collection by name
+func (conv *Xun) DeleteCollection(name string) error {
+	exists, err := conv.query.New().
+		Table(conv.getCollectionTable()).
+		Where("name", name).
+		Exists()
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("collection %s not found", name)
+	}
+
+	_, err = conv.query.New().
+		Table(conv.getCollectionTable()).
+		Where("name", name).
+		Delete()
+	return err
+}
+
+// SaveMemories saves the long-term memories of a user, the duplicated memories refresh the expiration only
+func (conv *Xun) SaveMemories(sid string, assistantID string, memories []string, ttl int) ([]string, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	teamID, err := conv.getTeamID(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiredAt interface{} = nil
+	if ttl = conv.memoryTTL(teamID, assistantID, ttl); ttl > 0 {
+		expiredAt = time.Now().Add(time.Duration(ttl) * time.Second)
+	}
+
+	// The unique contents in order
+	contents := []string{}
+	seen := map[string]bool{}
+	for _, content := range memories {
+		content = strings.TrimSpace(content)
+		if content == "" || seen[content] {
+			continue
+		}
+		seen[content] = true
+		contents = append(contents, content)
+	}
+
+	if len(contents) == 0 {
+		return []string{}, nil
+	}
+
+	rows, err := conv.query.New().
+		Table(conv.getMemoryTable()).
+		Select("memory_id", "content").
+		Where("sid", userID).
+		Where("assistant_id", assistantID).
+		WhereIn("content", contents).
+		Get()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[string]string{}
+	for _, row := range rows {
+		if row.Get("memory_id") != nil {
+			existing[fmt.Sprintf("%v", row.Get("content"))] = fmt.Sprintf("%v", row.Get("memory_id"))
+		}
+	}
+
+	now := time.Now()
+	ids := []string{}
+	refreshed := []string{}
+	values := []map[string]interface{}{}
+	for _, content := range contents {
+
+		// Refresh the existing memory
+		if id, has := existing[content]; has {
+			refreshed = append(refreshed, id)
+			ids = append(ids, id)
+			continue
+		}
+
+		id := uuid.New().String()
+		values = append(values, map[string]interface{}{
+			"memory_id":    id,
+			"sid":          userID,
+			"assistant_id": assistantID,
+			"content":      content,
+			"created_at":   now,
+			"expired_at":   expiredAt,
+		})
+		ids = append(ids, id)
+	}
+
+	if len(refreshed) > 0 {
+		_, err := conv.query.New().
+			Table(conv.getMemoryTable()).
+			WhereIn("memory_id", refreshed).
+			Update(map[string]interface{}{"updated_at": now, "expired_at": expiredAt})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = conv.insertBatch(conv.query, conv.getMemoryTable(), values)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// GetMemories retrieves the long-term memories of a user, the expired memories are excluded
+func (conv *Xun) GetMemories(sid string, filter MemoryFilter) ([]map[string]interface{}, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	qb := conv.query.New().
+		Table(conv.getMemoryTable()).
+		Select("memory_id", "assistant_id", "content", "created_at", "updated_at", "expired_at").
+		Where("sid", userID).
+		Where(func(qb query.Query) {
+			qb.WhereNull("expired_at").OrWhere("expired_at", ">", time.Now())
+		})
+
+	if filter.AssistantID != "" {
+		qb.Where("assistant_id", filter.AssistantID)
+	}
+
+	if keyword := strings.TrimSpace(filter.Keywords); keyword != "" {
+		qb.Where("content", "like", "%"+keyword+"%")
+	}
+
+	limit := 100
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+
+	rows, err := qb.OrderBy("id", "desc").Limit(limit).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	memories := []map[string]interface{}{}
+	for _, row := range rows {
+		memories = append(memories, row.ToMap())
+	}
+	return memories, nil
+}
+
+// DeleteMemory deletes a single memory of the user
+func (conv *Xun) DeleteMemory(sid string, memoryID string) error {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return err
+	}
+
+	nums, err := conv.query.New().
+		Table(conv.getMemoryTable()).
+		Where("sid", userID).
+		Where("memory_id", memoryID).
+		Delete()
+	if err != nil {
+		return err
+	}
+
+	if nums == 0 {
+		return fmt.Errorf("memory %s not found", memoryID)
+	}
+	return nil
+}
+
+// DeleteMemories deletes all the memories of a user, of the assistant if given
+func (conv *Xun) DeleteMemories(sid string, assistantID string) (int64, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return 0, err
+	}
+
+	qb := conv.query.New().
+		Table(conv.getMemoryTable()).
+		Where("sid", userID)
+
+	if assistantID != "" {
+		qb.Where("assistant_id", assistantID)
+	}
+	return qb.Delete()
+}
+
+// GetProfile retrieves the preference profile of a user, returns an empty profile if not set
+func (conv *Xun) GetProfile(sid string) (map[string]interface{}, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := conv.query.New().
+		Table(conv.getProfileTable()).
+		Select("language", "tone", "timezone", "instructions", "updated_at").
+		Where("sid", userID).
+		First()
+	if err != nil {
+		return nil, err
+	}
+
+	if row == nil {
+		return map[string]interface{}{}, nil
+	}
+	return row.ToMap(), nil
+}
+
+// SaveProfile saves the preference profile of a user, the fields not given are kept
+func (conv *Xun) SaveProfile(sid string, profile map[string]interface{}) error {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{}
+	for _, field := range []string{"language", "tone", "timezone", "instructions"} {
+		if value, has := profile[field]; has {
+			data[field] = value
+		}
+	}
+
+	exists, err := conv.query.New().
+		Table(conv.getProfileTable()).
+		Where("sid", userID).
+		Exists()
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		if len(data) == 0 {
+			return nil
+		}
+		data["updated_at"] = time.Now()
+		_, err = conv.query.New().
+			Table(conv.getProfileTable()).
+			Where("sid", userID).
+			Update(data)
+		return err
+	}
+
+	data["sid"] = userID
+	return conv.query.New().
+		Table(conv.getProfileTable()).
+		Insert(data)
+}
+
+// SaveWorkflowRun creates or updates a workflow run of the user
+func (conv *Xun) SaveWorkflowRun(sid string, run map[string]interface{}) error {
+	runID, ok := run["run_id"].(string)
+	if !ok || runID == "" {
+		return fmt.Errorf("run_id is required")
+	}
+
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{}
+	for _, field := range []string{"assistant_id", "workflow", "status", "step", "error"} {
+		if value, has := run[field]; has {
+			data[field] = value
+		}
+	}
+
+	if state, has := run["state"]; has {
+		value, err := conv.processJSONField(state)
+		if err != nil {
+			return err
+		}
+		data["state"] = value
+	}
+
+	exists, err := conv.query.New().
+		Table(conv.getWorkflowRunTable()).
+		Where("run_id", runID).
+		Where("sid", userID).
+		Exists()
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		data["updated_at"] = time.Now()
+		_, err = conv.query.New().
+			Table(conv.getWorkflowRunTable()).
+			Where("run_id", runID).
+			Where("sid", userID).
+			Update(data)
+		return err
+	}
+
+	data["run_id"] = runID
+	data["sid"] = userID
+	data["created_at"] = time.Now()
+	data["updated_at"] = time.Now()
+	return conv.query.New().
+		Table(conv.getWorkflowRunTable()).
+		Insert(data)
+}
+
+// GetWorkflowRun retrieves a single workflow run of the user
+func (conv *Xun) GetWorkflowRun(sid string, runID string) (map[string]interface{}, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := conv.query.New().
+		Table(conv.getWorkflowRunTable()).
+		Select("run_id", "assistant_id", "workflow", "status", "step", "state", "error", "created_at", "updated_at").
+		Where("run_id", runID).
+		Where("sid", userID).
+		First()
+	if err != nil {
+		return nil, err
+	}
+
+	if row == nil {
+		return nil, fmt.Errorf("workflow run %s not found", runID)
+	}
+
+	data := row.ToMap()
+	conv.parseJSONFields(data, []string{"state"})
+	return data, nil
+}
+
+// GetWorkflowRuns retrieves the workflow runs of the user, the states are not included
+func (conv *Xun) GetWorkflowRuns(sid string, filter WorkflowRunFilter) ([]map[string]interface{}, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	qb := conv.query.New().
+		Table(conv.getWorkflowRunTable()).
+		Select("run_id", "assistant_id", "workflow", "status", "step", "error", "created_at", "updated_at").
+		Where("sid", userID)
+
+	if filter.AssistantID != "" {
+		qb.Where("assistant_id", filter.AssistantID)
+	}
+
+	if filter.Status != "" {
+		qb.Where("status", filter.Status)
+	}
+
+	limit := 100
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+
+	rows, err := qb.OrderBy("updated_at", "desc").OrderBy("id", "desc").Limit(limit).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []map[string]interface{}{}
+	for _, row := range rows {
+		runs = append(runs, row.ToMap())
+	}
+	return runs, nil
+}
+
+// SaveApproval creates a pending tool approval of the requester
+func (conv *Xun) SaveApproval(sid string, approval map[string]interface{}) (string, error) {
+	tool, ok := approval["tool"].(string)
+	if !ok || tool == "" {
+		return "", fmt.Errorf("tool is required")
+	}
+
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]interface{}{}
+	for _, field := range []string{"assistant_id", "chat_id", "message"} {
+		if value, has := approval[field]; has {
+			data[field] = value
+		}
+	}
+
+	for _, field := range []string{"args", "context"} {
+		value, err := conv.processJSONField(approval[field])
+		if err != nil {
+			return "", err
+		}
+		data[field] = value
+	}
+
+	// The roles are delimited by the commas, so the roles are matched by like
+	if approvers, ok := approval["approvers"].([]string); ok && len(approvers) > 0 {
+		data["approvers"] = "," + strings.Join(approvers, ",") + ","
+	}
+
+	id := uuid.New().String()
+	data["approval_id"] = id
+	data["sid"] = userID
+	data["tool"] = tool
+	data["status"] = "pending"
+	data["created_at"] = time.Now()
+	data["updated_at"] = time.Now()
+	err = conv.query.New().
+		Table(conv.getApprovalTable()).
+		Insert(data)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// GetApproval retrieves a single tool approval
+func (conv *Xun) GetApproval(approvalID string) (map[string]interface{}, error) {
+	row, err := conv.query.New().
+		Table(conv.getApprovalTable()).
+		Select("approval_id", "sid", "assistant_id", "chat_id", "tool", "args", "context", "message", "status", "approvers", "approver", "comment", "created_at", "updated_at").
+		Where("approval_id", approvalID).
+		First()
+	if err != nil {
+		return nil, err
+	}
+
+	if row == nil {
+		return nil, fmt.Errorf("approval %s not found", approvalID)
+	}
+
+	data := row.ToMap()
+	conv.parseJSONFields(data, []string{"args", "context"})
+	data["approvers"] = approversOf(data["approvers"])
+	return data, nil
+}
+
+// GetApprovals retrieves the tool approvals requested by the user and the approvals the roles could decide,
+// the contexts are not included
+func (conv *Xun) GetApprovals(sid string, filter ApprovalFilter) ([]map[string]interface{}, error) {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	qb := conv.query.New().
+		Table(conv.getApprovalTable()).
+		Select("approval_id", "sid", "assistant_id", "chat_id", "tool", "args", "message", "status", "approvers", "approver", "comment", "created_at", "updated_at").
+		Where(func(qb query.Query) {
+			qb.Where("sid", userID)
+			for _, role := range filter.Roles {
+				if role != "" && !strings.Contains(role, ",") {
+					qb.OrWhere("approvers", "like", "%,"+role+",%")
+				}
+			}
+		})
+
+	if filter.ApprovalID != "" {
+		qb.Where("approval_id", filter.ApprovalID)
+	}
+
+	if filter.AssistantID != "" {
+		qb.Where("assistant_id", filter.AssistantID)
+	}
+
+	if filter.ChatID != "" {
+		qb.Where("chat_id", filter.ChatID)
+	}
+
+	if filter.Status != "" {
+		qb.Where("status", filter.Status)
+	}
+
+	limit := 100
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+
+	rows, err := qb.OrderBy("id", "desc").Limit(limit).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	approvals := []map[string]interface{}{}
+	for _, row := range rows {
+		data := row.ToMap()
+		conv.parseJSONFields(data, []string{"args"})
+		data["approvers"] = approversOf(data["approvers"])
+		approvals = append(approvals, data)
+	}
+	return approvals, nil
+}
+
+// approversOf the roles of the approvers stored, e.g. ,admin,ops,
+func approversOf(value interface{}) []string {
+	text, _ := value.(string)
+	roles := []string{}
+	for _, role := range strings.Split(text, ",") {
+		if role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// DecideApproval accepts or rejects a pending tool approval, only the pending approval can be decided,
+// the approval could not be decided by the requester
+func (conv *Xun) DecideApproval(approvalID string, sid string, approved bool, comment string) error {
+	userID, err := conv.getUserID(sid)
+	if err != nil {
+		return err
+	}
+
+	row, err := conv.query.New().
+		Table(conv.getApprovalTable()).
+		Select("sid").
+		Where("approval_id", approvalID).
+		First()
+	if err != nil {
+		return err
+	}
+
+	if row != nil && fmt.Sprintf("%v", row.ToMap()["sid"]) == userID {
+		return fmt.Errorf("approval %s could not be decided by the requester", approvalID)
+	}
+
+	status := "rejected"
+	if approved {
+		status = "approved"
+	}
+
+	nums, err := conv.query.New().
+		Table(conv.getApprovalTable()).
+		Where("approval_id", approvalID).
+		Where("status", "pending").
+		Update(map[string]interface{}{
+			"status":     status,
+			"approver":   userID,
+			"comment":    comment,
+			"updated_at": time.Now(),
+		})
+	if err != nil {
+		return err
+	}
+
+	if nums == 0 {
+		return fmt.Errorf("approval %s not found or already decided", approvalID)
+	}
+	return nil
+}
@@ -221,6 +221,48 @@ func TestXunSaveAndGetHistory(t *testing.T) {
 	assert.Equal(t, 2, len(data))
 }
 
+func TestXunSaveHistoryInBatches(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_history")
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_chat")
+
+	err := capsule.Schema().DropTableIfExists("__unit_test_conversation_history")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = capsule.Schema().DropTableIfExists("__unit_test_conversation_chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewXun(Setting{
+		Connector: "default",
+		Prefix:    "__unit_test_conversation_",
+		BatchSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// save 5 messages in 3 batches
+	messages := []map[string]interface{}{}
+	for i := 0; i < 5; i++ {
+		messages = append(messages, map[string]interface{}{"role": "user", "name": "user1", "content": fmt.Sprintf("message %d", i)})
+	}
+
+	cid := "batch-123456"
+	err = store.SaveHistory("123456", messages, cid, nil)
+	assert.Nil(t, err)
+
+	data, err := store.GetHistory("123456", cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, len(data))
+}
+
 func TestXunSaveAndGetHistoryWithCID(t *testing.T) {
 	test.Prepare(t, config.Conf)
 	defer test.Clean()
@@ -908,6 +950,69 @@ func TestXunAssistantCRUD(t *testing.T) {
 	assert.Equal(t, 0, len(resp.Data))
 }
 
+func TestXunUpdateAssistantConflict(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_assistant")
+
+	err := capsule.Schema().DropTableIfExists("__unit_test_conversation_assistant")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewXun(Setting{
+		Connector: "default",
+		Prefix:    "__unit_test_conversation_",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := store.SaveAssistant(map[string]interface{}{
+		"name":      "Test Assistant",
+		"type":      "assistant",
+		"connector": "openai",
+		"tags":      []string{"tag1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assistantID := v.(string)
+
+	assistant, err := store.GetAssistant(assistantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := AssistantETag(assistant)
+
+	// The first admin updates the assistant
+	next, err := store.UpdateAssistant(map[string]interface{}{"assistant_id": assistantID, "name": "Renamed by A", "tags": []string{"tag2"}}, etag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, etag, next)
+
+	// The second admin updates the outdated version
+	_, err = store.UpdateAssistant(map[string]interface{}{"assistant_id": assistantID, "name": "Renamed by B"}, etag)
+	conflict, ok := err.(*ConflictError)
+	if !ok {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	assert.Equal(t, "Renamed by A", conflict.Latest["name"])
+	assert.Equal(t, next, AssistantETag(conflict.Latest))
+
+	// Retry with the latest version
+	_, err = store.UpdateAssistant(map[string]interface{}{"assistant_id": assistantID, "name": "Renamed by B"}, next)
+	assert.Nil(t, err)
+
+	assistant, err = store.GetAssistant(assistantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Renamed by B", assistant["name"])
+	assert.Equal(t, []interface{}{"tag2"}, assistant["tags"])
+}
+
 func TestXunAssistantPagination(t *testing.T) {
 	test.Prepare(t, config.Conf)
 	defer test.Clean()
@@ -1321,3 +1426,421 @@ func TestGetAssistantTags(t *testing.T) {
 		}
 	}
 }
+
+func TestXunCollectionCRUD(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_collection")
+
+	store, err := NewXun(Setting{
+		Connector: "default",
+		Prefix:    "__unit_test_conversation_",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Name is required
+	_, err = store.SaveCollection(map[string]interface{}{"description": "no name"})
+	assert.Error(t, err)
+
+	// Create
+	name, err := store.SaveCollection(map[string]interface{}{
+		"name":        "docs",
+		"description": "Product docs",
+		"options":     map[string]interface{}{"chunk": map[string]interface{}{"size": 512, "splitter": "markdown"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "docs", name)
+
+	collection, err := store.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Product docs", collection["description"])
+	options, ok := collection["options"].(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "markdown", options["chunk"].(map[string]interface{})["splitter"])
+
+	// Update
+	_, err = store.SaveCollection(map[string]interface{}{"name": "docs", "description": "Updated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection, err = store.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Updated", collection["description"])
+	assert.NotNil(t, collection["options"])
+
+	_, err = store.SaveCollection(map[string]interface{}{"name": "blog"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collections, err := store.GetCollections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, collections, 2)
+	assert.Equal(t, "blog", collections[0]["name"])
+
+	// Delete
+	err = store.DeleteCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.GetCollection("docs")
+	assert.Error(t, err)
+	assert.Error(t, store.DeleteCollection("docs"))
+}
+
+func TestXunMemories(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_memory")
+
+	store, err := NewXun(Setting{
+		Connector: "default",
+		Prefix:    "__unit_test_conversation_",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sid := fmt.Sprintf("memory_%d", time.Now().UnixNano())
+	ids, err := store.SaveMemories(sid, "assistant-1", []string{"Likes green tea", "Lives in Berlin", " "}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, ids, 2)
+
+	// Duplicated memory is refreshed
+	again, err := store.SaveMemories(sid, "assistant-1", []string{"Likes green tea"}, 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ids[0], again[0])
+
+	_, err = store.SaveMemories(sid, "assistant-2", []string{"Prefers short answers"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memories, err := store.GetMemories(sid, MemoryFilter{AssistantID: "assistant-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, memories, 2)
+
+	memories, err = store.GetMemories(sid, MemoryFilter{Keywords: "Berlin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, memories, 1)
+
+	// Expired memories are excluded
+	_, err = store.SaveMemories(sid, "assistant-1", []string{"Expired"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	memories, err = store.GetMemories(sid, MemoryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, memories, 3)
+
+	err = store.DeleteMemory(sid, ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, store.DeleteMemory(sid, ids[1]))
+
+	nums, err := store.DeleteMemories(sid, "assistant-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), nums)
+
+	memories, err = store.GetMemories(sid, MemoryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, memories, 1)
+}
+
+func TestXunProfile(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_profile")
+
+	store, err := NewXun(Setting{
+		Connector: "default",
+		Prefix:    "__unit_test_conversation_",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sid := fmt.Sprintf("profile_%d", time.Now().UnixNano())
+	profile, err := store.GetProfile(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, profile)
+
+	err = store.SaveProfile(sid, map[string]interface{}{"language": "zh-CN", "tone": "friendly"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The fields not given are kept
+	err = store.SaveProfile(sid, map[string]interface{}{"timezone": "Asia/Shanghai", "unknown": "ignored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err = store.GetProfile(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "zh-CN", profile["language"])
+	assert.Equal(t, "friendly", profile["tone"])
+	assert.Equal(t, "Asia/Shanghai", profile["timezone"])
+	assert.Nil(t, profile["unknown"])
+}
+
+func TestXunWorkflowRuns(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_workflow_run")
+
+	store, err := NewXun(Setting{
+		Connector: "default",
+		Prefix:    "__unit_test_conversation_",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sid := fmt.Sprintf("workflow_%d", time.Now().UnixNano())
+	run := map[string]interface{}{
+		"run_id":       "run_" + sid,
+		"assistant_id": "assistant_1",
+		"workflow":     "refund",
+		"status":       "waiting",
+		"step":         "approve",
+		"state":        map[string]interface{}{"input": map[string]interface{}{"order_id": 1}},
+	}
+
+	err = store.SaveWorkflowRun(sid, map[string]interface{}{"status": "running"})
+	assert.Error(t, err)
+
+	err = store.SaveWorkflowRun(sid, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := store.GetWorkflowRun(sid, run["run_id"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "waiting", data["status"])
+	assert.Equal(t, float64(1), data["state"].(map[string]interface{})["input"].(map[string]interface{})["order_id"])
+
+	// Update
+	run["status"] = "completed"
+	run["step"] = ""
+	err = store.SaveWorkflowRun(sid, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := store.GetWorkflowRuns(sid, WorkflowRunFilter{AssistantID: "assistant_1", Status: "completed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, runs, 1)
+	assert.Nil(t, runs[0]["state"])
+
+	runs, err = store.GetWorkflowRuns(sid, WorkflowRunFilter{Status: "waiting"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, runs, 0)
+
+	// The runs of the other users are not visible
+	_, err = store.GetWorkflowRun("other_"+sid, run["run_id"].(string))
+	assert.Error(t, err)
+}
+
+func TestXunApprovals(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_approval")
+
+	store, err := NewXun(Setting{
+		Connector: "default",
+		Prefix:    "__unit_test_conversation_",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chatID := fmt.Sprintf("chat_%d", time.Now().UnixNano())
+	_, err = store.SaveApproval("requester", map[string]interface{}{"chat_id": chatID})
+	assert.Error(t, err)
+
+	id, err := store.SaveApproval("requester", map[string]interface{}{
+		"assistant_id": "assistant_1",
+		"chat_id":      chatID,
+		"tool":         "scripts.mail.Send",
+		"args":         []interface{}{"max@example.com"},
+		"context":      map[string]interface{}{"sid": "requester", "chat_id": chatID},
+		"message":      "Send the mail?",
+		"approvers":    []string{"admin"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	approval, err := store.GetApproval(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "pending", approval["status"])
+	assert.Equal(t, []interface{}{"max@example.com"}, approval["args"])
+	assert.Equal(t, chatID, approval["context"].(map[string]interface{})["chat_id"])
+
+	assert.Equal(t, []string{"admin"}, approval["approvers"])
+
+	// The approvals are scoped by the requester and the roles of the approvers
+	approvals, err := store.GetApprovals("requester", ApprovalFilter{ChatID: chatID, Status: "pending"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, approvals, 1)
+	assert.Nil(t, approvals[0]["context"])
+
+	approvals, err = store.GetApprovals("approver", ApprovalFilter{ChatID: chatID, Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, approvals, 1)
+
+	approvals, err = store.GetApprovals("approver", ApprovalFilter{ApprovalID: id, Roles: []string{"user"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, approvals, 0)
+
+	// The requester could not decide
+	err = store.DecideApproval(id, "requester", true, "")
+	assert.Error(t, err)
+
+	// Decide
+	err = store.DecideApproval(id, "approver", true, "Go ahead")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	approval, err = store.GetApproval(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "approved", approval["status"])
+	assert.Equal(t, "Go ahead", approval["comment"])
+
+	// Only the pending approval can be decided
+	err = store.DecideApproval(id, "approver", false, "")
+	assert.Error(t, err)
+
+	_, err = store.GetApproval("not_exists")
+	assert.Error(t, err)
+}
+
+func TestXunUserData(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	defer capsule.Schema().DropTableIfExists("__unit_test_conversation_deletion")
+
+	store, err := NewXun(Setting{
+		Connector: "default",
+		Prefix:    "__unit_test_conversation_",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sid := fmt.Sprintf("gdpr_%d", time.Now().UnixNano())
+	err = store.SaveHistory(sid, []map[string]interface{}{
+		{"role": "user", "name": "user1", "content": "hello"},
+		{"role": "assistant", "name": "user1", "content": "Hello there"},
+	}, "gdpr-chat", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.SaveMemories(sid, "assistant-1", []string{"Likes green tea"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.SaveProfile(sid, map[string]interface{}{"language": "en-US"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Export
+	data, err := store.ExportUserData(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "en-US", data["profile"].(map[string]interface{})["language"])
+	chats := data["chats"].([]map[string]interface{})
+	assert.Len(t, chats, 1)
+	assert.Len(t, chats[0]["history"], 2)
+	assert.Len(t, data["memories"], 1)
+
+	// Schedule and cancel
+	scheduledAt, err := store.ScheduleUserDeletion(sid, 30*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, scheduledAt.After(time.Now()))
+
+	deletion, err := store.GetUserDeletion(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, deletion)
+
+	err = store.CancelUserDeletion(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deletion, err = store.GetUserDeletion(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, deletion)
+
+	// Delete at once
+	_, err = store.ScheduleUserDeletion(sid, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = store.ExportUserData(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, data["chats"], 0)
+	assert.Len(t, data["memories"], 0)
+	assert.Empty(t, data["profile"])
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/yaoapp/yao/neo/store"
 	"github.com/yaoapp/yao/neo/vision"
 	"github.com/yaoapp/yao/neo/vision/driver"
+	"github.com/yaoapp/yao/trace"
 )
 
 // DSL AI assistant
@@ -25,6 +26,7 @@ type DSL struct {
 	Write         string                 `json:"write,omitempty" yaml:"write,omitempty"`
 	Prompts       []assistant.Prompt     `json:"prompts,omitempty" yaml:"prompts,omitempty"`
 	Allows        []string               `json:"allows,omitempty" yaml:"allows,omitempty"`
+	Logging       trace.Logging          `json:"logging,omitempty" yaml:"logging,omitempty"`
 	Assistant     assistant.API          `json:"-" yaml:"-"` // The default assistant
 	Store         store.Store            `json:"-" yaml:"-"`
 	RAG           *rag.RAG               `json:"-" yaml:"-"`
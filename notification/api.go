@@ -0,0 +1,146 @@
+package notification
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/yao/share"
+)
+
+// UserField the session field of the user id, the session id is used if the field is not set
+var UserField = "user_id"
+
+// SetRoutes the inbox and the preferences of the signed-in user
+//
+//	GET    <path>?unread=1&page=1&pagesize=20  The notifications, the latest first
+//	POST   <path>/read {"ids": [1, 2]}         Mark the notifications as read, all of them if the ids are empty
+//	DELETE <path>/:id                          Remove the notification
+//	GET    <path>/preferences                  The channels turned on or off
+//	PUT    <path>/preferences {"*": {"email": false}}
+func SetRoutes(router *gin.Engine, path string, guards ...gin.HandlerFunc) {
+	handlers := func(handler gin.HandlerFunc) []gin.HandlerFunc {
+		return append(append([]gin.HandlerFunc{}, guards...), handler)
+	}
+	router.GET(path, handlers(handleList)...)
+	router.POST(path+"/read", handlers(handleRead)...)
+	router.DELETE(path+"/:id", handlers(handleRemove)...)
+	router.GET(path+"/preferences", handlers(handlePreferences)...)
+	router.PUT(path+"/preferences", handlers(handleSetPreferences)...)
+}
+
+// user the user id of the session
+func user(c *gin.Context) (string, bool) {
+	sid := c.GetString("__sid")
+	if sid == "" || share.IsGuest(sid) {
+		c.AbortWithStatusJSON(403, gin.H{"code": 403, "message": "Not Authorized"})
+		return "", false
+	}
+
+	id, err := session.Global().ID(sid).Get(UserField)
+	if err != nil || id == nil || id == "" {
+		return sid, true
+	}
+	return fmt.Sprintf("%v", id), true
+}
+
+func handleList(c *gin.Context) {
+	uid, ok := user(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	pagesize, _ := strconv.Atoi(c.Query("pagesize"))
+	unread := c.Query("unread") == "1" || c.Query("unread") == "true"
+	res, err := List(uid, unread, page, pagesize)
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(200, res)
+}
+
+func handleRead(c *gin.Context) {
+	uid, ok := user(c)
+	if !ok {
+		return
+	}
+
+	payload := struct {
+		IDs []int64 `json:"ids"`
+	}{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+			return
+		}
+	}
+
+	n, err := Read(uid, payload.IDs...)
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"read": n})
+}
+
+func handleRemove(c *gin.Context) {
+	uid, ok := user(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": "the id should be an integer"})
+		return
+	}
+
+	n, err := Remove(uid, id)
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+
+	if n == 0 {
+		c.JSON(404, gin.H{"code": 404, "message": "the notification does not exist"})
+		return
+	}
+	c.JSON(200, gin.H{"removed": n})
+}
+
+func handlePreferences(c *gin.Context) {
+	uid, ok := user(c)
+	if !ok {
+		return
+	}
+
+	prefs, err := GetPreferences(uid)
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(200, prefs)
+}
+
+func handleSetPreferences(c *gin.Context) {
+	uid, ok := user(c)
+	if !ok {
+		return
+	}
+
+	prefs := Preferences{}
+	if err := c.ShouldBindJSON(&prefs); err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	saved, err := SetPreferences(uid, prefs)
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(200, saved)
+}
@@ -0,0 +1,70 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/yao/mailer"
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// sendEmail send the notification by the mailer of the template, the default mailer if it is not set.
+// The mail template renders the mail with the data and the notification, the text of the notification is sent without it.
+func sendEmail(tpl *Template, msg *Message, notification *Notification) error {
+	if msg.Email == "" {
+		return fmt.Errorf("the email address of the user %s is required", msg.User)
+	}
+
+	m := mailer.Mail{Mailer: tpl.Mailer, To: []string{msg.Email}}
+	if tpl.Mail != "" {
+		m.Template = tpl.Mail
+		m.Data = map[string]interface{}{}
+		for key, value := range msg.Data {
+			m.Data[key] = value
+		}
+		m.Data["notification"] = map[string]interface{}{"title": notification.Title, "content": notification.Content, "link": notification.Link}
+	} else {
+		m.Subject = notification.Title
+		m.Text = mailText(notification)
+	}
+
+	_, err := mailer.Send(m)
+	return err
+}
+
+// mailText the text of the notification mail, the link is appended to the content
+func mailText(notification *Notification) string {
+	body := notification.Content
+	if notification.Link != "" {
+		body = strings.TrimRight(body, "\n") + "\n\n" + notification.Link
+	}
+	return body
+}
+
+// sendWebhook post the notification to the webhook of the template
+func sendWebhook(tpl *Template, msg *Message, notification *Notification) error {
+	if tpl.Webhook == "" {
+		return fmt.Errorf("the webhook of the template %s is not set", tpl.ID)
+	}
+
+	payload, err := jsoniter.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	res, err := webhookClient.Post(tpl.Webhook, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("the webhook responds %d", res.StatusCode)
+	}
+	return nil
+}
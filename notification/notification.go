@@ -0,0 +1,174 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+func init() {
+	RegisterChannel(ChannelInbox, sendInbox)
+	RegisterChannel(ChannelEmail, sendEmail)
+	RegisterChannel(ChannelWebhook, sendWebhook)
+}
+
+// Load the notifications/*.yao templates and create the tables of the inbox
+func Load(cfg config.Config) error {
+	templates := map[string]*Template{}
+	messages := []string{}
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("notifications", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		tpl := Template{}
+		err = application.Parse(file, bytes, &tpl)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		tpl.ID = share.ID(root, file)
+		if tpl.Title == "" {
+			messages = append(messages, fmt.Sprintf("%s: the title is required", file))
+			return nil
+		}
+
+		if len(tpl.Channels) == 0 {
+			tpl.Channels = []string{ChannelInbox}
+		}
+		templates[tpl.ID] = &tpl
+		return nil
+	}, exts...)
+
+	lock.Lock()
+	Templates = templates
+	lock.Unlock()
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(templates) == 0 {
+		return nil
+	}
+	return migrate()
+}
+
+// RegisterChannel register the delivery channel, the channel of the same name is replaced
+func RegisterChannel(name string, channel Channel) {
+	lock.Lock()
+	defer lock.Unlock()
+	channels[name] = channel
+}
+
+// Send the notification to the channels of the template, the channels turned off by the user are skipped.
+// The inbox is delivered first and the other channels are delivered in the background, returns the inbox notification.
+func Send(msg Message) (*Notification, error) {
+	lock.RLock()
+	tpl, has := Templates[msg.Template]
+	lock.RUnlock()
+	if !has {
+		return nil, fmt.Errorf("the notification template %s does not exist", msg.Template)
+	}
+
+	if msg.User == "" {
+		return nil, fmt.Errorf("the user is required")
+	}
+
+	notification := Render(tpl, msg)
+	prefs, err := GetPreferences(msg.User)
+	if err != nil {
+		return nil, err
+	}
+
+	background := []string{}
+	for _, name := range tpl.Channels {
+		if !prefs.Enabled(tpl.ID, name) {
+			continue
+		}
+
+		if name != ChannelInbox {
+			background = append(background, name)
+			continue
+		}
+
+		if err := deliver(name, tpl, &msg, notification); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(background) > 0 {
+		go func() {
+			for _, name := range background {
+				if err := deliver(name, tpl, &msg, notification); err != nil {
+					log.Error("[Notification] %s %s %s: %s", tpl.ID, name, msg.User, err.Error())
+				}
+			}
+		}()
+	}
+
+	return notification, nil
+}
+
+func deliver(name string, tpl *Template, msg *Message, notification *Notification) error {
+	lock.RLock()
+	channel, has := channels[name]
+	lock.RUnlock()
+	if !has {
+		return fmt.Errorf("the channel %s does not exist", name)
+	}
+	return channel(tpl, msg, notification)
+}
+
+// Render the notification of the template, the {{ field }} of the texts are replaced by the data
+func Render(tpl *Template, msg Message) *Notification {
+	data := maps.Of(msg.Data).Dot()
+	bind := func(text string) string {
+		if !strings.Contains(text, "{{") {
+			return text
+		}
+		if res, ok := helper.Bind(text, data).(string); ok {
+			return res
+		}
+		return text
+	}
+
+	return &Notification{
+		User:     msg.User,
+		Template: tpl.ID,
+		Title:    bind(tpl.Title),
+		Content:  bind(tpl.Content),
+		Link:     bind(tpl.Link),
+		Data:     msg.Data,
+	}
+}
+
+// Enabled the channel of the template is turned on or not, the template preference takes precedence over "*"
+func (prefs Preferences) Enabled(template string, channel string) bool {
+	if enabled, has := prefs[template][channel]; has {
+		return enabled
+	}
+	if enabled, has := prefs["*"][channel]; has {
+		return enabled
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package notification
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	tpl := &Template{ID: "invitation", Title: "{{ inviter }} invited you", Content: "Join {{ team.name }}", Link: "/teams/{{ team.id }}"}
+	notification := Render(tpl, Message{
+		Template: "invitation",
+		User:     "1",
+		Data:     map[string]interface{}{"inviter": "Max", "team": map[string]interface{}{"id": 2, "name": "Yao"}},
+	})
+	assert.Equal(t, "invitation", notification.Template)
+	assert.Equal(t, "Max invited you", notification.Title)
+	assert.Equal(t, "Join Yao", notification.Content)
+	assert.Equal(t, "/teams/2", notification.Link)
+}
+
+func TestPreferencesEnabled(t *testing.T) {
+	prefs := Preferences{
+		"*":          {ChannelEmail: false},
+		"invitation": {ChannelEmail: true, ChannelInbox: false},
+	}
+	assert.True(t, prefs.Enabled("invitation", ChannelEmail))
+	assert.False(t, prefs.Enabled("invitation", ChannelInbox))
+	assert.False(t, prefs.Enabled("job.done", ChannelEmail))
+	assert.True(t, prefs.Enabled("job.done", ChannelInbox))
+	assert.True(t, Preferences{}.Enabled("job.done", ChannelWebhook))
+}
+
+func TestMailText(t *testing.T) {
+	assert.Equal(t, "The job is done\n\nhttps://example.com/jobs/1", mailText(&Notification{Title: "任务完成", Content: "The job is done\n", Link: "https://example.com/jobs/1"}))
+	assert.Equal(t, "The job is done", mailText(&Notification{Content: "The job is done"}))
+}
+
+func TestSendWebhook(t *testing.T) {
+	received := Notification{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		jsoniter.Unmarshal(body, &received)
+		if received.User == "fail" {
+			w.WriteHeader(500)
+		}
+	}))
+	defer srv.Close()
+
+	tpl := &Template{ID: "job.done", Webhook: srv.URL}
+	err := sendWebhook(tpl, &Message{}, &Notification{User: "1", Template: "job.done", Title: "Done"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Done", received.Title)
+
+	err = sendWebhook(tpl, &Message{}, &Notification{User: "fail"})
+	assert.Error(t, err)
+
+	err = sendWebhook(&Template{ID: "none"}, &Message{}, &Notification{})
+	assert.Error(t, err)
+}
+
+func TestToIDs(t *testing.T) {
+	assert.Equal(t, []int64{1, 2}, toIDs([]interface{}{1, 2.0, "x"}))
+	assert.Equal(t, []int64{3}, toIDs("3"))
+	assert.Equal(t, []int64{}, toIDs(nil))
+}
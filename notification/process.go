@@ -0,0 +1,147 @@
+package notification
+
+import (
+	"fmt"
+	"strconv"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("notification", map[string]process.Handler{
+		"send":           processSend,
+		"list":           processList,
+		"read":           processRead,
+		"remove":         processRemove,
+		"preferences":    processPreferences,
+		"setpreferences": processSetPreferences,
+	})
+}
+
+// processSend notification.Send(template, user, data, email?)
+func processSend(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	msg := Message{Template: process.ArgsString(0), User: process.ArgsString(1), Data: map[string]interface{}{}}
+	if process.NumOfArgs() > 2 {
+		msg.Data = process.ArgsMap(2)
+	}
+	if process.NumOfArgs() > 3 {
+		msg.Email = process.ArgsString(3)
+	}
+
+	notification, err := Send(msg)
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return notification
+}
+
+// processList notification.List(user, {"unread": true, "page": 1, "pagesize": 20}?)
+func processList(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	option := map[string]interface{}{}
+	if process.NumOfArgs() > 1 {
+		option = process.ArgsMap(1)
+	}
+
+	unread, _ := option["unread"].(bool)
+	res, err := List(process.ArgsString(0), unread, toInt(option["page"]), toInt(option["pagesize"]))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return res
+}
+
+// processRead notification.Read(user, ids?), all of the notifications if the ids are not given
+func processRead(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	ids := []int64{}
+	if process.NumOfArgs() > 1 {
+		ids = toIDs(process.Args[1])
+	}
+
+	n, err := Read(process.ArgsString(0), ids...)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return n
+}
+
+// processRemove notification.Remove(user, id)
+func processRemove(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	n, err := Remove(process.ArgsString(0), int64(toInt(process.Args[1])))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return n
+}
+
+// processPreferences notification.Preferences(user)
+func processPreferences(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	prefs, err := GetPreferences(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return prefs
+}
+
+// processSetPreferences notification.SetPreferences(user, {"<template>|*": {"<channel>": false}})
+func processSetPreferences(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	prefs := Preferences{}
+	bytes, err := jsoniter.Marshal(process.Args[1])
+	if err == nil {
+		err = jsoniter.Unmarshal(bytes, &prefs)
+	}
+	if err != nil {
+		exception.New("the preferences should be {\"<template>\": {\"<channel>\": true|false}}", 400).Throw()
+	}
+
+	saved, err := SetPreferences(process.ArgsString(0), prefs)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return saved
+}
+
+func toInt(v interface{}) int {
+	switch value := v.(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	case string:
+		n, _ := strconv.Atoi(value)
+		return n
+	}
+	return 0
+}
+
+func toIDs(v interface{}) []int64 {
+	ids := []int64{}
+	switch values := v.(type) {
+	case []interface{}:
+		for _, value := range values {
+			if id := toInt(value); id > 0 {
+				ids = append(ids, int64(id))
+			}
+		}
+	case []int64:
+		ids = values
+	case []int:
+		for _, value := range values {
+			ids = append(ids, int64(value))
+		}
+	default:
+		if id := toInt(fmt.Sprintf("%v", v)); id > 0 {
+			ids = append(ids, int64(id))
+		}
+	}
+	return ids
+}
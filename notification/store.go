@@ -0,0 +1,247 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+const (
+	inboxTable      = "yao_notification"
+	preferenceTable = "yao_notification_preference"
+)
+
+// ready the tables are created, the inbox is empty and the preferences are the defaults if not
+var ready = false
+
+// migrate create the tables of the inbox and the preferences
+func migrate() error {
+	if capsule.Global == nil {
+		return fmt.Errorf("the database is not connected")
+	}
+
+	sch := capsule.Schema()
+	has, err := sch.HasTable(inboxTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		err = sch.CreateTable(inboxTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("user_id", 255).Index()
+			table.String("template", 200).Index()
+			table.String("title", 500)
+			table.Text("content").Null()
+			table.String("link", 1000).Null()
+			table.JSON("data").Null()
+			table.TimestampTz("read_at").Null().Index()
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()").Index()
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the notification table: %s", inboxTable)
+	}
+
+	has, err = sch.HasTable(preferenceTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		err = sch.CreateTable(preferenceTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("user_id", 255).Unique()
+			table.JSON("preferences").Null()
+			table.TimestampTz("updated_at").Null()
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the notification table: %s", preferenceTable)
+	}
+
+	ready = true
+	return nil
+}
+
+func newQuery(table string) query.Query {
+	qb := capsule.Query()
+	qb.Table(table)
+	return qb
+}
+
+// sendInbox save the notification to the inbox of the user
+func sendInbox(tpl *Template, msg *Message, notification *Notification) error {
+	if !ready {
+		return fmt.Errorf("the notification tables are not created")
+	}
+
+	data, err := jsoniter.MarshalToString(notification.Data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	id, err := newQuery(inboxTable).InsertGetID(map[string]interface{}{
+		"user_id":    notification.User,
+		"template":   notification.Template,
+		"title":      notification.Title,
+		"content":    notification.Content,
+		"link":       notification.Link,
+		"data":       data,
+		"created_at": now,
+	})
+	if err != nil {
+		return err
+	}
+
+	notification.ID = id
+	notification.CreatedAt = now
+	return nil
+}
+
+// List the notifications of the inbox of the user, the latest first
+func List(user string, unread bool, page int, pagesize int) (*Page, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pagesize < 1 || pagesize > 100 {
+		pagesize = 20
+	}
+
+	res := &Page{Data: []Notification{}, Page: page, PageSize: pagesize}
+	if !ready {
+		return res, nil
+	}
+
+	qb := newQuery(inboxTable).Where("user_id", user)
+	if unread {
+		qb.WhereNull("read_at")
+	}
+
+	total, err := qb.Clone().Count()
+	if err != nil {
+		return nil, err
+	}
+	res.Total = total
+
+	res.Unread, err = newQuery(inboxTable).Where("user_id", user).WhereNull("read_at").Count()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := qb.OrderBy("id", "desc").Offset((page - 1) * pagesize).Limit(pagesize).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		notification := Notification{
+			User:      fmt.Sprintf("%v", row.Get("user_id")),
+			Template:  fmt.Sprintf("%v", row.Get("template")),
+			Title:     fmt.Sprintf("%v", row.Get("title")),
+			ReadAt:    row.Get("read_at"),
+			CreatedAt: row.Get("created_at"),
+		}
+		fmt.Sscanf(fmt.Sprintf("%v", row.Get("id")), "%d", &notification.ID)
+		if v, ok := row.Get("content").(string); ok {
+			notification.Content = v
+		}
+		if v, ok := row.Get("link").(string); ok {
+			notification.Link = v
+		}
+		if v, ok := row.Get("data").(string); ok && v != "" {
+			jsoniter.UnmarshalFromString(v, &notification.Data)
+		}
+		res.Data = append(res.Data, notification)
+	}
+	return res, nil
+}
+
+// Read mark the notifications of the user as read, all of the unread notifications if the ids are empty, returns the number of the notifications marked
+func Read(user string, ids ...int64) (int64, error) {
+	if !ready {
+		return 0, nil
+	}
+
+	qb := newQuery(inboxTable).Where("user_id", user).WhereNull("read_at")
+	if len(ids) > 0 {
+		qb.WhereIn("id", ids)
+	}
+	return qb.Update(map[string]interface{}{"read_at": time.Now()})
+}
+
+// Remove the notification of the user
+func Remove(user string, id int64) (int64, error) {
+	if !ready {
+		return 0, nil
+	}
+	return newQuery(inboxTable).Where("user_id", user).Where("id", id).Delete()
+}
+
+// GetPreferences the preferences of the user, empty if not saved
+func GetPreferences(user string) (Preferences, error) {
+	prefs := Preferences{}
+	if !ready {
+		return prefs, nil
+	}
+
+	row, err := newQuery(preferenceTable).Where("user_id", user).First()
+	if err != nil {
+		return nil, err
+	}
+
+	if v, ok := row.Get("preferences").(string); ok && v != "" {
+		if err := jsoniter.UnmarshalFromString(v, &prefs); err != nil {
+			return nil, err
+		}
+	}
+	return prefs, nil
+}
+
+// SetPreferences save the preferences of the user, the preferences are merged into the saved ones
+func SetPreferences(user string, prefs Preferences) (Preferences, error) {
+	if !ready {
+		return nil, fmt.Errorf("the notification tables are not created")
+	}
+
+	saved, err := GetPreferences(user)
+	if err != nil {
+		return nil, err
+	}
+
+	for template, values := range prefs {
+		if _, has := saved[template]; !has {
+			saved[template] = map[string]bool{}
+		}
+		for channel, enabled := range values {
+			saved[template][channel] = enabled
+		}
+	}
+
+	data, err := jsoniter.MarshalToString(saved)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	exists, err := newQuery(preferenceTable).Where("user_id", user).Exists()
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		_, err = newQuery(preferenceTable).Where("user_id", user).Update(map[string]interface{}{"preferences": data, "updated_at": now})
+		return saved, err
+	}
+
+	err = newQuery(preferenceTable).Insert(map[string]interface{}{"user_id": user, "preferences": data, "updated_at": now})
+	return saved, err
+}
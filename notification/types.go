@@ -0,0 +1,66 @@
+package notification
+
+import "sync"
+
+// Channels of the notifications
+const (
+	ChannelInbox   = "inbox"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Template the notification template, notifications/<name>.yao, the {{ field }} of the texts are replaced by the data
+type Template struct {
+	ID       string   `json:"id,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Title    string   `json:"title"`
+	Content  string   `json:"content,omitempty"`
+	Link     string   `json:"link,omitempty"`
+	Channels []string `json:"channels,omitempty"` // The delivery channels, inbox by default
+	Webhook  string   `json:"webhook,omitempty"`  // The URL of the webhook channel
+	Mail     string   `json:"mail,omitempty"`     // The mail template of the email channel, mails/<name>.yao, the data and the notification are bound
+	Mailer   string   `json:"mailer,omitempty"`   // The mailer of the email channel, the default mailer if it is empty
+}
+
+// Message the notification sent by the producers
+type Message struct {
+	Template string                 `json:"template"`
+	User     string                 `json:"user"`            // The user id of the recipient
+	Email    string                 `json:"email,omitempty"` // The email address of the recipient, required by the email channel
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+// Notification the rendered notification
+type Notification struct {
+	ID        int64                  `json:"id,omitempty"`
+	User      string                 `json:"user_id"`
+	Template  string                 `json:"template"`
+	Title     string                 `json:"title"`
+	Content   string                 `json:"content,omitempty"`
+	Link      string                 `json:"link,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	ReadAt    interface{}            `json:"read_at,omitempty"`
+	CreatedAt interface{}            `json:"created_at,omitempty"`
+}
+
+// Preferences the channels the user turned on or off, template => channel => enabled, the template "*" applies to all of the templates
+type Preferences map[string]map[string]bool
+
+// Channel deliver the notification, the message is the one sent by the producer
+type Channel func(tpl *Template, msg *Message, notification *Notification) error
+
+// Page the notifications of the inbox
+type Page struct {
+	Data     []Notification `json:"data"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"pagesize"`
+	Total    int64          `json:"total"`
+	Unread   int64          `json:"unread"`
+}
+
+// Templates the loaded templates
+var Templates = map[string]*Template{}
+
+// channels the delivery channels, registered by RegisterChannel
+var channels = map[string]Channel{}
+var lock sync.RWMutex
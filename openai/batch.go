@@ -0,0 +1,302 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/fs"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+)
+
+// Batch the batch inference job, the prompts or the rows of the query are fanned out to the connector
+type Batch struct {
+	Connector   string                 `json:"connector"`
+	Prompt      string                 `json:"prompt,omitempty"`      // The prompt template, the fields of the row are available, e.g. Classify the review: {{ content }}. The input is the prompt if empty
+	System      string                 `json:"system,omitempty"`      // The system prompt
+	Inputs      []interface{}          `json:"inputs,omitempty"`      // The prompts or the rows
+	Query       *BatchProcess          `json:"query,omitempty"`       // The process returns the rows, e.g. {"process": "models.review.Get", "args": [{"wheres": [{"column": "label", "op": "null"}]}]}
+	Output      *BatchOutput           `json:"output,omitempty"`      // Where the results are written to
+	Options     map[string]interface{} `json:"options,omitempty"`     // The chat completions options, e.g. {"temperature": 0}
+	Concurrency int                    `json:"concurrency,omitempty"` // The number of the concurrent requests, 4 by default
+	Retries     int                    `json:"retries,omitempty"`     // The retries of each failed request, 2 by default, -1 to disable
+}
+
+// BatchProcess the process and the args
+type BatchProcess struct {
+	Process string        `json:"process"`
+	Args    []interface{} `json:"args,omitempty"`
+}
+
+// BatchOutput where the results are written to
+type BatchOutput struct {
+	Model   string `json:"model,omitempty"`   // Update the row of the model with the result, the row must have the id
+	Field   string `json:"field,omitempty"`   // The field of the model the result is written to
+	Process string `json:"process,omitempty"` // The process is called with the input and the result of each succeeded item
+	File    string `json:"file,omitempty"`    // Write the results to the file of the data filesystem as JSON lines
+}
+
+// BatchResult the result of the item
+type BatchResult struct {
+	Index    int         `json:"index"`
+	Input    interface{} `json:"input"`
+	Output   string      `json:"output,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Attempts int         `json:"attempts"`
+}
+
+// batchComplete the chat completions of the messages
+type batchComplete func(ctx context.Context, messages []map[string]interface{}, options map[string]interface{}) (string, error)
+
+// batchBackoff the wait before the first retry, doubled for each retry
+var batchBackoff = time.Second
+
+// Run run the batch, the failed items are returned with the error instead of failing the batch
+func (batch *Batch) Run(ctx context.Context) ([]BatchResult, error) {
+	ai, err := New(batch.Connector)
+	if err != nil {
+		return nil, err
+	}
+
+	return batch.run(ctx, func(ctx context.Context, messages []map[string]interface{}, options map[string]interface{}) (string, error) {
+		res, ext := ai.ChatCompletionsWith(ctx, messages, options, nil)
+		if ext != nil {
+			return "", fmt.Errorf("%s", ext.Message)
+		}
+		content, ext := ai.GetContent(res)
+		if ext != nil {
+			return "", fmt.Errorf("%s", ext.Message)
+		}
+		return content, nil
+	})
+}
+
+func (batch *Batch) run(ctx context.Context, complete batchComplete) ([]BatchResult, error) {
+	inputs, err := batch.inputs()
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := batch.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	retries := batch.Retries
+	if retries < 0 {
+		retries = 0
+	} else if retries == 0 {
+		retries = 2
+	}
+
+	results := make([]BatchResult, len(inputs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, input interface{}) {
+			defer func() { <-sem; wg.Done() }()
+			results[i] = batch.item(ctx, complete, i, input, retries)
+		}(i, input)
+	}
+	wg.Wait()
+
+	return results, batch.write(results)
+}
+
+// inputs the inputs of the batch, the rows of the query are appended to the inputs
+func (batch *Batch) inputs() ([]interface{}, error) {
+	inputs := append([]interface{}{}, batch.Inputs...)
+	if batch.Query != nil && batch.Query.Process != "" {
+		p, err := process.Of(batch.Query.Process, batch.Query.Args...)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := p.Exec()
+		if err != nil {
+			return nil, fmt.Errorf("query %s error: %s", batch.Query.Process, err.Error())
+		}
+
+		// The paginated result, e.g. models.*.Paginate
+		if data, ok := res.(map[string]interface{}); ok {
+			res = data["data"]
+		}
+
+		rows, ok := res.([]interface{})
+		if !ok {
+			raw, err := jsoniter.Marshal(res)
+			if err != nil || jsoniter.Unmarshal(raw, &rows) != nil {
+				return nil, fmt.Errorf("query %s does not return the rows", batch.Query.Process)
+			}
+		}
+		inputs = append(inputs, rows...)
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("the inputs of the batch are empty")
+	}
+	return inputs, nil
+}
+
+// item run the item with the retries
+func (batch *Batch) item(ctx context.Context, complete batchComplete, index int, input interface{}, retries int) BatchResult {
+	res := BatchResult{Index: index, Input: input}
+	prompt, err := batch.prompt(input)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	messages := []map[string]interface{}{}
+	if batch.System != "" {
+		messages = append(messages, map[string]interface{}{"role": "system", "content": batch.System})
+	}
+	messages = append(messages, map[string]interface{}{"role": "user", "content": prompt})
+
+	backoff := batchBackoff
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				res.Error = ctx.Err().Error()
+				return res
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		// The options are changed by the request, copy them for each request
+		options := map[string]interface{}{}
+		for key, value := range batch.Options {
+			options[key] = value
+		}
+
+		res.Attempts++
+		output, err := complete(ctx, messages, options)
+		if err == nil {
+			res.Output = output
+			res.Error = ""
+			return res
+		}
+		res.Error = err.Error()
+		log.Warn("[OpenAI] batch item %d attempt %d: %s", index, res.Attempts, err.Error())
+	}
+	return res
+}
+
+// prompt render the prompt of the input
+func (batch *Batch) prompt(input interface{}) (string, error) {
+	if batch.Prompt == "" {
+		if text, ok := input.(string); ok {
+			return text, nil
+		}
+		raw, err := jsoniter.MarshalToString(input)
+		if err != nil {
+			return "", err
+		}
+		return raw, nil
+	}
+
+	data := map[string]interface{}{"input": input}
+	if row, ok := input.(map[string]interface{}); ok {
+		data = maps.Of(row).Dot()
+		data["input"] = row
+	}
+
+	text, ok := helper.Bind(batch.Prompt, data).(string)
+	if !ok {
+		return "", fmt.Errorf("the prompt is not a string")
+	}
+	return text, nil
+}
+
+// write write the results to the output
+func (batch *Batch) write(results []BatchResult) error {
+	if batch.Output == nil {
+		return nil
+	}
+
+	output := batch.Output
+	messages := []string{}
+	for _, res := range results {
+		if res.Error != "" {
+			continue
+		}
+
+		if output.Model != "" && output.Field != "" {
+			row, _ := res.Input.(map[string]interface{})
+			id, has := row["id"]
+			if !has {
+				messages = append(messages, fmt.Sprintf("item %d: the row has no id", res.Index))
+				continue
+			}
+
+			p, err := process.Of(fmt.Sprintf("models.%s.Update", output.Model), id, map[string]interface{}{output.Field: res.Output})
+			if err == nil {
+				_, err = p.Exec()
+			}
+			if err != nil {
+				messages = append(messages, fmt.Sprintf("item %d: %s", res.Index, err.Error()))
+			}
+		}
+
+		if output.Process != "" {
+			p, err := process.Of(output.Process, res.Input, res.Output)
+			if err == nil {
+				_, err = p.Exec()
+			}
+			if err != nil {
+				messages = append(messages, fmt.Sprintf("item %d: %s", res.Index, err.Error()))
+			}
+		}
+	}
+
+	if output.File != "" {
+		lines := make([]string, 0, len(results))
+		for _, res := range results {
+			line, err := jsoniter.MarshalToString(res)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, line)
+		}
+
+		data, err := fs.Get("data")
+		if err != nil {
+			return err
+		}
+		_, err = data.WriteFile(output.File, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(messages) > 0 {
+		return fmt.Errorf("write the results error: %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// BatchSummary the summary of the results
+func BatchSummary(results []BatchResult) map[string]interface{} {
+	failed := 0
+	for _, res := range results {
+		if res.Error != "" {
+			failed++
+		}
+	}
+	return map[string]interface{}{
+		"total":     len(results),
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+		"results":   results,
+	}
+}
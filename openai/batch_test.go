@@ -0,0 +1,81 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchRun(t *testing.T) {
+	batchBackoff = time.Millisecond
+	defer func() { batchBackoff = time.Second }()
+
+	var running, peak, calls int32
+	batch := &Batch{
+		System:      "Answer yes or no",
+		Inputs:      []interface{}{"a", "b", "fail", "flaky", "e"},
+		Concurrency: 2,
+		Options:     map[string]interface{}{"temperature": 0},
+	}
+
+	flaky := int32(0)
+	results, err := batch.run(context.Background(), func(ctx context.Context, messages []map[string]interface{}, options map[string]interface{}) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		options["stream"] = false
+		assert.Equal(t, "Answer yes or no", messages[0]["content"])
+		prompt := messages[1]["content"].(string)
+		switch {
+		case prompt == "fail":
+			return "", fmt.Errorf("server error")
+		case prompt == "flaky" && atomic.AddInt32(&flaky, 1) == 1:
+			return "", fmt.Errorf("rate limited")
+		}
+		return "yes:" + prompt, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, results, 5)
+	assert.LessOrEqual(t, peak, int32(2))
+	assert.Equal(t, "yes:a", results[0].Output)
+	assert.Equal(t, "server error", results[2].Error)
+	assert.Equal(t, 3, results[2].Attempts)
+	assert.Equal(t, "yes:flaky", results[3].Output)
+	assert.Equal(t, 2, results[3].Attempts)
+	assert.Equal(t, int32(8), calls)
+	assert.Nil(t, batch.Options["stream"])
+
+	summary := BatchSummary(results)
+	assert.Equal(t, 4, summary["succeeded"])
+	assert.Equal(t, 1, summary["failed"])
+
+	_, err = (&Batch{}).run(context.Background(), nil)
+	assert.Error(t, err)
+}
+
+func TestBatchPrompt(t *testing.T) {
+	batch := &Batch{Prompt: "Classify the review: {{ content }}"}
+	prompt, err := batch.prompt(map[string]interface{}{"id": 1, "content": "Great!"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Classify the review: Great!", prompt)
+
+	batch = &Batch{}
+	prompt, err = batch.prompt(map[string]interface{}{"id": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1}`, prompt)
+}
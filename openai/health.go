@@ -0,0 +1,205 @@
+package openai
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/yao/timeseries"
+)
+
+// The status of the connector
+const (
+	HealthOK       = "healthy"
+	HealthCooldown = "cooldown"
+)
+
+// CooldownFailures the consecutive failures to cool down the connector
+var CooldownFailures = 3
+
+// CooldownErrorRate the error rate of the recent requests to cool down the connector
+var CooldownErrorRate = 0.5
+
+// CooldownDuration how long the connector is cooling down, the requests fail fast during the cooldown
+var CooldownDuration = 60 * time.Second
+
+// healthWindow the number of the recent requests the error rate and the latency are calculated on
+const healthWindow = 50
+
+// healthMinRequests the minimum recent requests to cool down the connector by the error rate
+const healthMinRequests = 10
+
+// Health the health of the connector
+type Health struct {
+	Connector     string     `json:"connector"`
+	Status        string     `json:"status"`
+	Requests      int64      `json:"requests"`   // The total requests
+	Errors        int64      `json:"errors"`     // The total failures
+	ErrorRate     float64    `json:"error_rate"` // The error rate of the recent requests
+	Latency       int64      `json:"latency"`    // The average latency of the recent requests in milliseconds, the time to the first chunk for the stream
+	Failures      int        `json:"failures"`   // The consecutive failures
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	CooldownUntil *time.Time `json:"cooldown_until,omitempty"`
+}
+
+type healthSample struct {
+	latency time.Duration
+	failed  bool
+}
+
+type healthStats struct {
+	Health
+	samples []healthSample
+	next    int
+}
+
+var healths = struct {
+	mu   sync.RWMutex
+	data map[string]*healthStats
+}{data: map[string]*healthStats{}}
+
+// record record the result of the request, the code is the status of the response, 200 if succeeded.
+// The connector is cooled down if it keeps failing.
+func record(id string, latency time.Duration, code int, message string, now time.Time) {
+	if id == "" {
+		return
+	}
+
+	status := "ok"
+	if isFailure(code) {
+		status = "failed"
+	}
+	timeseries.Add(timeseries.Point{
+		Metric: "connector.latency",
+		Tags:   map[string]string{"connector": id, "status": status},
+		Value:  float64(latency.Milliseconds()),
+		Time:   now,
+	})
+
+	healths.mu.Lock()
+	defer healths.mu.Unlock()
+
+	stats, has := healths.data[id]
+	if !has {
+		stats = &healthStats{Health: Health{Connector: id}}
+		healths.data[id] = stats
+	}
+
+	failed := isFailure(code)
+	sample := healthSample{latency: latency, failed: failed}
+	if len(stats.samples) < healthWindow {
+		stats.samples = append(stats.samples, sample)
+	} else {
+		stats.samples[stats.next] = sample
+		stats.next = (stats.next + 1) % healthWindow
+	}
+
+	stats.Requests++
+	if !failed {
+		stats.Failures = 0
+		return
+	}
+
+	stats.Errors++
+	stats.Failures++
+	stats.LastError = message
+	stats.LastErrorAt = &now
+
+	rate, _ := stats.recent()
+	if stats.Failures >= CooldownFailures || (len(stats.samples) >= healthMinRequests && rate >= CooldownErrorRate) {
+		until := now.Add(CooldownDuration)
+		stats.CooldownUntil = &until
+
+		// The next failure after the cooldown cools down the connector again
+		stats.Failures = CooldownFailures - 1
+		stats.samples = nil
+		stats.next = 0
+	}
+}
+
+// isFailure whether the error is caused by the connector, the invalid requests are not counted.
+// The code is 0 if there is no response, e.g. the connection is refused.
+func isFailure(code int) bool {
+	switch {
+	case code <= 0, code >= 500, code == 401, code == 403, code == 408, code == 429:
+		return true
+	}
+	return false
+}
+
+// recent the error rate and the average latency of the recent requests
+func (stats *healthStats) recent() (float64, time.Duration) {
+	if len(stats.samples) == 0 {
+		return 0, 0
+	}
+
+	failed := 0
+	var latency time.Duration
+	for _, sample := range stats.samples {
+		latency += sample.latency
+		if sample.failed {
+			failed++
+		}
+	}
+	return float64(failed) / float64(len(stats.samples)), latency / time.Duration(len(stats.samples))
+}
+
+// health the snapshot of the health
+func (stats *healthStats) health(now time.Time) Health {
+	health := stats.Health
+	health.Status = HealthOK
+	if health.CooldownUntil != nil && now.Before(*health.CooldownUntil) {
+		health.Status = HealthCooldown
+	} else {
+		health.CooldownUntil = nil
+	}
+
+	rate, latency := stats.recent()
+	health.ErrorRate = rate
+	health.Latency = latency.Milliseconds()
+	return health
+}
+
+// Healthy whether the connector is available, false if it is cooling down
+func Healthy(id string) bool {
+	healths.mu.RLock()
+	defer healths.mu.RUnlock()
+	stats, has := healths.data[id]
+	if !has {
+		return true
+	}
+	return stats.health(time.Now()).Status == HealthOK
+}
+
+// HealthOf get the health of the connector
+func HealthOf(id string) Health {
+	healths.mu.RLock()
+	defer healths.mu.RUnlock()
+	stats, has := healths.data[id]
+	if !has {
+		return Health{Connector: id, Status: HealthOK}
+	}
+	return stats.health(time.Now())
+}
+
+// Healths get the health of the connectors have been requested, sorted by the connector
+func Healths() []Health {
+	healths.mu.RLock()
+	defer healths.mu.RUnlock()
+
+	now := time.Now()
+	res := make([]Health, 0, len(healths.data))
+	for _, stats := range healths.data {
+		res = append(res, stats.health(now))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Connector < res[j].Connector })
+	return res
+}
+
+// ResetHealth clear the health of the connector, ends the cooldown
+func ResetHealth(id string) {
+	healths.mu.Lock()
+	defer healths.mu.Unlock()
+	delete(healths.data, id)
+}
@@ -0,0 +1,63 @@
+package openai
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCooldown(t *testing.T) {
+	id := "unit.test.health.cooldown"
+	defer ResetHealth(id)
+
+	now := time.Now()
+	record(id, 100*time.Millisecond, 200, "", now)
+	record(id, 300*time.Millisecond, 400, "invalid request", now)
+	record(id, 200*time.Millisecond, 500, "server error", now)
+	record(id, 200*time.Millisecond, 0, "connection refused", now)
+
+	health := HealthOf(id)
+	assert.Equal(t, HealthOK, health.Status)
+	assert.Equal(t, int64(4), health.Requests)
+	assert.Equal(t, int64(2), health.Errors)
+	assert.Equal(t, 2, health.Failures)
+	assert.Equal(t, 0.5, health.ErrorRate)
+	assert.Equal(t, int64(200), health.Latency)
+	assert.Equal(t, "connection refused", health.LastError)
+
+	record(id, 200*time.Millisecond, 429, "rate limited", now)
+	assert.False(t, Healthy(id))
+	assert.Equal(t, HealthCooldown, HealthOf(id).Status)
+
+	// The cooldown ends, the next failure cools down the connector again
+	stats := healths.data[id]
+	until := now.Add(-time.Second)
+	stats.CooldownUntil = &until
+	assert.True(t, Healthy(id))
+	assert.Nil(t, HealthOf(id).CooldownUntil)
+
+	record(id, 200*time.Millisecond, 503, "unavailable", now)
+	assert.False(t, Healthy(id))
+
+	ResetHealth(id)
+	assert.True(t, Healthy(id))
+}
+
+func TestHealthErrorRate(t *testing.T) {
+	id := "unit.test.health.rate"
+	defer ResetHealth(id)
+
+	now := time.Now()
+	for i := 0; i < healthMinRequests; i++ {
+		code := 200
+		if i%2 == 1 {
+			code = 500
+		}
+		record(id, time.Millisecond, code, "server error", now)
+	}
+	assert.False(t, Healthy(id))
+
+	healths := Healths()
+	assert.Equal(t, id, healths[len(healths)-1].Connector)
+}
@@ -0,0 +1,157 @@
+package openai
+
+import (
+	"fmt"
+	"sort"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/http"
+	"github.com/yaoapp/kun/exception"
+)
+
+// The providers of the OpenAI compatible connectors. The local inference servers serve the OpenAI compatible API,
+// declare them as the openai connector with the provider option, the key and the host are optional, e.g.
+// {"type": "openai", "options": {"provider": "ollama", "model": "llama3.1", "capabilities": {"vision": false}}}
+const (
+	ProviderOpenAI   = "openai"
+	ProviderOllama   = "ollama"
+	ProviderLlamaCpp = "llamacpp"
+)
+
+// The default hosts of the local inference servers
+var localHosts = map[string]string{
+	ProviderOllama:   "http://127.0.0.1:11434",
+	ProviderLlamaCpp: "http://127.0.0.1:8080",
+}
+
+// Capabilities the capabilities of the connector
+type Capabilities struct {
+	Vision bool `json:"vision"` // The model accepts the images
+	Tools  bool `json:"tools"`  // The model accepts the tools, the functions of the assistant are dropped if not
+	Stream bool `json:"stream"` // The server streams the response, the response is replayed as the stream if not
+}
+
+// capabilitiesOf the default capabilities overridden by the setting, e.g. "capabilities": {"vision": true}
+func capabilitiesOf(setting interface{}) Capabilities {
+	capabilities := Capabilities{Tools: true, Stream: true}
+	values, ok := setting.(map[string]interface{})
+	if !ok {
+		return capabilities
+	}
+
+	if v, ok := values["vision"].(bool); ok {
+		capabilities.Vision = v
+	}
+	if v, ok := values["tools"].(bool); ok {
+		capabilities.Tools = v
+	}
+	if v, ok := values["stream"].(bool); ok {
+		capabilities.Stream = v
+	}
+	return capabilities
+}
+
+// Provider get the provider of the connector
+func (openai OpenAI) Provider() string {
+	return openai.provider
+}
+
+// Local whether the connector is a local inference server
+func (openai OpenAI) Local() bool {
+	_, has := localHosts[openai.provider]
+	return has
+}
+
+// Capabilities get the capabilities of the connector
+func (openai OpenAI) Capabilities() Capabilities {
+	return openai.capabilities
+}
+
+// Models list the models served by the connector, the local inference servers list the pulled or loaded models
+// https://platform.openai.com/docs/api-reference/models/list
+func (openai OpenAI) Models() ([]string, *exception.Exception) {
+	if openai.mock != nil {
+		return []string{openai.model}, nil
+	}
+
+	url := fmt.Sprintf("%s/v1/models", openai.host)
+	req := http.New(url).
+		WithHeader(map[string][]string{"Authorization": {fmt.Sprintf("Bearer %s", openai.key)}})
+
+	res := req.Get()
+	if err := openai.isError(res); err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	raw, err := jsoniter.Marshal(res.Data)
+	if err != nil {
+		return nil, exception.New(err.Error(), 500)
+	}
+	if err := jsoniter.Unmarshal(raw, &data); err != nil {
+		return nil, exception.New("The models response is invalid: %s", 500, err.Error())
+	}
+
+	models := make([]string, 0, len(data.Data))
+	for _, model := range data.Data {
+		models = append(models, model.ID)
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// replay replay the chat completions response as the stream, for the servers do not stream
+func replay(res interface{}, cb func(data []byte) int) {
+	data, _ := res.(map[string]interface{})
+	choices, _ := data["choices"].([]interface{})
+	if len(choices) == 0 {
+		return
+	}
+
+	choice, _ := choices[0].(map[string]interface{})
+	message, _ := choice["message"].(map[string]interface{})
+	reason, _ := choice["finish_reason"].(string)
+	if reason == "" {
+		reason = "stop"
+	}
+
+	delta := map[string]interface{}{"role": "assistant"}
+	if content, ok := message["content"].(string); ok {
+		delta["content"] = content
+	}
+	if toolCalls, ok := message["tool_calls"].([]interface{}); ok && len(toolCalls) > 0 {
+		calls := make([]interface{}, 0, len(toolCalls))
+		for i, call := range toolCalls {
+			if call, ok := call.(map[string]interface{}); ok {
+				call["index"] = i
+				calls = append(calls, call)
+			}
+		}
+		delta["tool_calls"] = calls
+	}
+
+	chunk := func(delta map[string]interface{}, reason interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"id":      data["id"],
+			"object":  "chat.completion.chunk",
+			"created": data["created"],
+			"model":   data["model"],
+			"choices": []interface{}{map[string]interface{}{"index": 0, "delta": delta, "finish_reason": reason}},
+		}
+	}
+
+	for _, value := range []map[string]interface{}{chunk(delta, nil), chunk(map[string]interface{}{}, reason)} {
+		raw, err := jsoniter.Marshal(value)
+		if err != nil {
+			return
+		}
+		if cb([]byte(fmt.Sprintf("data: %s", raw))) == 0 {
+			return
+		}
+	}
+	cb([]byte("data: [DONE]"))
+}
@@ -0,0 +1,68 @@
+package openai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilities(t *testing.T) {
+	assert.Equal(t, Capabilities{Tools: true, Stream: true}, capabilitiesOf(nil))
+	assert.Equal(t, Capabilities{Vision: true, Tools: false, Stream: true}, capabilitiesOf(map[string]interface{}{"vision": true, "tools": false}))
+
+	ai, err := NewOpenAI(map[string]interface{}{"provider": "ollama", "model": "llama3.1", "capabilities": map[string]interface{}{"stream": false}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "http://127.0.0.1:11434", ai.host)
+	assert.True(t, ai.Local())
+	assert.False(t, ai.Capabilities().Stream)
+
+	ai, err = NewOpenAI(map[string]interface{}{"provider": "llamacpp", "host": "http://10.0.0.2:8080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "http://10.0.0.2:8080", ai.host)
+
+	ai, err = NewOpenAI(map[string]interface{}{"key": "xxx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ProviderOpenAI, ai.Provider())
+	assert.False(t, ai.Local())
+}
+
+func TestReplay(t *testing.T) {
+	lines := []string{}
+	replay(map[string]interface{}{
+		"id":    "chatcmpl_1",
+		"model": "llama3.1",
+		"choices": []interface{}{map[string]interface{}{
+			"finish_reason": "tool_calls",
+			"message": map[string]interface{}{
+				"role": "assistant",
+				"tool_calls": []interface{}{map[string]interface{}{
+					"id": "call_1", "type": "function",
+					"function": map[string]interface{}{"name": "weather", "arguments": `{"city":"Paris"}`},
+				}},
+			},
+		}},
+	}, func(data []byte) int {
+		lines = append(lines, string(data))
+		return 1
+	})
+
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"tool_calls":[{`)
+	assert.Contains(t, lines[0], `"index":0`)
+	assert.Contains(t, lines[1], `"finish_reason":"tool_calls"`)
+	assert.Equal(t, "data: [DONE]", lines[2])
+
+	lines = []string{}
+	replay(map[string]interface{}{"choices": []interface{}{map[string]interface{}{"message": map[string]interface{}{"content": "Hi"}}}}, func(data []byte) int {
+		lines = append(lines, string(data))
+		return 0
+	})
+	assert.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `"content":"Hi"`)
+}
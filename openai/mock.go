@@ -0,0 +1,258 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/kun/exception"
+)
+
+// ProviderMock the mock provider, the responses are scripted by the fixtures and no request is sent. The fixtures are
+// the responses of the options or the JSON or the YAML file of the application, e.g.
+// {"type": "openai", "options": {"provider": "mock", "model": "mock", "fixtures": "mocks/support.yml"}}
+const ProviderMock = "mock"
+
+// MockResponse the scripted response, the first response matched by the last message is returned.
+// The response without the match and the role matches any message.
+type MockResponse struct {
+	Match     string         `json:"match,omitempty"`      // The regular expression of the content of the last message
+	Role      string         `json:"role,omitempty"`       // The role of the last message, e.g. user or tool
+	Content   string         `json:"content,omitempty"`    // The content of the reply
+	Chunks    []string       `json:"chunks,omitempty"`     // The chunks streamed, the content split by the words if not set
+	ToolCalls []MockToolCall `json:"tool_calls,omitempty"` // The tools called
+	Error     string         `json:"error,omitempty"`      // The error returned instead of the reply
+	Status    int            `json:"status,omitempty"`     // The status of the error, 500 by default
+	pattern   *regexp.Regexp
+}
+
+// MockToolCall the tool called by the mock response, the arguments are the JSON string or the value encoded
+type MockToolCall struct {
+	ID        string      `json:"id,omitempty"`
+	Name      string      `json:"name"`
+	Arguments interface{} `json:"arguments,omitempty"`
+}
+
+type mock struct {
+	responses []MockResponse
+}
+
+// mockJSON the JSON of the mock sorts the keys of the maps, the chunks are the same for the same fixtures
+var mockJSON = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// mockOf the mock of the setting, the responses of the setting are followed by the responses of the fixtures
+func mockOf(setting map[string]interface{}) (*mock, error) {
+	responses := []MockResponse{}
+	if v, has := setting["responses"]; has {
+		raw, err := jsoniter.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := jsoniter.Unmarshal(raw, &responses); err != nil {
+			return nil, fmt.Errorf("the responses of the mock connector are invalid: %s", err.Error())
+		}
+	}
+
+	if file, ok := setting["fixtures"].(string); ok && file != "" {
+		data, err := application.App.Read(file)
+		if err != nil {
+			return nil, err
+		}
+
+		fixtures := []MockResponse{}
+		if err := application.Parse(file, data, &fixtures); err != nil {
+			return nil, fmt.Errorf("the fixtures %s of the mock connector are invalid: %s", file, err.Error())
+		}
+		responses = append(responses, fixtures...)
+	}
+
+	for i := range responses {
+		if responses[i].Match == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(responses[i].Match)
+		if err != nil {
+			return nil, fmt.Errorf("the match of the mock response %d is invalid: %s", i, err.Error())
+		}
+		responses[i].pattern = pattern
+	}
+	return &mock{responses: responses}, nil
+}
+
+// respond the response matched by the last message of the payload, the index is the index of the response
+func (m *mock) respond(payload map[string]interface{}) (*MockResponse, int, *exception.Exception) {
+	role, content := lastMessage(payload)
+	for i, res := range m.responses {
+		if res.Role != "" && res.Role != role {
+			continue
+		}
+		if res.pattern != nil && !res.pattern.MatchString(content) {
+			continue
+		}
+
+		if res.Error != "" {
+			status := res.Status
+			if status == 0 {
+				status = 500
+			}
+			return nil, i, exception.New(res.Error, status)
+		}
+		return &m.responses[i], i, nil
+	}
+	return nil, -1, exception.New("No mock response matches the %s message: %s", 404, role, content)
+}
+
+// post the chat completions response of the matched response
+func (m *mock) post(path string, payload map[string]interface{}) (interface{}, *exception.Exception) {
+	if path != "/v1/chat/completions" {
+		return nil, exception.New("The mock connector does not support %s", 400, path)
+	}
+
+	res, i, err := m.respond(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	message := map[string]interface{}{"role": "assistant", "content": res.Content}
+	reason := "stop"
+	if len(res.ToolCalls) > 0 {
+		message["tool_calls"] = res.toolCalls(false)
+		reason = "tool_calls"
+	}
+
+	// The response is decoded as the response of the server
+	raw, _ := jsoniter.Marshal(map[string]interface{}{
+		"id":      fmt.Sprintf("chatcmpl-mock-%d", i),
+		"object":  "chat.completion",
+		"model":   payload["model"],
+		"choices": []interface{}{map[string]interface{}{"index": 0, "message": message, "finish_reason": reason}},
+	})
+	var data interface{}
+	jsoniter.Unmarshal(raw, &data)
+	return data, nil
+}
+
+// stream stream the chunks of the matched response
+func (m *mock) stream(ctx context.Context, path string, payload map[string]interface{}, cb func(data []byte) int) *exception.Exception {
+	if path != "/v1/chat/completions" {
+		return exception.New("The mock connector does not support %s", 400, path)
+	}
+
+	res, i, err := m.respond(payload)
+	if err != nil {
+		return err
+	}
+
+	id := fmt.Sprintf("chatcmpl-mock-%d", i)
+	send := func(delta map[string]interface{}, reason interface{}) bool {
+		if ctx != nil && ctx.Err() != nil {
+			return false
+		}
+		raw, _ := mockJSON.Marshal(map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"model":   payload["model"],
+			"choices": []interface{}{map[string]interface{}{"index": 0, "delta": delta, "finish_reason": reason}},
+		})
+		return cb([]byte(fmt.Sprintf("data: %s", raw))) != 0
+	}
+
+	for _, chunk := range res.chunks() {
+		if !send(map[string]interface{}{"role": "assistant", "content": chunk}, nil) {
+			return nil
+		}
+	}
+
+	reason := "stop"
+	if len(res.ToolCalls) > 0 {
+		reason = "tool_calls"
+		for _, call := range res.toolCalls(true) {
+			if !send(map[string]interface{}{"role": "assistant", "tool_calls": []interface{}{call}}, nil) {
+				return nil
+			}
+		}
+	}
+
+	if send(map[string]interface{}{}, reason) {
+		cb([]byte("data: [DONE]"))
+	}
+	return nil
+}
+
+// chunks the chunks of the content
+func (res *MockResponse) chunks() []string {
+	if len(res.Chunks) > 0 {
+		return res.Chunks
+	}
+	if res.Content == "" {
+		return []string{}
+	}
+	return strings.SplitAfter(res.Content, " ")
+}
+
+// toolCalls the tool calls of the response, the indexes are set for the stream
+func (res *MockResponse) toolCalls(indexed bool) []interface{} {
+	calls := make([]interface{}, 0, len(res.ToolCalls))
+	for i, call := range res.ToolCalls {
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i)
+		}
+
+		arguments, ok := call.Arguments.(string)
+		if !ok {
+			arguments = "{}"
+			if call.Arguments != nil {
+				arguments, _ = mockJSON.MarshalToString(call.Arguments)
+			}
+		}
+
+		value := map[string]interface{}{
+			"id":       id,
+			"type":     "function",
+			"function": map[string]interface{}{"name": call.Name, "arguments": arguments},
+		}
+		if indexed {
+			value["index"] = i
+		}
+		calls = append(calls, value)
+	}
+	return calls
+}
+
+// lastMessage the role and the text of the last message of the payload
+func lastMessage(payload map[string]interface{}) (string, string) {
+	raw, err := jsoniter.Marshal(payload["messages"])
+	if err != nil {
+		return "", ""
+	}
+
+	var messages []struct {
+		Role    string      `json:"role"`
+		Content interface{} `json:"content"`
+	}
+	if err := jsoniter.Unmarshal(raw, &messages); err != nil || len(messages) == 0 {
+		return "", ""
+	}
+
+	last := messages[len(messages)-1]
+	switch content := last.Content.(type) {
+	case string:
+		return last.Role, content
+
+	case []interface{}:
+		texts := []string{}
+		for _, part := range content {
+			if part, ok := part.(map[string]interface{}); ok {
+				if text, ok := part["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return last.Role, strings.Join(texts, "\n")
+	}
+	return last.Role, ""
+}
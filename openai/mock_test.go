@@ -0,0 +1,73 @@
+package openai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/kun/exception"
+)
+
+func TestMock(t *testing.T) {
+	ai, err := NewOpenAI(map[string]interface{}{
+		"provider": "mock",
+		"model":    "mock",
+		"responses": []interface{}{
+			map[string]interface{}{"role": "tool", "content": "The order 1 is refunded."},
+			map[string]interface{}{"match": "(?i)refund", "tool_calls": []interface{}{
+				map[string]interface{}{"name": "scripts_order_Refund", "arguments": map[string]interface{}{"id": 1}},
+			}},
+			map[string]interface{}{"match": "fail", "error": "rate limited", "status": 429},
+			map[string]interface{}{"match": "^Hello", "chunks": []interface{}{"Hi, ", "how can I help?"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ProviderMock, ai.Provider())
+
+	stream := func(messages ...map[string]interface{}) ([]string, *exception.Exception) {
+		lines := []string{}
+		_, err := ai.ChatCompletionsWith(context.Background(), messages, nil, func(data []byte) int {
+			lines = append(lines, string(data))
+			return 1
+		})
+		return lines, err
+	}
+
+	lines, ext := stream(map[string]interface{}{"role": "user", "content": "Hello"})
+	assert.Nil(t, ext)
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines[0], `"content":"Hi, "`)
+	assert.Contains(t, lines[2], `"finish_reason":"stop"`)
+	assert.Equal(t, "data: [DONE]", lines[3])
+
+	// The tool calls then the reply of the tool results
+	lines, ext = stream(map[string]interface{}{"role": "user", "content": []interface{}{map[string]interface{}{"type": "text", "text": "Please refund the order 1"}}})
+	assert.Nil(t, ext)
+	assert.Equal(t, `data: {"choices":[{"delta":{"role":"assistant","tool_calls":[{"function":{"arguments":"{\"id\":1}","name":"scripts_order_Refund"},"id":"call_0","index":0,"type":"function"}]},"finish_reason":null,"index":0}],"id":"chatcmpl-mock-1","model":"mock","object":"chat.completion.chunk"}`, lines[0])
+	assert.Contains(t, lines[0], `"arguments":"{\"id\":1}"`)
+	assert.Contains(t, lines[0], `"index":0`)
+	assert.Contains(t, lines[1], `"finish_reason":"tool_calls"`)
+
+	res, ext := ai.ChatCompletions([]map[string]interface{}{
+		{"role": "user", "content": "Please refund the order 1"},
+		{"role": "tool", "content": `{"refunded": true}`, "tool_call_id": "call_0"},
+	}, nil, nil)
+	assert.Nil(t, ext)
+	content, ext := ai.GetContent(res)
+	assert.Nil(t, ext)
+	assert.Equal(t, "The order 1 is refunded.", content)
+
+	_, ext = stream(map[string]interface{}{"role": "user", "content": "fail"})
+	assert.Equal(t, 429, ext.Code)
+	_, ext = stream(map[string]interface{}{"role": "user", "content": "Bye"})
+	assert.Equal(t, 404, ext.Code)
+
+	models, ext := ai.Models()
+	assert.Nil(t, ext)
+	assert.Equal(t, []string{"mock"}, models)
+
+	_, err = NewOpenAI(map[string]interface{}{"provider": "mock", "responses": []interface{}{map[string]interface{}{"match": "("}}})
+	assert.Error(t, err)
+}
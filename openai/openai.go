@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/pkoukk/tiktoken-go"
 	"github.com/yaoapp/gou/connector"
@@ -25,11 +26,15 @@ func Tiktoken(model string, input string) (int, error) {
 
 // OpenAI struct
 type OpenAI struct {
+	id           string // The connector id, used to track the health
 	key          string
 	model        string
 	host         string
 	organization string
 	maxToken     int
+	provider     string
+	capabilities Capabilities
+	mock         *mock // The scripted responses of the mock provider
 }
 
 // New create a new OpenAI instance by connector id
@@ -41,7 +46,12 @@ func New(id string) (*OpenAI, error) {
 		if strings.HasPrefix(id, "moapi:") {
 			model = strings.TrimPrefix(id, "moapi:")
 		}
-		return NewMoapi(model)
+		ai, err := NewMoapi(model)
+		if err != nil {
+			return nil, err
+		}
+		ai.id = "moapi"
+		return ai, nil
 	}
 
 	c, err := connector.Select(id)
@@ -54,7 +64,12 @@ func New(id string) (*OpenAI, error) {
 	}
 
 	setting := c.Setting()
-	return NewOpenAI(setting)
+	ai, err := NewOpenAI(setting)
+	if err != nil {
+		return nil, err
+	}
+	ai.id = id
+	return ai, nil
 }
 
 // NewOpenAI create a new OpenAI instance by setting
@@ -70,7 +85,15 @@ func NewOpenAI(setting map[string]interface{}) (*OpenAI, error) {
 		model = v
 	}
 
+	provider := ProviderOpenAI
+	if v, ok := setting["provider"].(string); ok && v != "" {
+		provider = v
+	}
+
 	host := "https://api.openai.com"
+	if v, has := localHosts[provider]; has {
+		host = v
+	}
 	if v, ok := setting["host"].(string); ok {
 		host = v
 	}
@@ -85,13 +108,24 @@ func NewOpenAI(setting map[string]interface{}) (*OpenAI, error) {
 		maxToken = v
 	}
 
-	return &OpenAI{
+	ai := &OpenAI{
 		key:          key,
 		model:        model,
 		host:         host,
 		organization: organization,
 		maxToken:     maxToken,
-	}, nil
+		provider:     provider,
+		capabilities: capabilitiesOf(setting["capabilities"]),
+	}
+
+	if provider == ProviderMock {
+		m, err := mockOf(setting)
+		if err != nil {
+			return nil, err
+		}
+		ai.mock = m
+	}
+	return ai, nil
 }
 
 // NewMoapi create a new OpenAI instance by model
@@ -124,6 +158,8 @@ func NewMoapi(model string) (*OpenAI, error) {
 		host:         url,
 		organization: organization,
 		maxToken:     16384,
+		provider:     ProviderOpenAI,
+		capabilities: capabilitiesOf(nil),
 	}, nil
 }
 
@@ -174,6 +210,16 @@ func (openai OpenAI) ChatCompletions(messages []map[string]interface{}, option m
 	}
 	option["messages"] = messages
 
+	if cb != nil && !openai.capabilities.Stream {
+		option["stream"] = false
+		res, err := openai.post("/v1/chat/completions", option)
+		if err != nil {
+			return nil, err
+		}
+		replay(res, cb)
+		return nil, nil
+	}
+
 	if cb != nil {
 		option["stream"] = true
 		return nil, openai.stream(context.Background(), "/v1/chat/completions", option, cb)
@@ -191,6 +237,16 @@ func (openai OpenAI) ChatCompletionsWith(ctx context.Context, messages []map[str
 	}
 	option["messages"] = messages
 
+	if cb != nil && !openai.capabilities.Stream {
+		option["stream"] = false
+		res, err := openai.post("/v1/chat/completions", option)
+		if err != nil {
+			return nil, err
+		}
+		replay(res, cb)
+		return nil, nil
+	}
+
 	if cb != nil {
 		option["stream"] = true
 		return nil, openai.stream(ctx, "/v1/chat/completions", option, cb)
@@ -357,12 +413,22 @@ func (openai OpenAI) post(path string, payload map[string]interface{}) (interfac
 	url := fmt.Sprintf("%s%s", openai.host, path)
 	key := fmt.Sprintf("Bearer %s", openai.key)
 	payload["model"] = openai.model
+	if openai.mock != nil {
+		return openai.mock.post(path, payload)
+	}
 
 	req := http.New(url).
 		WithHeader(map[string][]string{"Authorization": {key}})
 
+	if err := openai.available(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
 	res := req.Post(payload)
-	if err := openai.isError(res); err != nil {
+	err := openai.isError(res)
+	openai.record(start, err)
+	if err != nil {
 		return nil, err
 	}
 	return res.Data, nil
@@ -377,8 +443,15 @@ func (openai OpenAI) postWithoutModel(path string, payload map[string]interface{
 	req := http.New(url).
 		WithHeader(map[string][]string{"Authorization": {key}})
 
+	if err := openai.available(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
 	res := req.Post(payload)
-	if err := openai.isError(res); err != nil {
+	err := openai.isError(res)
+	openai.record(start, err)
+	if err != nil {
 		return nil, err
 	}
 	return res.Data, nil
@@ -401,8 +474,15 @@ func (openai OpenAI) postFile(path string, files map[string][]byte, option map[s
 		req.AddFileBytes(name, fmt.Sprintf("%s.mp3", name), data)
 	}
 
+	if err := openai.available(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
 	res := req.Send("POST", option)
-	if err := openai.isError(res); err != nil {
+	err := openai.isError(res)
+	openai.record(start, err)
+	if err != nil {
 		return nil, err
 	}
 	return res.Data, nil
@@ -424,8 +504,15 @@ func (openai OpenAI) postFileWithoutModel(path string, files map[string][]byte,
 		req.AddFileBytes(name, fmt.Sprintf("%s.mp3", name), data)
 	}
 
+	if err := openai.available(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
 	res := req.Send("POST", option)
-	if err := openai.isError(res); err != nil {
+	err := openai.isError(res)
+	openai.record(start, err)
+	if err != nil {
 		return nil, err
 	}
 	return res.Data, nil
@@ -436,20 +523,66 @@ func (openai OpenAI) stream(ctx context.Context, path string, payload map[string
 	url := fmt.Sprintf("%s%s", openai.host, path)
 	key := fmt.Sprintf("Bearer %s", openai.key)
 	payload["model"] = openai.model
+	if openai.mock != nil {
+		return openai.mock.stream(ctx, path, payload, cb)
+	}
+	if ext := openai.available(); ext != nil {
+		return ext
+	}
+
+	// The latency of the stream is the time to the first chunk, the upstream error is not a data line
+	start := time.Now()
+	first := true
 	req := http.New(url)
 	err := req.
 		WithHeader(map[string][]string{
 			"Content-Type":  {"application/json; charset=utf-8"},
 			"Authorization": {key},
 		}).
-		Stream(ctx, "POST", payload, cb)
+		Stream(ctx, "POST", payload, func(data []byte) int {
+			if first && len(strings.TrimSpace(string(data))) > 0 {
+				first = false
+				if line := strings.TrimSpace(string(data)); !strings.HasPrefix(line, "data:") {
+					openai.record(start, exception.New(line, 500))
+				} else {
+					openai.record(start, nil)
+				}
+			}
+			return cb(data)
+		})
 
 	if err != nil {
-		return exception.New(err.Error(), 500)
+		ext := exception.New(err.Error(), 500)
+		if first {
+			openai.record(start, ext)
+		}
+		return ext
 	}
 	return nil
 }
 
+// available fail fast if the connector is cooling down
+func (openai OpenAI) available() *exception.Exception {
+	if openai.id == "" {
+		return nil
+	}
+
+	health := HealthOf(openai.id)
+	if health.Status != HealthCooldown || health.CooldownUntil == nil {
+		return nil
+	}
+	return exception.New("The connector %s is cooling down until %s: %s", 503, openai.id, health.CooldownUntil.Format(time.RFC3339), health.LastError)
+}
+
+// record record the result of the request to the health of the connector
+func (openai OpenAI) record(start time.Time, err *exception.Exception) {
+	if err == nil {
+		record(openai.id, time.Since(start), 200, "", time.Now())
+		return
+	}
+	record(openai.id, time.Since(start), err.Code, err.Message, time.Now())
+}
+
 func (openai OpenAI) isError(res *http.Response) *exception.Exception {
 
 	if res.Status != 200 {
@@ -3,6 +3,7 @@ package openai
 import (
 	"context"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/http"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/gou/runtime/v8/bridge"
@@ -16,6 +17,8 @@ func init() {
 		"embeddings":           ProcessEmbeddings,
 		"chat.completions":     ProcessChatCompletions,
 		"audio.transcriptions": ProcessAudioTranscriptions,
+		"models":               ProcessModels,
+		"batch":                ProcessBatch,
 	})
 }
 
@@ -53,6 +56,52 @@ func ProcessEmbeddings(process *process.Process) interface{} {
 	return res
 }
 
+// ProcessModels openai.Models
+func ProcessModels(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	ai, err := New(process.ArgsString(0))
+	if err != nil {
+		exception.New("Models error: %s", 400, err).Throw()
+	}
+
+	models, ex := ai.Models()
+	if ex != nil {
+		ex.Throw()
+	}
+	return models
+}
+
+// ProcessBatch openai.Batch
+func ProcessBatch(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	raw, err := jsoniter.Marshal(process.Args[0])
+	if err != nil {
+		exception.New("Batch error: %s", 400, err).Throw()
+	}
+
+	var batch Batch
+	err = jsoniter.Unmarshal(raw, &batch)
+	if err != nil {
+		exception.New("Batch error: %s", 400, err).Throw()
+	}
+
+	ctx := process.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	results, err := batch.Run(ctx)
+	if results == nil && err != nil {
+		exception.New("Batch error: %s", 400, err).Throw()
+	}
+
+	summary := BatchSummary(results)
+	if err != nil {
+		summary["error"] = err.Error()
+	}
+	return summary
+}
+
 // ProcessAudioTranscriptions openai.audio.Transcriptions
 func ProcessAudioTranscriptions(process *process.Process) interface{} {
 	process.ValidateArgNums(2)
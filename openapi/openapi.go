@@ -0,0 +1,359 @@
+package openapi
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/api"
+	"github.com/yaoapp/gou/model"
+	yaoapi "github.com/yaoapp/yao/api"
+	"github.com/yaoapp/yao/dts"
+	"github.com/yaoapp/yao/share"
+)
+
+// columnTypes the schema types of the model column types, string by default
+var columnTypes = map[string]string{
+	"tinyInteger": "integer", "tinyIncrements": "integer", "unsignedTinyInteger": "integer",
+	"smallInteger": "integer", "smallIncrements": "integer", "unsignedSmallInteger": "integer",
+	"integer": "integer", "increments": "integer", "unsignedInteger": "integer",
+	"bigInteger": "integer", "bigIncrements": "integer", "unsignedBigInteger": "integer",
+	"id": "integer", "ID": "integer", "year": "integer",
+	"decimal": "number", "unsignedDecimal": "number",
+	"float": "number", "unsignedFloat": "number",
+	"double": "number", "unsignedDouble": "number",
+	"boolean": "boolean",
+	"json":    "", "JSON": "", "jsonb": "", "JSONB": "",
+}
+
+// Generate the OpenAPI document of the loaded APIs
+func Generate() *Spec {
+	spec := &Spec{
+		OpenAPI: "3.1.0",
+		Info:    Info{Title: share.App.Name, Version: share.App.Version, Description: share.App.Description},
+		Paths:   map[string]map[string]*Operation{},
+		Components: Components{
+			Schemas:         map[string]interface{}{},
+			SecuritySchemes: map[string]SecurityScheme{},
+		},
+	}
+
+	if spec.Info.Title == "" {
+		spec.Info.Title = "Yao Application"
+	}
+	if spec.Info.Version == "" {
+		spec.Info.Version = "1.0.0"
+	}
+
+	ids := make([]string, 0, len(api.APIs))
+	for id := range api.APIs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	models := map[string]bool{}
+	for _, id := range ids {
+		inst := api.APIs[id]
+		for _, path := range inst.HTTP.Paths {
+			ginRoute := filepath.ToSlash(filepath.Join("/api", inst.HTTP.Group, path.Path))
+			route, params := Route(ginRoute)
+			method := strings.ToLower(path.Method)
+
+			op := &Operation{
+				Summary:     path.Label,
+				Description: path.Description,
+				OperationID: fmt.Sprintf("%s.%s.%s", id, method, strings.Trim(strings.ReplaceAll(path.Path, "/", "."), ".")),
+				Tags:        []string{id},
+				Parameters:  []*Parameter{},
+				Responses:   map[string]*Response{},
+				Process:     path.Process,
+			}
+			if op.Summary == "" {
+				op.Summary = inst.HTTP.Name
+			}
+
+			for _, name := range params {
+				op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: map[string]interface{}{"type": "string"}})
+			}
+
+			in := []string{}
+			for _, v := range path.In {
+				in = append(in, fmt.Sprintf("%v", v))
+			}
+			_, op.Deprecated = yaoapi.DeprecationOf(path.Method, ginRoute)
+			validation := yaoapi.ValidationOf(path.Method, ginRoute)
+			inputs(op, in, validation)
+
+			if validation != nil && validation.Model != "" {
+				models[validation.Model] = true
+				body := op.body("application/json")
+				body.Schema = map[string]interface{}{"$ref": ref(validation.Model)}
+			}
+
+			// Responses
+			status := 200
+			if path.Out.Status > 0 {
+				status = path.Out.Status
+			}
+			contentType := "application/json"
+			if path.Out.Type != "" {
+				contentType = path.Out.Type
+			}
+
+			schema, mid := ProcessSchema(path.Process)
+			if mid != "" {
+				models[mid] = true
+			}
+			op.Responses[fmt.Sprintf("%d", status)] = &Response{
+				Description: "OK",
+				Content:     map[string]*MediaType{contentType: {Schema: schema}},
+			}
+
+			if validation != nil {
+				op.Responses["422"] = &Response{Description: "Validation failed"}
+			}
+
+			guard := path.Guard
+			if guard == "" {
+				guard = inst.HTTP.Guard
+			}
+			for _, name := range strings.Split(guard, ",") {
+				scheme, has := schemes[strings.TrimSpace(name)]
+				if !has {
+					continue
+				}
+				spec.Components.SecuritySchemes[scheme] = securitySchemes[scheme]
+				op.Security = append(op.Security, map[string][]string{scheme: {}})
+				op.Responses["403"] = &Response{Description: "Not Authorized"}
+			}
+
+			if _, has := spec.Paths[route]; !has {
+				spec.Paths[route] = map[string]*Operation{}
+			}
+			spec.Paths[route][method] = op
+		}
+	}
+
+	for id := range models {
+		if mod, has := model.Models[id]; has {
+			spec.Components.Schemas[schemaName(id)] = modelSchema(mod)
+		}
+	}
+
+	return spec
+}
+
+// Route the OpenAPI path and the path parameters of the gin route, e.g. /api/pet/:id => /api/pet/{id}
+func Route(route string) (string, []string) {
+	params := []string{}
+	parts := strings.Split(route, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			params = append(params, part[1:])
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+// inputs the parameters and the request body of the process arguments, e.g. $query.keyword, :payload
+func inputs(op *Operation, in []string, validation *yaoapi.Validation) {
+	var query *yaoapi.Schema
+	if validation != nil {
+		query = validation.Query
+	}
+
+	params := map[string]bool{}
+	for _, p := range op.Parameters {
+		params[p.In+"."+p.Name] = true
+	}
+
+	addParam := func(location string, name string) {
+		if params[location+"."+name] {
+			return
+		}
+		params[location+"."+name] = true
+
+		var schema interface{} = map[string]interface{}{"type": "string"}
+		if location == "query" && query != nil && query.Properties[name] != nil {
+			schema = toMap(query.Properties[name])
+		}
+
+		required := false
+		if location == "query" && query != nil {
+			for _, field := range query.Required {
+				required = required || field == name
+			}
+		}
+		op.Parameters = append(op.Parameters, &Parameter{Name: name, In: location, Required: required, Schema: schema})
+	}
+
+	properties := map[string]interface{}{}
+	for _, arg := range in {
+		switch {
+		case strings.HasPrefix(arg, "$query."):
+			addParam("query", strings.TrimPrefix(arg, "$query."))
+
+		case strings.HasPrefix(arg, "$header."):
+			addParam("header", strings.TrimPrefix(arg, "$header."))
+
+		case arg == ":payload" || arg == "$payload" || arg == ":body":
+			op.body("application/json")
+
+		case strings.HasPrefix(arg, "$payload."):
+			body := op.body("application/json")
+			properties[strings.TrimPrefix(arg, "$payload.")] = map[string]interface{}{}
+			body.Schema = map[string]interface{}{"type": "object", "properties": properties}
+
+		case arg == ":form" || strings.HasPrefix(arg, "$form."):
+			body := op.body("application/x-www-form-urlencoded")
+			if field := strings.TrimPrefix(arg, "$form."); field != arg {
+				schema, _ := body.Schema.(map[string]interface{})
+				props, ok := schema["properties"].(map[string]interface{})
+				if !ok {
+					props = map[string]interface{}{}
+					body.Schema = map[string]interface{}{"type": "object", "properties": props}
+				}
+				props[field] = map[string]interface{}{"type": "string"}
+			}
+
+		case strings.HasPrefix(arg, "$file."):
+			body := op.body("multipart/form-data")
+			body.Schema = map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{strings.TrimPrefix(arg, "$file."): map[string]interface{}{"type": "string", "format": "binary"}},
+			}
+		}
+	}
+
+	// The declared query parameters
+	if query != nil {
+		names := make([]string, 0, len(query.Properties))
+		for name := range query.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			addParam("query", name)
+		}
+	}
+
+	if validation != nil && validation.Body != nil {
+		body := op.body("application/json")
+		body.Schema = toMap(validation.Body)
+		op.RequestBody.Required = len(validation.Body.Required) > 0
+	}
+}
+
+// body the media type of the request body
+func (op *Operation) body(contentType string) *MediaType {
+	if op.RequestBody == nil {
+		op.RequestBody = &RequestBody{Content: map[string]*MediaType{}}
+	}
+	media, has := op.RequestBody.Content[contentType]
+	if !has {
+		media = &MediaType{Schema: map[string]interface{}{}}
+		op.RequestBody.Content[contentType] = media
+	}
+	return media
+}
+
+// ProcessSchema the response schema of the model processes, and the model id
+func ProcessSchema(name string) (interface{}, string) {
+	parts := strings.Split(strings.ToLower(name), ".")
+	if len(parts) < 3 || parts[0] != "models" {
+		return map[string]interface{}{}, ""
+	}
+
+	id := strings.Join(parts[1:len(parts)-1], ".")
+	item := map[string]interface{}{"$ref": ref(id)}
+	switch parts[len(parts)-1] {
+	case "find":
+		return item, id
+
+	case "get":
+		return map[string]interface{}{"type": "array", "items": item}, id
+
+	case "paginate":
+		return map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"data":     map[string]interface{}{"type": "array", "items": item},
+				"total":    map[string]interface{}{"type": "integer"},
+				"page":     map[string]interface{}{"type": "integer"},
+				"pagesize": map[string]interface{}{"type": "integer"},
+				"pagecnt":  map[string]interface{}{"type": "integer"},
+				"next":     map[string]interface{}{"type": "integer"},
+				"prev":     map[string]interface{}{"type": "integer"},
+			},
+		}, id
+
+	case "create", "save":
+		return map[string]interface{}{"type": "integer"}, ""
+	}
+	return map[string]interface{}{}, ""
+}
+
+// modelSchema the object schema of the model columns
+func modelSchema(mod *model.Model) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for _, column := range mod.MetaData.Columns {
+		properties[column.Name] = ColumnSchema(column.Type, column.Option, column.Nullable, column.Label)
+	}
+	return map[string]interface{}{"type": "object", "title": mod.MetaData.Name, "properties": properties}
+}
+
+// ColumnSchema the schema of the model column
+func ColumnSchema(typ string, options []string, nullable bool, label string) map[string]interface{} {
+	schema := map[string]interface{}{}
+	t, has := columnTypes[typ]
+	if !has {
+		t = "string"
+	}
+
+	if t != "" {
+		schema["type"] = t
+		if nullable {
+			schema["type"] = []string{t, "null"}
+		}
+	}
+
+	switch typ {
+	case "date":
+		schema["format"] = "date"
+	case "datetime", "timestamp", "dateTime", "dateTimeTz", "timestampTz":
+		schema["format"] = "date-time"
+	case "uuid":
+		schema["format"] = "uuid"
+	case "enum":
+		if len(options) > 0 {
+			schema["enum"] = options
+		}
+	}
+
+	if label != "" {
+		schema["description"] = label
+	}
+	return schema
+}
+
+func schemaName(id string) string {
+	return dts.Pascal(id)
+}
+
+func ref(id string) string {
+	return "#/components/schemas/" + schemaName(id)
+}
+
+// toMap the schema as a map, the unexported fields are dropped
+func toMap(v interface{}) map[string]interface{} {
+	res := map[string]interface{}{}
+	bytes, err := jsoniter.Marshal(v)
+	if err != nil {
+		return res
+	}
+	jsoniter.Unmarshal(bytes, &res)
+	return res
+}
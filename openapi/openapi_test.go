@@ -0,0 +1,49 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yaoapi "github.com/yaoapp/yao/api"
+)
+
+func TestRoute(t *testing.T) {
+	route, params := Route("/api/pet/:id/owner/*path")
+	assert.Equal(t, "/api/pet/{id}/owner/{path}", route)
+	assert.Equal(t, []string{"id", "path"}, params)
+}
+
+func TestInputs(t *testing.T) {
+	op := &Operation{Parameters: []*Parameter{{Name: "id", In: "path", Required: true}}}
+	validation := &yaoapi.Validation{Query: &yaoapi.Schema{
+		Required:   []string{"page"},
+		Properties: map[string]*yaoapi.Schema{"page": {Type: "integer"}, "status": {Type: "string"}},
+	}}
+	inputs(op, []string{"$param.id", "$query.keyword", "$query.page", "$header.X-Trace", "$payload.name", "$form.title"}, validation)
+
+	assert.Len(t, op.Parameters, 5)
+	assert.Equal(t, &Parameter{Name: "keyword", In: "query", Schema: map[string]interface{}{"type": "string"}}, op.Parameters[1])
+	assert.Equal(t, &Parameter{Name: "page", In: "query", Required: true, Schema: map[string]interface{}{"type": "integer"}}, op.Parameters[2])
+	assert.Equal(t, "header", op.Parameters[3].In)
+	assert.Equal(t, "status", op.Parameters[4].Name)
+
+	assert.Equal(t, map[string]interface{}{"type": "object", "properties": map[string]interface{}{"name": map[string]interface{}{}}}, op.RequestBody.Content["application/json"].Schema)
+	assert.Equal(t, map[string]interface{}{"type": "object", "properties": map[string]interface{}{"title": map[string]interface{}{"type": "string"}}}, op.RequestBody.Content["application/x-www-form-urlencoded"].Schema)
+}
+
+func TestProcessSchema(t *testing.T) {
+	schema, id := ProcessSchema("models.user.pet.Get")
+	assert.Equal(t, "user.pet", id)
+	assert.Equal(t, map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/components/schemas/UserPet"}}, schema)
+
+	schema, id = ProcessSchema("scripts.pet.Hello")
+	assert.Equal(t, "", id)
+	assert.Equal(t, map[string]interface{}{}, schema)
+}
+
+func TestColumnSchema(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{"type": []string{"integer", "null"}}, ColumnSchema("bigInteger", nil, true, ""))
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "date-time", "description": "Created"}, ColumnSchema("timestamp", nil, false, "Created"))
+	assert.Equal(t, map[string]interface{}{"type": "string", "enum": []string{"a", "b"}}, ColumnSchema("enum", []string{"a", "b"}, false, ""))
+	assert.Equal(t, map[string]interface{}{}, ColumnSchema("json", nil, false, ""))
+}
@@ -0,0 +1,62 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	yaoapi "github.com/yaoapp/yao/api"
+	"github.com/yaoapp/yao/share"
+)
+
+// DocumentCORS the cross-origin policy of the document, readable by the API tools of any origin without the credentials
+var DocumentCORS = &yaoapi.CORS{Origins: []string{"*"}, Methods: []string{"GET", "OPTIONS"}, Headers: []string{"Accept", "Content-Type"}, MaxAge: 86400}
+
+// uiTemplate the Swagger UI page, the assets are loaded from the base url
+const uiTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>%s API</title>
+  <link rel="stylesheet" href="%s/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="%s/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => { window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" }); };
+  </script>
+</body>
+</html>
+`
+
+// Serve the document at /openapi.json and the Swagger UI at /openapi, returns false if the path is not served
+func Serve(c *gin.Context) bool {
+	setting := share.App.OpenAPI
+	if setting == nil || !setting.Enable {
+		return false
+	}
+
+	switch c.Request.URL.Path {
+	case "/openapi.json":
+		if origin := c.GetHeader("Origin"); origin != "" {
+			DocumentCORS.SetHeaders(c.Writer.Header(), origin, c.Request.Method == http.MethodOptions)
+		}
+		if c.Request.Method == http.MethodOptions {
+			c.Status(http.StatusNoContent)
+			return true
+		}
+		c.JSON(http.StatusOK, Generate())
+		return true
+
+	case "/openapi", "/openapi/":
+		base := setting.UI
+		if base == "" {
+			base = "https://unpkg.com/swagger-ui-dist@5"
+		}
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.String(http.StatusOK, fmt.Sprintf(uiTemplate, share.App.Name, base, base))
+		return true
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package openapi
+
+// Spec the OpenAPI 3.1 document
+type Spec struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Servers    []Server                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+// Info the information of the application
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server the server of the API
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Operation the operation of the API path
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Process     string                `json:"x-process,omitempty"`
+}
+
+// Parameter the parameter of the operation
+type Parameter struct {
+	Name     string      `json:"name"`
+	In       string      `json:"in"` // path, query, header
+	Required bool        `json:"required,omitempty"`
+	Schema   interface{} `json:"schema,omitempty"`
+}
+
+// RequestBody the request body of the operation
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response the response of the operation
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType the content of the request or the response
+type MediaType struct {
+	Schema interface{} `json:"schema,omitempty"`
+}
+
+// Components the reusable schemas and security schemes
+type Components struct {
+	Schemas         map[string]interface{}    `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme the security scheme of the guards
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+}
+
+// schemes the security schemes of the builtin guards
+var schemes = map[string]string{
+	"bearer-jwt": "bearerAuth",
+	"query-jwt":  "queryAuth",
+	"cookie-jwt": "cookieAuth",
+}
+
+var securitySchemes = map[string]SecurityScheme{
+	"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+	"queryAuth":  {Type: "apiKey", Name: "__tk", In: "query"},
+	"cookieAuth": {Type: "apiKey", Name: "__tk", In: "cookie"},
+}
@@ -0,0 +1,137 @@
+package pack
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// binaryMagic the magic of the package appended to the binary.
+// The binary is the engine, the package, the SHA-256 and the size of the package and the magic.
+var binaryMagic = []byte("YAOPACK1")
+
+const trailerSize = sha256.Size + 8 + 8
+
+// Embed append the package to the engine and write the binary to the output, the binary runs the application embedded
+func Embed(engine string, yaz string, output string) error {
+	exe, err := os.Open(engine)
+	if err != nil {
+		return err
+	}
+	defer exe.Close()
+
+	info, err := exe.Stat()
+	if err != nil {
+		return err
+	}
+
+	// The engine embedding an application
+	size := info.Size()
+	if _, appended, err := trailer(exe, size); err == nil && appended > 0 {
+		size = size - appended - trailerSize
+	}
+
+	pkg, err := os.Open(yaz)
+	if err != nil {
+		return err
+	}
+	defer pkg.Close()
+
+	out, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, io.NewSectionReader(exe, 0, size)); err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(out, hash), pkg)
+	if err != nil {
+		return err
+	}
+
+	tail := make([]byte, 0, trailerSize)
+	tail = append(tail, hash.Sum(nil)...)
+	tail = binary.LittleEndian.AppendUint64(tail, uint64(n))
+	tail = append(tail, binaryMagic...)
+	_, err = out.Write(tail)
+	return err
+}
+
+// Embedded extract the package embedded in the binary to the cache and return the path of the package.
+// The path is empty if the binary does not embed a package, the package extracted is reused by the SHA-256.
+func Embedded(file string) (string, error) {
+	exe, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer exe.Close()
+
+	info, err := exe.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	sum, size, err := trailer(exe, info.Size())
+	if err != nil || size == 0 {
+		return "", err
+	}
+
+	cache := filepath.Join(os.TempDir(), "yao-pack", hex.EncodeToString(sum)+".yaz")
+	if stat, err := os.Stat(cache); err == nil && stat.Size() == size {
+		return cache, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cache), 0700); err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(cache), "*.yaz.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	offset := info.Size() - trailerSize - size
+	_, err = io.Copy(io.MultiWriter(tmp, hash), io.NewSectionReader(exe, offset, size))
+	tmp.Close()
+	if err != nil {
+		return "", err
+	}
+
+	if !bytes.Equal(hash.Sum(nil), sum) {
+		return "", fmt.Errorf("the package embedded in %s is corrupted", file)
+	}
+	return cache, os.Rename(tmp.Name(), cache)
+}
+
+// trailer the SHA-256 and the size of the package embedded, the size is 0 if no package is embedded
+func trailer(exe io.ReaderAt, size int64) ([]byte, int64, error) {
+	if size < trailerSize {
+		return nil, 0, nil
+	}
+
+	tail := make([]byte, trailerSize)
+	if _, err := exe.ReadAt(tail, size-trailerSize); err != nil {
+		return nil, 0, err
+	}
+
+	if !bytes.Equal(tail[trailerSize-8:], binaryMagic) {
+		return nil, 0, nil
+	}
+
+	n := int64(binary.LittleEndian.Uint64(tail[sha256.Size : sha256.Size+8]))
+	if n <= 0 || n > size-trailerSize {
+		return nil, 0, fmt.Errorf("the package embedded is invalid")
+	}
+	return tail[:sha256.Size], n, nil
+}
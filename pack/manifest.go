@@ -0,0 +1,203 @@
+package pack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/application"
+)
+
+// ManifestFile the manifest of the package, the integrity hashes of the files packed
+const ManifestFile = "manifest.pack.json"
+
+// Excludes the files not packed by default, the runtime data, the secrets and the outputs
+var Excludes = []string{".git", ".env", ".tmp", "dist", "data", "db", "logs", "node_modules", "*.yaz", ".DS_Store"}
+
+// modTime the modification time of the files staged, the archives of the same files are the same
+var modTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Manifest the version metadata and the integrity hashes of the package
+type Manifest struct {
+	Name    string `json:"name,omitempty"`    // The name of the application
+	Version string `json:"version,omitempty"` // The version of the application
+	Engine  string `json:"engine"`            // The version of the engine packing the application
+	Digest  string `json:"digest"`            // The SHA-256 of the files, the same files have the same digest
+	Files   []File `json:"files"`
+}
+
+// File the file packed
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Stage copy the files of the application to the dist and write the manifest, the files matched by the excludes are skipped.
+// The excludes are the names or the paths relative to the root, e.g. node_modules, public/uploads or *.log
+func Stage(root string, dist string, engine string, excludes []string) (*Manifest, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{Engine: engine, Files: []File{}}
+	err = filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(root, file)
+		if err != nil || name == "." {
+			return err
+		}
+		name = filepath.ToSlash(name)
+
+		if excluded(name, excludes) || name == ManifestFile {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() || !info.Mode().IsRegular() {
+			return nil
+		}
+
+		sum, err := copyFile(file, filepath.Join(dist, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, File{Path: name, Size: info.Size(), SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifest.Name, manifest.Version = appInfo(root)
+	manifest.Digest = manifest.digest()
+
+	raw, err := jsoniter.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	file := filepath.Join(dist, ManifestFile)
+	if err := os.WriteFile(file, raw, 0644); err != nil {
+		return nil, err
+	}
+	return manifest, os.Chtimes(file, modTime, modTime)
+}
+
+// Verify verify the files of the application with the manifest of the package
+func Verify(app application.Application) (*Manifest, error) {
+	raw, err := app.Read(ManifestFile)
+	if err != nil {
+		return nil, fmt.Errorf("the manifest of the package does not exist: %s", err.Error())
+	}
+
+	manifest := &Manifest{}
+	if err := jsoniter.Unmarshal(raw, manifest); err != nil {
+		return nil, fmt.Errorf("the manifest of the package is invalid: %s", err.Error())
+	}
+
+	if manifest.digest() != manifest.Digest {
+		return nil, fmt.Errorf("the digest of the manifest does not match")
+	}
+
+	for _, file := range manifest.Files {
+		data, err := app.Read(file.Path)
+		if err != nil {
+			return nil, fmt.Errorf("the file %s is missing: %s", file.Path, err.Error())
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != file.SHA256 {
+			return nil, fmt.Errorf("the file %s is modified", file.Path)
+		}
+	}
+	return manifest, nil
+}
+
+// digest the SHA-256 of the paths and the hashes of the files, the files are sorted by the walk
+func (manifest *Manifest) digest() string {
+	hash := sha256.New()
+	for _, file := range manifest.Files {
+		fmt.Fprintf(hash, "%s %s\n", file.SHA256, file.Path)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// excluded the file matches the excludes, the name or the path relative to the root
+func excluded(name string, excludes []string) bool {
+	for _, pattern := range excludes {
+		pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+		if name == pattern || strings.HasPrefix(name, pattern+"/") {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(name)); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// copyFile copy the file and return the SHA-256 of the content
+func copyFile(src string, dst string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, hash), in)
+	out.Close()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.Chtimes(dst, modTime, modTime); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// appInfo the name and the version of app.yao
+func appInfo(root string) (string, string) {
+	for _, name := range []string{"app.yao", "app.jsonc", "app.json"} {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			continue
+		}
+
+		var info struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if err := application.Parse(name, data, &info); err != nil {
+			return "", ""
+		}
+		return info.Name, info.Version
+	}
+	return "", ""
+}
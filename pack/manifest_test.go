@@ -0,0 +1,96 @@
+package pack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/application"
+)
+
+func TestStage(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"app.yao":                        `{"name": "CRM", "version": "1.2.0" }`,
+		"models/user.mod.yao":            `{"name": "user"}`,
+		"scripts/order.ts":               `function Refund(id) { return id }`,
+		"public/uploads/avatar.png":      "png",
+		".env":                           "YAO_DB_PRIMARY=secret",
+		"dist/app.yaz":                   "yaz",
+		"logs/application.log":           "log",
+		"neo/assistants/bot/prompts.yml": "- role: system",
+	}
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(file), os.ModePerm))
+		assert.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	}
+
+	dist := t.TempDir()
+	manifest, err := Stage(root, dist, "0.10.4", append(Excludes, "public/uploads"))
+	assert.NoError(t, err)
+	assert.Equal(t, "CRM", manifest.Name)
+	assert.Equal(t, "1.2.0", manifest.Version)
+
+	paths := []string{}
+	for _, file := range manifest.Files {
+		paths = append(paths, file.Path)
+	}
+	assert.Equal(t, []string{"app.yao", "models/user.mod.yao", "neo/assistants/bot/prompts.yml", "scripts/order.ts"}, paths)
+	assert.NoFileExists(t, filepath.Join(dist, ".env"))
+	assert.FileExists(t, filepath.Join(dist, ManifestFile))
+
+	// The same files have the same digest
+	again, err := Stage(root, t.TempDir(), "0.10.5", Excludes)
+	assert.NoError(t, err)
+	assert.NotEqual(t, manifest.Digest, again.Digest)
+
+	again, err = Stage(root, t.TempDir(), "0.10.5", append(Excludes, "public/uploads"))
+	assert.NoError(t, err)
+	assert.Equal(t, manifest.Digest, again.Digest)
+
+	app, err := application.OpenFromDisk(dist)
+	assert.NoError(t, err)
+	_, err = Verify(app)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dist, "scripts", "order.ts"), []byte("function Refund(id) { return 0 }"), 0644))
+	_, err = Verify(app)
+	assert.ErrorContains(t, err, "scripts/order.ts is modified")
+}
+
+func TestEmbed(t *testing.T) {
+	dir := t.TempDir()
+	engine := filepath.Join(dir, "yao")
+	yaz := filepath.Join(dir, "app.yaz")
+	assert.NoError(t, os.WriteFile(engine, []byte("#!engine"), 0755))
+	assert.NoError(t, os.WriteFile(yaz, []byte("the package"), 0644))
+
+	file, err := Embedded(engine)
+	assert.NoError(t, err)
+	assert.Empty(t, file)
+
+	binary := filepath.Join(dir, "crm")
+	assert.NoError(t, Embed(engine, yaz, binary))
+
+	file, err = Embedded(binary)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "the package", string(data))
+
+	// Embed the package again replaces the package embedded
+	assert.NoError(t, os.WriteFile(yaz, []byte("the new package"), 0644))
+	again := filepath.Join(dir, "crm2")
+	assert.NoError(t, Embed(binary, yaz, again))
+	raw, err := os.ReadFile(again)
+	assert.NoError(t, err)
+	assert.Equal(t, "#!engine", string(raw[:8]))
+	assert.Equal(t, len("#!engine")+len("the new package")+trailerSize, len(raw))
+
+	file, err = Embedded(again)
+	assert.NoError(t, err)
+	data, _ = os.ReadFile(file)
+	assert.Equal(t, "the new package", string(data))
+}
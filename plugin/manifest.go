@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/application"
+)
+
+// ManifestFile the manifest of the plugin directory, e.g. plugins/feishu/plugin.yao
+const ManifestFile = "plugin.yao"
+
+// Manifest the manifest of the plugin, the plugin is a go-plugin executable served over gRPC,
+// the methods are called as the processes plugins.<id>.<method>
+type Manifest struct {
+	ID          string            `json:"-"`
+	Dir         string            `json:"-"`
+	Name        string            `json:"name"`                  // Name of the plugin
+	Version     string            `json:"version,omitempty"`     // Version of the plugin, e.g. 1.0.0
+	Description string            `json:"description,omitempty"` // Description of the plugin
+	Executable  string            `json:"executable,omitempty"`  // Executable relative to the plugin directory, e.g. feishu.so
+	Platforms   map[string]string `json:"platforms,omitempty"`   // Executables by the platform, e.g. {"linux/amd64": "bin/feishu-linux-amd64"}
+	Checksums   map[string]string `json:"checksums,omitempty"`   // SHA-256 of the executables, the executable is not loaded if it does not match
+	Processes   []string          `json:"processes,omitempty"`   // Methods provided by the plugin, for the documentation and the list command
+	Disabled    bool              `json:"disabled,omitempty"`    // The plugin is not loaded
+}
+
+// Manifests the manifests of the plugin directories under the root, sorted by the id
+func Manifests(root string) ([]*Manifest, error) {
+	manifests := []*Manifest{}
+	err := filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if info == nil || info.IsDir() || info.Name() != ManifestFile {
+			return nil
+		}
+
+		manifest, err := OpenManifest(filepath.Dir(file), root)
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, manifest)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].ID < manifests[j].ID })
+	return manifests, nil
+}
+
+// OpenManifest read the manifest of the plugin directory, the id is the path of the directory under the root, e.g. foo.bar
+func OpenManifest(dir string, root string) (*Manifest, error) {
+	file := filepath.Join(dir, ManifestFile)
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	err = application.Parse(ManifestFile, bytes, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err.Error())
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest.ID = strings.ReplaceAll(filepath.ToSlash(rel), "/", ".")
+	manifest.Dir = dir
+	if manifest.Name == "" {
+		manifest.Name = manifest.ID
+	}
+	return manifest, nil
+}
+
+// Save write the manifest to the plugin directory
+func (manifest *Manifest) Save() error {
+	bytes, err := jsoniter.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(manifest.Dir, ManifestFile), bytes, 0644)
+}
+
+// File the executable of the current platform
+func (manifest *Manifest) File() (string, error) {
+	executable := manifest.Executable
+	if file, has := manifest.Platforms[runtime.GOOS+"/"+runtime.GOARCH]; has {
+		executable = file
+	}
+
+	if executable == "" {
+		return "", fmt.Errorf("plugin %s has no executable for %s/%s", manifest.ID, runtime.GOOS, runtime.GOARCH)
+	}
+
+	file := filepath.Join(manifest.Dir, executable)
+	if _, err := os.Stat(file); err != nil {
+		return "", fmt.Errorf("plugin %s: %s", manifest.ID, err.Error())
+	}
+	return file, nil
+}
+
+// Verify the checksum of the executable
+func (manifest *Manifest) Verify(file string) error {
+	rel, err := filepath.Rel(manifest.Dir, file)
+	if err != nil {
+		return err
+	}
+
+	expected, has := manifest.Checksums[filepath.ToSlash(rel)]
+	if !has {
+		return nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("plugin %s: the checksum of %s does not match", manifest.ID, rel)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifests(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "foo", "bar")
+	err := os.MkdirAll(filepath.Join(dir, "bin"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executable := []byte("#!/bin/sh\n")
+	sum := sha256.Sum256(executable)
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	err = os.WriteFile(filepath.Join(dir, "bin", "bar"), executable, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, ManifestFile), []byte(`{
+		"name": "Bar",
+		"version": "1.0.0",
+		"platforms": {"`+platform+`": "bin/bar"},
+		"checksums": {"bin/bar": "`+hex.EncodeToString(sum[:])+`"},
+		"processes": ["Hello"]
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifests, err := Manifests(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, manifests, 1)
+
+	manifest := manifests[0]
+	assert.Equal(t, "foo.bar", manifest.ID)
+	assert.Equal(t, "Bar", manifest.Name)
+
+	file, err := manifest.File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, filepath.Join(dir, "bin", "bar"), file)
+	assert.Nil(t, manifest.Verify(file))
+
+	// The executable is changed
+	err = os.WriteFile(file, []byte("changed"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, manifest.Verify(file))
+
+	// Disable the plugin
+	manifest.Disabled = true
+	err = manifest.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err = OpenManifest(dir, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, manifest.Disabled)
+	assert.Equal(t, "1.0.0", manifest.Version)
+}
@@ -20,8 +20,36 @@ func Load(cfg config.Config) error {
 	}
 
 	messages := []string{}
+
+	// The plugins with the manifest
+	manifests, err := Manifests(root)
+	if err != nil {
+		return err
+	}
+
+	dirs := map[string]bool{}
+	for _, manifest := range manifests {
+		dirs[manifest.Dir] = true
+		if manifest.Disabled {
+			continue
+		}
+
+		err := loadManifest(manifest)
+		if err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+
+	// The executables without the manifest
 	err = filepath.Walk(root, func(file string, info fs.FileInfo, err error) error {
-		if info == nil || info.IsDir() {
+		if info == nil {
+			return nil
+		}
+
+		if info.IsDir() {
+			if dirs[file] {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
@@ -44,6 +72,22 @@ func Load(cfg config.Config) error {
 
 }
 
+// loadManifest load the executable of the plugin for the current platform
+func loadManifest(manifest *Manifest) error {
+	file, err := manifest.File()
+	if err != nil {
+		return err
+	}
+
+	err = manifest.Verify(file)
+	if err != nil {
+		return err
+	}
+
+	_, err = plugin.Load(file, manifest.ID)
+	return err
+}
+
 // Root return plugin root
 func Root(cfg config.Config) (string, error) {
 	root := filepath.Join(cfg.ExtensionRoot, "plugins")
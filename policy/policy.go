@@ -0,0 +1,235 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+// Load the policies/*.yao files and guard the registered processes.
+// The processes are not guarded if there are no policy files.
+func Load(cfg config.Config) error {
+	policy := &Policy{Field: "roles", Public: []string{}, Roles: map[string]*Rule{}}
+	messages := []string{}
+	count := 0
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("policies", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		dsl := DSL{}
+		err = application.Parse(file, bytes, &dsl)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		policy.merge(dsl)
+		count++
+		return nil
+	}, exts...)
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Restore the handlers guarded by the previous load, the handlers registered again are kept
+	for name, handler := range originals {
+		if guarded(process.Handlers[name]) {
+			process.Handlers[name] = handler
+		}
+		delete(originals, name)
+	}
+
+	Current = nil
+	if count == 0 {
+		return nil
+	}
+
+	Current = policy
+	for name, handler := range process.Handlers {
+		originals[name] = handler
+		process.Handlers[name] = guard(handler)
+	}
+	return nil
+}
+
+// merge the policy file, the rules of the same role are appended
+func (policy *Policy) merge(dsl DSL) {
+	if dsl.Field != "" {
+		policy.Field = dsl.Field
+	}
+
+	policy.Public = append(policy.Public, lower(dsl.Public)...)
+	for role, rule := range dsl.Roles {
+		if _, has := policy.Roles[role]; !has {
+			policy.Roles[role] = &Rule{Allow: []string{}, Deny: []string{}}
+		}
+		policy.Roles[role].Allow = append(policy.Roles[role].Allow, lower(rule.Allow)...)
+		policy.Roles[role].Deny = append(policy.Roles[role].Deny, lower(rule.Deny)...)
+	}
+}
+
+// Check the roles could call the process
+func (policy *Policy) Check(name string, roles []string) error {
+	name = strings.ToLower(name)
+	if match(policy.Public, name) {
+		return nil
+	}
+
+	if len(roles) == 0 {
+		roles = []string{GuestRole}
+	}
+
+	allowed := false
+	for _, role := range roles {
+		rule, has := policy.Roles[role]
+		if !has {
+			continue
+		}
+
+		if match(rule.Deny, name) {
+			return fmt.Errorf("the process %s is denied for the role %s", name, role)
+		}
+
+		if match(rule.Allow, name) {
+			allowed = true
+		}
+	}
+
+	if !allowed {
+		return fmt.Errorf("the process %s is not allowed for the roles %s", name, strings.Join(roles, ", "))
+	}
+	return nil
+}
+
+// Roles the roles of the session read by the field of the current policy, default is roles.
+// The guest sessions and the calls without a session have no roles
+func Roles(sid string) []string {
+	if sid == "" {
+		return nil
+	}
+
+	lock.RLock()
+	policy := Current
+	lock.RUnlock()
+	if policy == nil {
+		policy = &Policy{Field: "roles"}
+	}
+	return policy.roles(sid)
+}
+
+// roles the roles of the session, the field could be a string or an array of strings,
+// the nested field is separated by the dot, e.g. user.roles. The guest sessions have no roles
+func (policy *Policy) roles(sid string) []string {
+	if share.IsGuest(sid) {
+		return nil
+	}
+
+	fields := strings.Split(policy.Field, ".")
+	value, err := session.Global().ID(sid).Get(fields[0])
+	if err != nil {
+		return nil
+	}
+
+	for _, field := range fields[1:] {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = data[field]
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+
+	case []string:
+		return v
+
+	case []interface{}:
+		roles := []string{}
+		for _, role := range v {
+			if role, ok := role.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+		return roles
+	}
+	return nil
+}
+
+// guard the handler checks the policy before the call. Every HTTP and websocket request has a session, the anonymous
+// requests have the guest sessions, so the calls without a session are the internal calls trusted, e.g. the schedules,
+// the tasks and the commands.
+func guard(handler process.Handler) process.Handler {
+	return func(p *process.Process) interface{} {
+		lock.RLock()
+		policy := Current
+		lock.RUnlock()
+
+		if policy != nil && !internal(p) {
+			if err := policy.Check(p.Name, policy.roles(p.Sid)); err != nil {
+				exception.New(err.Error(), 403).Throw()
+				return nil
+			}
+		}
+		return handler(p)
+	}
+}
+
+// internal the process is called by the internal callers, see the withGuestSession of the service
+func internal(p *process.Process) bool {
+	return p.Sid == ""
+}
+
+// guarded the handler is returned by guard, the closures of guard share the code pointer
+func guarded(handler process.Handler) bool {
+	return handler != nil && reflect.ValueOf(handler).Pointer() == reflect.ValueOf(guard(nil)).Pointer()
+}
+
+func match(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func lower(values []string) []string {
+	res := make([]string, 0, len(values))
+	for _, value := range values {
+		res = append(res, strings.ToLower(value))
+	}
+	return res
+}
@@ -0,0 +1,51 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/yao/share"
+)
+
+func TestCheck(t *testing.T) {
+	policy := &Policy{Field: "roles", Public: []string{}, Roles: map[string]*Rule{}}
+	policy.merge(DSL{
+		Public: []string{"yao.login.*"},
+		Roles: map[string]Rule{
+			"admin": {Allow: []string{"*"}},
+			"user":  {Allow: []string{"models.pet.*", "scripts.pet.*"}, Deny: []string{"models.pet.delete"}},
+		},
+	})
+	policy.merge(DSL{Field: "user.roles", Roles: map[string]Rule{"user": {Allow: []string{"Neo.Assistant.Find"}}}})
+
+	assert.Equal(t, "user.roles", policy.Field)
+	assert.NoError(t, policy.Check("yao.login.Admin", nil))
+	assert.NoError(t, policy.Check("models.pet.Find", []string{"user"}))
+	assert.NoError(t, policy.Check("neo.assistant.find", []string{"user"}))
+	assert.Error(t, policy.Check("models.pet.Delete", []string{"user"}))
+	assert.Error(t, policy.Check("models.pet.Delete", []string{"user", "admin"}))
+	assert.Error(t, policy.Check("models.user.Find", []string{"user"}))
+	assert.NoError(t, policy.Check("models.user.Find", []string{"admin"}))
+	assert.Error(t, policy.Check("models.pet.Find", nil))
+
+	policy.merge(DSL{Roles: map[string]Rule{GuestRole: {Allow: []string{"models.pet.find"}}}})
+	assert.NoError(t, policy.Check("models.pet.Find", nil))
+}
+
+func TestGuarded(t *testing.T) {
+	assert.True(t, guarded(guard(nil)))
+	assert.False(t, guarded(nil))
+}
+
+func TestGuest(t *testing.T) {
+	policy := &Policy{Field: "roles", Public: []string{}, Roles: map[string]*Rule{}}
+	policy.merge(DSL{Roles: map[string]Rule{"admin": {Allow: []string{"*"}}}})
+
+	// The anonymous requests are the guests, the calls without a session are internal
+	sid := share.GuestSID()
+	assert.Nil(t, policy.roles(sid))
+	assert.Error(t, policy.Check("models.pet.Find", policy.roles(sid)))
+	assert.False(t, internal(&process.Process{Sid: sid}))
+	assert.True(t, internal(&process.Process{}))
+}
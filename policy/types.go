@@ -0,0 +1,37 @@
+package policy
+
+import (
+	"sync"
+
+	"github.com/yaoapp/gou/process"
+)
+
+// GuestRole the role of the sessions without roles
+const GuestRole = "guest"
+
+// DSL the policy file, policies/<name>.yao
+type DSL struct {
+	Name   string          `json:"name,omitempty"`
+	Field  string          `json:"field,omitempty"`  // The session field of the roles, default is roles
+	Public []string        `json:"public,omitempty"` // The processes are allowed for everyone, e.g. yao.login.*
+	Roles  map[string]Rule `json:"roles,omitempty"`
+}
+
+// Rule the process name patterns of the role, the denied patterns take precedence
+type Rule struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// Policy the merged policies of the application
+type Policy struct {
+	Field  string
+	Public []string
+	Roles  map[string]*Rule
+}
+
+// Current the loaded policy, nil if there are no policy files
+var Current *Policy
+
+var originals = map[string]process.Handler{}
+var lock sync.RWMutex
@@ -0,0 +1,74 @@
+package pubsub
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// memory the messages of the process, the messages are not shared by the instances
+type memory struct {
+	sync.Mutex
+	seq    int64
+	topics map[string]map[chan entry]bool
+}
+
+type entry struct {
+	id      string
+	payload string
+}
+
+func newMemory() *memory {
+	return &memory{topics: map[string]map[chan entry]bool{}}
+}
+
+// Publish the message to the subscribers, it waits if the buffer of the subscriber is full
+func (m *memory) Publish(ctx context.Context, topic string, payload string) (string, error) {
+	m.Lock()
+	m.seq++
+	e := entry{id: strconv.FormatInt(m.seq, 10), payload: payload}
+	chans := []chan entry{}
+	for ch := range m.topics[topic] {
+		chans = append(chans, ch)
+	}
+	m.Unlock()
+
+	for _, ch := range chans {
+		select {
+		case ch <- e:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	return e.id, nil
+}
+
+func (m *memory) Subscribe(ctx context.Context, topic string, deliver func(id string, payload string)) <-chan struct{} {
+	ch := make(chan entry, 1000)
+	m.Lock()
+	if m.topics[topic] == nil {
+		m.topics[topic] = map[chan entry]bool{}
+	}
+	m.topics[topic][ch] = true
+	m.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			m.Lock()
+			delete(m.topics[topic], ch)
+			m.Unlock()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-ch:
+				deliver(e.id, e.payload)
+			}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,69 @@
+package pubsub
+
+import (
+	"fmt"
+
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("pubsub", map[string]process.Handler{
+		"publish":     processPublish,
+		"subscribe":   processSubscribe,
+		"unsubscribe": processUnsubscribe,
+	})
+}
+
+// processPublish pubsub.Publish("<topic>", <data>?), the message is broadcast to the subscribers on every instance
+//
+//	Process("pubsub.Publish", "cache.invalidate", { tags: ["orders"] })
+func processPublish(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	var data interface{}
+	if process.NumOfArgs() > 1 {
+		data = process.Args[1]
+	}
+
+	msg, err := Publish(process.ArgsString(0), data)
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return msg
+}
+
+// processSubscribe pubsub.Subscribe("<topic>", "<process>"), the process is called with the message on this instance,
+// and the message is delivered again if the process fails. The same process of the topic is subscribed once, e.g. in the afterLoad
+//
+//	Process("pubsub.Subscribe", "cache.invalidate", "scripts.cache.OnInvalidate")
+func processSubscribe(p *process.Process) interface{} {
+	p.ValidateArgNums(2)
+	name := p.ArgsString(1)
+	if name == "" {
+		exception.New("the process of the subscription is required", 400).Throw()
+	}
+
+	id, err := subscribe(p.ArgsString(0), name, func(msg *Message) error {
+		handler, err := process.Of(name, msg)
+		if err != nil {
+			return err
+		}
+		_, err = handler.Exec()
+		return err
+	})
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return id
+}
+
+// processUnsubscribe pubsub.Unsubscribe(<id>)
+func processUnsubscribe(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	id := process.ArgsInt(0)
+	if id <= 0 {
+		exception.New(fmt.Sprintf("the subscription %v is invalid", process.Args[0]), 400).Throw()
+	}
+	Unsubscribe(int64(id))
+	return nil
+}
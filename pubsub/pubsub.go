@@ -0,0 +1,181 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+)
+
+var topicRe = regexp.MustCompile(`^[a-zA-Z0-9_.:\-]{1,200}$`)
+
+var instance = instanceID()
+
+// Load the backend of the configuration, the subscriptions are moved to the backend
+func Load(cfg config.Config) error {
+	var b Backend
+	switch cfg.PubSub.Driver {
+	case "", "memory":
+		b = newMemory()
+	case "redis":
+		r, err := newRedis(cfg.PubSub.Redis, cfg.PubSub.MaxLen)
+		if err != nil {
+			return err
+		}
+		b = r
+	default:
+		return fmt.Errorf("the pubsub driver %s is not supported, memory or redis", cfg.PubSub.Driver)
+	}
+
+	lock.Lock()
+	backend = b
+	subs := make([]*subscription, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		subs = append(subs, sub)
+	}
+	lock.Unlock()
+
+	for _, sub := range subs {
+		sub.restart(b)
+	}
+	return nil
+}
+
+// Publish broadcast the data to the subscribers of the topic on every instance
+func Publish(topic string, data interface{}) (*Message, error) {
+	if !topicRe.MatchString(topic) {
+		return nil, fmt.Errorf("the topic %s is invalid", topic)
+	}
+
+	msg := &Message{Topic: topic, Data: data, Time: time.Now(), Instance: instance}
+	payload, err := jsoniter.MarshalToString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("the data of the topic %s is invalid: %s", topic, err.Error())
+	}
+
+	lock.Lock()
+	b := backend
+	lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	id, err := b.Publish(ctx, topic, payload)
+	if err != nil {
+		return nil, err
+	}
+	msg.ID = id
+	return msg, nil
+}
+
+// Subscribe the messages of the topic published after the subscription, returns the id of the subscription
+func Subscribe(topic string, handler Handler) (int64, error) {
+	return subscribe(topic, "", handler)
+}
+
+// Unsubscribe stop the subscription of the id, the message being delivered is not interrupted
+func Unsubscribe(id int64) {
+	lock.Lock()
+	sub, has := subscriptions[id]
+	delete(subscriptions, id)
+	lock.Unlock()
+
+	if has {
+		sub.mu.Lock()
+		sub.closed = true
+		sub.cancel()
+		sub.mu.Unlock()
+	}
+}
+
+func subscribe(topic string, process string, handler Handler) (int64, error) {
+	if !topicRe.MatchString(topic) {
+		return 0, fmt.Errorf("the topic %s is invalid", topic)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// The subscription of the process is subscribed once, e.g. the afterLoad script runs again when reloading
+	if process != "" {
+		for _, sub := range subscriptions {
+			if sub.topic == topic && sub.process == process {
+				return sub.id, nil
+			}
+		}
+	}
+
+	seq++
+	sub := &subscription{id: seq, topic: topic, process: process, handler: handler}
+	subscriptions[sub.id] = sub
+	sub.start(backend)
+	return sub.id, nil
+}
+
+func (sub *subscription) start(b Backend) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sub.cancel = cancel
+	sub.done = b.Subscribe(ctx, sub.topic, func(id string, payload string) {
+		sub.deliver(ctx, id, payload)
+	})
+}
+
+// restart the subscription on the backend, the message being delivered is completed before
+func (sub *subscription) restart(b Backend) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return
+	}
+	sub.cancel()
+	<-sub.done
+	sub.start(b)
+}
+
+// deliver the message to the handler, the message is delivered again if the handler fails
+func (sub *subscription) deliver(ctx context.Context, id string, payload string) {
+	msg := &Message{}
+	if err := jsoniter.UnmarshalFromString(payload, msg); err != nil {
+		log.Error("[PubSub] the message %s of the topic %s is invalid: %s", id, sub.topic, err.Error())
+		return
+	}
+	msg.ID = id
+
+	for i := 0; ; i++ {
+		err := handle(sub.handler, msg)
+		if err == nil {
+			return
+		}
+
+		if i >= len(retries) {
+			log.Error("[PubSub] the message %s of the topic %s is dropped: %s", id, sub.topic, err.Error())
+			return
+		}
+
+		log.Warn("[PubSub] the message %s of the topic %s is delivered again in %s: %s", id, sub.topic, retries[i], err.Error())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retries[i]):
+		}
+	}
+}
+
+// handle call the handler, the panic is the error
+func handle(handler Handler, msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return handler(msg)
+}
+
+func instanceID() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
@@ -0,0 +1,107 @@
+package pubsub
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/config"
+)
+
+func TestPubSub(t *testing.T) {
+	received := make(chan *Message, 10)
+	id, err := Subscribe("orders.created", func(msg *Message) error {
+		received <- msg
+		return nil
+	})
+	assert.NoError(t, err)
+	defer Unsubscribe(id)
+
+	msg, err := Publish("orders.created", map[string]interface{}{"id": 1})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, msg.ID)
+
+	select {
+	case got := <-received:
+		assert.Equal(t, msg.ID, got.ID)
+		assert.Equal(t, "orders.created", got.Topic)
+		assert.Equal(t, float64(1), got.Data.(map[string]interface{})["id"])
+		assert.Equal(t, instance, got.Instance)
+	case <-time.After(time.Second):
+		t.Fatal("the message is not delivered")
+	}
+
+	// The subscription is kept when the backend is reloaded
+	assert.NoError(t, Load(config.Config{}))
+	Publish("orders.created", nil)
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("the message is not delivered after reloaded")
+	}
+
+	// The subscription is stopped
+	Unsubscribe(id)
+	Publish("orders.created", nil)
+	select {
+	case <-received:
+		t.Fatal("the message is delivered after unsubscribed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	_, err = Publish("orders created", nil)
+	assert.Error(t, err)
+	assert.Error(t, Load(config.Config{PubSub: config.PubSub{Driver: "kafka"}}))
+}
+
+func TestDeliverAgain(t *testing.T) {
+	retries = []time.Duration{10 * time.Millisecond, 10 * time.Millisecond}
+
+	var calls atomic.Int32
+	done := make(chan struct{})
+	id, _ := Subscribe("jobs.failed", func(msg *Message) error {
+		if calls.Add(1) < 3 {
+			panic("the handler is failed")
+		}
+		close(done)
+		return nil
+	})
+	defer Unsubscribe(id)
+
+	Publish("jobs.failed", nil)
+	select {
+	case <-done:
+		assert.Equal(t, int32(3), calls.Load())
+	case <-time.After(time.Second):
+		t.Fatal("the message is not delivered again")
+	}
+
+	// The message is dropped after the retries
+	var drops atomic.Int32
+	id2, _ := Subscribe("jobs.dropped", func(msg *Message) error {
+		drops.Add(1)
+		return fmt.Errorf("failed")
+	})
+	defer Unsubscribe(id2)
+	Publish("jobs.dropped", nil)
+	assert.Eventually(t, func() bool { return drops.Load() == 3 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(3), drops.Load())
+}
+
+func TestSubscribeOnce(t *testing.T) {
+	handler := func(msg *Message) error { return nil }
+	id, err := subscribe("users.updated", "scripts.users.OnUpdated", handler)
+	assert.NoError(t, err)
+	defer Unsubscribe(id)
+
+	again, err := subscribe("users.updated", "scripts.users.OnUpdated", handler)
+	assert.NoError(t, err)
+	assert.Equal(t, id, again)
+
+	other, _ := subscribe("users.updated", "scripts.users.Audit", handler)
+	defer Unsubscribe(other)
+	assert.NotEqual(t, id, other)
+}
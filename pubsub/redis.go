@@ -0,0 +1,128 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/cast"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+)
+
+// rdb the streams of the redis, the messages are shared by the instances. The subscribers read the stream from the last
+// message delivered, the messages published while the redis is unreachable are delivered after it is reconnected
+type rdb struct {
+	client *redis.Client
+	maxLen int64
+}
+
+// newRedis the redis of the URL, the session redis if the URL is empty
+func newRedis(url string, maxLen int) (*rdb, error) {
+	if url == "" {
+		session := config.Conf.Session
+		url = fmt.Sprintf("redis://%s:%s@%s:%s/%s", session.Username, session.Password, session.Host, session.Port, session.DB)
+	}
+
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("the redis of the pubsub is invalid: %s", err.Error())
+	}
+
+	if maxLen <= 0 {
+		maxLen = 10000
+	}
+	return &rdb{client: redis.NewClient(options), maxLen: int64(maxLen)}, nil
+}
+
+// Publish add the message to the stream of the topic, the stream is trimmed to about the max length
+func (r *rdb) Publish(ctx context.Context, topic string, payload string) (string, error) {
+	return r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamOf(topic),
+		MaxLen: r.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{"payload": payload},
+	}).Result()
+}
+
+func (r *rdb) Subscribe(ctx context.Context, topic string, deliver func(id string, payload string)) <-chan struct{} {
+	stream := streamOf(topic)
+
+	// The messages after the last message of the stream, it is read again in the background if the redis is unreachable
+	c, cancel := context.WithTimeout(ctx, 3*time.Second)
+	last, err := r.last(c, stream)
+	cancel()
+	if err != nil {
+		log.Error("[PubSub] read the topic %s: %s", topic, err.Error())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for last == "" {
+			if !r.wait(ctx) {
+				return
+			}
+			if last, err = r.last(ctx, stream); err != nil && ctx.Err() == nil {
+				log.Error("[PubSub] read the topic %s: %s", topic, err.Error())
+			}
+		}
+
+		for {
+			streams, err := r.client.XRead(ctx, &redis.XReadArgs{Streams: []string{stream, last}, Count: 100, Block: 5 * time.Second}).Result()
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err == redis.Nil {
+				continue
+			}
+
+			if err != nil {
+				log.Error("[PubSub] read the topic %s: %s", topic, err.Error())
+				if !r.wait(ctx) {
+					return
+				}
+				continue
+			}
+
+			for _, s := range streams {
+				for _, message := range s.Messages {
+					last = message.ID
+					deliver(message.ID, cast.ToString(message.Values["payload"]))
+					if ctx.Err() != nil {
+						return
+					}
+				}
+			}
+		}
+	}()
+	return done
+}
+
+// last the id of the last message of the stream, 0-0 if the stream is empty
+func (r *rdb) last(ctx context.Context, stream string) (string, error) {
+	messages, err := r.client.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(messages) > 0 {
+		return messages[0].ID, nil
+	}
+	return "0-0", nil
+}
+
+// wait a second before reading again, returns false if the ctx is done
+func (r *rdb) wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(time.Second):
+		return true
+	}
+}
+
+func streamOf(topic string) string {
+	return "yao:pubsub:" + topic
+}
@@ -0,0 +1,47 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Message the message broadcast to the subscribers of the topic on every instance
+type Message struct {
+	ID       string      `json:"id"`
+	Topic    string      `json:"topic"`
+	Data     interface{} `json:"data,omitempty"`
+	Time     time.Time   `json:"time"`
+	Instance string      `json:"instance"` // The instance published the message
+}
+
+// Handler the handler of the subscriber, the message is delivered again if the handler returns an error
+type Handler func(msg *Message) error
+
+// Backend the transport of the messages. Subscribe delivers the messages published after it returns in the background
+// until the ctx is done, the next message is delivered after deliver returns. The channel is closed when it is stopped
+type Backend interface {
+	Publish(ctx context.Context, topic string, payload string) (string, error)
+	Subscribe(ctx context.Context, topic string, deliver func(id string, payload string)) <-chan struct{}
+}
+
+type subscription struct {
+	mu      sync.Mutex
+	closed  bool
+	id      int64
+	topic   string
+	process string // The process of the subscription of the scripts
+	handler Handler
+	cancel  context.CancelFunc
+	done    <-chan struct{}
+}
+
+var (
+	lock          sync.Mutex
+	backend       Backend = newMemory()
+	subscriptions         = map[int64]*subscription{}
+	seq           int64   = 0
+)
+
+// retries the delays of the deliveries again, the message is dropped after the retries
+var retries = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second}
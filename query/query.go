@@ -1,13 +1,20 @@
 package query
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/yaoapp/gou/connector"
 	"github.com/yaoapp/gou/model"
 	"github.com/yaoapp/gou/query"
 	dsl "github.com/yaoapp/gou/query/gou"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/log"
 	"github.com/yaoapp/xun/capsule"
 	"github.com/yaoapp/yao/config"
+	yaomodel "github.com/yaoapp/yao/model"
 )
 
 // Load 加载查询引擎
@@ -64,3 +71,76 @@ func registerDefault() {
 		})
 	}
 }
+
+// ops the operators of the query DSL of the operators of the wheres
+var ops = map[string]string{"eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<=", "in": "in"}
+
+// Scoped the query DSL of the session, the wheres of the row-level policy of the model queried are added.
+// The models of the policies could not be joined by the sessions. The internal calls without the session are not scoped.
+func Scoped(sid string, query map[string]interface{}) map[string]interface{} {
+	if sid == "" {
+		return query
+	}
+
+	joins, _ := query["joins"].([]interface{})
+	for _, join := range joins {
+		if join, ok := join.(map[string]interface{}); ok {
+			if id, _ := modelOf(join["from"]); id != "" && yaomodel.HasPolicy(id) {
+				exception.New("the model %s has the row-level policy, it could not be joined", 403, id).Throw()
+			}
+		}
+	}
+
+	id, alias := modelOf(query["from"])
+	if id == "" {
+		return query
+	}
+	return scopedWheres(query, alias, yaomodel.PolicyWheres(id, sid))
+}
+
+// scopedWheres the wheres of the query DSL are grouped and the wheres of the policy are added
+func scopedWheres(query map[string]interface{}, alias string, wheres []types.QueryWhere) map[string]interface{} {
+	if len(wheres) == 0 {
+		return query
+	}
+
+	res := map[string]interface{}{}
+	for key, value := range query {
+		res[key] = value
+	}
+
+	conditions := []interface{}{}
+	for _, where := range wheres {
+		field := fmt.Sprintf("%v", where.Column)
+		if alias != "" {
+			field = alias + "." + field
+		}
+		conditions = append(conditions, map[string]interface{}{"field": field, ops[where.OP]: where.Value})
+	}
+
+	if value, has := query["wheres"]; has && value != nil && len(any.Of(value).CArray()) > 0 {
+		conditions = append(conditions, map[string]interface{}{"wheres": value})
+	}
+	res["wheres"] = conditions
+	return res
+}
+
+// modelOf the model and the alias of the from of the query DSL, e.g. "$pet as p", empty if not a model
+func modelOf(from interface{}) (string, string) {
+	name, ok := from.(string)
+	name = strings.TrimSpace(name)
+	if !ok || !strings.HasPrefix(name, "$") {
+		return "", ""
+	}
+
+	fields := strings.Fields(name[1:])
+	switch {
+	case len(fields) == 3 && strings.EqualFold(fields[1], "as"):
+		return fields[0], fields[2]
+	case len(fields) == 2:
+		return fields[0], fields[1]
+	case len(fields) == 1:
+		return fields[0], ""
+	}
+	return "", ""
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yaoapp/gou/query"
+	"github.com/yaoapp/gou/types"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/connector"
 	"github.com/yaoapp/yao/test"
@@ -35,3 +36,23 @@ func loadConnectors(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestScoped(t *testing.T) {
+	query := map[string]interface{}{"from": "$pet as p", "wheres": []interface{}{map[string]interface{}{"field": "p.name", "=": "Cookie"}}}
+
+	// The internal calls are not scoped
+	assert.Equal(t, query, Scoped("", query))
+
+	id, alias := modelOf(query["from"])
+	assert.Equal(t, "pet", id)
+	assert.Equal(t, "p", alias)
+	id, _ = modelOf("pet")
+	assert.Empty(t, id)
+
+	res := scopedWheres(query, alias, []types.QueryWhere{{Column: "owner_id", OP: "eq", Value: 1}})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"field": "p.owner_id", "=": 1},
+		map[string]interface{}{"wheres": query["wheres"]},
+	}, res["wheres"])
+	assert.Len(t, query["wheres"], 1)
+}
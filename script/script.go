@@ -7,6 +7,7 @@ import (
 	v8 "github.com/yaoapp/gou/runtime/v8"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/unit"
 )
 
 // Load load all scripts and services
@@ -14,7 +15,7 @@ func Load(cfg config.Config) error {
 	v8.CLearModules()
 	exts := []string{"*.js", "*.ts"}
 	err := application.App.Walk("scripts", func(root, file string, isdir bool) error {
-		if isdir {
+		if isdir || unit.IsTestFile(file) {
 			return nil
 		}
 		_, err := v8.Load(file, share.ID(root, file))
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/yaoapp/yao/service/fs"
+	"github.com/yaoapp/yao/share"
+)
+
+// cacheHandler set the ETag and the Cache-Control of the static files, the fingerprinted files are cached as immutable
+func cacheHandler(fsys http.FileSystem, h http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		name := path.Clean("/" + r.URL.Path)
+		immutable := false
+		digest, err := fs.Digest(fsys, name)
+
+		// The fingerprinted name, e.g. /js/app.3f2a1b9c0d.js => /js/app.js
+		if err != nil && share.App.Static.Fingerprint {
+			if original, fingerprint, ok := fs.Original(name); ok {
+				if d, e := fs.Digest(fsys, original); e == nil {
+					digest, err = d, nil
+					immutable = strings.HasPrefix(d, fingerprint)
+					r.URL.Path = original
+				}
+			}
+		}
+
+		// Not found, responded by the file server
+		if err != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("ETag", fmt.Sprintf(`W/"%s"`, digest))
+		header.Set("Cache-Control", cacheControl(name, immutable))
+		h.ServeHTTP(w, r)
+	}
+}
+
+// cacheControl the Cache-Control of the file, the pages are always revalidated
+func cacheControl(name string, immutable bool) string {
+	if immutable {
+		return "public, max-age=31536000, immutable"
+	}
+
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" || ext == ".html" || ext == ".htm" || share.App.Static.MaxAge <= 0 {
+		return "no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", share.App.Static.MaxAge)
+}
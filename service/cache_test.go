@@ -0,0 +1,77 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/service/fs"
+	"github.com/yaoapp/yao/share"
+)
+
+func TestCacheHandler(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "js"), 0755)
+	os.WriteFile(filepath.Join(root, "index.html"), []byte("<html></html>"), 0644)
+	os.WriteFile(filepath.Join(root, "js", "app.js"), []byte("console.log('yao')"), 0644)
+
+	static := share.App.Static
+	share.App.Static.MaxAge = 600
+	share.App.Static.Fingerprint = true
+	defer func() { share.App.Static = static }()
+
+	fsys := http.Dir(root)
+	handler := cacheHandler(fsys, http.FileServer(fsys))
+
+	res := serve(handler, "/js/app.js", "")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "public, max-age=600", res.Header().Get("Cache-Control"))
+	etag := res.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	res = serve(handler, "/js/app.js", etag)
+	assert.Equal(t, 304, res.Code)
+
+	res = serve(handler, "/", "")
+	assert.Equal(t, "no-cache", res.Header().Get("Cache-Control"))
+
+	name, err := fs.Fingerprint(fsys, "/js/app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res = serve(handler, name, "")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "public, max-age=31536000, immutable", res.Header().Get("Cache-Control"))
+	assert.Equal(t, "console.log('yao')", res.Body.String())
+
+	res = serve(handler, "/js/app.0123456789.js", "")
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "public, max-age=600", res.Header().Get("Cache-Control"))
+
+	res = serve(handler, "/js/none.js", "")
+	assert.Equal(t, 404, res.Code)
+	assert.Empty(t, res.Header().Get("ETag"))
+}
+
+func TestFingerprint(t *testing.T) {
+	original, fingerprint, ok := fs.Original("/js/app.3f2a1b9c0d.js")
+	assert.True(t, ok)
+	assert.Equal(t, "/js/app.js", original)
+	assert.Equal(t, "3f2a1b9c0d", fingerprint)
+
+	_, _, ok = fs.Original("/js/app.min.js")
+	assert.False(t, ok)
+}
+
+func serve(handler http.Handler, path string, etag string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/yao/config"
+)
+
+// compressor the encoder of the content encoding
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// encoders the content encodings supported, in the order of preference
+var encoders = []struct {
+	Name string
+	New  func(w io.Writer) compressor
+}{
+	{Name: "gzip", New: func(w io.Writer) compressor { return gzip.NewWriter(w) }},
+	{Name: "deflate", New: func(w io.Writer) compressor {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	}},
+}
+
+// incompressible the content types are compressed already or streamed
+var incompressible = []string{"text/event-stream", "image/", "video/", "audio/", "application/zip", "application/gzip", "application/x-gzip"}
+
+// withCompression compress the API responses by the Accept-Encoding, the event streams, websockets and range requests are not compressed
+func withCompression(c *gin.Context) {
+	if !config.Conf.Compress || !strings.HasPrefix(c.Request.URL.Path, "/api/") || c.GetHeader("Range") != "" ||
+		c.GetHeader("Upgrade") != "" || strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		return
+	}
+
+	encoding := negotiate(c.GetHeader("Accept-Encoding"))
+	if encoding < 0 {
+		return
+	}
+
+	w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, min: config.Conf.CompressMin}
+	c.Writer = w
+	defer func() {
+		w.close()
+		c.Writer = w.ResponseWriter
+	}()
+	c.Next()
+}
+
+// negotiate the index of the preferred encoder accepted, -1 if none is accepted
+func negotiate(accept string) int {
+	if accept == "" {
+		return -1
+	}
+
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qualities[name] = q
+	}
+
+	best, index := 0.0, -1
+	for i, encoder := range encoders {
+		q, has := qualities[encoder.Name]
+		if !has {
+			q, has = qualities["*"]
+		}
+		if has && q > best {
+			best, index = q, i
+		}
+	}
+	return index
+}
+
+// compressWriter the response writer buffers the small responses, and compresses the others
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding    int
+	min         int
+	buf         []byte
+	writer      compressor
+	passthrough bool
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+
+	if w.writer != nil {
+		return w.writer.Write(data)
+	}
+
+	if !w.compressible() {
+		w.passthrough = true
+		if err := w.flushBuffer(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.min {
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush the flushed responses are streamed, the buffered content is compressed
+func (w *compressWriter) Flush() {
+	if !w.passthrough && w.writer == nil {
+		if w.compressible() {
+			w.start()
+		} else {
+			w.passthrough = true
+			w.flushBuffer()
+		}
+	}
+
+	if w.writer != nil {
+		w.writer.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// compressible the response is not encoded, not a partial content and the content type is compressible
+func (w *compressWriter) compressible() bool {
+	status := w.Status()
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent {
+		return false
+	}
+
+	// The ranges are the offsets of the content not encoded
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, prefix := range incompressible {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// start compress the buffered content and the following writes
+func (w *compressWriter) start() error {
+	header := w.Header()
+	header.Set("Content-Encoding", encoders[w.encoding].Name)
+	header.Add("Vary", "Accept-Encoding")
+	header.Del("Content-Length")
+
+	w.writer = encoders[w.encoding].New(w.ResponseWriter)
+	buf := w.buf
+	w.buf = nil
+	_, err := w.writer.Write(buf)
+	return err
+}
+
+func (w *compressWriter) flushBuffer() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close write the buffered content uncompressed, or close the encoder
+func (w *compressWriter) close() {
+	if w.writer != nil {
+		w.writer.Close()
+		return
+	}
+	w.flushBuffer()
+}
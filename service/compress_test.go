@@ -0,0 +1,100 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/config"
+)
+
+func TestCompression(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	compress, min := config.Conf.Compress, config.Conf.CompressMin
+	config.Conf.Compress, config.Conf.CompressMin = true, 64
+	defer func() { config.Conf.Compress, config.Conf.CompressMin = compress, min }()
+
+	large := strings.Repeat("yao", 100)
+	router := gin.New()
+	router.Use(withCompression)
+	router.GET("/api/large", func(c *gin.Context) { c.JSON(200, gin.H{"data": large}) })
+	router.GET("/api/small", func(c *gin.Context) { c.JSON(200, gin.H{"data": "yao"}) })
+	router.GET("/api/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "br")
+		c.String(200, large)
+	})
+	router.GET("/api/partial", func(c *gin.Context) {
+		c.Header("Content-Range", "bytes 0-299/600")
+		c.String(206, large)
+	})
+	router.GET("/api/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.String(200, "data: %s\n\n", large)
+	})
+
+	res := compressRequest(router, "/api/large", "gzip, deflate, br")
+	assert.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", res.Header().Get("Vary"))
+	reader, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(reader)
+	assert.Contains(t, string(body), large)
+
+	res = compressRequest(router, "/api/large", "gzip;q=0.5, deflate")
+	assert.Equal(t, "deflate", res.Header().Get("Content-Encoding"))
+
+	res = compressRequest(router, "/api/large", "")
+	assert.Empty(t, res.Header().Get("Content-Encoding"))
+	assert.Contains(t, res.Body.String(), large)
+
+	res = compressRequest(router, "/api/small", "gzip")
+	assert.Empty(t, res.Header().Get("Content-Encoding"))
+	assert.Equal(t, `{"data":"yao"}`, res.Body.String())
+
+	res = compressRequest(router, "/api/encoded", "gzip")
+	assert.Equal(t, "br", res.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, res.Body.String())
+
+	res = compressRequest(router, "/api/partial", "gzip")
+	assert.Empty(t, res.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, res.Body.String())
+
+	// The range requests are not compressed
+	req, _ := http.NewRequest("GET", "/api/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-99")
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Empty(t, res.Header().Get("Content-Encoding"))
+
+	res = compressRequest(router, "/api/stream", "gzip")
+	assert.Empty(t, res.Header().Get("Content-Encoding"))
+	assert.True(t, bytes.HasPrefix(res.Body.Bytes(), []byte("data: yao")))
+}
+
+func TestNegotiate(t *testing.T) {
+	assert.Equal(t, -1, negotiate(""))
+	assert.Equal(t, -1, negotiate("br"))
+	assert.Equal(t, 0, negotiate("br, gzip"))
+	assert.Equal(t, 1, negotiate("gzip;q=0.1, deflate;q=0.8"))
+	assert.Equal(t, -1, negotiate("gzip;q=0"))
+	assert.Equal(t, 0, negotiate("*"))
+}
+
+func compressRequest(router *gin.Engine, path string, encoding string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	if encoding != "" {
+		req.Header.Set("Accept-Encoding", encoding)
+	}
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
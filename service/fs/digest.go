@@ -0,0 +1,108 @@
+package fs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/yao/share"
+)
+
+// FingerprintLen the length of the fingerprint in the asset names
+const FingerprintLen = 10
+
+var reFingerprint = regexp.MustCompile(`^(.+)\.([0-9a-f]{10})(\.[A-Za-z0-9]+)$`)
+
+type digestKey struct {
+	fsys http.FileSystem
+	name string
+}
+
+type digestValue struct {
+	size    int64
+	modTime time.Time
+	digest  string
+}
+
+// digests the cache of the digests, the digest is renewed when the size or the modification time changes
+var digests = map[digestKey]digestValue{}
+var digestLock sync.RWMutex
+
+// Digest the content digest of the file, the directories are digested by the index.html
+func Digest(fsys http.FileSystem, name string) (string, error) {
+	name = path.Clean("/" + name)
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	if stat.IsDir() {
+		return Digest(fsys, path.Join(name, "index.html"))
+	}
+
+	key := digestKey{fsys: fsys, name: name}
+	digestLock.RLock()
+	cached, has := digests[key]
+	digestLock.RUnlock()
+	if has && cached.size == stat.Size() && cached.modTime.Equal(stat.ModTime()) {
+		return cached.digest, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	digest := hex.EncodeToString(hash.Sum(nil))[:16]
+
+	digestLock.Lock()
+	digests[key] = digestValue{size: stat.Size(), modTime: stat.ModTime(), digest: digest}
+	digestLock.Unlock()
+	return digest, nil
+}
+
+// Fingerprint the fingerprinted name of the file, e.g. /js/app.js => /js/app.3f2a1b9c0d.js, the names without extension are kept
+func Fingerprint(fsys http.FileSystem, name string) (string, error) {
+	digest, err := Digest(fsys, name)
+	if err != nil {
+		return "", err
+	}
+
+	name = path.Clean("/" + name)
+	ext := path.Ext(name)
+	if ext == "" || ext == path.Base(name) {
+		return name, nil
+	}
+	return name[:len(name)-len(ext)] + "." + digest[:FingerprintLen] + ext, nil
+}
+
+// Original the original name and the fingerprint of the fingerprinted name, e.g. /js/app.3f2a1b9c0d.js => /js/app.js, 3f2a1b9c0d
+func Original(name string) (string, string, bool) {
+	matches := reFingerprint.FindStringSubmatch(name)
+	if matches == nil {
+		return name, "", false
+	}
+	return matches[1] + matches[3], matches[2], true
+}
+
+// AssetURL the URL of the public file, the fingerprinted name under the CDN base URL if they are set, e.g. /js/app.js => https://cdn.example.com/js/app.3f2a1b9c0d.js
+func AssetURL(name string) string {
+	name = path.Clean("/" + name)
+	if share.App.Static.Fingerprint {
+		if fingerprinted, err := Fingerprint(Dir("public"), name); err == nil {
+			name = fingerprinted
+		}
+	}
+	return strings.TrimSuffix(share.App.Static.CDN, "/") + name
+}
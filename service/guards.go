@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/yaoapp/yao/billing"
 	"github.com/yaoapp/yao/helper"
 
 	"github.com/yaoapp/yao/widgets/chart"
@@ -26,6 +27,7 @@ var Guards = map[string]gin.HandlerFunc{
 	"widget-form":      form.Guard,       // Widget Form Guard
 	"widget-chart":     chart.Guard,      // Widget Chart Guard
 	"widget-dashboard": dashboard.Guard,  // Widget Dashboard Guard
+	"billing-plan":     billing.Guard,    // The paths of the features are allowed for the plans of them only
 }
 
 // guardCookieTrace set sid cookie
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/gou/connector/database"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/neo"
+	"github.com/yaoapp/yao/neo/store"
+	"github.com/yaoapp/yao/share"
+)
+
+// Check the readiness check, the server is not ready if the check returns an error
+type Check func(ctx context.Context) error
+
+// checks the readiness checks, the database, the connectors required and the migrations by default
+var checks = map[string]Check{
+	"db":         checkDB,
+	"connectors": checkConnectors,
+	"migrations": checkMigrations,
+}
+var checksLock sync.RWMutex
+
+// draining the server is stopping, the readiness fails and the requests are drained
+var draining atomic.Bool
+
+// RegisterCheck register the readiness check, the check of the same name is replaced
+func RegisterCheck(name string, check Check) {
+	checksLock.Lock()
+	defer checksLock.Unlock()
+	checks[name] = check
+}
+
+// Drain fail the readiness and wait for the delay, the load balancers stop sending the requests before the server stops
+func Drain(delay time.Duration) {
+	draining.Store(true)
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// handleHealthz the liveness, the server is running
+//
+//	curl http://127.0.0.1:5099/healthz
+func handleHealthz(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "ok"})
+}
+
+// handleReadyz the readiness, the database, the connectors required and the migrations are checked
+//
+//	curl http://127.0.0.1:5099/readyz
+//	{"status": "ok", "checks": {"db": {"status": "ok", "duration": 2}, "migrations": {"status": "ok", "duration": 5}}}
+func handleReadyz(c *gin.Context) {
+	if draining.Load() {
+		c.JSON(503, gin.H{"status": "fail", "message": "the server is stopping"})
+		return
+	}
+
+	// The application is reloading
+	if !reloading.TryLock() {
+		c.JSON(503, gin.H{"status": "fail", "message": "the application is reloading"})
+		return
+	}
+	reloading.Unlock()
+
+	ok, results := ready(c.Request.Context(), config.Conf.Health)
+	if !ok {
+		c.JSON(503, gin.H{"status": "fail", "checks": results})
+		return
+	}
+	c.JSON(200, gin.H{"status": "ok", "checks": results})
+}
+
+// ready run the checks not skipped concurrently, the durations are in milliseconds
+func ready(ctx context.Context, cfg config.Health) (bool, map[string]gin.H) {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	skipped := map[string]bool{}
+	for _, name := range cfg.Skip {
+		skipped[name] = true
+	}
+
+	checksLock.RLock()
+	names := []string{}
+	for name := range checks {
+		if !skipped[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	results := map[string]gin.H{}
+	errs := make([]error, len(names))
+	durations := make([]time.Duration, len(names))
+	wg := sync.WaitGroup{}
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			start := time.Now()
+			errs[i] = run(ctx, check)
+			durations[i] = time.Since(start)
+		}(i, checks[name])
+	}
+	checksLock.RUnlock()
+	wg.Wait()
+
+	ok := true
+	for i, name := range names {
+		result := gin.H{"status": "ok", "duration": durations[i].Milliseconds()}
+		if errs[i] != nil {
+			ok = false
+			result["status"] = "fail"
+			result["message"] = errs[i].Error()
+		}
+		results[name] = result
+	}
+	return ok, results
+}
+
+// run the check, the check is failed if it is timeout or panics
+func run(ctx context.Context, check Check) (err error) {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("%v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timeout")
+	}
+}
+
+// checkDB ping the default database and the connection pools of the database connectors
+func checkDB(ctx context.Context) error {
+	return share.DBPing(ctx)
+}
+
+// checkConnectors the connectors required are loaded, the database connectors are pinged
+func checkConnectors(ctx context.Context) error {
+	for _, id := range config.Conf.Health.Connectors {
+		conn, has := connector.Connectors[id]
+		if !has {
+			return fmt.Errorf("the connector %s is not loaded", id)
+		}
+
+		db, ok := conn.(*database.Xun)
+		if !ok || db.Manager == nil {
+			continue
+		}
+		for _, c := range db.Manager.Pool.Primary {
+			if err := c.DB.PingContext(ctx); err != nil {
+				return fmt.Errorf("the connector %s: %s", id, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// checkMigrations the migrations of the neo store are applied
+func checkMigrations(ctx context.Context) error {
+	if neo.Neo == nil || neo.Neo.Store == nil {
+		return nil
+	}
+
+	migrator, ok := neo.Neo.Store.(store.Migrator)
+	if !ok {
+		return nil
+	}
+
+	migrations, err := migrator.Migrations()
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, migration := range migrations {
+		if !migration.Applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d migrations of the neo store are pending, run yao store migrate", pending)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/config"
+)
+
+func TestReady(t *testing.T) {
+	RegisterCheck("queue", func(ctx context.Context) error { return nil })
+	defer delete(checks, "queue")
+
+	cfg := config.Health{Skip: []string{"db", "connectors", "migrations"}, Timeout: 1}
+	ok, results := ready(context.Background(), cfg)
+	assert.True(t, ok)
+	assert.Equal(t, "ok", results["queue"]["status"])
+	assert.NotContains(t, results, "db")
+
+	RegisterCheck("queue", func(ctx context.Context) error { return fmt.Errorf("the queue is full") })
+	RegisterCheck("cache", func(ctx context.Context) error { panic("the cache is closed") })
+	RegisterCheck("search", func(ctx context.Context) error { time.Sleep(3 * time.Second); return nil })
+	defer delete(checks, "cache")
+	defer delete(checks, "search")
+
+	start := time.Now()
+	ok, results = ready(context.Background(), cfg)
+	assert.False(t, ok)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Equal(t, "the queue is full", results["queue"]["message"])
+	assert.Equal(t, "the cache is closed", results["cache"]["message"])
+	assert.Equal(t, "timeout", results["search"]["message"])
+}
+
+func TestProbes(t *testing.T) {
+	router := gin.New()
+	router.GET("/healthz", handleHealthz)
+	router.GET("/readyz", handleReadyz)
+
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, 200, res.Code)
+
+	// The readiness fails while reloading
+	reloading.Lock()
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("GET", "/readyz", nil))
+	reloading.Unlock()
+	assert.Equal(t, 503, res.Code)
+	assert.Contains(t, res.Body.String(), "reloading")
+
+	Drain(0)
+	defer draining.Store(false)
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, 503, res.Code)
+	assert.Contains(t, res.Body.String(), "stopping")
+
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, 200, res.Code)
+}
@@ -0,0 +1,16 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/yao/cluster"
+	"github.com/yaoapp/yao/share"
+)
+
+// handleMetrics the stats of the database connection pools, the default database and the database connectors,
+// and the election of the cluster
+//
+//	{ "db": { "default": [{ "name": "primary-0", "open": 4, "in_use": 1, "idle": 3, "wait_count": 0, ... }] },
+//	  "cluster": { "backend": "redis", "owner": "web-1:12:5f2e1c3a", "leader": true, "since": "..." } }
+func handleMetrics(c *gin.Context) {
+	c.JSON(200, gin.H{"db": share.DBStats(), "cluster": cluster.Info()})
+}
@@ -10,6 +10,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/yaoapp/kun/log"
+	yaoapi "github.com/yaoapp/yao/api"
+	"github.com/yaoapp/yao/openapi"
 	"github.com/yaoapp/yao/share"
 	"github.com/yaoapp/yao/sui/api"
 )
@@ -17,7 +19,22 @@ import (
 // Middlewares the middlewares
 var Middlewares = []gin.HandlerFunc{
 	gin.Logger(),
+	withGuestSession,
+	withCompression,
 	withStaticFileServer,
+	yaoapi.CrossOrigin,
+	yaoapi.BodyLimit,
+	yaoapi.Deprecation,
+	yaoapi.Validate,
+}
+
+// withGuestSession every request has a session, the anonymous requests have the guest sessions checked as the guests by the policies.
+// The guards replace the session with the session of the user signed in, the handlers require the user check share.IsGuest
+func withGuestSession(c *gin.Context) {
+	if c.GetString("__sid") == "" {
+		c.Set("__sid", share.GuestSID())
+	}
+	c.Next()
 }
 
 // withStaticFileServer static file server
@@ -26,7 +43,8 @@ func withStaticFileServer(c *gin.Context) {
 	// Handle API & websocket
 	length := len(c.Request.URL.Path)
 	if (length >= 5 && c.Request.URL.Path[0:5] == "/api/") ||
-		(length >= 11 && c.Request.URL.Path[0:11] == "/websocket/") { // API & websocket
+		(length >= 11 && c.Request.URL.Path[0:11] == "/websocket/") ||
+		(length >= 10 && c.Request.URL.Path[0:10] == "/webhooks/") { // API, websocket & incoming webhooks
 		c.Next()
 		return
 	}
@@ -47,6 +65,12 @@ func withStaticFileServer(c *gin.Context) {
 		return
 	}
 
+	// OpenAPI document and Swagger UI
+	if openapi.Serve(c) {
+		c.Abort()
+		return
+	}
+
 	// Rewrite
 	for _, rewrite := range rewriteRules {
 		// log.Debug("Rewrite: %s => %s", c.Request.URL.Path, rewrite.Replacement)
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/gou/server/http"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/engine"
+	"github.com/yaoapp/yao/setup"
+)
+
+var reloading sync.Mutex
+
+// server the running server, restarted by the reload endpoint
+var server *http.Server
+
+// Reload the configuration and the application without restarting the process, the connectors, the models, the apis, the neo assistants...
+// The new configuration is validated first, the previous one is restored if the new one fails to apply.
+func Reload(srv *http.Server) error {
+	reloading.Lock()
+	defer reloading.Unlock()
+
+	prev := config.Conf
+	cfg, err := reloadConfig()
+	if err != nil {
+		return err
+	}
+
+	err = validateReload(prev, cfg)
+	if err != nil {
+		return err
+	}
+
+	// The mode is set by the start command, e.g. yao start --debug
+	cfg.Mode = prev.Mode
+
+	// The connectors are replaced by the reload, keep them for the rollback
+	connectors := map[string]connector.Connector{}
+	for id, conn := range connector.Connectors {
+		connectors[id] = conn
+	}
+
+	err = engine.Reload(cfg, engine.LoadOption{Action: "reload"})
+	if err != nil {
+		for id := range connector.Connectors {
+			if _, has := connectors[id]; !has {
+				delete(connector.Connectors, id)
+			}
+		}
+		for id, conn := range connectors {
+			connector.Connectors[id] = conn
+		}
+
+		config.Conf = prev
+		if rerr := engine.Reload(prev, engine.LoadOption{Action: "reload", IgnoredAfterLoad: true}); rerr != nil {
+			log.Error("[Reload] restore the previous configuration: %s", rerr.Error())
+		}
+		return fmt.Errorf("the new configuration failed to apply, the previous one is restored: %s", err.Error())
+	}
+
+	config.Conf = cfg
+	if srv == nil {
+		return nil
+	}
+	return Restart(srv, cfg)
+}
+
+// reloadConfig read the configuration from the .env file and the environment variables
+func reloadConfig() (cfg config.Config, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = exception.Catch(r)
+		}
+	}()
+
+	envfile := filepath.Join(config.Conf.Root, ".env")
+	if _, serr := os.Stat(envfile); serr != nil {
+		return config.Load(), nil
+	}
+	return config.LoadFrom(envfile), nil
+}
+
+// validateReload the new configuration is valid and does not change the settings only applied on start
+func validateReload(prev config.Config, cfg config.Config) error {
+	if !setup.IsYaoApp(cfg.Root) {
+		return fmt.Errorf("the app.yao file is missing in %s", cfg.Root)
+	}
+
+	restart := map[string][2]interface{}{
+		"YAO_HOST":    {prev.Host, cfg.Host},
+		"YAO_PORT":    {prev.Port, cfg.Port},
+		"YAO_DB":      {prev.DB, cfg.DB},
+		"YAO_SESSION": {prev.Session, cfg.Session},
+		"YAO_RUNTIME": {prev.Runtime, cfg.Runtime},
+	}
+
+	for name, values := range restart {
+		if !reflect.DeepEqual(values[0], values[1]) {
+			return fmt.Errorf("%s is changed, restart the process to apply it", name)
+		}
+	}
+	return nil
+}
+
+// handleReload the admin endpoint of the reload, only the requests from the local host are accepted.
+// The reload restarts the server, so it runs after the response.
+func handleReload(c *gin.Context) {
+	ip := net.ParseIP(c.RemoteIP())
+	if ip == nil || !ip.IsLoopback() {
+		c.JSON(403, gin.H{"code": 403, "message": "The reload is only allowed from the local host"})
+		c.Abort()
+		return
+	}
+
+	go func() {
+		if err := Reload(server); err != nil {
+			log.Error("[Reload] %s", err.Error())
+			return
+		}
+		log.Info("[Reload] Completed")
+	}()
+
+	c.JSON(202, gin.H{"message": "reloading"})
+}
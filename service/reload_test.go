@@ -0,0 +1,24 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/config"
+)
+
+func TestValidateReload(t *testing.T) {
+	cfg := config.Conf
+	assert.Nil(t, validateReload(config.Conf, cfg))
+
+	cfg.Port = config.Conf.Port + 1
+	assert.Error(t, validateReload(config.Conf, cfg))
+
+	cfg = config.Conf
+	cfg.Session.Store = "redis-changed"
+	assert.Error(t, validateReload(config.Conf, cfg))
+
+	cfg = config.Conf
+	cfg.Root = t.TempDir()
+	assert.Error(t, validateReload(config.Conf, cfg))
+}
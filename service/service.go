@@ -6,9 +6,21 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/yaoapp/gou/api"
 	"github.com/yaoapp/gou/server/http"
+	"github.com/yaoapp/yao/analytics"
+	yaoapi "github.com/yaoapp/yao/api"
+	"github.com/yaoapp/yao/billing"
 	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/dingtalk"
+	"github.com/yaoapp/yao/feishu"
+	"github.com/yaoapp/yao/market"
 	"github.com/yaoapp/yao/neo"
+	"github.com/yaoapp/yao/notification"
 	"github.com/yaoapp/yao/share"
+	"github.com/yaoapp/yao/slack"
+	"github.com/yaoapp/yao/telegram"
+	"github.com/yaoapp/yao/trace"
+	"github.com/yaoapp/yao/webhook"
+	"github.com/yaoapp/yao/wework"
 )
 
 // Start the yao service
@@ -24,6 +36,7 @@ func Start(cfg config.Config) (*http.Server, error) {
 	}
 
 	router := gin.New()
+	router.MaxMultipartMemory = int64(cfg.UploadMemory) << 20
 	router.Use(Middlewares...)
 	api.SetGuards(Guards)
 	api.SetRoutes(router, "/api", cfg.AllowFrom...)
@@ -35,10 +48,8 @@ func Start(cfg config.Config) (*http.Server, error) {
 		Timeout: 5 * time.Second,
 	})
 
-	// Neo API
-	if neo.Neo != nil {
-		neo.Neo.API(router, "/api/__yao/neo")
-	}
+	setRoutes(router)
+	server = srv
 
 	go func() {
 		err = srv.Start()
@@ -50,9 +61,11 @@ func Start(cfg config.Config) (*http.Server, error) {
 // Restart the yao service
 func Restart(srv *http.Server, cfg config.Config) error {
 	router := gin.New()
+	router.MaxMultipartMemory = int64(cfg.UploadMemory) << 20
 	router.Use(Middlewares...)
 	api.SetGuards(Guards)
 	api.SetRoutes(router, "/api", cfg.AllowFrom...)
+	setRoutes(router)
 	srv.Reset(router)
 	return srv.Restart()
 }
@@ -67,6 +80,64 @@ func Stop(srv *http.Server) error {
 	return nil
 }
 
+// setRoutes set the builtin routes
+func setRoutes(router *gin.Engine) {
+
+	// Neo API
+	if neo.Neo != nil {
+		neo.Neo.API(router, "/api/__yao/neo")
+	}
+
+	// The inbox and the notification preferences of the signed-in user
+	notification.SetRoutes(router, "/api/__yao/notifications", guardBearerJWT)
+
+	// The outbound webhooks and the delivery log, for the admins
+	webhook.SetRoutes(router, "/api/__yao/webhooks", guardBearerJWT)
+
+	// The stripe webhook, the plans and the subscription of the team of the signed-in user
+	billing.SetRoutes(router, "/api/__yao/billing", guardBearerJWT)
+
+	// The daily active users, the chats, the tokens and the top assistants, for the admins
+	analytics.SetRoutes(router, "/api/__yao/analytics", guardBearerJWT)
+
+	// The traces and the waterfalls of the agent turns, for the admins
+	trace.SetRoutes(router, "/api/__yao/traces", guardBearerJWT)
+
+	// The packages of the registry and the packages installed, for the admins
+	market.SetRoutes(router, "/api/__yao/market", guardBearerJWT)
+
+	// The incoming webhooks, the webhooks/*.yao files
+	webhook.SetReceiverRoutes(router, "/webhooks")
+
+	// The callbacks of the WeCom bots, the weworks/*.yao files
+	wework.SetRoutes(router, "/api/__yao/wework")
+
+	// The robot messages and the events of the DingTalk bots, the dingtalks/*.yao files
+	dingtalk.SetRoutes(router, "/api/__yao/dingtalk")
+
+	// The events of the Feishu bots, the feishus/*.yao files
+	feishu.SetRoutes(router, "/api/__yao/feishu")
+
+	// The events, the slash commands and the install flow of the Slack apps, the slacks/*.yao files
+	slack.SetRoutes(router, "/api/__yao/slack")
+
+	// The webhooks of the Telegram bots, the telegrams/*.yao files
+	telegram.SetRoutes(router, "/api/__yao/telegram")
+
+	// Proxy APIs, the apis/*.proxy.yao files
+	yaoapi.SetProxyRoutes(router, Guards)
+
+	// Reload the configuration, e.g. curl -X POST http://127.0.0.1:5099/api/__yao/reload -H 'Authorization: Bearer xxx'
+	router.POST("/api/__yao/reload", guardBearerJWT, handleReload)
+
+	// The stats of the database connection pools, e.g. curl http://127.0.0.1:5099/api/__yao/metrics -H 'Authorization: Bearer xxx'
+	router.GET("/api/__yao/metrics", guardBearerJWT, handleMetrics)
+
+	// The liveness and the readiness probes, e.g. curl http://127.0.0.1:5099/readyz
+	router.GET("/healthz", handleHealthz)
+	router.GET("/readyz", handleReadyz)
+}
+
 func prepare() error {
 
 	// Session server
@@ -15,8 +15,11 @@ import (
 // AppFileServer static file server
 var AppFileServer http.Handler
 
+// xgenV1 XGen v1.0 file system
+var xgenV1 = data.XgenV1()
+
 // XGenFileServerV1 XGen v1.0
-var XGenFileServerV1 http.Handler = http.FileServer(data.XgenV1())
+var XGenFileServerV1 http.Handler = cacheHandler(xgenV1, http.FileServer(xgenV1))
 
 // AdminRoot cache
 var AdminRoot = ""
@@ -37,12 +40,13 @@ func SetupStatic() error {
 	setupRewrite()
 
 	// Disable gzip compression for static files
+	public := fs.Dir("public")
 	if share.App.Static.DisableGzip {
-		AppFileServer = http.FileServer(fs.Dir("public"))
+		AppFileServer = cacheHandler(public, http.FileServer(public))
 		return nil
 	}
 
-	AppFileServer = gzipHandler(http.FileServer(fs.Dir("public")))
+	AppFileServer = cacheHandler(public, gzipHandler(http.FileServer(public)))
 	return nil
 }
 
@@ -0,0 +1,261 @@
+package setup
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// DefaultRegistry the default registry of the starter templates, the registry is the JSON index of the templates
+const DefaultRegistry = "https://mirrors.letsinfra.com/templates/index.json"
+
+// Template the starter template of the registry
+type Template struct {
+	Name        string             `json:"name"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	URL         string             `json:"url"`              // The zip of the template, relative to the registry if not absolute
+	Checksum    string             `json:"sha256,omitempty"` // The SHA-256 of the zip, verified if set
+	Variables   []TemplateVariable `json:"variables,omitempty"`
+}
+
+// TemplateVariable the variable of the template, the placeholder __YAO_<NAME>__ of the text files is replaced by the value.
+// The variable without the default is required.
+type TemplateVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+}
+
+// The built-in variables of the templates, the app name is the name of the directory by default
+var builtinVariables = []TemplateVariable{
+	{Name: "APP_NAME", Description: "The name of the application"},
+	{Name: "DB_DRIVER", Description: "The driver of the default connector, sqlite3, mysql or postgres", Default: "sqlite3"},
+	{Name: "DB_PRIMARY", Description: "The DSN of the default connector", Default: "./db/yao.db"},
+}
+
+var variableName = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// maxTemplateSize the max size of the zip of the template
+const maxTemplateSize = 200 << 20
+
+// Templates the templates of the registry, the registry is the URL or the path of the index
+func Templates(registry string) ([]Template, error) {
+	data, err := fetch(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	var index struct {
+		Templates []Template `json:"templates"`
+	}
+	if err := jsoniter.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("the registry %s is invalid: %s", registry, err.Error())
+	}
+
+	sort.Slice(index.Templates, func(i, j int) bool { return index.Templates[i].Name < index.Templates[j].Name })
+	return index.Templates, nil
+}
+
+// InstallTemplate download the template of the registry to the root and replace the placeholders of the variables.
+// The root should be empty, the variables not given are the defaults.
+func InstallTemplate(root string, registry string, name string, vars map[string]string) error {
+	templates, err := Templates(registry)
+	if err != nil {
+		return err
+	}
+
+	var tmpl *Template
+	for i := range templates {
+		if templates[i].Name == name {
+			tmpl = &templates[i]
+			break
+		}
+	}
+	if tmpl == nil {
+		return fmt.Errorf("the template %s does not exist in the registry %s", name, registry)
+	}
+
+	values, err := tmpl.values(root, vars)
+	if err != nil {
+		return err
+	}
+
+	data, err := fetch(resolve(registry, tmpl.URL))
+	if err != nil {
+		return err
+	}
+
+	if tmpl.Checksum != "" {
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), tmpl.Checksum) {
+			return fmt.Errorf("the checksum of the template %s does not match", name)
+		}
+	}
+
+	return extract(data, root, values)
+}
+
+// values the values of the variables, the given values override the defaults
+func (tmpl *Template) values(root string, vars map[string]string) (map[string]string, error) {
+	values := map[string]string{"APP_NAME": filepath.Base(root)}
+	for _, v := range append(append([]TemplateVariable{}, builtinVariables...), tmpl.Variables...) {
+		if v.Default != "" {
+			values[v.Name] = v.Default
+		}
+	}
+
+	for name, value := range vars {
+		if !variableName.MatchString(name) {
+			return nil, fmt.Errorf("the variable %s is invalid, e.g. APP_NAME", name)
+		}
+		values[name] = value
+	}
+
+	for _, v := range tmpl.Variables {
+		if _, has := values[v.Name]; !has {
+			return nil, fmt.Errorf("the variable %s of the template %s is required: %s", v.Name, tmpl.Name, v.Description)
+		}
+	}
+	return values, nil
+}
+
+// extract extract the zip to the root, the single top directory of the zip is removed
+func extract(data []byte, root string, values map[string]string) error {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("the template is not a zip file: %s", err.Error())
+	}
+
+	prefix := topDir(reader.File)
+	replacer := placeholders(values)
+	for _, file := range reader.File {
+		name := strings.TrimPrefix(file.Name, prefix)
+		if name == "" || file.FileInfo().IsDir() {
+			continue
+		}
+
+		dst := filepath.Join(root, filepath.FromSlash(name))
+		if !strings.HasPrefix(dst, filepath.Clean(root)+string(os.PathSeparator)) {
+			return fmt.Errorf("the file %s of the template is outside of the application", file.Name)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+			return err
+		}
+
+		content, err := readFile(file)
+		if err != nil {
+			return err
+		}
+
+		if isText(content) {
+			content = []byte(replacer.Replace(string(content)))
+		}
+
+		if err := os.WriteFile(dst, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, maxTemplateSize))
+}
+
+// topDir the single top directory of the files, empty if the files are not in the same directory
+func topDir(files []*zip.File) string {
+	prefix := ""
+	for _, file := range files {
+		parts := strings.SplitN(file.Name, "/", 2)
+		if len(parts) < 2 {
+			return ""
+		}
+		if prefix != "" && prefix != parts[0]+"/" {
+			return ""
+		}
+		prefix = parts[0] + "/"
+	}
+	return prefix
+}
+
+// placeholders the replacer of the placeholders __YAO_<NAME>__
+func placeholders(values map[string]string) *strings.Replacer {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := []string{}
+	for _, name := range names {
+		pairs = append(pairs, "__YAO_"+name+"__", values[name])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// isText the content is not binary, the content without the NUL bytes in the first 8000 bytes
+func isText(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return !bytes.Contains(content, []byte{0})
+}
+
+// resolve the URL of the template relative to the registry
+func resolve(registry string, ref string) string {
+	if strings.HasPrefix(registry, "http://") || strings.HasPrefix(registry, "https://") {
+		base, err := url.Parse(registry)
+		if err != nil {
+			return ref
+		}
+		u, err := base.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		return u.String()
+	}
+
+	if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") || filepath.IsAbs(ref) {
+		return ref
+	}
+	return filepath.Join(filepath.Dir(registry), ref)
+}
+
+// fetch read the URL or the local file
+func fetch(location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.ReadFile(location)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	res, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", location, res.Status)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, maxTemplateSize))
+}
@@ -0,0 +1,76 @@
+package setup
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallTemplate(t *testing.T) {
+	dir := t.TempDir()
+	data := templateZip(t, map[string]string{
+		"crm-main/app.yao":                 `{"name": "__YAO_APP_NAME__", "version": "1.0.0"}`,
+		"crm-main/.env":                    "YAO_DB_DRIVER=__YAO_DB_DRIVER__\nYAO_DB_PRIMARY=__YAO_DB_PRIMARY__\nCRM_CURRENCY=__YAO_CURRENCY__\n",
+		"crm-main/models/customer.mod.yao": `{"name": "{{ name }}"}`,
+		"crm-main/public/logo.png":         "\x89PNG\x00__YAO_APP_NAME__",
+	})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "crm.zip"), data, 0644))
+
+	sum := sha256.Sum256(data)
+	registry := filepath.Join(dir, "index.json")
+	assert.NoError(t, os.WriteFile(registry, []byte(fmt.Sprintf(`{"templates": [
+		{"name": "crm", "url": "crm.zip", "sha256": "%s", "variables": [{"name": "CURRENCY", "description": "The currency of the orders"}]},
+		{"name": "admin-only", "url": "crm.zip", "sha256": "0000"}
+	]}`, hex.EncodeToString(sum[:]))), 0644))
+
+	templates, err := Templates(registry)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin-only", templates[0].Name)
+
+	root := filepath.Join(dir, "mycrm")
+	err = InstallTemplate(root, registry, "crm", nil)
+	assert.ErrorContains(t, err, "CURRENCY")
+
+	err = InstallTemplate(root, registry, "crm", map[string]string{"CURRENCY": "EUR", "DB_DRIVER": "postgres"})
+	assert.NoError(t, err)
+
+	content, _ := os.ReadFile(filepath.Join(root, "app.yao"))
+	assert.Equal(t, `{"name": "mycrm", "version": "1.0.0"}`, string(content))
+	content, _ = os.ReadFile(filepath.Join(root, ".env"))
+	assert.Equal(t, "YAO_DB_DRIVER=postgres\nYAO_DB_PRIMARY=./db/yao.db\nCRM_CURRENCY=EUR\n", string(content))
+	content, _ = os.ReadFile(filepath.Join(root, "models", "customer.mod.yao"))
+	assert.Equal(t, `{"name": "{{ name }}"}`, string(content))
+	content, _ = os.ReadFile(filepath.Join(root, "public", "logo.png"))
+	assert.Equal(t, "\x89PNG\x00__YAO_APP_NAME__", string(content))
+
+	assert.ErrorContains(t, InstallTemplate(filepath.Join(dir, "admin"), registry, "admin-only", nil), "checksum")
+	assert.ErrorContains(t, InstallTemplate(filepath.Join(dir, "bot"), registry, "knowledge-bot", nil), "does not exist")
+	assert.Error(t, InstallTemplate(filepath.Join(dir, "crm2"), registry, "crm", map[string]string{"CURRENCY": "EUR", "app-name": "x"}))
+
+	// The files outside of the application are rejected
+	assert.ErrorContains(t, extract(templateZip(t, map[string]string{"app.yao": "{}", "../evil.sh": "rm -rf /"}), filepath.Join(dir, "evil"), nil), "outside")
+	assert.Equal(t, "https://mirrors.example.com/templates/crm.zip", resolve("https://mirrors.example.com/templates/index.json", "crm.zip"))
+}
+
+func templateZip(t *testing.T, files map[string]string) []byte {
+	buf := bytes.Buffer{}
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
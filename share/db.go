@@ -34,6 +34,11 @@ func DBConnect(dbconfig config.Database) (err error) {
 		}
 	}
 
+	err = DBPoolOf(dbconfig).Apply("default", manager)
+	if err != nil {
+		return err
+	}
+
 	manager.SetAsGlobal()
 	go func() {
 		for _, c := range manager.Pool.Primary {
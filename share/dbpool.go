@@ -0,0 +1,173 @@
+package share
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/config"
+)
+
+// DBPool the connection pool and the slow query log of the database connections, the zero values are the defaults of the driver
+//
+//	"pool": { "max_open": 50, "max_idle": 10, "max_lifetime": 3600, "slow_query": 500 }
+type DBPool struct {
+	MaxOpen     int `json:"max_open,omitempty"`      // The max open connections
+	MaxIdle     int `json:"max_idle,omitempty"`      // The max idle connections
+	MaxLifetime int `json:"max_lifetime,omitempty"`  // The max lifetime of the connections in seconds
+	MaxIdleTime int `json:"max_idle_time,omitempty"` // The max idle time of the connections in seconds
+	SlowQuery   int `json:"slow_query,omitempty"`    // The queries slower than the milliseconds are logged
+}
+
+// dbManagers the managers of the connections, the default database and the database connectors
+var dbManagers = map[string]*capsule.Manager{}
+var dbManagersLock sync.RWMutex
+
+// DBPoolOf the pool of the database config
+func DBPoolOf(cfg config.Database) DBPool {
+	return DBPool{
+		MaxOpen:     cfg.MaxOpen,
+		MaxIdle:     cfg.MaxIdle,
+		MaxLifetime: cfg.MaxLifetime,
+		MaxIdleTime: cfg.MaxIdleTime,
+		SlowQuery:   cfg.SlowQuery,
+	}
+}
+
+// Apply set the pool to the connections of the manager, the stats of the connections are collected with the name
+func (pool DBPool) Apply(name string, manager *capsule.Manager) error {
+	conns := append([]*capsule.Connection{}, manager.Pool.Primary...)
+	conns = append(conns, manager.Pool.Secondary...)
+	for _, conn := range conns {
+		if pool.SlowQuery > 0 {
+			db, err := pool.slowDB(conn)
+			if err != nil {
+				return fmt.Errorf("%s %s slow query log %s", name, conn.Config.Name, err.Error())
+			}
+			conn.DB = db
+		}
+		pool.set(conn.DB.DB)
+	}
+
+	dbManagersLock.Lock()
+	defer dbManagersLock.Unlock()
+	dbManagers[name] = manager
+	return nil
+}
+
+// set the limits of the pool
+func (pool DBPool) set(db *sql.DB) {
+	if pool.MaxOpen > 0 {
+		db.SetMaxOpenConns(pool.MaxOpen)
+	}
+	if pool.MaxIdle > 0 {
+		db.SetMaxIdleConns(pool.MaxIdle)
+	}
+	if pool.MaxLifetime > 0 {
+		db.SetConnMaxLifetime(time.Duration(pool.MaxLifetime) * time.Second)
+	}
+	if pool.MaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(time.Duration(pool.MaxIdleTime) * time.Second)
+	}
+}
+
+// slowDB the database of the connection logs the slow queries, the plans of the slow queries are logged in the development mode
+func (pool DBPool) slowDB(conn *capsule.Connection) (*sqlx.DB, error) {
+	threshold := time.Duration(pool.SlowQuery) * time.Millisecond
+	connector, err := newSlowConnector(conn.Config.Name, conn.DB.Driver(), conn.Config.DSN, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	// The plans are explained with the previous database, closed if not used
+	if config.Conf.Mode == "development" {
+		connector.explain = explainer(conn.Config.Name, conn.Config.Driver, conn.DB.DB)
+	} else if err := conn.DB.Close(); err != nil {
+		return nil, err
+	}
+	return sqlx.NewDb(sql.OpenDB(connector), conn.Config.Driver), nil
+}
+
+// DBPing ping the primary connections of the managers, the names are the default database and the database connectors,
+// all the managers are pinged if no name is given
+func DBPing(ctx context.Context, names ...string) error {
+	dbManagersLock.RLock()
+	managers := map[string]*capsule.Manager{}
+	for name, manager := range dbManagers {
+		managers[name] = manager
+	}
+	dbManagersLock.RUnlock()
+
+	if len(names) == 0 {
+		for name := range managers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		manager, has := managers[name]
+		if !has {
+			return fmt.Errorf("the database %s is not connected", name)
+		}
+		for _, conn := range manager.Pool.Primary {
+			if err := conn.DB.PingContext(ctx); err != nil {
+				return fmt.Errorf("%s %s: %s", name, conn.Config.Name, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// DBStats the stats of the connection pools, the keys are the names of the managers
+func DBStats() map[string][]map[string]interface{} {
+	dbManagersLock.RLock()
+	defer dbManagersLock.RUnlock()
+
+	stats := map[string][]map[string]interface{}{}
+	for name, manager := range dbManagers {
+		conns := append([]*capsule.Connection{}, manager.Pool.Primary...)
+		conns = append(conns, manager.Pool.Secondary...)
+
+		stats[name] = []map[string]interface{}{}
+		for _, conn := range conns {
+			stat := dbStat(conn.DB.Stats())
+			stat["name"] = conn.Config.Name
+			stat["driver"] = conn.Config.Driver
+			stat["readonly"] = conn.Config.ReadOnly
+			stats[name] = append(stats[name], stat)
+		}
+
+		sort.Slice(stats[name], func(i, j int) bool {
+			return fmt.Sprintf("%v", stats[name][i]["name"]) < fmt.Sprintf("%v", stats[name][j]["name"])
+		})
+	}
+	return stats
+}
+
+// dbStat the stats of the pool, the durations are in milliseconds
+func dbStat(stats sql.DBStats) map[string]interface{} {
+	return map[string]interface{}{
+		"max_open":             stats.MaxOpenConnections,
+		"open":                 stats.OpenConnections,
+		"in_use":               stats.InUse,
+		"idle":                 stats.Idle,
+		"wait_count":           stats.WaitCount,
+		"wait_duration":        stats.WaitDuration.Milliseconds(),
+		"max_idle_closed":      stats.MaxIdleClosed,
+		"max_idle_time_closed": stats.MaxIdleTimeClosed,
+		"max_lifetime_closed":  stats.MaxLifetimeClosed,
+	}
+}
+
+// DBUnregister remove the manager from the stats
+func DBUnregister(name string) {
+	dbManagersLock.Lock()
+	defer dbManagersLock.Unlock()
+	delete(dbManagers, name)
+}
@@ -3,6 +3,9 @@ package share
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
 
 	"github.com/yaoapp/kun/log"
 	"github.com/yaoapp/yao/config"
@@ -12,6 +15,19 @@ import (
 
 var sessionDB *session.BuntDB
 
+// GuestPrefix the prefix of the sessions of the anonymous requests
+const GuestPrefix = "guest:"
+
+// GuestSID a new session of the anonymous request, the session has no values
+func GuestSID() string {
+	return GuestPrefix + uuid.New().String()
+}
+
+// IsGuest the session is the session of the anonymous request
+func IsGuest(sid string) bool {
+	return strings.HasPrefix(sid, GuestPrefix)
+}
+
 // SessionStart start session
 func SessionStart() error {
 	if config.Conf.Session.Store == "file" {
@@ -48,7 +64,7 @@ func SessionRedis() error {
 
 	session.Register("redis", rdb)
 	session.Name = "redis"
-	log.Trace("Session Store:REDIS HOST:%s PORT:%s DB:%s", config.Conf.Session.Host, config.Conf.Session.Port, config.Conf.Session.DB)
+	log.Trace("Session Store:REDIS HOST:%s PORT:%s DB:%s TTL:%d", config.Conf.Session.Host, config.Conf.Session.Port, config.Conf.Session.DB, config.Conf.Session.TTL)
 	return nil
 }
 
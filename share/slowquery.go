@@ -0,0 +1,286 @@
+package share
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+)
+
+// slowConnector the connector of the database driver, the queries slower than the threshold are logged
+type slowConnector struct {
+	name      string
+	driver    driver.Driver
+	dsn       string
+	connector driver.Connector // nil if the driver does not implement driver.DriverContext
+	threshold time.Duration
+	explain   func(query string, args []driver.NamedValue) // nil if the slow queries are not explained
+}
+
+// newSlowConnector the connector of the driver logs the queries slower than the threshold
+func newSlowConnector(name string, drv driver.Driver, dsn string, threshold time.Duration) (*slowConnector, error) {
+	c := &slowConnector{name: name, driver: drv, dsn: dsn, threshold: threshold}
+	if ctx, ok := drv.(driver.DriverContext); ok {
+		connector, err := ctx.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		c.connector = connector
+	}
+	return c, nil
+}
+
+// Connect the connection of the driver
+func (c *slowConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	var conn driver.Conn
+	var err error
+	if c.connector != nil {
+		conn, err = c.connector.Connect(ctx)
+	} else {
+		conn, err = c.driver.Open(c.dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &slowConn{Conn: conn, connector: c}, nil
+}
+
+// Driver the driver of the connector
+func (c *slowConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// log the query if it is slower than the threshold, the select queries are explained
+func (c *slowConnector) log(query string, args []driver.NamedValue, start time.Time) {
+	elapsed := time.Since(start)
+	if elapsed < c.threshold {
+		return
+	}
+
+	log.Warn("[DB] %s slow query %dms: %s", c.name, elapsed.Milliseconds(), query)
+	if c.explain != nil && isSelect(query) {
+		go c.explain(query, args)
+	}
+}
+
+// isSelect the query is a select query
+func isSelect(query string) bool {
+	query = strings.ToUpper(strings.TrimLeft(query, " \t\r\n("))
+	return strings.HasPrefix(query, "SELECT") || strings.HasPrefix(query, "WITH")
+}
+
+// explainer explain the slow queries with the database, the plans are logged
+func explainer(name string, driverName string, db *sql.DB) func(query string, args []driver.NamedValue) {
+	prefix := "EXPLAIN "
+	if driverName == "sqlite3" {
+		prefix = "EXPLAIN QUERY PLAN "
+	}
+
+	return func(query string, args []driver.NamedValue) {
+		values := []interface{}{}
+		for _, arg := range args {
+			values = append(values, arg.Value)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		plan, err := explain(ctx, db, prefix+query, values)
+		if err != nil {
+			log.Error("[DB] %s explain the slow query: %s", name, err.Error())
+			return
+		}
+		log.Warn("[DB] %s the plan of the slow query: %s\n%s", name, query, plan)
+	}
+}
+
+// explain the lines of the plan, the columns of the rows are separated by the tabs
+func explain(ctx context.Context, db *sql.DB, query string, args []interface{}) (string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(ptrs...); err != nil {
+			return "", err
+		}
+
+		fields := []string{}
+		for _, value := range values {
+			if bytes, ok := value.([]byte); ok {
+				value = string(bytes)
+			}
+			fields = append(fields, fmt.Sprintf("%v", value))
+		}
+		lines = append(lines, strings.Join(fields, "\t"))
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
+
+// slowConn the connection of the slow query log, the interfaces of the driver are passed through
+type slowConn struct {
+	driver.Conn
+	connector *slowConnector
+}
+
+// ExecContext implements driver.ExecerContext
+func (conn *slowConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := conn.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	res, err := execer.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		conn.connector.log(query, args, start)
+	}
+	return res, err
+}
+
+// QueryContext implements driver.QueryerContext
+func (conn *slowConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := conn.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		conn.connector.log(query, args, start)
+	}
+	return rows, err
+}
+
+// Prepare implements driver.Conn
+func (conn *slowConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := conn.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &slowStmt{Stmt: stmt, query: query, connector: conn.connector}, nil
+}
+
+// PrepareContext implements driver.ConnPrepareContext
+func (conn *slowConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	preparer, ok := conn.Conn.(driver.ConnPrepareContext)
+	if !ok {
+		return conn.Prepare(query)
+	}
+
+	stmt, err := preparer.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &slowStmt{Stmt: stmt, query: query, connector: conn.connector}, nil
+}
+
+// BeginTx implements driver.ConnBeginTx
+func (conn *slowConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := conn.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return conn.Conn.Begin() //nolint:staticcheck // the drivers without driver.ConnBeginTx
+}
+
+// CheckNamedValue implements driver.NamedValueChecker
+func (conn *slowConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := conn.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// ResetSession implements driver.SessionResetter
+func (conn *slowConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := conn.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator
+func (conn *slowConn) IsValid() bool {
+	if validator, ok := conn.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// Ping implements driver.Pinger
+func (conn *slowConn) Ping(ctx context.Context) error {
+	if pinger, ok := conn.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// slowStmt the prepared statement of the slow query log
+type slowStmt struct {
+	driver.Stmt
+	query     string
+	connector *slowConnector
+}
+
+// ExecContext implements driver.StmtExecContext
+func (stmt *slowStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	defer stmt.connector.log(stmt.query, args, start)
+
+	if execer, ok := stmt.Stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+
+	values, err := valuesOf(args)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Stmt.Exec(values) //nolint:staticcheck // the drivers without driver.StmtExecContext
+}
+
+// QueryContext implements driver.StmtQueryContext
+func (stmt *slowStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	defer stmt.connector.log(stmt.query, args, start)
+
+	if queryer, ok := stmt.Stmt.(driver.StmtQueryContext); ok {
+		return queryer.QueryContext(ctx, args)
+	}
+
+	values, err := valuesOf(args)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Stmt.Query(values) //nolint:staticcheck // the drivers without driver.StmtQueryContext
+}
+
+// valuesOf the values of the ordinal args, the named args are not supported by the legacy statements
+func valuesOf(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("the driver does not support the named parameter %s", arg.Name)
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,90 @@
+package share
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowQuery(t *testing.T) {
+	connector, err := newSlowConnector("test", &testDriver{}, "test", 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explained := make(chan string, 10)
+	connector.explain = func(query string, args []driver.NamedValue) {
+		explained <- query
+	}
+
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	// The fast queries are not explained
+	rows, err := db.Query("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	// The slow updates are logged but not explained
+	_, err = db.Exec("UPDATE slow SET a = ?", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err = db.Query("SELECT * FROM slow WHERE a = ?", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	select {
+	case query := <-explained:
+		assert.Equal(t, "SELECT * FROM slow WHERE a = ?", query)
+	case <-time.After(time.Second):
+		t.Fatal("the slow query is not explained")
+	}
+	assert.Empty(t, explained)
+
+	assert.True(t, isSelect(" (select 1)"))
+	assert.True(t, isSelect("WITH t AS (SELECT 1) SELECT * FROM t"))
+	assert.False(t, isSelect("DELETE FROM t"))
+}
+
+// testDriver the queries of the slow table take 30ms
+type testDriver struct{}
+
+func (d *testDriver) Open(name string) (driver.Conn, error) { return &testConn{}, nil }
+
+type testConn struct{}
+
+func (c *testConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *testConn) Close() error                              { return nil }
+func (c *testConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (c *testConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "slow") {
+		time.Sleep(30 * time.Millisecond)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *testConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(query, "slow") {
+		time.Sleep(30 * time.Millisecond)
+	}
+	return &testRows{}, nil
+}
+
+type testRows struct{}
+
+func (r *testRows) Columns() []string              { return []string{"a"} }
+func (r *testRows) Close() error                   { return nil }
+func (r *testRows) Next(dest []driver.Value) error { return io.EOF }
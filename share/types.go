@@ -82,6 +82,7 @@ type AppInfo struct {
 	XGen         string                 `json:"xgen,omitempty"`
 	AdminRoot    string                 `json:"adminRoot,omitempty"`
 	Static       Static                 `json:"public,omitempty"`
+	OpenAPI      *OpenAPI               `json:"openapi,omitempty"`
 	Optional     map[string]interface{} `json:"optional,omitempty"`
 	Moapi        Moapi                  `json:"moapi,omitempty"`
 	AfterLoad    string                 `json:"afterLoad,omitempty"`    // Process executed after the app is loaded
@@ -96,11 +97,20 @@ type Moapi struct {
 	Organization string   `json:"organization,omitempty"`
 }
 
+// OpenAPI the OpenAPI document setting
+type OpenAPI struct {
+	Enable bool   `json:"enable,omitempty"` // Serve the document at /openapi.json and the Swagger UI at /openapi
+	UI     string `json:"ui,omitempty"`     // The base url of the Swagger UI assets, default is https://unpkg.com/swagger-ui-dist@5
+}
+
 // Static setting
 type Static struct {
 	DisableGzip bool                `json:"disableGzip,omitempty"`
 	Rewrite     []map[string]string `json:"rewrite,omitempty"`
 	SourceRoots map[string]string   `json:"sourceRoots,omitempty"`
+	MaxAge      int                 `json:"maxAge,omitempty"`      // The seconds the public files are cached, the files are revalidated by the ETag if it is 0
+	Fingerprint bool                `json:"fingerprint,omitempty"` // Serve the fingerprinted names, e.g. /js/app.3f2a1b9c0d.js, as immutable
+	CDN         string              `json:"cdn,omitempty"`         // The base URL of the asset URLs, e.g. https://cdn.example.com
 }
 
 // AppStorage 应用存储
@@ -0,0 +1,373 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+)
+
+// event the payload of the events API, or the url verification
+type event struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge,omitempty"`
+	TeamID    string `json:"team_id"`
+	EventID   string `json:"event_id"`
+	Event     struct {
+		Type        string `json:"type"`
+		Subtype     string `json:"subtype,omitempty"`
+		BotID       string `json:"bot_id,omitempty"`
+		User        string `json:"user"`
+		Text        string `json:"text"`
+		Channel     string `json:"channel"`
+		ChannelType string `json:"channel_type,omitempty"`
+		TS          string `json:"ts"`
+		ThreadTS    string `json:"thread_ts,omitempty"`
+	} `json:"event"`
+}
+
+// received the ids of the events received in the last 10 minutes, the retries of Slack are answered once
+var received = map[string]time.Time{}
+var receivedLock sync.Mutex
+
+// updateInterval the min interval of the updates of the streamed reply, chat.update is rate limited
+var updateInterval = time.Second
+
+// SetRoutes the request urls of the bots, the request urls of the bot slacks/support.yao are <path>/support/*
+//
+//	POST <path>/:id/events    The events API, the app mentions and the direct messages are replied in the thread
+//	POST <path>/:id/commands  The slash commands, the reply is sent by the response url
+//	GET  <path>/:id/install   Redirect to the page installing the app into the workspace
+//	GET  <path>/:id/oauth     The redirect url of the install flow, the bot token of the workspace is saved
+func SetRoutes(router *gin.Engine, path string) {
+	router.POST(path+"/:id/events", handleEvent)
+	router.POST(path+"/:id/commands", handleCommand)
+	router.GET(path+"/:id/install", func(c *gin.Context) { handleInstall(c, path) })
+	router.GET(path+"/:id/oauth", func(c *gin.Context) { handleOAuth(c, path) })
+}
+
+func handleEvent(c *gin.Context) {
+	bot, raw, ok := verify(c)
+	if !ok {
+		return
+	}
+
+	payload := event{}
+	if err := jsoniter.Unmarshal(raw, &payload); err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": errInvalid.Error()})
+		return
+	}
+
+	if payload.Type == "url_verification" {
+		c.JSON(200, gin.H{"challenge": payload.Challenge})
+		return
+	}
+
+	ev := payload.Event
+	switch ev.Type {
+	case "app_uninstalled", "tokens_revoked":
+		if err := Uninstall(bot.ID, payload.TeamID); err != nil {
+			log.Error("[Slack] %s uninstall %s: %s", bot.ID, payload.TeamID, err.Error())
+		}
+		c.Status(200)
+		return
+
+	case "app_mention":
+
+	case "message":
+		if ev.ChannelType != "im" || ev.Subtype != "" {
+			c.Status(200)
+			return
+		}
+
+	default:
+		c.Status(200)
+		return
+	}
+
+	// The messages of the bots are not answered, including the replies of the app
+	if ev.BotID != "" {
+		c.Status(200)
+		return
+	}
+
+	msg := &Message{
+		EventID:     payload.EventID,
+		TeamID:      payload.TeamID,
+		Channel:     ev.Channel,
+		ChannelType: ev.ChannelType,
+		User:        ev.User,
+		Text:        text(ev.Text),
+		TS:          ev.TS,
+		ThreadTS:    ev.ThreadTS,
+	}
+
+	// Slack retries the event not answered in 3 seconds, the message is answered in the background
+	if !duplicated(msg.EventID) {
+		go reply(bot, msg)
+	}
+	c.Status(200)
+}
+
+func handleCommand(c *gin.Context) {
+	bot, raw, ok := verify(c)
+	if !ok {
+		return
+	}
+
+	form, err := url.ParseQuery(string(raw))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": errInvalid.Error()})
+		return
+	}
+
+	msg := &Message{
+		TeamID:      form.Get("team_id"),
+		Channel:     form.Get("channel_id"),
+		User:        form.Get("user_id"),
+		Text:        strings.TrimSpace(form.Get("text")),
+		Command:     form.Get("command"),
+		ResponseURL: form.Get("response_url"),
+	}
+
+	// The command is acknowledged in 3 seconds and shown to the channel, the reply is sent later
+	go reply(bot, msg)
+	c.JSON(200, gin.H{"response_type": "in_channel"})
+}
+
+func handleInstall(c *gin.Context, path string) {
+	bot, err := Select(c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"code": 404, "message": err.Error()})
+		return
+	}
+
+	if bot.ClientID == "" {
+		c.JSON(400, gin.H{"code": 400, "message": fmt.Sprintf("the slack bot %s is not distributed", bot.ID)})
+		return
+	}
+
+	query := url.Values{}
+	query.Set("client_id", bot.ClientID)
+	query.Set("scope", strings.Join(bot.Scopes, ","))
+	query.Set("redirect_uri", redirectURL(c, path, bot.ID))
+	query.Set("state", bot.state(time.Now().Unix()))
+	c.Redirect(302, bot.API+"/oauth/v2/authorize?"+query.Encode())
+}
+
+func handleOAuth(c *gin.Context, path string) {
+	bot, err := Select(c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"code": 404, "message": err.Error()})
+		return
+	}
+
+	if e := c.Query("error"); e != "" {
+		c.JSON(400, gin.H{"code": 400, "message": e})
+		return
+	}
+
+	if !bot.checkState(c.Query("state")) {
+		c.JSON(400, gin.H{"code": 400, "message": "the state is invalid or expired"})
+		return
+	}
+
+	inst, err := bot.Access(c.Query("code"), redirectURL(c, path, bot.ID))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	err = Install(*inst)
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+
+	if bot.Redirect != "" {
+		c.Redirect(302, bot.Redirect)
+		return
+	}
+	c.JSON(200, gin.H{"bot": bot.ID, "team_id": inst.TeamID, "team_name": inst.TeamName})
+}
+
+// verify read the body and verify the signature of the request, the response is sent if it fails
+func verify(c *gin.Context) (*Bot, []byte, bool) {
+	bot, err := Select(c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"code": 404, "message": err.Error()})
+		return nil, nil, false
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return nil, nil, false
+	}
+
+	err = bot.Verify(c.GetHeader("X-Slack-Request-Timestamp"), c.GetHeader("X-Slack-Signature"), raw)
+	if err != nil {
+		c.JSON(401, gin.H{"code": 401, "message": err.Error()})
+		return nil, nil, false
+	}
+	return bot, raw, true
+}
+
+// redirectURL the redirect url of the install flow, must be one of the redirect urls of the app
+func redirectURL(c *gin.Context, path string, id string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return fmt.Sprintf("%s://%s%s/%s/oauth", scheme, c.Request.Host, path, id)
+}
+
+// state the state of the install flow, the timestamp signed by the client secret, expires in 10 minutes
+func (bot *Bot) state(ts int64) string {
+	mac := hmac.New(sha256.New, []byte(bot.ClientSecret))
+	mac.Write([]byte(fmt.Sprintf("%s.%d", bot.ID, ts)))
+	return fmt.Sprintf("%d.%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func (bot *Bot) checkState(state string) bool {
+	parts := strings.SplitN(state, ".", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || time.Now().Unix()-ts > 600 {
+		return false
+	}
+	return hmac.Equal([]byte(bot.state(ts)), []byte(state))
+}
+
+// reply answer the message and reply to it, the reply of the assistant is streamed to the thread
+func reply(bot *Bot, msg *Message) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error("[Slack] %s %s: %v", bot.ID, msg.User, err)
+		}
+	}()
+
+	if msg.Command != "" {
+		text, err := bot.Answer(msg, nil)
+		if err != nil {
+			log.Error("[Slack] %s %s %s: %s", bot.ID, msg.Command, msg.User, err.Error())
+			return
+		}
+
+		if text == "" {
+			return
+		}
+
+		if err := bot.Respond(msg.ResponseURL, text); err != nil {
+			log.Error("[Slack] %s respond to %s: %s", bot.ID, msg.Command, err.Error())
+		}
+		return
+	}
+
+	// The direct messages are replied in the thread only if they are in a thread
+	thread := msg.Thread()
+	if msg.ChannelType == "im" {
+		thread = msg.ThreadTS
+	}
+
+	s := &stream{bot: bot, msg: msg, thread: thread}
+	text, err := bot.Answer(msg, s.update)
+	if err != nil {
+		log.Error("[Slack] %s %s: %s", bot.ID, msg.User, err.Error())
+		return
+	}
+
+	if err := s.done(text); err != nil {
+		log.Error("[Slack] %s reply to %s: %s", bot.ID, msg.Channel, err.Error())
+	}
+}
+
+// stream the reply streamed to the message, posted with the first text and updated at most once per interval
+type stream struct {
+	bot     *Bot
+	msg     *Message
+	thread  string
+	ts      string
+	text    string
+	updated time.Time
+}
+
+func (s *stream) update(text string) {
+	if strings.TrimSpace(text) == "" || time.Since(s.updated) < updateInterval {
+		return
+	}
+
+	err := s.send(text)
+	if err != nil {
+		log.Warn("[Slack] %s stream to %s: %s", s.bot.ID, s.msg.Channel, err.Error())
+	}
+	s.updated = time.Now()
+}
+
+// done send the whole reply, the streamed message is updated if it is posted
+func (s *stream) done(text string) error {
+	if text == "" || text == s.text {
+		return nil
+	}
+	return s.send(text)
+}
+
+func (s *stream) send(text string) error {
+	if s.ts == "" {
+		ts, err := s.bot.Send(s.msg.TeamID, s.msg.Channel, text, s.thread)
+		if err != nil {
+			return err
+		}
+		s.ts = ts
+		s.text = text
+		return nil
+	}
+
+	err := s.bot.Update(s.msg.TeamID, s.msg.Channel, s.ts, text)
+	if err != nil {
+		return err
+	}
+	s.text = text
+	return nil
+}
+
+// duplicated the event is received before or not
+func duplicated(id string) bool {
+	if id == "" {
+		return false
+	}
+
+	receivedLock.Lock()
+	defer receivedLock.Unlock()
+
+	now := time.Now()
+	if at, has := received[id]; has && now.Sub(at) < 10*time.Minute {
+		return true
+	}
+
+	if len(received) > 1000 {
+		for key, at := range received {
+			if now.Sub(at) >= 10*time.Minute {
+				delete(received, key)
+			}
+		}
+	}
+	received[id] = now
+	return false
+}
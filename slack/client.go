@@ -0,0 +1,152 @@
+package slack
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+type apiResponse struct {
+	OK          bool   `json:"ok"`
+	Error       string `json:"error,omitempty"`
+	TS          string `json:"ts,omitempty"`
+	Channel     string `json:"channel,omitempty"`
+	AccessToken string `json:"access_token,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+	BotUserID   string `json:"bot_user_id,omitempty"`
+	Team        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"team,omitempty"`
+}
+
+// Send the message to the channel of the workspace, in the thread if the thread ts is set, returns the ts of the message
+func (bot *Bot) Send(team string, channel string, text string, thread string) (string, error) {
+	token, err := bot.token(team)
+	if err != nil {
+		return "", err
+	}
+
+	message := map[string]interface{}{"channel": channel, "text": text}
+	if thread != "" {
+		message["thread_ts"] = thread
+	}
+
+	res := apiResponse{}
+	err = bot.call("chat.postMessage", token, message, &res)
+	if err != nil {
+		return "", err
+	}
+	return res.TS, nil
+}
+
+// Update the text of the message
+func (bot *Bot) Update(team string, channel string, ts string, text string) error {
+	token, err := bot.token(team)
+	if err != nil {
+		return err
+	}
+	return bot.call("chat.update", token, map[string]interface{}{"channel": channel, "ts": ts, "text": text}, &apiResponse{})
+}
+
+// Respond to the slash command by the response url, the response is visible to the members of the channel
+func (bot *Bot) Respond(responseURL string, text string) error {
+	if !strings.HasPrefix(responseURL, "https://hooks.slack.com/") {
+		return fmt.Errorf("the response url %s is invalid", responseURL)
+	}
+
+	payload, err := jsoniter.Marshal(map[string]interface{}{"response_type": "in_channel", "text": text})
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(responseURL, "application/json; charset=utf-8", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("slack response url responds %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// Access exchange the code of the install flow for the bot token of the workspace
+func (bot *Bot) Access(code string, redirect string) (*Installation, error) {
+	form := url.Values{}
+	form.Set("client_id", bot.ClientID)
+	form.Set("client_secret", bot.ClientSecret)
+	form.Set("code", code)
+	form.Set("redirect_uri", redirect)
+
+	resp, err := client.PostForm(bot.API+"/api/oauth.v2.access", form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res := apiResponse{}
+	err = jsoniter.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return nil, fmt.Errorf("slack oauth.v2.access responds %d", resp.StatusCode)
+	}
+
+	if !res.OK {
+		return nil, fmt.Errorf("slack oauth.v2.access: %s", res.Error)
+	}
+
+	return &Installation{
+		Bot:       bot.ID,
+		TeamID:    res.Team.ID,
+		TeamName:  res.Team.Name,
+		BotUserID: res.BotUserID,
+		Token:     res.AccessToken,
+		Scope:     res.Scope,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// call the method of the Web API, the error of the response is returned if it is not ok
+func (bot *Bot) call(method string, token string, message map[string]interface{}, res *apiResponse) error {
+	payload, err := jsoniter.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, bot.API+"/api/"+method, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("slack %s is rate limited, retry after %ss", method, resp.Header.Get("Retry-After"))
+	}
+
+	err = jsoniter.NewDecoder(resp.Body).Decode(res)
+	if err != nil {
+		return fmt.Errorf("slack %s responds %d", method, resp.StatusCode)
+	}
+
+	if !res.OK {
+		return fmt.Errorf("slack %s: %s", method, res.Error)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package slack
+
+import (
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("yao.slack", map[string]process.Handler{
+		"send":   processSend,
+		"update": processUpdate,
+	})
+}
+
+// processSend yao.slack.Send(bot, team_id, channel, text, thread_ts?), returns the ts of the message
+func processSend(process *process.Process) interface{} {
+	process.ValidateArgNums(4)
+	bot, err := Select(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+
+	thread := ""
+	if process.NumOfArgs() > 4 {
+		thread = process.ArgsString(4)
+	}
+
+	ts, err := bot.Send(process.ArgsString(1), process.ArgsString(2), process.ArgsString(3), thread)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return ts
+}
+
+// processUpdate yao.slack.Update(bot, team_id, channel, ts, text)
+func processUpdate(process *process.Process) interface{} {
+	process.ValidateArgNums(5)
+	bot, err := Select(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+
+	err = bot.Update(process.ArgsString(1), process.ArgsString(2), process.ArgsString(3), process.ArgsString(4))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/neo/assistant"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	"github.com/yaoapp/yao/share"
+)
+
+// Bot the Slack app of the slacks/*.yao, the mentions, the direct messages and the slash commands are answered by the assistant or the process.
+// The app is installed into the workspaces by the OAuth flow, the token is used for the single workspace app without the installations.
+//
+//	{
+//	  "name": "Support",
+//	  "client_id": "$ENV.SLACK_CLIENT_ID",
+//	  "client_secret": "$ENV.SLACK_CLIENT_SECRET",
+//	  "signing_secret": "$ENV.SLACK_SIGNING_SECRET",
+//	  "scopes": ["app_mentions:read", "chat:write", "commands", "im:history"],
+//	  "assistant": "support"
+//	}
+type Bot struct {
+	ID            string   `json:"-"`
+	Name          string   `json:"name,omitempty"`
+	ClientID      string   `json:"client_id,omitempty"`
+	ClientSecret  string   `json:"client_secret,omitempty"`
+	SigningSecret string   `json:"signing_secret"`
+	Token         string   `json:"token,omitempty"`     // The bot token of the single workspace app, xoxb-
+	Scopes        []string `json:"scopes,omitempty"`    // The bot scopes requested by the install flow
+	Redirect      string   `json:"redirect,omitempty"`  // The page redirected to after the app is installed
+	Assistant     string   `json:"assistant,omitempty"` // The assistant answering the messages, the reply is streamed to the thread
+	Process       string   `json:"process,omitempty"`   // The process called with the message instead of the assistant, returns the reply
+	API           string   `json:"api,omitempty"`       // https://slack.com by default
+}
+
+// Message the message of the app_mention and message events, or the slash command
+type Message struct {
+	EventID     string `json:"event_id,omitempty"`
+	TeamID      string `json:"team_id"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type,omitempty"` // im, channel, group or mpim
+	User        string `json:"user"`
+	Text        string `json:"text"`
+	TS          string `json:"ts,omitempty"`
+	ThreadTS    string `json:"thread_ts,omitempty"`
+	Command     string `json:"command,omitempty"`      // The slash command, e.g. /ask
+	ResponseURL string `json:"response_url,omitempty"` // The response url of the slash command
+}
+
+// Bots the loaded bots
+var Bots = map[string]*Bot{}
+var lock sync.RWMutex
+
+// The errors of the requests
+var (
+	errInvalid   = errors.New("the request is invalid")
+	errSignature = errors.New("the signature is invalid")
+	errExpired   = errors.New("the request is expired")
+)
+
+// mentionRe the mentions of the message, e.g. <@U024BE7LH>
+var mentionRe = regexp.MustCompile(`<@[A-Z0-9]+(\|[^>]*)?>\s*`)
+
+// Load the slacks/*.yao bots, the table of the installations is created if the database is connected
+func Load(cfg config.Config) error {
+	bots := map[string]*Bot{}
+	messages := []string{}
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("slacks", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		bot := Bot{}
+		err = application.Parse(file, bytes, &bot)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		bot.ID = share.ID(root, file)
+		if err := bot.validate(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		bots[bot.ID] = &bot
+		return nil
+	}, exts...)
+
+	lock.Lock()
+	Bots = bots
+	lock.Unlock()
+
+	if len(bots) > 0 && capsule.Global != nil {
+		if err := migrate(); err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+	return err
+}
+
+// Select the bot
+func Select(id string) (*Bot, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	bot, has := Bots[id]
+	if !has {
+		return nil, fmt.Errorf("the slack bot %s does not exist", id)
+	}
+	return bot, nil
+}
+
+// Answer the reply of the message, empty if the message is not answered.
+// The text replied so far is passed to the cb while the reply of the assistant is streamed.
+func (bot *Bot) Answer(msg *Message, cb func(text string)) (string, error) {
+	if bot.Process != "" {
+		p, err := process.Of(bot.Process, bot.ID, msg.Map())
+		if err != nil {
+			return "", err
+		}
+
+		res, err := p.Exec()
+		if err != nil {
+			return "", err
+		}
+
+		if res == nil {
+			return "", nil
+		}
+		return fmt.Sprintf("%v", res), nil
+	}
+
+	if msg.Text == "" {
+		return "", nil
+	}
+
+	ast, err := assistant.Get(bot.Assistant)
+	if err != nil {
+		return "", err
+	}
+
+	sid, cid := msg.chat(bot.ID)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	return ast.ReplyStream(chatctx.Context{Context: ctx, Sid: sid, ChatID: cid, AssistantID: ast.ID}, msg.Text, cb)
+}
+
+// chat the sid and the chat id of the message.
+// The user chats with the assistant in a single chat by the direct messages and the slash commands, the thread of the channel is a chat shared by the members.
+func (msg *Message) chat(bot string) (string, string) {
+	sid := fmt.Sprintf("slack:%s:%s:%s", bot, msg.TeamID, msg.User)
+	if msg.Command != "" || msg.ChannelType == "im" {
+		return sid, sid
+	}
+	return sid, fmt.Sprintf("slack:%s:%s:%s:%s", bot, msg.TeamID, msg.Channel, msg.Thread())
+}
+
+// Thread the ts of the thread replied to, the message starts the thread if it is not in a thread
+func (msg *Message) Thread() string {
+	if msg.ThreadTS != "" {
+		return msg.ThreadTS
+	}
+	return msg.TS
+}
+
+// Map the message as map, the args of the process
+func (msg *Message) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"event_id":     msg.EventID,
+		"team_id":      msg.TeamID,
+		"channel":      msg.Channel,
+		"channel_type": msg.ChannelType,
+		"user":         msg.User,
+		"text":         msg.Text,
+		"ts":           msg.TS,
+		"thread_ts":    msg.ThreadTS,
+		"command":      msg.Command,
+	}
+}
+
+// Sign the X-Slack-Signature of the request, v0= and the hex of HMAC-SHA256 of v0:timestamp:body
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify the signature of the request, the timestamp must be within 5 minutes
+func (bot *Bot) Verify(timestamp string, signature string, body []byte) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errInvalid
+	}
+
+	if diff := time.Now().Unix() - ts; diff > 300 || diff < -300 {
+		return errExpired
+	}
+
+	if !hmac.Equal([]byte(Sign(bot.SigningSecret, timestamp, body)), []byte(signature)) {
+		return errSignature
+	}
+	return nil
+}
+
+// text the text of the message, the mentions are removed
+func text(value string) string {
+	return strings.TrimSpace(mentionRe.ReplaceAllString(value, ""))
+}
+
+func (bot *Bot) validate() error {
+	for _, value := range []*string{&bot.ClientID, &bot.ClientSecret, &bot.SigningSecret, &bot.Token} {
+		if strings.HasPrefix(*value, "$ENV.") {
+			*value = os.Getenv(strings.TrimPrefix(*value, "$ENV."))
+		}
+	}
+
+	if bot.SigningSecret == "" {
+		return fmt.Errorf("the signing_secret is required")
+	}
+
+	if bot.Token == "" && (bot.ClientID == "" || bot.ClientSecret == "") {
+		return fmt.Errorf("the token or the client_id and client_secret are required")
+	}
+
+	if bot.Assistant == "" && bot.Process == "" {
+		return fmt.Errorf("the assistant or the process is required")
+	}
+
+	if len(bot.Scopes) == 0 {
+		bot.Scopes = []string{"app_mentions:read", "chat:write", "commands", "im:history"}
+	}
+
+	if bot.API == "" {
+		bot.API = "https://slack.com"
+	}
+	bot.API = strings.TrimRight(bot.API, "/")
+	return nil
+}
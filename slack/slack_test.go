@@ -0,0 +1,158 @@
+package slack
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func testBot(api string) *Bot {
+	return &Bot{ID: "support", Name: "Support", ClientID: "c1", ClientSecret: "secret", SigningSecret: "signing", Token: "xoxb-1", Process: "scripts.slack.Reply", API: api}
+}
+
+func signed(bot *Bot, target string, body string) *http.Request {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req := httptest.NewRequest("POST", target, strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", Sign(bot.SigningSecret, ts, []byte(body)))
+	return req
+}
+
+func TestVerify(t *testing.T) {
+	bot := testBot("")
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	body := []byte(`{"type":"event_callback"}`)
+	assert.NoError(t, bot.Verify(ts, Sign("signing", ts, body), body))
+	assert.Equal(t, errSignature, bot.Verify(ts, Sign("other", ts, body), body))
+	assert.Equal(t, errInvalid, bot.Verify("", "", body))
+
+	old := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	assert.Equal(t, errExpired, bot.Verify(old, Sign("signing", old, body), body))
+
+	// The example of the Slack document
+	assert.Equal(t, "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503", Sign("8f742231b10e8888abcd99yyyzzz85a5", "1531420618", []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c")))
+}
+
+func TestText(t *testing.T) {
+	assert.Equal(t, "hello", text("<@U024BE7LH> hello"))
+	assert.Equal(t, "hi there", text("<@U024BE7LH|bot> hi there"))
+}
+
+func TestChat(t *testing.T) {
+	msg := &Message{TeamID: "T1", Channel: "C1", ChannelType: "channel", User: "U1", TS: "1.1"}
+	sid, cid := msg.chat("support")
+	assert.Equal(t, "slack:support:T1:U1", sid)
+	assert.Equal(t, "slack:support:T1:C1:1.1", cid)
+
+	msg.ThreadTS = "0.9"
+	_, cid = msg.chat("support")
+	assert.Equal(t, "slack:support:T1:C1:0.9", cid)
+
+	msg.ChannelType = "im"
+	_, cid = msg.chat("support")
+	assert.Equal(t, sid, cid)
+}
+
+func TestState(t *testing.T) {
+	bot := testBot("")
+	assert.True(t, bot.checkState(bot.state(time.Now().Unix())))
+	assert.False(t, bot.checkState(bot.state(time.Now().Add(-time.Hour).Unix())))
+	assert.False(t, bot.checkState("invalid"))
+
+	other := testBot("")
+	other.ClientSecret = "other"
+	assert.False(t, bot.checkState(other.state(time.Now().Unix())))
+}
+
+func TestHandleEvent(t *testing.T) {
+	defer func(bots map[string]*Bot) { Bots = bots }(Bots)
+	bot := testBot("http://127.0.0.1:0")
+	Bots = map[string]*Bot{"support": bot}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetRoutes(router, "/api/__yao/slack")
+
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, signed(bot, "/api/__yao/slack/support/events", `{"type":"url_verification","challenge":"c1"}`))
+	assert.Equal(t, 200, res.Code)
+	assert.JSONEq(t, `{"challenge":"c1"}`, res.Body.String())
+
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("POST", "/api/__yao/slack/support/events", strings.NewReader(`{"type":"url_verification","challenge":"c1"}`)))
+	assert.Equal(t, 401, res.Code)
+
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, signed(bot, "/api/__yao/slack/unknown/events", `{}`))
+	assert.Equal(t, 404, res.Code)
+
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, signed(bot, "/api/__yao/slack/support/events", `{"type":"event_callback","event":{"type":"message","subtype":"message_changed","channel_type":"im"}}`))
+	assert.Equal(t, 200, res.Code)
+}
+
+func TestHandleInstall(t *testing.T) {
+	defer func(bots map[string]*Bot) { Bots = bots }(Bots)
+	Bots = map[string]*Bot{"support": testBot("https://slack.com")}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetRoutes(router, "/api/__yao/slack")
+
+	res := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/__yao/slack/support/install", nil)
+	req.Host = "example.com"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	router.ServeHTTP(res, req)
+	assert.Equal(t, 302, res.Code)
+
+	location := res.Header().Get("Location")
+	assert.True(t, strings.HasPrefix(location, "https://slack.com/oauth/v2/authorize?"))
+	assert.Contains(t, location, "redirect_uri=https%3A%2F%2Fexample.com%2Fapi%2F__yao%2Fslack%2Fsupport%2Foauth")
+
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("GET", "/api/__yao/slack/support/oauth?code=c1&state=invalid", nil))
+	assert.Equal(t, 400, res.Code)
+}
+
+func TestStream(t *testing.T) {
+	defer func(interval time.Duration) { updateInterval = interval }(updateInterval)
+	updateInterval = 0
+
+	calls := []map[string]interface{}{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer xoxb-1", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		call := map[string]interface{}{}
+		jsoniter.Unmarshal(body, &call)
+		call["method"] = strings.TrimPrefix(r.URL.Path, "/api/")
+		calls = append(calls, call)
+		w.Write([]byte(`{"ok":true,"channel":"C1","ts":"2.2"}`))
+	}))
+	defer srv.Close()
+
+	s := &stream{bot: testBot(srv.URL), msg: &Message{TeamID: "T1", Channel: "C1", TS: "1.1"}, thread: "1.1"}
+	s.update("Hel")
+	s.update("Hello")
+	assert.NoError(t, s.done("Hello"))
+	assert.NoError(t, s.done("Hello!"))
+
+	assert.Len(t, calls, 3)
+	assert.Equal(t, "chat.postMessage", calls[0]["method"])
+	assert.Equal(t, "1.1", calls[0]["thread_ts"])
+	assert.Equal(t, "chat.update", calls[1]["method"])
+	assert.Equal(t, "2.2", calls[1]["ts"])
+	assert.Equal(t, "Hello!", calls[2]["text"])
+}
+
+func TestRespond(t *testing.T) {
+	assert.Error(t, testBot("").Respond("https://example.com/commands/1", "Hello"))
+}
@@ -0,0 +1,161 @@
+package slack
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+const installationTable = "yao_slack_installation"
+
+// ready the table is created
+var ready = false
+
+// Installation the app installed into the workspace
+type Installation struct {
+	Bot       string    `json:"bot"`
+	TeamID    string    `json:"team_id"`
+	TeamName  string    `json:"team_name,omitempty"`
+	BotUserID string    `json:"bot_user_id,omitempty"`
+	Token     string    `json:"-"`
+	Scope     string    `json:"scope,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// migrate create the table of the installations
+func migrate() error {
+	if ready {
+		return nil
+	}
+
+	sch := capsule.Schema()
+	has, err := sch.HasTable(installationTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		err = sch.CreateTable(installationTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("bot", 200).Index()
+			table.String("team_id", 50).Index()
+			table.String("team_name", 200).Null()
+			table.String("bot_user_id", 50).Null()
+			table.String("token", 500)
+			table.Text("scope").Null()
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()")
+			table.TimestampTz("updated_at").Null()
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the slack table: %s", installationTable)
+	}
+
+	ready = true
+	return nil
+}
+
+func newQuery() query.Query {
+	qb := capsule.Query()
+	qb.Table(installationTable)
+	return qb
+}
+
+// Install save the installation, the token of the workspace installed before is replaced
+func Install(inst Installation) error {
+	if !ready {
+		return fmt.Errorf("the slack table is not created")
+	}
+
+	now := time.Now()
+	data := map[string]interface{}{
+		"team_name":   inst.TeamName,
+		"bot_user_id": inst.BotUserID,
+		"token":       inst.Token,
+		"scope":       inst.Scope,
+	}
+
+	has, err := newQuery().Where("bot", inst.Bot).Where("team_id", inst.TeamID).Exists()
+	if err != nil {
+		return err
+	}
+
+	if has {
+		data["updated_at"] = now
+		_, err = newQuery().Where("bot", inst.Bot).Where("team_id", inst.TeamID).Update(data)
+		return err
+	}
+
+	data["bot"] = inst.Bot
+	data["team_id"] = inst.TeamID
+	data["created_at"] = now
+	return newQuery().Insert(data)
+}
+
+// Uninstall remove the installation of the workspace
+func Uninstall(bot string, team string) error {
+	if !ready {
+		return nil
+	}
+	_, err := newQuery().Where("bot", bot).Where("team_id", team).Delete()
+	return err
+}
+
+// GetInstallation the installation of the workspace
+func GetInstallation(bot string, team string) (*Installation, error) {
+	if !ready {
+		return nil, fmt.Errorf("the slack table is not created")
+	}
+
+	row, err := newQuery().Where("bot", bot).Where("team_id", team).First()
+	if err != nil {
+		return nil, err
+	}
+
+	if row == nil || len(row.ToMap()) == 0 {
+		return nil, fmt.Errorf("the slack bot %s is not installed into %s", bot, team)
+	}
+
+	inst := Installation{
+		Bot:       fmt.Sprintf("%v", row.Get("bot")),
+		TeamID:    fmt.Sprintf("%v", row.Get("team_id")),
+		TeamName:  toString(row.Get("team_name")),
+		BotUserID: toString(row.Get("bot_user_id")),
+		Token:     toString(row.Get("token")),
+		Scope:     toString(row.Get("scope")),
+	}
+	if at, ok := row.Get("created_at").(time.Time); ok {
+		inst.CreatedAt = at
+	}
+	return &inst, nil
+}
+
+// token the bot token of the workspace, the token of the bot is used if it is set
+func (bot *Bot) token(team string) (string, error) {
+	if bot.Token != "" {
+		return bot.Token, nil
+	}
+
+	inst, err := GetInstallation(bot.ID, team)
+	if err != nil {
+		return "", err
+	}
+	return inst.Token, nil
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,139 @@
+package sms
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func init() {
+	Register(TypeAliyun, newAliyun)
+}
+
+// aliyun the sender of the Short Message Service and the Voice Messaging Service of Alibaba Cloud
+type aliyun struct{ *Provider }
+
+func newAliyun(p *Provider) (Sender, error) {
+	if p.Key == "" || p.Secret == "" || p.Sign == "" || p.Template == "" {
+		return nil, fmt.Errorf("the key, the secret, the sign and the template are required")
+	}
+	if p.Voice != "" && p.From == "" {
+		return nil, fmt.Errorf("the from is required by the voice")
+	}
+	if p.Region == "" {
+		p.Region = "cn-hangzhou"
+	}
+	return &aliyun{p}, nil
+}
+
+// SendSMS send the code by the SendSms action
+func (a *aliyun) SendSMS(phone string, code string) error {
+	return a.call("https://dysmsapi.aliyuncs.com", map[string]string{
+		"Action":        "SendSms",
+		"PhoneNumbers":  aliyunPhone(phone),
+		"SignName":      a.Sign,
+		"TemplateCode":  a.Template,
+		"TemplateParam": aliyunParam(code),
+	})
+}
+
+// SendVoice call the phone by the SingleCallByTts action
+func (a *aliyun) SendVoice(phone string, code string) error {
+	if a.Voice == "" {
+		return fmt.Errorf("the voice of the sms provider %s is not configured", a.ID)
+	}
+	return a.call("https://dyvmsapi.aliyuncs.com", map[string]string{
+		"Action":           "SingleCallByTts",
+		"CalledNumber":     aliyunPhone(phone),
+		"CalledShowNumber": a.From,
+		"TtsCode":          a.Voice,
+		"TtsParam":         aliyunParam(code),
+	})
+}
+
+// call the RPC API, the request is signed by the HMAC-SHA1 of the secret
+func (a *aliyun) call(api string, params map[string]string) error {
+	if a.API != "" {
+		api = a.API
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	params["Format"] = "JSON"
+	params["Version"] = "2017-05-25"
+	params["AccessKeyId"] = a.Key
+	params["RegionId"] = a.Region
+	params["SignatureMethod"] = "HMAC-SHA1"
+	params["SignatureVersion"] = "1.0"
+	params["SignatureNonce"] = hex.EncodeToString(nonce)
+	params["Timestamp"] = time.Now().UTC().Format("2006-01-02T15:04:05Z")
+	params["Signature"] = aliyunSign(a.Secret, "POST", params)
+
+	form := url.Values{}
+	for key, value := range params {
+		form.Set(key, value)
+	}
+
+	req, err := http.NewRequest("POST", api+"/", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res := struct {
+		Code    string `json:"Code"`
+		Message string `json:"Message"`
+	}{}
+	if err := do(req, "aliyun", &res); err != nil {
+		return err
+	}
+
+	if res.Code != "OK" {
+		return fmt.Errorf("aliyun responds %s: %s", res.Code, res.Message)
+	}
+	return nil
+}
+
+// aliyunSign the signature of the RPC API
+func aliyunSign(secret string, method string, params map[string]string) string {
+	keys := []string{}
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		pairs = append(pairs, aliyunEscape(key)+"="+aliyunEscape(params[key]))
+	}
+
+	text := method + "&" + aliyunEscape("/") + "&" + aliyunEscape(strings.Join(pairs, "&"))
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(text))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliyunEscape the percent encoding of RFC 3986
+func aliyunEscape(value string) string {
+	return strings.NewReplacer("+", "%20", "*", "%2A", "%7E", "~").Replace(url.QueryEscape(value))
+}
+
+// aliyunPhone the number of aliyun, the mainland numbers are without the country code
+func aliyunPhone(phone string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(phone, "+86"), "+")
+}
+
+func aliyunParam(code string) string {
+	param, _ := jsoniter.MarshalToString(map[string]string{"code": code})
+	return param
+}
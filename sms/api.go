@@ -0,0 +1,39 @@
+package sms
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// do the request of the provider, the JSON response is decoded to the res
+func do(req *http.Request, name string, res interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		if len(body) > 1024 {
+			body = body[:1024]
+		}
+		return fmt.Errorf("%s responds %d: %s", name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := jsoniter.Unmarshal(body, res); err != nil {
+		return fmt.Errorf("%s responds %s: %s", name, strings.TrimSpace(string(body)), err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package sms
+
+import (
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("sms", map[string]process.Handler{
+		"send":   processSend,
+		"verify": processVerify,
+	})
+}
+
+// processSend sms.Send(provider, phone, channel?), channel is sms or voice, returns the ticket
+func processSend(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	p, err := Select(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+
+	phone, err := Phone(process.ArgsString(1))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+
+	channel := ChannelSMS
+	if process.NumOfArgs() > 2 {
+		channel = process.ArgsString(2)
+	}
+	if channel != ChannelSMS && channel != ChannelVoice {
+		exception.New("the channel %s is not supported, sms or voice", 400, channel).Throw()
+	}
+
+	ticket, err := p.Send(phone, channel)
+	if err != nil {
+		exception.New(err.Error(), status(err)).Throw()
+	}
+	return ticket
+}
+
+// processVerify sms.Verify(provider, phone, code), returns true or throws the 400 exception
+func processVerify(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	p, err := Select(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+
+	phone, err := Phone(process.ArgsString(1))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+
+	err = p.Check(phone, process.ArgsString(2))
+	if err != nil {
+		exception.New(err.Error(), status(err)).Throw()
+	}
+	return true
+}
+
+// status the code of the exception of the error
+func status(err error) int {
+	switch err {
+	case ErrTooFrequent, ErrLimited:
+		return 429
+	case ErrInvalid, ErrExpired:
+		return 400
+	}
+	return 500
+}
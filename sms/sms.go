@@ -0,0 +1,134 @@
+package sms
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+var phoneRe = regexp.MustCompile(`^\+?[0-9]{6,15}$`)
+
+// Register the sender of the type, the providers of the type are sent by it
+func Register(typ string, factory func(*Provider) (Sender, error)) {
+	lock.Lock()
+	defer lock.Unlock()
+	senders[typ] = factory
+}
+
+// Load the sms/*.yao providers
+func Load(cfg config.Config) error {
+	providers := map[string]*Provider{}
+	messages := []string{}
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("sms", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		p := Provider{}
+		err = application.Parse(file, bytes, &p)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		p.ID = share.ID(root, file)
+		if err := p.validate(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		providers[p.ID] = &p
+		return nil
+	}, exts...)
+	if err != nil {
+		messages = append(messages, err.Error())
+	}
+
+	lock.Lock()
+	Providers = providers
+	lock.Unlock()
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+	return nil
+}
+
+// Select the provider, "default" if the id is empty
+func Select(id string) (*Provider, error) {
+	if id == "" {
+		id = "default"
+	}
+
+	lock.RLock()
+	defer lock.RUnlock()
+	p, has := Providers[id]
+	if !has {
+		return nil, fmt.Errorf("the sms provider %s does not exist", id)
+	}
+	return p, nil
+}
+
+// Phone the phone number without the spaces, the dashes and the brackets, e.g. +86 139-0000-0000 is +8613900000000
+func Phone(phone string) (string, error) {
+	normalized := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(strings.TrimSpace(phone))
+	if !phoneRe.MatchString(normalized) {
+		return "", fmt.Errorf("the phone %s is invalid", phone)
+	}
+	return normalized, nil
+}
+
+func (p *Provider) validate() error {
+	for _, value := range []*string{&p.Key, &p.Secret, &p.From} {
+		if strings.HasPrefix(*value, "$ENV.") {
+			*value = os.Getenv(strings.TrimPrefix(*value, "$ENV."))
+		}
+	}
+
+	lock.RLock()
+	factory, has := senders[p.Type]
+	lock.RUnlock()
+	if !has {
+		return fmt.Errorf("the type %s is not supported", p.Type)
+	}
+
+	sender, err := factory(p)
+	if err != nil {
+		return err
+	}
+	p.sender = sender
+	p.API = strings.TrimRight(p.API, "/")
+
+	if p.Verify.Length == 0 {
+		p.Verify.Length = 6
+	}
+	if p.Verify.Length < 4 || p.Verify.Length > 10 {
+		return fmt.Errorf("the length of the code must be 4 to 10 digits")
+	}
+	if p.Verify.Expire == 0 {
+		p.Verify.Expire = 300
+	}
+	if p.Verify.Interval == 0 {
+		p.Verify.Interval = 60
+	}
+	if p.Verify.Limit == 0 {
+		p.Verify.Limit = 5
+	}
+	if p.Verify.Attempts == 0 {
+		p.Verify.Attempts = 5
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package sms
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// fake the sender of the tests, the codes are kept
+type fake struct {
+	codes    map[string]string
+	channels map[string]string
+}
+
+func (f *fake) SendSMS(phone string, code string) error {
+	f.codes[phone], f.channels[phone] = code, ChannelSMS
+	return nil
+}
+
+func (f *fake) SendVoice(phone string, code string) error {
+	f.codes[phone], f.channels[phone] = code, ChannelVoice
+	return nil
+}
+
+func prepare(t *testing.T, option VerifyOption) (*Provider, *fake) {
+	f := &fake{codes: map[string]string{}, channels: map[string]string{}}
+	Register("fake", func(p *Provider) (Sender, error) { return f, nil })
+	p := &Provider{ID: t.Name(), Type: "fake", Verify: option}
+	if err := p.validate(); err != nil {
+		t.Fatal(err)
+	}
+	return p, f
+}
+
+func TestPhone(t *testing.T) {
+	phone, err := Phone(" +86 139-0000-0000 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "+8613900000000", phone)
+
+	phone, err = Phone("(415) 555 0100")
+	assert.NoError(t, err)
+	assert.Equal(t, "4155550100", phone)
+
+	_, err = Phone("139abc")
+	assert.Error(t, err)
+
+	_, err = Phone("+12")
+	assert.Error(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	p, f := prepare(t, VerifyOption{Length: 4, Interval: 60})
+
+	ticket, err := p.Send("+86 139 0000 0000", ChannelVoice)
+	assert.NoError(t, err)
+	assert.Equal(t, &Ticket{Phone: "+8613900000000", Channel: ChannelVoice, ExpiresIn: 300, Interval: 60}, ticket)
+
+	code := f.codes["+8613900000000"]
+	assert.Len(t, code, 4)
+	assert.Equal(t, ChannelVoice, f.channels["+8613900000000"])
+
+	_, err = p.Send("+8613900000000", ChannelSMS)
+	assert.Equal(t, ErrTooFrequent, err)
+
+	_, err = p.Send("+8613900000000", "fax")
+	assert.Error(t, err)
+
+	wrong := "0000"
+	if code == wrong {
+		wrong = "1111"
+	}
+	assert.Equal(t, ErrInvalid, p.Check("+8613900000000", wrong))
+	assert.NoError(t, p.Check("+86 139 0000 0000", code))
+
+	// The code is revoked when it is verified
+	assert.Equal(t, ErrInvalid, p.Check("+8613900000000", code))
+}
+
+func TestVerifyLimit(t *testing.T) {
+	p, f := prepare(t, VerifyOption{Interval: -1, Limit: 2, Attempts: 2})
+
+	_, err := p.Send("+8613900000000", "")
+	assert.NoError(t, err)
+	_, err = p.Send("+8613900000000", "")
+	assert.NoError(t, err)
+	_, err = p.Send("+8613900000000", "")
+	assert.Equal(t, ErrLimited, err)
+
+	// The code is revoked when the attempts are exceeded
+	code := f.codes["+8613900000000"]
+	assert.Equal(t, ErrInvalid, p.Check("+8613900000000", "x"))
+	assert.Equal(t, ErrInvalid, p.Check("+8613900000000", "x"))
+	assert.Equal(t, ErrInvalid, p.Check("+8613900000000", code))
+}
+
+func TestVerifyExpired(t *testing.T) {
+	p, f := prepare(t, VerifyOption{})
+
+	_, err := p.Send("+8613900000000", "")
+	assert.NoError(t, err)
+
+	rec := getRecord(memory, p.key("+8613900000000"))
+	rec.Expires = time.Now().Add(-time.Second).Unix()
+	setRecord(memory, p.key("+8613900000000"), rec)
+	assert.Equal(t, ErrExpired, p.Check("+8613900000000", f.codes["+8613900000000"]))
+}
+
+func TestTwilio(t *testing.T) {
+	forms := []url.Values{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, token, _ := r.BasicAuth()
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "token", token)
+		r.ParseForm()
+		forms = append(forms, r.PostForm)
+		if strings.HasSuffix(r.URL.Path, "/Calls.json") {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"code":21211,"message":"Invalid 'To' Phone Number"}`))
+			return
+		}
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		w.Write([]byte(`{"sid":"SM123","status":"queued"}`))
+	}))
+	defer srv.Close()
+
+	p := &Provider{Type: TypeTwilio, Key: "AC123", Secret: "token", From: "+15005550006", API: srv.URL}
+	assert.NoError(t, p.validate())
+	assert.NoError(t, p.sender.SendSMS("4155550100", "123456"))
+	assert.Equal(t, "+4155550100", forms[0].Get("To"))
+	assert.Equal(t, "Your verification code is 123456", forms[0].Get("Body"))
+
+	err := p.sender.SendVoice("+4155550100", "12")
+	assert.Contains(t, err.Error(), "twilio responds 400")
+	assert.Contains(t, forms[1].Get("Twiml"), "<Say>Your verification code is 1, 2</Say>")
+
+	assert.Error(t, (&Provider{Type: TypeTwilio, Key: "AC123"}).validate())
+}
+
+func TestAliyun(t *testing.T) {
+	params := []url.Values{}
+	code := "OK"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		params = append(params, r.PostForm)
+
+		values := map[string]string{}
+		for key := range r.PostForm {
+			if key != "Signature" {
+				values[key] = r.PostForm.Get(key)
+			}
+		}
+		assert.Equal(t, aliyunSign("secret", "POST", values), r.PostForm.Get("Signature"))
+		w.Write([]byte(`{"Code":"` + code + `","Message":"failed"}`))
+	}))
+	defer srv.Close()
+
+	p := &Provider{Type: TypeAliyun, Key: "key", Secret: "secret", Sign: "Yao", Template: "SMS_1", API: srv.URL}
+	assert.NoError(t, p.validate())
+	assert.NoError(t, p.sender.SendSMS("+8613900000000", "123456"))
+	assert.Equal(t, "SendSms", params[0].Get("Action"))
+	assert.Equal(t, "13900000000", params[0].Get("PhoneNumbers"))
+	assert.Equal(t, `{"code":"123456"}`, params[0].Get("TemplateParam"))
+	assert.Equal(t, "cn-hangzhou", params[0].Get("RegionId"))
+
+	// The voice is not configured
+	assert.Error(t, p.sender.SendVoice("+8613900000000", "123456"))
+
+	code = "isv.BUSINESS_LIMIT_CONTROL"
+	assert.EqualError(t, p.sender.SendSMS("+85200000000", "123456"), "aliyun responds isv.BUSINESS_LIMIT_CONTROL: failed")
+	assert.Equal(t, "85200000000", params[1].Get("PhoneNumbers"))
+
+	assert.Equal(t, "a%20b%2A~%2F", aliyunEscape("a b*~/"))
+	assert.Error(t, (&Provider{Type: TypeAliyun, Key: "key", Secret: "secret", Sign: "Yao", Template: "SMS_1", Voice: "TTS_1"}).validate())
+}
+
+func TestTencent(t *testing.T) {
+	payloads := []map[string]interface{}{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		payload := map[string]interface{}{}
+		jsoniter.Unmarshal(body, &payload)
+		payloads = append(payloads, payload)
+
+		ts := r.Header.Get("X-TC-Timestamp")
+		assert.NotEmpty(t, ts)
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "TC3-HMAC-SHA256 Credential=AKID/"))
+
+		switch r.Header.Get("X-TC-Action") {
+		case "SendSms":
+			assert.Equal(t, "2021-01-11", r.Header.Get("X-TC-Version"))
+			w.Write([]byte(`{"Response":{"SendStatusSet":[{"Code":"Ok","Message":"send success"}],"RequestId":"1"}}`))
+		default:
+			w.Write([]byte(`{"Response":{"Error":{"Code":"AuthFailure.SignatureFailure","Message":"invalid"},"RequestId":"2"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	p := &Provider{Type: TypeTencent, Key: "AKID", Secret: "secret", AppID: "1400000000", Sign: "Yao", Template: "1000", Voice: "2000", VoiceAppID: "1400000001", API: srv.URL}
+	assert.NoError(t, p.validate())
+	assert.NoError(t, p.sender.SendSMS("13900000000", "123456"))
+	assert.Equal(t, []interface{}{"+8613900000000"}, payloads[0]["PhoneNumberSet"])
+	assert.Equal(t, []interface{}{"123456"}, payloads[0]["TemplateParamSet"])
+
+	assert.EqualError(t, p.sender.SendVoice("+8613900000000", "123456"), "tencent responds AuthFailure.SignatureFailure: invalid")
+	assert.Equal(t, "1400000001", payloads[1]["VoiceSdkAppid"])
+
+	// The credential scope is the UTC date of the request
+	now := time.Date(2019, 2, 25, 16, 4, 25, 0, time.UTC)
+	auth := tencentSign("AKID", "secret", "cvm", "cvm.tencentcloudapi.com", now, []byte(`{}`))
+	assert.True(t, strings.HasPrefix(auth, "TC3-HMAC-SHA256 Credential=AKID/2019-02-25/cvm/tc3_request, SignedHeaders=content-type;host, Signature="))
+	assert.Len(t, auth[strings.LastIndex(auth, "=")+1:], 64)
+	assert.NotEqual(t, auth, tencentSign("AKID", "secret", "cvm", "cvm.tencentcloudapi.com", now, []byte(`{"Limit":1}`)))
+}
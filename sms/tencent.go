@@ -0,0 +1,167 @@
+package sms
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func init() {
+	Register(TypeTencent, newTencent)
+}
+
+// tencent the sender of the SMS and the Voice Message Service of Tencent Cloud
+type tencent struct{ *Provider }
+
+// tencentError the error of the response of the API 3.0
+type tencentError struct {
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+func newTencent(p *Provider) (Sender, error) {
+	if p.Key == "" || p.Secret == "" || p.AppID == "" || p.Sign == "" || p.Template == "" {
+		return nil, fmt.Errorf("the key, the secret, the app_id, the sign and the template are required")
+	}
+	if p.Voice != "" && p.VoiceAppID == "" {
+		return nil, fmt.Errorf("the voice_app_id is required by the voice")
+	}
+	if p.Region == "" {
+		p.Region = "ap-guangzhou"
+	}
+	return &tencent{p}, nil
+}
+
+// SendSMS send the code by the SendSms action
+func (t *tencent) SendSMS(phone string, code string) error {
+	res := struct {
+		Response struct {
+			Error         *tencentError  `json:"Error"`
+			SendStatusSet []tencentError `json:"SendStatusSet"`
+		} `json:"Response"`
+	}{}
+
+	err := t.call("sms", "SendSms", "2021-01-11", map[string]interface{}{
+		"PhoneNumberSet":   []string{tencentPhone(phone)},
+		"SmsSdkAppId":      t.AppID,
+		"SignName":         t.Sign,
+		"TemplateId":       t.Template,
+		"TemplateParamSet": []string{code},
+	}, &res)
+	if err != nil {
+		return err
+	}
+
+	if res.Response.Error != nil {
+		return fmt.Errorf("tencent responds %s: %s", res.Response.Error.Code, res.Response.Error.Message)
+	}
+
+	for _, status := range res.Response.SendStatusSet {
+		if status.Code != "Ok" {
+			return fmt.Errorf("tencent responds %s: %s", status.Code, status.Message)
+		}
+	}
+	return nil
+}
+
+// SendVoice call the phone by the SendTtsVoice action
+func (t *tencent) SendVoice(phone string, code string) error {
+	if t.Voice == "" {
+		return fmt.Errorf("the voice of the sms provider %s is not configured", t.ID)
+	}
+
+	res := struct {
+		Response struct {
+			Error *tencentError `json:"Error"`
+		} `json:"Response"`
+	}{}
+
+	err := t.call("vms", "SendTtsVoice", "2020-09-02", map[string]interface{}{
+		"CalledNumber":     tencentPhone(phone),
+		"VoiceSdkAppid":    t.VoiceAppID,
+		"TemplateId":       t.Voice,
+		"TemplateParamSet": []string{code},
+	}, &res)
+	if err != nil {
+		return err
+	}
+
+	if res.Response.Error != nil {
+		return fmt.Errorf("tencent responds %s: %s", res.Response.Error.Code, res.Response.Error.Message)
+	}
+	return nil
+}
+
+// call the API 3.0, the request is signed by the TC3-HMAC-SHA256
+func (t *tencent) call(service string, action string, version string, payload map[string]interface{}, res interface{}) error {
+	api := fmt.Sprintf("https://%s.tencentcloudapi.com", service)
+	if t.API != "" {
+		api = t.API
+	}
+
+	u, err := url.Parse(api)
+	if err != nil {
+		return err
+	}
+
+	body, err := jsoniter.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	req, err := http.NewRequest("POST", api+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("X-TC-Action", action)
+	req.Header.Set("X-TC-Version", version)
+	req.Header.Set("X-TC-Region", t.Region)
+	req.Header.Set("X-TC-Timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("Authorization", tencentSign(t.Key, t.Secret, service, u.Host, now, body))
+	return do(req, "tencent", res)
+}
+
+// tencentSign the Authorization of the TC3-HMAC-SHA256
+func tencentSign(id string, secret string, service string, host string, now time.Time, body []byte) string {
+	payload := sha256.Sum256(body)
+	canonical := "POST\n/\n\ncontent-type:application/json; charset=utf-8\nhost:" + host + "\n\ncontent-type;host\n" + hex.EncodeToString(payload[:])
+
+	date := now.Format("2006-01-02")
+	scope := date + "/" + service + "/tc3_request"
+	hashed := sha256.Sum256([]byte(canonical))
+	text := "TC3-HMAC-SHA256\n" + strconv.FormatInt(now.Unix(), 10) + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := tencentHMAC([]byte("TC3"+secret), date)
+	key = tencentHMAC(key, service)
+	key = tencentHMAC(key, "tc3_request")
+	signature := hex.EncodeToString(tencentHMAC(key, text))
+	return fmt.Sprintf("TC3-HMAC-SHA256 Credential=%s/%s, SignedHeaders=content-type;host, Signature=%s", id, scope, signature)
+}
+
+func tencentHMAC(key []byte, text string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(text))
+	return mac.Sum(nil)
+}
+
+// tencentPhone the E.164 number of tencent, the numbers without the country code are the mainland ones
+func tencentPhone(phone string) string {
+	if strings.HasPrefix(phone, "+") {
+		return phone
+	}
+	if len(phone) == 11 && strings.HasPrefix(phone, "1") {
+		return "+86" + phone
+	}
+	return "+" + phone
+}
@@ -0,0 +1,68 @@
+package sms
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	Register(TypeTwilio, newTwilio)
+}
+
+// twilio the sender of the Programmable Messaging and the Voice API of Twilio
+type twilio struct{ *Provider }
+
+func newTwilio(p *Provider) (Sender, error) {
+	if p.Key == "" || p.Secret == "" || p.From == "" {
+		return nil, fmt.Errorf("the key, the secret and the from are required")
+	}
+	if p.Message == "" {
+		p.Message = "Your verification code is {{ code }}"
+	}
+	return &twilio{p}, nil
+}
+
+// SendSMS send the code by the Messages API
+func (t *twilio) SendSMS(phone string, code string) error {
+	return t.post("Messages.json", url.Values{"To": {twilioPhone(phone)}, "From": {t.From}, "Body": {t.text(code)}})
+}
+
+// SendVoice call the phone and say the code twice by the TwiML
+func (t *twilio) SendVoice(phone string, code string) error {
+	// The digits are said one by one
+	text := html.EscapeString(t.text(strings.Join(strings.Split(code, ""), ", ")))
+	twiml := fmt.Sprintf(`<Response><Say>%s</Say><Pause length="1"/><Say>%s</Say></Response>`, text, text)
+	return t.post("Calls.json", url.Values{"To": {twilioPhone(phone)}, "From": {t.From}, "Twiml": {twiml}})
+}
+
+func (t *twilio) text(code string) string {
+	return strings.ReplaceAll(t.Message, "{{ code }}", code)
+}
+
+func (t *twilio) post(resource string, form url.Values) error {
+	api := t.API
+	if api == "" {
+		api = "https://api.twilio.com"
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/2010-04-01/Accounts/%s/%s", api, url.PathEscape(t.Key), resource), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.Key, t.Secret)
+
+	res := map[string]interface{}{}
+	return do(req, "twilio", &res)
+}
+
+// twilioPhone the E.164 number of twilio
+func twilioPhone(phone string) string {
+	if strings.HasPrefix(phone, "+") {
+		return phone
+	}
+	return "+" + phone
+}
@@ -0,0 +1,97 @@
+package sms
+
+import (
+	"sync"
+	"time"
+)
+
+// The types of the providers
+const (
+	TypeTwilio  = "twilio"
+	TypeAliyun  = "aliyun"
+	TypeTencent = "tencent"
+)
+
+// The channels of the verification code
+const (
+	ChannelSMS   = "sms"
+	ChannelVoice = "voice"
+)
+
+// Provider the SMS provider of the sms/*.yao, the provider "default" sends the codes without the provider.
+//
+//	{
+//	  "type": "aliyun",
+//	  "key": "$ENV.ALIYUN_ACCESS_KEY_ID",
+//	  "secret": "$ENV.ALIYUN_ACCESS_KEY_SECRET",
+//	  "sign": "Yao",
+//	  "template": "SMS_154950909",
+//	  "voice": "TTS_154950909",
+//	  "from": "0571000000",
+//	  "verify": { "length": 6, "expire": 300, "interval": 60, "limit": 5, "attempts": 5, "store": "cache" }
+//	}
+type Provider struct {
+	ID         string       `json:"-"`
+	Name       string       `json:"name,omitempty"`
+	Type       string       `json:"type"`                   // twilio, aliyun, tencent or the type registered by Register
+	Key        string       `json:"key"`                    // The account SID of twilio, the access key id of aliyun or the secret id of tencent
+	Secret     string       `json:"secret"`                 // The auth token of twilio, the access key secret of aliyun or the secret key of tencent
+	From       string       `json:"from,omitempty"`         // The number of twilio or the show number of the voice calls of aliyun
+	Sign       string       `json:"sign,omitempty"`         // The sign name of aliyun and tencent
+	Template   string       `json:"template,omitempty"`     // The template of the SMS of aliyun and tencent, the param of it is the code
+	Voice      string       `json:"voice,omitempty"`        // The template of the voice calls of aliyun and tencent
+	Message    string       `json:"message,omitempty"`      // The text of twilio, the {{ code }} is the code
+	AppID      string       `json:"app_id,omitempty"`       // The SmsSdkAppId of tencent
+	VoiceAppID string       `json:"voice_app_id,omitempty"` // The VoiceSdkAppid of tencent
+	Region     string       `json:"region,omitempty"`       // cn-hangzhou of aliyun and ap-guangzhou of tencent by default
+	API        string       `json:"api,omitempty"`          // The base url of the API instead of the one of the provider
+	Verify     VerifyOption `json:"verify,omitempty"`
+	sender     Sender
+}
+
+// VerifyOption the verification code option, the durations are seconds
+type VerifyOption struct {
+	Length   int    `json:"length,omitempty"`   // The digits of the code, 6 by default
+	Expire   int    `json:"expire,omitempty"`   // The code expires after 300 seconds by default
+	Interval int    `json:"interval,omitempty"` // The code can be sent again after 60 seconds by default
+	Limit    int    `json:"limit,omitempty"`    // The codes sent to a phone within an hour, 5 by default
+	Attempts int    `json:"attempts,omitempty"` // The wrong codes before the code is revoked, 5 by default
+	Store    string `json:"store,omitempty"`    // The store of the codes, e.g. a redis store of the application, the memory by default
+}
+
+// Sender the SMS provider, the Twilio, Aliyun and Tencent senders are built in
+type Sender interface {
+	SendSMS(phone string, code string) error
+	SendVoice(phone string, code string) error
+}
+
+// Ticket the verification code sent
+type Ticket struct {
+	Phone     string `json:"phone"`
+	Channel   string `json:"channel"`
+	ExpiresIn int    `json:"expires_in"` // Seconds
+	Interval  int    `json:"interval"`   // The seconds before the code can be sent again
+}
+
+// record the state of the verification of a phone
+type record struct {
+	Hash     string  `json:"hash,omitempty"`
+	Expires  int64   `json:"expires,omitempty"`
+	Attempts int     `json:"attempts,omitempty"`
+	Sent     []int64 `json:"sent,omitempty"` // The times of the codes sent within the window
+}
+
+// kv the store of the records, the stores of the application are used if the store is set
+type kv interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}, ttl time.Duration) error
+	Del(key string) error
+}
+
+// Providers the loaded providers
+var Providers = map[string]*Provider{}
+
+// senders the factories of the senders of the types
+var senders = map[string]func(*Provider) (Sender, error){}
+
+var lock sync.RWMutex
@@ -0,0 +1,273 @@
+package sms
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/kun/log"
+)
+
+// window the duration of the limit of the codes sent to a phone
+const window = time.Hour
+
+// The errors of the verification
+var (
+	ErrTooFrequent = errors.New("the code was sent too frequently, please try again later")
+	ErrLimited     = errors.New("too many codes were sent to the phone, please try again later")
+	ErrInvalid     = errors.New("the code is invalid")
+	ErrExpired     = errors.New("the code is expired, please send a new one")
+)
+
+// verifying the send and the verify of the records are serialized
+var verifying sync.Mutex
+
+// Send the verification code to the phone by the SMS or the voice call of the provider
+func Send(id string, phone string, channel string) (*Ticket, error) {
+	p, err := Select(id)
+	if err != nil {
+		return nil, err
+	}
+	return p.Send(phone, channel)
+}
+
+// Verify the code of the phone, the code is revoked when it is verified
+func Verify(id string, phone string, code string) error {
+	p, err := Select(id)
+	if err != nil {
+		return err
+	}
+	return p.Check(phone, code)
+}
+
+// Send the verification code to the phone, the interval and the limit of the provider are checked
+func (p *Provider) Send(phone string, channel string) (*Ticket, error) {
+	phone, err := Phone(phone)
+	if err != nil {
+		return nil, err
+	}
+
+	if channel == "" {
+		channel = ChannelSMS
+	}
+	if channel != ChannelSMS && channel != ChannelVoice {
+		return nil, fmt.Errorf("the channel %s is not supported, sms or voice", channel)
+	}
+
+	pool, err := p.store()
+	if err != nil {
+		return nil, err
+	}
+
+	verifying.Lock()
+	defer verifying.Unlock()
+
+	key := p.key(phone)
+	rec := getRecord(pool, key)
+	now := time.Now()
+
+	sent := []int64{}
+	for _, at := range rec.Sent {
+		if now.Sub(time.Unix(at, 0)) < window {
+			sent = append(sent, at)
+		}
+	}
+
+	if len(sent) > 0 && now.Sub(time.Unix(sent[len(sent)-1], 0)) < time.Duration(p.Verify.Interval)*time.Second {
+		return nil, ErrTooFrequent
+	}
+	if len(sent) >= p.Verify.Limit {
+		return nil, ErrLimited
+	}
+
+	code, err := p.code()
+	if err != nil {
+		return nil, err
+	}
+
+	if channel == ChannelVoice {
+		err = p.sender.SendVoice(phone, code)
+	} else {
+		err = p.sender.SendSMS(phone, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rec = record{
+		Hash:    hash(phone, code),
+		Expires: now.Add(time.Duration(p.Verify.Expire) * time.Second).Unix(),
+		Sent:    append(sent, now.Unix()),
+	}
+	setRecord(pool, key, rec)
+	return &Ticket{Phone: phone, Channel: channel, ExpiresIn: p.Verify.Expire, Interval: p.Verify.Interval}, nil
+}
+
+// Check the code of the phone, the code is revoked when it is verified or the attempts are exceeded
+func (p *Provider) Check(phone string, code string) error {
+	phone, err := Phone(phone)
+	if err != nil {
+		return err
+	}
+
+	pool, err := p.store()
+	if err != nil {
+		return err
+	}
+
+	verifying.Lock()
+	defer verifying.Unlock()
+
+	key := p.key(phone)
+	rec := getRecord(pool, key)
+	if rec.Hash == "" {
+		return ErrInvalid
+	}
+
+	if time.Now().Unix() > rec.Expires {
+		rec.Hash = ""
+		setRecord(pool, key, rec)
+		return ErrExpired
+	}
+
+	if subtle.ConstantTimeCompare([]byte(rec.Hash), []byte(hash(phone, code))) != 1 {
+		rec.Attempts++
+		if rec.Attempts >= p.Verify.Attempts {
+			rec.Hash = ""
+		}
+		setRecord(pool, key, rec)
+		return ErrInvalid
+	}
+
+	// The sent times are kept for the limit
+	rec.Hash, rec.Attempts = "", 0
+	setRecord(pool, key, rec)
+	return nil
+}
+
+// code the random digits of the code
+func (p *Provider) code() (string, error) {
+	upper := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(p.Verify.Length)), nil)
+	n, err := rand.Int(rand.Reader, upper)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", p.Verify.Length, n), nil
+}
+
+func (p *Provider) key(phone string) string {
+	return "sms:" + p.ID + ":" + phone
+}
+
+func (p *Provider) store() (kv, error) {
+	if p.Verify.Store == "" {
+		return memory, nil
+	}
+
+	pool, has := store.Pools[p.Verify.Store]
+	if !has {
+		return nil, fmt.Errorf("the store %s of the sms provider %s is not found", p.Verify.Store, p.ID)
+	}
+	return pool, nil
+}
+
+// hash the code of the phone, the codes are not kept in the store
+func hash(phone string, code string) string {
+	sum := sha256.Sum256([]byte(phone + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+func getRecord(pool kv, key string) record {
+	rec := record{}
+	value, has := pool.Get(key)
+	if !has {
+		return rec
+	}
+
+	var raw []byte
+	switch v := value.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return rec
+	}
+
+	if err := jsoniter.Unmarshal(raw, &rec); err != nil {
+		log.Warn("[SMS] the record %s is invalid: %s", key, err.Error())
+		return record{}
+	}
+	return rec
+}
+
+func setRecord(pool kv, key string, rec record) {
+	if rec.Hash == "" && len(rec.Sent) == 0 {
+		pool.Del(key)
+		return
+	}
+
+	raw, err := jsoniter.MarshalToString(rec)
+	if err != nil {
+		log.Error("[SMS] save the record %s: %s", key, err.Error())
+		return
+	}
+
+	if err := pool.Set(key, raw, window); err != nil {
+		log.Error("[SMS] save the record %s: %s", key, err.Error())
+	}
+}
+
+// memoryStore the records of the providers without the store
+type memoryStore struct {
+	sync.Mutex
+	values map[string]memoryValue
+}
+
+type memoryValue struct {
+	value   interface{}
+	expires time.Time
+}
+
+var memory = &memoryStore{values: map[string]memoryValue{}}
+
+func (m *memoryStore) Get(key string) (interface{}, bool) {
+	m.Lock()
+	defer m.Unlock()
+	v, has := m.values[key]
+	if !has || time.Now().After(v.expires) {
+		delete(m.values, key)
+		return nil, false
+	}
+	return v.value, true
+}
+
+func (m *memoryStore) Set(key string, value interface{}, ttl time.Duration) error {
+	m.Lock()
+	defer m.Unlock()
+
+	// The expired values are removed when the values are set
+	now := time.Now()
+	for k, v := range m.values {
+		if now.After(v.expires) {
+			delete(m.values, k)
+		}
+	}
+	m.values[key] = memoryValue{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+func (m *memoryStore) Del(key string) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.values, key)
+	return nil
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/sui/core"
 )
 
@@ -411,7 +412,7 @@ func payload(c *gin.Context) (map[string]interface{}, interface{}, error) {
 		break
 
 	case "multipart/form-data":
-		c.Request.ParseMultipartForm(32 << 20)
+		c.Request.ParseMultipartForm(int64(config.Conf.UploadMemory) << 20)
 		payload = make(map[string]interface{})
 		for key, value := range c.Request.MultipartForm.Value {
 			payload[key] = value
@@ -0,0 +1,183 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/fs"
+)
+
+// maxTextLength the max characters of a text message, the longer replies are sent in parts
+const maxTextLength = 4096
+
+// pollTimeout the timeout of the long polling
+const pollTimeout = 30
+
+// client the timeout is longer than the long polling
+var client = &http.Client{Timeout: (pollTimeout + 30) * time.Second}
+
+type apiResponse struct {
+	OK          bool                `json:"ok"`
+	Result      jsoniter.RawMessage `json:"result,omitempty"`
+	ErrorCode   int                 `json:"error_code,omitempty"`
+	Description string              `json:"description,omitempty"`
+}
+
+// Send the text message to the chat, as the reply of the message if the reply to is not 0, the text longer than 4096 characters is sent in parts
+func (bot *Bot) Send(chatID string, text string, replyTo int64) error {
+	for _, part := range split(text, maxTextLength) {
+		message := map[string]interface{}{"chat_id": chatID, "text": part}
+		if replyTo != 0 {
+			message["reply_parameters"] = map[string]interface{}{"message_id": replyTo, "allow_sending_without_reply": true}
+		}
+
+		err := bot.call("sendMessage", message, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendFile send the file of the data file system or the url to the chat, the images are sent as the photos
+func (bot *Bot) SendFile(chatID string, file string, caption string) error {
+	method, field := "sendDocument", "document"
+	if ext := strings.ToLower(filepath.Ext(strings.SplitN(file, "?", 2)[0])); ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".webp" {
+		method, field = "sendPhoto", "photo"
+	}
+
+	if strings.HasPrefix(file, "http://") || strings.HasPrefix(file, "https://") {
+		message := map[string]interface{}{"chat_id": chatID, field: file}
+		if caption != "" {
+			message["caption"] = caption
+		}
+		return bot.call(method, message, nil)
+	}
+
+	data, err := fs.Get("data")
+	if err != nil {
+		return err
+	}
+
+	raw, err := data.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	form.WriteField("chat_id", chatID)
+	if caption != "" {
+		form.WriteField("caption", caption)
+	}
+
+	part, err := form.CreateFormFile(field, filepath.Base(file))
+	if err != nil {
+		return err
+	}
+	part.Write(raw)
+	form.Close()
+
+	return bot.post(context.Background(), method, form.FormDataContentType(), body, nil)
+}
+
+// updates the updates after the offset by the long polling
+func (bot *Bot) updates(ctx context.Context, offset int64) ([]update, error) {
+	payload, _ := jsoniter.Marshal(map[string]interface{}{
+		"offset":          offset,
+		"timeout":         pollTimeout,
+		"allowed_updates": []string{"message"},
+	})
+
+	res := []update{}
+	err := bot.post(ctx, "getUpdates", "application/json", bytes.NewReader(payload), &res)
+	return res, err
+}
+
+// setWebhook register the webhook of the bot, the long polling is disabled
+func (bot *Bot) setWebhook() error {
+	message := map[string]interface{}{"url": bot.Webhook, "allowed_updates": []string{"message"}}
+	if bot.Secret != "" {
+		message["secret_token"] = bot.Secret
+	}
+	return bot.call("setWebhook", message, nil)
+}
+
+// deleteWebhook remove the webhook of the bot, the updates are received by the long polling
+func (bot *Bot) deleteWebhook() error {
+	return bot.call("deleteWebhook", map[string]interface{}{}, nil)
+}
+
+// call the method of the Bot API with the JSON payload, the result is decoded to the res if it is not nil
+func (bot *Bot) call(method string, message map[string]interface{}, res interface{}) error {
+	payload, err := jsoniter.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return bot.post(context.Background(), method, "application/json", bytes.NewReader(payload), res)
+}
+
+func (bot *Bot) post(ctx context.Context, method string, contentType string, body io.Reader, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/bot%s/%s", bot.API, bot.Token, method), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data := apiResponse{}
+	err = jsoniter.NewDecoder(resp.Body).Decode(&data)
+	if err != nil {
+		return fmt.Errorf("telegram %s responds %d", method, resp.StatusCode)
+	}
+
+	if !data.OK {
+		return fmt.Errorf("telegram %s %d: %s", method, data.ErrorCode, data.Description)
+	}
+
+	if res != nil {
+		return jsoniter.Unmarshal(data.Result, res)
+	}
+	return nil
+}
+
+// split the text into the parts of the max characters, split at the line breaks if possible
+func split(text string, max int) []string {
+	parts := []string{}
+	for utf8.RuneCountInString(text) > max {
+		runes := []rune(text)
+		cut := max
+		if i := strings.LastIndex(string(runes[:max]), "\n"); i > 0 {
+			cut = utf8.RuneCountInString(string(runes[:max])[:i]) + 1
+		}
+		parts = append(parts, string(runes[:cut]))
+		text = string(runes[cut:])
+	}
+	if text != "" {
+		parts = append(parts, text)
+	}
+	return parts
+}
+
+// contentType the content type of the file name
+func contentType(name string) string {
+	if typ := mime.TypeByExtension(filepath.Ext(name)); typ != "" {
+		return typ
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,126 @@
+package telegram
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"time"
+
+	"github.com/yaoapp/gou/fs"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/neo/assistant"
+	chatctx "github.com/yaoapp/yao/neo/context"
+)
+
+// maxFileSize the max size of the files downloaded, the limit of the Bot API
+const maxFileSize = 20 << 20
+
+// extensions the extensions of the files without the name, the photos are JPEG and the voices are OGG
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"audio/ogg":  ".ogg",
+	"audio/mpeg": ".mp3",
+	"video/mp4":  ".mp4",
+}
+
+// save download the files of the message to the data file system, __telegram/<bot>/<date>/<file_unique_id><ext>
+func (bot *Bot) save(msg *Message) {
+	if len(msg.Files) == 0 {
+		return
+	}
+
+	data, err := fs.Get("data")
+	if err != nil {
+		log.Error("[Telegram] %s get the data file system: %s", bot.ID, err.Error())
+		return
+	}
+
+	for i := range msg.Files {
+		file := &msg.Files[i]
+		raw, err := bot.download(file)
+		if err != nil {
+			log.Error("[Telegram] %s download %s: %s", bot.ID, file.ID, err.Error())
+			continue
+		}
+
+		path := fmt.Sprintf("__telegram/%s/%s/%s%s", bot.ID, time.Now().Format("20060102"), file.UniqueID, filepath.Ext(file.Name))
+		_, err = data.Write(path, bytes.NewReader(raw), 0644)
+		if err != nil {
+			log.Error("[Telegram] %s save %s: %s", bot.ID, file.ID, err.Error())
+			continue
+		}
+		file.Path = path
+	}
+}
+
+// upload download the file and upload it to the assistant, the images are described if the assistant supports the vision
+func (bot *Bot) upload(ast *assistant.Assistant, ctx chatctx.Context, file *File) (*assistant.File, error) {
+	raw, err := bot.download(file)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &multipart.FileHeader{
+		Filename: file.Name,
+		Size:     int64(len(raw)),
+		Header:   textproto.MIMEHeader{"Content-Type": []string{file.ContentType}},
+	}
+
+	res, err := ast.Upload(ctx, header, bytes.NewReader(raw), map[string]interface{}{
+		"sid":     ctx.Sid,
+		"chat_id": ctx.ChatID,
+		"rag":     bot.RAG,
+		"vision":  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	file.Path = res.ID
+	return res, nil
+}
+
+// download the file of the message by the getFile method
+func (bot *Bot) download(file *File) ([]byte, error) {
+	if file.Size > maxFileSize {
+		return nil, fmt.Errorf("the file size %d exceeds the limit of %d", file.Size, maxFileSize)
+	}
+
+	res := struct {
+		FilePath string `json:"file_path"`
+	}{}
+	err := bot.call("getFile", map[string]interface{}{"file_id": file.ID}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Get(fmt.Sprintf("%s/file/bot%s/%s", bot.API, bot.Token, res.FilePath))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("telegram download %s responds %d", file.ID, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxFileSize))
+}
+
+// fileName the name of the file, the name of the photos and the voices are generated
+func fileName(file *File, kind string) string {
+	if file.Name != "" {
+		return file.Name
+	}
+
+	ext, has := extensions[file.ContentType]
+	if !has {
+		ext = ".bin"
+		if exts, _ := mime.ExtensionsByType(file.ContentType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return kind + "_" + file.UniqueID + ext
+}
@@ -0,0 +1,48 @@
+package telegram
+
+import (
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("yao.telegram", map[string]process.Handler{
+		"send":     processSend,
+		"sendfile": processSendFile,
+	})
+}
+
+// processSend yao.telegram.Send(bot, chat_id, text)
+func processSend(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	bot, err := Select(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+
+	err = bot.Send(process.ArgsString(1), process.ArgsString(2), 0)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return nil
+}
+
+// processSendFile yao.telegram.SendFile(bot, chat_id, path|url, caption?), the path of the data file system
+func processSendFile(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	bot, err := Select(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+
+	caption := ""
+	if process.NumOfArgs() > 3 {
+		caption = process.ArgsString(3)
+	}
+
+	err = bot.SendFile(process.ArgsString(1), process.ArgsString(2), caption)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return nil
+}
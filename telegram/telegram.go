@@ -0,0 +1,286 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/neo/assistant"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	"github.com/yaoapp/yao/share"
+)
+
+// Bot the Telegram bot of the telegrams/*.yao, the messages of the chats are answered by the assistant or the process.
+// The updates are received by the long polling, or by the webhook if the webhook is set.
+//
+//	{
+//	  "name": "Support",
+//	  "token": "$ENV.TELEGRAM_BOT_TOKEN",
+//	  "webhook": "https://example.com/api/__yao/telegram/support",
+//	  "secret": "$ENV.TELEGRAM_SECRET",
+//	  "assistant": "support"
+//	}
+type Bot struct {
+	ID        string `json:"-"`
+	Name      string `json:"name,omitempty"`
+	Token     string `json:"token"`
+	Webhook   string `json:"webhook,omitempty"`   // The url of the webhook registered on start, the long polling is used if it is empty
+	Secret    string `json:"secret,omitempty"`    // The secret token of the webhook, X-Telegram-Bot-Api-Secret-Token, required with the webhook
+	Assistant string `json:"assistant,omitempty"` // The assistant answering the messages, the files are uploaded to the assistant
+	Process   string `json:"process,omitempty"`   // The process called with the message instead of the assistant, returns the reply
+	RAG       bool   `json:"rag,omitempty"`       // The files uploaded to the assistant are indexed
+	API       string `json:"api,omitempty"`       // https://api.telegram.org by default, the local Bot API server
+}
+
+// Message the message of the update, the files are downloaded before it is answered
+type Message struct {
+	UpdateID  int64  `json:"update_id"`
+	MessageID int64  `json:"message_id"`
+	ChatID    int64  `json:"chat_id"`
+	ChatType  string `json:"chat_type"` // private, group, supergroup or channel
+	User      int64  `json:"user"`
+	Username  string `json:"username,omitempty"`
+	Text      string `json:"text,omitempty"` // The text or the caption of the message
+	Files     []File `json:"files,omitempty"`
+}
+
+// File the file of the message
+type File struct {
+	ID          string `json:"file_id"`
+	UniqueID    string `json:"file_unique_id"`
+	Name        string `json:"file_name,omitempty"`
+	ContentType string `json:"mime_type,omitempty"`
+	Size        int64  `json:"file_size,omitempty"`
+	Path        string `json:"path,omitempty"` // The path of the data file system, or the file id of the assistant
+}
+
+// Reply the reply of the message, the files are the paths of the data file system or the urls
+type Reply struct {
+	Text  string   `json:"text,omitempty"`
+	Files []string `json:"files,omitempty"`
+}
+
+// Bots the loaded bots
+var Bots = map[string]*Bot{}
+var lock sync.RWMutex
+
+// errSecret the secret token of the webhook is invalid
+var errSecret = errors.New("the secret token is invalid")
+
+// Load the telegrams/*.yao bots, the running bots are restarted
+func Load(cfg config.Config) error {
+	bots := map[string]*Bot{}
+	messages := []string{}
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("telegrams", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		bot := Bot{}
+		err = application.Parse(file, bytes, &bot)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		bot.ID = share.ID(root, file)
+		if err := bot.validate(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		bots[bot.ID] = &bot
+		return nil
+	}, exts...)
+
+	restart := running()
+	if restart {
+		Stop()
+	}
+
+	lock.Lock()
+	Bots = bots
+	lock.Unlock()
+
+	if restart {
+		Start()
+	}
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+	return err
+}
+
+// Select the bot
+func Select(id string) (*Bot, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	bot, has := Bots[id]
+	if !has {
+		return nil, fmt.Errorf("the telegram bot %s does not exist", id)
+	}
+	return bot, nil
+}
+
+// Answer the reply of the message, nil if the message is not answered.
+// The files of the message are uploaded to the assistant, or saved to the data file system for the process.
+func (bot *Bot) Answer(msg *Message) (*Reply, error) {
+	if bot.Process != "" {
+		bot.save(msg)
+		p, err := process.Of(bot.Process, bot.ID, msg.Map())
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := p.Exec()
+		if err != nil {
+			return nil, err
+		}
+		return toReply(res), nil
+	}
+
+	// The commands, e.g. /start, are not answered by the assistant
+	if strings.HasPrefix(msg.Text, "/") || (msg.Text == "" && len(msg.Files) == 0) {
+		return nil, nil
+	}
+
+	ast, err := assistant.Get(bot.Assistant)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user chats with the assistant in the private chat, the members of the group share the chat of the group
+	sid := fmt.Sprintf("telegram:%s:%d", bot.ID, msg.User)
+	cid := fmt.Sprintf("telegram:%s:%d", bot.ID, msg.ChatID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	chat := chatctx.Context{Context: ctx, Sid: sid, ChatID: cid, AssistantID: ast.ID}
+	input := msg.Text
+	for i := range msg.Files {
+		file, err := bot.upload(ast, chat, &msg.Files[i])
+		if err != nil {
+			log.Error("[Telegram] %s upload %s: %s", bot.ID, msg.Files[i].ID, err.Error())
+			continue
+		}
+		input = strings.TrimSpace(input + "\n\n" + attachmentText(msg.Files[i].Name, file))
+	}
+
+	if input == "" {
+		return nil, nil
+	}
+
+	text, err := ast.Reply(chat, input)
+	if err != nil {
+		return nil, err
+	}
+	return &Reply{Text: text}, nil
+}
+
+// Map the message as map, the args of the process
+func (msg *Message) Map() map[string]interface{} {
+	files := []interface{}{}
+	for _, file := range msg.Files {
+		files = append(files, map[string]interface{}{
+			"file_id":   file.ID,
+			"file_name": file.Name,
+			"mime_type": file.ContentType,
+			"file_size": file.Size,
+			"path":      file.Path,
+		})
+	}
+
+	return map[string]interface{}{
+		"update_id":  msg.UpdateID,
+		"message_id": msg.MessageID,
+		"chat_id":    msg.ChatID,
+		"chat_type":  msg.ChatType,
+		"user":       msg.User,
+		"username":   msg.Username,
+		"text":       msg.Text,
+		"files":      files,
+	}
+}
+
+// attachmentText the text of the file uploaded to the assistant, sent with the input
+func attachmentText(name string, file *assistant.File) string {
+	text := fmt.Sprintf("[Attachment: %s, %s, %s]", name, file.ContentType, file.ID)
+	if file.Description != "" {
+		text = text + "\n" + file.Description
+	}
+	return text
+}
+
+// toReply the reply of the process, the text, or the map of the text and the files
+func toReply(res interface{}) *Reply {
+	switch v := res.(type) {
+	case nil:
+		return nil
+
+	case string:
+		return &Reply{Text: v}
+
+	case map[string]interface{}:
+		reply := &Reply{}
+		if text, ok := v["text"].(string); ok {
+			reply.Text = text
+		}
+		switch files := v["files"].(type) {
+		case []string:
+			reply.Files = files
+		case []interface{}:
+			for _, file := range files {
+				if file, ok := file.(string); ok && file != "" {
+					reply.Files = append(reply.Files, file)
+				}
+			}
+		}
+		return reply
+	}
+	return &Reply{Text: fmt.Sprintf("%v", res)}
+}
+
+func (bot *Bot) validate() error {
+	for _, value := range []*string{&bot.Token, &bot.Secret} {
+		if strings.HasPrefix(*value, "$ENV.") {
+			*value = os.Getenv(strings.TrimPrefix(*value, "$ENV."))
+		}
+	}
+
+	if bot.Token == "" {
+		return fmt.Errorf("the token is required")
+	}
+
+	if bot.Assistant == "" && bot.Process == "" {
+		return fmt.Errorf("the assistant or the process is required")
+	}
+
+	// The updates of the webhook without the secret token could be forged by anyone knows the bot
+	if bot.Webhook != "" && bot.Secret == "" {
+		return fmt.Errorf("the secret is required with the webhook")
+	}
+
+	if bot.API == "" {
+		bot.API = "https://api.telegram.org"
+	}
+	bot.API = strings.TrimRight(bot.API, "/")
+	return nil
+}
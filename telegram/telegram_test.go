@@ -0,0 +1,155 @@
+package telegram
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func testBot(api string) *Bot {
+	return &Bot{ID: "support", Name: "Support", Token: "123:abc", Secret: "secret", Process: "scripts.telegram.Reply", API: api}
+}
+
+func TestMessage(t *testing.T) {
+	upd := update{}
+	err := jsoniter.UnmarshalFromString(`{"update_id":10,"message":{"message_id":5,"from":{"id":7,"is_bot":false,"username":"max"},"chat":{"id":-100,"type":"group"},"caption":" Look ",
+		"photo":[{"file_id":"s","file_unique_id":"us","file_size":10},{"file_id":"l","file_unique_id":"ul","file_size":100}],
+		"document":{"file_id":"d","file_unique_id":"ud","file_name":"report.pdf"},
+		"voice":{"file_id":"v","file_unique_id":"uv","mime_type":"audio/ogg"}}}`, &upd)
+	assert.NoError(t, err)
+
+	msg := upd.message()
+	assert.Equal(t, int64(-100), msg.ChatID)
+	assert.Equal(t, int64(7), msg.User)
+	assert.Equal(t, "Look", msg.Text)
+	assert.Len(t, msg.Files, 3)
+	assert.Equal(t, "l", msg.Files[0].ID)
+	assert.Equal(t, "photo_ul.jpg", msg.Files[0].Name)
+	assert.Equal(t, "report.pdf", msg.Files[1].Name)
+	assert.Equal(t, "application/pdf", msg.Files[1].ContentType)
+	assert.Equal(t, "voice_uv.ogg", msg.Files[2].Name)
+
+	jsoniter.UnmarshalFromString(`{"update_id":11,"message":{"from":{"id":8,"is_bot":true},"chat":{"id":1},"text":"hi"}}`, &upd)
+	assert.Nil(t, upd.message())
+	assert.Nil(t, (&update{UpdateID: 12}).message())
+}
+
+func TestToReply(t *testing.T) {
+	assert.Nil(t, toReply(nil))
+	assert.Equal(t, &Reply{Text: "Hi"}, toReply("Hi"))
+	assert.Equal(t, &Reply{Text: "Done", Files: []string{"reports/a.pdf"}}, toReply(map[string]interface{}{"text": "Done", "files": []interface{}{"reports/a.pdf", 1}}))
+	assert.Equal(t, &Reply{Text: "1"}, toReply(1))
+}
+
+func TestSplit(t *testing.T) {
+	assert.Equal(t, []string{"ab\n", "cd"}, split("ab\ncd", 4))
+	assert.Equal(t, []string{"你好", "世界"}, split("你好世界", 2))
+	assert.Equal(t, []string{}, split("", 2))
+}
+
+func TestHandleUpdate(t *testing.T) {
+	defer func(bots map[string]*Bot) { Bots = bots }(Bots)
+	bot := testBot("http://127.0.0.1:0")
+	bot.Webhook = "https://example.com/api/__yao/telegram/support"
+	Bots = map[string]*Bot{"support": bot, "polling": testBot("http://127.0.0.1:0")}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetRoutes(router, "/api/__yao/telegram")
+
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("POST", "/api/__yao/telegram/support", strings.NewReader(`{"update_id":1}`)))
+	assert.Equal(t, 401, res.Code)
+
+	res = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/__yao/telegram/support", strings.NewReader(`{"update_id":1}`))
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", "secret")
+	router.ServeHTTP(res, req)
+	assert.Equal(t, 200, res.Code)
+
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("POST", "/api/__yao/telegram/unknown", strings.NewReader(`{}`)))
+	assert.Equal(t, 404, res.Code)
+
+	// The bots of the long polling do not receive the updates
+	res = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/api/__yao/telegram/polling", strings.NewReader(`{"update_id":1}`))
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", "secret")
+	router.ServeHTTP(res, req)
+	assert.Equal(t, 404, res.Code)
+}
+
+func TestValidate(t *testing.T) {
+	bot := testBot("")
+	bot.Webhook = "https://example.com/api/__yao/telegram/support"
+	assert.NoError(t, bot.validate())
+
+	bot.Secret = ""
+	assert.EqualError(t, bot.validate(), "the secret is required with the webhook")
+}
+
+func TestSend(t *testing.T) {
+	calls := []map[string]interface{}{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		call := map[string]interface{}{}
+		jsoniter.Unmarshal(body, &call)
+		call["path"] = r.URL.Path
+		calls = append(calls, call)
+
+		switch r.URL.Path {
+		case "/bot123:abc/getUpdates":
+			w.Write([]byte(`{"ok":true,"result":[{"update_id":3,"message":{"message_id":1,"from":{"id":7},"chat":{"id":7,"type":"private"},"text":"hi"}}]}`))
+		case "/bot123:abc/sendPhoto":
+			w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: wrong file identifier"}`))
+		default:
+			w.Write([]byte(`{"ok":true,"result":{}}`))
+		}
+	}))
+	defer srv.Close()
+
+	bot := testBot(srv.URL)
+	assert.NoError(t, bot.Send("7", strings.Repeat("a", maxTextLength+1), 5))
+	assert.Len(t, calls, 2)
+	assert.Equal(t, "/bot123:abc/sendMessage", calls[0]["path"])
+	assert.Equal(t, float64(5), calls[0]["reply_parameters"].(map[string]interface{})["message_id"])
+	assert.Equal(t, "a", calls[1]["text"])
+
+	err := bot.SendFile("7", "https://example.com/chart.png?v=1", "Chart")
+	assert.EqualError(t, err, "telegram sendPhoto 400: Bad Request: wrong file identifier")
+
+	updates, err := bot.updates(context.Background(), 3)
+	assert.NoError(t, err)
+	assert.Len(t, updates, 1)
+	assert.Equal(t, "hi", updates[0].message().Text)
+	assert.Equal(t, float64(3), calls[len(calls)-1]["offset"])
+}
+
+func TestDownload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bot123:abc/getFile":
+			w.Write([]byte(`{"ok":true,"result":{"file_id":"d","file_path":"documents/file_1.pdf"}}`))
+		case "/file/bot123:abc/documents/file_1.pdf":
+			w.Write([]byte("%PDF"))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer srv.Close()
+
+	bot := testBot(srv.URL)
+	raw, err := bot.download(&File{ID: "d"})
+	assert.NoError(t, err)
+	assert.Equal(t, "%PDF", string(raw))
+
+	_, err = bot.download(&File{ID: "d", Size: maxFileSize + 1})
+	assert.Error(t, err)
+}
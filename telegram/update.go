@@ -0,0 +1,244 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+)
+
+// update the update of the Bot API, the messages only
+type update struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		MessageID int64 `json:"message_id"`
+		From      struct {
+			ID       int64  `json:"id"`
+			IsBot    bool   `json:"is_bot"`
+			Username string `json:"username"`
+		} `json:"from"`
+		Chat struct {
+			ID   int64  `json:"id"`
+			Type string `json:"type"`
+		} `json:"chat"`
+		Text     string `json:"text,omitempty"`
+		Caption  string `json:"caption,omitempty"`
+		Document *File  `json:"document,omitempty"`
+		Photo    []File `json:"photo,omitempty"`
+		Audio    *File  `json:"audio,omitempty"`
+		Voice    *File  `json:"voice,omitempty"`
+		Video    *File  `json:"video,omitempty"`
+	} `json:"message,omitempty"`
+}
+
+// pollers the cancel functions of the long polling of the bots
+var pollers = map[string]context.CancelFunc{}
+var started = false
+var pollerLock sync.Mutex
+
+// SetRoutes the webhooks of the bots, the webhook of the bot telegrams/support.yao is <path>/support
+//
+//	POST <path>/:id  The updates of the bot, the messages are answered in the background
+func SetRoutes(router *gin.Engine, path string) {
+	router.POST(path+"/:id", handleUpdate)
+}
+
+func handleUpdate(c *gin.Context) {
+	bot, err := Select(c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"code": 404, "message": err.Error()})
+		return
+	}
+
+	// The bots of the long polling do not receive the updates of the webhook
+	if bot.Webhook == "" || bot.Secret == "" {
+		c.JSON(404, gin.H{"code": 404, "message": fmt.Sprintf("the bot %s has no webhook", bot.ID)})
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Telegram-Bot-Api-Secret-Token")), []byte(bot.Secret)) != 1 {
+		c.JSON(401, gin.H{"code": 401, "message": errSecret.Error()})
+		return
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	upd := update{}
+	if err := jsoniter.Unmarshal(raw, &upd); err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	// Telegram retries the update not answered, the message is answered in the background
+	if msg := upd.message(); msg != nil {
+		go reply(bot, msg)
+	}
+	c.Status(200)
+}
+
+// Start the bots, the webhooks are registered and the long polling of the others are started
+func Start() {
+	pollerLock.Lock()
+	defer pollerLock.Unlock()
+
+	lock.RLock()
+	defer lock.RUnlock()
+
+	for id, bot := range Bots {
+		if bot.Webhook != "" {
+			go func(bot *Bot) {
+				if err := bot.setWebhook(); err != nil {
+					log.Error("[Telegram] %s set the webhook: %s", bot.ID, err.Error())
+				}
+			}(bot)
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		pollers[id] = cancel
+		go bot.poll(ctx)
+		log.Info("[Telegram] %s start", id)
+	}
+	started = true
+}
+
+// Stop the long polling of the bots
+func Stop() {
+	pollerLock.Lock()
+	defer pollerLock.Unlock()
+
+	for id, cancel := range pollers {
+		cancel()
+		log.Info("[Telegram] %s stop", id)
+	}
+	pollers = map[string]context.CancelFunc{}
+	started = false
+}
+
+func running() bool {
+	pollerLock.Lock()
+	defer pollerLock.Unlock()
+	return started
+}
+
+// poll receive the updates by the long polling until the context is canceled, retries in 5 seconds if it fails
+func (bot *Bot) poll(ctx context.Context) {
+	if err := bot.deleteWebhook(); err != nil {
+		log.Warn("[Telegram] %s delete the webhook: %s", bot.ID, err.Error())
+	}
+
+	offset := int64(0)
+	for {
+		updates, err := bot.updates(ctx, offset)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			log.Error("[Telegram] %s get the updates: %s", bot.ID, err.Error())
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		for _, upd := range updates {
+			offset = upd.UpdateID + 1
+			if msg := upd.message(); msg != nil {
+				go reply(bot, msg)
+			}
+		}
+	}
+}
+
+// message the message of the update, nil if it is not the message of the user
+func (upd *update) message() *Message {
+	m := upd.Message
+	if m == nil || m.From.IsBot {
+		return nil
+	}
+
+	msg := &Message{
+		UpdateID:  upd.UpdateID,
+		MessageID: m.MessageID,
+		ChatID:    m.Chat.ID,
+		ChatType:  m.Chat.Type,
+		User:      m.From.ID,
+		Username:  m.From.Username,
+		Text:      strings.TrimSpace(m.Text + m.Caption),
+		Files:     []File{},
+	}
+
+	// The largest size of the photo
+	if len(m.Photo) > 0 {
+		photo := m.Photo[len(m.Photo)-1]
+		photo.ContentType = "image/jpeg"
+		photo.Name = fileName(&photo, "photo")
+		msg.Files = append(msg.Files, photo)
+	}
+
+	kinds := []string{"document", "audio", "voice", "video"}
+	for i, file := range []*File{m.Document, m.Audio, m.Voice, m.Video} {
+		if file == nil {
+			continue
+		}
+		kind := kinds[i]
+		if file.ContentType == "" {
+			file.ContentType = contentType(file.Name)
+		}
+		file.Name = fileName(file, kind)
+		msg.Files = append(msg.Files, *file)
+	}
+
+	if msg.Text == "" && len(msg.Files) == 0 {
+		return nil
+	}
+	return msg
+}
+
+// reply answer the message and reply to it, the files of the reply are sent after the text
+func reply(bot *Bot, msg *Message) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error("[Telegram] %s %d: %v", bot.ID, msg.ChatID, err)
+		}
+	}()
+
+	res, err := bot.Answer(msg)
+	if err != nil {
+		log.Error("[Telegram] %s %d: %s", bot.ID, msg.ChatID, err.Error())
+		return
+	}
+
+	if res == nil {
+		return
+	}
+
+	chatID := strconv.FormatInt(msg.ChatID, 10)
+	if res.Text != "" {
+		if err := bot.Send(chatID, res.Text, msg.MessageID); err != nil {
+			log.Error("[Telegram] %s reply to %d: %s", bot.ID, msg.ChatID, err.Error())
+			return
+		}
+	}
+
+	for _, file := range res.Files {
+		if err := bot.SendFile(chatID, file, ""); err != nil {
+			log.Error("[Telegram] %s send %s to %d: %s", bot.ID, file, msg.ChatID, err.Error())
+		}
+	}
+}
@@ -202,7 +202,7 @@ func AutoLogin(id int) (map[string]interface{}, error) {
 		"sid":        sid,
 		"issuer":     "admin",
 	})
-	session.Global().Expire(time.Duration(token.ExpiresAt)*time.Second).ID(sid).Set("user_id", id)
+	session.Global().Expire(time.Hour).ID(sid).Set("user_id", id)
 	session.Global().ID(sid).Set("user", row)
 	session.Global().ID(sid).Set("issuer", "admin")
 
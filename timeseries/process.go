@@ -0,0 +1,56 @@
+package timeseries
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("timeseries", map[string]process.Handler{
+		"write": processWrite,
+		"query": processQuery,
+	})
+}
+
+// processWrite timeseries.Write({"metric": "robot.cost", "tags": {"robot": "sales"}, "value": 0.12, "time": "2024-01-31T12:00:00Z"}, ...)
+func processWrite(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	points := []Point{}
+	for i := range process.Args {
+		point := Point{}
+		if err := convert(process.Args[i], &point); err != nil {
+			exception.New("the point %d is invalid: %s", 400, i, err.Error()).Throw()
+		}
+		points = append(points, point)
+	}
+
+	if err := Write(points...); err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return nil
+}
+
+// processQuery timeseries.Query({"metric": "robot.cost", "tags": {"robot": "sales"}, "start": "2024-01-01T00:00:00Z", "interval": "1d", "aggregate": "sum"})
+func processQuery(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	r := Range{}
+	if err := convert(process.Args[0], &r); err != nil {
+		exception.New("the range is invalid: %s", 400, err.Error()).Throw()
+	}
+
+	buckets, err := Query(r)
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return buckets
+}
+
+// convert the argument to the value, the times are RFC 3339 strings
+func convert(arg interface{}, v interface{}) error {
+	bytes, err := jsoniter.Marshal(arg)
+	if err != nil {
+		return err
+	}
+	return jsoniter.Unmarshal(bytes, v)
+}
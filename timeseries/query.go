@@ -0,0 +1,263 @@
+package timeseries
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yaoapp/xun/dbal"
+)
+
+// Range the range query of the metric, the points are downsampled into the buckets of the interval
+//
+//	{ "metric": "connector.latency", "tags": { "connector": "gpt-4o" }, "start": "2024-01-01T00:00:00Z", "interval": "1h", "aggregate": "avg" }
+type Range struct {
+	Metric    string            `json:"metric"`
+	Tags      map[string]string `json:"tags,omitempty"`      // The series matched all of the tags
+	Start     time.Time         `json:"start"`               // 24 hours before the end by default
+	End       time.Time         `json:"end"`                 // Excluded, now by default
+	Interval  string            `json:"interval,omitempty"`  // The bucket size, e.g. 1m, 5m, 1h, 1d, 1h by default
+	Aggregate string            `json:"aggregate,omitempty"` // avg, sum, min, max or count, avg by default
+}
+
+// Bucket the aggregated value of the points of the bucket, the time is the start of the bucket
+type Bucket struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+	Count int64     `json:"count"`
+}
+
+// maxBuckets the max buckets of the range
+const maxBuckets = 10000
+
+var aggregates = map[string]bool{"avg": true, "sum": true, "min": true, "max": true, "count": true}
+
+// stats the stats of the points of the bucket, the buckets of the tables are merged
+type stats struct {
+	sum   float64
+	count int64
+	min   float64
+	max   float64
+}
+
+// Query the buckets of the range, the buckets without the points are omitted.
+// The points are aggregated by the database, the buckets of the monthly tables are merged.
+func Query(r Range) ([]Bucket, error) {
+	interval, err := r.normalize(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !ready {
+		return []Bucket{}, nil
+	}
+
+	step := interval.Milliseconds()
+	buckets := map[int64]*stats{}
+	for _, month := range months(r.Start, r.End) {
+		table, err := partition(month, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if table == "" {
+			continue
+		}
+
+		qb := newQuery(table).
+			Select(
+				dbal.Raw(fmt.Sprintf("(ts - ts %% %d) AS bucket", step)),
+				dbal.Raw("SUM(value) AS sum"),
+				dbal.Raw("COUNT(*) AS count"),
+				dbal.Raw("MIN(value) AS min"),
+				dbal.Raw("MAX(value) AS max"),
+			).
+			Where("metric", r.Metric).
+			Where("ts", ">=", r.Start.UnixMilli()).
+			Where("ts", "<", r.End.UnixMilli())
+
+		for _, pattern := range patterns(r.Tags) {
+			qb.WhereRaw("series LIKE ? ESCAPE '!'", pattern)
+		}
+
+		rows, err := qb.GroupBy("bucket").Get()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			merge(buckets, toInt64(row["bucket"]), stats{
+				sum:   toFloat64(row["sum"]),
+				count: toInt64(row["count"]),
+				min:   toFloat64(row["min"]),
+				max:   toFloat64(row["max"]),
+			})
+		}
+	}
+	return r.buckets(buckets), nil
+}
+
+// normalize validate the range and set the defaults, returns the interval
+func (r *Range) normalize(now time.Time) (time.Duration, error) {
+	if !nameRe.MatchString(r.Metric) {
+		return 0, fmt.Errorf("the metric %s is invalid", r.Metric)
+	}
+
+	if r.End.IsZero() {
+		r.End = now
+	}
+
+	if r.Start.IsZero() {
+		r.Start = r.End.Add(-24 * time.Hour)
+	}
+
+	if !r.Start.Before(r.End) {
+		return 0, fmt.Errorf("the start %s is not before the end %s", r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339))
+	}
+
+	if r.Interval == "" {
+		r.Interval = "1h"
+	}
+
+	interval, err := parseInterval(r.Interval)
+	if err != nil {
+		return 0, err
+	}
+
+	if r.End.Sub(r.Start)/interval > maxBuckets {
+		return 0, fmt.Errorf("the range has more than %d buckets of %s", maxBuckets, r.Interval)
+	}
+
+	r.Aggregate = strings.ToLower(r.Aggregate)
+	if r.Aggregate == "" {
+		r.Aggregate = "avg"
+	}
+
+	if !aggregates[r.Aggregate] {
+		return 0, fmt.Errorf("the aggregate %s is invalid, avg, sum, min, max or count", r.Aggregate)
+	}
+	return interval, nil
+}
+
+// buckets the values of the aggregate of the buckets, ordered by the time
+func (r Range) buckets(buckets map[int64]*stats) []Bucket {
+	keys := []int64{}
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	res := []Bucket{}
+	for _, key := range keys {
+		s := buckets[key]
+		bucket := Bucket{Time: time.UnixMilli(key).UTC(), Count: s.count}
+		switch r.Aggregate {
+		case "sum":
+			bucket.Value = s.sum
+		case "min":
+			bucket.Value = s.min
+		case "max":
+			bucket.Value = s.max
+		case "count":
+			bucket.Value = float64(s.count)
+		default:
+			if s.count > 0 {
+				bucket.Value = s.sum / float64(s.count)
+			}
+		}
+		res = append(res, bucket)
+	}
+	return res
+}
+
+// merge the stats of the bucket, the buckets of the weeks may span the monthly tables
+func merge(buckets map[int64]*stats, key int64, s stats) {
+	bucket, has := buckets[key]
+	if !has {
+		buckets[key] = &s
+		return
+	}
+
+	bucket.sum += s.sum
+	bucket.count += s.count
+	if s.min < bucket.min {
+		bucket.min = s.min
+	}
+	if s.max > bucket.max {
+		bucket.max = s.max
+	}
+}
+
+// parseInterval the duration of the interval, the days are supported, e.g. 1d, 7d
+func parseInterval(value string) (time.Duration, error) {
+	var interval time.Duration
+	var err error
+	if strings.HasSuffix(value, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(value, "d"))
+		interval = time.Duration(days) * 24 * time.Hour
+	} else {
+		interval, err = time.ParseDuration(value)
+	}
+
+	if err != nil || interval < time.Second || interval%time.Second != 0 {
+		return 0, fmt.Errorf("the interval %s is invalid, e.g. 1m, 5m, 1h, 1d", value)
+	}
+	return interval, nil
+}
+
+// patterns the LIKE patterns of the tags, ! is the escape character
+func patterns(tags map[string]string) []string {
+	escape := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	keys := []string{}
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	res := []string{}
+	for _, key := range keys {
+		res = append(res, "%,"+escape.Replace(key+"="+tags[key])+",%")
+	}
+	return res
+}
+
+func toInt64(v interface{}) int64 {
+	switch value := v.(type) {
+	case int:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	case uint64:
+		return int64(value)
+	case float64:
+		return int64(value)
+	case []byte:
+		n, _ := strconv.ParseInt(string(value), 10, 64)
+		return n
+	case string:
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return n
+	}
+	return 0
+}
+
+func toFloat64(v interface{}) float64 {
+	switch value := v.(type) {
+	case float64:
+		return value
+	case float32:
+		return float64(value)
+	case []byte:
+		n, _ := strconv.ParseFloat(string(value), 64)
+		return n
+	case string:
+		n, _ := strconv.ParseFloat(value, 64)
+		return n
+	}
+	return float64(toInt64(v))
+}
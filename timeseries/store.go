@@ -0,0 +1,130 @@
+package timeseries
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// tablePrefix the prefix of the monthly tables, e.g. yao_timeseries_202401
+const tablePrefix = "yao_timeseries_"
+
+// ready the store is loaded
+var ready = false
+
+// tables the monthly tables existed
+var tables = struct {
+	mu   sync.Mutex
+	data map[string]bool
+}{data: map[string]bool{}}
+
+// partition the monthly table of the time, the table is created if create is true.
+// Returns an empty name if the table does not exist and is not created.
+func partition(at time.Time, create bool) (string, error) {
+	name := tableOf(at)
+
+	tables.mu.Lock()
+	defer tables.mu.Unlock()
+	if tables.data[name] {
+		return name, nil
+	}
+
+	sch := capsule.Schema()
+	has, err := sch.HasTable(name)
+	if err != nil {
+		return "", err
+	}
+
+	if has {
+		tables.data[name] = true
+		return name, nil
+	}
+
+	if !create {
+		return "", nil
+	}
+
+	err = sch.CreateTable(name, func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("metric", 200).Index()
+		table.String("series", 1000)   // ,<tag>=<value>,...
+		table.BigInteger("ts").Index() // The unix milliseconds
+		table.Double("value").SetDefault(0)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Trace("Create the time-series table: %s", name)
+	tables.data[name] = true
+	return name, nil
+}
+
+// tableOf the name of the monthly table of the time
+func tableOf(at time.Time) string {
+	return tablePrefix + at.UTC().Format("200601")
+}
+
+// months the first days of the months of the period [start, end)
+func months(start time.Time, end time.Time) []time.Time {
+	res := []time.Time{}
+	start, end = start.UTC(), end.UTC()
+	at := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for at.Before(end) {
+		res = append(res, at)
+		at = at.AddDate(0, 1, 0)
+	}
+	return res
+}
+
+// expired the months of the tables to drop, the months ended before the retention.
+// The tables of the 24 months before are checked.
+func expired(now time.Time, days int) []time.Time {
+	if days <= 0 {
+		return nil
+	}
+
+	cutoff := now.UTC().AddDate(0, 0, -days)
+	last := time.Date(cutoff.Year(), cutoff.Month(), 1, 0, 0, 0, 0, time.UTC) // The month of the cutoff is kept
+	return months(last.AddDate(0, -24, 0), last)
+}
+
+// drop the expired tables
+func drop(now time.Time) error {
+	lock.Lock()
+	days := retention
+	lock.Unlock()
+
+	sch := capsule.Schema()
+	for _, month := range expired(now, days) {
+		name := tableOf(month)
+		has, err := sch.HasTable(name)
+		if err != nil {
+			return err
+		}
+		if !has {
+			continue
+		}
+
+		if err := sch.DropTableIfExists(name); err != nil {
+			return fmt.Errorf("drop %s: %s", name, err.Error())
+		}
+
+		tables.mu.Lock()
+		delete(tables.data, name)
+		tables.mu.Unlock()
+		log.Info("[TimeSeries] drop the expired table %s", name)
+	}
+	return nil
+}
+
+func newQuery(table string) query.Query {
+	qb := capsule.Query()
+	qb.Table(table)
+	return qb
+}
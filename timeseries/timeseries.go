@@ -0,0 +1,201 @@
+package timeseries
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/cluster"
+	"github.com/yaoapp/yao/config"
+)
+
+// Point the value of the metric at the time, the tags are the dimensions of the series
+//
+//	{ "metric": "connector.latency", "tags": { "connector": "gpt-4o" }, "value": 320, "time": "2024-01-31T12:00:00Z" }
+type Point struct {
+	Metric string            `json:"metric"`
+	Tags   map[string]string `json:"tags,omitempty"`
+	Value  float64           `json:"value"`
+	Time   time.Time         `json:"time"` // Now if zero
+}
+
+// bufferSize the points buffered by Add, the points are dropped if the buffer is full
+const bufferSize = 10000
+
+// flushInterval how often the buffered points are written
+var flushInterval = time.Second
+
+var nameRe = regexp.MustCompile(`^[a-zA-Z0-9_.:\-]{1,200}$`)
+
+var buffer = make(chan Point, bufferSize)
+var retention = 0
+var started = false
+var lock sync.Mutex
+
+// Load create the table of the current month, the buffered points are written in the background.
+// The monthly tables ended before the retention are dropped daily.
+func Load(cfg config.Config) error {
+	if capsule.Global == nil {
+		return nil
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	retention = cfg.Metrics.Retention
+	if _, err := partition(time.Now(), true); err != nil {
+		return err
+	}
+
+	ready = true
+	if !started {
+		started = true
+		go run()
+	}
+	return nil
+}
+
+// Write the points, the points are written to the monthly tables of the times of them
+func Write(points ...Point) error {
+	if !ready {
+		return fmt.Errorf("the time-series store is not ready")
+	}
+
+	rows := map[string][]map[string]interface{}{}
+	for _, point := range points {
+		if err := point.validate(); err != nil {
+			return err
+		}
+
+		at := point.Time
+		if at.IsZero() {
+			at = time.Now()
+		}
+
+		table, err := partition(at, true)
+		if err != nil {
+			return err
+		}
+
+		rows[table] = append(rows[table], map[string]interface{}{
+			"metric": point.Metric,
+			"series": series(point.Tags),
+			"ts":     at.UnixMilli(),
+			"value":  point.Value,
+		})
+	}
+
+	for table, values := range rows {
+		if err := newQuery(table).Insert(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add buffer the point, the point is written in the background.
+// The point is dropped if the store is not ready, the point is invalid or the buffer is full, the metrics do not block the callers.
+func Add(point Point) {
+	if !ready {
+		return
+	}
+
+	if err := point.validate(); err != nil {
+		log.Warn("[TimeSeries] %s, the point is dropped", err.Error())
+		return
+	}
+
+	if point.Time.IsZero() {
+		point.Time = time.Now()
+	}
+
+	select {
+	case buffer <- point:
+	default:
+		log.Warn("[TimeSeries] the buffer is full, the point %s is dropped", point.Metric)
+	}
+}
+
+// run write the buffered points and drop the expired tables
+func run() {
+	flush := time.NewTicker(flushInterval)
+	prune := time.NewTicker(24 * time.Hour)
+	defer flush.Stop()
+	defer prune.Stop()
+
+	if cluster.IsLeader() {
+		if err := drop(time.Now()); err != nil {
+			log.Error("[TimeSeries] %s", err.Error())
+		}
+	}
+
+	points := []Point{}
+	for {
+		select {
+		case point := <-buffer:
+			points = append(points, point)
+			if len(points) < 500 {
+				continue
+			}
+
+		case <-flush.C:
+			if len(points) == 0 {
+				continue
+			}
+
+		case <-prune.C:
+			// The expired tables are dropped by the leader of the cluster only
+			if !cluster.IsLeader() {
+				continue
+			}
+			if err := drop(time.Now()); err != nil {
+				log.Error("[TimeSeries] %s", err.Error())
+			}
+			continue
+		}
+
+		if err := Write(points...); err != nil {
+			log.Error("[TimeSeries] write %d points: %s", len(points), err.Error())
+		}
+		points = []Point{}
+	}
+}
+
+func (point Point) validate() error {
+	if !nameRe.MatchString(point.Metric) {
+		return fmt.Errorf("the metric %s is invalid", point.Metric)
+	}
+
+	for key, value := range point.Tags {
+		if !nameRe.MatchString(key) {
+			return fmt.Errorf("the tag %s of the metric %s is invalid", key, point.Metric)
+		}
+		if strings.ContainsAny(value, ",=") {
+			return fmt.Errorf("the tag %s=%s of the metric %s is invalid, the values must not contain , or =", key, value, point.Metric)
+		}
+	}
+	return nil
+}
+
+// series the key of the series of the tags, the tags are sorted, e.g. ,connector=gpt-4o,team=1,
+func series(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ","
+	}
+
+	keys := []string{}
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+tags[key])
+	}
+	return "," + strings.Join(pairs, ",") + ","
+}
@@ -0,0 +1,75 @@
+package timeseries
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoint(t *testing.T) {
+	assert.Equal(t, ",", series(nil))
+	assert.Equal(t, ",connector=gpt-4o,team=1,", series(map[string]string{"team": "1", "connector": "gpt-4o"}))
+
+	assert.NoError(t, Point{Metric: "connector.latency", Tags: map[string]string{"connector": "gpt-4o"}}.validate())
+	assert.Error(t, Point{Metric: "robot cost"}.validate())
+	assert.Error(t, Point{Metric: "robot.cost", Tags: map[string]string{"robot": "a,b"}}.validate())
+
+	assert.Equal(t, []string{"%,connector=gpt!_4o,%", "%,team=1!%,%"}, patterns(map[string]string{"team": "1%", "connector": "gpt_4o"}))
+}
+
+func TestRange(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	r := Range{Metric: "robot.cost"}
+	interval, err := r.normalize(now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, interval)
+	assert.Equal(t, now.Add(-24*time.Hour), r.Start)
+	assert.Equal(t, "avg", r.Aggregate)
+
+	r = Range{Metric: "robot.cost", Interval: "1d", Aggregate: "SUM", Start: now.AddDate(0, 0, -7), End: now}
+	interval, err = r.normalize(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, interval)
+	assert.Equal(t, "sum", r.Aggregate)
+
+	_, err = (&Range{Metric: "robot.cost", Interval: "1s", Start: now.AddDate(-1, 0, 0)}).normalize(now)
+	assert.Error(t, err)
+	_, err = (&Range{Metric: "robot.cost", Interval: "500ms"}).normalize(now)
+	assert.Error(t, err)
+	_, err = (&Range{Metric: "robot.cost", Aggregate: "last"}).normalize(now)
+	assert.Error(t, err)
+	_, err = (&Range{Metric: "robot.cost", Start: now, End: now}).normalize(now)
+	assert.Error(t, err)
+}
+
+func TestBuckets(t *testing.T) {
+	buckets := map[int64]*stats{}
+	merge(buckets, 7200000, stats{sum: 6, count: 2, min: 1, max: 5})
+	merge(buckets, 3600000, stats{sum: 4, count: 1, min: 4, max: 4})
+	merge(buckets, 7200000, stats{sum: 4, count: 2, min: 0, max: 3})
+
+	res := Range{Aggregate: "avg"}.buckets(buckets)
+	assert.Equal(t, []Bucket{
+		{Time: time.UnixMilli(3600000).UTC(), Value: 4, Count: 1},
+		{Time: time.UnixMilli(7200000).UTC(), Value: 2.5, Count: 4},
+	}, res)
+
+	assert.Equal(t, float64(0), Range{Aggregate: "min"}.buckets(buckets)[1].Value)
+	assert.Equal(t, float64(5), Range{Aggregate: "max"}.buckets(buckets)[1].Value)
+	assert.Equal(t, float64(10), Range{Aggregate: "sum"}.buckets(buckets)[1].Value)
+}
+
+func TestPartitions(t *testing.T) {
+	assert.Equal(t, "yao_timeseries_202401", tableOf(time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC)))
+
+	res := months(time.Date(2023, 12, 20, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, []string{"yao_timeseries_202312", "yao_timeseries_202401"}, []string{tableOf(res[0]), tableOf(res[1])})
+
+	// The month of the cutoff 2024-01-15 is kept
+	res = expired(time.Date(2024, 4, 14, 0, 0, 0, 0, time.UTC), 90)
+	assert.Len(t, res, 24)
+	assert.Equal(t, "yao_timeseries_202312", tableOf(res[23]))
+	assert.Empty(t, expired(time.Now(), 0))
+}
@@ -0,0 +1,46 @@
+package trace
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetRoutes the traces of the agent turns, for the admins
+//
+//	GET <path>?chat_id=xxx&assistant_id=xxx&start=2024-01-01T00:00:00Z&end=...&status=error&limit=50    The turns matched, the latest first
+//	GET <path>/:id                                                                                      The waterfall of the trace
+func SetRoutes(router *gin.Engine, path string, guards ...gin.HandlerFunc) {
+	handlers := func(handler gin.HandlerFunc) []gin.HandlerFunc {
+		return append(append([]gin.HandlerFunc{}, guards...), handler)
+	}
+	router.GET(path, handlers(handleSearch)...)
+	router.GET(path+"/:id", handlers(handleGet)...)
+}
+
+func handleSearch(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	spans, err := Search(Filter{
+		ChatID:      c.Query("chat_id"),
+		AssistantID: c.Query("assistant_id"),
+		Sid:         c.Query("sid"),
+		Status:      c.Query("status"),
+		Start:       c.Query("start"),
+		End:         c.Query("end"),
+		Limit:       limit,
+	})
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(200, spans)
+}
+
+func handleGet(c *gin.Context) {
+	res, err := Get(c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"code": 404, "message": err.Error()})
+		return
+	}
+	c.JSON(200, res)
+}
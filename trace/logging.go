@@ -0,0 +1,171 @@
+package trace
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// The modes of the logging of the prompts and the completions
+const (
+	LoggingFull     = "full"     // The prompts and the completions are stored as is
+	LoggingRedacted = "redacted" // The redaction rules are applied before the prompts and the completions are stored
+	LoggingMetadata = "metadata" // Only the sizes of the prompts and the completions are stored
+)
+
+// Logging the logging of the prompts and the completions, the inputs and the outputs of the LLM spans
+type Logging struct {
+	Mode  string      `json:"mode,omitempty" yaml:"mode,omitempty"`   // full, redacted or metadata, full by default
+	Rules []Redaction `json:"rules,omitempty" yaml:"rules,omitempty"` // The redaction rules of the redacted mode
+}
+
+// Redaction the redaction rule, the texts matched by the pattern or the value of the path are replaced
+type Redaction struct {
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"` // The regular expression, e.g. \b\d{16}\b
+	Path    string `json:"path,omitempty" yaml:"path,omitempty"`       // The field path from the input or the output, * matches any key or index, e.g. input.messages.*.name
+	Replace string `json:"replace,omitempty" yaml:"replace,omitempty"` // [REDACTED] by default
+}
+
+type redaction struct {
+	pattern *regexp.Regexp
+	path    []string
+	replace string
+}
+
+var loggingMode = LoggingFull
+var redactions = []redaction{}
+var loggingLock sync.RWMutex
+
+// SetLogging set the logging of the prompts and the completions
+func SetLogging(setting Logging) error {
+	if setting.Mode == "" {
+		setting.Mode = LoggingFull
+	}
+
+	if setting.Mode != LoggingFull && setting.Mode != LoggingRedacted && setting.Mode != LoggingMetadata {
+		return fmt.Errorf("the logging mode %s is invalid, full, redacted or metadata", setting.Mode)
+	}
+
+	rules := []redaction{}
+	for i, rule := range setting.Rules {
+		if (rule.Pattern == "") == (rule.Path == "") {
+			return fmt.Errorf("the redaction rule %d should have either the pattern or the path", i)
+		}
+
+		r := redaction{replace: rule.Replace}
+		if r.replace == "" {
+			r.replace = "[REDACTED]"
+		}
+
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return fmt.Errorf("the pattern of the redaction rule %d is invalid: %s", i, err.Error())
+			}
+			r.pattern = pattern
+		}
+
+		if rule.Path != "" {
+			r.path = strings.Split(rule.Path, ".")
+			if r.path[0] != "input" && r.path[0] != "output" {
+				return fmt.Errorf("the path of the redaction rule %d should start with input or output", i)
+			}
+		}
+		rules = append(rules, r)
+	}
+
+	loggingLock.Lock()
+	defer loggingLock.Unlock()
+	loggingMode, redactions = setting.Mode, rules
+	return nil
+}
+
+// logged the input, the output and the attributes of the LLM span stored
+func logged(span *Span) (interface{}, interface{}, map[string]interface{}) {
+	loggingLock.RLock()
+	mode, rules := loggingMode, redactions
+	loggingLock.RUnlock()
+
+	switch mode {
+	case LoggingRedacted:
+		return redact(span.Input, []string{"input"}, rules), redact(span.Output, []string{"output"}, rules), span.Attributes
+
+	case LoggingMetadata:
+		attributes := map[string]interface{}{}
+		for key, value := range span.Attributes {
+			attributes[key] = value
+		}
+		attributes["input_size"] = size(span.Input)
+		attributes["output_size"] = size(span.Output)
+		if input, ok := decode(payload(span.Input)).(map[string]interface{}); ok {
+			if messages, ok := input["messages"].([]interface{}); ok {
+				attributes["messages"] = len(messages)
+			}
+		}
+		return nil, nil, attributes
+	}
+	return span.Input, span.Output, span.Attributes
+}
+
+// redact apply the rules to the value, the value is decoded from its JSON
+func redact(value interface{}, path []string, rules []redaction) interface{} {
+	if value == nil || len(rules) == 0 {
+		return value
+	}
+	return walk(decode(payload(value)), path, rules)
+}
+
+func walk(value interface{}, path []string, rules []redaction) interface{} {
+	for _, rule := range rules {
+		if rule.path != nil && matchPath(rule.path, path) {
+			return rule.replace
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		for _, rule := range rules {
+			if rule.pattern != nil {
+				v = rule.pattern.ReplaceAllLiteralString(v, rule.replace)
+			}
+		}
+		return v
+
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			res[key] = walk(item, append(path[:len(path):len(path)], key), rules)
+		}
+		return res
+
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			res[i] = walk(item, append(path[:len(path):len(path)], fmt.Sprintf("%d", i)), rules)
+		}
+		return res
+	}
+	return value
+}
+
+func matchPath(pattern []string, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// size the size of the JSON of the value in bytes
+func size(value interface{}) int {
+	raw, ok := payload(value).(string)
+	if !ok {
+		return 0
+	}
+	return len(raw)
+}
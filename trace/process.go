@@ -0,0 +1,41 @@
+package trace
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("trace", map[string]process.Handler{
+		"search": processSearch,
+		"get":    processGet,
+	})
+}
+
+// processSearch trace.Search({"chat_id": "xxx", "assistant_id": "xxx", "start": "2024-01-01T00:00:00Z", "status": "error", "limit": 50}?)
+func processSearch(process *process.Process) interface{} {
+	filter := Filter{}
+	if process.NumOfArgs() > 0 {
+		raw, _ := jsoniter.Marshal(process.Args[0])
+		if err := jsoniter.Unmarshal(raw, &filter); err != nil {
+			exception.New("the filter is invalid: %s", 400, err.Error()).Throw()
+		}
+	}
+
+	spans, err := Search(filter)
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return spans
+}
+
+// processGet trace.Get("<trace_id>"), the waterfall of the trace
+func processGet(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	res, err := Get(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+	return res
+}
@@ -0,0 +1,269 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Filter the filter of the traces, the times are RFC 3339, e.g. 2024-01-31T00:00:00Z
+type Filter struct {
+	ChatID      string `json:"chat_id,omitempty"`
+	AssistantID string `json:"assistant_id,omitempty"`
+	Sid         string `json:"sid,omitempty"`
+	Status      string `json:"status,omitempty"` // ok or error
+	Start       string `json:"start,omitempty"`  // 24 hours before the end by default
+	End         string `json:"end,omitempty"`    // Now by default
+	Limit       int    `json:"limit,omitempty"`  // 50 by default, 500 at most
+}
+
+// Waterfall the spans of the trace for the waterfall chart, the children follow the parents ordered by the start.
+// The offset is the milliseconds from the start of the trace.
+type Waterfall struct {
+	TraceID   string          `json:"trace_id"`
+	Status    string          `json:"status"`
+	StartedAt time.Time       `json:"started_at"`
+	Duration  int64           `json:"duration"`
+	Spans     []WaterfallSpan `json:"spans"`
+}
+
+// WaterfallSpan the span of the waterfall
+type WaterfallSpan struct {
+	*Span
+	Depth    int   `json:"depth"`
+	Offset   int64 `json:"offset"`
+	Duration int64 `json:"duration"`
+}
+
+var summaryColumns = []interface{}{"span_id", "trace_id", "parent_id", "kind", "name", "sid", "chat_id", "assistant_id", "status", "error", "start", "duration"}
+
+// Search the root spans of the traces matched, the latest first. The inputs and the outputs are not included.
+func Search(filter Filter) ([]*Span, error) {
+	start, end, err := filter.period(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !ready {
+		return []*Span{}, nil
+	}
+
+	qb := newQuery().
+		Select(summaryColumns...).
+		Where("started_at", ">=", start).
+		Where("started_at", "<", end).
+		Where("kind", KindTurn).
+		Where("parent_id", "")
+
+	if filter.ChatID != "" {
+		qb.Where("chat_id", filter.ChatID)
+	}
+	if filter.AssistantID != "" {
+		qb.Where("assistant_id", filter.AssistantID)
+	}
+	if filter.Sid != "" {
+		qb.Where("sid", filter.Sid)
+	}
+	if filter.Status != "" {
+		qb.Where("status", filter.Status)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	rows, err := qb.OrderBy("started_at", "desc").Limit(limit).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	spans := []*Span{}
+	for _, row := range rows {
+		spans = append(spans, spanOf(row))
+	}
+	return spans, nil
+}
+
+// Get the waterfall of the trace
+func Get(traceID string) (*Waterfall, error) {
+	if !ready {
+		return nil, fmt.Errorf("the trace is not enabled")
+	}
+
+	rows, err := newQuery().Where("trace_id", traceID).OrderBy("start", "asc").Limit(10000).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("the trace %s does not exist", traceID)
+	}
+
+	spans := []*Span{}
+	for _, row := range rows {
+		spans = append(spans, spanOf(row))
+	}
+	return waterfall(traceID, spans), nil
+}
+
+// waterfall the spans ordered by the parents, the spans of the missing parents are the roots
+func waterfall(traceID string, spans []*Span) *Waterfall {
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].StartedAt.Before(spans[j].StartedAt) })
+
+	ids := map[string]bool{}
+	for _, span := range spans {
+		ids[span.ID] = true
+	}
+
+	children := map[string][]*Span{}
+	roots := []*Span{}
+	for _, span := range spans {
+		if span.ParentID == "" || !ids[span.ParentID] {
+			roots = append(roots, span)
+			continue
+		}
+		children[span.ParentID] = append(children[span.ParentID], span)
+	}
+
+	res := &Waterfall{TraceID: traceID, Status: StatusOK, Spans: []WaterfallSpan{}}
+	if len(spans) == 0 {
+		return res
+	}
+
+	res.StartedAt = spans[0].StartedAt
+	var end time.Time
+	var walk func(span *Span, depth int)
+	walk = func(span *Span, depth int) {
+		res.Spans = append(res.Spans, WaterfallSpan{
+			Span:     span,
+			Depth:    depth,
+			Offset:   span.StartedAt.Sub(res.StartedAt).Milliseconds(),
+			Duration: span.Duration(),
+		})
+
+		if span.EndedAt.After(end) {
+			end = span.EndedAt
+		}
+		if span.Status == StatusError {
+			res.Status = StatusError
+		}
+
+		for _, child := range children[span.ID] {
+			walk(child, depth+1)
+		}
+	}
+
+	for _, root := range roots {
+		walk(root, 0)
+	}
+	res.Duration = end.Sub(res.StartedAt).Milliseconds()
+	return res
+}
+
+// period the period of the filter
+func (filter Filter) period(now time.Time) (time.Time, time.Time, error) {
+	end := now
+	if filter.End != "" {
+		t, err := time.Parse(time.RFC3339, filter.End)
+		if err != nil {
+			return end, end, fmt.Errorf("the end %s is invalid, e.g. 2024-01-31T00:00:00Z", filter.End)
+		}
+		end = t
+	}
+
+	start := end.Add(-24 * time.Hour)
+	if filter.Start != "" {
+		t, err := time.Parse(time.RFC3339, filter.Start)
+		if err != nil {
+			return start, end, fmt.Errorf("the start %s is invalid, e.g. 2024-01-01T00:00:00Z", filter.Start)
+		}
+		start = t
+	}
+
+	if !start.Before(end) {
+		return start, end, fmt.Errorf("the start %s is not before the end %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return start, end, nil
+}
+
+// spanOf the span of the row, the start and the duration are the times of the span
+func spanOf(row map[string]interface{}) *Span {
+	start := toInt64(row["start"])
+	span := &Span{
+		ID:          toString(row["span_id"]),
+		TraceID:     toString(row["trace_id"]),
+		ParentID:    toString(row["parent_id"]),
+		Kind:        toString(row["kind"]),
+		Name:        toString(row["name"]),
+		Sid:         toString(row["sid"]),
+		ChatID:      toString(row["chat_id"]),
+		AssistantID: toString(row["assistant_id"]),
+		Status:      toString(row["status"]),
+		Error:       toString(row["error"]),
+		Input:       decode(row["input"]),
+		Output:      decode(row["output"]),
+		StartedAt:   time.UnixMilli(start).UTC(),
+		EndedAt:     time.UnixMilli(start + toInt64(row["duration"])).UTC(),
+		ended:       true,
+	}
+
+	if attributes, ok := decode(row["attributes"]).(map[string]interface{}); ok {
+		span.Attributes = attributes
+	}
+	return span
+}
+
+// decode the JSON of the column, nil if the column is null
+func decode(value interface{}) interface{} {
+	raw := toString(value)
+	if raw == "" {
+		return nil
+	}
+
+	var res interface{}
+	if err := jsoniter.UnmarshalFromString(raw, &res); err != nil {
+		return raw
+	}
+	return res
+}
+
+func toInt64(v interface{}) int64 {
+	switch value := v.(type) {
+	case int:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	case uint64:
+		return int64(value)
+	case float64:
+		return int64(value)
+	case []byte:
+		n, _ := strconv.ParseInt(string(value), 10, 64)
+		return n
+	case string:
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return n
+	}
+	return 0
+}
+
+func toString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,102 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Replay the recorded spans of the trace replayed. The tools, the processes and the hooks take the outputs recorded
+// in the order they were called if mock is true, the chat completions are always requested again.
+type Replay struct {
+	TraceID string
+	Mock    bool
+
+	mu       sync.Mutex
+	recorded map[string][]*Span
+}
+
+// Recorded the recorded span taken by the replay
+type Recorded struct {
+	Output   interface{}
+	Err      error
+	Diverged bool // The input differs from the input recorded
+}
+
+type replayKey struct{}
+
+// NewReplay the replay of the waterfall
+func NewReplay(w *Waterfall, mock bool) *Replay {
+	replay := &Replay{TraceID: w.TraceID, Mock: mock, recorded: map[string][]*Span{}}
+	for _, span := range ordered(w) {
+		if span.Kind == KindTool || span.Kind == KindProcess || span.Kind == KindHook {
+			key := span.Kind + ":" + span.Name
+			replay.recorded[key] = append(replay.recorded[key], span)
+		}
+	}
+	return replay
+}
+
+// WithReplay the context of the replay
+func WithReplay(ctx context.Context, replay *Replay) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, replayKey{}, replay)
+}
+
+// ReplayOf the replay of the context, nil if the context is not replaying
+func ReplayOf(ctx context.Context) *Replay {
+	if ctx == nil {
+		return nil
+	}
+	replay, _ := ctx.Value(replayKey{}).(*Replay)
+	return replay
+}
+
+// Take take the next recorded span of the kind and the name, false if the replay does not mock or nothing is left
+func (replay *Replay) Take(kind string, name string, input interface{}) (*Recorded, bool) {
+	if replay == nil || !replay.Mock {
+		return nil, false
+	}
+
+	replay.mu.Lock()
+	defer replay.mu.Unlock()
+
+	key := kind + ":" + name
+	spans := replay.recorded[key]
+	if len(spans) == 0 {
+		return nil, false
+	}
+	span := spans[0]
+	replay.recorded[key] = spans[1:]
+
+	res := &Recorded{Output: span.Output, Diverged: !same(span.Input, input)}
+	if span.Status == StatusError {
+		res.Err = fmt.Errorf("%s", span.Error)
+	}
+	return res, true
+}
+
+// ordered the spans of the waterfall ordered by the start, the waterfall is ordered by the parents
+func ordered(w *Waterfall) []*Span {
+	spans := make([]*Span, 0, len(w.Spans))
+	for _, span := range w.Spans {
+		spans = append(spans, span.Span)
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].StartedAt.Before(spans[j].StartedAt) })
+	return spans
+}
+
+// same the input is the input recorded, the input recorded is decoded from the JSON stored
+func same(recorded interface{}, input interface{}) bool {
+	raw, err := jsoniter.MarshalToString(input)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(recorded, decode(raw))
+}
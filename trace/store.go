@@ -0,0 +1,124 @@
+package trace
+
+import (
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+	"github.com/yaoapp/yao/cluster"
+)
+
+const spanTable = "yao_trace_spans"
+
+// maxPayload the max size of the input and the output of the span in bytes, the larger payloads are truncated
+const maxPayload = 1 << 20
+
+// migrate create the table of the spans
+func migrate() error {
+	sch := capsule.Schema()
+	has, err := sch.HasTable(spanTable)
+	if err != nil || has {
+		return err
+	}
+
+	err = sch.CreateTable(spanTable, func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("span_id", 32).Unique()
+		table.String("trace_id", 64).Index()
+		table.String("parent_id", 32).Null()
+		table.String("kind", 20)
+		table.String("name", 255)
+		table.String("sid", 255).Null()
+		table.String("chat_id", 200).Null().Index()
+		table.String("assistant_id", 200).Null().Index()
+		table.String("status", 20)
+		table.Text("error").Null()
+		table.JSON("input").Null()
+		table.JSON("output").Null()
+		table.JSON("attributes").Null()
+		table.BigInteger("start").SetDefault(0)    // The unix milliseconds, the offsets of the waterfall
+		table.BigInteger("duration").SetDefault(0) // The milliseconds
+		table.TimestampTz("started_at").Index()
+		table.TimestampTz("ended_at").Null()
+	})
+	if err != nil {
+		return err
+	}
+	log.Trace("Create the trace table: %s", spanTable)
+	return nil
+}
+
+// save the span, the prompts and the completions of the LLM spans are logged by the logging mode
+func save(span *Span) error {
+	input, output, attributes := span.Input, span.Output, span.Attributes
+	if span.Kind == KindLLM {
+		input, output, attributes = logged(span)
+	}
+
+	return newQuery().Insert(map[string]interface{}{
+		"span_id":      span.ID,
+		"trace_id":     span.TraceID,
+		"parent_id":    span.ParentID,
+		"kind":         span.Kind,
+		"name":         span.Name,
+		"sid":          span.Sid,
+		"chat_id":      span.ChatID,
+		"assistant_id": span.AssistantID,
+		"status":       span.Status,
+		"error":        span.Error,
+		"input":        payload(input),
+		"output":       payload(output),
+		"attributes":   payload(attributes),
+		"start":        span.StartedAt.UnixMilli(),
+		"duration":     span.Duration(),
+		"started_at":   span.StartedAt,
+		"ended_at":     span.EndedAt,
+	})
+}
+
+// payload the JSON of the value, the value larger than maxPayload is replaced by the truncated text
+func payload(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	raw, err := jsoniter.MarshalToString(value)
+	if err != nil {
+		raw, _ = jsoniter.MarshalToString(map[string]interface{}{"__error": err.Error()})
+	}
+
+	if len(raw) > maxPayload {
+		raw, _ = jsoniter.MarshalToString(map[string]interface{}{"__truncated": raw[:maxPayload]})
+	}
+	return raw
+}
+
+// prune remove the spans older than the retention daily
+func prune() {
+	for {
+		lock.Lock()
+		days := retention
+		lock.Unlock()
+
+		// The spans are removed by the leader of the cluster only
+		if days > 0 && cluster.IsLeader() {
+			before := time.Now().AddDate(0, 0, -days)
+			n, err := newQuery().Where("started_at", "<", before).Delete()
+			if err != nil {
+				log.Error("[Trace] remove the expired spans: %s", err.Error())
+			} else if n > 0 {
+				log.Info("[Trace] remove %d expired spans", n)
+			}
+		}
+		time.Sleep(24 * time.Hour)
+	}
+}
+
+func newQuery() query.Query {
+	qb := capsule.Query()
+	qb.Table(spanTable)
+	return qb
+}
@@ -0,0 +1,172 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/config"
+)
+
+// The kinds of the spans
+const (
+	KindTurn    = "turn"    // The turn of the assistant, the root span of the trace
+	KindLLM     = "llm"     // The chat completions request, the input is the messages and the options
+	KindHook    = "hook"    // The hook of the assistant script
+	KindTool    = "tool"    // The tool called by the assistant
+	KindProcess = "process" // The process run by the workflow or the tools
+)
+
+// The status of the spans
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Span the span of the trace, the spans of the trace share the session, the chat and the assistant of the root span
+type Span struct {
+	ID          string                 `json:"id"`
+	TraceID     string                 `json:"trace_id"`
+	ParentID    string                 `json:"parent_id,omitempty"`
+	Kind        string                 `json:"kind"`
+	Name        string                 `json:"name"`
+	Sid         string                 `json:"sid,omitempty"`
+	ChatID      string                 `json:"chat_id,omitempty"`
+	AssistantID string                 `json:"assistant_id,omitempty"`
+	Status      string                 `json:"status"`
+	Error       string                 `json:"error,omitempty"`
+	Input       interface{}            `json:"input,omitempty"`
+	Output      interface{}            `json:"output,omitempty"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+	StartedAt   time.Time              `json:"started_at"`
+	EndedAt     time.Time              `json:"ended_at"`
+
+	mu    sync.Mutex
+	ended bool
+}
+
+type spanKey struct{}
+
+// ready the table of the spans is created
+var ready = false
+var retention = 0
+var started = false
+var lock sync.Mutex
+
+// Load create the table of the spans, the spans older than the retention are removed daily.
+func Load(cfg config.Config) error {
+	if capsule.Global == nil || cfg.Trace.Disabled {
+		return nil
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	retention = cfg.Trace.Retention
+	if err := migrate(); err != nil {
+		return err
+	}
+
+	ready = true
+	if !started {
+		started = true
+		go prune()
+	}
+	return nil
+}
+
+// Start start the span of the context, the span is the root span of a new trace if the context has no span.
+// Returns the context of the span, the spans started with it are the children of the span.
+func Start(ctx context.Context, kind string, name string, input interface{}) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	span := &Span{
+		ID:        newID(8),
+		Kind:      kind,
+		Name:      name,
+		Status:    StatusOK,
+		Input:     input,
+		StartedAt: time.Now(),
+	}
+
+	if parent := FromContext(ctx); parent != nil {
+		span.TraceID, span.ParentID = parent.TraceID, parent.ID
+		span.Sid, span.ChatID, span.AssistantID = parent.Sid, parent.ChatID, parent.AssistantID
+	} else {
+		span.TraceID = newID(16)
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// FromContext the span of the context, nil if the context has no span
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Chat set the session, the chat and the assistant of the span
+func (span *Span) Chat(sid string, chatID string, assistantID string) *Span {
+	span.Sid, span.ChatID, span.AssistantID = sid, chatID, assistantID
+	return span
+}
+
+// Set set the attribute of the span
+func (span *Span) Set(key string, value interface{}) *Span {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+	if span.Attributes == nil {
+		span.Attributes = map[string]interface{}{}
+	}
+	span.Attributes[key] = value
+	return span
+}
+
+// End end the span with the output and the error, the span is stored in the background.
+// The span is ended once, the later calls are ignored.
+func (span *Span) End(output interface{}, err error) {
+	if span == nil {
+		return
+	}
+
+	span.mu.Lock()
+	if span.ended {
+		span.mu.Unlock()
+		return
+	}
+	span.ended = true
+	span.EndedAt = time.Now()
+	span.Output = output
+	if err != nil {
+		span.Status, span.Error = StatusError, err.Error()
+	}
+	span.mu.Unlock()
+
+	if !ready {
+		return
+	}
+
+	go func() {
+		if err := save(span); err != nil {
+			log.Error("[Trace] save the span %s %s of %s: %s", span.Kind, span.Name, span.TraceID, err.Error())
+		}
+	}()
+}
+
+// Duration the duration of the span in milliseconds
+func (span *Span) Duration() int64 {
+	return span.EndedAt.Sub(span.StartedAt).Milliseconds()
+}
+
+func newID(size int) string {
+	buf := make([]byte, size)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,164 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpan(t *testing.T) {
+	ctx, turn := Start(context.Background(), KindTurn, "writer", map[string]interface{}{"input": "hello"})
+	turn.Chat("u1", "chat-1", "writer")
+	assert.Len(t, turn.TraceID, 32)
+	assert.Empty(t, turn.ParentID)
+	assert.Equal(t, turn, FromContext(ctx))
+
+	_, tool := Start(ctx, KindTool, "scripts.search", []interface{}{"yao"})
+	assert.Equal(t, turn.TraceID, tool.TraceID)
+	assert.Equal(t, turn.ID, tool.ParentID)
+	assert.Equal(t, "chat-1", tool.ChatID)
+
+	tool.End("done", fmt.Errorf("timeout"))
+	tool.End("again", nil)
+	assert.Equal(t, StatusError, tool.Status)
+	assert.Equal(t, "timeout", tool.Error)
+	assert.Equal(t, "done", tool.Output)
+
+	var span *Span
+	span.End(nil, nil)
+	assert.Nil(t, FromContext(context.Background()))
+}
+
+func TestWaterfall(t *testing.T) {
+	at := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	span := func(id, parent string, start, duration int, status string) *Span {
+		started := at.Add(time.Duration(start) * time.Millisecond)
+		return &Span{ID: id, ParentID: parent, Status: status, StartedAt: started, EndedAt: started.Add(time.Duration(duration) * time.Millisecond)}
+	}
+
+	res := waterfall("t1", []*Span{
+		span("tool", "llm", 300, 100, StatusOK),
+		span("turn", "", 0, 1000, StatusOK),
+		span("done", "turn", 900, 50, StatusError),
+		span("llm", "turn", 100, 700, StatusOK),
+	})
+
+	ids := []string{}
+	for _, span := range res.Spans {
+		ids = append(ids, fmt.Sprintf("%s:%d:%d", span.ID, span.Depth, span.Offset))
+	}
+	assert.Equal(t, []string{"turn:0:0", "llm:1:100", "tool:2:300", "done:1:900"}, ids)
+	assert.Equal(t, int64(1000), res.Duration)
+	assert.Equal(t, StatusError, res.Status)
+	assert.Empty(t, waterfall("t2", nil).Spans)
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	start, end, err := Filter{}.period(now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(-24*time.Hour), start)
+	assert.Equal(t, now, end)
+
+	_, _, err = Filter{Start: "2024-01-31"}.period(now)
+	assert.Error(t, err)
+	_, _, err = Filter{Start: "2024-02-01T00:00:00Z"}.period(now)
+	assert.Error(t, err)
+}
+
+func TestPayload(t *testing.T) {
+	assert.Nil(t, payload(nil))
+	assert.Equal(t, `{"input":"hello"}`, payload(map[string]interface{}{"input": "hello"}))
+	assert.Contains(t, payload(strings.Repeat("x", maxPayload+1)), "__truncated")
+
+	span := spanOf(map[string]interface{}{
+		"span_id": "s1", "trace_id": "t1", "kind": KindTool, "status": StatusOK,
+		"input": []byte(`["yao"]`), "attributes": `{"hops":1}`, "start": int64(1706702400000), "duration": "250",
+	})
+	assert.Equal(t, []interface{}{"yao"}, span.Input)
+	assert.Equal(t, map[string]interface{}{"hops": float64(1)}, span.Attributes)
+	assert.Equal(t, int64(250), span.Duration())
+}
+
+func TestReplay(t *testing.T) {
+	at := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	span := func(id, parent, kind, name string, start int, input, output interface{}, err string) *Span {
+		started := at.Add(time.Duration(start) * time.Millisecond)
+		status := StatusOK
+		if err != "" {
+			status = StatusError
+		}
+		return &Span{ID: id, ParentID: parent, Kind: kind, Name: name, Input: input, Output: output, Status: status, Error: err, StartedAt: started, EndedAt: started}
+	}
+
+	w := waterfall("t1", []*Span{
+		span("turn", "", KindTurn, "writer", 0, nil, nil, ""),
+		span("llm", "turn", KindLLM, "gpt-4o", 10, nil, nil, ""),
+		span("tool-2", "llm", KindTool, "scripts.search", 300, []interface{}{"go"}, nil, "timeout"),
+		span("tool-1", "turn", KindTool, "scripts.search", 200, []interface{}{"yao"}, map[string]interface{}{"total": float64(2)}, ""),
+	})
+
+	ctx := WithReplay(context.Background(), NewReplay(w, true))
+	_, child := Start(ctx, KindTool, "scripts.search", nil)
+	assert.NotNil(t, child)
+
+	replay := ReplayOf(ctx)
+	res, ok := replay.Take(KindTool, "scripts.search", []interface{}{"yao"})
+	assert.True(t, ok)
+	assert.False(t, res.Diverged)
+	assert.Equal(t, map[string]interface{}{"total": float64(2)}, res.Output)
+
+	res, ok = replay.Take(KindTool, "scripts.search", []interface{}{"yaoapp"})
+	assert.True(t, ok)
+	assert.True(t, res.Diverged)
+	assert.EqualError(t, res.Err, "timeout")
+
+	_, ok = replay.Take(KindTool, "scripts.search", nil)
+	assert.False(t, ok)
+	_, ok = NewReplay(w, false).Take(KindTool, "scripts.search", nil)
+	assert.False(t, ok)
+	_, ok = ReplayOf(context.Background()).Take(KindTool, "scripts.search", nil)
+	assert.False(t, ok)
+}
+
+func TestLogging(t *testing.T) {
+	defer SetLogging(Logging{})
+
+	span := &Span{
+		Kind:       KindLLM,
+		Input:      map[string]interface{}{"messages": []interface{}{map[string]interface{}{"role": "user", "name": "Max", "text": "My card is 4111111111111111"}}},
+		Output:     []interface{}{map[string]interface{}{"type": "text", "text": "Card 4111111111111111 is charged"}},
+		Attributes: map[string]interface{}{"hops": 0},
+	}
+
+	input, output, _ := logged(span)
+	assert.Equal(t, span.Input, input)
+	assert.Equal(t, span.Output, output)
+
+	assert.NoError(t, SetLogging(Logging{Mode: LoggingRedacted, Rules: []Redaction{
+		{Pattern: `\b\d{16}\b`, Replace: "[CARD]"},
+		{Path: "input.messages.*.name"},
+	}}))
+	input, output, _ = logged(span)
+	assert.Equal(t, map[string]interface{}{"messages": []interface{}{map[string]interface{}{"role": "user", "name": "[REDACTED]", "text": "My card is [CARD]"}}}, input)
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "text", "text": "Card [CARD] is charged"}}, output)
+	assert.Equal(t, "Max", span.Input.(map[string]interface{})["messages"].([]interface{})[0].(map[string]interface{})["name"])
+
+	assert.NoError(t, SetLogging(Logging{Mode: LoggingMetadata}))
+	input, output, attributes := logged(span)
+	assert.Nil(t, input)
+	assert.Nil(t, output)
+	assert.Equal(t, 1, attributes["messages"])
+	assert.Equal(t, 0, attributes["hops"])
+	assert.Greater(t, attributes["input_size"], 0)
+	assert.NotContains(t, span.Attributes, "messages")
+
+	assert.Error(t, SetLogging(Logging{Mode: "none"}))
+	assert.Error(t, SetLogging(Logging{Mode: LoggingRedacted, Rules: []Redaction{{Pattern: "("}}}))
+	assert.Error(t, SetLogging(Logging{Mode: LoggingRedacted, Rules: []Redaction{{Pattern: "a", Path: "input.a"}}}))
+	assert.Error(t, SetLogging(Logging{Mode: LoggingRedacted, Rules: []Redaction{{Path: "messages.*.name"}}}))
+}
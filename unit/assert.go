@@ -0,0 +1,152 @@
+package unit
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+// skipPrefix the prefix of the message thrown by unit.Skip
+const skipPrefix = "unit.skip: "
+
+// processEqual unit.Equal expected, actual, [message]
+func processEqual(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	expected, actual := process.Args[0], process.Args[1]
+	if !Equal(expected, actual) {
+		fail(process, 2, "expected %s, got %s", format(expected), format(actual))
+	}
+	return nil
+}
+
+// processNotEqual unit.NotEqual expected, actual, [message]
+func processNotEqual(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	expected, actual := process.Args[0], process.Args[1]
+	if Equal(expected, actual) {
+		fail(process, 2, "expected not %s", format(expected))
+	}
+	return nil
+}
+
+// processTrue unit.True value, [message]
+func processTrue(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	if v, ok := process.Args[0].(bool); !ok || !v {
+		fail(process, 1, "expected true, got %s", format(process.Args[0]))
+	}
+	return nil
+}
+
+// processFalse unit.False value, [message]
+func processFalse(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	if v, ok := process.Args[0].(bool); !ok || v {
+		fail(process, 1, "expected false, got %s", format(process.Args[0]))
+	}
+	return nil
+}
+
+// processNil unit.Nil value, [message]
+func processNil(process *process.Process) interface{} {
+	if len(process.Args) > 0 && process.Args[0] != nil {
+		fail(process, 1, "expected null, got %s", format(process.Args[0]))
+	}
+	return nil
+}
+
+// processNotNil unit.NotNil value, [message]
+func processNotNil(process *process.Process) interface{} {
+	if len(process.Args) == 0 || process.Args[0] == nil {
+		fail(process, 1, "expected not null")
+	}
+	return nil
+}
+
+// processContains unit.Contains container, item, [message]
+func processContains(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	if !Contains(process.Args[0], process.Args[1]) {
+		fail(process, 2, "%s does not contain %s", format(process.Args[0]), format(process.Args[1]))
+	}
+	return nil
+}
+
+// processFail unit.Fail [message]
+func processFail(process *process.Process) interface{} {
+	exception.New(process.ArgsString(0, "failed"), 400).Throw()
+	return nil
+}
+
+// processSkip unit.Skip [reason]
+func processSkip(process *process.Process) interface{} {
+	exception.New(skipPrefix+process.ArgsString(0, "skipped"), 400).Throw()
+	return nil
+}
+
+// Equal compare the values as JSON, the numbers of the scripts are float64
+func Equal(expected, actual interface{}) bool {
+	return reflect.DeepEqual(normalize(expected), normalize(actual))
+}
+
+// Contains the string contains the substring, the array contains the item or the object has the key
+func Contains(container, item interface{}) bool {
+	switch v := normalize(container).(type) {
+	case string:
+		s, ok := item.(string)
+		return ok && strings.Contains(v, s)
+
+	case []interface{}:
+		for _, value := range v {
+			if Equal(value, item) {
+				return true
+			}
+		}
+
+	case map[string]interface{}:
+		key, ok := item.(string)
+		if !ok {
+			return false
+		}
+		_, has := v[key]
+		return has
+	}
+	return false
+}
+
+func normalize(v interface{}) interface{} {
+	bytes, err := jsoniter.Marshal(v)
+	if err != nil {
+		return v
+	}
+
+	var res interface{}
+	err = jsoniter.Unmarshal(bytes, &res)
+	if err != nil {
+		return v
+	}
+	return res
+}
+
+func format(v interface{}) string {
+	bytes, err := jsoniter.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(bytes)
+}
+
+// fail throw the assertion error, the message argument is prepended if given
+func fail(process *process.Process, index int, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if len(process.Args) > index {
+		if custom, ok := process.Args[index].(string); ok && custom != "" {
+			message = fmt.Sprintf("%s: %s", custom, message)
+		}
+	}
+	exception.New(message, 400).Throw()
+}
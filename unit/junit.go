@@ -0,0 +1,107 @@
+package unit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Error    *junitError `xml:"error,omitempty"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitError struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// JUnit write the report in the JUnit XML format
+func (report *Report) JUnit(w io.Writer) error {
+	suites := junitSuites{
+		Tests:    report.Passed + report.Failed + report.Skipped,
+		Failures: report.Failed,
+		Skipped:  report.Skipped,
+		Time:     seconds(report.Time),
+		Suites:   []junitSuite{},
+	}
+
+	for _, suite := range report.Suites {
+		classname := strings.TrimSuffix(strings.TrimSuffix(suite.Name, ".ts"), ".js")
+		s := junitSuite{Name: suite.Name, Tests: len(suite.Cases), Time: seconds(suite.Time), Cases: []junitCase{}}
+		if suite.Error != "" {
+			s.Errors = 1
+			s.Error = &junitError{Message: suite.Error}
+		}
+
+		for _, c := range suite.Cases {
+			jc := junitCase{Name: c.Name, Classname: classname, Time: seconds(c.Time)}
+			switch c.Status {
+			case StatusFailed:
+				s.Failures++
+				jc.Failure = &junitFailure{Message: firstLine(c.Message), Body: c.Message}
+			case StatusSkipped:
+				s.Skipped++
+				jc.Skipped = &junitSkipped{Message: c.Message}
+			}
+			s.Cases = append(s.Cases, jc)
+		}
+		suites.Suites = append(suites.Suites, s)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(suites)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	return line
+}
@@ -0,0 +1,161 @@
+package unit
+
+import (
+	"path"
+	"strings"
+
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+// httpProcesses the http processes and the index of the url argument
+var httpProcesses = map[string]int{
+	"http.get":    0,
+	"http.post":   0,
+	"http.put":    0,
+	"http.patch":  0,
+	"http.delete": 0,
+	"http.head":   0,
+	"http.send":   1,
+}
+
+// processMock unit.Mock name, result, the process returns the result until the test ends
+func processMock(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	name := strings.ToLower(process.ArgsString(0))
+	if strings.HasPrefix(name, "unit.") {
+		exception.New("the unit processes can not be mocked", 400).Throw()
+	}
+
+	Mock(name, returns(process.Args[1]))
+	return nil
+}
+
+// processMockHTTP unit.MockHTTP method, url, response, the url could be a pattern, e.g. https://api.example.com/users/*
+func processMockHTTP(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	method := strings.ToLower(process.ArgsString(0))
+	pattern := process.ArgsString(1)
+
+	mockLock.Lock()
+	httpMocks = append(httpMocks, httpMock{method: method, pattern: pattern, response: process.Args[2]})
+	mockLock.Unlock()
+
+	for name := range httpProcesses {
+		mockHTTP(name)
+	}
+	return nil
+}
+
+// processCalls unit.Calls name, the arguments of the calls of the mocked process
+func processCalls(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	name := strings.ToLower(process.ArgsString(0))
+
+	mockLock.Lock()
+	defer mockLock.Unlock()
+	m, has := mocks[name]
+	if !has {
+		exception.New("%s is not mocked", 400, name).Throw()
+	}
+
+	calls := []interface{}{}
+	for _, args := range m.calls {
+		calls = append(calls, args)
+	}
+	return calls
+}
+
+// processRestore unit.Restore, restore the mocked processes
+func processRestore(process *process.Process) interface{} {
+	Restore()
+	return nil
+}
+
+// Mock replace the handler of the process, the calls are recorded
+func Mock(name string, handler process.Handler) {
+	name = strings.ToLower(name)
+
+	mockLock.Lock()
+	defer mockLock.Unlock()
+
+	m, has := mocks[name]
+	if !has {
+		m = &mock{original: process.Handlers[name]}
+		mocks[name] = m
+	}
+
+	process.Handlers[name] = func(p *process.Process) interface{} {
+		mockLock.Lock()
+		m.calls = append(m.calls, p.Args)
+		mockLock.Unlock()
+		return handler(p)
+	}
+}
+
+// Restore the original handlers of the mocked processes
+func Restore() {
+	mockLock.Lock()
+	defer mockLock.Unlock()
+
+	for name, m := range mocks {
+		if m.original == nil {
+			delete(process.Handlers, name)
+		} else {
+			process.Handlers[name] = m.original
+		}
+		delete(mocks, name)
+	}
+	httpMocks = []httpMock{}
+}
+
+// returns the handler returns the result
+func returns(result interface{}) process.Handler {
+	return func(p *process.Process) interface{} { return result }
+}
+
+// mockHTTP the http process returns the mocked response if the url matches, or calls the original handler
+func mockHTTP(name string) {
+	mockLock.Lock()
+	_, has := mocks[name]
+	original := process.Handlers[name]
+	mockLock.Unlock()
+	if has {
+		return
+	}
+
+	index := httpProcesses[name]
+	Mock(name, func(p *process.Process) interface{} {
+		url := p.ArgsString(index)
+		method := strings.TrimPrefix(name, "http.")
+		if name == "http.send" {
+			method = strings.ToLower(p.ArgsString(0))
+		}
+
+		if response, ok := matchHTTP(method, url); ok {
+			return response
+		}
+
+		if original == nil {
+			exception.New("%s %s is not mocked", 400, name, url).Throw()
+		}
+		return original(p)
+	})
+}
+
+// matchHTTP the response of the last matched mock
+func matchHTTP(method string, url string) (interface{}, bool) {
+	mockLock.Lock()
+	defer mockLock.Unlock()
+
+	for i := len(httpMocks) - 1; i >= 0; i-- {
+		m := httpMocks[i]
+		if m.method != "*" && m.method != method {
+			continue
+		}
+		if matched, _ := path.Match(m.pattern, url); matched || m.pattern == url {
+			return m.response, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,21 @@
+package unit
+
+import "github.com/yaoapp/gou/process"
+
+func init() {
+	process.RegisterGroup("unit", map[string]process.Handler{
+		"equal":    processEqual,
+		"notequal": processNotEqual,
+		"true":     processTrue,
+		"false":    processFalse,
+		"nil":      processNil,
+		"notnil":   processNotNil,
+		"contains": processContains,
+		"fail":     processFail,
+		"skip":     processSkip,
+		"mock":     processMock,
+		"mockhttp": processMockHTTP,
+		"calls":    processCalls,
+		"restore":  processRestore,
+	})
+}
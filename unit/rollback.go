@@ -0,0 +1,93 @@
+package unit
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/xun/capsule"
+)
+
+var reTableName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// snapshot the copies of the model tables, the tables are restored after each test.
+// The models do not share a connection so the tests can not run in a transaction.
+type snapshot struct {
+	tables map[string]string // table => the copy
+}
+
+// newSnapshot copy the tables of the loaded models
+func newSnapshot() (*snapshot, error) {
+	snap := &snapshot{tables: map[string]string{}}
+	if capsule.Global == nil {
+		return snap, nil
+	}
+
+	tables := map[string]bool{}
+	for _, mod := range model.Models {
+		name := mod.MetaData.Table.Name
+		if name != "" && reTableName.MatchString(name) {
+			tables[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	schema := capsule.Schema()
+	db := capsule.Query().DB(true)
+	for _, name := range names {
+		has, err := schema.HasTable(name)
+		if err != nil {
+			snap.drop()
+			return nil, err
+		}
+		if !has {
+			continue
+		}
+
+		backup := fmt.Sprintf("__yao_unit_%s", name)
+		_, err = db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", backup))
+		if err == nil {
+			_, err = db.Exec(fmt.Sprintf("CREATE TABLE %s AS SELECT * FROM %s", backup, name))
+		}
+		if err != nil {
+			snap.drop()
+			return nil, fmt.Errorf("snapshot %s: %s", name, err.Error())
+		}
+		snap.tables[name] = backup
+	}
+	return snap, nil
+}
+
+// restore the rows of the tables
+func (snap *snapshot) restore() error {
+	db := capsule.Query().DB(true)
+	for name, backup := range snap.tables {
+		_, err := db.Exec(fmt.Sprintf("DELETE FROM %s", name))
+		if err == nil {
+			_, err = db.Exec(fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", name, backup))
+		}
+		if err != nil {
+			return fmt.Errorf("restore %s: %s", name, err.Error())
+		}
+	}
+	return nil
+}
+
+// drop the copies of the tables
+func (snap *snapshot) drop() {
+	if len(snap.tables) == 0 {
+		return
+	}
+
+	db := capsule.Query().DB(true)
+	for name, backup := range snap.tables {
+		db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", backup))
+		delete(snap.tables, name)
+	}
+}
@@ -0,0 +1,66 @@
+package unit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yaoapp/gou/process"
+)
+
+// The status of the test cases
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Option the option of the test runner
+type Option struct {
+	Patterns []string            // The test files contain one of the patterns, all test files if empty
+	Run      string              // The regular expression of the test names
+	Rollback bool                // Restore the model tables after each test
+	OnCase   func(*Suite, *Case) // Called after each test
+}
+
+// Report the result of the test run
+type Report struct {
+	Suites  []*Suite      `json:"suites"`
+	Passed  int           `json:"passed"`
+	Failed  int           `json:"failed"`
+	Skipped int           `json:"skipped"`
+	Time    time.Duration `json:"time"`
+}
+
+// Suite the tests of a test file
+type Suite struct {
+	Name  string        `json:"name"`
+	File  string        `json:"file"`
+	Cases []*Case       `json:"cases"`
+	Error string        `json:"error,omitempty"` // The file could not be loaded
+	Time  time.Duration `json:"time"`
+}
+
+// Case the result of a test function
+type Case struct {
+	Name    string        `json:"name"`
+	Status  string        `json:"status"`
+	Message string        `json:"message,omitempty"`
+	Time    time.Duration `json:"time"`
+}
+
+// mock the mocked process, the original handler is restored after the test
+type mock struct {
+	original process.Handler
+	calls    [][]interface{}
+}
+
+// httpMock the mocked response of the http processes
+type httpMock struct {
+	method   string
+	pattern  string
+	response interface{}
+}
+
+var mocks = map[string]*mock{}
+var httpMocks = []httpMock{}
+var mockLock sync.Mutex
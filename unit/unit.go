@@ -0,0 +1,229 @@
+package unit
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	v8 "github.com/yaoapp/gou/runtime/v8"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+// reTest the test functions, e.g. function TestUserCreate() {}
+var reTest = regexp.MustCompile(`(?m)^\s*(?:export\s+)?(?:async\s+)?function\s+(Test[A-Za-z0-9_]*)\s*\(`)
+
+// skipDirs the directories are not searched for the test files
+var skipDirs = map[string]bool{"node_modules": true, "public": true, "data": true, "db": true}
+
+// Run the tests of the application
+func Run(cfg config.Config, option Option) (*Report, error) {
+	var run *regexp.Regexp
+	if option.Run != "" {
+		var err error
+		run, err = regexp.Compile(option.Run)
+		if err != nil {
+			return nil, fmt.Errorf("the test name pattern %s is invalid: %s", option.Run, err.Error())
+		}
+	}
+
+	files, err := Discover(cfg.Root, option.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	var snap *snapshot
+	if option.Rollback {
+		snap, err = newSnapshot()
+		if err != nil {
+			return nil, err
+		}
+		defer snap.drop()
+	}
+
+	start := time.Now()
+	report := &Report{Suites: []*Suite{}}
+	for _, file := range files {
+		suite := runSuite(cfg.Root, file, run, snap, option.OnCase)
+		for _, c := range suite.Cases {
+			switch c.Status {
+			case StatusPassed:
+				report.Passed++
+			case StatusFailed:
+				report.Failed++
+			case StatusSkipped:
+				report.Skipped++
+			}
+		}
+		if suite.Error != "" {
+			report.Failed++
+		}
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = time.Since(start)
+	return report, nil
+}
+
+// Discover the *_test.ts and *_test.js files under the root, the files are filtered by the patterns
+func Discover(root string, patterns []string) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := d.Name()
+		if d.IsDir() {
+			if file != root && (strings.HasPrefix(name, ".") || skipDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !IsTestFile(name) {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, file)
+		if len(patterns) == 0 {
+			files = append(files, file)
+			return nil
+		}
+
+		for _, pattern := range patterns {
+			if strings.Contains(filepath.ToSlash(rel), pattern) {
+				files = append(files, file)
+				break
+			}
+		}
+		return nil
+	})
+
+	sort.Strings(files)
+	return files, err
+}
+
+// IsTestFile the name of the test file, e.g. user_test.ts
+func IsTestFile(name string) bool {
+	return strings.HasSuffix(name, "_test.ts") || strings.HasSuffix(name, "_test.js")
+}
+
+// Tests the names of the test functions in the source
+func Tests(source []byte) []string {
+	names := []string{}
+	for _, match := range reTest.FindAllSubmatch(source, -1) {
+		names = append(names, string(match[1]))
+	}
+	return names
+}
+
+func runSuite(root string, file string, run *regexp.Regexp, snap *snapshot, onCase func(*Suite, *Case)) *Suite {
+	start := time.Now()
+	rel, _ := filepath.Rel(root, file)
+	suite := &Suite{Name: filepath.ToSlash(rel), File: file, Cases: []*Case{}}
+	defer func() { suite.Time = time.Since(start) }()
+
+	source, err := os.ReadFile(file)
+	if err != nil {
+		suite.Error = err.Error()
+		return suite
+	}
+
+	script, err := v8.Load(file, "__yao_unit."+share.ID(root, file))
+	if err != nil {
+		suite.Error = err.Error()
+		return suite
+	}
+
+	err = callScript(script, "BeforeAll")
+	if err != nil {
+		suite.Error = fmt.Sprintf("BeforeAll: %s", err.Error())
+		return suite
+	}
+
+	for _, name := range Tests(source) {
+		if run != nil && !run.MatchString(name) {
+			continue
+		}
+
+		c := runCase(script, name, snap)
+		suite.Cases = append(suite.Cases, c)
+		if onCase != nil {
+			onCase(suite, c)
+		}
+	}
+
+	err = callScript(script, "AfterAll")
+	if err != nil {
+		suite.Error = fmt.Sprintf("AfterAll: %s", err.Error())
+	}
+	return suite
+}
+
+func runCase(script *v8.Script, name string, snap *snapshot) *Case {
+	start := time.Now()
+	c := &Case{Name: name, Status: StatusPassed}
+
+	// The mocks and the rows are restored whatever the result is
+	defer func() {
+		Restore()
+		if snap != nil {
+			if err := snap.restore(); err != nil && c.Status != StatusFailed {
+				c.Status = StatusFailed
+				c.Message = err.Error()
+			}
+		}
+		c.Time = time.Since(start)
+	}()
+
+	// The hooks and the test share the context
+	ctx, err := script.NewContext("", nil)
+	if err == nil {
+		defer ctx.Close()
+		err = call(ctx, "BeforeEach")
+		if err == nil {
+			err = call(ctx, name)
+			if afterErr := call(ctx, "AfterEach"); err == nil {
+				err = afterErr
+			}
+		}
+	}
+
+	if err != nil {
+		c.Status = StatusFailed
+		c.Message = err.Error()
+		if i := strings.Index(c.Message, skipPrefix); i >= 0 {
+			c.Status = StatusSkipped
+			c.Message = c.Message[i+len(skipPrefix):]
+		}
+	}
+	return c
+}
+
+// callScript call the function of the script in a new context
+func callScript(script *v8.Script, method string) error {
+	ctx, err := script.NewContext("", nil)
+	if err != nil {
+		return err
+	}
+	defer ctx.Close()
+	return call(ctx, method)
+}
+
+// call the function, the optional hooks are ignored if not defined
+func call(ctx *v8.Context, method string) error {
+	if !ctx.Global().Has(method) {
+		if strings.HasPrefix(method, "Test") {
+			return fmt.Errorf("%s is not defined", method)
+		}
+		return nil
+	}
+
+	_, err := ctx.Call(method)
+	return err
+}
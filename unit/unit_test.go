@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTests(t *testing.T) {
+	source := []byte(`
+function TestCreate() {}
+export function TestUpdate () {}
+async function TestAsync() {}
+function helper() {}
+// function TestComment() {}
+`)
+	assert.Equal(t, []string{"TestCreate", "TestUpdate", "TestAsync"}, Tests(source))
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"scripts/user_test.ts":             "",
+		"scripts/user.ts":                  "",
+		"services/pet_test.js":             "",
+		"node_modules/lib/lib_test.js":     "",
+		".tmp/cache_test.ts":               "",
+		"assistants/chat/src/index.ts":     "",
+		"assistants/chat/src/hook_test.ts": "",
+	}
+	for name := range files {
+		file := filepath.Join(root, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		assert.NoError(t, os.WriteFile(file, []byte{}, 0644))
+	}
+
+	found, err := Discover(root, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(root, "assistants/chat/src/hook_test.ts"),
+		filepath.Join(root, "scripts/user_test.ts"),
+		filepath.Join(root, "services/pet_test.js"),
+	}, found)
+
+	found, err = Discover(root, []string{"scripts/"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "scripts/user_test.ts")}, found)
+}
+
+func TestEqual(t *testing.T) {
+	assert.True(t, Equal(1, float64(1)))
+	assert.True(t, Equal(map[string]interface{}{"id": 1}, map[string]interface{}{"id": 1.0}))
+	assert.False(t, Equal("1", 1))
+	assert.True(t, Contains("hello world", "world"))
+	assert.True(t, Contains([]interface{}{1, "a"}, 1.0))
+	assert.True(t, Contains(map[string]interface{}{"id": 1}, "id"))
+	assert.False(t, Contains([]interface{}{1}, 2))
+}
+
+func TestMatchHTTP(t *testing.T) {
+	defer Restore()
+	httpMocks = []httpMock{
+		{method: "get", pattern: "https://api.example.com/users/*", response: "users"},
+		{method: "*", pattern: "https://api.example.com/users/1", response: "user"},
+	}
+
+	res, ok := matchHTTP("get", "https://api.example.com/users/1")
+	assert.True(t, ok)
+	assert.Equal(t, "user", res)
+
+	res, ok = matchHTTP("get", "https://api.example.com/users/2")
+	assert.True(t, ok)
+	assert.Equal(t, "users", res)
+
+	_, ok = matchHTTP("post", "https://api.example.com/users/2")
+	assert.False(t, ok)
+}
+
+func TestJUnit(t *testing.T) {
+	report := &Report{
+		Passed: 1, Failed: 1, Skipped: 1, Time: time.Second,
+		Suites: []*Suite{{
+			Name: "scripts/user_test.ts",
+			Time: time.Second,
+			Cases: []*Case{
+				{Name: "TestCreate", Status: StatusPassed},
+				{Name: "TestUpdate", Status: StatusFailed, Message: "expected 1, got 2\n at TestUpdate"},
+				{Name: "TestDelete", Status: StatusSkipped, Message: "todo"},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, report.JUnit(&buf))
+	xml := buf.String()
+	assert.Contains(t, xml, `<testsuites tests="3" failures="1" skipped="1" time="1.000">`)
+	assert.Contains(t, xml, `<testcase name="TestCreate" classname="scripts/user_test" time="0.000"></testcase>`)
+	assert.Contains(t, xml, `<failure message="expected 1, got 2">expected 1, got 2&#xA; at TestUpdate</failure>`)
+	assert.Contains(t, xml, `<skipped message="todo"></skipped>`)
+}
@@ -0,0 +1,225 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/kun/log"
+)
+
+// fetchRequest the request of yao.fetch
+type fetchRequest struct {
+	Method  string            `json:"method,omitempty"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// fetchResponse the response of yao.fetch
+type fetchResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// host export the host API as the module "yao", the functions check the capabilities of the module on each call
+func (module *Module) host(ctx context.Context) error {
+	_, err := module.runtime.NewHostModuleBuilder("yao").
+		NewFunctionBuilder().WithFunc(module.log).Export("log").
+		NewFunctionBuilder().WithFunc(module.kvGet).Export("kv_get").
+		NewFunctionBuilder().WithFunc(module.kvSet).Export("kv_set").
+		NewFunctionBuilder().WithFunc(module.fetch).Export("fetch").
+		Instantiate(ctx)
+	return err
+}
+
+// log(level i32, ptr i32, len i32), the levels: 0 trace, 1 debug, 2 info, 3 warn, 4 error
+func (module *Module) log(ctx context.Context, instance api.Module, level uint32, ptr uint32, size uint32) {
+	if !module.caps[CapLog] {
+		return
+	}
+
+	message, ok := instance.Memory().Read(ptr, size)
+	if !ok {
+		return
+	}
+
+	format := "[WASM] %s %s"
+	switch level {
+	case 0:
+		log.Trace(format, module.ID, string(message))
+	case 1:
+		log.Debug(format, module.ID, string(message))
+	case 3:
+		log.Warn(format, module.ID, string(message))
+	case 4:
+		log.Error(format, module.ID, string(message))
+	default:
+		log.Info(format, module.ID, string(message))
+	}
+}
+
+// kv_get(key_ptr i32, key_len i32) i64, returns the value as ptr<<32 | len, 0 if the key does not exist
+func (module *Module) kvGet(ctx context.Context, instance api.Module, ptr uint32, size uint32) uint64 {
+	kv, key, ok := module.kv(instance, ptr, size)
+	if !ok {
+		return 0
+	}
+
+	value, has := kv.Get(key)
+	if !has || value == nil {
+		return 0
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		data = []byte(fmt.Sprintf("%v", v))
+	}
+
+	ptr, size, err := write(ctx, instance, data)
+	if err != nil {
+		log.Error("[WASM] %s kv_get: %s", module.ID, err.Error())
+		return 0
+	}
+	return pack(ptr, size)
+}
+
+// kv_set(key_ptr i32, key_len i32, value_ptr i32, value_len i32, ttl i32) i32, the ttl is in seconds, returns 1 if the value is set
+func (module *Module) kvSet(ctx context.Context, instance api.Module, ptr uint32, size uint32, valuePtr uint32, valueSize uint32, ttl uint32) uint32 {
+	kv, key, ok := module.kv(instance, ptr, size)
+	if !ok {
+		return 0
+	}
+
+	value, ok := instance.Memory().Read(valuePtr, valueSize)
+	if !ok {
+		return 0
+	}
+
+	kv.Set(key, string(value), time.Duration(ttl)*time.Second)
+	return 1
+}
+
+// kv the store and the key scoped by the module id
+func (module *Module) kv(instance api.Module, ptr uint32, size uint32) (store.Store, string, bool) {
+	if !module.caps[CapKV] {
+		return nil, "", false
+	}
+
+	kv, has := store.Pools[module.Option.Store]
+	if !has {
+		log.Warn("[WASM] %s the store %s is not found", module.ID, module.Option.Store)
+		return nil, "", false
+	}
+
+	key, ok := instance.Memory().Read(ptr, size)
+	if !ok {
+		return nil, "", false
+	}
+	return kv, "wasm:" + module.ID + ":" + string(key), true
+}
+
+// fetch(request_ptr i32, request_len i32) i64, the request and the response are JSON, returns the response as ptr<<32 | len
+func (module *Module) fetch(ctx context.Context, instance api.Module, ptr uint32, size uint32) uint64 {
+	res := module.doFetch(ctx, instance, ptr, size)
+	data, err := jsoniter.Marshal(res)
+	if err != nil {
+		return 0
+	}
+
+	ptr, size, err = write(ctx, instance, data)
+	if err != nil {
+		log.Error("[WASM] %s fetch: %s", module.ID, err.Error())
+		return 0
+	}
+	return pack(ptr, size)
+}
+
+func (module *Module) doFetch(ctx context.Context, instance api.Module, ptr uint32, size uint32) fetchResponse {
+	if !module.caps[CapHTTP] {
+		return fetchResponse{Error: "the http capability is not granted"}
+	}
+
+	raw, ok := instance.Memory().Read(ptr, size)
+	if !ok {
+		return fetchResponse{Error: "the request is out of the memory"}
+	}
+
+	req := fetchRequest{}
+	if err := jsoniter.Unmarshal(raw, &req); err != nil {
+		return fetchResponse{Error: err.Error()}
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fetchResponse{Error: fmt.Sprintf("the url %s is invalid", req.URL)}
+	}
+
+	if !module.allowed(u.Hostname()) {
+		return fetchResponse{Error: fmt.Sprintf("the host %s is not allowed", u.Hostname())}
+	}
+
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, req.URL, bytes.NewBufferString(req.Body))
+	if err != nil {
+		return fetchResponse{Error: err.Error()}
+	}
+	for name, value := range req.Headers {
+		request.Header.Set(name, value)
+	}
+
+	// The redirects are checked by the allowed hosts too
+	client := &http.Client{CheckRedirect: func(r *http.Request, via []*http.Request) error {
+		if !module.allowed(r.URL.Hostname()) {
+			return fmt.Errorf("the host %s is not allowed", r.URL.Hostname())
+		}
+		return nil
+	}}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return fetchResponse{Error: err.Error()}
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 10<<20))
+	if err != nil {
+		return fetchResponse{Error: err.Error()}
+	}
+
+	headers := map[string]string{}
+	for name := range response.Header {
+		headers[name] = response.Header.Get(name)
+	}
+	return fetchResponse{Status: response.StatusCode, Headers: headers, Body: string(body)}
+}
+
+// allowed the host matches the hosts of the module, e.g. *.yaoapps.com
+func (module *Module) allowed(host string) bool {
+	for _, pattern := range module.Option.Hosts {
+		if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(host)); matched {
+			return true
+		}
+	}
+	return false
+}
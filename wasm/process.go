@@ -0,0 +1,28 @@
+package wasm
+
+import (
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.Register("wasms", processWasms)
+}
+
+// processWasms wasms.<id> <function> [...args]
+func processWasms(process *process.Process) interface{} {
+
+	process.ValidateArgNums(1)
+	module, err := Select(process.ID)
+	if err != nil {
+		exception.New("wasms.%s not loaded", 404, process.ID).Throw()
+		return nil
+	}
+
+	function := process.ArgsString(0)
+	res, err := module.Call(function, process.Args[1:]...)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return res
+}
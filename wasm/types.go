@@ -0,0 +1,43 @@
+package wasm
+
+import (
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// Capabilities of the host API, the module calls the host functions of the granted capabilities only
+const (
+	CapLog  = "log"  // yao.log(level, ptr, len)
+	CapKV   = "kv"   // yao.kv_get(key_ptr, key_len) yao.kv_set(key_ptr, key_len, value_ptr, value_len, ttl)
+	CapHTTP = "http" // yao.fetch(request_ptr, request_len)
+)
+
+// Option the option of the module, <name>.wasm.yao next to the module
+type Option struct {
+	Capabilities []string `json:"capabilities,omitempty"` // Granted capabilities: log, kv, http, log by default
+	Store        string   `json:"store,omitempty"`        // KV store name, the keys are prefixed with the module id
+	Hosts        []string `json:"hosts,omitempty"`        // Hosts the module fetches, e.g. ["api.github.com", "*.yaoapps.com"]
+	Timeout      int      `json:"timeout,omitempty"`      // Seconds of each call, defaults to 5
+	Memory       int      `json:"memory,omitempty"`       // Memory limit in MB, defaults to 16
+}
+
+// Module the compiled WebAssembly module, called as the process wasms.<id>
+//
+// The module exports:
+//
+//	alloc(size i32) i32              allocate the memory for the arguments and the host results
+//	<function>(ptr i32, len i32) i64 read the JSON arguments, return the JSON result as ptr<<32 | len
+type Module struct {
+	ID       string
+	File     string
+	Option   Option
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	caps     map[string]bool
+}
+
+// Modules the loaded modules
+var Modules = map[string]*Module{}
+
+var lock sync.RWMutex
@@ -0,0 +1,237 @@
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+// Load the WebAssembly modules under <YAO_EXTENSION_ROOT>/wasms
+func Load(cfg config.Config) error {
+
+	root, err := Root(cfg)
+	if err != nil {
+		return err
+	}
+
+	messages := []string{}
+	modules := map[string]*Module{}
+	err = filepath.Walk(root, func(file string, info fs.FileInfo, err error) error {
+		if info == nil || info.IsDir() || !strings.HasSuffix(file, ".wasm") {
+			return nil
+		}
+
+		module, err := Open(file, share.ID(root, file))
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+		modules[module.ID] = module
+		return nil
+	})
+
+	// Replace the loaded modules
+	lock.Lock()
+	for id, module := range Modules {
+		module.Close()
+		delete(Modules, id)
+	}
+	for id, module := range modules {
+		Modules[id] = module
+	}
+	lock.Unlock()
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+
+	return err
+}
+
+// Root return the root of the modules
+func Root(cfg config.Config) (string, error) {
+	root := filepath.Join(cfg.ExtensionRoot, "wasms")
+	if cfg.ExtensionRoot == "" {
+		root = filepath.Join(cfg.Root, "wasms")
+	}
+	return filepath.Abs(root)
+}
+
+// Select the loaded module
+func Select(id string) (*Module, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	module, has := Modules[id]
+	if !has {
+		return nil, fmt.Errorf("wasm %s not found", id)
+	}
+	return module, nil
+}
+
+// Open compile the module, the option is read from <name>.wasm.yao if exists
+func Open(file string, id string) (*Module, error) {
+	module := &Module{ID: id, File: file, Option: Option{Capabilities: []string{CapLog}}}
+
+	if bytes, err := os.ReadFile(file + ".yao"); err == nil {
+		err = application.Parse(filepath.Base(file)+".yao", bytes, &module.Option)
+		if err != nil {
+			return nil, fmt.Errorf("wasm %s: %s", id, err.Error())
+		}
+	}
+
+	module.caps = map[string]bool{}
+	for _, capability := range module.Option.Capabilities {
+		module.caps[capability] = true
+	}
+
+	if module.caps[CapKV] && module.Option.Store == "" {
+		return nil, fmt.Errorf("wasm %s: the store of the kv capability is required", id)
+	}
+
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// The memory is limited by the pages of 64KB
+	memory := 16
+	if module.Option.Memory > 0 {
+		memory = module.Option.Memory
+	}
+
+	ctx := context.Background()
+	module.runtime = wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(memory*16)).
+		WithCloseOnContextDone(true))
+
+	// WASI without the file system, the environment and the network
+	_, err = wasi_snapshot_preview1.Instantiate(ctx, module.runtime)
+	if err != nil {
+		module.Close()
+		return nil, fmt.Errorf("wasm %s: %s", id, err.Error())
+	}
+
+	err = module.host(ctx)
+	if err != nil {
+		module.Close()
+		return nil, fmt.Errorf("wasm %s: %s", id, err.Error())
+	}
+
+	module.compiled, err = module.runtime.CompileModule(ctx, source)
+	if err != nil {
+		module.Close()
+		return nil, fmt.Errorf("wasm %s: %s", id, err.Error())
+	}
+
+	if _, has := module.compiled.ExportedFunctions()["alloc"]; !has {
+		module.Close()
+		return nil, fmt.Errorf("wasm %s: the alloc function is not exported", id)
+	}
+
+	return module, nil
+}
+
+// Close release the runtime of the module
+func (module *Module) Close() {
+	if module.runtime != nil {
+		module.runtime.Close(context.Background())
+	}
+}
+
+// Call the exported function with the JSON arguments, each call runs in a new instance of the module
+func (module *Module) Call(function string, args ...interface{}) (interface{}, error) {
+	if _, has := module.compiled.ExportedFunctions()[function]; !has || function == "alloc" {
+		return nil, fmt.Errorf("wasm %s: function %s is not exported", module.ID, function)
+	}
+
+	timeout := 5 * time.Second
+	if module.Option.Timeout > 0 {
+		timeout = time.Duration(module.Option.Timeout) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	instance, err := module.runtime.InstantiateModule(ctx, module.compiled, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return nil, fmt.Errorf("wasm %s: %s", module.ID, err.Error())
+	}
+	defer instance.Close(ctx)
+
+	input, err := jsoniter.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+
+	ptr, size, err := write(ctx, instance, input)
+	if err != nil {
+		return nil, fmt.Errorf("wasm %s: %s", module.ID, err.Error())
+	}
+
+	results, err := instance.ExportedFunction(function).Call(ctx, uint64(ptr), uint64(size))
+	if err != nil {
+		return nil, fmt.Errorf("wasm %s.%s: %s", module.ID, function, err.Error())
+	}
+
+	if len(results) == 0 || results[0] == 0 {
+		return nil, nil
+	}
+
+	output, err := read(instance, results[0])
+	if err != nil {
+		return nil, fmt.Errorf("wasm %s.%s: %s", module.ID, function, err.Error())
+	}
+
+	var res interface{}
+	err = jsoniter.Unmarshal(output, &res)
+	if err != nil {
+		return nil, fmt.Errorf("wasm %s.%s: the result is not JSON: %s", module.ID, function, err.Error())
+	}
+	return res, nil
+}
+
+// write the data to the memory allocated by the module
+func write(ctx context.Context, instance api.Module, data []byte) (uint32, uint32, error) {
+	results, err := instance.ExportedFunction("alloc").Call(ctx, uint64(len(data)))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ptr := uint32(results[0])
+	if !instance.Memory().Write(ptr, data) {
+		return 0, 0, fmt.Errorf("write %d bytes at %d out of the memory", len(data), ptr)
+	}
+	return ptr, uint32(len(data)), nil
+}
+
+// read the data of the packed ptr<<32 | len
+func read(instance api.Module, packed uint64) ([]byte, error) {
+	ptr, size := unpack(packed)
+	data, ok := instance.Memory().Read(ptr, size)
+	if !ok {
+		return nil, fmt.Errorf("read %d bytes at %d out of the memory", size, ptr)
+	}
+
+	// The memory is reused by the next instance
+	return append([]byte{}, data...), nil
+}
+
+func pack(ptr uint32, size uint32) uint64 {
+	return uint64(ptr)<<32 | uint64(size)
+}
+
+func unpack(packed uint64) (uint32, uint32) {
+	return uint32(packed >> 32), uint32(packed)
+}
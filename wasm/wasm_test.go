@@ -0,0 +1,45 @@
+package wasm
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/config"
+)
+
+func TestPack(t *testing.T) {
+	ptr, size := unpack(pack(1024, 64))
+	assert.Equal(t, uint32(1024), ptr)
+	assert.Equal(t, uint32(64), size)
+}
+
+func TestAllowed(t *testing.T) {
+	module := &Module{Option: Option{Hosts: []string{"api.github.com", "*.yaoapps.com"}}}
+	assert.True(t, module.allowed("api.github.com"))
+	assert.True(t, module.allowed("Docs.YaoApps.com"))
+	assert.False(t, module.allowed("yaoapps.com"))
+	assert.False(t, module.allowed("github.com"))
+	assert.False(t, (&Module{}).allowed("api.github.com"))
+}
+
+func TestRoot(t *testing.T) {
+	cfg := config.Config{Root: "/app"}
+	root, err := Root(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, filepath.Join("/app", "wasms"), root)
+
+	cfg.ExtensionRoot = "/extensions"
+	root, err = Root(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, filepath.Join("/extensions", "wasms"), root)
+}
+
+func TestSelect(t *testing.T) {
+	_, err := Select("not-found")
+	assert.Error(t, err)
+}
@@ -0,0 +1,240 @@
+package watcher
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/cluster"
+)
+
+// runner the watching of the directory of the watcher, the changes of a file are merged in the debounce
+type runner struct {
+	watcher *Watcher
+	data    string // The data root
+	root    string
+	fsw     *fsnotify.Watcher
+	mu      sync.Mutex
+	pending map[string]*change
+	done    chan struct{}
+}
+
+type change struct {
+	op    string
+	timer *time.Timer
+}
+
+var runners = map[string]*runner{}
+var started = false
+var runnerLock sync.Mutex
+
+// Start watching the directories of the watchers
+func Start() {
+	runnerLock.Lock()
+	defer runnerLock.Unlock()
+
+	lock.RLock()
+	defer lock.RUnlock()
+
+	for id, w := range Watchers {
+		if w.Disabled {
+			continue
+		}
+
+		r, err := watch(w, dataRoot)
+		if err != nil {
+			log.Error("[Watcher] %s watch %s: %s", id, w.Path, err.Error())
+			continue
+		}
+		runners[id] = r
+		log.Info("[Watcher] %s start watching %s", id, w.Path)
+	}
+	started = true
+}
+
+// Stop watching the directories, the changes pending are dropped
+func Stop() {
+	runnerLock.Lock()
+	defer runnerLock.Unlock()
+
+	for id, r := range runners {
+		r.stop()
+		log.Info("[Watcher] %s stop", id)
+	}
+	runners = map[string]*runner{}
+	started = false
+}
+
+func running() bool {
+	runnerLock.Lock()
+	defer runnerLock.Unlock()
+	return started
+}
+
+// watch the directory of the data root, the directory is created if it does not exist
+func watch(w *Watcher, data string) (*runner, error) {
+	root := filepath.Join(data, filepath.FromSlash(w.Path))
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &runner{watcher: w, data: data, root: root, fsw: fsw, pending: map[string]*change{}, done: make(chan struct{})}
+	if err := r.add(root, false); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	go r.run()
+	return r, nil
+}
+
+func (r *runner) run() {
+	defer close(r.done)
+	for {
+		select {
+		case event, ok := <-r.fsw.Events:
+			if !ok {
+				return
+			}
+			r.handle(event)
+
+		case err, ok := <-r.fsw.Errors:
+			if !ok {
+				return
+			}
+			log.Error("[Watcher] %s: %s", r.watcher.ID, err.Error())
+		}
+	}
+}
+
+func (r *runner) stop() {
+	r.fsw.Close()
+	<-r.done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, c := range r.pending {
+		c.timer.Stop()
+		delete(r.pending, name)
+	}
+}
+
+// add watch the directory, and the sub directories if the watcher is recursive.
+// The files of the new directory are created, e.g. the directory is moved into the watched directory
+func (r *runner) add(dir string, created bool) error {
+	if !r.watcher.Recursive {
+		return r.fsw.Add(dir)
+	}
+
+	return filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return r.fsw.Add(name)
+		}
+		if created {
+			r.schedule(name, "create")
+		}
+		return nil
+	})
+}
+
+func (r *runner) handle(event fsnotify.Event) {
+	op := ""
+	switch {
+	case event.Has(fsnotify.Create):
+		op = "create"
+	case event.Has(fsnotify.Write):
+		op = "write"
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		op = "remove"
+	default:
+		return
+	}
+
+	if op == "create" {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if r.watcher.Recursive {
+				if err := r.add(event.Name, true); err != nil {
+					log.Error("[Watcher] %s watch %s: %s", r.watcher.ID, event.Name, err.Error())
+				}
+			}
+			return
+		}
+	}
+	r.schedule(event.Name, op)
+}
+
+// schedule the change of the file, the process is called when the file is not changed in the debounce.
+// The file created and written in the debounce is created
+func (r *runner) schedule(name string, op string) {
+	rel, err := filepath.Rel(r.root, name)
+	if err != nil || !r.watcher.Match(rel) {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, has := r.pending[name]; has {
+		if !(c.op == "create" && op == "write") {
+			c.op = op
+		}
+		c.timer.Reset(r.watcher.debounce())
+		return
+	}
+
+	r.pending[name] = &change{op: op, timer: time.AfterFunc(r.watcher.debounce(), func() { r.fire(name) })}
+}
+
+// fire call the process with the file, the file removed before is skipped
+func (r *runner) fire(name string) {
+	r.mu.Lock()
+	c, has := r.pending[name]
+	delete(r.pending, name)
+	r.mu.Unlock()
+	if !has {
+		return
+	}
+
+	w := r.watcher
+	file := &File{Watcher: w.ID, Op: c.op, Name: filepath.Base(name), Time: time.Now()}
+	info, err := os.Stat(name)
+	switch {
+	case c.op == "remove" && err == nil:
+		return
+	case c.op != "remove" && (err != nil || info.IsDir()):
+		return
+	case err == nil:
+		file.Size = info.Size()
+	}
+
+	if !w.on(c.op) || (w.Leader && !cluster.IsLeader()) {
+		return
+	}
+
+	rel, _ := filepath.Rel(r.data, name)
+	file.Path = "/" + filepath.ToSlash(rel)
+
+	p, err := process.Of(w.Process, w.Bind(file)...)
+	if err != nil {
+		log.Error("[Watcher] %s %s: %s", w.ID, file.Path, err.Error())
+		return
+	}
+
+	_, err = p.Exec()
+	if err != nil {
+		log.Error("[Watcher] %s %s: %s", w.ID, file.Path, err.Error())
+	}
+}
@@ -0,0 +1,223 @@
+package watcher
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+// Watcher the watcher of the watchers/*.yao, the process is called when the files of the directory of the data root
+// are created, written or removed. The process is called with the file if the args are not set
+//
+//	{
+//	  "name": "Import the orders",
+//	  "path": "imports/orders",
+//	  "patterns": ["*.csv", "*.xlsx"],
+//	  "ignore": [".*", "*.tmp"],
+//	  "events": ["create"],
+//	  "debounce": 2000,
+//	  "process": "scripts.orders.Import",
+//	  "args": ["{{ path }}"]
+//	}
+type Watcher struct {
+	ID        string        `json:"-"`
+	Name      string        `json:"name,omitempty"`
+	Path      string        `json:"path"`                // The directory relative to the data root, it is created if it does not exist
+	Patterns  []string      `json:"patterns,omitempty"`  // The files watched, all files by default. The patterns without / match the file names
+	Ignore    []string      `json:"ignore,omitempty"`    // The files ignored, e.g. the temporary files of the uploads
+	Events    []string      `json:"events,omitempty"`    // create, write or remove, create and write by default
+	Recursive bool          `json:"recursive,omitempty"` // The sub directories are watched
+	Debounce  int           `json:"debounce,omitempty"`  // The milliseconds the file is not changed before the process is called, 1000 by default
+	Leader    bool          `json:"leader,omitempty"`    // The process is called on the leader of the cluster only, e.g. the directory is a shared volume
+	Process   string        `json:"process"`
+	Args      []interface{} `json:"args,omitempty"`
+	Disabled  bool          `json:"disabled,omitempty"`
+}
+
+// File the file changed, the path is relative to the data root
+type File struct {
+	Watcher string    `json:"watcher"`
+	Op      string    `json:"op"` // create, write or remove
+	Path    string    `json:"path"`
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Time    time.Time `json:"time"`
+}
+
+// Watchers the loaded watchers
+var Watchers = map[string]*Watcher{}
+var lock sync.RWMutex
+
+// dataRoot the data root of the directories
+var dataRoot = ""
+
+var ops = map[string]bool{"create": true, "write": true, "remove": true}
+
+// Load the watchers/*.yao watchers, the running watchers are restarted
+func Load(cfg config.Config) error {
+	watchers := map[string]*Watcher{}
+	messages := []string{}
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("watchers", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		w := Watcher{}
+		err = application.Parse(file, bytes, &w)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		w.ID = share.ID(root, file)
+		if err := w.validate(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		watchers[w.ID] = &w
+		return nil
+	}, exts...)
+
+	restart := running()
+	if restart {
+		Stop()
+	}
+
+	lock.Lock()
+	Watchers = watchers
+	dataRoot = cfg.DataRoot
+	lock.Unlock()
+
+	if restart {
+		Start()
+	}
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+	return err
+}
+
+// Map the file as map, the default args of the process
+func (file *File) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"watcher": file.Watcher,
+		"op":      file.Op,
+		"path":    file.Path,
+		"name":    file.Name,
+		"size":    file.Size,
+		"time":    file.Time,
+	}
+}
+
+// Bind the args of the watcher, the {{ field }} of the args are replaced by the fields of the file
+func (w *Watcher) Bind(file *File) []interface{} {
+	if len(w.Args) == 0 {
+		return []interface{}{file.Map()}
+	}
+
+	data := maps.Of(file.Map()).Dot()
+	args := []interface{}{}
+	for _, arg := range w.Args {
+		if text, ok := arg.(string); ok && strings.Contains(text, "{{") {
+			arg = helper.Bind(text, data)
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+// Match the file of the path relative to the directory is watched or not
+func (w *Watcher) Match(name string) bool {
+	name = filepath.ToSlash(name)
+	for _, pattern := range w.Ignore {
+		if match(pattern, name) {
+			return false
+		}
+	}
+
+	if len(w.Patterns) == 0 {
+		return true
+	}
+	for _, pattern := range w.Patterns {
+		if match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// on the op is watched or not
+func (w *Watcher) on(op string) bool {
+	if len(w.Events) == 0 {
+		return op == "create" || op == "write"
+	}
+	for _, event := range w.Events {
+		if event == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) debounce() time.Duration {
+	if w.Debounce <= 0 {
+		return time.Second
+	}
+	return time.Duration(w.Debounce) * time.Millisecond
+}
+
+func (w *Watcher) validate() error {
+	if w.Process == "" {
+		return fmt.Errorf("the process is required")
+	}
+
+	if w.Path == "" {
+		return fmt.Errorf("the path is required")
+	}
+
+	clean := path.Clean("/" + filepath.ToSlash(w.Path))
+	if clean == "/" || clean != "/"+strings.Trim(filepath.ToSlash(w.Path), "/") {
+		return fmt.Errorf("the path %s is invalid, the directory of the data root is required", w.Path)
+	}
+
+	for _, pattern := range append(append([]string{}, w.Patterns...), w.Ignore...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("the pattern %s is invalid", pattern)
+		}
+	}
+
+	for _, event := range w.Events {
+		if !ops[event] {
+			return fmt.Errorf("the event %s is not supported, create, write or remove", event)
+		}
+	}
+	return nil
+}
+
+// match the patterns without / match the file names, the others match the paths
+func match(pattern string, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
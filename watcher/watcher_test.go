@@ -0,0 +1,94 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestMatch(t *testing.T) {
+	w := &Watcher{Patterns: []string{"*.csv", "archive/*.xlsx"}, Ignore: []string{".*", "*.tmp.csv"}}
+	assert.True(t, w.Match("orders.csv"))
+	assert.True(t, w.Match(filepath.Join("2024", "orders.csv")))
+	assert.True(t, w.Match("archive/orders.xlsx"))
+	assert.False(t, w.Match("orders.xlsx"))
+	assert.False(t, w.Match(".orders.csv"))
+	assert.False(t, w.Match("orders.tmp.csv"))
+
+	assert.True(t, (&Watcher{}).Match("orders.pdf"))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, (&Watcher{Path: "imports/orders/", Process: "scripts.orders.Import"}).validate())
+	assert.Error(t, (&Watcher{Path: "imports"}).validate())
+	assert.Error(t, (&Watcher{Path: "/", Process: "scripts.orders.Import"}).validate())
+	assert.Error(t, (&Watcher{Path: "imports/../../etc", Process: "scripts.orders.Import"}).validate())
+	assert.Error(t, (&Watcher{Path: "imports", Process: "scripts.orders.Import", Patterns: []string{"[*.csv"}}).validate())
+	assert.Error(t, (&Watcher{Path: "imports", Process: "scripts.orders.Import", Events: []string{"chmod"}}).validate())
+}
+
+func TestWatch(t *testing.T) {
+	calls := make(chan []interface{}, 10)
+	process.Register("unit.watcher.collect", func(p *process.Process) interface{} {
+		calls <- p.Args
+		return nil
+	})
+
+	w := &Watcher{
+		ID:        "orders",
+		Path:      "imports/orders",
+		Patterns:  []string{"*.csv"},
+		Ignore:    []string{".*"},
+		Recursive: true,
+		Debounce:  100,
+		Process:   "unit.watcher.collect",
+		Args:      []interface{}{"{{ path }}", "{{ op }}"},
+	}
+
+	data := t.TempDir()
+	r, err := watch(w, data)
+	assert.NoError(t, err)
+	defer r.stop()
+	root := filepath.Join(data, "imports", "orders")
+	assert.DirExists(t, root)
+
+	// The file created and written in the debounce is created once
+	file := filepath.Join(root, "orders.csv")
+	assert.NoError(t, os.WriteFile(file, []byte("id,total\n"), 0644))
+	f, _ := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("1,100\n")
+	f.Close()
+	os.WriteFile(filepath.Join(root, ".orders.csv"), []byte("id"), 0644)
+	os.WriteFile(filepath.Join(root, "orders.txt"), []byte("id"), 0644)
+
+	assert.Equal(t, []interface{}{"/imports/orders/orders.csv", "create"}, wait(t, calls))
+
+	// The files of the directory moved into the watched directory
+	moved := filepath.Join(data, "2024")
+	os.MkdirAll(moved, os.ModePerm)
+	os.WriteFile(filepath.Join(moved, "january.csv"), []byte("id"), 0644)
+	assert.NoError(t, os.Rename(moved, filepath.Join(root, "2024")))
+	assert.Equal(t, []interface{}{"/imports/orders/2024/january.csv", "create"}, wait(t, calls))
+
+	// The removed files are not watched by default
+	os.Remove(file)
+	select {
+	case args := <-calls:
+		t.Fatalf("the process is called with %v", args)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func wait(t *testing.T, calls chan []interface{}) []interface{} {
+	select {
+	case args := <-calls:
+		return args
+	case <-time.After(2 * time.Second):
+		t.Fatal("the process is not called")
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetRoutes the webhooks and the delivery log, for the admins
+//
+//	GET    <path>                                        The webhooks
+//	POST   <path> {"url": "...", "events": ["chat.*"]}   Register the webhook, the secret is generated if not given
+//	GET    <path>/:id                                    The webhook
+//	PUT    <path>/:id {"enabled": false}                 Update the webhook
+//	DELETE <path>/:id                                    Remove the webhook and the delivery log of it
+//	GET    <path>/:id/deliveries?status=failed&page=1    The delivery log, the latest first
+//	POST   <path>/:id/deliveries/:delivery/redeliver     Deliver the payload of the delivery again
+func SetRoutes(router *gin.Engine, path string, guards ...gin.HandlerFunc) {
+	handlers := func(handler gin.HandlerFunc) []gin.HandlerFunc {
+		return append(append([]gin.HandlerFunc{}, guards...), handler)
+	}
+	router.GET(path, handlers(handleList)...)
+	router.POST(path, handlers(handleCreate)...)
+	router.GET(path+"/:id", handlers(handleFind)...)
+	router.PUT(path+"/:id", handlers(handleUpdate)...)
+	router.DELETE(path+"/:id", handlers(handleRemove)...)
+	router.GET(path+"/:id/deliveries", handlers(handleDeliveries)...)
+	router.POST(path+"/:id/deliveries/:delivery/redeliver", handlers(handleRedeliver)...)
+}
+
+// param the integer of the path parameter
+func param(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": "the " + name + " should be an integer"})
+		return 0, false
+	}
+	return id, true
+}
+
+func handleList(c *gin.Context) {
+	hooks, err := List()
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(200, hooks)
+}
+
+func handleCreate(c *gin.Context) {
+	hook := Webhook{Enabled: true}
+	if err := c.ShouldBindJSON(&hook); err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	created, err := Create(hook)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(201, created)
+}
+
+func handleFind(c *gin.Context) {
+	id, ok := param(c, "id")
+	if !ok {
+		return
+	}
+
+	hook, err := Get(id)
+	if err != nil {
+		c.JSON(404, gin.H{"code": 404, "message": err.Error()})
+		return
+	}
+	c.JSON(200, hook)
+}
+
+func handleUpdate(c *gin.Context) {
+	id, ok := param(c, "id")
+	if !ok {
+		return
+	}
+
+	data := map[string]interface{}{}
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	hook, err := Update(id, data)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(200, hook)
+}
+
+func handleRemove(c *gin.Context) {
+	id, ok := param(c, "id")
+	if !ok {
+		return
+	}
+
+	n, err := Remove(id)
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+
+	if n == 0 {
+		c.JSON(404, gin.H{"code": 404, "message": "the webhook does not exist"})
+		return
+	}
+	c.JSON(200, gin.H{"removed": n})
+}
+
+func handleDeliveries(c *gin.Context) {
+	id, ok := param(c, "id")
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	pagesize, _ := strconv.Atoi(c.Query("pagesize"))
+	res, err := Deliveries(id, c.Query("status"), page, pagesize)
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+	c.JSON(200, res)
+}
+
+func handleRedeliver(c *gin.Context) {
+	id, ok := param(c, "id")
+	if !ok {
+		return
+	}
+
+	deliveryID, ok := param(c, "delivery")
+	if !ok {
+		return
+	}
+
+	origin, err := GetDelivery(deliveryID)
+	if err != nil || origin.Webhook != id {
+		c.JSON(404, gin.H{"code": 404, "message": "the delivery does not exist"})
+		return
+	}
+
+	delivery, err := Redeliver(deliveryID)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(202, delivery)
+}
@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"fmt"
+	"strconv"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func init() {
+	process.RegisterGroup("webhook", map[string]process.Handler{
+		"list":       processList,
+		"find":       processFind,
+		"create":     processCreate,
+		"update":     processUpdate,
+		"remove":     processRemove,
+		"deliveries": processDeliveries,
+		"redeliver":  processRedeliver,
+	})
+}
+
+// processList webhook.List()
+func processList(process *process.Process) interface{} {
+	hooks, err := List()
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return hooks
+}
+
+// processFind webhook.Find(id)
+func processFind(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	hook, err := Get(toInt64(process.Args[0]))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+	return hook
+}
+
+// processCreate webhook.Create({"url": "https://example.com/hooks", "events": ["chat.created"], "secret"?: "...", "enabled"?: true})
+func processCreate(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	hook := Webhook{Enabled: true}
+	bytes, err := jsoniter.Marshal(process.Args[0])
+	if err == nil {
+		err = jsoniter.Unmarshal(bytes, &hook)
+	}
+	if err != nil {
+		exception.New("the webhook should be {\"url\": \"<url>\", \"events\": [\"<event>\"]}", 400).Throw()
+	}
+
+	created, err := Create(hook)
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return created
+}
+
+// processUpdate webhook.Update(id, {"enabled": false})
+func processUpdate(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	hook, err := Update(toInt64(process.Args[0]), process.ArgsMap(1))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return hook
+}
+
+// processRemove webhook.Remove(id)
+func processRemove(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	n, err := Remove(toInt64(process.Args[0]))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return n
+}
+
+// processDeliveries webhook.Deliveries(id, {"status": "failed", "page": 1, "pagesize": 20}?)
+func processDeliveries(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	option := map[string]interface{}{}
+	if process.NumOfArgs() > 1 {
+		option = process.ArgsMap(1)
+	}
+
+	res, err := Deliveries(toInt64(process.Args[0]), toString(option["status"]), int(toInt64(option["page"])), int(toInt64(option["pagesize"])))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return res
+}
+
+// processRedeliver webhook.Redeliver(delivery)
+func processRedeliver(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	delivery, err := Redeliver(toInt64(process.Args[0]))
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return delivery
+}
+
+func toInt64(v interface{}) int64 {
+	switch value := v.(type) {
+	case int:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	case uint64:
+		return int64(value)
+	case float64:
+		return int64(value)
+	case []byte:
+		n, _ := strconv.ParseInt(string(value), 10, 64)
+		return n
+	case string:
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return n
+	}
+	return 0
+}
+
+func toString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func toBool(v interface{}) bool {
+	switch value := v.(type) {
+	case bool:
+		return value
+	case string:
+		enabled, _ := strconv.ParseBool(value)
+		return enabled
+	}
+	return toInt64(v) != 0
+}
@@ -0,0 +1,376 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/share"
+)
+
+// The signature verifications of the receivers
+const (
+	VerifyHMAC   = "hmac"   // The HMAC of the raw body in the header, the algorithm, encoding and prefix are configurable
+	VerifyGithub = "github" // X-Hub-Signature-256: sha256=<hex>
+	VerifyStripe = "stripe" // Stripe-Signature: t=<timestamp>,v1=<hex of "<timestamp>.<body>">
+	VerifyYao    = "yao"    // X-Yao-Timestamp and X-Yao-Signature of the Yao outbound webhooks
+	VerifyToken  = "token"  // The header equals the secret, e.g. X-Gitlab-Token
+)
+
+// Receiver the incoming webhook of the webhooks/*.yao, served at /webhooks/<id>, the process is called with the request
+//
+//	{
+//	  "name": "GitHub",
+//	  "process": "scripts.github.Push",
+//	  "verify": { "type": "github", "secret": "$ENV.GITHUB_WEBHOOK_SECRET" }
+//	}
+type Receiver struct {
+	ID      string   `json:"-"`
+	Name    string   `json:"name,omitempty"`
+	Process string   `json:"process"`
+	Methods []string `json:"methods,omitempty"` // POST by default
+	Verify  *Verify  `json:"verify,omitempty"`
+}
+
+// Verify the signature verification of the receiver
+type Verify struct {
+	Type      string `json:"type"`
+	Secret    string `json:"secret"`              // $ENV.NAME is replaced by the environment variable
+	Header    string `json:"header,omitempty"`    // The header of the signature or the token
+	Algorithm string `json:"algorithm,omitempty"` // sha256 by default, sha1 or sha512
+	Encoding  string `json:"encoding,omitempty"`  // hex by default, or base64
+	Prefix    string `json:"prefix,omitempty"`    // The prefix of the signature, e.g. sha256=
+	Tolerance int    `json:"tolerance,omitempty"` // The seconds the timestamp of the stripe and yao signatures is valid, 300 by default
+}
+
+// Receivers the loaded receivers
+var Receivers = map[string]*Receiver{}
+var receiverLock sync.RWMutex
+
+// errSignature the signature of the request is missing or invalid
+var errSignature = errors.New("the signature is invalid")
+
+// loadReceivers load the webhooks/*.yao receivers, the receivers loaded before are replaced
+func loadReceivers() error {
+	receivers := map[string]*Receiver{}
+	messages := []string{}
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("webhooks", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		receiver := Receiver{}
+		err = application.Parse(file, bytes, &receiver)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		receiver.ID = share.ID(root, file)
+		if err := receiver.validate(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		receivers[receiver.ID] = &receiver
+		return nil
+	}, exts...)
+
+	receiverLock.Lock()
+	Receivers = receivers
+	receiverLock.Unlock()
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+	return err
+}
+
+// SetReceiverRoutes the incoming webhooks, /webhooks/github serves the webhooks/github.yao
+func SetReceiverRoutes(router *gin.Engine, path string) {
+	router.Any(path+"/*id", handleReceive)
+}
+
+func handleReceive(c *gin.Context) {
+	id := strings.ReplaceAll(strings.Trim(c.Param("id"), "/"), "/", ".")
+	receiverLock.RLock()
+	receiver, has := Receivers[id]
+	receiverLock.RUnlock()
+	if !has {
+		c.JSON(404, gin.H{"code": 404, "message": "the webhook does not exist"})
+		return
+	}
+
+	if !receiver.Allowed(c.Request.Method) {
+		c.JSON(405, gin.H{"code": 405, "message": fmt.Sprintf("the method %s is not allowed", c.Request.Method)})
+		return
+	}
+
+	raw := []byte{}
+	if c.Request.Body != nil {
+		body := c.Request.Body
+		if config.Conf.BodyLimit > 0 {
+			body = http.MaxBytesReader(c.Writer, body, int64(config.Conf.BodyLimit)<<20)
+		}
+
+		var err error
+		raw, err = io.ReadAll(body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(413, gin.H{"code": 413, "message": fmt.Sprintf("the request body is larger than %d bytes", tooLarge.Limit)})
+				return
+			}
+			c.JSON(400, gin.H{"code": 400, "message": err.Error()})
+			return
+		}
+	}
+
+	if err := receiver.Verify.Check(c.Request.Header, raw, time.Now()); err != nil {
+		log.Warn("[Webhook] %s %s: %s", receiver.ID, c.ClientIP(), err.Error())
+		c.JSON(401, gin.H{"code": 401, "message": err.Error()})
+		return
+	}
+
+	p, err := process.Of(receiver.Process, Request(c.Request, receiver.ID, raw))
+	if err != nil {
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+
+	res, err := p.Exec()
+	if err != nil {
+		log.Error("[Webhook] %s %s: %s", receiver.ID, receiver.Process, err.Error())
+		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		return
+	}
+
+	switch value := res.(type) {
+	case nil:
+		c.Status(204)
+	case string:
+		c.String(200, value)
+	default:
+		c.JSON(200, value)
+	}
+}
+
+// Request the args of the receiver process, the raw body is kept for the signature checks of the process
+//
+//	{"id": "github", "method": "POST", "path": "/webhooks/github", "headers": {}, "query": {}, "body": {}, "raw": "..."}
+func Request(req *http.Request, id string, raw []byte) map[string]interface{} {
+	headers := map[string]interface{}{}
+	for name, values := range req.Header {
+		headers[name] = strings.Join(values, ", ")
+	}
+
+	query := map[string]interface{}{}
+	for name, values := range req.URL.Query() {
+		query[name] = strings.Join(values, ",")
+	}
+
+	var body interface{} = string(raw)
+	contentType := strings.ToLower(req.Header.Get("Content-Type"))
+	switch {
+	case strings.Contains(contentType, "json"):
+		var data interface{}
+		if err := jsoniter.Unmarshal(raw, &data); err == nil {
+			body = data
+		}
+
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		if values, err := url.ParseQuery(string(raw)); err == nil {
+			form := map[string]interface{}{}
+			for name := range values {
+				form[name] = values.Get(name)
+			}
+			body = form
+		}
+	}
+
+	return map[string]interface{}{
+		"id":      id,
+		"method":  req.Method,
+		"path":    req.URL.Path,
+		"headers": headers,
+		"query":   query,
+		"body":    body,
+		"raw":     string(raw),
+	}
+}
+
+// Allowed the method is allowed or not, POST only if the methods are not set
+func (receiver *Receiver) Allowed(method string) bool {
+	if len(receiver.Methods) == 0 {
+		return method == http.MethodPost
+	}
+	for _, allowed := range receiver.Methods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (receiver *Receiver) validate() error {
+	if receiver.Process == "" {
+		return fmt.Errorf("the process is required")
+	}
+
+	if receiver.Verify == nil {
+		return nil
+	}
+
+	if strings.HasPrefix(receiver.Verify.Secret, "$ENV.") {
+		receiver.Verify.Secret = os.Getenv(strings.TrimPrefix(receiver.Verify.Secret, "$ENV."))
+	}
+
+	switch receiver.Verify.Type {
+	case VerifyHMAC, VerifyToken:
+		if receiver.Verify.Header == "" {
+			return fmt.Errorf("the header of the %s verification is required", receiver.Verify.Type)
+		}
+	case VerifyGithub, VerifyStripe, VerifyYao:
+		receiver.Verify.Algorithm, receiver.Verify.Encoding, receiver.Verify.Prefix = "", "", ""
+	default:
+		return fmt.Errorf("the verification %s is not supported, hmac, github, stripe, yao or token", receiver.Verify.Type)
+	}
+
+	if receiver.Verify.Secret == "" {
+		return fmt.Errorf("the secret of the verification is required")
+	}
+
+	if _, err := receiver.Verify.hash(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Check the signature of the raw body, the request passes if the verification is not set
+func (verify *Verify) Check(header http.Header, raw []byte, now time.Time) error {
+	if verify == nil {
+		return nil
+	}
+
+	switch verify.Type {
+	case VerifyToken:
+		if !hmac.Equal([]byte(header.Get(verify.Header)), []byte(verify.Secret)) {
+			return fmt.Errorf("the token is invalid")
+		}
+		return nil
+
+	case VerifyGithub:
+		return verify.compare(header.Get("X-Hub-Signature-256"), "sha256=", verify.sign(raw))
+
+	case VerifyYao:
+		timestamp := header.Get(HeaderTimestamp)
+		if err := verify.fresh(timestamp, now); err != nil {
+			return err
+		}
+		return verify.compare(header.Get(HeaderSignature), "", Sign(verify.Secret, timestamp, raw))
+
+	case VerifyStripe:
+		timestamp, signatures := "", []string{}
+		for _, item := range strings.Split(header.Get("Stripe-Signature"), ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(item), "=")
+			switch key {
+			case "t":
+				timestamp = value
+			case "v1":
+				signatures = append(signatures, value)
+			}
+		}
+		if err := verify.fresh(timestamp, now); err != nil {
+			return err
+		}
+		expected := verify.sign([]byte(timestamp + "." + string(raw)))
+		for _, signature := range signatures {
+			if verify.compare(signature, "", expected) == nil {
+				return nil
+			}
+		}
+		return errSignature
+	}
+
+	return verify.compare(header.Get(verify.Header), verify.Prefix, verify.sign(raw))
+}
+
+// compare the signature of the header with the expected one in constant time
+func (verify *Verify) compare(signature string, prefix string, expected string) error {
+	if signature == "" {
+		return errSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(prefix+expected)) {
+		return errSignature
+	}
+	return nil
+}
+
+// fresh the timestamp of the signature is in the tolerance
+func (verify *Verify) fresh(timestamp string, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("the timestamp of the signature is invalid")
+	}
+
+	tolerance := verify.Tolerance
+	if tolerance <= 0 {
+		tolerance = 300
+	}
+	if math.Abs(float64(now.Unix()-seconds)) > float64(tolerance) {
+		return fmt.Errorf("the timestamp of the signature is expired")
+	}
+	return nil
+}
+
+// sign the encoded HMAC of the data
+func (verify *Verify) sign(data []byte) string {
+	fn, _ := verify.hash()
+	mac := hmac.New(fn, []byte(verify.Secret))
+	mac.Write(data)
+	if verify.Encoding == "base64" {
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (verify *Verify) hash() (func() hash.Hash, error) {
+	switch strings.ToLower(verify.Algorithm) {
+	case "", "sha256":
+		return sha256.New, nil
+	case "sha1":
+		return sha1.New, nil
+	case "sha512":
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("the algorithm %s is not supported, sha256, sha1 or sha512", verify.Algorithm)
+}
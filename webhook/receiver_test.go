@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCheck(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	raw := []byte(`{"action":"opened"}`)
+	sum := func(fn func() hash.Hash, data string) []byte {
+		h := hmac.New(fn, []byte("secret"))
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	header := func(values ...string) http.Header {
+		h := http.Header{}
+		for i := 0; i < len(values); i += 2 {
+			h.Set(values[i], values[i+1])
+		}
+		return h
+	}
+
+	var none *Verify
+	assert.NoError(t, none.Check(http.Header{}, raw, now))
+
+	github := &Verify{Type: VerifyGithub, Secret: "secret"}
+	assert.NoError(t, github.Check(header("X-Hub-Signature-256", "sha256="+hex.EncodeToString(sum(sha256.New, string(raw)))), raw, now))
+	assert.Error(t, github.Check(header("X-Hub-Signature-256", "sha256="+hex.EncodeToString(sum(sha256.New, "changed"))), raw, now))
+	assert.Error(t, github.Check(http.Header{}, raw, now))
+
+	custom := &Verify{Type: VerifyHMAC, Secret: "secret", Header: "X-Signature", Algorithm: "sha1", Encoding: "base64", Prefix: "v1:"}
+	assert.NoError(t, custom.Check(header("X-Signature", "v1:"+base64.StdEncoding.EncodeToString(sum(sha1.New, string(raw)))), raw, now))
+	assert.Error(t, custom.Check(header("X-Signature", base64.StdEncoding.EncodeToString(sum(sha1.New, string(raw)))), raw, now))
+
+	stripe := &Verify{Type: VerifyStripe, Secret: "secret"}
+	signed := hex.EncodeToString(sum(sha256.New, "1700000000."+string(raw)))
+	assert.NoError(t, stripe.Check(header("Stripe-Signature", "t=1700000000,v1=bad,v1="+signed), raw, now))
+	assert.Error(t, stripe.Check(header("Stripe-Signature", "t=1700000000,v1=bad"), raw, now))
+	assert.Error(t, stripe.Check(header("Stripe-Signature", "t=1700000000,v1="+signed), raw, now.Add(10*time.Minute)))
+
+	yao := &Verify{Type: VerifyYao, Secret: "secret", Tolerance: 60}
+	assert.NoError(t, yao.Check(header(HeaderTimestamp, "1700000000", HeaderSignature, Sign("secret", "1700000000", raw)), raw, now))
+	assert.Error(t, yao.Check(header(HeaderTimestamp, "1700000000", HeaderSignature, Sign("secret", "1700000000", raw)), raw, now.Add(2*time.Minute)))
+
+	token := &Verify{Type: VerifyToken, Secret: "secret", Header: "X-Gitlab-Token"}
+	assert.NoError(t, token.Check(header("X-Gitlab-Token", "secret"), raw, now))
+	assert.Error(t, token.Check(header("X-Gitlab-Token", "other"), raw, now))
+}
+
+func TestReceiverValidate(t *testing.T) {
+	t.Setenv("YAO_TEST_WEBHOOK_SECRET", "secret")
+	receiver := &Receiver{Process: "scripts.github.Push", Verify: &Verify{Type: VerifyGithub, Secret: "$ENV.YAO_TEST_WEBHOOK_SECRET", Algorithm: "sha1"}}
+	assert.NoError(t, receiver.validate())
+	assert.Equal(t, "secret", receiver.Verify.Secret)
+	assert.Empty(t, receiver.Verify.Algorithm)
+
+	assert.Error(t, (&Receiver{}).validate())
+	assert.NoError(t, (&Receiver{Process: "flows.hook"}).validate())
+	assert.Error(t, (&Receiver{Process: "flows.hook", Verify: &Verify{Type: "rsa", Secret: "secret"}}).validate())
+	assert.Error(t, (&Receiver{Process: "flows.hook", Verify: &Verify{Type: VerifyToken, Secret: "secret"}}).validate())
+	assert.Error(t, (&Receiver{Process: "flows.hook", Verify: &Verify{Type: VerifyHMAC, Header: "X-Signature"}}).validate())
+	assert.Error(t, (&Receiver{Process: "flows.hook", Verify: &Verify{Type: VerifyHMAC, Header: "X-Signature", Secret: "secret", Algorithm: "md5"}}).validate())
+}
+
+func TestRequest(t *testing.T) {
+	req := httptest.NewRequest("POST", "/webhooks/github?delivery=1", strings.NewReader(`{"action":"opened"}`))
+	req.Header.Set("Content-Type", "application/json")
+	data := Request(req, "github", []byte(`{"action":"opened"}`))
+	assert.Equal(t, "github", data["id"])
+	assert.Equal(t, "POST", data["method"])
+	assert.Equal(t, "/webhooks/github", data["path"])
+	assert.Equal(t, "1", data["query"].(map[string]interface{})["delivery"])
+	assert.Equal(t, "application/json", data["headers"].(map[string]interface{})["Content-Type"])
+	assert.Equal(t, "opened", data["body"].(map[string]interface{})["action"])
+	assert.Equal(t, `{"action":"opened"}`, data["raw"])
+
+	req = httptest.NewRequest("POST", "/webhooks/form", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	data = Request(req, "form", []byte("name=yao&name=max&type=push"))
+	assert.Equal(t, map[string]interface{}{"name": "yao", "type": "push"}, data["body"])
+}
+
+func TestReceive(t *testing.T) {
+	defer func(receivers map[string]*Receiver) { Receivers = receivers }(Receivers)
+	Receivers = map[string]*Receiver{
+		"github.push": {ID: "github.push", Process: "scripts.github.Push", Verify: &Verify{Type: VerifyGithub, Secret: "secret"}},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetReceiverRoutes(router, "/webhooks")
+
+	cases := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{"POST", "/webhooks/stripe", 404},
+		{"GET", "/webhooks/github/push", 405},
+		{"POST", "/webhooks/github/push", 401},
+	}
+
+	for _, c := range cases {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest(c.method, c.path, strings.NewReader(`{}`)))
+		assert.Equal(t, c.code, res.Code, c.method+" "+c.path)
+	}
+}
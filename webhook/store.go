@@ -0,0 +1,379 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+const (
+	webhookTable  = "yao_webhook"
+	deliveryTable = "yao_webhook_delivery"
+)
+
+// ready the tables are created
+var ready = false
+
+// migrate create the tables of the webhooks and the deliveries
+func migrate() error {
+	sch := capsule.Schema()
+	has, err := sch.HasTable(webhookTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		err = sch.CreateTable(webhookTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.String("url", 1000)
+			table.JSON("events")
+			table.String("secret", 200)
+			table.String("description", 500).Null()
+			table.Boolean("enabled").SetDefault(true).Index()
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()")
+			table.TimestampTz("updated_at").Null()
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the webhook table: %s", webhookTable)
+	}
+
+	has, err = sch.HasTable(deliveryTable)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		err = sch.CreateTable(deliveryTable, func(table schema.Blueprint) {
+			table.ID("id")
+			table.Integer("webhook_id").Index()
+			table.String("event", 200).Index()
+			table.String("event_id", 200).Index()
+			table.String("status", 20).Index()
+			table.Integer("attempts").SetDefault(0)
+			table.Integer("status_code").Null()
+			table.Text("error").Null()
+			table.JSON("payload").Null()
+			table.TimestampTz("created_at").SetDefaultRaw("NOW()").Index()
+			table.TimestampTz("updated_at").Null()
+		})
+		if err != nil {
+			return err
+		}
+		log.Trace("Create the webhook table: %s", deliveryTable)
+	}
+
+	ready = true
+	return nil
+}
+
+func newQuery(table string) query.Query {
+	qb := capsule.Query()
+	qb.Table(table)
+	return qb
+}
+
+// refresh reload the enabled webhooks
+func refresh() error {
+	hooks, err := List()
+	if err != nil {
+		return err
+	}
+
+	enabled := []*Webhook{}
+	for i := range hooks {
+		if hooks[i].Enabled {
+			enabled = append(enabled, &hooks[i])
+		}
+	}
+
+	lock.Lock()
+	endpoints = enabled
+	lock.Unlock()
+	return nil
+}
+
+// List the webhooks
+func List() ([]Webhook, error) {
+	hooks := []Webhook{}
+	if !ready {
+		return hooks, nil
+	}
+
+	rows, err := newQuery(webhookTable).OrderBy("id", "asc").Get()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		hooks = append(hooks, toWebhook(row.ToMap()))
+	}
+	return hooks, nil
+}
+
+// Get the webhook
+func Get(id int64) (*Webhook, error) {
+	if !ready {
+		return nil, fmt.Errorf("the webhook tables are not created")
+	}
+
+	row, err := newQuery(webhookTable).Where("id", id).First()
+	if err != nil {
+		return nil, err
+	}
+
+	if row == nil || len(row.ToMap()) == 0 {
+		return nil, fmt.Errorf("the webhook %d does not exist", id)
+	}
+
+	hook := toWebhook(row.ToMap())
+	return &hook, nil
+}
+
+// Create the webhook, the secret is generated if not given
+func Create(hook Webhook) (*Webhook, error) {
+	if !ready {
+		return nil, fmt.Errorf("the webhook tables are not created")
+	}
+
+	if err := hook.validate(); err != nil {
+		return nil, err
+	}
+
+	if hook.Secret == "" {
+		hook.Secret = secret()
+	}
+
+	events, err := jsoniter.MarshalToString(hook.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	id, err := newQuery(webhookTable).InsertGetID(map[string]interface{}{
+		"url":         hook.URL,
+		"events":      events,
+		"secret":      hook.Secret,
+		"description": hook.Description,
+		"enabled":     hook.Enabled,
+		"created_at":  now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hook.ID = id
+	hook.CreatedAt = now
+	return &hook, refresh()
+}
+
+// Update the fields of the webhook, the url, events, secret, description and enabled
+func Update(id int64, data map[string]interface{}) (*Webhook, error) {
+	hook, err := Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := jsoniter.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = jsoniter.Unmarshal(bytes, hook)
+	if err != nil {
+		return nil, err
+	}
+	hook.ID = id
+
+	if err := hook.validate(); err != nil {
+		return nil, err
+	}
+
+	events, err := jsoniter.MarshalToString(hook.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = newQuery(webhookTable).Where("id", id).Update(map[string]interface{}{
+		"url":         hook.URL,
+		"events":      events,
+		"secret":      hook.Secret,
+		"description": hook.Description,
+		"enabled":     hook.Enabled,
+		"updated_at":  time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hook, refresh()
+}
+
+// Remove the webhook and the deliveries of it
+func Remove(id int64) (int64, error) {
+	if !ready {
+		return 0, nil
+	}
+
+	n, err := newQuery(webhookTable).Where("id", id).Delete()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = newQuery(deliveryTable).Where("webhook_id", id).Delete()
+	if err != nil {
+		return n, err
+	}
+	return n, refresh()
+}
+
+// Deliveries the delivery log of the webhook, the latest first, all of the statuses if the status is empty
+func Deliveries(id int64, status string, page int, pagesize int) (*Page, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pagesize < 1 || pagesize > 100 {
+		pagesize = 20
+	}
+
+	res := &Page{Data: []Delivery{}, Page: page, PageSize: pagesize}
+	if !ready {
+		return res, nil
+	}
+
+	qb := newQuery(deliveryTable).Where("webhook_id", id)
+	if status != "" {
+		qb.Where("status", status)
+	}
+
+	total, err := qb.Clone().Count()
+	if err != nil {
+		return nil, err
+	}
+	res.Total = total
+
+	rows, err := qb.OrderBy("id", "desc").Offset((page - 1) * pagesize).Limit(pagesize).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		res.Data = append(res.Data, toDelivery(row.ToMap()))
+	}
+	return res, nil
+}
+
+// GetDelivery the delivery
+func GetDelivery(id int64) (*Delivery, error) {
+	if !ready {
+		return nil, fmt.Errorf("the webhook tables are not created")
+	}
+
+	row, err := newQuery(deliveryTable).Where("id", id).First()
+	if err != nil {
+		return nil, err
+	}
+
+	if row == nil || len(row.ToMap()) == 0 {
+		return nil, fmt.Errorf("the delivery %d does not exist", id)
+	}
+
+	delivery := toDelivery(row.ToMap())
+	return &delivery, nil
+}
+
+// createDelivery save the pending delivery of the event
+func createDelivery(webhook int64, name string, eventID string, payload string) (*Delivery, error) {
+	if !ready {
+		return nil, fmt.Errorf("the webhook tables are not created")
+	}
+
+	now := time.Now()
+	id, err := newQuery(deliveryTable).InsertGetID(map[string]interface{}{
+		"webhook_id": webhook,
+		"event":      name,
+		"event_id":   eventID,
+		"status":     StatusPending,
+		"attempts":   0,
+		"payload":    payload,
+		"created_at": now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Delivery{ID: id, Webhook: webhook, Event: name, EventID: eventID, Status: StatusPending, Payload: payload, CreatedAt: now}, nil
+}
+
+// saveAttempt save the result of the last attempt of the delivery
+func saveAttempt(delivery *Delivery) error {
+	if !ready {
+		return nil
+	}
+
+	now := time.Now()
+	var code interface{} = nil
+	if delivery.StatusCode > 0 {
+		code = delivery.StatusCode
+	}
+
+	_, err := newQuery(deliveryTable).Where("id", delivery.ID).Update(map[string]interface{}{
+		"status":      delivery.Status,
+		"attempts":    delivery.Attempts,
+		"status_code": code,
+		"error":       delivery.Error,
+		"updated_at":  now,
+	})
+	delivery.UpdatedAt = now
+	return err
+}
+
+func (hook *Webhook) validate() error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("the url %s is invalid, http(s)://host/path is required", hook.URL)
+	}
+
+	if len(hook.Events) == 0 {
+		return fmt.Errorf("the events to deliver are required")
+	}
+	return nil
+}
+
+func toWebhook(row map[string]interface{}) Webhook {
+	hook := Webhook{
+		ID:        toInt64(row["id"]),
+		URL:       toString(row["url"]),
+		Secret:    toString(row["secret"]),
+		Enabled:   toBool(row["enabled"]),
+		CreatedAt: row["created_at"],
+		UpdatedAt: row["updated_at"],
+	}
+	hook.Description = toString(row["description"])
+	if v := toString(row["events"]); v != "" {
+		jsoniter.UnmarshalFromString(v, &hook.Events)
+	}
+	return hook
+}
+
+func toDelivery(row map[string]interface{}) Delivery {
+	return Delivery{
+		ID:         toInt64(row["id"]),
+		Webhook:    toInt64(row["webhook_id"]),
+		Event:      toString(row["event"]),
+		EventID:    toString(row["event_id"]),
+		Status:     toString(row["status"]),
+		Attempts:   int(toInt64(row["attempts"])),
+		StatusCode: int(toInt64(row["status_code"])),
+		Error:      toString(row["error"]),
+		Payload:    toString(row["payload"]),
+		CreatedAt:  row["created_at"],
+		UpdatedAt:  row["updated_at"],
+	}
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The status of the delivery
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// The headers of the delivery, the receiver verifies the signature with the secret of the webhook
+const (
+	HeaderEvent     = "X-Yao-Event"
+	HeaderDelivery  = "X-Yao-Delivery"
+	HeaderTimestamp = "X-Yao-Timestamp"
+	HeaderSignature = "X-Yao-Signature"
+)
+
+// Webhook the endpoint registered for the events, e.g. {"url": "https://example.com/hooks", "events": ["chat.created", "assistant.*"]}
+type Webhook struct {
+	ID          int64       `json:"id"`
+	URL         string      `json:"url"`
+	Events      []string    `json:"events"`
+	Secret      string      `json:"secret,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Enabled     bool        `json:"enabled"`
+	CreatedAt   interface{} `json:"created_at,omitempty"`
+	UpdatedAt   interface{} `json:"updated_at,omitempty"`
+}
+
+// Delivery the delivery of an event to the webhook
+type Delivery struct {
+	ID         int64       `json:"id"`
+	Webhook    int64       `json:"webhook_id"`
+	Event      string      `json:"event"`
+	EventID    string      `json:"event_id"`
+	Status     string      `json:"status"`
+	Attempts   int         `json:"attempts"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Payload    string      `json:"payload,omitempty"`
+	CreatedAt  interface{} `json:"created_at,omitempty"`
+	UpdatedAt  interface{} `json:"updated_at,omitempty"`
+}
+
+// Page the deliveries of the page
+type Page struct {
+	Data     []Delivery `json:"data"`
+	Page     int        `json:"page"`
+	PageSize int        `json:"pagesize"`
+	Total    int64      `json:"total"`
+}
+
+// MaxAttempts the attempts of a delivery, the failed attempts are retried with the backoff
+var MaxAttempts = 5
+
+// Backoff the wait before the first retry, doubled for each of the next retries
+var Backoff = 2 * time.Second
+
+// endpoints the enabled webhooks, refreshed when the webhooks are changed
+var endpoints = []*Webhook{}
+var lock sync.RWMutex
+
+var client = &http.Client{Timeout: 10 * time.Second}
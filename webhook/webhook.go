@@ -0,0 +1,214 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/event"
+)
+
+// subscriber the subscriber id of the event bus, the webhooks subscribe all of the events once
+var subscriber int64 = 0
+
+// Load the webhooks/*.yao receivers, create the tables of the webhooks and the deliveries, and deliver the events to the webhooks
+func Load(cfg config.Config) error {
+	messages := []string{}
+	if err := loadReceivers(); err != nil {
+		messages = append(messages, err.Error())
+	}
+
+	if capsule.Global != nil {
+		if err := loadWebhooks(); err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+	return nil
+}
+
+// loadWebhooks create the tables and subscribe the events of the bus once
+func loadWebhooks() error {
+	err := migrate()
+	if err != nil {
+		return err
+	}
+
+	err = refresh()
+	if err != nil {
+		return err
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if subscriber == 0 {
+		subscriber = event.Subscribe(dispatch, "*")
+	}
+	return nil
+}
+
+// Sign the signature of the payload, sha256=hex(hmac_sha256(secret, "<timestamp>.<payload>"))
+func Sign(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Match the webhook subscribes the event or not
+func (hook *Webhook) Match(name string) bool {
+	for _, pattern := range hook.Events {
+		if event.Match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch deliver the event to the enabled webhooks subscribing it
+func dispatch(ev *event.Event) {
+	lock.RLock()
+	hooks := []*Webhook{}
+	for _, hook := range endpoints {
+		if hook.Match(ev.Name) {
+			hooks = append(hooks, hook)
+		}
+	}
+	lock.RUnlock()
+
+	if len(hooks) == 0 {
+		return
+	}
+
+	payload, err := jsoniter.Marshal(ev)
+	if err != nil {
+		log.Error("[Webhook] %s %s: %s", ev.Name, ev.ID, err.Error())
+		return
+	}
+
+	for _, hook := range hooks {
+		delivery, err := createDelivery(hook.ID, ev.Name, ev.ID, string(payload))
+		if err != nil {
+			log.Error("[Webhook] %d %s %s: %s", hook.ID, ev.Name, ev.ID, err.Error())
+			continue
+		}
+		go Deliver(hook, delivery)
+	}
+}
+
+// Deliver post the payload of the delivery to the webhook, the failed attempts are retried with the backoff.
+// The attempts are saved to the delivery log, returns the delivery of the last attempt.
+func Deliver(hook *Webhook, delivery *Delivery) *Delivery {
+	wait := Backoff
+	for delivery.Attempts < MaxAttempts {
+		delivery.Attempts++
+		code, err := post(hook, delivery)
+		delivery.StatusCode = code
+		delivery.Error = ""
+		delivery.Status = StatusSuccess
+		retry := false
+		if err != nil {
+			delivery.Error = err.Error()
+			delivery.Status = StatusFailed
+			retry = retryable(code)
+		}
+
+		if delivery.Status == StatusFailed && retry && delivery.Attempts < MaxAttempts {
+			delivery.Status = StatusPending
+		}
+
+		if err := saveAttempt(delivery); err != nil {
+			log.Error("[Webhook] %d delivery %d: %s", hook.ID, delivery.ID, err.Error())
+		}
+
+		if delivery.Status != StatusPending {
+			break
+		}
+
+		time.Sleep(wait)
+		wait = wait * 2
+	}
+
+	if delivery.Status == StatusFailed {
+		log.Error("[Webhook] %d delivery %d %s failed after %d attempts: %s", hook.ID, delivery.ID, delivery.Event, delivery.Attempts, delivery.Error)
+	}
+	return delivery
+}
+
+// Redeliver deliver the payload of the delivery again, the attempts are saved as a new delivery
+func Redeliver(id int64) (*Delivery, error) {
+	origin, err := GetDelivery(id)
+	if err != nil {
+		return nil, err
+	}
+
+	hook, err := Get(origin.Webhook)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := createDelivery(hook.ID, origin.Event, origin.EventID, origin.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	go Deliver(hook, delivery)
+	return delivery, nil
+}
+
+// post the payload of the delivery to the webhook, returns the status code of the response
+func post(hook *Webhook, delivery *Delivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Yao-Webhook")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, payload))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("the webhook responds %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// retryable the network errors, the timeouts, the 429 and the 5xx are retried, the other responses are not
+func retryable(code int) bool {
+	return code == 0 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+}
+
+// secret a random secret of the webhook
+func secret() string {
+	buf := make([]byte, 24)
+	rand.Read(buf)
+	return "whsec_" + hex.EncodeToString(buf)
+}
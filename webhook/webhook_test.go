@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	signature := Sign("whsec_test", "1700000000", []byte(`{"name":"chat.created"}`))
+	assert.Equal(t, signature, Sign("whsec_test", "1700000000", []byte(`{"name":"chat.created"}`)))
+	assert.NotEqual(t, signature, Sign("whsec_test", "1700000001", []byte(`{"name":"chat.created"}`)))
+	assert.NotEqual(t, signature, Sign("whsec_other", "1700000000", []byte(`{"name":"chat.created"}`)))
+	assert.Len(t, signature, len("sha256=")+64)
+}
+
+func TestWebhookMatch(t *testing.T) {
+	hook := &Webhook{Events: []string{"chat.created", "assistant.*"}}
+	assert.True(t, hook.Match("chat.created"))
+	assert.True(t, hook.Match("assistant.updated"))
+	assert.False(t, hook.Match("job.failed"))
+}
+
+func TestWebhookValidate(t *testing.T) {
+	assert.Error(t, (&Webhook{URL: "ftp://example.com", Events: []string{"*"}}).validate())
+	assert.Error(t, (&Webhook{URL: "https://", Events: []string{"*"}}).validate())
+	assert.Error(t, (&Webhook{URL: "https://example.com/hooks"}).validate())
+	assert.NoError(t, (&Webhook{URL: "https://example.com/hooks", Events: []string{"*"}}).validate())
+}
+
+func TestDeliver(t *testing.T) {
+	defer func(attempts int, backoff time.Duration) { MaxAttempts, Backoff = attempts, backoff }(MaxAttempts, Backoff)
+	MaxAttempts, Backoff = 3, time.Millisecond
+
+	calls := 0
+	status := []int{500, 429, 200}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "chat.created", r.Header.Get(HeaderEvent))
+		assert.Equal(t, "7", r.Header.Get(HeaderDelivery))
+		assert.Equal(t, Sign("whsec_test", r.Header.Get(HeaderTimestamp), body), r.Header.Get(HeaderSignature))
+		w.WriteHeader(status[calls])
+		calls++
+	}))
+	defer srv.Close()
+
+	hook := &Webhook{ID: 1, URL: srv.URL, Secret: "whsec_test", Events: []string{"*"}}
+	delivery := Deliver(hook, &Delivery{ID: 7, Webhook: 1, Event: "chat.created", Payload: `{"name":"chat.created"}`})
+	assert.Equal(t, StatusSuccess, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, 200, delivery.StatusCode)
+	assert.Empty(t, delivery.Error)
+
+	calls = 0
+	status = []int{500, 500, 500}
+	delivery = Deliver(hook, &Delivery{ID: 7, Webhook: 1, Event: "chat.created", Payload: `{}`})
+	assert.Equal(t, StatusFailed, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, 500, delivery.StatusCode)
+
+	calls = 0
+	status = []int{404}
+	delivery = Deliver(hook, &Delivery{ID: 7, Webhook: 1, Event: "chat.created", Payload: `{}`})
+	assert.Equal(t, StatusFailed, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Contains(t, delivery.Error, "404")
+}
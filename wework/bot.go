@@ -0,0 +1,194 @@
+package wework
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/yao/config"
+	"github.com/yaoapp/yao/neo/assistant"
+	chatctx "github.com/yaoapp/yao/neo/context"
+	"github.com/yaoapp/yao/share"
+)
+
+// Bot the WeCom app of the weworks/*.yao, the messages of the callback are answered by the assistant or the process
+//
+//	{
+//	  "name": "Support",
+//	  "corp_id": "$ENV.WEWORK_CORP_ID",
+//	  "agent_id": 1000002,
+//	  "secret": "$ENV.WEWORK_SECRET",
+//	  "token": "$ENV.WEWORK_TOKEN",
+//	  "aes_key": "$ENV.WEWORK_AES_KEY",
+//	  "assistant": "support"
+//	}
+type Bot struct {
+	ID        string `json:"-"`
+	Name      string `json:"name,omitempty"`
+	CorpID    string `json:"corp_id"`
+	AgentID   int64  `json:"agent_id"`
+	Secret    string `json:"secret"`              // The secret of the app, for the access token of the API
+	Token     string `json:"token"`               // The token of the callback, for the msg_signature
+	AESKey    string `json:"aes_key"`             // The EncodingAESKey of the callback
+	Assistant string `json:"assistant,omitempty"` // The assistant answering the text messages
+	Process   string `json:"process,omitempty"`   // The process called with the message instead of the assistant, returns the reply
+	API       string `json:"api,omitempty"`       // https://qyapi.weixin.qq.com by default
+}
+
+// Message the decrypted message of the callback
+type Message struct {
+	ToUserName   string `xml:"ToUserName" json:"to"`
+	FromUserName string `xml:"FromUserName" json:"from"`
+	CreateTime   int64  `xml:"CreateTime" json:"create_time"`
+	MsgType      string `xml:"MsgType" json:"type"`
+	Content      string `xml:"Content" json:"content,omitempty"`
+	MsgID        string `xml:"MsgId" json:"id,omitempty"`
+	AgentID      int64  `xml:"AgentID" json:"agent_id"`
+	Event        string `xml:"Event" json:"event,omitempty"`
+	EventKey     string `xml:"EventKey" json:"event_key,omitempty"`
+	PicURL       string `xml:"PicUrl" json:"pic_url,omitempty"`
+	MediaID      string `xml:"MediaId" json:"media_id,omitempty"`
+}
+
+// Bots the loaded bots
+var Bots = map[string]*Bot{}
+var lock sync.RWMutex
+
+// Load the weworks/*.yao bots
+func Load(cfg config.Config) error {
+	bots := map[string]*Bot{}
+	messages := []string{}
+
+	exts := []string{"*.yao", "*.json", "*.jsonc"}
+	err := application.App.Walk("weworks", func(root, file string, isdir bool) error {
+		if isdir {
+			return nil
+		}
+
+		bytes, err := application.App.Read(file)
+		if err != nil {
+			messages = append(messages, err.Error())
+			return nil
+		}
+
+		bot := Bot{}
+		err = application.Parse(file, bytes, &bot)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		bot.ID = share.ID(root, file)
+		if err := bot.validate(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", file, err.Error()))
+			return nil
+		}
+
+		bots[bot.ID] = &bot
+		return nil
+	}, exts...)
+
+	lock.Lock()
+	Bots = bots
+	lock.Unlock()
+
+	if len(messages) > 0 {
+		return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+	}
+	return err
+}
+
+// Select the bot
+func Select(id string) (*Bot, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	bot, has := Bots[id]
+	if !has {
+		return nil, fmt.Errorf("the wework bot %s does not exist", id)
+	}
+	return bot, nil
+}
+
+// Answer the reply of the message, empty if the message is not answered
+func (bot *Bot) Answer(msg *Message) (string, error) {
+	if bot.Process != "" {
+		p, err := process.Of(bot.Process, bot.ID, msg.Map())
+		if err != nil {
+			return "", err
+		}
+
+		res, err := p.Exec()
+		if err != nil {
+			return "", err
+		}
+
+		if res == nil {
+			return "", nil
+		}
+		return fmt.Sprintf("%v", res), nil
+	}
+
+	// The assistant answers the text messages only
+	if msg.MsgType != "text" || strings.TrimSpace(msg.Content) == "" {
+		return "", nil
+	}
+
+	ast, err := assistant.Get(bot.Assistant)
+	if err != nil {
+		return "", err
+	}
+
+	// The user chats with the assistant in a single chat of the bot
+	sid := fmt.Sprintf("wework:%s:%s", bot.ID, msg.FromUserName)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	return ast.Reply(chatctx.Context{Context: ctx, Sid: sid, ChatID: sid, AssistantID: ast.ID}, msg.Content)
+}
+
+// Map the message as map, the args of the process
+func (msg *Message) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"to":          msg.ToUserName,
+		"from":        msg.FromUserName,
+		"create_time": msg.CreateTime,
+		"type":        msg.MsgType,
+		"content":     msg.Content,
+		"id":          msg.MsgID,
+		"agent_id":    msg.AgentID,
+		"event":       msg.Event,
+		"event_key":   msg.EventKey,
+		"pic_url":     msg.PicURL,
+		"media_id":    msg.MediaID,
+	}
+}
+
+func (bot *Bot) validate() error {
+	for _, value := range []*string{&bot.CorpID, &bot.Secret, &bot.Token, &bot.AESKey} {
+		if strings.HasPrefix(*value, "$ENV.") {
+			*value = os.Getenv(strings.TrimPrefix(*value, "$ENV."))
+		}
+	}
+
+	if bot.CorpID == "" || bot.Secret == "" || bot.AgentID == 0 {
+		return fmt.Errorf("the corp_id, agent_id and secret are required")
+	}
+
+	if bot.Token == "" || len(bot.AESKey) != 43 {
+		return fmt.Errorf("the token and the aes_key of 43 characters are required")
+	}
+
+	if bot.Assistant == "" && bot.Process == "" {
+		return fmt.Errorf("the assistant or the process is required")
+	}
+
+	if bot.API == "" {
+		bot.API = "https://qyapi.weixin.qq.com"
+	}
+	bot.API = strings.TrimRight(bot.API, "/")
+	return nil
+}
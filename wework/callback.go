@@ -0,0 +1,152 @@
+package wework
+
+import (
+	"encoding/xml"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/kun/log"
+)
+
+// envelope the encrypted message of the callback
+type envelope struct {
+	ToUserName string `xml:"ToUserName"`
+	AgentID    string `xml:"AgentID"`
+	Encrypt    string `xml:"Encrypt"`
+}
+
+// received the ids of the messages received in the last 10 minutes, the retries of WeCom are answered once
+var received = map[string]time.Time{}
+var receivedLock sync.Mutex
+
+// SetRoutes the callback of the bots, the callback url of the bot weworks/support.yao is <path>/support
+//
+//	GET  <path>/:id?msg_signature=&timestamp=&nonce=&echostr=  Verify the callback url
+//	POST <path>/:id?msg_signature=&timestamp=&nonce=           Receive the message, the reply is sent by the API
+func SetRoutes(router *gin.Engine, path string) {
+	router.GET(path+"/:id", handleVerify)
+	router.POST(path+"/:id", handleCallback)
+}
+
+func handleVerify(c *gin.Context) {
+	bot, ok := selectBot(c)
+	if !ok {
+		return
+	}
+
+	echostr := c.Query("echostr")
+	if Signature(bot.Token, c.Query("timestamp"), c.Query("nonce"), echostr) != c.Query("msg_signature") {
+		c.String(401, "the signature is invalid")
+		return
+	}
+
+	res, err := Decrypt(bot.AESKey, echostr, false)
+	if err != nil || res["receiveid"] != bot.CorpID {
+		c.String(400, "the echostr is invalid")
+		return
+	}
+	c.String(200, "%s", res["message"])
+}
+
+func handleCallback(c *gin.Context) {
+	bot, ok := selectBot(c)
+	if !ok {
+		return
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.String(400, err.Error())
+		return
+	}
+
+	env := envelope{}
+	if err := xml.Unmarshal(raw, &env); err != nil || env.Encrypt == "" {
+		c.String(400, "the message is invalid")
+		return
+	}
+
+	if Signature(bot.Token, c.Query("timestamp"), c.Query("nonce"), env.Encrypt) != c.Query("msg_signature") {
+		c.String(401, "the signature is invalid")
+		return
+	}
+
+	res, err := Decrypt(bot.AESKey, env.Encrypt, false)
+	if err != nil || res["receiveid"] != bot.CorpID {
+		c.String(400, "the message is invalid")
+		return
+	}
+
+	msg := &Message{}
+	if err := xml.Unmarshal([]byte(res["message"].(string)), msg); err != nil {
+		c.String(400, "the message is invalid")
+		return
+	}
+
+	// WeCom retries the callback not answered in 5 seconds, the message is answered in the background
+	if !duplicated(msg) {
+		go reply(bot, msg)
+	}
+	c.String(200, "success")
+}
+
+func selectBot(c *gin.Context) (*Bot, bool) {
+	bot, err := Select(c.Param("id"))
+	if err != nil {
+		c.String(404, err.Error())
+		return nil, false
+	}
+	return bot, true
+}
+
+// reply answer the message and send the reply to the user
+func reply(bot *Bot, msg *Message) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error("[WeWork] %s %s: %v", bot.ID, msg.FromUserName, err)
+		}
+	}()
+
+	text, err := bot.Answer(msg)
+	if err != nil {
+		log.Error("[WeWork] %s %s: %s", bot.ID, msg.FromUserName, err.Error())
+		return
+	}
+
+	if text == "" {
+		return
+	}
+
+	err = bot.Send(msg.FromUserName, text)
+	if err != nil {
+		log.Error("[WeWork] %s send to %s: %s", bot.ID, msg.FromUserName, err.Error())
+	}
+}
+
+// duplicated the message is received before or not
+func duplicated(msg *Message) bool {
+	id := msg.MsgID
+	if id == "" {
+		return false
+	}
+
+	receivedLock.Lock()
+	defer receivedLock.Unlock()
+
+	now := time.Now()
+	if at, has := received[id]; has && now.Sub(at) < 10*time.Minute {
+		return true
+	}
+
+	if len(received) > 1000 {
+		for key, at := range received {
+			if now.Sub(at) >= 10*time.Minute {
+				delete(received, key)
+			}
+		}
+	}
+	received[id] = now
+	return false
+}
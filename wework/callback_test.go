@@ -0,0 +1,114 @@
+package wework
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func testBot(api string) *Bot {
+	return &Bot{
+		ID:      "support",
+		CorpID:  "wwe146299c731e6301",
+		AgentID: 1000002,
+		Secret:  "secret",
+		Token:   "token",
+		AESKey:  "RhH75tStMzrH8bMxkTw8BrBfr0ZWULL5himUaRWCs7H",
+		Process: "scripts.wework.Reply",
+		API:     api,
+	}
+}
+
+func TestCallback(t *testing.T) {
+	defer func(bots map[string]*Bot) { Bots = bots }(Bots)
+	bot := testBot("http://127.0.0.1:0")
+	Bots = map[string]*Bot{"support": bot}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetRoutes(router, "/api/__yao/wework")
+
+	// Verify the callback url
+	echostr, _ := Encrypt(bot.AESKey, bot.CorpID, "1616140317555161061")
+	query := url.Values{"timestamp": {"1409659813"}, "nonce": {"nonce"}, "echostr": {echostr}}
+	query.Set("msg_signature", Signature(bot.Token, "1409659813", "nonce", echostr))
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("GET", "/api/__yao/wework/support?"+query.Encode(), nil))
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "1616140317555161061", res.Body.String())
+
+	query.Set("msg_signature", "invalid")
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("GET", "/api/__yao/wework/support?"+query.Encode(), nil))
+	assert.Equal(t, 401, res.Code)
+
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("GET", "/api/__yao/wework/unknown", nil))
+	assert.Equal(t, 404, res.Code)
+
+	// Receive the message
+	encrypted, _ := Encrypt(bot.AESKey, bot.CorpID, `<xml><ToUserName><![CDATA[wwe146299c731e6301]]></ToUserName><FromUserName><![CDATA[max]]></FromUserName><CreateTime>1409659813</CreateTime><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[hello]]></Content><MsgId>4561255354251345929</MsgId><AgentID>1000002</AgentID></xml>`)
+	body := "<xml><ToUserName><![CDATA[wwe146299c731e6301]]></ToUserName><AgentID><![CDATA[1000002]]></AgentID><Encrypt><![CDATA[" + encrypted + "]]></Encrypt></xml>"
+	query = url.Values{"timestamp": {"1409659813"}, "nonce": {"nonce"}}
+	query.Set("msg_signature", Signature(bot.Token, "1409659813", "nonce", encrypted))
+
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("POST", "/api/__yao/wework/support?"+query.Encode(), strings.NewReader(body)))
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, "success", res.Body.String())
+
+	query.Set("msg_signature", "invalid")
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("POST", "/api/__yao/wework/support?"+query.Encode(), strings.NewReader(body)))
+	assert.Equal(t, 401, res.Code)
+}
+
+func TestDuplicated(t *testing.T) {
+	msg := &Message{MsgID: "duplicated-4561255354251345929"}
+	assert.False(t, duplicated(msg))
+	assert.True(t, duplicated(msg))
+	assert.False(t, duplicated(&Message{}))
+}
+
+func TestSend(t *testing.T) {
+	tokens = map[string]accessToken{}
+	issued := 0
+	sent := []map[string]interface{}{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/gettoken":
+			assert.Equal(t, "secret", r.URL.Query().Get("corpsecret"))
+			issued++
+			w.Write([]byte(`{"errcode":0,"access_token":"token-` + string(rune('0'+issued)) + `","expires_in":7200}`))
+
+		case "/cgi-bin/message/send":
+			// The first token is expired
+			if r.URL.Query().Get("access_token") == "token-1" {
+				w.Write([]byte(`{"errcode":42001,"errmsg":"access_token expired"}`))
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			message := map[string]interface{}{}
+			jsoniter.Unmarshal(body, &message)
+			sent = append(sent, message)
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}
+	}))
+	defer srv.Close()
+
+	bot := testBot(srv.URL)
+	err := bot.Send("max", strings.Repeat("a", maxTextBytes)+"b")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, issued)
+	assert.Len(t, sent, 2)
+	assert.Equal(t, "max", sent[0]["touser"])
+	assert.Equal(t, float64(1000002), sent[0]["agentid"])
+	assert.Equal(t, "b", sent[1]["text"].(map[string]interface{})["content"])
+}
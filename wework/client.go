@@ -0,0 +1,146 @@
+package wework
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// maxTextBytes the max bytes of a text message, the longer replies are sent in parts
+const maxTextBytes = 2000
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// tokens the access tokens of the bots
+var tokens = map[string]accessToken{}
+var tokenLock sync.Mutex
+
+type accessToken struct {
+	value   string
+	expires time.Time
+}
+
+type apiResponse struct {
+	ErrCode     int    `json:"errcode"`
+	ErrMsg      string `json:"errmsg"`
+	AccessToken string `json:"access_token,omitempty"`
+	ExpiresIn   int    `json:"expires_in,omitempty"`
+}
+
+// Send the text message to the user by the app of the bot, the text longer than 2000 bytes is sent in parts
+func (bot *Bot) Send(user string, text string) error {
+	for _, part := range split(text, maxTextBytes) {
+		err := bot.send(map[string]interface{}{
+			"touser":  user,
+			"msgtype": "text",
+			"agentid": bot.AgentID,
+			"text":    map[string]interface{}{"content": part},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send the message, the access token is refreshed once if it is expired
+func (bot *Bot) send(message map[string]interface{}) error {
+	payload, err := jsoniter.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	for retry := 0; ; retry++ {
+		token, err := bot.AccessToken(retry > 0)
+		if err != nil {
+			return err
+		}
+
+		res := apiResponse{}
+		err = bot.call(http.MethodPost, "/cgi-bin/message/send?access_token="+url.QueryEscape(token), payload, &res)
+		if err != nil {
+			return err
+		}
+
+		switch res.ErrCode {
+		case 0:
+			return nil
+		case 40001, 40014, 42001: // The access token is invalid or expired
+			if retry == 0 {
+				continue
+			}
+		}
+		return fmt.Errorf("wework message/send %d: %s", res.ErrCode, res.ErrMsg)
+	}
+}
+
+// AccessToken the access token of the app, cached until 5 minutes before it expires
+func (bot *Bot) AccessToken(refresh bool) (string, error) {
+	tokenLock.Lock()
+	defer tokenLock.Unlock()
+
+	key := bot.CorpID + ":" + fmt.Sprintf("%d", bot.AgentID)
+	if token, has := tokens[key]; has && !refresh && time.Now().Before(token.expires) {
+		return token.value, nil
+	}
+
+	res := apiResponse{}
+	err := bot.call(http.MethodGet, "/cgi-bin/gettoken?corpid="+url.QueryEscape(bot.CorpID)+"&corpsecret="+url.QueryEscape(bot.Secret), nil, &res)
+	if err != nil {
+		return "", err
+	}
+
+	if res.ErrCode != 0 || res.AccessToken == "" {
+		return "", fmt.Errorf("wework gettoken %d: %s", res.ErrCode, res.ErrMsg)
+	}
+
+	expires := time.Duration(res.ExpiresIn)*time.Second - 5*time.Minute
+	tokens[key] = accessToken{value: res.AccessToken, expires: time.Now().Add(expires)}
+	return res.AccessToken, nil
+}
+
+func (bot *Bot) call(method string, path string, payload []byte, res interface{}) error {
+	req, err := http.NewRequest(method, bot.API+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		api, _, _ := strings.Cut(path, "?")
+		return fmt.Errorf("wework %s responds %d", api, resp.StatusCode)
+	}
+	return jsoniter.NewDecoder(resp.Body).Decode(res)
+}
+
+// split the text into the parts of the max bytes, the runes are not broken
+func split(text string, max int) []string {
+	parts := []string{}
+	for len(text) > max {
+		n := max
+		for n > 0 && !utf8.RuneStart(text[n]) {
+			n--
+		}
+		parts = append(parts, text[:n])
+		text = text[n:]
+	}
+	if text != "" {
+		parts = append(parts, text)
+	}
+	return parts
+}
@@ -8,6 +8,8 @@ import (
 func init() {
 	process.RegisterGroup("yao.wework", map[string]process.Handler{
 		"decrypt": processDecrypt,
+		"encrypt": processEncrypt,
+		"send":    processSend,
 	})
 }
 
@@ -29,3 +31,28 @@ func processDecrypt(process *process.Process) interface{} {
 
 	return res
 }
+
+// processEncrypt yao.wework.Encrypt(encodingAESKey, receiveid, message)
+func processEncrypt(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	res, err := Encrypt(process.ArgsString(0), process.ArgsString(1), process.ArgsString(2))
+	if err != nil {
+		exception.New("error: %s", 400, err).Throw()
+	}
+	return res
+}
+
+// processSend yao.wework.Send(bot, user, text), send the text message to the user by the app of the bot
+func processSend(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	bot, err := Select(process.ArgsString(0))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+
+	err = bot.Send(process.ArgsString(1), process.ArgsString(2))
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return nil
+}
@@ -4,8 +4,13 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -28,12 +33,19 @@ func Decrypt(encodingAESKey string, msgEncrypt string, parse bool) (map[string]i
 		return nil, err
 	}
 
+	if len(randMsg) < 20 {
+		return nil, fmt.Errorf("the message is too short")
+	}
+
 	content := randMsg[16:]
 	buf := bytes.NewBuffer(content[0:4])
-	var len int32
-	binary.Read(buf, binary.BigEndian, &len)
-	msg := content[4 : len+4]
-	receiveid := content[len+4:]
+	var size int32
+	binary.Read(buf, binary.BigEndian, &size)
+	if size < 0 || int(size)+4 > len(content) {
+		return nil, fmt.Errorf("the length of the message is invalid")
+	}
+	msg := content[4 : size+4]
+	receiveid := content[size+4:]
 
 	data := map[string]interface{}{}
 	if parse {
@@ -50,6 +62,39 @@ func Decrypt(encodingAESKey string, msgEncrypt string, parse bool) (map[string]i
 	}, nil
 }
 
+// Encrypt wework msg Encrypt, the reverse of the Decrypt
+func Encrypt(encodingAESKey string, receiveid string, msg string) (string, error) {
+	aesKey, err := base64.StdEncoding.DecodeString(encodingAESKey + "=")
+	if err != nil {
+		return "", err
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(random)
+	binary.Write(&buf, binary.BigEndian, int32(len(msg)))
+	buf.WriteString(msg)
+	buf.WriteString(receiveid)
+
+	crypted, err := aesEncrypt(buf.Bytes(), aesKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(crypted), nil
+}
+
+// Signature the msg_signature of the callback, sha1 of the sorted token, timestamp, nonce and the encrypted message
+func Signature(token string, timestamp string, nonce string, msgEncrypt string) string {
+	values := []string{token, timestamp, nonce, msgEncrypt}
+	sort.Strings(values)
+	sum := sha1.Sum([]byte(strings.Join(values, "")))
+	return hex.EncodeToString(sum[:])
+}
+
 func parseXML(data string) (map[string]interface{}, error) {
 
 	decoder := NewDecoder(strings.NewReader(data))
@@ -67,15 +112,41 @@ func aesDecrypt(crypted, key []byte) ([]byte, error) {
 	}
 
 	blockSize := block.BlockSize()
+	if len(crypted) == 0 || len(crypted)%blockSize != 0 {
+		return nil, fmt.Errorf("the size of the encrypted message is invalid")
+	}
+
 	blockMode := cipher.NewCBCDecrypter(block, key[:blockSize])
 	origData := make([]byte, len(crypted))
 	blockMode.CryptBlocks(origData, crypted)
-	origData = pckS5UnPadding(origData)
-	return origData, nil
+	return pckS5UnPadding(origData)
+}
+
+func aesEncrypt(origData, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	blockSize := block.BlockSize()
+	origData = pckS7Padding(origData, len(key))
+	blockMode := cipher.NewCBCEncrypter(block, key[:blockSize])
+	crypted := make([]byte, len(origData))
+	blockMode.CryptBlocks(crypted, origData)
+	return crypted, nil
 }
 
-func pckS5UnPadding(origData []byte) []byte {
+func pckS5UnPadding(origData []byte) ([]byte, error) {
 	length := len(origData)
 	unpadding := int(origData[length-1])
-	return origData[:(length - unpadding)]
+	if unpadding < 1 || unpadding > length {
+		return nil, fmt.Errorf("the padding of the message is invalid")
+	}
+	return origData[:(length - unpadding)], nil
+}
+
+// pckS7Padding pad to the multiple of the size, the WeCom messages are padded to 32 bytes
+func pckS7Padding(origData []byte, size int) []byte {
+	padding := size - len(origData)%size
+	return append(origData, bytes.Repeat([]byte{byte(padding)}, padding)...)
 }
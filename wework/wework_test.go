@@ -59,3 +59,34 @@ func TestWeworkParseXML(t *testing.T) {
 	assert.Equal(t, "218", res.Get("xml.AgentID"))
 	assert.Equal(t, "111", res.Get("xml.Nest.Id"))
 }
+
+func TestWeworkEncrypt(t *testing.T) {
+	encodingAESKey := "RhH75tStMzrH8bMxkTw8BrBfr0ZWULL5himUaRWCs7H"
+	msgEncrypt, err := Encrypt(encodingAESKey, "wwe146299c731e6301", "<xml><Content>你好</Content></xml>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Decrypt(encodingAESKey, msgEncrypt, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "<xml><Content>你好</Content></xml>", res["message"])
+	assert.Equal(t, "wwe146299c731e6301", res["receiveid"])
+
+	_, err = Decrypt(encodingAESKey, "bWVzc2FnZQ==", false)
+	assert.Error(t, err)
+}
+
+func TestWeworkSignature(t *testing.T) {
+	signature := Signature("token", "1409659813", "nonce", "encrypted")
+	assert.Len(t, signature, 40)
+	assert.Equal(t, signature, Signature("token", "1409659813", "nonce", "encrypted"))
+	assert.NotEqual(t, signature, Signature("token", "1409659814", "nonce", "encrypted"))
+}
+
+func TestWeworkSplit(t *testing.T) {
+	assert.Equal(t, []string{"hello"}, split("hello", 10))
+	assert.Equal(t, []string{}, split("", 10))
+	assert.Equal(t, []string{"你好", "世界"}, split("你好世界", 7))
+}
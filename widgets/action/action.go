@@ -12,5 +12,10 @@ func ProcessOf(p *Process) *Process {
 	if p == nil {
 		p = NewProcess()
 	}
+
+	// The generations of the models are written to the store of the cache since the widget is loaded
+	if p.Cache != nil && p.Cache.Store != "" {
+		watch(p.Cache.Store)
+	}
 	return p
 }
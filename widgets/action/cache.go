@@ -0,0 +1,270 @@
+package action
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	_ "github.com/yaoapp/gou/model" // The model processes are registered before they are watched
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/yao/cache"
+	"github.com/yaoapp/yao/model"
+)
+
+// Cache the response cache of the action, the responses are cached by the widget and the args of the action.
+// The responses are cached by the session unless the session fields are set, and by the row-level policies of the models.
+//
+//	"action": {
+//	  "data": { "cache": { "ttl": 300, "models": ["order", "order.item"], "session": ["team_id"], "store": "cache" } }
+//	}
+type Cache struct {
+	TTL     int      `json:"ttl,omitempty"`     // The seconds of the response cached, 60 by default
+	Store   string   `json:"store,omitempty"`   // The store of the responses, the cache of the application by default
+	Models  []string `json:"models,omitempty"`  // The responses are invalidated when the models are written
+	Session []string `json:"session,omitempty"` // The session fields of the cache key instead of the session, e.g. the responses of the teams
+}
+
+// kv the store of the responses and the generations of the models
+type kv interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}, ttl time.Duration) error
+	Del(key string) error
+}
+
+// writes the model processes invalidating the responses
+var writes = []string{
+	"save", "create", "insert", "update", "updatewhere", "delete", "deletewhere",
+	"destroy", "destroywhere", "eachsave", "eachsaveafterdelete", "upsert",
+}
+
+// generations the stores of the caches, the generations of the models written are set to the stores
+var generations = struct {
+	sync.RWMutex
+	stores map[string]bool
+}{stores: map[string]bool{}}
+
+func init() {
+	for _, method := range writes {
+		name := "models." + method
+		if handler, has := process.Handlers[name]; has {
+			process.Handlers[name] = invalidator(handler)
+		}
+	}
+}
+
+// invalidator invalidate the responses of the model after the write
+func invalidator(handler process.Handler) process.Handler {
+	return func(p *process.Process) interface{} {
+		defer Invalidate(p.ID)
+		return handler(p)
+	}
+}
+
+// Invalidate the cached responses of the model, the responses are not removed but never hit again
+func Invalidate(model string) {
+	model = strings.ToLower(model)
+	gen := time.Now().UnixNano()
+	if err := cache.Flush(modelTag(model)); err != nil {
+		log.Error("[Cache] invalidate the model %s: %s", model, err.Error())
+	}
+
+	generations.Lock()
+	stores := []string{}
+	for name := range generations.stores {
+		stores = append(stores, name)
+	}
+	generations.Unlock()
+
+	for _, name := range stores {
+		if pool, has := store.Pools[name]; has {
+			if err := pool.Set(generationKey(model), gen, 0); err != nil {
+				log.Error("[Cache] invalidate the model %s of the store %s: %s", model, name, err.Error())
+			}
+		}
+	}
+}
+
+// cached execute the handler of the action, the cached response is returned if it is not expired or invalidated
+func (p *Process) cached(process *process.Process) (interface{}, error) {
+	pool, err := p.Cache.store()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := p.cacheKey(pool, process)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, has := pool.Get(key); has {
+		var res interface{}
+		if err := unmarshal(value, &res); err == nil {
+			return res, nil
+		}
+	}
+
+	res, err := p.Handler(p, process)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := jsoniter.MarshalToString(res)
+	if err != nil {
+		log.Error("[Cache] %s %s: %s", p.Name, key, err.Error())
+		return res, nil
+	}
+
+	if err := pool.Set(key, raw, p.Cache.ttl()); err != nil {
+		log.Error("[Cache] %s %s: %s", p.Name, key, err.Error())
+	}
+	return res, nil
+}
+
+// cacheKey the key of the response, the hash of the widget, the args, the session or the session fields,
+// the wheres of the policies and the generations of the models
+func (p *Process) cacheKey(pool kv, process *process.Process) (key string, err error) {
+	parts := []interface{}{p.Name, process.Args[0], p.Args(process)}
+	if len(p.Cache.Session) == 0 {
+		parts = append(parts, process.Sid)
+	}
+
+	// The rows of the models of the policies are the rows of the session
+	defer func() {
+		if r := recover(); r != nil {
+			err = exception.Catch(r)
+		}
+	}()
+	for _, name := range p.Cache.Models {
+		parts = append(parts, model.PolicyWheres(strings.ToLower(name), process.Sid))
+	}
+
+	for _, field := range p.Cache.Session {
+		var value interface{}
+		if process.Sid != "" {
+			v, err := session.Global().ID(process.Sid).Get(field)
+			if err != nil {
+				return "", err
+			}
+			value = v
+		}
+		parts = append(parts, value)
+	}
+
+	// The responses of the cache of the application are invalidated by the tags of the models
+	if p.Cache.Store != "" {
+		for _, name := range p.Cache.Models {
+			parts = append(parts, p.Cache.generation(pool, strings.ToLower(name)))
+		}
+	}
+
+	raw, err := jsoniter.Marshal(parts)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(raw)
+	return "yao:widget:cache:" + hex.EncodeToString(sum[:]), nil
+}
+
+// generation the time of the last write of the model
+func (c *Cache) generation(pool kv, model string) int64 {
+	if value, has := pool.Get(generationKey(model)); has {
+		var gen int64
+		if err := unmarshal(value, &gen); err == nil {
+			return gen
+		}
+	}
+	return 0
+}
+
+func (c *Cache) store() (kv, error) {
+	if c.Store == "" {
+		tags := []string{}
+		for _, model := range c.Models {
+			tags = append(tags, modelTag(strings.ToLower(model)))
+		}
+		return &tagged{tags: tags}, nil
+	}
+
+	pool, has := store.Pools[c.Store]
+	if !has {
+		return nil, fmt.Errorf("the store %s of the cache is not found", c.Store)
+	}
+
+	watch(c.Store)
+	return pool, nil
+}
+
+// watch the store, the generations of the models written are set to the store
+func watch(name string) {
+	generations.Lock()
+	generations.stores[name] = true
+	generations.Unlock()
+}
+
+func (c *Cache) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(c.TTL) * time.Second
+}
+
+func generationKey(model string) string {
+	return "yao:widget:cache:model:" + model
+}
+
+// modelTag the tag of the responses of the model in the cache of the application
+func modelTag(model string) string {
+	return "widget:model:" + model
+}
+
+// unmarshal the value of the store, the stores keep the values as is or as the json
+func unmarshal(value interface{}, v interface{}) error {
+	switch data := value.(type) {
+	case string:
+		return jsoniter.UnmarshalFromString(data, v)
+	case []byte:
+		return jsoniter.Unmarshal(data, v)
+	}
+
+	raw, err := jsoniter.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return jsoniter.Unmarshal(raw, v)
+}
+
+// tagged the cache of the application, the responses are tagged by the models
+type tagged struct {
+	tags []string
+}
+
+func (t *tagged) Get(key string) (interface{}, bool) {
+	var value string
+	has, err := cache.Get(t.key(key), &value)
+	if err != nil {
+		log.Error("[Cache] %s: %s", key, err.Error())
+	}
+	return value, has
+}
+
+func (t *tagged) Set(key string, value interface{}, ttl time.Duration) error {
+	return cache.Set(t.key(key), value, ttl, t.tags...)
+}
+
+func (t *tagged) Del(key string) error {
+	return cache.Del(t.key(key))
+}
+
+// key the key of the cache of the application, the keys are prefixed by the cache
+func (t *tagged) key(key string) string {
+	return strings.TrimPrefix(key, "yao:")
+}
@@ -0,0 +1,62 @@
+package action
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestCache(t *testing.T) {
+	calls := 0
+	p := &Process{
+		Name:    "yao.chart.Data",
+		Default: []interface{}{nil},
+		Cache:   &Cache{TTL: 60, Models: []string{"Order"}},
+		Handler: func(p *Process, process *process.Process) (interface{}, error) {
+			calls++
+			return map[string]interface{}{"total": calls}, nil
+		},
+	}
+
+	run := func(args ...interface{}) interface{} {
+		res, err := p.Exec(&process.Process{Args: args})
+		assert.NoError(t, err)
+		return res
+	}
+
+	assert.Equal(t, map[string]interface{}{"total": 1}, run("dashboard", map[string]interface{}{"range": "7d"}))
+	assert.Equal(t, map[string]interface{}{"total": float64(1)}, run("dashboard", map[string]interface{}{"range": "7d"}))
+	assert.Equal(t, 1, calls)
+
+	// The responses are cached by the args and the widget
+	run("dashboard", map[string]interface{}{"range": "30d"})
+	run("sales", map[string]interface{}{"range": "7d"})
+	assert.Equal(t, 3, calls)
+
+	// The responses are cached by the session without the session fields
+	res, err := p.Exec(&process.Process{Args: []interface{}{"dashboard", map[string]interface{}{"range": "7d"}}, Sid: "__unit_test_cache"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"total": 4}, res)
+	assert.Equal(t, 4, calls)
+
+	// The responses are invalidated when the model is written
+	Invalidate("order")
+	run("dashboard", map[string]interface{}{"range": "7d"})
+	assert.Equal(t, 5, calls)
+
+	// The actions without the cache are executed every time
+	p.Cache = nil
+	run("dashboard", map[string]interface{}{"range": "7d"})
+	assert.Equal(t, 6, calls)
+
+	p.Cache = &Cache{Store: "not-found"}
+	_, err = p.Exec(&process.Process{Args: []interface{}{"dashboard"}})
+	assert.Error(t, err)
+}
+
+func TestCacheTTL(t *testing.T) {
+	assert.Equal(t, 60*time.Second, (&Cache{}).ttl())
+	assert.Equal(t, 300*time.Second, (&Cache{TTL: 300}).ttl())
+}
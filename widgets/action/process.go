@@ -126,6 +126,10 @@ func (p *Process) Exec(process *process.Process) (interface{}, error) {
 	if p.Handler == nil {
 		return nil, fmt.Errorf("%s handler does not set", p.Name)
 	}
+
+	if p.Cache != nil {
+		return p.cached(process)
+	}
 	return p.Handler(p, process)
 }
 
@@ -13,6 +13,7 @@ type Process struct {
 	Guard       string        `json:"guard,omitempty"`
 	Default     []interface{} `json:"default,omitempty"`
 	Disable     bool          `json:"disable,omitempty"`
+	Cache       *Cache        `json:"cache,omitempty"`
 	Before      *hook.Before  `json:"-"`
 	After       *hook.After   `json:"-"`
 	Handler     Handler       `json:"-"`
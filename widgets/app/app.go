@@ -20,6 +20,7 @@ import (
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/data"
 	"github.com/yaoapp/yao/i18n"
+	"github.com/yaoapp/yao/service/fs"
 	"github.com/yaoapp/yao/share"
 	"github.com/yaoapp/yao/widgets/login"
 )
@@ -37,6 +38,7 @@ import (
 // 	 yao.app.Setting Return the App DSL
 // 	 yao.app.Xgen Return the Xgen setting ( merge app & login )
 //   yao.app.Menu Return the menu list
+//   yao.app.Asset Return the URL of the public file, e.g. /js/app.js => https://cdn.example.com/js/app.3f2a1b9c0d.js
 //
 
 // Setting the application setting
@@ -251,6 +253,12 @@ func exportProcess() {
 	process.Register("yao.app.setup", processSetup)
 	process.Register("yao.app.check", processCheck)
 	process.Register("yao.app.service", processService)
+	process.Register("yao.app.asset", processAsset)
+}
+
+func processAsset(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	return fs.AssetURL(process.ArgsString(0))
 }
 
 func processService(process *process.Process) interface{} {
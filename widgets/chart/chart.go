@@ -107,6 +107,12 @@ func (dsl *DSL) parse(id string, root string) error {
 	}
 	dsl.Action.SetDefaultProcess()
 
+	// The data of the query is pushed down to ClickHouse, yao.chart.Query (:params, :chart)
+	if dsl.Query != nil && dsl.Action.Data.Process == "" {
+		dsl.Action.Data.Process = "yao.chart.Query"
+		dsl.Action.Data.Default = []interface{}{nil, id}
+	}
+
 	if dsl.Layout == nil {
 		dsl.Layout = &LayoutDSL{}
 	}
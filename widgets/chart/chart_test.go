@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/yao/clickhouse"
 	"github.com/yaoapp/yao/config"
 	"github.com/yaoapp/yao/i18n"
 	"github.com/yaoapp/yao/test"
@@ -37,3 +38,28 @@ func prepare(t *testing.T, language ...string) {
 		t.Fatal(err)
 	}
 }
+
+func TestQuery(t *testing.T) {
+	query := &QueryDSL{
+		Model:      "event",
+		Dimensions: map[string]string{"date": "day(created_at)"},
+		Metrics:    map[string]string{"events": "count()"},
+		Filters:    map[string]string{"team": "team", "from": "created_at >=", "teams": "team in"},
+		Orders:     []string{"-events"},
+	}
+	assert.Nil(t, query.validate())
+	assert.Equal(t, "data", query.Bind)
+
+	agg, err := query.aggregate(map[string]interface{}{"from": "2024-01-01", "team": "", "teams": "1,2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.ElementsMatch(t, []clickhouse.Where{
+		{Column: "created_at", OP: "ge", Value: "2024-01-01"},
+		{Column: "team", OP: "in", Value: "1,2"},
+	}, agg.Wheres)
+	assert.Equal(t, []clickhouse.Order{{Column: "events", Desc: true}}, agg.Orders)
+
+	query.Filters["bad"] = "team; DROP"
+	assert.Error(t, query.validate())
+}
@@ -15,6 +15,7 @@ func exportProcess() {
 	process.Register("yao.chart.xgen", processXgen)
 	process.Register("yao.chart.component", processComponent)
 	process.Register("yao.chart.data", processData)
+	process.Register("yao.chart.query", processQuery)
 }
 
 func processXgen(process *process.Process) interface{} {
@@ -0,0 +1,117 @@
+package chart
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/yao/clickhouse"
+)
+
+// QueryDSL the aggregation of the ClickHouse model pushed down to ClickHouse, the data of the chart if the data process is not set.
+// The filters are the params of the data and the conditions of the columns, <column> [op].
+//
+//	"query": {
+//	  "model": "event",
+//	  "dimensions": { "date": "day(created_at)" },
+//	  "metrics": { "events": "count()", "users": "uniq(uid)" },
+//	  "filters": { "team": "team", "from": "created_at >=", "to": "created_at <" },
+//	  "orders": ["date"],
+//	  "limit": 366,
+//	  "bind": "events"
+//	}
+type QueryDSL struct {
+	Model      string            `json:"model"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	Metrics    map[string]string `json:"metrics"`
+	Filters    map[string]string `json:"filters,omitempty"`
+	Orders     []string          `json:"orders,omitempty"` // The aliases, -<alias> is descending
+	Limit      int               `json:"limit,omitempty"`
+	Bind       string            `json:"bind,omitempty"` // The key of the rows of the data, data by default
+}
+
+var filterRe = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=|!=|<>|>=|<=|>|<|\bin\b|\blike\b)?\s*$`)
+
+var filterOps = map[string]string{"": "eq", "=": "eq", "!=": "ne", "<>": "ne", ">": "gt", ">=": "ge", "<": "lt", "<=": "le", "in": "in", "like": "match"}
+
+// aggregate the aggregation of the params, the filters without the values are ignored
+func (query *QueryDSL) aggregate(params map[string]interface{}) (clickhouse.Aggregate, error) {
+	agg := clickhouse.Aggregate{Dimensions: query.Dimensions, Metrics: query.Metrics, Limit: query.Limit}
+	for name, filter := range query.Filters {
+		value, has := params[name]
+		if !has || value == nil || value == "" {
+			continue
+		}
+
+		matches := filterRe.FindStringSubmatch(filter)
+		if matches == nil {
+			return agg, fmt.Errorf("the filter %s %s is invalid, <column> [op]", name, filter)
+		}
+		agg.Wheres = append(agg.Wheres, clickhouse.Where{Column: matches[1], OP: filterOps[strings.ToLower(matches[2])], Value: value})
+	}
+
+	for _, order := range query.Orders {
+		agg.Orders = append(agg.Orders, clickhouse.Order{Column: strings.TrimPrefix(order, "-"), Desc: strings.HasPrefix(order, "-")})
+	}
+	return agg, nil
+}
+
+// validate the model is a ClickHouse model and the filters are valid
+func (query *QueryDSL) validate() error {
+	if query.Model == "" {
+		return fmt.Errorf("query.model is required")
+	}
+
+	if len(query.Metrics) == 0 {
+		return fmt.Errorf("query.metrics is required")
+	}
+
+	if query.Bind == "" {
+		query.Bind = "data"
+	}
+
+	for name, filter := range query.Filters {
+		if !filterRe.MatchString(filter) {
+			return fmt.Errorf("query.filters.%s %s is invalid, <column> [op]", name, filter)
+		}
+	}
+	return nil
+}
+
+// processQuery yao.chart.Query (:params, :chart), the data of the query of the chart
+func processQuery(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	chart, err := Get(process.ArgsString(1))
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+
+	if chart.Query == nil {
+		exception.New("the chart %s has no query", 400, chart.ID).Throw()
+	}
+
+	table, has := clickhouse.Model(chart.Query.Model)
+	if !has {
+		exception.New("the chart %s query.model %s is not a ClickHouse model", 400, chart.ID, chart.Query.Model).Throw()
+	}
+
+	params := map[string]interface{}{}
+	if process.Args[0] != nil {
+		params = any.Of(process.Args[0]).MapStr()
+	}
+
+	agg, err := chart.Query.aggregate(params)
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+
+	rows, err := table.Aggregate(context.Background(), agg)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return map[string]interface{}{chart.Query.Bind: rows}
+}
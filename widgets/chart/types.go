@@ -16,6 +16,7 @@ type DSL struct {
 	Action *ActionDSL             `json:"action"`
 	Layout *LayoutDSL             `json:"layout"`
 	Fields *FieldsDSL             `json:"fields"`
+	Query  *QueryDSL              `json:"query,omitempty"`
 	Config map[string]interface{} `json:"config,omitempty"`
 	CProps field.CloudProps       `json:"-"`
 	compute.Computable
@@ -1,6 +1,13 @@
 package chart
 
+import "fmt"
+
 // Validate table
 func (dsl *DSL) Validate() error {
+	if dsl.Query != nil {
+		if err := dsl.Query.validate(); err != nil {
+			return fmt.Errorf("[chart] %s %s", dsl.ID, err.Error())
+		}
+	}
 	return nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/yaoapp/gou/query"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/utils"
+	yaoquery "github.com/yaoapp/yao/query"
 )
 
 var varRe = regexp.MustCompile(`\[\[\s*\$([A-Za-z0-9_\-]+)\s*\]\]`)
@@ -79,7 +80,8 @@ func processGetOptions(process *process.Process) interface{} {
 			utils.Dump(p.dsl)
 		}
 
-		qb, err := engine.Load(p.dsl)
+		// The rows of the models of the policies are the rows of the session
+		qb, err := engine.Load(yaoquery.Scoped(process.Sid, p.dsl))
 		if err != nil {
 			exception.New(err.Error(), 400).Throw()
 		}
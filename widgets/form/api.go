@@ -57,7 +57,7 @@ func (form *DSL) getAction(path string) (*action.Process, error) {
 		return form.Action.Download, nil
 	case "/api/__yao/form/:id/find/:primary":
 		return form.Action.Find, nil
-	case "/api/__yao/form/:id/save":
+	case "/api/__yao/form/:id/save", "/api/__yao/form/:id/step/:step":
 		return form.Action.Save, nil
 	case "/api/__yao/form/:id/create":
 		return form.Action.Create, nil
@@ -170,6 +170,18 @@ func exportAPI() error {
 	}
 	http.Paths = append(http.Paths, path)
 
+	//  POST  /api/__yao/form/:id/step/:step  				-> Default process: yao.form.Step $param.id $param.step :payload
+	path = api.Path{
+		Label:       "Step",
+		Description: "Validate the data of the step of the wizard",
+		Path:        "/:id/step/:step",
+		Method:      "POST",
+		Process:     "yao.form.Step",
+		In:          []interface{}{"$param.id", "$param.step", ":payload"},
+		Out:         api.Out{Status: 200, Type: "application/json"},
+	}
+	http.Paths = append(http.Paths, path)
+
 	//  POST  /api/__yao/form/:id/create  						-> Default process: yao.form.Create $param.id :payload
 	path = api.Path{
 		Label:       "Create",
@@ -259,12 +259,17 @@ func (layout *LayoutDSL) Xgen(data map[string]interface{}, excludes map[string]b
 		clone.Form.Sections = sections
 	}
 
+	// layout.form.steps and the visibility of the sections
+	if clone.Form != nil {
+		clone.Form.wizard(data)
+	}
+
 	return clone, nil
 }
 
 // Filter exclude filter
 func (section SectionDSL) Filter(excludes map[string]bool, mapping *mapping.Mapping) (SectionDSL, error) {
-	new := SectionDSL{Columns: []Column{}, Title: section.Title, Desc: section.Desc, Icon: section.Icon, Weight: section.Weight, Color: section.Color}
+	new := SectionDSL{Columns: []Column{}, Title: section.Title, Desc: section.Desc, Icon: section.Icon, Weight: section.Weight, Color: section.Color, Step: section.Step, Visible: section.Visible, Hidden: section.Hidden}
 	columns, err := section.filterColumns(section.Columns, excludes, mapping)
 	if err != nil {
 		return new, err
@@ -28,6 +28,7 @@ func exportProcess() {
 	gouProcess.Register("yao.form.create", processCreate)
 	gouProcess.Register("yao.form.update", processUpdate)
 	gouProcess.Register("yao.form.delete", processDelete)
+	gouProcess.Register("yao.form.step", processStep)
 	gouProcess.Register("yao.form.load", processLoad)
 	gouProcess.Register("yao.form.reload", processReload)
 	gouProcess.Register("yao.form.unload", processUnload)
@@ -168,6 +169,18 @@ func processSetting(process *gouProcess.Process) interface{} {
 	return form.Action.Setting.MustExec(process)
 }
 
+// processStep yao.form.Step (:form, :step, :payload), validate the data of the step of the wizard
+func processStep(process *gouProcess.Process) interface{} {
+	process.ValidateArgNums(2)
+	form := MustGet(process) // 0
+	data := process.ArgsMap(2, map[string]interface{}{})
+	res, err := form.Step(process, process.ArgsString(1), data)
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return res
+}
+
 func processSave(process *gouProcess.Process) interface{} {
 	form := MustGet(process)
 	return form.Action.Save.MustExec(process)
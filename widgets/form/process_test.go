@@ -354,6 +354,61 @@ func TestProcessXgenWithPermissions(t *testing.T) {
 
 }
 
+func TestProcessStep(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+	prepare(t)
+
+	form, err := LoadSource([]byte(`{"name": "Pet Wizard", "action": { "bind": { "model": "pet" } } }`), "dynamic.wizard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unload("dynamic.wizard")
+
+	columns := form.Layout.Form.Sections[0].Columns
+	form.Layout.Form.Sections = []SectionDSL{
+		{Title: "Basic", Step: "basic", Columns: columns[:1]},
+		{Title: "Stay", Step: "detail", Visible: `mode == "enabled"`, Columns: columns[1:2]},
+		{Title: "Cost", Step: "detail", Columns: columns[2:]},
+		{Title: "Doctor", Step: "doctor", Columns: columns[2:]},
+	}
+	form.Layout.Form.Steps = []StepDSL{
+		{Name: "basic", Title: "Basic"},
+		{Name: "detail", Title: "Detail"},
+		{Name: "doctor", Title: "Doctor", Visible: `cost > 100`},
+	}
+	assert.NoError(t, form.Validate())
+
+	res := process.New("yao.form.Step", "dynamic.wizard", "basic", map[string]interface{}{"mode": "disabled", "cost": 105}).Run().(map[string]interface{})
+	assert.True(t, res["valid"].(bool))
+	assert.Equal(t, "detail", res["next"])
+	assert.Equal(t, []int{1}, res["hidden"])
+
+	res = process.New("yao.form.Step", "dynamic.wizard", "detail", map[string]interface{}{"mode": "enabled"}).Run().(map[string]interface{})
+	assert.Equal(t, "", res["next"])
+	assert.Equal(t, []int{3}, res["hidden"])
+
+	// The expressions and the processes are not sent to the xgen
+	steps := res["steps"].([]StepDSL)
+	assert.Equal(t, []int{1, 2}, steps[1].Sections)
+	assert.Equal(t, "", steps[2].Visible)
+	assert.True(t, steps[2].Hidden)
+
+	assert.Panics(t, func() {
+		process.New("yao.form.Step", "dynamic.wizard", "doctor", map[string]interface{}{"cost": 10}).Run()
+	})
+
+	assert.Panics(t, func() {
+		process.New("yao.form.Step", "dynamic.wizard", "not-found", map[string]interface{}{}).Run()
+	})
+
+	form.Layout.Form.Sections[0].Step = "not-found"
+	assert.Error(t, form.Validate())
+	form.Layout.Form.Sections[0].Step = "basic"
+	form.Layout.Form.Steps[2].Visible = `cost >`
+	assert.Error(t, form.Validate())
+}
+
 func TestProcessLoad(t *testing.T) {
 	test.Prepare(t, config.Conf)
 	defer test.Clean()
@@ -76,6 +76,7 @@ type FieldsDSL struct {
 type ViewLayoutDSL struct {
 	Props    component.PropsDSL `json:"props,omitempty"`
 	Sections []SectionDSL       `json:"sections,omitempty"`
+	Steps    []StepDSL          `json:"steps,omitempty"`
 	Frame    FrameDSL           `json:"frame,omitempty"`
 }
 
@@ -94,9 +95,24 @@ type SectionDSL struct {
 	Icon    interface{} `json:"icon,omitempty"`
 	Color   string      `json:"color,omitempty"`
 	Weight  interface{} `json:"weight,omitempty"`
+	Step    string      `json:"step,omitempty"`    // The name of the step of the wizard
+	Visible string      `json:"visible,omitempty"` // The expression of the visibility, e.g. type == "company"
+	Hidden  bool        `json:"hidden,omitempty"`
 	Columns []Column    `json:"columns,omitempty"`
 }
 
+// StepDSL layout.form.steps[*], the steps of the wizard in order
+type StepDSL struct {
+	Name     string      `json:"name"`
+	Title    string      `json:"title,omitempty"`
+	Desc     string      `json:"desc,omitempty"`
+	Icon     interface{} `json:"icon,omitempty"`
+	Visible  string      `json:"visible,omitempty"`  // The expression of the visibility
+	Validate string      `json:"validate,omitempty"` // The process validating the data of the step
+	Hidden   bool        `json:"hidden,omitempty"`
+	Sections []int       `json:"sections,omitempty"` // The indexes of the sections of the step, set by the xgen
+}
+
 // Column table columns
 type Column struct {
 	Tabs []SectionDSL `json:"tabs,omitempty"`
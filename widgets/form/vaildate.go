@@ -1,6 +1,49 @@
 package form
 
+import (
+	"fmt"
+
+	"github.com/expr-lang/expr"
+)
+
 // Validate table
 func (dsl *DSL) Validate() error {
+	if dsl.Layout == nil || dsl.Layout.Form == nil {
+		return nil
+	}
+	return dsl.Layout.Form.validateWizard()
+}
+
+// validateWizard the names of the steps are unique, the sections belong to the steps and the expressions are compiled
+func (view *ViewLayoutDSL) validateWizard() error {
+	steps := map[string]bool{}
+	for i, step := range view.Steps {
+		if step.Name == "" {
+			return fmt.Errorf("layout.form.steps[%d].name is required", i)
+		}
+
+		if steps[step.Name] {
+			return fmt.Errorf("layout.form.steps[%d].name %s is duplicated", i, step.Name)
+		}
+		steps[step.Name] = true
+
+		if step.Visible != "" {
+			if _, err := expr.Compile(step.Visible, expr.AllowUndefinedVariables()); err != nil {
+				return fmt.Errorf("layout.form.steps[%d].visible %s", i, err.Error())
+			}
+		}
+	}
+
+	for i, section := range view.Sections {
+		if len(steps) > 0 && !steps[section.Step] {
+			return fmt.Errorf("layout.form.sections[%d].step %s does not exist", i, section.Step)
+		}
+
+		if section.Visible != "" {
+			if _, err := expr.Compile(section.Visible, expr.AllowUndefinedVariables()); err != nil {
+				return fmt.Errorf("layout.form.sections[%d].visible %s", i, err.Error())
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,172 @@
+package form
+
+import (
+	"fmt"
+
+	"github.com/expr-lang/expr"
+	gouProcess "github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/yao/widgets/app"
+)
+
+// Step validate the data of the step of the wizard, returns the errors, the next step and the visibility of the steps and the sections
+//
+//	{ "step": "basic", "valid": true, "errors": {}, "next": "detail", "steps": [...], "hidden": [2] }
+func (dsl *DSL) Step(process *gouProcess.Process, name string, data map[string]interface{}) (map[string]interface{}, error) {
+	if dsl.Layout == nil || dsl.Layout.Form == nil {
+		return nil, fmt.Errorf("the form %s is not a wizard", dsl.ID)
+	}
+
+	var step *StepDSL
+	for i := range dsl.Layout.Form.Steps {
+		if dsl.Layout.Form.Steps[i].Name == name {
+			step = &dsl.Layout.Form.Steps[i]
+			break
+		}
+	}
+
+	if step == nil {
+		return nil, fmt.Errorf("the step %s of the form %s does not exist", name, dsl.ID)
+	}
+
+	excludes := app.Permissions(process, "forms", dsl.ID)
+	layout, err := dsl.Layout.Xgen(data, excludes, dsl.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := layout.Form.Steps
+	next := ""
+	for i := range steps {
+		if steps[i].Name != name {
+			continue
+		}
+
+		if steps[i].Hidden {
+			return nil, fmt.Errorf("the step %s of the form %s is hidden", name, dsl.ID)
+		}
+
+		for _, s := range steps[i+1:] {
+			if !s.Hidden {
+				next = s.Name
+				break
+			}
+		}
+		break
+	}
+
+	hidden := []int{}
+	for i, section := range layout.Form.Sections {
+		if section.Hidden {
+			hidden = append(hidden, i)
+		}
+	}
+
+	errors := map[string]interface{}{}
+	valid := true
+	if step.Validate != "" {
+		valid, errors, err = validateStep(process, step.Validate, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]interface{}{
+		"step":   name,
+		"valid":  valid,
+		"errors": errors,
+		"next":   next,
+		"steps":  steps,
+		"hidden": hidden,
+	}, nil
+}
+
+// validateStep run the validation process of the step with the data.
+// The data is valid if the process returns null, true or an empty map, a map of the field errors or a message otherwise.
+func validateStep(process *gouProcess.Process, name string, data map[string]interface{}) (bool, map[string]interface{}, error) {
+	p, err := gouProcess.Of(name, data)
+	if err != nil {
+		return false, nil, err
+	}
+
+	res, err := p.WithSID(process.Sid).WithGlobal(process.Global).Exec()
+	if err != nil {
+		return false, map[string]interface{}{"": err.Error()}, nil
+	}
+
+	switch value := res.(type) {
+	case nil:
+		return true, map[string]interface{}{}, nil
+	case bool:
+		return value, map[string]interface{}{}, nil
+	case string:
+		return value == "", map[string]interface{}{"": value}, nil
+	}
+
+	errors, ok := res.(map[string]interface{})
+	if values, is := res.(maps.MapStrAny); is {
+		errors, ok = values, true
+	}
+	if !ok {
+		return false, map[string]interface{}{"": fmt.Sprintf("%v", res)}, nil
+	}
+	return len(errors) == 0, errors, nil
+}
+
+// wizard evaluate the visibility of the steps and the sections with the data, hidden if the expression fails.
+// The expressions and the processes are not sent to the xgen.
+func (view *ViewLayoutDSL) wizard(data map[string]interface{}) {
+	for i := range view.Sections {
+		section := &view.Sections[i]
+		if section.Visible != "" {
+			visible, err := visible(section.Visible, data)
+			if err != nil {
+				log.Warn("[Form] layout.form.sections[%d].visible %s %s", i, section.Visible, err.Error())
+			}
+			section.Hidden = section.Hidden || !visible
+			section.Visible = ""
+		}
+	}
+
+	for i := range view.Steps {
+		step := &view.Steps[i]
+		if step.Visible != "" {
+			visible, err := visible(step.Visible, data)
+			if err != nil {
+				log.Warn("[Form] layout.form.steps[%d].visible %s %s", i, step.Visible, err.Error())
+			}
+			step.Hidden = step.Hidden || !visible
+			step.Visible = ""
+		}
+		step.Validate = ""
+
+		step.Sections = []int{}
+		for j := range view.Sections {
+			if view.Sections[j].Step == step.Name {
+				step.Sections = append(step.Sections, j)
+				view.Sections[j].Hidden = view.Sections[j].Hidden || step.Hidden
+			}
+		}
+	}
+}
+
+// visible evaluate the expression of the visibility, the fields of the data are the variables
+func visible(stmt string, data map[string]interface{}) (bool, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	program, err := expr.Compile(stmt, expr.Env(data), expr.AllowUndefinedVariables(), expr.AsBool())
+	if err != nil {
+		return false, err
+	}
+
+	res, err := expr.Run(program, data)
+	if err != nil {
+		return false, err
+	}
+
+	value, _ := res.(bool)
+	return value, nil
+}
@@ -99,7 +99,12 @@ func auth(field string, value string, password string, sid string) maps.Map {
 		exception.New("Login password error (%v)", 403, value).Throw()
 	}
 
-	expiresAt := time.Now().Unix() + 3600*8
+	// The session is shared by the instances if the session store is redis, it expires with the token
+	ttl := time.Duration(config.Conf.Session.TTL) * time.Second
+	if ttl <= 0 {
+		ttl = 8 * time.Hour
+	}
+	expiresAt := time.Now().Add(ttl).Unix()
 
 	// token := MakeToken(row, expiresAt)
 	id := any.Of(row.Get("id")).CInt()
@@ -109,9 +114,9 @@ func auth(field string, value string, password string, sid string) maps.Map {
 		"issuer":     "yao",
 	})
 	log.Debug("[login] auth sid=%s", sid)
-	session.Global().Expire(time.Duration(token.ExpiresAt)*time.Second).ID(sid).Set("user_id", id)
-	session.Global().Expire(time.Duration(token.ExpiresAt)*time.Second).ID(sid).Set("user", row)
-	session.Global().Expire(time.Duration(token.ExpiresAt)*time.Second).ID(sid).Set("issuer", "yao")
+	session.Global().Expire(ttl).ID(sid).Set("user_id", id)
+	session.Global().Expire(ttl).ID(sid).Set("user", row)
+	session.Global().Expire(ttl).ID(sid).Set("issuer", "yao")
 
 	studio := map[string]interface{}{}
 	if config.Conf.Mode == "development" {
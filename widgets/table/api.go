@@ -55,8 +55,14 @@ func (table *DSL) getAction(path string) (*action.Process, error) {
 		return table.Action.Upload, nil
 	case "/api/__yao/table/:id/download/:field":
 		return table.Action.Download, nil
-	case "/api/__yao/table/:id/search":
+	case "/api/__yao/table/:id/search", "/api/__yao/table/:id/export", "/api/__yao/table/:id/export/:task":
 		return table.Action.Search, nil
+	case "/api/__yao/table/:id/batch/select", "/api/__yao/table/:id/batch/:job":
+		return table.Action.Search, nil
+	case "/api/__yao/table/:id/batch/update", "/api/__yao/table/:id/batch/process":
+		return table.Action.UpdateIn, nil
+	case "/api/__yao/table/:id/export/:task/download":
+		return table.Action.Download, nil
 	case "/api/__yao/table/:id/get":
 		return table.Action.Get, nil
 	case "/api/__yao/table/:id/find/:primary":
@@ -120,6 +126,97 @@ func exportAPI() error {
 	}
 	http.Paths = append(http.Paths, path)
 
+	//   POST  /api/__yao/table/:id/export  					-> Default process: yao.table.ExportStart $param.id :query-param :payload
+	path = api.Path{
+		Label:       "Export",
+		Description: "Export the current view as xlsx or csv in the background",
+		Path:        "/:id/export",
+		Method:      "POST",
+		Process:     "yao.table.ExportStart",
+		In:          []interface{}{"$param.id", ":query-param", ":payload"},
+		Out:         api.Out{Status: 200, Type: "application/json"},
+	}
+	http.Paths = append(http.Paths, path)
+
+	//   GET  /api/__yao/table/:id/export/:task  				-> Default process: yao.table.ExportStatus $param.id $param.task
+	path = api.Path{
+		Label:       "Export Status",
+		Description: "The progress of the export",
+		Path:        "/:id/export/:task",
+		Method:      "GET",
+		Process:     "yao.table.ExportStatus",
+		In:          []interface{}{"$param.id", "$param.task"},
+		Out:         api.Out{Status: 200, Type: "application/json"},
+	}
+	http.Paths = append(http.Paths, path)
+
+	//   GET  /api/__yao/table/:id/export/:task/download  		-> Default process: yao.table.ExportDownload $param.id $param.task $query.token
+	path = api.Path{
+		Label:       "Export Download",
+		Description: "The file of the export",
+		Path:        "/:id/export/:task/download",
+		Method:      "GET",
+		Process:     "yao.table.ExportDownload",
+		In:          []interface{}{"$param.id", "$param.task", "$query.token"},
+		Out: api.Out{
+			Status: 200,
+			Body:   "{{content}}",
+			Headers: map[string]string{
+				"Content-Type":        "{{type}}",
+				"Content-Disposition": `attachment; filename="{{name}}"`,
+			},
+		},
+	}
+	http.Paths = append(http.Paths, path)
+
+	//   POST  /api/__yao/table/:id/batch/select  			-> Default process: yao.table.BatchSelect $param.id :query-param
+	path = api.Path{
+		Label:       "Batch Select",
+		Description: "The primary keys of the rows matched",
+		Path:        "/:id/batch/select",
+		Method:      "POST",
+		Process:     "yao.table.BatchSelect",
+		In:          []interface{}{"$param.id", ":query-param"},
+		Out:         api.Out{Status: 200, Type: "application/json"},
+	}
+	http.Paths = append(http.Paths, path)
+
+	//   POST  /api/__yao/table/:id/batch/update  			-> Default process: yao.table.BatchUpdate $param.id :query-param :payload
+	path = api.Path{
+		Label:       "Batch Update",
+		Description: "Update the fields of the rows selected or matched in the background",
+		Path:        "/:id/batch/update",
+		Method:      "POST",
+		Process:     "yao.table.BatchUpdate",
+		In:          []interface{}{"$param.id", ":query-param", ":payload"},
+		Out:         api.Out{Status: 200, Type: "application/json"},
+	}
+	http.Paths = append(http.Paths, path)
+
+	//   POST  /api/__yao/table/:id/batch/process  			-> Default process: yao.table.BatchProcess $param.id :query-param :payload
+	path = api.Path{
+		Label:       "Batch Process",
+		Description: "Run the process with each row selected or matched in the background",
+		Path:        "/:id/batch/process",
+		Method:      "POST",
+		Process:     "yao.table.BatchProcess",
+		In:          []interface{}{"$param.id", ":query-param", ":payload"},
+		Out:         api.Out{Status: 200, Type: "application/json"},
+	}
+	http.Paths = append(http.Paths, path)
+
+	//   GET  /api/__yao/table/:id/batch/:job  				-> Default process: yao.table.BatchStatus $param.id $param.job
+	path = api.Path{
+		Label:       "Batch Status",
+		Description: "The progress of the batch",
+		Path:        "/:id/batch/:job",
+		Method:      "GET",
+		Process:     "yao.table.BatchStatus",
+		In:          []interface{}{"$param.id", "$param.job"},
+		Out:         api.Out{Status: 200, Type: "application/json"},
+	}
+	http.Paths = append(http.Paths, path)
+
 	//   GET  /api/__yao/table/:id/get  						-> Default process: yao.table.Get $param.id :query
 	path = api.Path{
 		Label:       "Get",
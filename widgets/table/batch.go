@@ -0,0 +1,294 @@
+package table
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaoapp/gou/model"
+	gouProcess "github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+)
+
+// The statuses of the batch jobs
+const (
+	BatchRunning = "running"
+	BatchDone    = "done"
+	BatchFailed  = "failed"
+)
+
+// BatchDSL the batch operations of the table
+//
+//	"action": {
+//	  "batch": { "processes": ["scripts.pet.Notify"], "chunk": 200 }
+//	}
+type BatchDSL struct {
+	Processes []string `json:"processes,omitempty"` // The processes could be run per row, none by default
+	Chunk     int      `json:"chunk,omitempty"`     // The rows of a chunk, 200 by default
+}
+
+// BatchOption the rows and the operation of the batch, the rows matched by the query param if the ids are empty
+type BatchOption struct {
+	IDs     []interface{}          `json:"ids,omitempty"`     // The primary keys of the rows selected
+	Data    map[string]interface{} `json:"data,omitempty"`    // The fields updated of the rows, the batch update
+	Process string                 `json:"process,omitempty"` // The process run with each row, the batch process
+	Chunk   int                    `json:"chunk,omitempty"`   // The rows of a chunk, the chunk of the batch DSL by default
+}
+
+// BatchJob the batch running in the background
+type BatchJob struct {
+	ID        string    `json:"id"`
+	Table     string    `json:"table"`
+	Type      string    `json:"type"` // update or process
+	Status    string    `json:"status"`
+	Total     int       `json:"total"`
+	Done      int       `json:"done"`
+	Failed    int       `json:"failed"`
+	Progress  int       `json:"progress"`         // The percent of the rows done or failed
+	Errors    []string  `json:"errors,omitempty"` // The first errors of the rows failed
+	CreatedAt time.Time `json:"created_at"`
+	sid       string
+}
+
+// batchErrors the errors kept of a batch job
+const batchErrors = 20
+
+// batchJobs the jobs of the batches, removed an hour after they are finished
+var batchJobs = map[string]*BatchJob{}
+var batchLock sync.RWMutex
+
+// Select the primary keys of the rows matched by the query param, the rows are searched page by page
+func (dsl *DSL) Select(process *gouProcess.Process, params types.QueryParam) ([]interface{}, error) {
+	ids := []interface{}{}
+	page := 1
+	for page > 0 {
+		p, err := gouProcess.Of("yao.table.search", dsl.ID, params, page, 500)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := dsl.Action.Search.Exec(p.WithSID(process.Sid).WithGlobal(process.Global))
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := dsl.paginated(data)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range exportRows(res["data"]) {
+			ids = append(ids, row.Get(dsl.Layout.Primary))
+		}
+
+		if _, has := res["next"]; !has {
+			break
+		}
+		page = any.Of(res["next"]).CInt()
+	}
+	return ids, nil
+}
+
+// StartBatch update the fields of the rows or run the process with each row in the background, chunk by chunk
+func (dsl *DSL) StartBatch(process *gouProcess.Process, params types.QueryParam, option BatchOption) (*BatchJob, error) {
+	job := &BatchJob{
+		ID:        uuid.NewString(),
+		Table:     dsl.ID,
+		Type:      "update",
+		Status:    BatchRunning,
+		CreatedAt: time.Now(),
+		sid:       process.Sid,
+	}
+
+	if option.Process != "" {
+		if !dsl.batchAllowed(option.Process) {
+			return nil, fmt.Errorf("the process %s is not allowed in the batch of the table %s", option.Process, dsl.ID)
+		}
+		job.Type = "process"
+	} else if len(option.Data) == 0 {
+		return nil, fmt.Errorf("the data or the process of the batch is required")
+	}
+
+	if option.Chunk <= 0 && dsl.Action.Batch != nil {
+		option.Chunk = dsl.Action.Batch.Chunk
+	}
+	if option.Chunk <= 0 {
+		option.Chunk = 200
+	}
+
+	batchLock.Lock()
+	for id, j := range batchJobs {
+		if j.Status != BatchRunning && time.Since(j.CreatedAt) > time.Hour {
+			delete(batchJobs, id)
+		}
+	}
+	batchJobs[job.ID] = job
+	batchLock.Unlock()
+
+	// The rows are selected before the update, the fields updated may change the rows matched
+	go func() {
+		ids := option.IDs
+		var err error
+		if len(ids) == 0 {
+			ids, err = dsl.Select(process, params)
+		}
+
+		if err == nil {
+			batchLock.Lock()
+			job.Total = len(ids)
+			batchLock.Unlock()
+			err = dsl.batchRun(process, job, ids, option)
+		}
+
+		batchLock.Lock()
+		defer batchLock.Unlock()
+		if err != nil {
+			log.Error("[table] batch %s %s: %s", dsl.ID, job.ID, err.Error())
+			job.Status = BatchFailed
+			job.Errors = append(job.Errors, err.Error())
+			return
+		}
+		job.Status, job.Progress = BatchDone, 100
+	}()
+
+	return job.copy(), nil
+}
+
+// GetBatch the batch job of the session
+func GetBatch(id string, sid string) (*BatchJob, error) {
+	batchLock.RLock()
+	defer batchLock.RUnlock()
+	job, has := batchJobs[id]
+	if !has || job.sid != sid {
+		return nil, fmt.Errorf("the batch %s does not exist", id)
+	}
+	return job.copy(), nil
+}
+
+// batchRun run the chunks of the rows, the rows of the chunks failed are counted and the batch goes on
+func (dsl *DSL) batchRun(process *gouProcess.Process, job *BatchJob, ids []interface{}, option BatchOption) error {
+	for start := 0; start < len(ids); start += option.Chunk {
+		end := start + option.Chunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+
+		param := model.QueryParam{
+			Wheres: []model.QueryWhere{
+				{Column: dsl.Layout.Primary, OP: "in", Value: chunk},
+			},
+		}
+
+		if job.Type == "update" {
+			p, err := gouProcess.Of("yao.table.updatein", dsl.ID, param, option.Data)
+			if err != nil {
+				return err
+			}
+
+			_, err = dsl.Action.UpdateIn.Exec(p.WithSID(process.Sid).WithGlobal(process.Global))
+			job.done(len(chunk), err)
+			continue
+		}
+
+		p, err := gouProcess.Of("yao.table.search", dsl.ID, param, 1, len(chunk))
+		if err != nil {
+			return err
+		}
+
+		data, err := dsl.Action.Search.Exec(p.WithSID(process.Sid).WithGlobal(process.Global))
+		if err != nil {
+			job.done(len(chunk), err)
+			continue
+		}
+
+		res, err := dsl.paginated(data)
+		if err != nil {
+			return err
+		}
+
+		rows := exportRows(res["data"])
+		for _, row := range rows {
+			job.done(1, batchExec(process, option.Process, row))
+		}
+
+		// The rows removed after the selection
+		if missing := len(chunk) - len(rows); missing > 0 {
+			job.done(missing, fmt.Errorf("%d rows of the table %s do not exist", missing, dsl.ID))
+		}
+	}
+	return nil
+}
+
+// batchExec run the process with the row, the exceptions thrown are the errors of the row
+func batchExec(process *gouProcess.Process, name string, row maps.MapStr) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	p, err := gouProcess.Of(name, row)
+	if err != nil {
+		return err
+	}
+	_, err = p.WithSID(process.Sid).WithGlobal(process.Global).Exec()
+	return err
+}
+
+// done count the rows done or failed
+func (job *BatchJob) done(rows int, err error) {
+	batchLock.Lock()
+	defer batchLock.Unlock()
+
+	if err != nil {
+		job.Failed += rows
+		if len(job.Errors) < batchErrors {
+			job.Errors = append(job.Errors, err.Error())
+		}
+	} else {
+		job.Done += rows
+	}
+
+	if job.Total > 0 {
+		job.Progress = (job.Done + job.Failed) * 100 / job.Total
+	}
+}
+
+// batchAllowed the process is declared in the batch of the table
+func (dsl *DSL) batchAllowed(name string) bool {
+	if dsl.Action.Batch == nil {
+		return false
+	}
+
+	for _, allowed := range dsl.Action.Batch.Processes {
+		if strings.EqualFold(allowed, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// paginated the paginated result of the search
+func (dsl *DSL) paginated(data interface{}) (map[string]interface{}, error) {
+	if values, is := data.(maps.MapStrAny); is {
+		return values, nil
+	}
+
+	res, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the search of the table %s responds %T, the paginated result is required", dsl.ID, data)
+	}
+	return res, nil
+}
+
+func (job *BatchJob) copy() *BatchJob {
+	copied := *job
+	copied.Errors = append([]string{}, job.Errors...)
+	return &copied
+}
@@ -18,20 +18,7 @@ func (dsl *DSL) Export(filename string, data interface{}, page int, chunkSize in
 
 	log.Trace("[Export] %s %d %d Before: %#v", filename, page, chunkSize, data)
 
-	rows := []maps.MapStr{}
-	if values, ok := data.([]maps.MapStrAny); ok {
-		for _, row := range values {
-			rows = append(rows, row.Dot())
-		}
-	} else if values, ok := data.([]map[string]interface{}); ok {
-		for _, row := range values {
-			rows = append(rows, maps.Of(row).Dot())
-		}
-	} else if values, ok := data.([]interface{}); ok {
-		for _, row := range values {
-			rows = append(rows, any.Of(row).MapStr().Dot())
-		}
-	}
+	rows := exportRows(data)
 
 	log.Trace("[Export] %s %d %d After: %#v", filename, page, chunkSize, data)
 	columns, err := dsl.exportSetting()
@@ -120,3 +107,22 @@ func (dsl *DSL) exportSetting() ([]map[string]string, error) {
 
 	return setting, nil
 }
+
+// exportRows the rows of the search result, the nested fields are flattened, e.g. owner.name
+func exportRows(data interface{}) []maps.MapStr {
+	rows := []maps.MapStr{}
+	if values, ok := data.([]maps.MapStrAny); ok {
+		for _, row := range values {
+			rows = append(rows, row.Dot())
+		}
+	} else if values, ok := data.([]map[string]interface{}); ok {
+		for _, row := range values {
+			rows = append(rows, maps.Of(row).Dot())
+		}
+	} else if values, ok := data.([]interface{}); ok {
+		for _, row := range values {
+			rows = append(rows, any.Of(row).MapStr().Dot())
+		}
+	}
+	return rows
+}
@@ -0,0 +1,338 @@
+package table
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+	"github.com/yaoapp/gou/fs"
+	gouProcess "github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/types"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/log"
+)
+
+// The statuses of the export tasks
+const (
+	ExportRunning = "running"
+	ExportDone    = "done"
+	ExportFailed  = "failed"
+)
+
+// ExportOption the option of the export of the current view, the filters and the sorts are the query param
+type ExportOption struct {
+	Format  string   `json:"format,omitempty"`  // xlsx or csv, xlsx by default
+	Columns []string `json:"columns,omitempty"` // The names of the columns selected, the columns of the layout by default
+	Chunk   int      `json:"chunk,omitempty"`   // The rows of a page of the search, 500 by default
+}
+
+// ExportTask the export running in the background
+type ExportTask struct {
+	ID        string    `json:"id"`
+	Table     string    `json:"table"`
+	Format    string    `json:"format"`
+	Status    string    `json:"status"`
+	Total     int       `json:"total"`
+	Exported  int       `json:"exported"`
+	Progress  int       `json:"progress"` // The percent of the rows exported
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	file      string
+	sid       string
+}
+
+// exportWriter the writer of the exported file, a row a time
+type exportWriter interface {
+	Write(values []interface{}) error
+	Close() error
+}
+
+// exportTasks the tasks of the exports, removed an hour after they are finished
+var exportTasks = map[string]*ExportTask{}
+var exportLock sync.RWMutex
+
+// StartExport export the current view of the table in the background, the rows are searched page by page
+func (dsl *DSL) StartExport(process *gouProcess.Process, params types.QueryParam, option ExportOption) (*ExportTask, error) {
+	if option.Format == "" {
+		option.Format = "xlsx"
+	}
+
+	if option.Format != "xlsx" && option.Format != "csv" {
+		return nil, fmt.Errorf("the format %s is not supported, xlsx or csv", option.Format)
+	}
+
+	if option.Chunk <= 0 {
+		option.Chunk = 500
+	}
+
+	columns, err := dsl.exportColumns(option.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	// The files of the exports are kept in the data of the system like the yao.table.Export
+	system, err := fs.Get("system")
+	if err != nil {
+		return nil, err
+	}
+
+	dir := time.Now().Format("20060102")
+	if has, _ := system.Exists(dir); !has {
+		system.MkdirAll(dir, uint32(os.ModePerm))
+	}
+
+	task := &ExportTask{
+		ID:        uuid.NewString(),
+		Table:     dsl.ID,
+		Format:    option.Format,
+		Status:    ExportRunning,
+		CreatedAt: time.Now(),
+		sid:       process.Sid,
+	}
+	task.file = filepath.Join(string(os.PathSeparator), dir, fmt.Sprintf("%s.%s", task.ID, option.Format))
+
+	writer, err := newExportWriter(option.Format, filepath.Join(system.Root(), task.file), dsl.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	exportLock.Lock()
+	for id, t := range exportTasks {
+		if t.Status != ExportRunning && time.Since(t.CreatedAt) > time.Hour {
+			delete(exportTasks, id)
+		}
+	}
+	exportTasks[task.ID] = task
+	exportLock.Unlock()
+
+	global := process.Global
+	go func() {
+		err := dsl.exportTo(writer, process.Sid, global, params, columns, option.Chunk, func(total int, exported int) {
+			exportLock.Lock()
+			task.Total, task.Exported = total, exported
+			if total > 0 {
+				task.Progress = exported * 100 / total
+			}
+			exportLock.Unlock()
+		})
+
+		if e := writer.Close(); err == nil {
+			err = e
+		}
+
+		exportLock.Lock()
+		defer exportLock.Unlock()
+		if err != nil {
+			log.Error("[table] export %s %s: %s", dsl.ID, task.ID, err.Error())
+			task.Status, task.Error = ExportFailed, err.Error()
+			return
+		}
+		task.Status, task.Progress = ExportDone, 100
+	}()
+
+	return task.copy(), nil
+}
+
+// GetExport the export task of the session
+func GetExport(id string, sid string) (*ExportTask, error) {
+	exportLock.RLock()
+	defer exportLock.RUnlock()
+	task, has := exportTasks[id]
+	if !has || task.sid != sid {
+		return nil, fmt.Errorf("the export %s does not exist", id)
+	}
+	return task.copy(), nil
+}
+
+// exportTo write the header and the rows of the pages of the search
+func (dsl *DSL) exportTo(writer exportWriter, sid string, global map[string]interface{}, params types.QueryParam, columns []map[string]string, chunk int, progress func(total int, exported int)) error {
+	header := []interface{}{}
+	for _, column := range columns {
+		header = append(header, column["name"])
+	}
+
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	exported := 0
+	page := 1
+	for page > 0 {
+		p, err := gouProcess.Of("yao.table.search", dsl.ID, params, page, chunk)
+		if err != nil {
+			return err
+		}
+
+		data, err := dsl.Action.Search.Exec(p.WithSID(sid).WithGlobal(global))
+		if err != nil {
+			return err
+		}
+
+		res, err := dsl.paginated(data)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range exportRows(res["data"]) {
+			values := []interface{}{}
+			for _, column := range columns {
+				values = append(values, row.Get(column["field"]))
+			}
+
+			if err := writer.Write(values); err != nil {
+				return err
+			}
+			exported++
+		}
+
+		total := any.Of(res["total"]).CInt()
+		if total < exported {
+			total = exported
+		}
+		progress(total, exported)
+
+		if _, has := res["next"]; !has {
+			break
+		}
+		page = any.Of(res["next"]).CInt()
+	}
+	return nil
+}
+
+// exportColumns the columns selected, the names of the columns of the layout
+func (dsl *DSL) exportColumns(names []string) ([]map[string]string, error) {
+	setting, err := dsl.exportSetting()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(setting) == 0 {
+		return nil, fmt.Errorf("the table does not support export")
+	}
+
+	if len(names) == 0 {
+		return setting, nil
+	}
+
+	columns := []map[string]string{}
+	for _, name := range names {
+		found := false
+		for _, column := range setting {
+			if column["name"] == name {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("the column %s of the table %s does not exist", name, dsl.ID)
+		}
+	}
+	return columns, nil
+}
+
+func (task *ExportTask) copy() *ExportTask {
+	copied := *task
+	return &copied
+}
+
+func newExportWriter(format string, filename string, sheet string) (exportWriter, error) {
+	if format == "csv" {
+		file, err := os.Create(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		// The BOM of UTF-8, Excel opens the csv file as UTF-8
+		file.WriteString("\xEF\xBB\xBF")
+		return &csvWriter{file: file, writer: csv.NewWriter(file)}, nil
+	}
+
+	file := excelize.NewFile()
+	name := sheetName(sheet)
+	if err := file.SetSheetName(file.GetSheetName(file.GetActiveSheetIndex()), name); err != nil {
+		return nil, err
+	}
+
+	stream, err := file.NewStreamWriter(name)
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{file: file, stream: stream, filename: filename}, nil
+}
+
+// csvWriter the writer of the csv file
+type csvWriter struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+func (w *csvWriter) Write(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		if value != nil {
+			record[i] = fmt.Sprintf("%v", value)
+		}
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// xlsxWriter the writer of the excel file, the rows are streamed to the temporary files of excelize
+type xlsxWriter struct {
+	file     *excelize.File
+	stream   *excelize.StreamWriter
+	filename string
+	line     int
+}
+
+func (w *xlsxWriter) Write(values []interface{}) error {
+	w.line++
+	cell, err := excelize.CoordinatesToCellName(1, w.line)
+	if err != nil {
+		return err
+	}
+	return w.stream.SetRow(cell, values)
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.file.Close()
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	return w.file.SaveAs(w.filename)
+}
+
+// sheetName the name of the sheet, 31 characters at most without []:*?/\
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+
+	if strings.TrimSpace(name) == "" {
+		return "Sheet1"
+	}
+	return name
+}
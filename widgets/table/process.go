@@ -43,6 +43,13 @@ func exportProcess() {
 	gouProcess.Register("yao.table.deletewhere", processDeleteWhere)
 	gouProcess.Register("yao.table.deletein", processDeleteIn)
 	gouProcess.Register("yao.table.export", processExport)
+	gouProcess.Register("yao.table.exportstart", processExportStart)
+	gouProcess.Register("yao.table.exportstatus", processExportStatus)
+	gouProcess.Register("yao.table.exportdownload", processExportDownload)
+	gouProcess.Register("yao.table.batchselect", processBatchSelect)
+	gouProcess.Register("yao.table.batchupdate", processBatchUpdate)
+	gouProcess.Register("yao.table.batchprocess", processBatchProcess)
+	gouProcess.Register("yao.table.batchstatus", processBatchStatus)
 	gouProcess.Register("yao.table.load", processLoad)
 	gouProcess.Register("yao.table.reload", processReload)
 	gouProcess.Register("yao.table.unload", processUnload)
@@ -318,6 +325,165 @@ func processExport(process *gouProcess.Process) interface{} {
 	return filename
 }
 
+// processExportStart yao.table.ExportStart (:table, :queryParam, {"format": "csv", "columns": ["Name", "Status"]})
+// Export the current view in the background, returns the task of the export
+func processExportStart(process *gouProcess.Process) interface{} {
+	process.ValidateArgNums(1)
+	tab := MustGet(process) // 0
+	params := process.ArgsQueryParams(1, types.QueryParam{})
+
+	option := ExportOption{}
+	if process.NumOfArgs() > 2 && process.Args[2] != nil {
+		data := any.Of(process.Args[2]).MapStr()
+		option.Format, _ = data.Get("format").(string)
+		option.Chunk = any.Of(data.Get("chunk")).CInt()
+		if columns, ok := data.Get("columns").([]interface{}); ok {
+			for _, column := range columns {
+				option.Columns = append(option.Columns, fmt.Sprintf("%v", column))
+			}
+		}
+	}
+
+	task, err := tab.StartExport(process, params, option)
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return task
+}
+
+// processExportStatus yao.table.ExportStatus (:table, :task), the progress of the export
+func processExportStatus(process *gouProcess.Process) interface{} {
+	process.ValidateArgNums(2)
+	MustGet(process) // 0
+	task, err := GetExport(process.ArgsString(1), process.Sid)
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+	return task
+}
+
+// processExportDownload yao.table.ExportDownload (:table, :task, :token), the file of the export finished
+func processExportDownload(process *gouProcess.Process) interface{} {
+	process.ValidateArgNums(3)
+	tab := MustGet(process) // 0
+
+	tokenString := strings.TrimSpace(strings.TrimPrefix(process.ArgsString(2), "Bearer "))
+	if tokenString == "" {
+		exception.New("%s No permission", 403, tab.ID).Throw()
+	}
+	claims := helper.JwtValidate(tokenString)
+
+	task, err := GetExport(process.ArgsString(1), claims.SID)
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+
+	if task.Status != ExportDone {
+		exception.New("the export %s is %s", 400, task.ID, task.Status).Throw()
+	}
+
+	content, err := fs.MustGet("system").ReadFile(task.file)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+
+	mime := "text/csv; charset=utf-8"
+	if task.Format == "xlsx" {
+		mime = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return map[string]interface{}{"content": content, "type": mime, "name": fmt.Sprintf("%s.%s", tab.ID, task.Format)}
+}
+
+// processBatchSelect yao.table.BatchSelect (:table, :queryParam), the primary keys of the rows matched
+func processBatchSelect(process *gouProcess.Process) interface{} {
+	process.ValidateArgNums(1)
+	tab := MustGet(process) // 0
+	params := process.ArgsQueryParams(1, types.QueryParam{})
+
+	ids, err := tab.Select(process, params)
+	if err != nil {
+		exception.New(err.Error(), 500).Throw()
+	}
+	return map[string]interface{}{"ids": ids, "total": len(ids)}
+}
+
+// processBatchUpdate yao.table.BatchUpdate (:table, :queryParam, {"ids": [1, 2], "data": {"status": "checked"}})
+// Update the fields of the rows selected or matched in the background, returns the job of the batch
+func processBatchUpdate(process *gouProcess.Process) interface{} {
+	process.ValidateArgNums(3)
+	tab := MustGet(process) // 0
+	params := process.ArgsQueryParams(1, types.QueryParam{})
+	option := batchOptionOf(process.Args[2])
+	option.Process = ""
+	if len(option.Data) == 0 {
+		exception.New("the data of the batch is required", 400).Throw()
+	}
+
+	job, err := tab.StartBatch(process, params, option)
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return job
+}
+
+// processBatchProcess yao.table.BatchProcess (:table, :queryParam, {"ids": [1, 2], "process": "scripts.pet.Notify"})
+// Run the process with each row selected or matched in the background, the process must be declared in the action.batch
+func processBatchProcess(process *gouProcess.Process) interface{} {
+	process.ValidateArgNums(3)
+	tab := MustGet(process) // 0
+	params := process.ArgsQueryParams(1, types.QueryParam{})
+	option := batchOptionOf(process.Args[2])
+	option.Data = nil
+	if option.Process == "" {
+		exception.New("the process of the batch is required", 400).Throw()
+	}
+
+	job, err := tab.StartBatch(process, params, option)
+	if err != nil {
+		exception.New(err.Error(), 400).Throw()
+	}
+	return job
+}
+
+// processBatchStatus yao.table.BatchStatus (:table, :job), the progress of the batch
+func processBatchStatus(process *gouProcess.Process) interface{} {
+	process.ValidateArgNums(2)
+	MustGet(process) // 0
+	job, err := GetBatch(process.ArgsString(1), process.Sid)
+	if err != nil {
+		exception.New(err.Error(), 404).Throw()
+	}
+	return job
+}
+
+func batchOptionOf(value interface{}) BatchOption {
+	option := BatchOption{}
+	if value == nil {
+		return option
+	}
+
+	data := any.Of(value).MapStr()
+	option.Process, _ = data.Get("process").(string)
+	option.Chunk = any.Of(data.Get("chunk")).CInt()
+	if values, ok := data.Get("data").(map[string]interface{}); ok {
+		option.Data = values
+	}
+	if values, ok := data.Get("data").(maps.MapStrAny); ok {
+		option.Data = values
+	}
+	switch ids := data.Get("ids").(type) {
+	case []interface{}:
+		option.IDs = ids
+	case string:
+		for _, id := range strings.Split(ids, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				option.IDs = append(option.IDs, id)
+			}
+		}
+	}
+	return option
+}
+
 // processLoad yao.table.Load table_name file <source>
 func processLoad(process *gouProcess.Process) interface{} {
 	process.ValidateArgNums(1)
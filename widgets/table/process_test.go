@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yaoapp/gou/fs"
@@ -582,6 +583,109 @@ func TestProcessExport(t *testing.T) {
 	assert.Greater(t, size, 1000)
 }
 
+func TestProcessExportStart(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+
+	prepare(t)
+	clear(t)
+	testData(t)
+
+	setting, err := Tables["pet"].exportSetting()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"csv", "xlsx"} {
+		option := map[string]interface{}{"format": format, "columns": []interface{}{setting[1]["name"], setting[0]["name"]}, "chunk": 2}
+		task := process.New("yao.table.ExportStart", "pet", nil, option).Run().(*ExportTask)
+		assert.Equal(t, format, task.Format)
+
+		// The export runs in the background
+		for i := 0; i < 100 && task.Status == ExportRunning; i++ {
+			time.Sleep(50 * time.Millisecond)
+			task, err = GetExport(task.ID, "")
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, ExportDone, task.Status)
+		assert.Equal(t, 100, task.Progress)
+		assert.Greater(t, task.Exported, 0)
+		assert.Equal(t, task.Total, task.Exported)
+
+		size, _ := fs.MustGet("system").Size(task.file)
+		assert.Greater(t, size, 0)
+	}
+
+	assert.Panics(t, func() {
+		process.New("yao.table.ExportStart", "pet", nil, map[string]interface{}{"format": "pdf"}).Run()
+	})
+
+	assert.Panics(t, func() {
+		process.New("yao.table.ExportStart", "pet", nil, map[string]interface{}{"columns": []interface{}{"not-found"}}).Run()
+	})
+
+	_, err = GetExport("not-found", "")
+	assert.Error(t, err)
+	assert.Equal(t, "Sheet1", sheetName(" "))
+	assert.Equal(t, "Pets_2024_", sheetName("Pets/2024?"))
+}
+
+func TestProcessBatch(t *testing.T) {
+	test.Prepare(t, config.Conf)
+	defer test.Clean()
+
+	prepare(t)
+	clear(t)
+	testData(t)
+
+	wait := func(job *BatchJob) *BatchJob {
+		var err error
+		for i := 0; i < 100 && job.Status == BatchRunning; i++ {
+			time.Sleep(50 * time.Millisecond)
+			job, err = GetBatch(job.ID, "")
+			assert.NoError(t, err)
+		}
+		return job
+	}
+
+	params := model.QueryParam{Wheres: []model.QueryWhere{{Column: "type", Value: "cat"}}}
+	selected := process.New("yao.table.BatchSelect", "pet", params).Run().(map[string]interface{})
+	assert.Equal(t, 1, selected["total"])
+
+	// Update the rows matched chunk by chunk
+	option := map[string]interface{}{"data": map[string]interface{}{"status": "unchecked"}, "chunk": 2}
+	job := wait(process.New("yao.table.BatchUpdate", "pet", nil, option).Run().(*BatchJob))
+	assert.Equal(t, BatchDone, job.Status)
+	assert.Equal(t, 3, job.Total)
+	assert.Equal(t, 3, job.Done)
+	assert.Equal(t, 100, job.Progress)
+
+	rows, err := model.Select("pet").Get(model.QueryParam{Wheres: []model.QueryWhere{{Column: "status", Value: "unchecked"}}})
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+
+	// The processes must be declared in the action.batch
+	option = map[string]interface{}{"ids": selected["ids"], "process": "models.pet.Save"}
+	assert.Panics(t, func() {
+		process.New("yao.table.BatchProcess", "pet", nil, option).Run()
+	})
+
+	Tables["pet"].Action.Batch = &BatchDSL{Processes: []string{"models.pet.Save"}}
+	defer func() { Tables["pet"].Action.Batch = nil }()
+	job = wait(process.New("yao.table.BatchProcess", "pet", nil, option).Run().(*BatchJob))
+	assert.Equal(t, BatchDone, job.Status)
+	assert.Equal(t, 1, job.Total)
+	assert.Equal(t, 1, job.Done+job.Failed)
+
+	assert.Panics(t, func() {
+		process.New("yao.table.BatchUpdate", "pet", nil, map[string]interface{}{}).Run()
+	})
+
+	_, err = GetBatch(job.ID, "another-session")
+	assert.Error(t, err)
+}
+
 func TestProcessLoad(t *testing.T) {
 	test.Prepare(t, config.Conf)
 	defer test.Clean()
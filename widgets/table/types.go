@@ -45,6 +45,7 @@ type ActionDSL struct {
 	Update            *action.Process `json:"update,omitempty"`
 	UpdateIn          *action.Process `json:"update-in,omitempty"`
 	UpdateWhere       *action.Process `json:"update-where,omitempty"`
+	Batch             *BatchDSL       `json:"batch,omitempty"`
 	BeforeFind        *hook.Before    `json:"before:find,omitempty"`
 	AfterFind         *hook.After     `json:"after:find,omitempty"`
 	BeforeSearch      *hook.Before    `json:"before:search,omitempty"`